@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"log"
+	"os"
+	"time"
+
+	"github.com/mahigadamsetty/Inshorts-task/internal/config"
+	"github.com/mahigadamsetty/Inshorts-task/internal/db"
+	"github.com/mahigadamsetty/Inshorts-task/internal/llm"
+	"github.com/mahigadamsetty/Inshorts-task/internal/services"
+	"github.com/mahigadamsetty/Inshorts-task/internal/tenant"
+)
+
+func main() {
+	days := flag.Int("days", 30, "analyze searches logged in the last N days")
+	tenantID := flag.String("tenant", "", "only analyze searches of this tenant (default: all tenants)")
+	top := flag.Int("top", 20, "number of entities and locations to report")
+	flag.Parse()
+
+	// Load configuration
+	cfg := config.Load()
+
+	// Initialize database
+	database, err := db.Open(cfg.DatabaseURL)
+	if err != nil {
+		log.Fatalf("could not initialize database: %v", err)
+	}
+	svc := services.New(database, services.Options{})
+
+	ctx := context.Background()
+	if *tenantID != "" {
+		ctx = tenant.NewContext(ctx, &tenant.Tenant{ID: *tenantID})
+	}
+
+	client, err := llm.New(llm.ClientOptions{
+		APIKey:     cfg.LLMAPIKey,
+		Models:     cfg.ModelChain(),
+		LatencySLO: cfg.LatencySLO(),
+		API: llm.APIOptions{
+			Provider:     cfg.LLMProvider,
+			BaseURL:      cfg.LLMBaseURL,
+			Organization: cfg.OpenAIOrganization,
+			Project:      cfg.OpenAIProject,
+			APIVersion:   cfg.OpenAIAPIVersion,
+		},
+		Queue: llm.NewRequestQueue(cfg.LLMMaxConcurrent, cfg.LLMRequestsPerMinute, time.Duration(cfg.LLMQueueTimeout)*time.Second),
+	})
+	if err != nil {
+		log.Fatalf("could not configure the LLM API: %v", err)
+	}
+
+	since := time.Now().AddDate(0, 0, -*days)
+	analytics, err := svc.AnalyzeSearchLogs(ctx, client, since, *top)
+	if err != nil {
+		log.Fatalf("could not analyze search logs: %v", err)
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(analytics); err != nil {
+		log.Fatalf("could not write report: %v", err)
+	}
+}
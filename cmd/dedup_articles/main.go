@@ -0,0 +1,54 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"log"
+	"os"
+	"time"
+
+	"github.com/mahigadamsetty/Inshorts-task/internal/config"
+	"github.com/mahigadamsetty/Inshorts-task/internal/db"
+	"github.com/mahigadamsetty/Inshorts-task/internal/services"
+)
+
+func main() {
+	// Load configuration
+	cfg := config.Load()
+
+	dryRun := flag.Bool("dry-run", false, "report the duplicates without merging them")
+	resolve := flag.Bool("resolve-redirects", cfg.ResolveURLRedirects, "follow each article URL's redirects before canonicalizing it")
+	flag.Parse()
+
+	// Initialize database
+	database, err := db.Open(cfg.DatabaseURL)
+	if err != nil {
+		log.Fatalf("could not initialize database: %v", err)
+	}
+
+	// Resolve redirects politely, per source
+	svc := services.New(database, services.Options{
+		Crawler: services.NewCrawler(database, services.CrawlDefaults{
+			UserAgent:     cfg.CrawlUserAgent,
+			Delay:         time.Duration(cfg.CrawlDelayMs) * time.Millisecond,
+			MaxConcurrent: cfg.CrawlMaxConcurrent,
+		}),
+	})
+
+	report, err := svc.DedupArticles(context.Background(), *resolve, *dryRun)
+	if err != nil {
+		log.Fatalf("could not deduplicate articles: %v", err)
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(report); err != nil {
+		log.Fatalf("could not write report: %v", err)
+	}
+	if *dryRun {
+		log.Printf("Dry run: %d duplicate articles in %d groups would be merged", report.Merged, report.Groups)
+	} else {
+		log.Printf("Merged %d duplicate articles in %d groups", report.Merged, report.Groups)
+	}
+}
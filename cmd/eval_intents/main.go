@@ -0,0 +1,72 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"log"
+	"os"
+	"time"
+
+	"github.com/mahigadamsetty/Inshorts-task/internal/config"
+	"github.com/mahigadamsetty/Inshorts-task/internal/llm"
+	"github.com/mahigadamsetty/Inshorts-task/internal/services"
+)
+
+func main() {
+	samplesPath := flag.String("samples", "eval/intents.json", "JSON array of labeled queries: id, query, intent, entities and locations")
+	minLLMAccuracy := flag.Float64("min-llm-accuracy", 0, "exit with status 1 when the LLM's intent accuracy falls below this")
+	minHeuristicAccuracy := flag.Float64("min-heuristic-accuracy", 0, "exit with status 1 when the heuristic's intent accuracy falls below this")
+	flag.Parse()
+
+	// Load configuration
+	cfg := config.Load()
+
+	samples, err := services.LoadIntentSamples(*samplesPath)
+	if err != nil {
+		log.Fatalf("could not load samples: %v", err)
+	}
+
+	client, err := llm.New(llm.ClientOptions{
+		APIKey:     cfg.LLMAPIKey,
+		Models:     cfg.ModelChain(),
+		LatencySLO: cfg.LatencySLO(),
+		API: llm.APIOptions{
+			Provider:     cfg.LLMProvider,
+			BaseURL:      cfg.LLMBaseURL,
+			Organization: cfg.OpenAIOrganization,
+			Project:      cfg.OpenAIProject,
+			APIVersion:   cfg.OpenAIAPIVersion,
+		},
+		Queue: llm.NewRequestQueue(cfg.LLMMaxConcurrent, cfg.LLMRequestsPerMinute, time.Duration(cfg.LLMQueueTimeout)*time.Second),
+	})
+	if err != nil {
+		log.Fatalf("could not configure the LLM API: %v", err)
+	}
+
+	report := services.EvaluateIntents(client, cfg.ModelChain(), samples)
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(report); err != nil {
+		log.Fatalf("could not write report: %v", err)
+	}
+
+	failed := false
+	if report.LLM == nil {
+		log.Printf("OPENAI_API_KEY is not set, only the heuristic was evaluated")
+	} else {
+		log.Printf("LLM intent accuracy %.3f over %d queries (%d fallbacks)\n%s",
+			report.LLM.Accuracy, report.LLM.Scored, report.LLM.Fallbacks, services.ConfusionTable(report.LLM.Confusion))
+		failed = report.LLM.Accuracy < *minLLMAccuracy
+	}
+	log.Printf("Heuristic intent accuracy %.3f over %d queries\n%s",
+		report.Heuristic.Accuracy, report.Heuristic.Scored, services.ConfusionTable(report.Heuristic.Confusion))
+	if report.Heuristic.Accuracy < *minHeuristicAccuracy {
+		failed = true
+	}
+
+	if failed {
+		log.Printf("intent accuracy below threshold: LLM min %.3f, heuristic min %.3f", *minLLMAccuracy, *minHeuristicAccuracy)
+		os.Exit(1)
+	}
+}
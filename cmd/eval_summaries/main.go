@@ -0,0 +1,62 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"log"
+	"os"
+	"time"
+
+	"github.com/mahigadamsetty/Inshorts-task/internal/config"
+	"github.com/mahigadamsetty/Inshorts-task/internal/llm"
+	"github.com/mahigadamsetty/Inshorts-task/internal/services"
+)
+
+func main() {
+	samplesPath := flag.String("samples", "eval/summaries.json", "JSON array of labeled samples: id, title, text and reference summary")
+	maxWords := flag.Int("max-words", services.DefaultSummaryEvalOptions.MaxWords, "longest compliant summary in words, 0 for no limit")
+	minRougeL := flag.Float64("min-rouge-l", 0, "exit with status 1 when the average ROUGE-L F1 falls below this")
+	maxHallucination := flag.Float64("max-hallucination-rate", 1, "exit with status 1 when more summaries than this share hallucinate an entity")
+	flag.Parse()
+
+	// Load configuration
+	cfg := config.Load()
+
+	samples, err := services.LoadSummarySamples(*samplesPath)
+	if err != nil {
+		log.Fatalf("could not load samples: %v", err)
+	}
+
+	client, err := llm.New(llm.ClientOptions{
+		APIKey:     cfg.LLMAPIKey,
+		Models:     cfg.ModelChain(),
+		LatencySLO: cfg.LatencySLO(),
+		API: llm.APIOptions{
+			Provider:     cfg.LLMProvider,
+			BaseURL:      cfg.LLMBaseURL,
+			Organization: cfg.OpenAIOrganization,
+			Project:      cfg.OpenAIProject,
+			APIVersion:   cfg.OpenAIAPIVersion,
+		},
+		Queue: llm.NewRequestQueue(cfg.LLMMaxConcurrent, cfg.LLMRequestsPerMinute, time.Duration(cfg.LLMQueueTimeout)*time.Second),
+	})
+	if err != nil {
+		log.Fatalf("could not configure the LLM API: %v", err)
+	}
+
+	options := services.DefaultSummaryEvalOptions
+	options.MaxWords = *maxWords
+	report := services.EvaluateSummaries(client, cfg.ModelChain(), samples, options)
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(report); err != nil {
+		log.Fatalf("could not write report: %v", err)
+	}
+
+	if report.RougeL < *minRougeL || report.HallucinationRate > *maxHallucination {
+		log.Printf("summary quality below threshold: ROUGE-L %.3f (min %.3f), hallucination rate %.3f (max %.3f)",
+			report.RougeL, *minRougeL, report.HallucinationRate, *maxHallucination)
+		os.Exit(1)
+	}
+}
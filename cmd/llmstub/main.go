@@ -0,0 +1,38 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"net/http"
+
+	"github.com/mahigadamsetty/Inshorts-task/internal/llmstub"
+)
+
+func main() {
+	addr := flag.String("addr", ":8787", "address to listen on")
+	fixturesPath := flag.String("fixtures", "", "JSON file of request fingerprint -> canned response (default: generic answers only)")
+	flag.Parse()
+
+	fixtures := llmstub.Fixtures{}
+	if *fixturesPath != "" {
+		loaded, err := llmstub.LoadFixtures(*fixturesPath)
+		if err != nil {
+			log.Fatalf("could not load fixtures: %v", err)
+		}
+		fixtures = loaded
+	}
+
+	handler := llmstub.NewHandler(fixtures)
+	logged := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handler.ServeHTTP(w, r)
+		if fingerprint := w.Header().Get(llmstub.FingerprintHeader); fingerprint != "" {
+			_, matched := fixtures[fingerprint]
+			log.Printf("%s %s fingerprint=%s fixture=%v", r.Method, r.URL.Path, fingerprint, matched)
+		}
+	})
+
+	log.Printf("LLM stub listening on %s with %d fixtures; set OPENAI_BASE_URL=http://localhost%s/v1", *addr, len(fixtures), *addr)
+	if err := http.ListenAndServe(*addr, logged); err != nil {
+		log.Fatalf("LLM stub stopped: %v", err)
+	}
+}
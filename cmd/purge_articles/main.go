@@ -0,0 +1,60 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"log"
+	"os"
+
+	"github.com/mahigadamsetty/Inshorts-task/internal/config"
+	"github.com/mahigadamsetty/Inshorts-task/internal/db"
+	"github.com/mahigadamsetty/Inshorts-task/internal/services"
+)
+
+func main() {
+	// Load configuration
+	cfg := config.Load()
+
+	var input services.PurgeInput
+	flag.StringVar(&input.Tenant, "tenant", "", "only purge articles of this tenant")
+	flag.StringVar(&input.Source, "source", "", "purge articles of this source")
+	flag.StringVar(&input.Category, "category", "", "purge articles in this category")
+	flag.StringVar(&input.From, "from", "", "purge articles published on or after this day, like 2024-01-31")
+	flag.StringVar(&input.To, "to", "", "purge articles published on or before this day, like 2024-01-31")
+	confirm := flag.Int64("confirm", -1, "delete the articles, given the article count reported by a dry run; without it only counts are reported")
+	flag.Parse()
+
+	filter, err := input.Filter()
+	if err != nil {
+		log.Fatalf("invalid filter: %v", err)
+	}
+
+	// Initialize database
+	database, err := db.Open(cfg.DatabaseURL)
+	if err != nil {
+		log.Fatalf("could not initialize database: %v", err)
+	}
+	svc := services.New(database, services.Options{})
+
+	var records map[string]int64
+	if *confirm < 0 {
+		records, err = svc.CountPurge(context.Background(), filter)
+	} else {
+		records, err = svc.PurgeArticles(context.Background(), filter, *confirm)
+	}
+	if err != nil {
+		log.Fatalf("could not purge articles: %v", err)
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(records); err != nil {
+		log.Fatalf("could not write report: %v", err)
+	}
+	if *confirm < 0 {
+		log.Printf("Dry run: %d articles would be purged, run again with -confirm %d to delete them", records["articles"], records["articles"])
+	} else {
+		log.Printf("Purged %d articles", records["articles"])
+	}
+}
@@ -1,36 +1,77 @@
 package main
 
 import (
+	"context"
 	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
 
+	"github.com/gin-gonic/gin"
+	"github.com/mahigadamsetty/Inshorts-task/internal/app"
 	"github.com/mahigadamsetty/Inshorts-task/internal/config"
-	"github.com/mahigadamsetty/Inshorts-task/internal/db"
-	"github.com/mahigadamsetty/Inshorts-task/internal/router"
-	"github.com/mahigadamsetty/Inshorts-task/internal/services"
+	"github.com/mahigadamsetty/Inshorts-task/internal/scrub"
+	"github.com/mahigadamsetty/Inshorts-task/internal/tuning"
 )
 
 func main() {
 	// Load configuration
 	cfg := config.Load()
 	
-	// Initialize database
-	if err := db.Init(cfg.DatabaseURL); err != nil {
-		log.Fatalf("Failed to initialize database: %v", err)
+	// Keep emails, phone numbers and exact coordinates out of logs and LLM prompts
+	if err := scrub.Configure(cfg.PIIPatterns); err != nil {
+		log.Fatalf("Failed to configure PII scrubbing: %v", err)
 	}
+	log.SetOutput(scrub.NewWriter(os.Stderr))
+	gin.DefaultWriter = scrub.NewWriter(os.Stdout)
+	gin.DefaultErrorWriter = scrub.NewWriter(os.Stderr)
 	
-	// Initialize trending cache
-	services.InitTrendingCache(cfg.TrendingCacheTTL)
+	// Back up, restore or replay recorded requests instead of serving when asked to
+	if runCommand(cfg, os.Args[1:]) {
+		return
+	}
+	
+	// Build the database, caches, LLM client, services and handlers
+	server, err := app.New(cfg)
+	if err != nil {
+		log.Fatalf("Failed to initialize: %v", err)
+	}
+	
+	// Reload stop words and ranking weights on SIGHUP
+	hangup := make(chan os.Signal, 1)
+	signal.Notify(hangup, syscall.SIGHUP)
+	go func() {
+		for range hangup {
+			if _, err := tuning.Reload(); err != nil {
+				log.Printf("Tuning reload failed, keeping the previous settings: %v", err)
+			} else {
+				log.Printf("Tuning settings reloaded")
+			}
+		}
+	}()
 	
-	// Setup router
-	r := router.SetupRouter(cfg)
+	// Stop on SIGTERM or SIGINT, once buffered writes are flushed
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, os.Interrupt)
+	defer stop()
+	
+	// Run the scheduled jobs and background workers
+	if err := server.Start(ctx); err != nil {
+		log.Fatalf("Failed to start: %v", err)
+	}
 	
 	// Start server
-	addr := ":" + cfg.Port
-	log.Printf("Starting server on %s", addr)
-	log.Printf("OpenAI API Key configured: %v", cfg.OpenAIAPIKey != "")
-	log.Printf("LLM Model: %s", cfg.LLMModel)
+	log.Printf("Starting server on :%s", cfg.Port)
+	log.Printf("LLM provider: %s, API key configured: %v", cfg.LLMProvider, cfg.LLMAPIKey != "")
+	log.Printf("LLM Models: %s", strings.Join(cfg.ModelChain(), " -> "))
+	if cfg.FaultInjectionEnabled() {
+		log.Printf("Fault injection enabled via the X-Inject-Fault header")
+	} else if cfg.FaultInjection {
+		log.Printf("Ignoring FAULT_INJECTION in the %s environment", cfg.Environment)
+	}
 	
-	if err := r.Run(addr); err != nil {
+	if err := server.Run(ctx); err != nil {
 		log.Fatalf("Failed to start server: %v", err)
 	}
+	log.Printf("Server stopped")
 }
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/mahigadamsetty/Inshorts-task/internal/config"
+	"github.com/mahigadamsetty/Inshorts-task/internal/db"
+	"github.com/mahigadamsetty/Inshorts-task/internal/models"
+	"github.com/mahigadamsetty/Inshorts-task/internal/replay"
+	"github.com/mahigadamsetty/Inshorts-task/internal/tenant"
+)
+
+// replayRecordings re-issues recorded requests against another server and
+// prints the ones whose responses differ. It exits with status 1 when any
+// differ or fail.
+func replayRecordings(cfg *config.Config, args []string) {
+	flags := flag.NewFlagSet("replay", flag.ExitOnError)
+	target := flags.String("target", "", "base URL of the server to replay against, e.g. http://localhost:8081 (required)")
+	since := flags.Duration("since", 24*time.Hour, "replay requests recorded within this long")
+	path := flags.String("path", "", "only replay requests whose path starts with this")
+	tenantID := flags.String("tenant", "", "only replay requests of this tenant")
+	limit := flags.Int("limit", 200, "most requests to replay, most recent first")
+	ignore := flags.String("ignore", "session_id", "comma-separated JSON keys to leave out of the comparison")
+	maxDiffs := flags.Int("diffs", 5, "differences to print per request, 0 for all")
+	apiKey := flags.String("api-key", "", "API key to send with every request instead of the tenants' keys from TENANTS_FILE")
+	timeout := flags.Duration("timeout", 30*time.Second, "timeout of each request")
+	flags.Parse(args)
+	if *target == "" {
+		log.Fatal("-target is required")
+	}
+
+	database, err := db.Open(cfg.DatabaseURL)
+	if err != nil {
+		log.Fatalf("Failed to initialize database: %v", err)
+	}
+	query := database.Where("created_at > ?", time.Now().Add(-*since))
+	if *path != "" {
+		query = query.Where("substr(path, 1, ?) = ?", len(*path), *path)
+	}
+	if *tenantID != "" {
+		query = query.Where("tenant_id = ?", *tenantID)
+	}
+	var recordings []models.RecordedRequest
+	if err := query.Order("created_at DESC").Limit(*limit).Find(&recordings).Error; err != nil {
+		log.Fatalf("Failed to load recorded requests: %v", err)
+	}
+
+	options := replay.Options{
+		Target:   *target,
+		Keys:     make(map[string]string),
+		Ignore:   make(map[string]bool),
+		MaxDiffs: *maxDiffs,
+		Client:   &http.Client{Timeout: *timeout},
+	}
+	for _, key := range strings.Split(*ignore, ",") {
+		if key = strings.TrimSpace(key); key != "" {
+			options.Ignore[key] = true
+		}
+	}
+	tenants, err := tenant.LoadRegistry(cfg.TenantsFile)
+	if err != nil {
+		log.Fatalf("Failed to load tenants: %v", err)
+	}
+	for _, recording := range recordings {
+		if *apiKey != "" {
+			options.Keys[recording.TenantID] = *apiKey
+		} else if t, ok := tenants.Get(recording.TenantID); ok {
+			options.Keys[recording.TenantID] = t.APIKey
+		}
+	}
+
+	// Replay oldest first, in the order the traffic came in
+	matched, differed, failed := 0, 0, 0
+	for i := len(recordings) - 1; i >= 0; i-- {
+		result := replay.Replay(context.Background(), recordings[i], options)
+		request := recordings[i].Path
+		if recordings[i].Query != "" {
+			request += "?" + recordings[i].Query
+		}
+		switch {
+		case result.Err != nil:
+			failed++
+			fmt.Printf("FAIL %s %s (tenant %s): %v\n", recordings[i].Method, request, recordings[i].TenantID, result.Err)
+		case len(result.Diffs) > 0:
+			differed++
+			fmt.Printf("DIFF %s %s (tenant %s, recorded #%d)\n", recordings[i].Method, request, recordings[i].TenantID, recordings[i].ID)
+			for _, diff := range result.Diffs {
+				fmt.Printf("    %s\n", diff)
+			}
+		default:
+			matched++
+		}
+	}
+
+	log.Printf("Replayed %d requests against %s: %d matched, %d differed, %d failed", len(recordings), *target, matched, differed, failed)
+	if differed+failed > 0 {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/mahigadamsetty/Inshorts-task/internal/config"
+	"github.com/mahigadamsetty/Inshorts-task/internal/db"
+	"github.com/mahigadamsetty/Inshorts-task/internal/snapshot"
+)
+
+// runCommand runs the backup, restore or replay subcommand named by args and
+// reports whether it did, so anything else starts the server
+func runCommand(cfg *config.Config, args []string) bool {
+	if len(args) == 0 {
+		return false
+	}
+	switch args[0] {
+	case "backup":
+		backup(cfg, args[1:])
+	case "restore":
+		restore(cfg, args[1:])
+	case "replay":
+		replayRecordings(cfg, args[1:])
+	default:
+		return false
+	}
+	return true
+}
+
+// backup writes a snapshot of the database to a file. The snapshot is written
+// to a temporary file first so a failed backup never leaves a partial one.
+func backup(cfg *config.Config, args []string) {
+	flags := flag.NewFlagSet("backup", flag.ExitOnError)
+	out := flags.String("out", "news-snapshot-"+time.Now().Format("20060102-150405")+".jsonl.gz", "file to write the snapshot to")
+	flags.Parse(args)
+
+	database, err := db.Open(cfg.DatabaseURL)
+	if err != nil {
+		log.Fatalf("Failed to initialize database: %v", err)
+	}
+
+	file, err := os.CreateTemp(filepath.Dir(*out), ".snapshot-*")
+	if err != nil {
+		log.Fatalf("Failed to create snapshot: %v", err)
+	}
+
+	counts, err := snapshot.Write(context.Background(), database, file)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(file.Name(), *out)
+	}
+	if err != nil {
+		os.Remove(file.Name())
+		log.Fatalf("Failed to write snapshot: %v", err)
+	}
+
+	printCounts(counts)
+	log.Printf("Wrote snapshot %s", *out)
+}
+
+// restore loads a snapshot into the database, migrating its schema first
+func restore(cfg *config.Config, args []string) {
+	flags := flag.NewFlagSet("restore", flag.ExitOnError)
+	in := flags.String("in", "", "snapshot file to restore (required)")
+	replace := flags.Bool("replace", false, "delete the rows of the snapshot's tables before restoring")
+	flags.Parse(args)
+	if *in == "" {
+		log.Fatal("-in is required")
+	}
+
+	file, err := os.Open(*in)
+	if err != nil {
+		log.Fatalf("Failed to open snapshot: %v", err)
+	}
+	defer file.Close()
+
+	database, err := db.Open(cfg.DatabaseURL)
+	if err != nil {
+		log.Fatalf("Failed to initialize database: %v", err)
+	}
+
+	counts, err := snapshot.Restore(context.Background(), database, file, *replace)
+	if err != nil {
+		log.Fatalf("Failed to restore snapshot: %v", err)
+	}
+
+	printCounts(counts)
+	log.Printf("Restored snapshot %s; stories and topics are rebuilt by their clustering jobs", *in)
+}
+
+// printCounts writes the rows per table to stdout as JSON
+func printCounts(counts map[string]int64) {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(counts); err != nil {
+		log.Fatalf("Failed to write counts: %v", err)
+	}
+}
@@ -1,8 +1,11 @@
 package main
 
 import (
+	"context"
+	"flag"
 	"fmt"
 	"log"
+	"time"
 
 	"github.com/mahigadamsetty/Inshorts-task/internal/config"
 	"github.com/mahigadamsetty/Inshorts-task/internal/db"
@@ -11,20 +14,33 @@ import (
 )
 
 func main() {
-	// Load configuration
+	seed := flag.Int64("seed", 0, "random seed; the same seed over the same articles simulates the same events (default: time-based)")
+	profileName := flag.String("profile", "", "named traffic profile to simulate instead of 1000 uniform events")
+	profilesFile := flag.String("profiles", "", "YAML file of traffic profiles (default: SIMULATION_PROFILES_FILE)")
+	realtime := flag.Bool("realtime", false, "play the profile over its duration instead of backfilling it")
+	flag.Parse()
+	if *seed == 0 {
+		*seed = time.Now().UnixNano()
+	}
+
+	// Load configuration, refusing to write simulated events unless asked to
 	cfg := config.Load()
+	if !cfg.SimulationEnabled {
+		log.Fatal("Event simulation is disabled. Set SIMULATION_ENABLED=true to write simulated events.")
+	}
 
 	// Initialize database
-	if err := db.Init(cfg.DatabaseURL); err != nil {
+	database, err := db.Open(cfg.DatabaseURL)
+	if err != nil {
 		log.Fatalf("could not initialize database: %v", err)
 	}
+	svc := services.New(database, services.Options{Simulation: services.SimulationOptions{Enabled: true}})
 
 	fmt.Println("Database initialized.")
 
 	// Fetch all article IDs to simulate events for
 	var articles []models.Article
-	database := db.GetDB()
-	if err := database.Select("id, latitude, longitude").Find(&articles).Error; err != nil {
+	if err := database.Select("id, latitude, longitude, tenant_id").Scopes(services.HasLocation).Order("id").Find(&articles).Error; err != nil {
 		log.Fatalf("could not fetch articles: %v", err)
 	}
 
@@ -32,14 +48,38 @@ func main() {
 		log.Fatal("No articles found in the database. Please import data first.")
 	}
 
-	// Number of events to simulate
-	eventCount := 1000
+	// Count simulated events, writing the counters once at the end
+	svc.InitViewCounter(0)
+
+	if *profileName != "" {
+		if *profilesFile == "" {
+			*profilesFile = cfg.SimulationProfilesFile
+		}
+		profile, err := services.LoadSimulationProfile(*profilesFile, *profileName)
+		if err != nil {
+			log.Fatalf("could not load simulation profile: %v", err)
+		}
 
-	fmt.Printf("Simulating %d user events...\n", eventCount)
+		fmt.Printf("Simulating profile %s over %s with seed %d...\n", profile.Name, profile.Duration, *seed)
+		recorded, err := svc.RunSimulationProfile(context.Background(), articles, profile, *seed, *realtime)
+		if err != nil {
+			log.Fatalf("could not simulate user events: %v", err)
+		}
+		fmt.Printf("Recorded %d events.\n", recorded)
+	} else {
+		// Number of events to simulate
+		eventCount := 1000
+
+		fmt.Printf("Simulating %d user events with seed %d...\n", eventCount, *seed)
+
+		// Simulate events
+		if err := svc.SimulateUserEvents(articles, eventCount, *seed); err != nil {
+			log.Fatalf("could not simulate user events: %v", err)
+		}
+	}
 
-	// Simulate events
-	if err := services.SimulateUserEvents(articles, eventCount); err != nil {
-		log.Fatalf("could not simulate user events: %v", err)
+	if _, err := svc.FlushViewCounts(context.Background()); err != nil {
+		log.Fatalf("could not save view counts: %v", err)
 	}
 
 	fmt.Println("Successfully simulated user events.")
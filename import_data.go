@@ -1,17 +1,23 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"log"
 	"os"
+	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/mahigadamsetty/Inshorts-task/internal/config"
 	"github.com/mahigadamsetty/Inshorts-task/internal/db"
+	"github.com/mahigadamsetty/Inshorts-task/internal/llm"
 	"github.com/mahigadamsetty/Inshorts-task/internal/models"
 	"github.com/mahigadamsetty/Inshorts-task/internal/services"
+	"github.com/mahigadamsetty/Inshorts-task/internal/tenant"
+	"gorm.io/gorm/clause"
 )
 
 type JSONArticle struct {
@@ -25,20 +31,29 @@ type JSONArticle struct {
 	RelevanceScore  float64  `json:"relevance_score"`
 	Latitude        float64  `json:"latitude"`
 	Longitude       float64  `json:"longitude"`
+	VisibleFrom     string   `json:"visible_from,omitempty"` // Optional embargo, in any publication date layout
+	ExpiresAt       string   `json:"expires_at,omitempty"`   // Optional expiry, in any publication date layout
 }
 
 func main() {
 	if len(os.Args) < 2 {
-		log.Fatal("Usage: go run import_data.go <path_to_json_file>")
+		log.Fatal("Usage: go run import_data.go <path_to_json_file> [tenant_id]")
 	}
 
 	filename := os.Args[1]
 
+	// Articles belong to the default tenant unless one is given
+	tenantID := tenant.DefaultID
+	if len(os.Args) > 2 {
+		tenantID = os.Args[2]
+	}
+
 	// Load configuration
 	cfg := config.Load()
 
 	// Initialize database
-	if err := db.Init(cfg.DatabaseURL); err != nil {
+	database, err := db.Open(cfg.DatabaseURL)
+	if err != nil {
 		log.Fatalf("Failed to initialize database: %v", err)
 	}
 
@@ -57,37 +72,113 @@ func main() {
 
 	log.Printf("Found %d articles to import", len(jsonArticles))
 
-	// Convert to GORM models
-	articles := make([]models.Article, len(jsonArticles))
-	for i, ja := range jsonArticles {
-		// Parse publication date
-		pubDate, err := time.Parse("2006-01-02T15:04:05", ja.PublicationDate)
+	// Fetch article pages politely, per source, when resolving redirects and storing text
+	svc := services.New(database, services.Options{
+		Crawler: services.NewCrawler(database, services.CrawlDefaults{
+			UserAgent:     cfg.CrawlUserAgent,
+			Delay:         time.Duration(cfg.CrawlDelayMs) * time.Millisecond,
+			MaxConcurrent: cfg.CrawlMaxConcurrent,
+		}),
+		Simulation: services.SimulationOptions{Enabled: cfg.SimulationEnabled},
+	})
+
+	// Publication dates are parsed with each source's layouts, then the defaults
+	dateFormats, err := services.LoadDateFormats(cfg.DateFormatsFile)
+	if err != nil {
+		log.Fatalf("Failed to load date formats: %v", err)
+	}
+
+	// Store categories under the hierarchy's names, seeding it on a new database
+	if _, err := svc.SeedCategories(context.Background()); err != nil {
+		log.Fatalf("Failed to seed categories: %v", err)
+	}
+
+	// Convert to GORM models, setting aside articles whose date can't be parsed
+	// rather than giving them a made-up one
+	articles := make([]models.Article, 0, len(jsonArticles))
+	var rejected []JSONArticle
+	invalidLocations, imputedLocations := 0, 0
+	seen := map[string]int{} // Dedup key -> index in articles
+	duplicates := 0
+	for _, ja := range jsonArticles {
+		pubDate, err := dateFormats.Parse(ja.SourceName, ja.PublicationDate)
 		if err != nil {
-			// Try alternative formats
-			pubDate, err = time.Parse(time.RFC3339, ja.PublicationDate)
-			if err != nil {
-				log.Printf("Warning: Failed to parse date for article %s: %v", ja.ID, err)
-				pubDate = time.Now()
-			}
+			log.Printf("Warning: Skipping article %s from %s: %v", ja.ID, ja.SourceName, err)
+			rejected = append(rejected, ja)
+			continue
+		}
+
+		visibleFrom, expiresAt, err := parseVisibility(dateFormats, ja)
+		if err != nil {
+			log.Printf("Warning: Skipping article %s from %s: %v", ja.ID, ja.SourceName, err)
+			rejected = append(rejected, ja)
+			continue
 		}
 
-		articles[i] = models.Article{
+		article := models.Article{
 			ID:              ja.ID,
 			Title:           ja.Title,
 			Description:     ja.Description,
 			URL:             ja.URL,
 			PublicationDate: pubDate,
 			SourceName:      ja.SourceName,
-			Category:        models.StringArray(ja.Category),
+			Category:        models.StringArray(svc.CanonicalCategories(context.Background(), ja.Category)),
 			RelevanceScore:  ja.RelevanceScore,
 			Latitude:        ja.Latitude,
 			Longitude:       ja.Longitude,
+			TenantID:        tenantID,
+			VisibleFrom:     visibleFrom,
+			ExpiresAt:       expiresAt,
+		}
+
+		// Merge articles of the file with the same canonical URL and title into the first
+		article.CanonicalURL = svc.CanonicalArticleURL(context.Background(), ja.SourceName, ja.URL, cfg.ResolveURLRedirects)
+		article.TitleKey = services.TitleKey(ja.Title)
+		if key := services.DedupKey(article); key != "" {
+			if first, ok := seen[key]; ok {
+				log.Printf("Article %s duplicates %s (%s), merging", ja.ID, articles[first].ID, *article.CanonicalURL)
+				mergeCategories(&articles[first], article.Category)
+				duplicates++
+				continue
+			}
+			seen[key] = len(articles)
+		}
+
+		// Flag out-of-range and 0,0 coordinates, imputing them from a named place if enabled
+		if !services.ValidateLocation(&article, cfg.ImputeLocations) {
+			invalidLocations++
+			if article.LocationSource == models.LocationImputed {
+				log.Printf("Article %s has invalid coordinates %g,%g, imputed from its text", ja.ID, ja.Latitude, ja.Longitude)
+				imputedLocations++
+			} else {
+				log.Printf("Warning: Article %s has invalid coordinates %g,%g and is excluded from geo endpoints", ja.ID, ja.Latitude, ja.Longitude)
+			}
+		}
+		articles = append(articles, article)
+	}
+	if duplicates > 0 {
+		log.Printf("Merged %d duplicate articles within the file", duplicates)
+	}
+	if invalidLocations > 0 {
+		log.Printf("%d articles had invalid coordinates: %d imputed, %d left without a location", invalidLocations, imputedLocations, invalidLocations-imputedLocations)
+	}
+
+	// Keep rejected articles for fixing and re-importing
+	if len(rejected) > 0 {
+		rejectedFile := strings.TrimSuffix(filename, filepath.Ext(filename)) + ".rejected.json"
+		out, err := json.MarshalIndent(rejected, "", "  ")
+		if err == nil {
+			err = os.WriteFile(rejectedFile, out, 0644)
+		}
+		if err != nil {
+			log.Printf("Warning: could not write rejected articles: %v", err)
+		} else {
+			log.Printf("Rejected %d articles with unparseable or inconsistent dates, written to %s", len(rejected), rejectedFile)
 		}
 	}
 
 	// Import in batches
 	batchSize := 100
-	database := db.GetDB()
 
 	for i := 0; i < len(articles); i += batchSize {
 		end := i + batchSize
@@ -95,23 +186,67 @@ func main() {
 			end = len(articles)
 		}
 
+		// Articles already stored, by ID or canonical URL and title, are skipped
 		batch := articles[i:end]
-		if err := database.Create(&batch).Error; err != nil {
-			log.Printf("Warning: Failed to import batch %d-%d: %v", i, end, err)
+		result := database.Clauses(clause.OnConflict{DoNothing: true}).Create(&batch)
+		if result.Error != nil {
+			log.Printf("Warning: Failed to import batch %d-%d: %v", i, end, result.Error)
 		} else {
-			log.Printf("Imported articles %d-%d", i, end)
+			log.Printf("Imported articles %d-%d, %d already stored", i, end, int64(len(batch))-result.RowsAffected)
 		}
 	}
 
 	log.Println("Import complete!")
 
-	// After importing, simulate some user events for trending analysis
-	log.Println("Simulating user events...")
+	// Store the full text of the new articles so summaries never fetch URLs
+	log.Println("Fetching article text...")
+	stored, err := svc.FetchArticleTexts(context.Background(), services.TextFetchPolicy{
+		Workers:     cfg.TextFetchWorkers,
+		BatchSize:   batchSize,
+		MinQuality:  cfg.ExtractionMinQuality,
+		MaxAttempts: cfg.TextFetchMaxAttempts,
+	})
+	if err != nil {
+		log.Printf("Warning: article text fetch failed: %v", err)
+	} else {
+		log.Printf("Stored full text for %d articles", stored)
+	}
+
+	// Run the content safety pass over the new articles
+	log.Println("Moderating imported articles...")
+	client, err := llm.New(llm.ClientOptions{
+		APIKey:     cfg.LLMAPIKey,
+		Models:     cfg.ModelChain(),
+		LatencySLO: cfg.LatencySLO(),
+		API: llm.APIOptions{
+			Provider:     cfg.LLMProvider,
+			BaseURL:      cfg.LLMBaseURL,
+			Organization: cfg.OpenAIOrganization,
+			Project:      cfg.OpenAIProject,
+			APIVersion:   cfg.OpenAIAPIVersion,
+		},
+		Queue: llm.NewRequestQueue(cfg.LLMMaxConcurrent, cfg.LLMRequestsPerMinute, time.Duration(cfg.LLMQueueTimeout)*time.Second),
+	})
+	if err != nil {
+		log.Fatalf("could not configure the LLM API: %v", err)
+	}
+	moderated, err := svc.ModerateUnratedArticles(context.Background(), client, batchSize)
+	if err != nil {
+		log.Printf("Warning: content moderation failed: %v", err)
+	} else {
+		log.Printf("Moderated %d articles", moderated)
+	}
+
+	// After importing, simulate some user events for trending analysis, when
+	// the simulation is enabled
 	var importedArticles []models.Article
-	if err := database.Find(&importedArticles).Error; err != nil {
+	if !cfg.SimulationEnabled {
+		log.Println("Skipping user event simulation, set SIMULATION_ENABLED=true to simulate events for trending")
+	} else if err := database.Where("tenant_id = ?", tenantID).Scopes(services.HasLocation).Find(&importedArticles).Error; err != nil {
 		log.Printf("Warning: could not fetch imported articles for event simulation: %v", err)
 	} else {
-		if err := services.SimulateUserEvents(importedArticles, 1000); err != nil {
+		log.Println("Simulating user events...")
+		if err := svc.SimulateUserEvents(importedArticles, 1000, time.Now().UnixNano()); err != nil {
 			log.Printf("Warning: failed to simulate user events: %v", err)
 		} else {
 			log.Println("Successfully simulated user events.")
@@ -127,3 +262,42 @@ func main() {
 	database.Model(&models.Event{}).Count(&eventCount)
 	fmt.Printf("Database now contains %d events\n", eventCount)
 }
+
+// parseVisibility parses an article's optional embargo and expiry times with
+// the layouts of its source
+func parseVisibility(dateFormats *services.DateFormats, ja JSONArticle) (visibleFrom, expiresAt *time.Time, err error) {
+	if ja.VisibleFrom != "" {
+		t, err := dateFormats.ParseSchedule(ja.SourceName, ja.VisibleFrom)
+		if err != nil {
+			return nil, nil, fmt.Errorf("visible_from: %w", err)
+		}
+		visibleFrom = &t
+	}
+	if ja.ExpiresAt != "" {
+		t, err := dateFormats.ParseSchedule(ja.SourceName, ja.ExpiresAt)
+		if err != nil {
+			return nil, nil, fmt.Errorf("expires_at: %w", err)
+		}
+		expiresAt = &t
+	}
+	if visibleFrom != nil && expiresAt != nil && !expiresAt.After(*visibleFrom) {
+		return nil, nil, fmt.Errorf("expires_at %s is not after visible_from %s", ja.ExpiresAt, ja.VisibleFrom)
+	}
+	return visibleFrom, expiresAt, nil
+}
+
+// mergeCategories adds the categories an article doesn't have yet
+func mergeCategories(article *models.Article, categories []string) {
+	for _, category := range categories {
+		found := false
+		for _, existing := range article.Category {
+			if existing == category {
+				found = true
+				break
+			}
+		}
+		if !found {
+			article.Category = append(article.Category, category)
+		}
+	}
+}
@@ -0,0 +1,452 @@
+// Package app builds the server's components once, in dependency order:
+// config, database, caches, LLM client, services and handlers. Each component
+// is created with its constructor and passed to the ones that use it.
+package app
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mahigadamsetty/Inshorts-task/internal/blob"
+	"github.com/mahigadamsetty/Inshorts-task/internal/cache"
+	"github.com/mahigadamsetty/Inshorts-task/internal/config"
+	"github.com/mahigadamsetty/Inshorts-task/internal/db"
+	"github.com/mahigadamsetty/Inshorts-task/internal/geoip"
+	"github.com/mahigadamsetty/Inshorts-task/internal/handlers"
+	"github.com/mahigadamsetty/Inshorts-task/internal/llm"
+	"github.com/mahigadamsetty/Inshorts-task/internal/publisher"
+	"github.com/mahigadamsetty/Inshorts-task/internal/router"
+	"github.com/mahigadamsetty/Inshorts-task/internal/scheduler"
+	"github.com/mahigadamsetty/Inshorts-task/internal/search"
+	"github.com/mahigadamsetty/Inshorts-task/internal/services"
+	"github.com/mahigadamsetty/Inshorts-task/internal/telegram"
+	"github.com/mahigadamsetty/Inshorts-task/internal/tenant"
+	"github.com/mahigadamsetty/Inshorts-task/internal/tts"
+	"github.com/mahigadamsetty/Inshorts-task/internal/tuning"
+	"github.com/mahigadamsetty/Inshorts-task/internal/utils"
+	"gorm.io/gorm"
+)
+
+// App holds the components of the server
+type App struct {
+	Config       *config.Config
+	DB           *gorm.DB
+	Trending     *services.TrendingCache
+	Conversation *services.ConversationCache
+	Heatmap      *services.HeatmapCache
+	Crawler      *services.Crawler
+	Services     *services.Services
+	LLM          *llm.Client
+	GeoIP        *geoip.DB // Nil unless GEOIP_FALLBACK is set
+	Tenants      *tenant.Registry
+	Publishers   *publisher.Registry
+	Scheduler    *scheduler.Scheduler
+	Summarizer   *services.Summarizer
+	Reindexer    *services.Reindexer
+	DataRequests *services.DataRequests
+	Outbox       *services.Outbox
+	Telegram     *services.TelegramBot // Nil unless TELEGRAM_BOT_TOKEN is set
+	Router       *gin.Engine
+}
+
+// New builds every component from the configuration. Nothing runs in the
+// background until Start is called.
+func New(cfg *config.Config) (*App, error) {
+	a := &App{Config: cfg}
+
+	// Load stop words and ranking weights
+	ranking := tuning.DefaultRanking
+	ranking.FreshnessWeight = cfg.FreshnessWeight
+	ranking.FreshnessHalfLifeHours = cfg.FreshnessHalfLifeHours
+	ranking.ReliabilityBoost = cfg.SourceReliabilityBoost
+	ranking.PopularityHalfLifeHours = cfg.PopularityHalfLifeHours
+	if err := tuning.Configure(cfg.TuningFile, ranking); err != nil {
+		return nil, fmt.Errorf("failed to load tuning settings: %w", err)
+	}
+
+	database, err := db.Open(cfg.DatabaseURL)
+	if err != nil {
+		return nil, err
+	}
+	a.DB = database
+
+	// Cache trending lists, heatmaps and /query sessions in this process, or
+	// in the database for every replica
+	cacheStore, err := cache.New(cfg.CacheStore, cache.Options{DB: database})
+	if err != nil {
+		return nil, err
+	}
+	a.Trending = services.NewTrendingCache(cfg.TrendingCacheTTL, cacheStore)
+	a.Conversation = services.NewConversationCache(cfg.ConversationTTL, cacheStore)
+	a.Heatmap = services.NewHeatmapCache(cfg.HeatmapCacheTTL, cacheStore)
+
+	// Fetch article pages politely, per source
+	a.Crawler = services.NewCrawler(database, services.CrawlDefaults{
+		UserAgent:     cfg.CrawlUserAgent,
+		Delay:         time.Duration(cfg.CrawlDelayMs) * time.Millisecond,
+		MaxConcurrent: cfg.CrawlMaxConcurrent,
+	})
+
+	// Pace LLM requests from handlers and background jobs alike, and talk to
+	// the configured provider, or another endpoint of it, e.g. a gateway or
+	// cmd/llmstub
+	llmQueue := llm.NewRequestQueue(cfg.LLMMaxConcurrent, cfg.LLMRequestsPerMinute, time.Duration(cfg.LLMQueueTimeout)*time.Second)
+	llmAPI := llm.APIOptions{
+		Provider:     cfg.LLMProvider,
+		BaseURL:      cfg.LLMBaseURL,
+		Organization: cfg.OpenAIOrganization,
+		Project:      cfg.OpenAIProject,
+		APIVersion:   cfg.OpenAIAPIVersion,
+	}
+	a.LLM, err = llm.New(llm.ClientOptions{
+		APIKey:     cfg.LLMAPIKey,
+		Models:     cfg.ModelChain(),
+		LatencySLO: cfg.LatencySLO(),
+		API:        llmAPI,
+		Queue:      llmQueue,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if a.Tenants, err = tenant.LoadRegistry(cfg.TenantsFile); err != nil {
+		return nil, fmt.Errorf("failed to load tenants: %w", err)
+	}
+	if a.Publishers, err = publisher.LoadRegistry(cfg.PublishersFile, a.Tenants); err != nil {
+		return nil, fmt.Errorf("failed to load publishers: %w", err)
+	}
+
+	// Pull articles from the feeds, queries and crawl lists configured through
+	// the admin API, parsing their dates with the import's layouts
+	dateFormats, err := services.LoadDateFormats(cfg.DateFormatsFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load date formats: %w", err)
+	}
+
+	// Keep article text, preview images, spoken summaries and exports in the
+	// configured blob store, one directory or bucket prefix per kind of file
+	stores := map[string]blob.Store{}
+	for _, kind := range []struct {
+		name    string
+		dir     string
+		private bool
+	}{
+		{"audio", cfg.AudioDir, false},
+		{"exports", cfg.ExportDir, true},
+		{"texts", cfg.TextDir, false},
+		{"images", cfg.ImageDir, false},
+	} {
+		store, err := newBlobStore(cfg, kind.name, kind.dir, kind.private)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create %s blob store: %w", kind.name, err)
+		}
+		stores[kind.name] = store
+	}
+
+	// Read summaries aloud with the configured text-to-speech provider. Its
+	// requests go through the LLM queue, without the chat latency SLO.
+	audio := services.AudioOptions{Store: stores["audio"]}
+	if cfg.TTSProvider != "" {
+		speechLLM, err := llm.New(llm.ClientOptions{APIKey: cfg.LLMAPIKey, API: llmAPI, Queue: llmQueue})
+		if err != nil {
+			return nil, err
+		}
+		if audio.Provider, err = tts.New(cfg.TTSProvider, tts.Options{
+			Model: cfg.TTSModel,
+			Voice: cfg.TTSVoice,
+			LLM:   speechLLM,
+		}); err != nil {
+			return nil, fmt.Errorf("failed to create TTS provider: %w", err)
+		}
+	}
+
+	// Match keyword searches in the configured search engine instead of the
+	// database, once the search-index job has copied the articles over
+	var searchBackend search.Backend
+	if cfg.SearchBackend != "" {
+		if searchBackend, err = search.New(cfg.SearchBackend, search.Options{
+			URL:      cfg.SearchURL,
+			Index:    cfg.SearchIndex,
+			Username: cfg.SearchUsername,
+			Password: cfg.SearchPassword,
+		}); err != nil {
+			return nil, fmt.Errorf("failed to create search backend: %w", err)
+		}
+	}
+
+	a.Services = services.New(database, services.Options{
+		Trending:      a.Trending,
+		Conversations: a.Conversation,
+		Heatmaps:      a.Heatmap,
+		Crawler:       a.Crawler,
+		Tenants:       a.Tenants,
+
+		// Truncate user coordinates before they are stored or logged, and
+		// give out coordinates as precisely as tenants may see them
+		LocationPrivacy:     &services.LocationPrivacy{Precision: cfg.LocationPrecision, CoarsePrecision: cfg.CoarseLocationPrecision},
+		ResponseCoordinates: &utils.CoordinateLimit{Decimals: cfg.CoordinatePrecision, Omit: cfg.OmitCoordinates},
+
+		// Reject list requests that would load too many articles to rank
+		MaxCandidates: cfg.QueryMaxCandidates,
+
+		// Keep tenants' webhooks off internal addresses
+		PrivateWebhooks: cfg.WebhookAllowPrivate,
+
+		// Keep simulated traffic out unless the deployment asks for it
+		Simulation: services.SimulationOptions{Enabled: cfg.SimulationEnabled, IncludeInTrending: cfg.TrendingSimulated},
+
+		Ingestion: services.IngestionOptions{
+			NewsAPIKey:     cfg.NewsAPIKey,
+			NewsAPIBaseURL: cfg.NewsAPIBaseURL,
+			Dates:          dateFormats,
+			Publish:        services.PublishOptions{ResolveRedirects: cfg.ResolveURLRedirects, ImputeLocations: cfg.ImputeLocations},
+		},
+		Blobs: services.BlobStores{
+			Text:        stores["texts"],
+			Images:      stores["images"],
+			StoreText:   cfg.StoreArticleText,
+			StoreImages: cfg.StoreArticleImages,
+		},
+		Audio:               audio,
+		Search:              searchBackend,
+		SearchMaxCandidates: cfg.SearchMaxCandidates,
+	})
+
+	// Seed source reliability and bias metadata
+	if seeded, err := a.Services.SeedSources(context.Background()); err != nil {
+		return nil, fmt.Errorf("failed to seed sources: %w", err)
+	} else if seeded > 0 {
+		log.Printf("Seeded %d sources", seeded)
+	}
+
+	// Seed the category hierarchy category filters expand through
+	if seeded, err := a.Services.SeedCategories(context.Background()); err != nil {
+		return nil, fmt.Errorf("failed to seed categories: %w", err)
+	} else if seeded > 0 {
+		log.Printf("Seeded %d categories", seeded)
+	}
+
+	// Initialize buffered view counters and the queue of ingested events
+	a.Services.InitViewCounter(cfg.ViewFlushInterval)
+	a.Services.InitEventQueue(cfg.EventQueueSize, cfg.EventFlushInterval, cfg.EventMaxAttempts)
+
+	// Meter each API key's monthly usage against its quotas
+	a.Services.InitUsage(cfg.UsageFlushInterval)
+
+	// Sample the articles list endpoints return as impressions, for CTR reports
+	a.Services.InitImpressions(cfg.ImpressionSampleRate, cfg.ImpressionFlushInterval)
+
+	// Order the discovery feeds with the configured bandit policy
+	discovery := services.DiscoveryOptions{Policy: cfg.DiscoveryPolicy, Epsilon: cfg.DiscoveryEpsilon, PoolSize: cfg.DiscoveryPoolSize}
+	if err := a.Services.InitDiscovery(discovery); err != nil {
+		return nil, fmt.Errorf("invalid discovery settings: %w", err)
+	}
+
+	a.Outbox = services.NewOutbox(a.Services, services.OutboxOptions{
+		Workers:      cfg.OutboxWorkers,
+		MaxAttempts:  cfg.OutboxMaxAttempts,
+		Backoff:      time.Duration(cfg.OutboxBackoff) * time.Second,
+		PollInterval: time.Duration(cfg.OutboxPollInterval) * time.Second,
+	})
+
+	// Let Telegram users subscribe to alerts in chat, delivered through the outbox
+	if cfg.TelegramBotToken != "" {
+		subscribers, ok := a.Tenants.Get(cfg.TelegramTenant)
+		if !ok {
+			return nil, fmt.Errorf("telegram tenant %s is not defined", cfg.TelegramTenant)
+		}
+		bot := telegram.NewClient(cfg.TelegramBotToken, cfg.TelegramAPIURL)
+		a.Outbox.UseTelegram(bot)
+		a.Telegram = services.NewTelegramBot(a.Services, bot, services.TelegramBotOptions{
+			Tenant:         subscribers,
+			RadiusKm:       cfg.TelegramRadiusKm,
+			SpikeThreshold: cfg.TelegramSpikeThreshold,
+		})
+	}
+	a.Summarizer = services.NewSummarizer(a.Services, a.LLM, cfg.SummarizerWorkers)
+	a.Reindexer = services.NewReindexer(a.Services, a.LLM)
+	a.DataRequests = services.NewDataRequests(a.Services, stores["exports"])
+	a.Scheduler = scheduler.New(database)
+	if err := a.registerJobs(); err != nil {
+		return nil, err
+	}
+
+	// Slash commands are answered as the integrations tenant
+	if _, err := cfg.DiscordKey(); err != nil {
+		return nil, err
+	}
+	integrationsTenant, ok := a.Tenants.Get(cfg.IntegrationsTenant)
+	if !ok {
+		return nil, fmt.Errorf("integrations tenant %s is not defined", cfg.IntegrationsTenant)
+	}
+
+	// Locate clients sending no coordinates from their IP address
+	if cfg.GeoIPFallback {
+		if a.GeoIP, err = geoip.Open(cfg.GeoIPFile); err != nil {
+			return nil, fmt.Errorf("failed to load GeoIP table: %w", err)
+		}
+		log.Printf("GeoIP fallback on, %d networks", a.GeoIP.Len())
+	}
+
+	news := handlers.NewNewsHandler(cfg, database, a.Services, a.LLM, a.Summarizer, a.GeoIP)
+	routes := router.Handlers{
+		News:         news,
+		Admin:        handlers.NewAdminHandler(cfg, database, a.Services, a.Scheduler, a.Summarizer, a.Reindexer),
+		UserData:     handlers.NewUserDataHandler(a.Services, a.DataRequests),
+		Publisher:    handlers.NewPublisherHandler(cfg, a.Services, a.Scheduler),
+		ShortLinks:   handlers.NewShortLinkHandler(cfg, a.Services, a.Tenants),
+		Integrations: handlers.NewIntegrationHandler(cfg, news, integrationsTenant),
+		MCP:          handlers.NewMCPHandler(cfg),
+		Usage:        handlers.NewUsageHandler(a.Services),
+	}
+	if cfg.SiteURL != "" {
+		site, ok := a.Tenants.Get(cfg.SiteTenant)
+		if !ok {
+			return nil, fmt.Errorf("site tenant %s is not defined", cfg.SiteTenant)
+		}
+		routes.Sitemap = handlers.NewSitemapHandler(cfg, a.Services, site)
+	}
+	a.Router = router.SetupRouter(cfg, a.Tenants, a.Publishers, a.Services, routes)
+	return a, nil
+}
+
+// Start runs the scheduler, the background workers and, when configured, the
+// simulated traffic, then kicks off the startup passes of the jobs
+func (a *App) Start(ctx context.Context) error {
+	a.Scheduler.Start(ctx)
+
+	// Requested and bulk summaries, embedding index rebuilds, data requests,
+	// geofence alert deliveries and the Telegram bot
+	a.Summarizer.Start(ctx)
+	if a.Config.SummaryPrewarm > 0 {
+		queued, err := a.Summarizer.Prewarm(ctx, a.Config.SummaryPrewarm)
+		if err != nil {
+			log.Printf("Failed to prewarm summaries: %v", err)
+		} else if queued > 0 {
+			log.Printf("Prewarming summaries of %d of the newest %d articles", queued, a.Config.SummaryPrewarm)
+		}
+	}
+	a.Reindexer.Start(ctx)
+	a.DataRequests.Start(ctx)
+	a.Outbox.Start(ctx)
+	if a.Telegram != nil {
+		a.Telegram.Start(ctx)
+	}
+
+	// Play a simulated traffic profile for demos, when the simulation is enabled
+	if a.Config.SimulationProfile != "" && !a.Config.SimulationEnabled {
+		log.Printf("Ignoring SIMULATION_PROFILE %s, set SIMULATION_ENABLED to play it", a.Config.SimulationProfile)
+	} else if a.Config.SimulationProfile != "" {
+		profile, err := services.LoadSimulationProfile(a.Config.SimulationProfilesFile, a.Config.SimulationProfile)
+		if err != nil {
+			return fmt.Errorf("failed to load simulation profile: %w", err)
+		}
+		if err := a.Services.StartEventSimulation(ctx, profile, time.Now().UnixNano()); err != nil {
+			return fmt.Errorf("failed to start simulation profile: %w", err)
+		}
+	}
+
+	// Run startup passes without waiting for the first tick, checking the data
+	// they read first when asked to
+	go func() {
+		if a.Config.IntegrityCheckOnStartup {
+			logIntegrity(ctx, a.Services)
+		}
+		for _, name := range startupJobs {
+			if err := a.Scheduler.RunIfDue(ctx, name); err != nil {
+				log.Printf("Startup run of %s failed: %v", name, err)
+			}
+		}
+	}()
+	return nil
+}
+
+// Run serves the API on the configured port until the server fails or ctx is
+// done. It then stops accepting connections, waits up to the shutdown timeout
+// for the requests in flight, and writes out the events, view counts,
+// impressions and usage buffered in memory.
+func (a *App) Run(ctx context.Context) error {
+	server := &http.Server{Addr: ":" + a.Config.Port, Handler: a.Router}
+	failed := make(chan error, 1)
+	go func() {
+		failed <- server.ListenAndServe()
+	}()
+
+	select {
+	case err := <-failed:
+		return err
+	case <-ctx.Done():
+	}
+
+	timeout := time.Duration(a.Config.ShutdownTimeout) * time.Second
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	err := server.Shutdown(shutdownCtx)
+	if err != nil {
+		log.Printf("Requests still in flight at shutdown: %v", err)
+	}
+
+	flushCtx, cancelFlush := context.WithTimeout(context.Background(), timeout)
+	defer cancelFlush()
+	a.flush(flushCtx)
+	return nil
+}
+
+// flush writes what the services buffer in memory. Events go first, since
+// writing them counts views and impression clicks.
+func (a *App) flush(ctx context.Context) {
+	if n, err := a.Services.FlushEvents(ctx); err != nil {
+		log.Printf("Failed to flush events at shutdown: %v", err)
+	} else if n > 0 {
+		log.Printf("Flushed %d events at shutdown", n)
+	}
+	if _, err := a.Services.FlushViewCounts(ctx); err != nil {
+		log.Printf("Failed to flush view counts at shutdown: %v", err)
+	}
+	if _, err := a.Services.FlushImpressions(ctx); err != nil {
+		log.Printf("Failed to flush impressions at shutdown: %v", err)
+	}
+	if _, err := a.Services.FlushUsage(ctx); err != nil {
+		log.Printf("Failed to flush API usage at shutdown: %v", err)
+	}
+}
+
+// newBlobStore creates the store of one kind of file: a directory of its own
+// for the local store, a prefix named after the kind in a bucket
+func newBlobStore(cfg *config.Config, kind, dir string, private bool) (blob.Store, error) {
+	return blob.New(cfg.BlobStore, blob.Options{
+		Dir:             dir,
+		Private:         private,
+		Bucket:          cfg.BlobBucket,
+		Prefix:          cfg.BlobPrefix + kind + "/",
+		Endpoint:        cfg.BlobEndpoint,
+		Region:          cfg.BlobRegion,
+		AccessKeyID:     cfg.BlobAccessKeyID,
+		SecretAccessKey: cfg.BlobSecretAccessKey,
+		PublicURL:       cfg.BlobPublicURL,
+		LinkTTL:         time.Duration(cfg.BlobLinkTTL) * time.Second,
+	})
+}
+
+// logIntegrity runs the integrity checks and logs what they found
+func logIntegrity(ctx context.Context, svc *services.Services) {
+	report, err := svc.CheckIntegrity(ctx)
+	if err != nil {
+		log.Printf("Integrity check failed: %v", err)
+		return
+	}
+	if report.Clean {
+		log.Printf("Integrity check of %d articles and %d events found no issues", report.Articles, report.Events)
+		return
+	}
+	for _, check := range report.Checks {
+		if check.Count > 0 {
+			log.Printf("Integrity check: %d %s, e.g. %s", check.Count, check.Name, strings.Join(check.Samples, ", "))
+		}
+	}
+}
@@ -0,0 +1,187 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/mahigadamsetty/Inshorts-task/internal/scheduler"
+	"github.com/mahigadamsetty/Inshorts-task/internal/services"
+)
+
+// startupJobs are run once at startup without waiting for their first tick
+var startupJobs = []string{"text-fetch", "content-moderation", "story-clustering", "topic-clustering", "score-recalibration", "search-index"}
+
+// registerJobs registers the scheduled jobs with the app's scheduler
+func (a *App) registerJobs() error {
+	cfg := a.Config
+	jobs := map[string]struct {
+		spec string
+		run  scheduler.JobFunc
+	}{
+		// Store the full text of newly ingested articles for summarization, retry
+		// poor extractions and re-summarize recent articles whose text changed
+		"text-fetch": {fmt.Sprintf("@every %ds", cfg.TextFetchInterval), func(ctx context.Context) error {
+			policy := services.TextFetchPolicy{
+				Workers:       cfg.TextFetchWorkers,
+				BatchSize:     100,
+				MinQuality:    cfg.ExtractionMinQuality,
+				MaxAttempts:   cfg.TextFetchMaxAttempts,
+				RefetchAfter:  time.Duration(cfg.TextRefetchAfterHours) * time.Hour,
+				RefetchWindow: time.Duration(cfg.TextRefetchWindowHours) * time.Hour,
+			}
+			changed, err := a.Services.FetchArticleTexts(ctx, policy)
+			if err != nil {
+				return err
+			}
+			// Move texts fetched before the text store was enabled, a batch a run
+			if moved, err := a.Services.OffloadArticleTexts(ctx, 100); err != nil {
+				log.Printf("Failed to move article texts to the blob store: %v", err)
+			} else if moved > 0 {
+				log.Printf("Text fetch moved %d article texts to the blob store", moved)
+			}
+			refreshed, err := a.Services.RefreshStaleSummaries(ctx, a.LLM, 100)
+			if err == nil && changed > 0 {
+				log.Printf("Text fetch stored new text for %d articles and refreshed %d summaries", changed, refreshed)
+			}
+			return err
+		}},
+		// Tag articles that have not been through the content safety pass yet
+		"content-moderation": {"@every 10m", func(ctx context.Context) error {
+			moderated, err := a.Services.ModerateUnratedArticles(ctx, a.LLM, 100)
+			if err == nil {
+				log.Printf("Content moderation tagged %d articles", moderated)
+			}
+			return err
+		}},
+		// Group related articles into developing stories, classifying the rebuilt
+		// stories right away
+		"story-clustering": {fmt.Sprintf("@every %ds", cfg.StoryClusterInterval), func(ctx context.Context) error {
+			count, err := a.Services.ClusterStories(ctx, cfg.StorySimilarity)
+			if err != nil {
+				return err
+			}
+			log.Printf("Story clustering produced %d stories", count)
+			_, err = a.Services.UpdateLifecycleStates(ctx, int64(cfg.BreakingMinEvents))
+			return err
+		}},
+		// Move stories and articles between breaking, developing and stale
+		"lifecycle-states": {fmt.Sprintf("@every %ds", cfg.LifecycleInterval), func(ctx context.Context) error {
+			changed, err := a.Services.UpdateLifecycleStates(ctx, int64(cfg.BreakingMinEvents))
+			if err == nil && changed.Stories+changed.Articles > 0 {
+				log.Printf("Lifecycle states changed for %d stories and %d articles", changed.Stories, changed.Articles)
+			}
+			return err
+		}},
+		// Embed new articles and regroup the corpus into topic hubs
+		"topic-clustering": {fmt.Sprintf("@every %ds", cfg.TopicClusterInterval), func(ctx context.Context) error {
+			embedded, err := a.Services.EmbedArticles(ctx, a.LLM)
+			if err != nil {
+				return err
+			}
+			count, err := a.Services.ClusterTopics(ctx, a.LLM, cfg.TopicCount)
+			if err == nil {
+				log.Printf("Topic clustering embedded %d articles and produced %d topics", embedded, count)
+			}
+			return err
+		}},
+		// Raise webhook alerts for new articles and reading spikes inside geofences
+		"geofence-alerts": {fmt.Sprintf("@every %ds", cfg.GeofenceCheckInterval), func(ctx context.Context) error {
+			window := time.Duration(cfg.SpikeWindowSeconds) * time.Second
+			raised, err := a.Services.EvaluateGeofences(ctx, window, cfg.SpikeFactor)
+			if err == nil && raised > 0 {
+				log.Printf("Geofence evaluation raised %d alerts", raised)
+			}
+			return err
+		}},
+		// Recompute relevance from engagement, source reliability and recency
+		"score-recalibration": {cfg.RecalibrationSchedule, func(ctx context.Context) error {
+			updated, err := a.Services.RecalibrateScores(ctx, services.CurrentRankingProfile())
+			if err == nil {
+				log.Printf("Score recalibration updated %d articles", updated)
+			}
+			return err
+		}},
+		// Fold new viewers into decayed popularity and sample scores for charting
+		"popularity-decay": {cfg.PopularityDecaySchedule, func(ctx context.Context) error {
+			sampled, err := a.Services.DecayPopularity(ctx, services.CurrentRankingProfile(), cfg.ScoreHistoryDays)
+			if err == nil {
+				log.Printf("Popularity decay recorded scores of %d articles", sampled)
+			}
+			return err
+		}},
+		// Group anonymous clients' events and reads into sessions
+		"session-stitching": {fmt.Sprintf("@every %ds", cfg.SessionStitchInterval), func(ctx context.Context) error {
+			stitched, err := a.Services.StitchSessions(ctx)
+			if err == nil && stitched > 0 {
+				log.Printf("Session stitching updated %d sessions", stitched)
+			}
+			return err
+		}},
+		// Estimate position bias from impressions and clicks, for the debiased
+		// engagement signal of score recalibration
+		"click-model": {fmt.Sprintf("@every %ds", cfg.ClickModelInterval), func(ctx context.Context) error {
+			window := time.Duration(cfg.ClickModelWindowDays) * 24 * time.Hour
+			result, err := a.Services.EstimateClickModel(ctx, window)
+			if err == nil && result.Articles > 0 {
+				log.Printf("Click model estimated %d positions and %d articles", result.Positions, result.Articles)
+			}
+			return err
+		}},
+		// Pull new articles from the ingestion sources that are due
+		"ingestion": {fmt.Sprintf("@every %ds", cfg.IngestionPollInterval), func(ctx context.Context) error {
+			result, err := a.Services.IngestDueSources(ctx)
+			if err == nil && result.Sources > 0 {
+				log.Printf("Ingestion ran %d sources (%d failed) and stored %d articles", result.Sources, result.Failed, result.Ingested)
+			}
+			return err
+		}},
+		// Read new and rewritten summaries aloud, when a TTS provider is configured
+		"audio-summaries": {fmt.Sprintf("@every %ds", cfg.AudioInterval), func(ctx context.Context) error {
+			result, err := a.Services.SynthesizeSummaries(ctx, cfg.AudioBatchSize)
+			if err == nil && result.Synthesized+result.Removed > 0 {
+				log.Printf("Audio summaries synthesized %d (%d failed) and removed %d unused files", result.Synthesized, result.Failed, result.Removed)
+			}
+			return err
+		}},
+		// Copy written articles to the search backend, when one is configured
+		"search-index": {fmt.Sprintf("@every %ds", cfg.SearchIndexInterval), func(ctx context.Context) error {
+			result, err := a.Services.IndexArticles(ctx, cfg.SearchIndexBatch)
+			if err == nil && (result.Created || result.Indexed > 0) {
+				log.Printf("Search indexing copied %d articles (index created: %t)", result.Indexed, result.Created)
+			}
+			return err
+		}},
+		// Remove delivered notifications once they are past retention
+		"outbox-cleanup": {"@daily", func(ctx context.Context) error {
+			purged, err := a.Services.PurgeOutbox(ctx, time.Duration(cfg.OutboxRetentionDays)*24*time.Hour)
+			if err == nil && purged > 0 {
+				log.Printf("Outbox cleanup removed %d delivered messages", purged)
+			}
+			return err
+		}},
+		// Remove recorded requests once they are past retention
+		"recording-cleanup": {"@hourly", func(ctx context.Context) error {
+			purged, err := a.Services.PurgeRecordings(ctx, time.Duration(cfg.RecordRetentionHours)*time.Hour)
+			if err == nil && purged > 0 {
+				log.Printf("Recording cleanup removed %d recorded requests", purged)
+			}
+			return err
+		}},
+		// Remove stored texts and images no article refers to any more
+		"blob-cleanup": {"@daily", func(ctx context.Context) error {
+			result, err := a.Services.CleanupBlobs(ctx)
+			if err == nil && result.Texts+result.Images > 0 {
+				log.Printf("Blob cleanup removed %d texts and %d images", result.Texts, result.Images)
+			}
+			return err
+		}},
+	}
+	for name, job := range jobs {
+		if err := a.Scheduler.Register(name, job.spec, job.run); err != nil {
+			return fmt.Errorf("failed to register job: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,78 @@
+// Package apperr defines the domain errors returned by services. Errors wrap
+// one of the sentinels so handlers can map them to HTTP statuses in one place
+// without depending on the database or the LLM client.
+package apperr
+
+import (
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// Domain error kinds. Test for them with errors.Is.
+var (
+	ErrNotFound      = errors.New("not found")
+	ErrInvalidFilter = errors.New("invalid filter")
+	ErrUpstreamLLM   = errors.New("language model request failed")
+	ErrCacheMiss     = errors.New("cache miss")
+	ErrTooExpensive  = errors.New("request too expensive")
+	ErrConflict      = errors.New("conflict")
+	ErrUnavailable   = errors.New("temporarily unavailable")
+)
+
+// domainError is an error of a kind with a message meant for the caller
+type domainError struct {
+	kind    error
+	message string
+}
+
+func (e *domainError) Error() string { return e.message }
+
+func (e *domainError) Is(target error) bool { return target == e.kind }
+
+// NotFoundf returns an ErrNotFound with the formatted message
+func NotFoundf(format string, args ...interface{}) error {
+	return &domainError{kind: ErrNotFound, message: fmt.Sprintf(format, args...)}
+}
+
+// InvalidFilterf returns an ErrInvalidFilter with the formatted message. It
+// reports any invalid filter or parameter of a request.
+func InvalidFilterf(format string, args ...interface{}) error {
+	return &domainError{kind: ErrInvalidFilter, message: fmt.Sprintf(format, args...)}
+}
+
+// TooExpensivef returns an ErrTooExpensive with the formatted message. It
+// reports a request that would load more rows than the server allows.
+func TooExpensivef(format string, args ...interface{}) error {
+	return &domainError{kind: ErrTooExpensive, message: fmt.Sprintf(format, args...)}
+}
+
+// Conflictf returns an ErrConflict with the formatted message. It reports a
+// request that clashes with the current state, like a job already running.
+func Conflictf(format string, args ...interface{}) error {
+	return &domainError{kind: ErrConflict, message: fmt.Sprintf(format, args...)}
+}
+
+// Unavailablef returns an ErrUnavailable with the formatted message. It
+// reports a request refused for now that may succeed when retried.
+func Unavailablef(format string, args ...interface{}) error {
+	return &domainError{kind: ErrUnavailable, message: fmt.Sprintf(format, args...)}
+}
+
+// NotFound translates a missing record into an ErrNotFound naming what was
+// looked up, like "Story not found". Other errors are returned unchanged.
+func NotFound(err error, what string) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return NotFoundf("%s not found", what)
+	}
+	return err
+}
+
+// UpstreamLLM wraps an error of the language model API as an ErrUpstreamLLM
+func UpstreamLLM(err error) error {
+	if err == nil {
+		return nil
+	}
+	return fmt.Errorf("%w: %w", ErrUpstreamLLM, err)
+}
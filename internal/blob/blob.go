@@ -0,0 +1,124 @@
+// Package blob keeps files — fetched article text, preview images, spoken
+// summaries and data exports — out of the database, on local disk or in an
+// S3 or GCS bucket. Stores register a factory under a name, and the server
+// creates one store per kind of file from the one BLOB_STORE names. The
+// database refers to blobs by the URL Put returns, e.g. s3://bucket/key.
+package blob
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrNotExist is returned for blobs that are not stored
+var ErrNotExist = errors.New("blob does not exist")
+
+// Object is a stored blob
+type Object struct {
+	URL      string
+	Name     string // Key of the blob within its store
+	Size     int64
+	Modified time.Time
+}
+
+// Store keeps blobs under names, like "ab12.mp3". Names may not contain "/".
+type Store interface {
+	// Put stores size bytes read from r under name, replacing any blob there,
+	// and returns the blob's URL. Readers never see a partly written blob.
+	Put(ctx context.Context, name string, r io.Reader, size int64, contentType string) (string, error)
+	// Open reads the blob at a URL Put returned
+	Open(ctx context.Context, url string) (io.ReadCloser, error)
+	// Stat describes the blob at a URL, or returns ErrNotExist
+	Stat(ctx context.Context, url string) (Object, error)
+	// Delete removes the blob at a URL. Missing blobs are not an error.
+	Delete(ctx context.Context, url string) error
+	// List describes every blob of the store
+	List(ctx context.Context) ([]Object, error)
+	// URL is the URL a blob stored under name has, whether or not it exists
+	URL(name string) string
+	// Link returns a URL clients can download the blob from directly, or ""
+	// when it has to be served through the API
+	Link(ctx context.Context, url string) (string, error)
+}
+
+// Options configure a store. Stores ignore the options they don't use.
+type Options struct {
+	Dir             string // Directory of a local store
+	Private         bool   // Local files are only readable by the server
+	Bucket          string
+	Prefix          string // Prepended to names in the bucket, e.g. "audio/"
+	Endpoint        string // API address, for S3-compatible services
+	Region          string
+	AccessKeyID     string
+	SecretAccessKey string
+	PublicURL       string        // Base URL the bucket is publicly readable at, e.g. a CDN
+	LinkTTL         time.Duration // Lifetime of presigned links when there is no public URL
+}
+
+// Factory creates a store from its options
+type Factory func(options Options) (Store, error)
+
+var (
+	mu        sync.RWMutex
+	factories = map[string]Factory{}
+)
+
+// Register makes a store available under a name, replacing any registered
+// under the same name
+func Register(name string, factory Factory) {
+	mu.Lock()
+	defer mu.Unlock()
+	factories[strings.ToLower(name)] = factory
+}
+
+// New creates the store registered under name
+func New(name string, options Options) (Store, error) {
+	mu.RLock()
+	factory, ok := factories[strings.ToLower(name)]
+	mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown blob store %q, expected one of %s", name, strings.Join(Names(), ", "))
+	}
+	return factory(options)
+}
+
+// Names lists the registered stores
+func Names() []string {
+	mu.RLock()
+	defer mu.RUnlock()
+	names := make([]string, 0, len(factories))
+	for name := range factories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ReadAll reads the whole blob at a URL, failing for blobs over limit bytes
+func ReadAll(ctx context.Context, store Store, url string, limit int64) ([]byte, error) {
+	r, err := store.Open(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	data, err := io.ReadAll(io.LimitReader(r, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > limit {
+		return nil, fmt.Errorf("blob %s is larger than %d bytes", url, limit)
+	}
+	return data, nil
+}
+
+// validName reports whether a name can be stored: no separators or dot names
+// that would leave the store's directory or prefix
+func validName(name string) bool {
+	return name != "" && name != "." && name != ".." && !strings.ContainsAny(name, "/\\\x00")
+}
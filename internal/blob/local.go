@@ -0,0 +1,138 @@
+package blob
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+func init() {
+	Register("local", newLocal)
+}
+
+// local keeps blobs as files of one directory. Its URLs are file:// URLs;
+// plain paths, which the database held before blob storage, are read too.
+type local struct {
+	dir     string
+	private bool
+}
+
+func newLocal(options Options) (Store, error) {
+	if options.Dir == "" {
+		return nil, fmt.Errorf("the local blob store needs a directory")
+	}
+	dir, err := filepath.Abs(options.Dir)
+	if err != nil {
+		return nil, err
+	}
+	return &local{dir: dir, private: options.Private}, nil
+}
+
+func (s *local) Put(ctx context.Context, name string, r io.Reader, size int64, contentType string) (string, error) {
+	if !validName(name) {
+		return "", fmt.Errorf("invalid blob name %q", name)
+	}
+	dirMode, fileMode := os.FileMode(0o755), os.FileMode(0o644)
+	if s.private {
+		dirMode, fileMode = 0o700, 0o600
+	}
+	if err := os.MkdirAll(s.dir, dirMode); err != nil {
+		return "", err
+	}
+
+	// Write under a temporary name first, so readers never see a partial file
+	file, err := os.CreateTemp(s.dir, ".blob-*")
+	if err != nil {
+		return "", err
+	}
+	_, err = io.Copy(file, r)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(file.Name(), fileMode)
+	}
+	if err == nil {
+		err = os.Rename(file.Name(), filepath.Join(s.dir, name))
+	}
+	if err != nil {
+		os.Remove(file.Name())
+		return "", err
+	}
+	return s.URL(name), nil
+}
+
+func (s *local) Open(ctx context.Context, rawURL string) (io.ReadCloser, error) {
+	file, err := os.Open(s.path(rawURL))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrNotExist
+	}
+	return file, err
+}
+
+func (s *local) Stat(ctx context.Context, rawURL string) (Object, error) {
+	path := s.path(rawURL)
+	info, err := os.Stat(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return Object{}, ErrNotExist
+	}
+	if err != nil {
+		return Object{}, err
+	}
+	return Object{URL: rawURL, Name: filepath.Base(path), Size: info.Size(), Modified: info.ModTime()}, nil
+}
+
+func (s *local) Delete(ctx context.Context, rawURL string) error {
+	err := os.Remove(s.path(rawURL))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+func (s *local) List(ctx context.Context) ([]Object, error) {
+	entries, err := os.ReadDir(s.dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	objects := make([]Object, 0, len(entries))
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue // Removed since the directory was read
+		}
+		objects = append(objects, Object{URL: s.URL(entry.Name()), Name: entry.Name(), Size: info.Size(), Modified: info.ModTime()})
+	}
+	return objects, nil
+}
+
+func (s *local) URL(name string) string {
+	return (&url.URL{Scheme: "file", Path: filepath.ToSlash(filepath.Join(s.dir, name))}).String()
+}
+
+// Link is always empty: local files are served through the API
+func (s *local) Link(ctx context.Context, rawURL string) (string, error) {
+	return "", nil
+}
+
+// path turns a file:// URL, or a plain path, into a file path
+func (s *local) path(rawURL string) string {
+	if strings.HasPrefix(rawURL, "file://") {
+		if parsed, err := url.Parse(rawURL); err == nil {
+			return filepath.FromSlash(parsed.Path)
+		}
+	}
+	return rawURL
+}
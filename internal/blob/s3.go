@@ -0,0 +1,376 @@
+package blob
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Defaults of the s3 and gcs stores
+const (
+	S3Region    = "us-east-1"
+	GCSEndpoint = "https://storage.googleapis.com"
+	LinkTTL     = time.Hour
+)
+
+// emptyPayloadHash is the SHA-256 of an empty body
+const emptyPayloadHash = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+
+func init() {
+	Register("s3", func(options Options) (Store, error) {
+		if options.Region == "" {
+			options.Region = S3Region
+		}
+		if options.Endpoint == "" {
+			options.Endpoint = "https://s3." + options.Region + ".amazonaws.com"
+		}
+		return newBucket("s3", options)
+	})
+	// GCS speaks the S3 API through its XML API, with HMAC keys of a service
+	// account as the access key and secret
+	Register("gcs", func(options Options) (Store, error) {
+		if options.Region == "" {
+			options.Region = "auto"
+		}
+		if options.Endpoint == "" {
+			options.Endpoint = GCSEndpoint
+		}
+		return newBucket("gs", options)
+	})
+}
+
+// bucket keeps blobs as objects under a prefix of an S3-compatible bucket,
+// signing requests with AWS Signature Version 4. Its URLs are
+// <scheme>://bucket/key.
+type bucket struct {
+	scheme    string
+	endpoint  *url.URL
+	options   Options
+	publicURL string
+	http      *http.Client
+}
+
+func newBucket(scheme string, options Options) (Store, error) {
+	if options.Bucket == "" {
+		return nil, fmt.Errorf("the %s blob store needs a bucket", scheme)
+	}
+	if options.AccessKeyID == "" || options.SecretAccessKey == "" {
+		return nil, fmt.Errorf("the %s blob store needs an access key ID and secret", scheme)
+	}
+	endpoint, err := url.Parse(strings.TrimRight(options.Endpoint, "/"))
+	if err != nil || (endpoint.Scheme != "http" && endpoint.Scheme != "https") || endpoint.Host == "" {
+		return nil, fmt.Errorf("invalid blob endpoint %q", options.Endpoint)
+	}
+	if options.LinkTTL <= 0 {
+		options.LinkTTL = LinkTTL
+	}
+	return &bucket{
+		scheme:    scheme,
+		endpoint:  endpoint,
+		options:   options,
+		publicURL: strings.TrimRight(options.PublicURL, "/"),
+		http:      &http.Client{Timeout: 5 * time.Minute}, // Exports can be large
+	}, nil
+}
+
+func (s *bucket) Put(ctx context.Context, name string, r io.Reader, size int64, contentType string) (string, error) {
+	if !validName(name) {
+		return "", fmt.Errorf("invalid blob name %q", name)
+	}
+	key := s.options.Prefix + name
+	req, err := s.request(ctx, "PUT", key, nil, r)
+	if err != nil {
+		return "", err
+	}
+	req.ContentLength = size
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	resp, err := s.send(req, "UNSIGNED-PAYLOAD")
+	if err != nil {
+		return "", err
+	}
+	resp.Body.Close()
+	return s.URL(name), nil
+}
+
+func (s *bucket) Open(ctx context.Context, rawURL string) (io.ReadCloser, error) {
+	key, err := s.key(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	req, err := s.request(ctx, "GET", key, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.send(req, emptyPayloadHash)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+func (s *bucket) Stat(ctx context.Context, rawURL string) (Object, error) {
+	key, err := s.key(rawURL)
+	if err != nil {
+		return Object{}, err
+	}
+	req, err := s.request(ctx, "HEAD", key, nil, nil)
+	if err != nil {
+		return Object{}, err
+	}
+	resp, err := s.send(req, emptyPayloadHash)
+	if err != nil {
+		return Object{}, err
+	}
+	resp.Body.Close()
+	modified, _ := http.ParseTime(resp.Header.Get("Last-Modified"))
+	return Object{URL: rawURL, Name: strings.TrimPrefix(key, s.options.Prefix), Size: resp.ContentLength, Modified: modified}, nil
+}
+
+func (s *bucket) Delete(ctx context.Context, rawURL string) error {
+	key, err := s.key(rawURL)
+	if err != nil {
+		return err
+	}
+	req, err := s.request(ctx, "DELETE", key, nil, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := s.send(req, emptyPayloadHash)
+	if errors.Is(err, ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+func (s *bucket) List(ctx context.Context) ([]Object, error) {
+	var objects []Object
+	token := ""
+	for {
+		query := url.Values{"list-type": {"2"}, "prefix": {s.options.Prefix}}
+		if token != "" {
+			query.Set("continuation-token", token)
+		}
+		req, err := s.request(ctx, "GET", "", query, nil)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := s.send(req, emptyPayloadHash)
+		if err != nil {
+			return nil, err
+		}
+		var page struct {
+			Contents []struct {
+				Key          string    `xml:"Key"`
+				Size         int64     `xml:"Size"`
+				LastModified time.Time `xml:"LastModified"`
+			} `xml:"Contents"`
+			IsTruncated           bool   `xml:"IsTruncated"`
+			NextContinuationToken string `xml:"NextContinuationToken"`
+		}
+		err = xml.NewDecoder(resp.Body).Decode(&page)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("listing blobs: %w", err)
+		}
+		for _, content := range page.Contents {
+			name := strings.TrimPrefix(content.Key, s.options.Prefix)
+			if !validName(name) {
+				continue // Under a deeper prefix, e.g. another kind of blob's
+			}
+			objects = append(objects, Object{URL: s.URL(name), Name: name, Size: content.Size, Modified: content.LastModified})
+		}
+		if !page.IsTruncated || page.NextContinuationToken == "" {
+			return objects, nil
+		}
+		token = page.NextContinuationToken
+	}
+}
+
+func (s *bucket) URL(name string) string {
+	return s.scheme + "://" + s.options.Bucket + "/" + s.options.Prefix + name
+}
+
+// Link returns the object's address under the public URL when the bucket has
+// one, and a presigned GET link otherwise
+func (s *bucket) Link(ctx context.Context, rawURL string) (string, error) {
+	key, err := s.key(rawURL)
+	if err != nil {
+		return "", err
+	}
+	if s.publicURL != "" {
+		return s.publicURL + "/" + escapePath(key), nil
+	}
+
+	now := time.Now().UTC()
+	query := url.Values{
+		"X-Amz-Algorithm":     {"AWS4-HMAC-SHA256"},
+		"X-Amz-Credential":    {s.options.AccessKeyID + "/" + s.scope(now)},
+		"X-Amz-Date":          {now.Format("20060102T150405Z")},
+		"X-Amz-Expires":       {strconv.Itoa(int(s.options.LinkTTL.Seconds()))},
+		"X-Amz-SignedHeaders": {"host"},
+	}
+	target := s.objectURL(key, nil)
+	canonical := strings.Join([]string{
+		"GET",
+		target.EscapedPath(),
+		canonicalQuery(query),
+		"host:" + target.Host + "\n",
+		"host",
+		"UNSIGNED-PAYLOAD",
+	}, "\n")
+	query.Set("X-Amz-Signature", s.signature(now, canonical))
+	target.RawQuery = canonicalQuery(query)
+	return target.String(), nil
+}
+
+// key returns the object key of a URL of this store
+func (s *bucket) key(rawURL string) (string, error) {
+	prefix := s.scheme + "://" + s.options.Bucket + "/"
+	if !strings.HasPrefix(rawURL, prefix) {
+		return "", fmt.Errorf("blob %s is not in bucket %s", rawURL, s.options.Bucket)
+	}
+	return strings.TrimPrefix(rawURL, prefix), nil
+}
+
+// objectURL addresses an object, or the bucket for an empty key, path-style
+func (s *bucket) objectURL(key string, query url.Values) *url.URL {
+	target := *s.endpoint
+	target.Path = s.endpoint.Path + "/" + s.options.Bucket
+	if key != "" {
+		target.Path += "/" + key
+	}
+	target.RawPath = s.endpoint.Path + "/" + escapePath(s.options.Bucket)
+	if key != "" {
+		target.RawPath += "/" + escapePath(key)
+	}
+	if query != nil {
+		target.RawQuery = canonicalQuery(query)
+	}
+	return &target
+}
+
+// request builds an unsigned request for an object or the bucket
+func (s *bucket) request(ctx context.Context, method, key string, query url.Values, body io.Reader) (*http.Request, error) {
+	return http.NewRequestWithContext(ctx, method, s.objectURL(key, query).String(), body)
+}
+
+// send signs and sends a request, turning 404s into ErrNotExist and other
+// failures into errors carrying the service's message
+func (s *bucket) send(req *http.Request, payloadHash string) (*http.Response, error) {
+	now := time.Now().UTC()
+	req.Header.Set("X-Amz-Date", now.Format("20060102T150405Z"))
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	signed := []string{"host", "x-amz-content-sha256", "x-amz-date"}
+	canonical := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		canonicalQuery(req.URL.Query()),
+		"host:" + req.URL.Host + "\n" +
+			"x-amz-content-sha256:" + payloadHash + "\n" +
+			"x-amz-date:" + req.Header.Get("X-Amz-Date") + "\n",
+		strings.Join(signed, ";"),
+		payloadHash,
+	}, "\n")
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.options.AccessKeyID, s.scope(now), strings.Join(signed, ";"), s.signature(now, canonical)))
+
+	resp, err := s.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 200 && resp.StatusCode <= 299 {
+		return resp, nil
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, ErrNotExist
+	}
+	var failure struct {
+		Code    string `xml:"Code"`
+		Message string `xml:"Message"`
+	}
+	xml.NewDecoder(io.LimitReader(resp.Body, 64<<10)).Decode(&failure)
+	return nil, fmt.Errorf("%s %s returned status %d: %s %s", req.Method, req.URL.Path, resp.StatusCode, failure.Code, failure.Message)
+}
+
+// scope is the credential scope of requests signed at now
+func (s *bucket) scope(now time.Time) string {
+	return now.Format("20060102") + "/" + s.options.Region + "/s3/aws4_request"
+}
+
+// signature signs a canonical request with a key derived from the secret
+func (s *bucket) signature(now time.Time, canonical string) string {
+	digest := sha256.Sum256([]byte(canonical))
+	toSign := "AWS4-HMAC-SHA256\n" + now.Format("20060102T150405Z") + "\n" + s.scope(now) + "\n" + hex.EncodeToString(digest[:])
+
+	key := []byte("AWS4" + s.options.SecretAccessKey)
+	for _, part := range []string{now.Format("20060102"), s.options.Region, "s3", "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	return hex.EncodeToString(hmacSHA256(key, toSign))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// canonicalQuery encodes query parameters sorted by name, escaped as
+// Signature Version 4 requires
+func canonicalQuery(query url.Values) string {
+	names := make([]string, 0, len(query))
+	for name := range query {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var parts []string
+	for _, name := range names {
+		values := append([]string(nil), query[name]...)
+		sort.Strings(values)
+		for _, value := range values {
+			parts = append(parts, escape(name)+"="+escape(value))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+// escapePath escapes each segment of a key, keeping the slashes
+func escapePath(key string) string {
+	segments := strings.Split(key, "/")
+	for i, segment := range segments {
+		segments[i] = escape(segment)
+	}
+	return strings.Join(segments, "/")
+}
+
+// escape percent-encodes everything but unreserved characters
+func escape(s string) string {
+	var b strings.Builder
+	for _, c := range []byte(s) {
+		if ('A' <= c && c <= 'Z') || ('a' <= c && c <= 'z') || ('0' <= c && c <= '9') || c == '-' || c == '_' || c == '.' || c == '~' {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
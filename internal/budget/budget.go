@@ -0,0 +1,98 @@
+// Package budget gives requests a response-time budget. Optional stages, like
+// summary enrichment, check the budget and are skipped once it is spent, so
+// the request returns partial results in time instead of timing out.
+package budget
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Stages that are skipped or cut short when the budget runs out
+const (
+	StageIntent     = "intent"     // LLM intent extraction on /query, replaced by keyword heuristics
+	StageRelaxation = "relaxation" // Further /query constraint relaxations while nothing matched
+	StageDiversify  = "diversify"  // Source and category diversification of /trending
+	StageSummaries  = "summaries"  // Summaries of articles that don't have one yet
+)
+
+// Budget is the time a request may spend and the stages it skipped
+type Budget struct {
+	deadline time.Time
+	mu       sync.Mutex
+	skipped  []string
+}
+
+type contextKey struct{}
+
+// NewContext returns a context carrying a budget of d from now
+func NewContext(ctx context.Context, d time.Duration) context.Context {
+	return context.WithValue(ctx, contextKey{}, &Budget{deadline: time.Now().Add(d)})
+}
+
+// FromContext returns the budget stored in the context, or nil
+func FromContext(ctx context.Context) *Budget {
+	if ctx == nil {
+		return nil
+	}
+	b, _ := ctx.Value(contextKey{}).(*Budget)
+	return b
+}
+
+// Spent reports whether the context's budget has run out. Requests without a
+// budget never run out.
+func Spent(ctx context.Context) bool {
+	b := FromContext(ctx)
+	return b != nil && !time.Now().Before(b.deadline)
+}
+
+// WithDeadline bounds a stage's work by the context's budget, so a slow call
+// is cut short when the budget runs out. Without a budget the context is
+// returned as is.
+func WithDeadline(ctx context.Context) (context.Context, context.CancelFunc) {
+	b := FromContext(ctx)
+	if b == nil {
+		return ctx, func() {}
+	}
+	return context.WithDeadline(ctx, b.deadline)
+}
+
+// Skip notes a stage that was skipped or cut short on the context's budget
+func Skip(ctx context.Context, stage string) {
+	FromContext(ctx).Skip(stage)
+}
+
+// Skipped returns the stages skipped on the context's budget
+func Skipped(ctx context.Context) []string {
+	return FromContext(ctx).Skipped()
+}
+
+// Skip notes that a stage was skipped, once. Safe on a nil budget.
+func (b *Budget) Skip(stage string) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, skipped := range b.skipped {
+		if skipped == stage {
+			return
+		}
+	}
+	b.skipped = append(b.skipped, stage)
+}
+
+// Skipped returns a copy of the skipped stages in the order they were skipped,
+// or nil when the request ran in full
+func (b *Budget) Skipped() []string {
+	if b == nil {
+		return nil
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if len(b.skipped) == 0 {
+		return nil
+	}
+	return append([]string(nil), b.skipped...)
+}
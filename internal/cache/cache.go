@@ -0,0 +1,105 @@
+// Package cache keeps short-lived computed values — trending lists and
+// scores, heatmaps and /query sessions — in memory or in the database. The
+// memory store is private to one process; the database store is shared, so
+// replicas behind a load balancer see the same entries and invalidate them
+// for each other. Stores register a factory under a name, and the server
+// creates the one CACHE_STORE names.
+package cache
+
+import (
+	"bytes"
+	"context"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ErrMiss is returned for keys that hold no value, or only an expired one
+var ErrMiss = errors.New("cache miss")
+
+// Store keeps encoded values under keys like "trending|default|12.9:77.6"
+type Store interface {
+	// Get returns the value stored under key and when it was stored, or ErrMiss
+	Get(ctx context.Context, key string) ([]byte, time.Time, error)
+	// Set stores value under key, replacing any value there, until keep has passed
+	Set(ctx context.Context, key string, value []byte, keep time.Duration) error
+	// DeletePrefix drops every value whose key starts with prefix and returns
+	// how many were dropped
+	DeletePrefix(ctx context.Context, prefix string) (int, error)
+}
+
+// Options configure a store. Stores ignore the options they don't use.
+type Options struct {
+	DB              *gorm.DB      // Database of the database store
+	CleanupInterval time.Duration // How often expired values are removed, default a minute
+}
+
+// Factory creates a store from its options
+type Factory func(options Options) (Store, error)
+
+var (
+	mu        sync.RWMutex
+	factories = map[string]Factory{}
+)
+
+// Register makes a store available under a name, replacing any registered
+// under the same name
+func Register(name string, factory Factory) {
+	mu.Lock()
+	defer mu.Unlock()
+	factories[strings.ToLower(name)] = factory
+}
+
+// New creates the store registered under name
+func New(name string, options Options) (Store, error) {
+	mu.RLock()
+	factory, ok := factories[strings.ToLower(name)]
+	mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown cache store %q, expected one of %s", name, strings.Join(Names(), ", "))
+	}
+	if options.CleanupInterval <= 0 {
+		options.CleanupInterval = time.Minute
+	}
+	return factory(options)
+}
+
+// Names lists the registered stores
+func Names() []string {
+	mu.RLock()
+	defer mu.RUnlock()
+	names := make([]string, 0, len(factories))
+	for name := range factories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// GetValue decodes the value under key into v, returning ErrMiss when there
+// is none or it was stored more than maxAge ago
+func GetValue(ctx context.Context, store Store, key string, maxAge time.Duration, v interface{}) error {
+	data, stored, err := store.Get(ctx, key)
+	if err != nil {
+		return err
+	}
+	if time.Since(stored) > maxAge {
+		return ErrMiss
+	}
+	return gob.NewDecoder(bytes.NewReader(data)).Decode(v)
+}
+
+// SetValue encodes v and stores it under key until keep has passed
+func SetValue(ctx context.Context, store Store, key string, v interface{}, keep time.Duration) error {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(v); err != nil {
+		return err
+	}
+	return store.Set(ctx, key, buf.Bytes(), keep)
+}
@@ -0,0 +1,76 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+	"unicode/utf8"
+
+	"github.com/mahigadamsetty/Inshorts-task/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+func init() {
+	Register("database", newDatabase)
+}
+
+// databaseStore keeps values in the cache_entries table, shared by every
+// replica using the database
+type databaseStore struct {
+	db *gorm.DB
+}
+
+func newDatabase(options Options) (Store, error) {
+	if options.DB == nil {
+		return nil, fmt.Errorf("database cache store requires a database")
+	}
+	s := &databaseStore{db: options.DB}
+	go s.cleanup(options.CleanupInterval)
+	return s, nil
+}
+
+// cleanup periodically removes expired values. Every replica runs it, which
+// costs one indexed delete each.
+func (s *databaseStore) cleanup(interval time.Duration) {
+	for range time.Tick(interval) {
+		if err := s.db.Where("expires_at < ?", time.Now()).Delete(&models.CacheEntry{}).Error; err != nil {
+			log.Printf("Failed to remove expired cache entries: %v", err)
+		}
+	}
+}
+
+func (s *databaseStore) Get(ctx context.Context, key string) ([]byte, time.Time, error) {
+	var entry models.CacheEntry
+	err := s.db.WithContext(ctx).Where("cache_key = ? AND expires_at > ?", key, time.Now()).First(&entry).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, time.Time{}, ErrMiss
+	}
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	return entry.Value, entry.StoredAt, nil
+}
+
+func (s *databaseStore) Set(ctx context.Context, key string, value []byte, keep time.Duration) error {
+	now := time.Now()
+	return s.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "cache_key"}},
+		DoUpdates: clause.AssignmentColumns([]string{"value", "stored_at", "expires_at"}),
+	}).Create(&models.CacheEntry{
+		CacheKey:  key,
+		Value:     value,
+		StoredAt:  now,
+		ExpiresAt: now.Add(keep),
+	}).Error
+}
+
+func (s *databaseStore) DeletePrefix(ctx context.Context, prefix string) (int, error) {
+	// substr rather than LIKE, which ignores case and treats _ and % as wildcards
+	result := s.db.WithContext(ctx).
+		Where("substr(cache_key, 1, ?) = ?", utf8.RuneCountInString(prefix), prefix).
+		Delete(&models.CacheEntry{})
+	return int(result.RowsAffected), result.Error
+}
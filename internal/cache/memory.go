@@ -0,0 +1,75 @@
+package cache
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+)
+
+func init() {
+	Register("memory", newMemory)
+}
+
+type memoryEntry struct {
+	value   []byte
+	stored  time.Time
+	expires time.Time
+}
+
+// memoryStore keeps values in a map of this process
+type memoryStore struct {
+	entries map[string]memoryEntry
+	mu      sync.RWMutex
+}
+
+func newMemory(options Options) (Store, error) {
+	s := &memoryStore{entries: make(map[string]memoryEntry)}
+	go s.cleanup(options.CleanupInterval)
+	return s, nil
+}
+
+// cleanup periodically removes expired values
+func (s *memoryStore) cleanup(interval time.Duration) {
+	for range time.Tick(interval) {
+		s.mu.Lock()
+		now := time.Now()
+		for key, entry := range s.entries {
+			if now.After(entry.expires) {
+				delete(s.entries, key)
+			}
+		}
+		s.mu.Unlock()
+	}
+}
+
+func (s *memoryStore) Get(ctx context.Context, key string) ([]byte, time.Time, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	entry, ok := s.entries[key]
+	if !ok || time.Now().After(entry.expires) {
+		return nil, time.Time{}, ErrMiss
+	}
+	return entry.value, entry.stored, nil
+}
+
+func (s *memoryStore) Set(ctx context.Context, key string, value []byte, keep time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	s.entries[key] = memoryEntry{value: value, stored: now, expires: now.Add(keep)}
+	return nil
+}
+
+func (s *memoryStore) DeletePrefix(ctx context.Context, prefix string) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	dropped := 0
+	for key := range s.entries {
+		if strings.HasPrefix(key, prefix) {
+			delete(s.entries, key)
+			dropped++
+		}
+	}
+	return dropped, nil
+}
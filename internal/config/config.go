@@ -1,20 +1,190 @@
 package config
 
 import (
+	"crypto/ed25519"
+	"encoding/hex"
+	"fmt"
 	"log"
+	"net/http"
+	"net/url"
 	"os"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
 
 type Config struct {
-	DatabaseURL            string
-	OpenAIAPIKey           string
-	LLMModel               string
-	TrendingCacheTTL       int
-	LocationClusterDegrees float64
-	Port                   string
+	DatabaseURL             string
+	OpenAIAPIKey            string
+	OpenAIBaseURL           string
+	OpenAIOrganization      string
+	OpenAIProject           string
+	OpenAIAPIVersion        string
+	LLMProvider             string
+	LLMBaseURL              string
+	LLMAPIKey               string
+	LLMModel                string
+	LLMFallbackModels       []string
+	LLMLatencySLOMs         int
+	LLMMaxConcurrent        int
+	LLMRequestsPerMinute    int
+	LLMQueueTimeout         int
+	CacheStore              string
+	TrendingCacheTTL        int
+	ConversationTTL         int
+	HeatmapCacheTTL         int
+	ViewFlushInterval       int
+	EventFlushInterval      int
+	EventQueueSize          int
+	EventBatchMax           int
+	EventMaxAttempts        int
+	UsageFlushInterval      int
+	QuotaExceededStatus     int
+	ImpressionSampleRate    float64
+	ImpressionFlushInterval int
+	DiscoveryPolicy         string
+	DiscoveryEpsilon        float64
+	DiscoveryPoolSize       int
+	QueryMaxLimit           int
+	QueryMaxCandidates      int
+	RecordSampleRate        float64
+	RecordMaxBytes          int
+	RecordRetentionHours    int
+	LocationClusterDegrees  float64
+	LocationPrecision       int
+	CoarseLocationPrecision int
+	CoordinatePrecision     int
+	OmitCoordinates         bool
+	GeoIPFallback           bool
+	GeoIPFile               string
+	FreshnessWeight         float64
+	FreshnessHalfLifeHours  float64
+	SourceReliabilityBoost  float64
+	TuningFile              string
+	DiversityMaxPerSource   int
+	DiversityMaxPerCategory int
+	StoryClusterInterval    int
+	StorySimilarity         float64
+	TopicClusterInterval    int
+	TopicCount              int
+	RecalibrationSchedule   string
+	PopularityDecaySchedule string
+	PopularityHalfLifeHours float64
+	ScoreHistoryDays        int
+	TextFetchInterval       int
+	TextFetchWorkers        int
+	TextFetchMaxAttempts    int
+	TextRefetchAfterHours   int
+	TextRefetchWindowHours  int
+	ExtractionMinQuality    float64
+	CrawlUserAgent          string
+	CrawlDelayMs            int
+	CrawlMaxConcurrent      int
+	SummarizerWorkers       int
+	InlineSummaries         bool
+	SummaryPrewarm          int
+	ExportDir               string
+	DateFormatsFile         string
+	ImputeLocations         bool
+	ResolveURLRedirects     bool
+	IntegrityCheckOnStartup bool
+	PIIPatterns             string
+	SimulationProfilesFile  string
+	SimulationProfile       string
+	SimulationEnabled       bool
+	TrendingSimulated       bool
+	Environment             string
+	FaultInjection          bool
+	SummarizeMaxArticles    int
+	GeofenceCheckInterval   int
+	SessionStitchInterval   int
+	ClickModelInterval      int
+	LifecycleInterval       int
+	BreakingMinEvents       int
+	ClickModelWindowDays    int
+	GeofenceMaxRadiusKm     float64
+	GeoDistanceMaxArticles  int
+	SpikeWindowSeconds      int
+	SpikeFactor             float64
+	OutboxWorkers           int
+	OutboxMaxAttempts       int
+	OutboxBackoff           int
+	OutboxPollInterval      int
+	OutboxRetentionDays     int
+	WebhookAllowPrivate     bool
+	TelegramBotToken        string
+	TelegramAPIURL          string
+	TelegramTenant          string
+	TelegramRadiusKm        float64
+	TelegramSpikeThreshold  int
+	TenantsFile             string
+	PublishersFile          string
+	IngestionPollInterval   int
+	NewsAPIKey              string
+	NewsAPIBaseURL          string
+	TTSProvider             string
+	TTSModel                string
+	TTSVoice                string
+	AudioDir                string
+	AudioInterval           int
+	AudioBatchSize          int
+	SearchBackend           string
+	SearchURL               string
+	SearchIndex             string
+	SearchUsername          string
+	SearchPassword          string
+	SearchIndexInterval     int
+	SearchIndexBatch        int
+	SearchMaxCandidates     int
+	BlobStore               string
+	BlobBucket              string
+	BlobPrefix              string
+	BlobEndpoint            string
+	BlobRegion              string
+	BlobAccessKeyID         string
+	BlobSecretAccessKey     string
+	BlobPublicURL           string
+	BlobLinkTTL             int
+	TextDir                 string
+	ImageDir                string
+	StoreArticleText        bool
+	StoreArticleImages      bool
+	ShortLinkBaseURL        string
+	SlackSigningSecret      string
+	DiscordPublicKey        string
+	IntegrationsTenant      string
+	IntegrationsBudgetMs    int
+	MCPToolRateLimits       []string
+	SiteURL                 string
+	SiteTenant              string
+	SiteArticlePath         string
+	SitemapPageSize         int
+	SitemapCacheSeconds     int
+	RobotsDisallow          []string
+	RequireAPIKey           bool
+	AdminAPIKey             string
+	RequestTimeout          int
+	LLMRouteTimeout         int
+	AdminRequestTimeout     int
+	SearchBudgetMs          int
+	QueryBudgetMs           int
+	TrendingBudgetMs        int
+	CompactRoutes           []string
+	CompactLimit            int
+	CompactSummaryChars     int
+	Port                    string
+	ShutdownTimeout         int
+}
+
+// defaultLLMModels are the models used when LLM_MODEL is not set, by
+// LLM_PROVIDER
+var defaultLLMModels = map[string]string{
+	"openai":    "gpt-4o-mini",
+	"anthropic": "claude-3-5-haiku-latest",
+	"gemini":    "gemini-1.5-flash",
+	"ollama":    "llama3.1",
 }
 
 func Load() *Config {
@@ -22,15 +192,278 @@ func Load() *Config {
 		log.Println("Error loading .env file, will use environment variables if set")
 	}
 	return &Config{
-		DatabaseURL:            getEnv("DATABASE_URL", "news.db"),
-		OpenAIAPIKey:           getEnv("OPENAI_API_KEY", ""),
-		LLMModel:               getEnv("LLM_MODEL", "gpt-4o-mini"),
-		TrendingCacheTTL:       getEnvAsInt("TRENDING_CACHE_TTL", 300),
-		LocationClusterDegrees: getEnvAsFloat("LOCATION_CLUSTER_DEGREES", 0.5),
-		Port:                   getEnv("PORT", "8080"),
+		DatabaseURL:             getEnv("DATABASE_URL", "news.db"),
+		OpenAIAPIKey:            getEnv("OPENAI_API_KEY", ""),
+		OpenAIBaseURL:           getEnv("OPENAI_BASE_URL", ""),
+		OpenAIOrganization:      getEnv("OPENAI_ORGANIZATION", ""),
+		OpenAIProject:           getEnv("OPENAI_PROJECT", ""),
+		OpenAIAPIVersion:        getEnv("OPENAI_API_VERSION", ""),
+		LLMProvider:             getEnv("LLM_PROVIDER", "openai"),
+		LLMBaseURL:              getEnv("LLM_BASE_URL", getEnv("OPENAI_BASE_URL", "")),
+		LLMAPIKey:               getEnv("LLM_API_KEY", getEnv("OPENAI_API_KEY", "")),
+		LLMModel:                getEnv("LLM_MODEL", defaultLLMModels[strings.ToLower(getEnv("LLM_PROVIDER", "openai"))]),
+		LLMFallbackModels:       getEnvAsList("LLM_FALLBACK_MODELS", nil),
+		LLMLatencySLOMs:         getEnvAsInt("LLM_LATENCY_SLO_MS", 8000),
+		LLMMaxConcurrent:        getEnvAsInt("LLM_MAX_CONCURRENT", 4),
+		LLMRequestsPerMinute:    getEnvAsInt("LLM_REQUESTS_PER_MINUTE", 300),
+		LLMQueueTimeout:         getEnvAsInt("LLM_QUEUE_TIMEOUT", 20),
+		CacheStore:              getEnv("CACHE_STORE", "memory"),
+		TrendingCacheTTL:        getEnvAsInt("TRENDING_CACHE_TTL", 300),
+		ConversationTTL:         getEnvAsInt("CONVERSATION_TTL", 900),
+		HeatmapCacheTTL:         getEnvAsInt("HEATMAP_CACHE_TTL", 60),
+		ViewFlushInterval:       getEnvAsInt("VIEW_FLUSH_INTERVAL", 30),
+		EventFlushInterval:      getEnvAsInt("EVENT_FLUSH_INTERVAL", 5),
+		EventQueueSize:          getEnvAsInt("EVENT_QUEUE_SIZE", 10000),
+		EventBatchMax:           getEnvAsInt("EVENT_BATCH_MAX", 100),
+		EventMaxAttempts:        getEnvAsInt("EVENT_MAX_ATTEMPTS", 5),
+		UsageFlushInterval:      getEnvAsInt("USAGE_FLUSH_INTERVAL", 30),
+		QuotaExceededStatus:     getEnvAsInt("QUOTA_EXCEEDED_STATUS", http.StatusPaymentRequired),
+		ImpressionSampleRate:    getEnvAsFloat("IMPRESSION_SAMPLE_RATE", 0.1),
+		ImpressionFlushInterval: getEnvAsInt("IMPRESSION_FLUSH_INTERVAL", 30),
+		DiscoveryPolicy:         getEnv("DISCOVERY_POLICY", "thompson"),
+		DiscoveryEpsilon:        getEnvAsFloat("DISCOVERY_EPSILON", 0.1),
+		DiscoveryPoolSize:       getEnvAsInt("DISCOVERY_POOL_SIZE", 200),
+		QueryMaxLimit:           getEnvAsInt("QUERY_MAX_LIMIT", 100),
+		QueryMaxCandidates:      getEnvAsInt("QUERY_MAX_CANDIDATES", 5000),
+		RecordSampleRate:        getEnvAsFloat("RECORD_SAMPLE_RATE", 0),
+		RecordMaxBytes:          getEnvAsInt("RECORD_MAX_BYTES", 1<<20),
+		RecordRetentionHours:    getEnvAsInt("RECORD_RETENTION_HOURS", 72),
+		LocationClusterDegrees:  getEnvAsFloat("LOCATION_CLUSTER_DEGREES", 0.5),
+		LocationPrecision:       getEnvAsInt("LOCATION_PRECISION", 3),
+		CoarseLocationPrecision: getEnvAsInt("COARSE_LOCATION_PRECISION", 1),
+		CoordinatePrecision:     getEnvAsInt("RESPONSE_COORDINATE_PRECISION", -1),
+		OmitCoordinates:         getEnvAsBool("OMIT_RESPONSE_COORDINATES", false),
+		GeoIPFallback:           getEnvAsBool("GEOIP_FALLBACK", false),
+		GeoIPFile:               getEnv("GEOIP_FILE", ""),
+		FreshnessWeight:         getEnvAsFloat("FRESHNESS_WEIGHT", 0.3),
+		FreshnessHalfLifeHours:  getEnvAsFloat("FRESHNESS_HALF_LIFE_HOURS", 24),
+		SourceReliabilityBoost:  getEnvAsFloat("SOURCE_RELIABILITY_BOOST", 0.2),
+		TuningFile:              getEnv("TUNING_FILE", ""),
+		DiversityMaxPerSource:   getEnvAsInt("DIVERSITY_MAX_PER_SOURCE", 2),
+		DiversityMaxPerCategory: getEnvAsInt("DIVERSITY_MAX_PER_CATEGORY", 3),
+		StoryClusterInterval:    getEnvAsInt("STORY_CLUSTER_INTERVAL", 1800),
+		StorySimilarity:         getEnvAsFloat("STORY_SIMILARITY", 0.35),
+		TopicClusterInterval:    getEnvAsInt("TOPIC_CLUSTER_INTERVAL", 3600),
+		TopicCount:              getEnvAsInt("TOPIC_COUNT", 20),
+		RecalibrationSchedule:   getEnv("RECALIBRATION_SCHEDULE", "@hourly"),
+		PopularityDecaySchedule: getEnv("POPULARITY_DECAY_SCHEDULE", "0 3 * * *"),
+		PopularityHalfLifeHours: getEnvAsFloat("POPULARITY_HALF_LIFE_HOURS", 72),
+		ScoreHistoryDays:        getEnvAsInt("SCORE_HISTORY_DAYS", 90),
+		TextFetchInterval:       getEnvAsInt("TEXT_FETCH_INTERVAL", 300),
+		TextFetchWorkers:        getEnvAsInt("TEXT_FETCH_WORKERS", 4),
+		TextFetchMaxAttempts:    getEnvAsInt("TEXT_FETCH_MAX_ATTEMPTS", 3),
+		TextRefetchAfterHours:   getEnvAsInt("TEXT_REFETCH_AFTER_HOURS", 6),
+		TextRefetchWindowHours:  getEnvAsInt("TEXT_REFETCH_WINDOW_HOURS", 48),
+		ExtractionMinQuality:    getEnvAsFloat("EXTRACTION_MIN_QUALITY", 0.5),
+		CrawlUserAgent:          getEnv("CRAWL_USER_AGENT", ""),
+		CrawlDelayMs:            getEnvAsInt("CRAWL_DELAY_MS", 1000),
+		CrawlMaxConcurrent:      getEnvAsInt("CRAWL_MAX_CONCURRENT", 2),
+		SummarizerWorkers:       getEnvAsInt("SUMMARIZER_WORKERS", 2),
+		InlineSummaries:         getEnvAsBool("INLINE_SUMMARIES", false),
+		SummaryPrewarm:          getEnvAsInt("SUMMARY_PREWARM", 0),
+		ExportDir:               getEnv("EXPORT_DIR", "exports"),
+		DateFormatsFile:         getEnv("DATE_FORMATS_FILE", ""),
+		ImputeLocations:         getEnvAsBool("IMPUTE_LOCATIONS", false),
+		ResolveURLRedirects:     getEnvAsBool("RESOLVE_URL_REDIRECTS", false),
+		IntegrityCheckOnStartup: getEnvAsBool("INTEGRITY_CHECK_ON_STARTUP", false),
+		PIIPatterns:             getEnv("PII_PATTERNS", ""),
+		SimulationProfilesFile:  getEnv("SIMULATION_PROFILES_FILE", "simulation_profiles.yml"),
+		SimulationProfile:       getEnv("SIMULATION_PROFILE", ""),
+		SimulationEnabled:       getEnvAsBool("SIMULATION_ENABLED", false),
+		TrendingSimulated:       getEnvAsBool("TRENDING_INCLUDE_SIMULATED", getEnvAsBool("SIMULATION_ENABLED", false)),
+		Environment:             getEnv("APP_ENV", "development"),
+		FaultInjection:          getEnvAsBool("FAULT_INJECTION", false),
+		SummarizeMaxArticles:    getEnvAsInt("SUMMARIZE_MAX_ARTICLES", 100),
+		GeofenceCheckInterval:   getEnvAsInt("GEOFENCE_CHECK_INTERVAL", 60),
+		SessionStitchInterval:   getEnvAsInt("SESSION_STITCH_INTERVAL", 300),
+		ClickModelInterval:      getEnvAsInt("CLICK_MODEL_INTERVAL", 3600),
+		LifecycleInterval:       getEnvAsInt("LIFECYCLE_INTERVAL", 300),
+		BreakingMinEvents:       getEnvAsInt("BREAKING_MIN_EVENTS", 10),
+		ClickModelWindowDays:    getEnvAsInt("CLICK_MODEL_WINDOW_DAYS", 14),
+		GeofenceMaxRadiusKm:     getEnvAsFloat("GEOFENCE_MAX_RADIUS_KM", 500),
+		GeoDistanceMaxArticles:  getEnvAsInt("GEO_DISTANCE_MAX_ARTICLES", 200),
+		SpikeWindowSeconds:      getEnvAsInt("SPIKE_WINDOW_SECONDS", 900),
+		SpikeFactor:             getEnvAsFloat("SPIKE_FACTOR", 3),
+		OutboxWorkers:           getEnvAsInt("OUTBOX_WORKERS", getEnvAsInt("ALERT_WORKERS", 2)),
+		OutboxMaxAttempts:       getEnvAsInt("OUTBOX_MAX_ATTEMPTS", getEnvAsInt("ALERT_MAX_ATTEMPTS", 5)),
+		OutboxBackoff:           getEnvAsInt("OUTBOX_BACKOFF", 10),
+		OutboxPollInterval:      getEnvAsInt("OUTBOX_POLL_INTERVAL", 5),
+		OutboxRetentionDays:     getEnvAsInt("OUTBOX_RETENTION_DAYS", 7),
+		WebhookAllowPrivate:     getEnvAsBool("WEBHOOK_ALLOW_PRIVATE", false),
+		TelegramBotToken:        getEnv("TELEGRAM_BOT_TOKEN", ""),
+		TelegramAPIURL:          getEnv("TELEGRAM_API_URL", "https://api.telegram.org"),
+		TelegramTenant:          getEnv("TELEGRAM_TENANT", "default"),
+		TelegramRadiusKm:        getEnvAsFloat("TELEGRAM_RADIUS_KM", 25),
+		TelegramSpikeThreshold:  getEnvAsInt("TELEGRAM_SPIKE_THRESHOLD", 20),
+		TenantsFile:             getEnv("TENANTS_FILE", ""),
+		PublishersFile:          getEnv("PUBLISHERS_FILE", ""),
+		IngestionPollInterval:   getEnvAsInt("INGESTION_POLL_INTERVAL", 60),
+		NewsAPIKey:              getEnv("NEWSAPI_KEY", ""),
+		NewsAPIBaseURL:          getEnv("NEWSAPI_BASE_URL", ""),
+		TTSProvider:             getEnv("TTS_PROVIDER", ""),
+		TTSModel:                getEnv("TTS_MODEL", ""),
+		TTSVoice:                getEnv("TTS_VOICE", ""),
+		AudioDir:                getEnv("AUDIO_DIR", "audio"),
+		AudioInterval:           getEnvAsInt("AUDIO_INTERVAL", 600),
+		AudioBatchSize:          getEnvAsInt("AUDIO_BATCH_SIZE", 20),
+		SearchBackend:           getEnv("SEARCH_BACKEND", ""),
+		SearchURL:               getEnv("SEARCH_URL", ""),
+		SearchIndex:             getEnv("SEARCH_INDEX", ""),
+		SearchUsername:          getEnv("SEARCH_USERNAME", ""),
+		SearchPassword:          getEnv("SEARCH_PASSWORD", ""),
+		SearchIndexInterval:     getEnvAsInt("SEARCH_INDEX_INTERVAL", 30),
+		SearchIndexBatch:        getEnvAsInt("SEARCH_INDEX_BATCH", 500),
+		SearchMaxCandidates:     getEnvAsInt("SEARCH_MAX_CANDIDATES", 1000),
+		BlobStore:               getEnv("BLOB_STORE", "local"),
+		BlobBucket:              getEnv("BLOB_BUCKET", ""),
+		BlobPrefix:              getEnv("BLOB_PREFIX", ""),
+		BlobEndpoint:            getEnv("BLOB_ENDPOINT", ""),
+		BlobRegion:              getEnv("BLOB_REGION", ""),
+		BlobAccessKeyID:         getEnv("BLOB_ACCESS_KEY_ID", ""),
+		BlobSecretAccessKey:     getEnv("BLOB_SECRET_ACCESS_KEY", ""),
+		BlobPublicURL:           getEnv("BLOB_PUBLIC_URL", ""),
+		BlobLinkTTL:             getEnvAsInt("BLOB_LINK_TTL", 3600),
+		TextDir:                 getEnv("TEXT_DIR", "texts"),
+		ImageDir:                getEnv("IMAGE_DIR", "images"),
+		StoreArticleText:        getEnvAsBool("STORE_ARTICLE_TEXT", false),
+		StoreArticleImages:      getEnvAsBool("STORE_ARTICLE_IMAGES", false),
+		ShortLinkBaseURL:        getEnv("SHORTLINK_BASE_URL", ""),
+		SlackSigningSecret:      getEnv("SLACK_SIGNING_SECRET", ""),
+		DiscordPublicKey:        getEnv("DISCORD_PUBLIC_KEY", ""),
+		IntegrationsTenant:      getEnv("INTEGRATIONS_TENANT", "default"),
+		IntegrationsBudgetMs:    getEnvAsInt("INTEGRATIONS_BUDGET_MS", 2500),
+		MCPToolRateLimits:       getEnvAsList("MCP_TOOL_RATE_LIMITS", []string{"search_news:60", "nearby_news:60", "trending_news:60"}),
+		SiteURL:                 getEnv("SITE_URL", ""),
+		SiteTenant:              getEnv("SITE_TENANT", "default"),
+		SiteArticlePath:         getEnv("SITE_ARTICLE_PATH", "/news/{id}"),
+		SitemapPageSize:         getEnvAsInt("SITEMAP_PAGE_SIZE", 10000),
+		SitemapCacheSeconds:     getEnvAsInt("SITEMAP_CACHE_SECONDS", 3600),
+		RobotsDisallow:          getEnvAsList("ROBOTS_DISALLOW", []string{"/api/"}),
+		RequireAPIKey:           getEnvAsBool("REQUIRE_API_KEY", false),
+		AdminAPIKey:             getEnv("ADMIN_API_KEY", ""),
+		RequestTimeout:          getEnvAsInt("REQUEST_TIMEOUT", 15),
+		LLMRouteTimeout:         getEnvAsInt("LLM_ROUTE_TIMEOUT", 30),
+		AdminRequestTimeout:     getEnvAsInt("ADMIN_REQUEST_TIMEOUT", 300),
+		SearchBudgetMs:          getEnvAsInt("SEARCH_BUDGET_MS", 3000),
+		QueryBudgetMs:           getEnvAsInt("QUERY_BUDGET_MS", 10000),
+		TrendingBudgetMs:        getEnvAsInt("TRENDING_BUDGET_MS", 3000),
+		CompactRoutes:           getEnvAsList("COMPACT_ROUTES", []string{"category", "source", "score", "search", "nearby", "trending", "query"}),
+		CompactLimit:            getEnvAsInt("COMPACT_LIMIT", 3),
+		CompactSummaryChars:     getEnvAsInt("COMPACT_SUMMARY_CHARS", 120),
+		Port:                    getEnv("PORT", "8080"),
+		ShutdownTimeout:         getEnvAsInt("SHUTDOWN_TIMEOUT", 30),
+	}
+}
+
+// ModelChain returns the primary model followed by its fallbacks
+func (c *Config) ModelChain() []string {
+	return append([]string{c.LLMModel}, c.LLMFallbackModels...)
+}
+
+// ArticlePageURL returns the URL of an article's page on the public site, or
+// "" when SITE_URL is not set
+func (c *Config) ArticlePageURL(id string) string {
+	if c.SiteURL == "" {
+		return ""
+	}
+	return strings.TrimRight(c.SiteURL, "/") + strings.ReplaceAll(c.SiteArticlePath, "{id}", url.PathEscape(id))
+}
+
+// DiscordKey decodes DISCORD_PUBLIC_KEY, returning nil when it is not set
+func (c *Config) DiscordKey() (ed25519.PublicKey, error) {
+	if c.DiscordPublicKey == "" {
+		return nil, nil
+	}
+	key, err := hex.DecodeString(c.DiscordPublicKey)
+	if err != nil || len(key) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("DISCORD_PUBLIC_KEY must be a hex-encoded Ed25519 public key")
+	}
+	return ed25519.PublicKey(key), nil
+}
+
+// FaultInjectionEnabled reports whether requests may inject faults, which is
+// never the case in production
+func (c *Config) FaultInjectionEnabled() bool {
+	return c.FaultInjection && c.Environment != "production"
+}
+
+// QuotaStatus returns the status of requests refused for a spent monthly
+// quota: 402 Payment Required, or 429 Too Many Requests when
+// QUOTA_EXCEEDED_STATUS asks for it
+func (c *Config) QuotaStatus() int {
+	if c.QuotaExceededStatus == http.StatusTooManyRequests {
+		return http.StatusTooManyRequests
+	}
+	return http.StatusPaymentRequired
+}
+
+// LatencySLO returns the per-attempt LLM latency limit
+func (c *Config) LatencySLO() time.Duration {
+	return time.Duration(c.LLMLatencySLOMs) * time.Millisecond
+}
+
+// RouteTimeouts returns the deadline of API requests, with overrides for the
+// news routes, which wait on the LLM for summaries, the MCP server, whose
+// tool calls run them, and the admin routes
+func (c *Config) RouteTimeouts() (time.Duration, map[string]time.Duration) {
+	return time.Duration(c.RequestTimeout) * time.Second, map[string]time.Duration{
+		"/api/v1/news":  time.Duration(c.LLMRouteTimeout) * time.Second,
+		"/api/v1/admin": time.Duration(c.AdminRequestTimeout) * time.Second,
+		"/mcp":          time.Duration(c.LLMRouteTimeout) * time.Second,
 	}
 }
 
+// ResponseBudgets returns the response-time budgets of the routes that return
+// partial results rather than running past them
+func (c *Config) ResponseBudgets() map[string]time.Duration {
+	return map[string]time.Duration{
+		"/api/v1/news/search":   time.Duration(c.SearchBudgetMs) * time.Millisecond,
+		"/api/v1/news/query":    time.Duration(c.QueryBudgetMs) * time.Millisecond,
+		"/api/v1/news/trending": time.Duration(c.TrendingBudgetMs) * time.Millisecond,
+		// Chat apps give up on commands not answered within 3 seconds
+		"/integrations/slack/command":        time.Duration(c.IntegrationsBudgetMs) * time.Millisecond,
+		"/integrations/discord/interactions": time.Duration(c.IntegrationsBudgetMs) * time.Millisecond,
+	}
+}
+
+// CompactLimits returns the default limits of compact responses by route
+// pattern, for the news routes in CompactRoutes. A route is listed by name,
+// like search, or with its own limit, like search:5.
+func (c *Config) CompactLimits() map[string]int {
+	limits := make(map[string]int, len(c.CompactRoutes))
+	for _, route := range c.CompactRoutes {
+		name, limitStr, found := strings.Cut(route, ":")
+		limit := c.CompactLimit
+		if found {
+			parsed, err := strconv.Atoi(strings.TrimSpace(limitStr))
+			if err != nil {
+				log.Printf("Ignoring invalid compact limit %q for route %s", limitStr, name)
+				continue
+			}
+			limit = parsed
+		}
+		limits["/api/v1/news/"+strings.Trim(strings.TrimSpace(name), "/")] = limit
+	}
+	return limits
+}
+
+// MCPToolLimits returns the per-minute call limit of each MCP tool for one
+// tenant, from entries like search_news:60. Tools without one are limited by
+// the tenant's rate limit only.
+func (c *Config) MCPToolLimits() map[string]int {
+	limits := make(map[string]int, len(c.MCPToolRateLimits))
+	for _, entry := range c.MCPToolRateLimits {
+		name, limitStr, _ := strings.Cut(entry, ":")
+		limit, err := strconv.Atoi(strings.TrimSpace(limitStr))
+		if err != nil || limit < 0 {
+			log.Printf("Ignoring invalid MCP tool rate limit %q", entry)
+			continue
+		}
+		limits[strings.TrimSpace(name)] = limit
+	}
+	return limits
+}
+
 func getEnv(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
 		return value
@@ -53,3 +486,25 @@ func getEnvAsFloat(key string, defaultValue float64) float64 {
 	}
 	return defaultValue
 }
+
+func getEnvAsList(key string, defaultValue []string) []string {
+	valueStr := getEnv(key, "")
+	if valueStr == "" {
+		return defaultValue
+	}
+	var values []string
+	for _, value := range strings.Split(valueStr, ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+func getEnvAsBool(key string, defaultValue bool) bool {
+	valueStr := getEnv(key, "")
+	if value, err := strconv.ParseBool(valueStr); err == nil {
+		return value
+	}
+	return defaultValue
+}
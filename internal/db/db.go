@@ -10,30 +10,46 @@ import (
 	"gorm.io/gorm/logger"
 )
 
-var DB *gorm.DB
-
-// Init initializes the database connection and runs migrations
-func Init(databaseURL string) error {
-	var err error
-	
+// Open connects to a database, registers the tenant scope and fault injection
+// callbacks and runs migrations
+func Open(databaseURL string) (*gorm.DB, error) {
 	// Open database connection
-	DB, err = gorm.Open(sqlite.Open(databaseURL), &gorm.Config{
+	database, err := gorm.Open(sqlite.Open(databaseURL), &gorm.Config{
 		Logger: logger.Default.LogMode(logger.Silent),
 	})
 	if err != nil {
-		return fmt.Errorf("failed to connect to database: %w", err)
+		return nil, fmt.Errorf("failed to connect to database: %w", err)
+	}
+
+	// Scope tenant-aware models to the tenant carried in the statement context
+	if err := registerTenantScope(database); err != nil {
+		return nil, fmt.Errorf("failed to register tenant scope: %w", err)
+	}
+
+	// Hide embargoed and expired articles from client requests
+	if err := registerVisibilityScope(database); err != nil {
+		return nil, fmt.Errorf("failed to register visibility scope: %w", err)
+	}
+
+	// Delay statements of requests that inject database latency
+	if err := registerFaultInjection(database); err != nil {
+		return nil, fmt.Errorf("failed to register fault injection: %w", err)
 	}
 
 	// Run migrations
-	if err := DB.AutoMigrate(&models.Article{}, &models.Event{}); err != nil {
-		return fmt.Errorf("failed to migrate database: %w", err)
+	if err := database.AutoMigrate(&models.Article{}, &models.Event{}, &models.Story{}, &models.JobLock{}, &models.JobRun{}, &models.Source{}, &models.SummaryJob{}, &models.Topic{}, &models.ArticleEmbedding{}, &models.Geofence{}, &models.GeofenceAlert{}, &models.ArticleViews{}, &models.SearchLog{}, &models.CrawlPolicy{}, &models.ReindexJob{}, &models.DataRequest{}, &models.ArticlePopularity{}, &models.ScoreHistory{}, &models.ShadowComparison{}, &models.ShadowClick{}, &models.Read{}, &models.Session{}, &models.Impression{}, &models.PositionBias{}, &models.ArticleAttractiveness{}, &models.IngestionSource{}, &models.ArticleAudio{}, &models.ShortLink{}, &models.ShortLinkClicks{}, &models.OutboxMessage{}, &models.CacheEntry{}, &models.RecordedRequest{}, &models.Category{}, &models.APIUsage{}, &models.DiscoveryArm{}, &models.DiscoveryPull{}); err != nil {
+		return nil, fmt.Errorf("failed to migrate database: %w", err)
 	}
 
-	log.Println("Database initialized successfully")
-	return nil
-}
+	// Index article titles and descriptions for keyword search
+	fullText, err = ensureFullText(database)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create full-text index: %w", err)
+	}
+	if !fullText {
+		log.Println("SQLite was built without FTS5, keyword search matches with LIKE")
+	}
 
-// GetDB returns the database instance
-func GetDB() *gorm.DB {
-	return DB
+	log.Println("Database initialized successfully")
+	return database, nil
 }
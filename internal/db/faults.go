@@ -0,0 +1,31 @@
+package db
+
+import (
+	"github.com/mahigadamsetty/Inshorts-task/internal/faults"
+	"gorm.io/gorm"
+)
+
+// registerFaultInjection installs callbacks that delay every statement by the
+// database latency injected into its context, if any
+func registerFaultInjection(database *gorm.DB) error {
+	delay := func(tx *gorm.DB) {
+		faults.DelayDB(tx.Statement.Context)
+	}
+	callbacks := database.Callback()
+	if err := callbacks.Query().Before("gorm:query").Register("faults:delay_query", delay); err != nil {
+		return err
+	}
+	if err := callbacks.Row().Before("gorm:row").Register("faults:delay_row", delay); err != nil {
+		return err
+	}
+	if err := callbacks.Raw().Before("gorm:raw").Register("faults:delay_raw", delay); err != nil {
+		return err
+	}
+	if err := callbacks.Update().Before("gorm:update").Register("faults:delay_update", delay); err != nil {
+		return err
+	}
+	if err := callbacks.Delete().Before("gorm:delete").Register("faults:delay_delete", delay); err != nil {
+		return err
+	}
+	return callbacks.Create().Before("gorm:create").Register("faults:delay_create", delay)
+}
@@ -0,0 +1,227 @@
+package db
+
+import (
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// FullTextTable is the FTS5 index of article titles and descriptions.
+// Triggers on the articles table keep it in step with every write, whichever
+// code path makes it.
+const FullTextTable = "articles_fts"
+
+// FullTextKeys gives every article the integer key of its entry in the
+// full-text index. Articles have a text primary key, and the implicit rowid
+// SQLite gives them can change when the database is vacuumed, so the index
+// can't be keyed on it.
+const FullTextKeys = "article_search_keys"
+
+// Shadow tables the full-text index is rebuilt in
+const (
+	fullTextShadowTable = "articles_fts_rebuild"
+	fullTextShadowKeys  = "article_search_keys_rebuild"
+)
+
+// fullTextTriggers key new articles, then add, replace or remove their entry
+// in the index
+var fullTextTriggers = map[string]string{
+	"articles_fts_insert": `CREATE TRIGGER articles_fts_insert AFTER INSERT ON articles BEGIN
+  INSERT OR IGNORE INTO article_search_keys(article_id) VALUES (new.id);
+  INSERT OR REPLACE INTO articles_fts(rowid, title, description)
+    SELECT key, new.title, new.description FROM article_search_keys WHERE article_id = new.id;
+END`,
+	"articles_fts_delete": `CREATE TRIGGER articles_fts_delete AFTER DELETE ON articles BEGIN
+  DELETE FROM articles_fts WHERE rowid = (SELECT key FROM article_search_keys WHERE article_id = old.id);
+  DELETE FROM article_search_keys WHERE article_id = old.id;
+END`,
+	"articles_fts_update": `CREATE TRIGGER articles_fts_update AFTER UPDATE OF title, description ON articles BEGIN
+  UPDATE articles_fts SET title = new.title, description = new.description
+    WHERE rowid = (SELECT key FROM article_search_keys WHERE article_id = new.id);
+END`,
+}
+
+// fullText is set once the database has the full-text index
+var fullText bool
+
+// FullText reports whether the database has the full-text index. It doesn't
+// when SQLite was built without FTS5, and keyword searches match with LIKE.
+func FullText() bool {
+	return fullText
+}
+
+// ensureFullText creates the full-text index, its keys and triggers unless
+// they exist, filled from the articles, and drops the shadow tables of an
+// interrupted rebuild. An index left by earlier versions, keyed on the
+// articles' rowid, is replaced. It reports false when SQLite was built
+// without FTS5.
+func ensureFullText(database *gorm.DB) (bool, error) {
+	var enabled int
+	if err := database.Raw("SELECT sqlite_compileoption_used('ENABLE_FTS5')").Scan(&enabled).Error; err != nil {
+		return false, err
+	}
+	if enabled == 0 {
+		// Triggers left by a build with FTS5 would fail every article write
+		return false, dropFullTextTriggers(database)
+	}
+
+	if err := dropFullTextTables(database, fullTextShadowTable, fullTextShadowKeys); err != nil {
+		return false, err
+	}
+
+	var existing []struct {
+		Name string
+		SQL  string
+	}
+	err := database.Raw("SELECT name, sql FROM sqlite_master WHERE (type = 'table' AND name IN ?) OR (type = 'trigger' AND tbl_name = 'articles')",
+		[]string{FullTextTable, FullTextKeys}).Scan(&existing).Error
+	if err != nil {
+		return false, err
+	}
+	found := make(map[string]string, len(existing))
+	for _, object := range existing {
+		found[object.Name] = object.SQL
+	}
+	_, complete := found[FullTextTable]
+	for _, name := range []string{FullTextKeys, "articles_fts_insert", "articles_fts_delete", "articles_fts_update"} {
+		_, ok := found[name]
+		complete = complete && ok
+	}
+	// The index of earlier versions read its content from the articles table
+	if complete && !strings.Contains(found[FullTextTable], "content=") {
+		return true, nil
+	}
+
+	err = database.Transaction(func(tx *gorm.DB) error {
+		if err := dropFullTextTriggers(tx); err != nil {
+			return err
+		}
+		if err := dropFullTextTables(tx, FullTextTable, FullTextKeys); err != nil {
+			return err
+		}
+		if err := createFullTextTables(tx, FullTextTable, FullTextKeys); err != nil {
+			return err
+		}
+		if err := fillFullText(tx, FullTextTable, FullTextKeys); err != nil {
+			return err
+		}
+		return createFullTextTriggers(tx)
+	})
+	return err == nil, err
+}
+
+// RebuildFullText rebuilds the full-text index from the articles, compacting
+// it and dropping entries left behind by writes that bypassed the triggers.
+// The new index is built in shadow tables while searches keep using the live
+// one. It is then caught up with the articles written meanwhile and swapped
+// in, in a single transaction, so searches see either the old index or the
+// new one. Without the index it does nothing.
+func RebuildFullText(database *gorm.DB) error {
+	if !fullText {
+		return nil
+	}
+	if err := dropFullTextTables(database, fullTextShadowTable, fullTextShadowKeys); err != nil {
+		return err
+	}
+	if err := createFullTextTables(database, fullTextShadowTable, fullTextShadowKeys); err != nil {
+		return err
+	}
+	swapped := false
+	defer func() {
+		if !swapped {
+			dropFullTextTables(database, fullTextShadowTable, fullTextShadowKeys)
+		}
+	}()
+	if err := fillFullText(database, fullTextShadowTable, fullTextShadowKeys); err != nil {
+		return err
+	}
+
+	err := database.Transaction(func(tx *gorm.DB) error {
+		// Drop the entries of articles deleted or changed since they were
+		// indexed, then index those missing
+		err := tx.Exec("DELETE FROM " + fullTextShadowTable + " WHERE rowid IN (" +
+			"SELECT keys.key FROM " + fullTextShadowKeys + " keys " +
+			"JOIN " + fullTextShadowTable + " entries ON entries.rowid = keys.key " +
+			"LEFT JOIN articles ON articles.id = keys.article_id " +
+			"WHERE articles.id IS NULL OR entries.title IS NOT articles.title OR entries.description IS NOT articles.description)").Error
+		if err != nil {
+			return err
+		}
+		err = tx.Exec("DELETE FROM " + fullTextShadowKeys + " WHERE key NOT IN (SELECT rowid FROM " + fullTextShadowTable + ")").Error
+		if err != nil {
+			return err
+		}
+		if err := fillFullText(tx, fullTextShadowTable, fullTextShadowKeys); err != nil {
+			return err
+		}
+
+		if err := dropFullTextTriggers(tx); err != nil {
+			return err
+		}
+		if err := dropFullTextTables(tx, FullTextTable, FullTextKeys); err != nil {
+			return err
+		}
+		if err := tx.Exec("ALTER TABLE " + fullTextShadowKeys + " RENAME TO " + FullTextKeys).Error; err != nil {
+			return err
+		}
+		if err := tx.Exec("ALTER TABLE " + fullTextShadowTable + " RENAME TO " + FullTextTable).Error; err != nil {
+			return err
+		}
+		return createFullTextTriggers(tx)
+	})
+	if err != nil {
+		return err
+	}
+	swapped = true
+	return nil
+}
+
+// createFullTextTables creates a full-text index and the table keying its
+// entries
+func createFullTextTables(database *gorm.DB, index, keys string) error {
+	err := database.Exec("CREATE TABLE " + keys + " (key INTEGER PRIMARY KEY, article_id TEXT NOT NULL UNIQUE)").Error
+	if err != nil {
+		return err
+	}
+	return database.Exec("CREATE VIRTUAL TABLE " + index + " USING fts5(title, description, " +
+		"tokenize='porter unicode61 remove_diacritics 2')").Error
+}
+
+// fillFullText keys the articles missing from a full-text index and indexes
+// them
+func fillFullText(database *gorm.DB, index, keys string) error {
+	err := database.Exec("INSERT INTO " + keys + "(article_id) SELECT id FROM articles WHERE id NOT IN (SELECT article_id FROM " + keys + ")").Error
+	if err != nil {
+		return err
+	}
+	return database.Exec("INSERT INTO " + index + "(rowid, title, description) " +
+		"SELECT keys.key, articles.title, articles.description FROM " + keys + " keys " +
+		"JOIN articles ON articles.id = keys.article_id " +
+		"WHERE keys.key NOT IN (SELECT rowid FROM " + index + ")").Error
+}
+
+// dropFullTextTables drops a full-text index and its keys if they exist
+func dropFullTextTables(database *gorm.DB, index, keys string) error {
+	if err := database.Exec("DROP TABLE IF EXISTS " + index).Error; err != nil {
+		return err
+	}
+	return database.Exec("DROP TABLE IF EXISTS " + keys).Error
+}
+
+func createFullTextTriggers(database *gorm.DB) error {
+	for _, statement := range fullTextTriggers {
+		if err := database.Exec(statement).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func dropFullTextTriggers(database *gorm.DB) error {
+	for name := range fullTextTriggers {
+		if err := database.Exec("DROP TRIGGER IF EXISTS " + name).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+package db
+
+import (
+	"reflect"
+
+	"github.com/mahigadamsetty/Inshorts-task/internal/tenant"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const tenantField = "TenantID"
+
+// registerTenantScope installs callbacks that scope every query, update and delete
+// on tenant-aware models to the tenant in the statement context, and stamp the
+// tenant on created records. Statements without a tenant in context are unscoped.
+func registerTenantScope(database *gorm.DB) error {
+	callbacks := database.Callback()
+	if err := callbacks.Query().Before("gorm:query").Register("tenant:scope_query", scopeToTenant); err != nil {
+		return err
+	}
+	if err := callbacks.Row().Before("gorm:row").Register("tenant:scope_row", scopeToTenant); err != nil {
+		return err
+	}
+	if err := callbacks.Update().Before("gorm:update").Register("tenant:scope_update", scopeToTenant); err != nil {
+		return err
+	}
+	if err := callbacks.Delete().Before("gorm:delete").Register("tenant:scope_delete", scopeToTenant); err != nil {
+		return err
+	}
+	return callbacks.Create().Before("gorm:create").Register("tenant:stamp_create", stampTenant)
+}
+
+// scopeToTenant adds a tenant_id condition to the statement
+func scopeToTenant(tx *gorm.DB) {
+	tenantID := tenant.IDFromContext(tx.Statement.Context)
+	if tenantID == "" || tx.Statement.Schema == nil {
+		return
+	}
+	field := tx.Statement.Schema.LookUpField(tenantField)
+	if field == nil {
+		return
+	}
+
+	tx.Statement.AddClause(clause.Where{Exprs: []clause.Expression{
+		clause.Eq{Column: clause.Column{Table: clause.CurrentTable, Name: field.DBName}, Value: tenantID},
+	}})
+}
+
+// stampTenant sets the tenant on new records that don't have one yet
+func stampTenant(tx *gorm.DB) {
+	tenantID := tenant.IDFromContext(tx.Statement.Context)
+	if tenantID == "" || tx.Statement.Schema == nil {
+		return
+	}
+	field := tx.Statement.Schema.LookUpField(tenantField)
+	if field == nil {
+		return
+	}
+
+	ctx := tx.Statement.Context
+	rv := tx.Statement.ReflectValue
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < rv.Len(); i++ {
+			elem := reflect.Indirect(rv.Index(i))
+			if _, isZero := field.ValueOf(ctx, elem); isZero {
+				field.Set(ctx, elem, tenantID)
+			}
+		}
+	case reflect.Struct:
+		if _, isZero := field.ValueOf(ctx, rv); isZero {
+			field.Set(ctx, rv, tenantID)
+		}
+	}
+}
@@ -0,0 +1,194 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mahigadamsetty/Inshorts-task/internal/models"
+	"github.com/mahigadamsetty/Inshorts-task/internal/tenant"
+	"gorm.io/gorm"
+)
+
+// openTenantTestDB opens an in-memory database with the tenant and visibility
+// scopes and seeds two articles per tenant, one of them embargoed
+func openTenantTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	database, err := Open("file:" + t.Name() + "?mode=memory&cache=shared")
+	if err != nil {
+		t.Fatalf("Open() returned error: %v", err)
+	}
+	sqlDB, err := database.DB()
+	if err != nil {
+		t.Fatalf("DB() returned error: %v", err)
+	}
+	t.Cleanup(func() { sqlDB.Close() })
+
+	embargo := time.Now().Add(time.Hour)
+	for _, tenantID := range []string{"a", "b"} {
+		ctx := tenant.NewContext(context.Background(), &tenant.Tenant{ID: tenantID})
+		articles := []models.Article{
+			{ID: tenantID + "1", Title: "Visible", TitleKey: tenantID + "1"},
+			{ID: tenantID + "2", Title: "Embargoed", TitleKey: tenantID + "2", VisibleFrom: &embargo},
+		}
+		if err := database.WithContext(ctx).Create(&articles).Error; err != nil {
+			t.Fatalf("Create() for tenant %s returned error: %v", tenantID, err)
+		}
+	}
+	return database
+}
+
+func tenantContext(id string) context.Context {
+	return tenant.NewContext(context.Background(), &tenant.Tenant{ID: id})
+}
+
+func articleIDs(articles []models.Article) string {
+	ids := make([]string, len(articles))
+	for i, article := range articles {
+		ids[i] = article.ID
+	}
+	sort.Strings(ids)
+	return strings.Join(ids, " ")
+}
+
+func TestTenantScopeStampsCreates(t *testing.T) {
+	database := openTenantTestDB(t)
+
+	var articles []models.Article
+	if err := database.Order("id").Find(&articles).Error; err != nil {
+		t.Fatalf("Find() returned error: %v", err)
+	}
+	for _, article := range articles {
+		if want := article.ID[:1]; article.TenantID != want {
+			t.Errorf("article %s has tenant %q, want %q", article.ID, article.TenantID, want)
+		}
+	}
+
+	// A tenant set explicitly is kept, so jobs can write on a tenant's behalf
+	article := models.Article{ID: "b3", Title: "Explicit", TitleKey: "b3", TenantID: "b"}
+	if err := database.WithContext(tenantContext("a")).Create(&article).Error; err != nil {
+		t.Fatalf("Create() returned error: %v", err)
+	}
+	if article.TenantID != "b" {
+		t.Errorf("explicit tenant overwritten with %q", article.TenantID)
+	}
+}
+
+func TestTenantScopeReads(t *testing.T) {
+	database := openTenantTestDB(t)
+
+	tests := []struct {
+		name string
+		ctx  context.Context
+		want string
+	}{
+		{"tenant a", tenantContext("a"), "a1"},
+		{"tenant b", tenantContext("b"), "b1"},
+		{"tenant a including hidden", IncludeHidden(tenantContext("a")), "a1 a2"},
+		{"tenant a visible only", VisibleOnly(tenantContext("a")), "a1"},
+		{"admin", context.Background(), "a1 a2 b1 b2"},
+		{"admin including hidden", IncludeHidden(context.Background()), "a1 a2 b1 b2"},
+		{"admin visible only", VisibleOnly(context.Background()), "a1 b1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var articles []models.Article
+			if err := database.WithContext(tt.ctx).Find(&articles).Error; err != nil {
+				t.Fatalf("Find() returned error: %v", err)
+			}
+			if got := articleIDs(articles); got != tt.want {
+				t.Errorf("Find() = %q, want %q", got, tt.want)
+			}
+
+			// Row queries are scoped like Find
+			var count int64
+			row := database.WithContext(tt.ctx).Model(&models.Article{}).Select("COUNT(*)").Row()
+			if err := row.Scan(&count); err != nil {
+				t.Fatalf("Row() returned error: %v", err)
+			}
+			if want := int64(len(strings.Fields(tt.want))); count != want {
+				t.Errorf("Row() count = %d, want %d", count, want)
+			}
+		})
+	}
+
+	// Looking up another tenant's record by ID finds nothing, even with hidden records included
+	for _, ctx := range []context.Context{tenantContext("a"), IncludeHidden(tenantContext("a"))} {
+		var article models.Article
+		if err := database.WithContext(ctx).First(&article, "id = ?", "b1").Error; !errors.Is(err, gorm.ErrRecordNotFound) {
+			t.Errorf("First(b1) as tenant a returned %v, want ErrRecordNotFound", err)
+		}
+	}
+}
+
+func TestTenantScopeUpdates(t *testing.T) {
+	tests := []struct {
+		name    string
+		ctx     context.Context
+		updated string
+	}{
+		{"tenant a", tenantContext("a"), "a1 a2"},
+		{"tenant a including hidden", IncludeHidden(tenantContext("a")), "a1 a2"},
+		{"admin", context.Background(), "a1 a2 b1 b2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			database := openTenantTestDB(t)
+
+			result := database.WithContext(tt.ctx).Model(&models.Article{}).
+				Where("id IN ?", []string{"a1", "a2", "b1", "b2"}).Update("title", "Edited")
+			if result.Error != nil {
+				t.Fatalf("Update() returned error: %v", result.Error)
+			}
+			if want := int64(len(strings.Fields(tt.updated))); result.RowsAffected != want {
+				t.Errorf("Update() affected %d rows, want %d", result.RowsAffected, want)
+			}
+
+			var edited []models.Article
+			if err := database.Where("title = ?", "Edited").Find(&edited).Error; err != nil {
+				t.Fatalf("Find() returned error: %v", err)
+			}
+			if got := articleIDs(edited); got != tt.updated {
+				t.Errorf("updated %q, want %q", got, tt.updated)
+			}
+		})
+	}
+}
+
+func TestTenantScopeDeletes(t *testing.T) {
+	tests := []struct {
+		name string
+		ctx  context.Context
+		kept string
+	}{
+		{"tenant a", tenantContext("a"), "b1 b2"},
+		{"tenant b", tenantContext("b"), "a1 a2"},
+		{"tenant a including hidden", IncludeHidden(tenantContext("a")), "b1 b2"},
+		{"admin", context.Background(), ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			database := openTenantTestDB(t)
+
+			err := database.WithContext(tt.ctx).
+				Where("id IN ?", []string{"a1", "a2", "b1", "b2"}).Delete(&models.Article{}).Error
+			if err != nil {
+				t.Fatalf("Delete() returned error: %v", err)
+			}
+
+			var kept []models.Article
+			if err := database.Find(&kept).Error; err != nil {
+				t.Fatalf("Find() returned error: %v", err)
+			}
+			if got := articleIDs(kept); got != tt.kept {
+				t.Errorf("kept %q, want %q", got, tt.kept)
+			}
+		})
+	}
+}
@@ -0,0 +1,73 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/mahigadamsetty/Inshorts-task/internal/tenant"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	visibleFromField = "VisibleFrom"
+	expiresAtField   = "ExpiresAt"
+)
+
+type visibleOnlyKey struct{}
+
+type includeHiddenKey struct{}
+
+// VisibleOnly marks a context so its queries only see visible records, like
+// those of a client request, for background work that publishes what it reads
+func VisibleOnly(ctx context.Context) context.Context {
+	return context.WithValue(ctx, visibleOnlyKey{}, true)
+}
+
+// IncludeHidden marks a context so its queries also see embargoed and expired
+// records, even when it carries a tenant, for partners managing their own
+// articles
+func IncludeHidden(ctx context.Context) context.Context {
+	return context.WithValue(ctx, includeHiddenKey{}, true)
+}
+
+// registerVisibilityScope installs callbacks that hide records before their
+// visible_from time and from their expires_at time on models that have them.
+// Client requests, which carry a tenant, and contexts marked VisibleOnly are
+// scoped; jobs and admin requests see everything so embargoed content can be
+// prepared ahead of time.
+func registerVisibilityScope(database *gorm.DB) error {
+	callbacks := database.Callback()
+	if err := callbacks.Query().Before("gorm:query").Register("visibility:scope_query", scopeToVisible); err != nil {
+		return err
+	}
+	return callbacks.Row().Before("gorm:row").Register("visibility:scope_row", scopeToVisible)
+}
+
+// HidesInvisible reports whether queries with the context are scoped to
+// visible records, for lookups outside the database that must agree with them
+func HidesInvisible(ctx context.Context) bool {
+	visibleOnly, _ := ctx.Value(visibleOnlyKey{}).(bool)
+	includeHidden, _ := ctx.Value(includeHiddenKey{}).(bool)
+	return (tenant.IDFromContext(ctx) != "" || visibleOnly) && !includeHidden
+}
+
+// scopeToVisible adds visible_from and expires_at conditions to the statement
+func scopeToVisible(tx *gorm.DB) {
+	if !HidesInvisible(tx.Statement.Context) || tx.Statement.Schema == nil {
+		return
+	}
+	visibleFrom := tx.Statement.Schema.LookUpField(visibleFromField)
+	expiresAt := tx.Statement.Schema.LookUpField(expiresAtField)
+	if visibleFrom == nil || expiresAt == nil {
+		return
+	}
+
+	now := time.Now()
+	from := clause.Column{Table: clause.CurrentTable, Name: visibleFrom.DBName}
+	until := clause.Column{Table: clause.CurrentTable, Name: expiresAt.DBName}
+	tx.Statement.AddClause(clause.Where{Exprs: []clause.Expression{
+		clause.Or(clause.Eq{Column: from, Value: nil}, clause.Lte{Column: from, Value: now}),
+		clause.Or(clause.Eq{Column: until, Value: nil}, clause.Gt{Column: until, Value: now}),
+	}})
+}
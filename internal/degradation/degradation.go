@@ -0,0 +1,88 @@
+package degradation
+
+import (
+	"context"
+	"sync"
+)
+
+// Subsystems that can run in a degraded mode
+const (
+	SubsystemLLMSummary = "llm_summary"
+	SubsystemIntent     = "intent"
+	SubsystemCache      = "cache"
+	SubsystemEmbeddings = "embeddings"
+	SubsystemTrending   = "trending"
+	SubsystemSearch     = "search"
+	SubsystemLimit      = "limit"
+)
+
+// Degraded modes reported for a subsystem
+const (
+	ModeFallback  = "fallback"
+	ModeHeuristic = "heuristic"
+	ModeStale     = "stale"
+	ModeBypassed  = "bypassed"
+	ModeCapped    = "capped"
+)
+
+// Report collects the subsystems that fell back to a degraded mode while serving a request
+type Report struct {
+	mu    sync.Mutex
+	modes map[string]string
+}
+
+type contextKey struct{}
+
+// NewContext returns a context carrying a fresh report
+func NewContext(ctx context.Context) context.Context {
+	return context.WithValue(ctx, contextKey{}, &Report{})
+}
+
+// FromContext returns the report stored in the context, or nil
+func FromContext(ctx context.Context) *Report {
+	if ctx == nil {
+		return nil
+	}
+	report, _ := ctx.Value(contextKey{}).(*Report)
+	return report
+}
+
+// Record notes a degraded subsystem on the context's report, if any
+func Record(ctx context.Context, subsystem, mode string) {
+	FromContext(ctx).Record(subsystem, mode)
+}
+
+// Modes returns the degraded subsystems recorded on the context's report
+func Modes(ctx context.Context) map[string]string {
+	return FromContext(ctx).Modes()
+}
+
+// Record notes that a subsystem ran in a degraded mode. Safe on a nil report.
+func (r *Report) Record(subsystem, mode string) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.modes == nil {
+		r.modes = make(map[string]string)
+	}
+	r.modes[subsystem] = mode
+}
+
+// Modes returns a copy of the recorded modes, or nil when nothing degraded
+func (r *Report) Modes() map[string]string {
+	if r == nil {
+		return nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.modes) == 0 {
+		return nil
+	}
+	modes := make(map[string]string, len(r.modes))
+	for subsystem, mode := range r.modes {
+		modes[subsystem] = mode
+	}
+	return modes
+}
@@ -0,0 +1,95 @@
+package faults
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Header names the faults to inject into a request, as comma-separated
+// settings like "db_latency=300ms, llm=429, cache=down"
+const Header = "X-Inject-Fault"
+
+// LLM faults
+const (
+	LLMRateLimited = "429"     // Every OpenAI request is answered with 429 Too Many Requests
+	LLMTimeout     = "timeout" // Every OpenAI request times out
+)
+
+// Faults are the failures injected into one request. A nil *Faults injects nothing.
+type Faults struct {
+	DBLatency time.Duration // Added before every database statement
+	LLM       string        // LLMRateLimited, LLMTimeout or empty
+	CacheDown bool          // In-memory caches miss and drop writes
+}
+
+type contextKey struct{}
+
+// Parse reads a fault header value
+func Parse(value string) (*Faults, error) {
+	f := &Faults{}
+	for _, setting := range strings.Split(value, ",") {
+		setting = strings.TrimSpace(setting)
+		if setting == "" {
+			continue
+		}
+		name, arg, _ := strings.Cut(setting, "=")
+		name, arg = strings.TrimSpace(name), strings.TrimSpace(arg)
+		switch name {
+		case "db_latency":
+			latency, err := time.ParseDuration(arg)
+			if err != nil || latency < 0 {
+				return nil, fmt.Errorf("db_latency must be a duration like 300ms")
+			}
+			f.DBLatency = latency
+		case "llm":
+			if arg != LLMRateLimited && arg != LLMTimeout {
+				return nil, fmt.Errorf("llm must be %s or %s", LLMRateLimited, LLMTimeout)
+			}
+			f.LLM = arg
+		case "cache":
+			if arg != "down" {
+				return nil, fmt.Errorf("cache must be down")
+			}
+			f.CacheDown = true
+		default:
+			return nil, fmt.Errorf("unknown fault %q", name)
+		}
+	}
+	return f, nil
+}
+
+// NewContext returns a context carrying the faults
+func NewContext(ctx context.Context, f *Faults) context.Context {
+	return context.WithValue(ctx, contextKey{}, f)
+}
+
+// FromContext returns the faults stored in the context, or nil
+func FromContext(ctx context.Context) *Faults {
+	if ctx == nil {
+		return nil
+	}
+	f, _ := ctx.Value(contextKey{}).(*Faults)
+	return f
+}
+
+// CacheDown reports whether the context's request simulates unavailable caches
+func CacheDown(ctx context.Context) bool {
+	f := FromContext(ctx)
+	return f != nil && f.CacheDown
+}
+
+// DelayDB waits out the injected database latency, returning early when ctx is done
+func DelayDB(ctx context.Context) {
+	f := FromContext(ctx)
+	if f == nil || f.DBLatency <= 0 {
+		return
+	}
+	timer := time.NewTimer(f.DBLatency)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-ctx.Done():
+	}
+}
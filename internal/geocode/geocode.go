@@ -0,0 +1,105 @@
+package geocode
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/mahigadamsetty/Inshorts-task/internal/utils"
+)
+
+//go:embed data/places.json
+var bundledPlaces []byte
+
+// Place is a named location with the radius that covers it
+type Place struct {
+	Name      string   `json:"name"`
+	Latitude  float64  `json:"latitude"`
+	Longitude float64  `json:"longitude"`
+	RadiusKm  float64  `json:"radius_km"`
+	Aliases   []string `json:"aliases,omitempty"`
+
+	coordinates *utils.CoordinateLimit // How MarshalJSON gives out the coordinates, nil for as stored
+}
+
+// LimitCoordinates makes the place's JSON give out its coordinates as limit
+// says. Nil gives them as stored.
+func (p *Place) LimitCoordinates(limit *utils.CoordinateLimit) {
+	p.coordinates = limit
+}
+
+// MarshalJSON encodes the place with its coordinates limited, see
+// LimitCoordinates
+func (p Place) MarshalJSON() ([]byte, error) {
+	type place Place // The fields, without this method
+	if p.coordinates == nil || !p.coordinates.Limits() {
+		return json.Marshal(place(p))
+	}
+	latitude, longitude := p.coordinates.Apply(p.Latitude, p.Longitude)
+	if latitude == nil {
+		return json.Marshal(struct {
+			place
+			Latitude  *float64 `json:"latitude,omitempty"`
+			Longitude *float64 `json:"longitude,omitempty"`
+		}{place: place(p)})
+	}
+	p.Latitude, p.Longitude = *latitude, *longitude
+	return json.Marshal(place(p))
+}
+
+var (
+	loadOnce sync.Once
+	places   map[string]Place
+	loadErr  error
+)
+
+// load indexes the bundled gazetteer by normalized name and alias
+func load() {
+	var list []Place
+	if err := json.Unmarshal(bundledPlaces, &list); err != nil {
+		loadErr = fmt.Errorf("failed to parse bundled places: %w", err)
+		return
+	}
+
+	places = make(map[string]Place, len(list))
+	for _, place := range list {
+		places[normalize(place.Name)] = place
+		for _, alias := range place.Aliases {
+			places[normalize(alias)] = place
+		}
+	}
+}
+
+// normalize lowercases a place name and strips surrounding punctuation
+func normalize(name string) string {
+	return strings.Trim(strings.ToLower(strings.TrimSpace(name)), ".,;:!?'\"")
+}
+
+// Resolve looks up a place name in the bundled gazetteer. Names like "Pune,
+// Maharashtra" fall back to their first component.
+func Resolve(name string) (Place, bool) {
+	loadOnce.Do(load)
+	if loadErr != nil {
+		return Place{}, false
+	}
+
+	if place, ok := places[normalize(name)]; ok {
+		return place, true
+	}
+	if i := strings.Index(name, ","); i > 0 {
+		return Resolve(name[:i])
+	}
+	return Place{}, false
+}
+
+// ResolveFirst returns the first of the names that resolves to a place
+func ResolveFirst(names []string) (Place, bool) {
+	for _, name := range names {
+		if place, ok := Resolve(name); ok {
+			return place, true
+		}
+	}
+	return Place{}, false
+}
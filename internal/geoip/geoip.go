@@ -0,0 +1,168 @@
+// Package geoip locates clients by IP address from a table of networks, so
+// requests without coordinates can still get local results. A small table
+// covering the documentation address ranges is bundled for development; point
+// GEOIP_FILE at a full table, like the GeoLite2 City blocks CSV, in production.
+package geoip
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"net/netip"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+//go:embed data/networks.csv
+var bundledNetworks []byte
+
+// Location is where the addresses of a network are, to within RadiusKm
+type Location struct {
+	City      string
+	Latitude  float64
+	Longitude float64
+	RadiusKm  float64
+}
+
+// network is a row of the table
+type network struct {
+	prefix   netip.Prefix
+	last     netip.Addr // Highest address of the network
+	location Location
+}
+
+// DB is a table of networks sorted by their first address
+type DB struct {
+	networks []network
+}
+
+// Open reads a CSV table of networks, or the bundled one for an empty path.
+// The header names the columns: network (a CIDR prefix), latitude and
+// longitude are required, accuracy_radius (km) and city are optional, and
+// other columns are ignored. Rows without coordinates are skipped, and
+// networks must not overlap.
+func Open(path string) (*DB, error) {
+	if path == "" {
+		return parse(bytes.NewReader(bundledNetworks))
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	db, err := parse(file)
+	if err != nil {
+		return nil, fmt.Errorf("invalid GeoIP table %s: %w", path, err)
+	}
+	return db, nil
+}
+
+func parse(r io.Reader) (*DB, error) {
+	reader := csv.NewReader(r)
+	reader.ReuseRecord = true
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read header: %w", err)
+	}
+	columns := map[string]int{}
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, required := range []string{"network", "latitude", "longitude"} {
+		if _, ok := columns[required]; !ok {
+			return nil, fmt.Errorf("missing %s column", required)
+		}
+	}
+	field := func(record []string, name string) string {
+		if i, ok := columns[name]; ok && i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+
+	db := &DB{}
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if field(record, "latitude") == "" || field(record, "longitude") == "" {
+			continue
+		}
+		prefix, err := netip.ParsePrefix(field(record, "network"))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		prefix = prefix.Masked()
+		location := Location{City: field(record, "city")}
+		if location.Latitude, err = strconv.ParseFloat(field(record, "latitude"), 64); err != nil {
+			return nil, fmt.Errorf("line %d: invalid latitude: %w", line, err)
+		}
+		if location.Longitude, err = strconv.ParseFloat(field(record, "longitude"), 64); err != nil {
+			return nil, fmt.Errorf("line %d: invalid longitude: %w", line, err)
+		}
+		if radius := field(record, "accuracy_radius"); radius != "" {
+			if location.RadiusKm, err = strconv.ParseFloat(radius, 64); err != nil {
+				return nil, fmt.Errorf("line %d: invalid accuracy_radius: %w", line, err)
+			}
+		}
+		db.networks = append(db.networks, network{prefix: prefix, last: lastAddr(prefix), location: location})
+	}
+
+	sort.Slice(db.networks, func(i, j int) bool {
+		return db.networks[i].prefix.Addr().Less(db.networks[j].prefix.Addr())
+	})
+	for i := 1; i < len(db.networks); i++ {
+		if !db.networks[i-1].last.Less(db.networks[i].prefix.Addr()) {
+			return nil, fmt.Errorf("network %s overlaps %s", db.networks[i].prefix, db.networks[i-1].prefix)
+		}
+	}
+	return db, nil
+}
+
+// lastAddr returns the highest address of a network
+func lastAddr(prefix netip.Prefix) netip.Addr {
+	bytes := prefix.Addr().AsSlice()
+	for bit := prefix.Bits(); bit < len(bytes)*8; bit++ {
+		bytes[bit/8] |= 1 << (7 - bit%8)
+	}
+	addr, _ := netip.AddrFromSlice(bytes)
+	return addr
+}
+
+// Len returns the number of networks in the table
+func (db *DB) Len() int {
+	if db == nil {
+		return 0
+	}
+	return len(db.networks)
+}
+
+// Lookup locates an IP address, and returns false for addresses in no network
+// of the table, like private and loopback ones. A nil table locates nothing.
+func (db *DB) Lookup(ip string) (Location, bool) {
+	if db == nil {
+		return Location{}, false
+	}
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return Location{}, false
+	}
+	addr = addr.Unmap()
+	// The last network starting at or before the address is the only one that
+	// can hold it
+	i := sort.Search(len(db.networks), func(i int) bool {
+		return addr.Less(db.networks[i].prefix.Addr())
+	}) - 1
+	if i < 0 || !db.networks[i].prefix.Contains(addr) {
+		return Location{}, false
+	}
+	return db.networks[i].location, true
+}
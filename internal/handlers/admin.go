@@ -0,0 +1,125 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mahigadamsetty/Inshorts-task/internal/config"
+	"github.com/mahigadamsetty/Inshorts-task/internal/llm"
+	"github.com/mahigadamsetty/Inshorts-task/internal/scheduler"
+	"github.com/mahigadamsetty/Inshorts-task/internal/scrub"
+	"github.com/mahigadamsetty/Inshorts-task/internal/services"
+	"github.com/mahigadamsetty/Inshorts-task/internal/tuning"
+	"gorm.io/gorm"
+)
+
+type AdminHandler struct {
+	db         *gorm.DB // Answers archive searches directly
+	svc        *services.Services
+	scheduler  *scheduler.Scheduler
+	summarizer *services.Summarizer
+	reindexer  *services.Reindexer
+	config     *config.Config
+}
+
+func NewAdminHandler(cfg *config.Config, database *gorm.DB, svc *services.Services, sched *scheduler.Scheduler, summarizer *services.Summarizer, reindexer *services.Reindexer) *AdminHandler {
+	return &AdminHandler{
+		db:         database,
+		svc:        svc,
+		scheduler:  sched,
+		summarizer: summarizer,
+		reindexer:  reindexer,
+		config:     cfg,
+	}
+}
+
+// ListJobs handles /admin/scheduler/jobs endpoint
+func (h *AdminHandler) ListJobs(c *gin.Context) {
+	jobs := h.scheduler.Status()
+	c.JSON(http.StatusOK, gin.H{"jobs": jobs, "count": len(jobs)})
+}
+
+// GetJobRuns handles /admin/scheduler/jobs/:name/runs endpoint
+func (h *AdminHandler) GetJobRuns(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if err != nil || limit <= 0 {
+		limit = 20
+	}
+
+	runs, err := h.scheduler.History(c.Param("name"), limit)
+	if err != nil {
+		respondError(c, err, "Failed to fetch job runs")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"runs": runs, "count": len(runs)})
+}
+
+// RunJob handles POST /admin/scheduler/jobs/:name/run, triggering a job in the background
+func (h *AdminHandler) RunJob(c *gin.Context) {
+	name := c.Param("name")
+	if err := h.scheduler.Lookup(name); err != nil {
+		respondError(c, err, "Failed to trigger job")
+		return
+	}
+
+	go h.scheduler.RunNow(context.Background(), name)
+
+	c.JSON(http.StatusAccepted, gin.H{"job": name, "status": "triggered"})
+}
+
+// GetLLMUsage handles /admin/llm/usage endpoint, reporting requests, errors and
+// latency per model of the fallback chain since startup
+func (h *AdminHandler) GetLLMUsage(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"chain": h.config.ModelChain(), "models": llm.Usage()})
+}
+
+// GetScrubbedCounts handles GET /admin/pii/scrubbed
+func (h *AdminHandler) GetScrubbedCounts(c *gin.Context) {
+	counts := scrub.Counts()
+	var total int64
+	for _, n := range counts {
+		total += n
+	}
+	c.JSON(http.StatusOK, gin.H{"total": total, "patterns": counts})
+}
+
+// GetTuning handles GET /admin/tuning, returning the active stop words and
+// ranking weights
+func (h *AdminHandler) GetTuning(c *gin.Context) {
+	file, loadedAt := tuning.Status()
+	c.JSON(http.StatusOK, gin.H{"file": file, "loaded_at": loadedAt, "settings": tuning.Current()})
+}
+
+// ReloadTuning handles POST /admin/tuning/reload, rereading the tuning file.
+// The previous settings stay active when the file is invalid.
+func (h *AdminHandler) ReloadTuning(c *gin.Context) {
+	settings, err := tuning.Reload()
+	if err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		return
+	}
+	file, loadedAt := tuning.Status()
+	c.JSON(http.StatusOK, gin.H{"file": file, "loaded_at": loadedAt, "settings": settings})
+}
+
+// GetShadowRanking handles GET /admin/ranking/shadow, comparing each shadow
+// ranking candidate with the live ranking over the last days of sampled
+// requests, from one client platform with platform=
+func (h *AdminHandler) GetShadowRanking(c *gin.Context) {
+	days, err := strconv.Atoi(c.DefaultQuery("days", "7"))
+	if err != nil || days <= 0 {
+		days = 7
+	}
+	since := time.Now().AddDate(0, 0, -days)
+
+	reports, err := h.svc.ShadowReports(c.Request.Context(), since, c.Query("platform"))
+	if err != nil {
+		respondError(c, err, "Failed to compare shadow rankings")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"since": since, "active": tuning.Current().Shadow, "candidates": reports})
+}
@@ -0,0 +1,120 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mahigadamsetty/Inshorts-task/internal/apperr"
+)
+
+// maxTermWindow caps the window of /analytics/terms, which reads every article
+// and search of twice the window, /analytics/heatmap and /analytics/sessions
+const maxTermWindow = 90 * 24 * time.Hour
+
+// maxRisingTerms caps how many terms /analytics/terms returns
+const maxRisingTerms = 100
+
+// GetRisingTerms handles /analytics/terms, returning the terms mentioned by
+// recent articles and searches that grew most over the previous window
+func (h *NewsHandler) GetRisingTerms(c *gin.Context) {
+	windowStr := c.DefaultQuery("window", "7d")
+	window, err := parseWindow(windowStr)
+	if err != nil {
+		respondError(c, err, "Invalid window")
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if err != nil || limit <= 0 {
+		limit = 20
+	}
+	if limit > maxRisingTerms {
+		limit = maxRisingTerms
+	}
+
+	trends, err := h.svc.RisingTerms(c.Request.Context(), window, limit)
+	if err != nil {
+		respondError(c, err, "Failed to analyze terms")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"window": windowStr, "trends": trends})
+}
+
+// maxHeatmapCells caps how many cells /analytics/heatmap returns
+const maxHeatmapCells = 5000
+
+// GetHeatmap handles /analytics/heatmap, returning the events of the last
+// window per geohash cell for rendering an engagement heatmap
+func (h *NewsHandler) GetHeatmap(c *gin.Context) {
+	windowStr := c.DefaultQuery("window", "24h")
+	window, err := parseWindow(windowStr)
+	if err != nil {
+		respondError(c, err, "Invalid window")
+		return
+	}
+
+	precision, err := strconv.Atoi(c.DefaultQuery("precision", "5"))
+	if err != nil || precision < 1 || precision > 8 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "precision must be between 1 and 8"})
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "500"))
+	if err != nil || limit <= 0 {
+		limit = 500
+	}
+	if limit > maxHeatmapCells {
+		limit = maxHeatmapCells
+	}
+
+	heatmap, err := h.svc.EventHeatmap(c.Request.Context(), window, precision, limit)
+	if err != nil {
+		respondError(c, err, "Failed to compute heatmap")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"window": windowStr, "heatmap": heatmap})
+}
+
+// GetSessionMetrics handles /analytics/sessions, summarizing the sessions of
+// anonymous clients started in the last window
+func (h *NewsHandler) GetSessionMetrics(c *gin.Context) {
+	windowStr := c.DefaultQuery("window", "7d")
+	window, err := parseWindow(windowStr)
+	if err != nil {
+		respondError(c, err, "Invalid window")
+		return
+	}
+
+	metrics, err := h.svc.GetSessionMetrics(c.Request.Context(), time.Now().Add(-window))
+	if err != nil {
+		respondError(c, err, "Failed to compute session metrics")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"window": windowStr, "sessions": metrics})
+}
+
+// parseWindow reads a window like 7d or 12h: whole days, or any duration
+// time.ParseDuration accepts, from an hour to maxTermWindow
+func parseWindow(value string) (time.Duration, error) {
+	var window time.Duration
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return 0, apperr.InvalidFilterf("window must be like 7d or 12h")
+		}
+		window = time.Duration(n) * 24 * time.Hour
+	} else {
+		parsed, err := time.ParseDuration(value)
+		if err != nil {
+			return 0, apperr.InvalidFilterf("window must be like 7d or 12h")
+		}
+		window = parsed
+	}
+	if window < time.Hour || window > maxTermWindow {
+		return 0, apperr.InvalidFilterf("window must be between 1h and %dd", int(maxTermWindow.Hours()/24))
+	}
+	return window, nil
+}
@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mahigadamsetty/Inshorts-task/internal/llm"
+	"github.com/mahigadamsetty/Inshorts-task/internal/models"
+	"github.com/mahigadamsetty/Inshorts-task/internal/services"
+)
+
+// maxArchiveResults caps one page of archive search results
+const maxArchiveResults = 100
+
+// ArchiveArticle is an archive search result, labelled with its tenant
+type ArchiveArticle struct {
+	archivedArticle
+	TenantID string `json:"tenant_id"`
+}
+
+// archivedArticle is an article without its MarshalJSON, so that the fields of
+// ArchiveArticle are encoded together
+type archivedArticle models.Article
+
+// SearchArchive handles /admin/archive/search. Unlike /search it spans every
+// tenant, skips the moderation, paywall and reliability filters, and returns
+// each article's rolled-up interaction counters.
+func (h *AdminHandler) SearchArchive(c *gin.Context) {
+	query := c.Query("query")
+	if query == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "query parameter is required"})
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if err != nil || limit <= 0 {
+		limit = 20
+	}
+	if limit > maxArchiveResults {
+		limit = maxArchiveResults
+	}
+	offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if err != nil || offset < 0 {
+		offset = 0
+	}
+
+	// Only the publication date bounds of the shared filters apply
+	scope := services.SearchScope{TenantID: c.Query("tenant")}
+	for param, bound := range map[string]*time.Time{"from": &scope.Filter.PublishedFrom, "to": &scope.Filter.PublishedTo} {
+		value := c.Query(param)
+		if value == "" {
+			continue
+		}
+		day, err := time.Parse(llm.DateLayout, value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": param + " must be a date like 2024-01-31"})
+			return
+		}
+		if param == "to" {
+			day = day.AddDate(0, 0, 1) // Include the whole end day
+		}
+		*bound = day
+	}
+
+	database := h.db.WithContext(c.Request.Context())
+	queryBuilder := database.Model(&models.Article{}).
+		Scopes(scope.Filter.Scope).
+		Where(keywordMatch(c.Request.Context(), h.svc, database, query, scope))
+	if scope.TenantID != "" {
+		queryBuilder = queryBuilder.Where("tenant_id = ?", scope.TenantID)
+	}
+
+	var articles []models.Article
+	err = queryBuilder.
+		Order("publication_date DESC").
+		Order("id").
+		Limit(limit).
+		Offset(offset).
+		Find(&articles).Error
+	if err != nil {
+		respondError(c, err, "Failed to fetch articles")
+		return
+	}
+
+	h.svc.AttachSourceMeta(c.Request.Context(), articles)
+	if err := h.svc.AttachViews(c.Request.Context(), articles); err != nil {
+		respondError(c, err, "Failed to fetch view counts")
+		return
+	}
+
+	results := make([]ArchiveArticle, len(articles))
+	for i, article := range articles {
+		results[i] = ArchiveArticle{archivedArticle: archivedArticle(article), TenantID: article.TenantID}
+	}
+	c.JSON(http.StatusOK, gin.H{"articles": results, "count": len(results), "limit": limit, "offset": offset})
+}
@@ -0,0 +1,40 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mahigadamsetty/Inshorts-task/internal/models"
+	"github.com/mahigadamsetty/Inshorts-task/internal/services"
+)
+
+// EditArticle handles PATCH /admin/news/:id, correcting an article's title,
+// description, categories or location. A summary made stale by the edit is
+// queued for regeneration at high priority, and the job is returned as
+// summary_job.
+func (h *AdminHandler) EditArticle(c *gin.Context) {
+	var edit services.ArticleEdit
+	if err := c.ShouldBindJSON(&edit); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	article, err := h.svc.EditArticle(ctx, c.Param("id"), edit)
+	if err != nil {
+		respondError(c, err, "Failed to edit article")
+		return
+	}
+
+	var job *models.SummaryJob
+	if article.SummaryStale {
+		// The text-fetch job refreshes stale summaries too, so a failure here
+		// only delays the new summary
+		job, err = h.summarizer.Submit(ctx, []string{article.ID}, services.SummaryPriorityHigh)
+		if err != nil {
+			log.Printf("Failed to queue summary of edited article %s: %v", article.ID, err)
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{"article": article, "summary_job": job})
+}
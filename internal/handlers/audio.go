@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mahigadamsetty/Inshorts-task/internal/services"
+)
+
+// audioPath is where audio files are served. Files are named after a hash of
+// their summary, so they never change and need no API key, which audio
+// players can't send.
+const audioPath = "/audio/"
+
+// audioMaxBriefing caps the summaries of an audio briefing
+const audioMaxBriefing = 50
+
+// BriefingResponse is an audio briefing: spoken summaries in playing order
+type BriefingResponse struct {
+	Category        string                  `json:"category"`
+	Items           []services.BriefingItem `json:"items"`
+	Count           int                     `json:"count"`
+	DurationSeconds float64                 `json:"duration_seconds"`
+}
+
+// GetAudioBriefing handles /audio/briefing, returning the newest spoken
+// summaries of a category as JSON or, with format=m3u, as a playlist
+func (h *NewsHandler) GetAudioBriefing(c *gin.Context) {
+	format := c.DefaultQuery("format", "json")
+	if format != "json" && format != "m3u" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be json or m3u"})
+		return
+	}
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "10"))
+	if err != nil || limit <= 0 {
+		limit = 10
+	}
+	if limit > audioMaxBriefing {
+		limit = audioMaxBriefing
+	}
+
+	category := c.Query("category")
+	filter, err := parseArticleFilter(c)
+	if err != nil {
+		respondError(c, err, "Invalid filter")
+		return
+	}
+	items, err := h.svc.AudioBriefing(c.Request.Context(), category, filter, limit, audioPath)
+	if err != nil {
+		respondError(c, err, "Failed to build audio briefing")
+		return
+	}
+
+	if format == "m3u" {
+		var playlist strings.Builder
+		playlist.WriteString("#EXTM3U\n")
+		for _, item := range items {
+			// Titles end at the line break, as the format requires
+			title := strings.Join(strings.Fields(item.SourceName+" - "+item.Title), " ")
+			fmt.Fprintf(&playlist, "#EXTINF:%d,%s\n%s\n", int(math.Ceil(item.DurationSeconds)), title, item.AudioURL)
+		}
+		c.Data(http.StatusOK, "audio/x-mpegurl; charset=utf-8", []byte(playlist.String()))
+		return
+	}
+
+	response := BriefingResponse{Category: category, Items: items, Count: len(items)}
+	for _, item := range items {
+		response.DurationSeconds += item.DurationSeconds
+	}
+	response.DurationSeconds = math.Round(response.DurationSeconds*10) / 10
+	c.JSON(http.StatusOK, response)
+}
+
+// GetAudioFile handles /audio/:file, serving the spoken summary stored under
+// that name
+func (h *NewsHandler) GetAudioFile(c *gin.Context) {
+	file, err := h.svc.AudioFile(c.Request.Context(), c.Param("file"))
+	if err != nil {
+		respondError(c, err, "Failed to fetch audio")
+		return
+	}
+	serveBlob(c, file, c.Param("file"))
+}
@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	"bytes"
+	"html/template"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mahigadamsetty/Inshorts-task/internal/services"
+)
+
+// cardTemplate renders a preview card's meta tags, for a page's head, and a
+// page carrying them with a visible preview, for unfurlers fetching the link
+var cardTemplate = template.Must(template.New("meta").Parse(`{{range .}}{{if .Property}}<meta property="{{.Property}}" content="{{.Content}}">{{else}}<meta name="{{.Name}}" content="{{.Content}}">{{end}}
+{{end}}`))
+
+var cardPageTemplate = template.Must(template.Must(cardTemplate.Clone()).New("page").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Card.Title}}</title>
+<link rel="canonical" href="{{.Card.CanonicalURL}}">
+{{template "meta" .Meta}}<style>body{max-width:32em;margin:2em auto;padding:0 1em;font:1em/1.5 sans-serif;color:#222}img{max-width:100%;border-radius:4px}p.site{color:#666;font-size:.85em}</style>
+</head>
+<body>
+<article>
+{{if .Card.Image}}<img src="{{.Card.Image}}" alt="">
+{{end}}<p class="site">{{.Card.SiteName}}</p>
+<h1>{{.Card.Title}}</h1>
+<p>{{.Card.Description}}</p>
+<p><a href="{{.Card.URL}}" rel="noopener">Read the article</a></p>
+</article>
+</body>
+</html>
+`))
+
+// cardMeta is one OpenGraph (property) or Twitter card (name) meta tag
+type cardMeta struct {
+	Property string `json:"property,omitempty"`
+	Name     string `json:"name,omitempty"`
+	Content  string `json:"content"`
+}
+
+// CardResponse is a preview card with its meta tags, listed and rendered as
+// HTML for a page's head
+type CardResponse struct {
+	*services.ArticleCard
+	Meta []cardMeta `json:"meta"`
+	HTML string     `json:"html"`
+}
+
+// GetCard handles /:id/card, returning an article's OpenGraph and Twitter card
+// metadata as JSON or, with format=html, as a page unfurlers can fetch
+func (h *NewsHandler) GetCard(c *gin.Context) {
+	format := c.DefaultQuery("format", "json")
+	if format != "json" && format != "html" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be json or html"})
+		return
+	}
+
+	id := c.Param("id")
+	filter, err := parseArticleFilter(c)
+	if err != nil {
+		respondError(c, err, "Invalid filter")
+		return
+	}
+	card, err := h.svc.GetArticleCard(c.Request.Context(), id, filter, h.config.ArticlePageURL(id))
+	if err != nil {
+		respondError(c, err, "Failed to fetch article")
+		return
+	}
+
+	meta := cardMetaTags(card)
+	var buf bytes.Buffer
+	if format == "html" {
+		err = cardPageTemplate.Execute(&buf, struct {
+			Card *services.ArticleCard
+			Meta []cardMeta
+		}{card, meta})
+	} else {
+		err = cardTemplate.Execute(&buf, meta)
+	}
+	if err != nil {
+		respondError(c, err, "Failed to render card")
+		return
+	}
+
+	c.Header("Cache-Control", "max-age=300")
+	if format == "html" {
+		c.Header("Content-Security-Policy", "default-src 'none'; img-src http: https:; style-src 'unsafe-inline'")
+		c.Data(http.StatusOK, "text/html; charset=utf-8", buf.Bytes())
+		return
+	}
+	c.JSON(http.StatusOK, CardResponse{ArticleCard: card, Meta: meta, HTML: strings.TrimSpace(buf.String())})
+}
+
+// cardMetaTags lists the OpenGraph and Twitter card tags of a preview card.
+// Cards with an image get the large image layout.
+func cardMetaTags(card *services.ArticleCard) []cardMeta {
+	twitterCard := "summary"
+	if card.Image != "" {
+		twitterCard = "summary_large_image"
+	}
+	meta := []cardMeta{
+		{Property: "og:type", Content: "article"},
+		{Property: "og:title", Content: card.Title},
+		{Property: "og:description", Content: card.Description},
+		{Property: "og:url", Content: card.URL},
+		{Property: "og:site_name", Content: card.SiteName},
+	}
+	if card.Image != "" {
+		meta = append(meta, cardMeta{Property: "og:image", Content: card.Image})
+	}
+	meta = append(meta,
+		cardMeta{Property: "article:published_time", Content: card.PublicationDate.UTC().Format(time.RFC3339)},
+		cardMeta{Name: "twitter:card", Content: twitterCard},
+		cardMeta{Name: "twitter:title", Content: card.Title},
+		cardMeta{Name: "twitter:description", Content: card.Description},
+	)
+	if card.Image != "" {
+		meta = append(meta, cardMeta{Name: "twitter:image", Content: card.Image})
+	}
+	return meta
+}
@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mahigadamsetty/Inshorts-task/internal/services"
+)
+
+// GetCategoryCounts handles /categories endpoint, counting the articles per
+// category with descendants rolled up: per child of parent, or per category
+// at level (default 1)
+func (h *NewsHandler) GetCategoryCounts(c *gin.Context) {
+	level, err := strconv.Atoi(c.DefaultQuery("level", "1"))
+	if err != nil || level < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "level must be a positive integer"})
+		return
+	}
+	parent := c.Query("parent")
+
+	filter, err := parseArticleFilter(c)
+	if err != nil {
+		respondError(c, err, "Invalid filter")
+		return
+	}
+	counts, level, err := h.svc.CategoryFacets(c.Request.Context(), filter, parent, level)
+	if err != nil {
+		respondError(c, err, "Failed to count categories")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"categories": counts, "count": len(counts), "level": level, "parent": parent})
+}
+
+// ListCategories handles /admin/categories endpoint
+func (h *AdminHandler) ListCategories(c *gin.Context) {
+	categories, err := h.svc.ListCategories(c.Request.Context())
+	if err != nil {
+		respondError(c, err, "Failed to fetch categories")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"categories": categories, "count": len(categories)})
+}
+
+// SaveCategory handles PUT /admin/categories/:name, creating or replacing a
+// category with its parent and aliases
+func (h *AdminHandler) SaveCategory(c *gin.Context) {
+	var input services.CategoryInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+	input.Name = c.Param("name")
+
+	category, err := h.svc.SaveCategory(c.Request.Context(), input)
+	if err != nil {
+		respondError(c, err, "Failed to save category")
+		return
+	}
+
+	c.JSON(http.StatusOK, category)
+}
+
+// DeleteCategory handles DELETE /admin/categories/:name
+func (h *AdminHandler) DeleteCategory(c *gin.Context) {
+	if err := h.svc.DeleteCategory(c.Request.Context(), c.Param("name")); err != nil {
+		respondError(c, err, "Failed to delete category")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// MigrateCategories handles POST /admin/categories/migrate, adding the flat
+// categories of stored articles to the hierarchy and renaming aliases. With
+// dry_run=true it only reports what would change.
+func (h *AdminHandler) MigrateCategories(c *gin.Context) {
+	dryRun, _ := strconv.ParseBool(c.DefaultQuery("dry_run", "false"))
+
+	migration, err := h.svc.MigrateCategories(c.Request.Context(), dryRun)
+	if err != nil {
+		respondError(c, err, "Failed to migrate categories")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"dry_run": dryRun, "added": migration.Added, "rewritten": migration.Rewritten})
+}
@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/mahigadamsetty/Inshorts-task/internal/geocode"
+	"github.com/mahigadamsetty/Inshorts-task/internal/models"
+	"github.com/mahigadamsetty/Inshorts-task/internal/services"
+	"github.com/mahigadamsetty/Inshorts-task/internal/utils"
+)
+
+// coordinateResponse is a response body giving out coordinates
+type coordinateResponse interface {
+	// limitCoordinates makes the body's JSON give out its coordinates as
+	// limit says
+	limitCoordinates(limit *utils.CoordinateLimit)
+}
+
+// respondWithCoordinates writes a JSON response whose coordinates are rounded
+// or left out as the request's tenant and the deployment ask. See
+// services.Services.ResponseCoordinates.
+func (h *NewsHandler) respondWithCoordinates(c *gin.Context, status int, body coordinateResponse) {
+	if limit := h.svc.ResponseCoordinates(c.Request.Context()); limit != nil {
+		body.limitCoordinates(limit)
+	}
+	c.JSON(status, body)
+}
+
+// limitArticles returns copies of articles whose JSON gives out their
+// coordinates as limit says. Shared articles, like those of cached trending
+// lists, are left untouched.
+func limitArticles(articles []models.Article, limit *utils.CoordinateLimit) []models.Article {
+	if limit == nil || articles == nil {
+		return articles
+	}
+	limited := make([]models.Article, len(articles))
+	for i := range articles {
+		limited[i] = articles[i]
+		limited[i].LimitCoordinates(limit)
+	}
+	return limited
+}
+
+// limitPlace returns a copy of a place whose JSON gives out its coordinates
+// as limit says
+func limitPlace(place *geocode.Place, limit *utils.CoordinateLimit) *geocode.Place {
+	if limit == nil || place == nil {
+		return place
+	}
+	limited := *place
+	limited.LimitCoordinates(limit)
+	return &limited
+}
+
+func (r *Response) limitCoordinates(limit *utils.CoordinateLimit) {
+	r.Articles = limitArticles(r.Articles, limit)
+	r.Meta.Location = limitPlace(r.Meta.Location, limit)
+}
+
+func (r *TrendingComparisonResponse) limitCoordinates(limit *utils.CoordinateLimit) {
+	for i := range r.Locations {
+		location := &r.Locations[i]
+		location.Latitude, location.Longitude = limit.Apply(*location.Latitude, *location.Longitude)
+		location.Articles = limitArticles(location.Articles, limit)
+	}
+	r.Meta.Location = limitPlace(r.Meta.Location, limit)
+}
+
+func (r *StoriesResponse) limitCoordinates(limit *utils.CoordinateLimit) {
+	stories := make([]models.Story, len(r.Stories))
+	for i := range r.Stories {
+		stories[i] = r.Stories[i]
+		stories[i].LimitCoordinates(limit)
+	}
+	r.Stories = stories
+	r.Meta.Location = limitPlace(r.Meta.Location, limit)
+}
+
+func (r *StoryResponse) limitCoordinates(limit *utils.CoordinateLimit) {
+	r.Story.LimitCoordinates(limit)
+	r.Timeline = limitArticles(r.Timeline, limit)
+}
+
+func (r *TopicResponse) limitCoordinates(limit *utils.CoordinateLimit) {
+	r.Articles = limitArticles(r.Articles, limit)
+	r.Meta.Location = limitPlace(r.Meta.Location, limit)
+}
+
+func (r *TimelineResponse) limitCoordinates(limit *utils.CoordinateLimit) {
+	buckets := make([]services.TimelineBucket, len(r.Buckets))
+	for i, bucket := range r.Buckets {
+		bucket.Articles = limitArticles(bucket.Articles, limit)
+		buckets[i] = bucket
+	}
+	r.Buckets = buckets
+	r.Meta.Location = limitPlace(r.Meta.Location, limit)
+}
@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mahigadamsetty/Inshorts-task/internal/services"
+)
+
+// ListCrawlPolicies handles /admin/crawl-policies endpoint
+func (h *AdminHandler) ListCrawlPolicies(c *gin.Context) {
+	policies, err := h.svc.ListCrawlPolicies(c.Request.Context())
+	if err != nil {
+		respondError(c, err, "Failed to fetch crawl policies")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"crawl_policies": policies, "count": len(policies)})
+}
+
+// GetCrawlPolicy handles GET /admin/sources/:name/crawl
+func (h *AdminHandler) GetCrawlPolicy(c *gin.Context) {
+	policy, err := h.svc.GetCrawlPolicy(c.Request.Context(), c.Param("name"))
+	if err != nil {
+		respondError(c, err, "Failed to fetch crawl policy")
+		return
+	}
+
+	c.JSON(http.StatusOK, policy)
+}
+
+// SaveCrawlPolicy handles PUT /admin/sources/:name/crawl, creating or replacing a source's crawl policy
+func (h *AdminHandler) SaveCrawlPolicy(c *gin.Context) {
+	var input services.CrawlPolicyInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+	input.SourceName = c.Param("name")
+
+	policy, err := input.Policy()
+	if err != nil {
+		respondError(c, err, "Invalid crawl policy")
+		return
+	}
+
+	if err := h.svc.SaveCrawlPolicy(c.Request.Context(), &policy); err != nil {
+		respondError(c, err, "Failed to save crawl policy")
+		return
+	}
+
+	c.JSON(http.StatusOK, policy)
+}
+
+// DeleteCrawlPolicy handles DELETE /admin/sources/:name/crawl
+func (h *AdminHandler) DeleteCrawlPolicy(c *gin.Context) {
+	if err := h.svc.DeleteCrawlPolicy(c.Request.Context(), c.Param("name")); err != nil {
+		respondError(c, err, "Failed to delete crawl policy")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
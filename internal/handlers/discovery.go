@@ -0,0 +1,105 @@
+package handlers
+
+import (
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mahigadamsetty/Inshorts-task/internal/degradation"
+	"github.com/mahigadamsetty/Inshorts-task/internal/services"
+)
+
+// GetRandom handles /random endpoint, returning a discovery feed of recent
+// articles ordered by the bandit policy
+func (h *NewsHandler) GetRandom(c *gin.Context) {
+	h.discover(c, "random", false)
+}
+
+// GetForYou handles /for-you endpoint, returning the discovery feed weighted
+// towards the categories the client's recent events were in, without the
+// articles it saw. Clients without an anonymous ID get the /random feed.
+func (h *NewsHandler) GetForYou(c *gin.Context) {
+	h.discover(c, "for-you", true)
+}
+
+// discover answers a discovery feed request, drawing its random choices from
+// the seed parameter, or a time-based seed
+func (h *NewsHandler) discover(c *gin.Context, endpoint string, personal bool) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "10"))
+	if err != nil || limit <= 0 {
+		limit = 10
+	}
+	seed := time.Now().UnixNano()
+	if raw := c.Query("seed"); raw != "" {
+		if seed, err = strconv.ParseInt(raw, 10, 64); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "seed must be an integer"})
+			return
+		}
+	}
+
+	ctx := c.Request.Context()
+	filter, err := parseArticleFilter(c)
+	if err != nil {
+		respondError(c, err, "Invalid filter")
+		return
+	}
+	articles, err := h.svc.Discover(ctx, services.DiscoveryRequest{
+		Endpoint:    endpoint,
+		Limit:       limit,
+		Filter:      filter,
+		ClientID:    clientIdentity(c, c.Query("client_id")),
+		AnonymousID: anonymousID(c, c.Query("client_id")),
+		Personal:    personal,
+		Rand:        rand.New(rand.NewSource(seed)),
+	})
+	if err != nil {
+		respondError(c, err, "Failed to fetch articles")
+		return
+	}
+
+	h.svc.AttachSourceMeta(ctx, articles)
+	h.recordImpressions(c, endpoint, articles)
+	applyExplain(c, articles)
+	h.enrichWithSummaries(c, articles)
+
+	h.respondWithCoordinates(c, http.StatusOK, &Response{
+		Articles: articles,
+		Meta: Meta{
+			Count:       len(articles),
+			Limit:       limit,
+			Endpoint:    endpoint,
+			Degradation: degradation.Modes(ctx),
+			Seed:        &seed,
+		},
+	})
+}
+
+// GetDiscovery handles /admin/analytics/discovery, returning the discovery
+// feed's policy, its pulls and clicks of the last days per policy and choice,
+// and the arms with the best expected click-through rate
+func (h *AdminHandler) GetDiscovery(c *gin.Context) {
+	days, err := strconv.Atoi(c.DefaultQuery("days", "7"))
+	if err != nil || days <= 0 {
+		days = 7
+	}
+	if days > maxEngagementDays {
+		days = maxEngagementDays
+	}
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if err != nil || limit <= 0 {
+		limit = 20
+	}
+	if limit > 100 {
+		limit = 100
+	}
+
+	since := time.Now().AddDate(0, 0, -days)
+	report, err := h.svc.DiscoveryReports(c.Request.Context(), since, c.Query("tenant"), limit)
+	if err != nil {
+		respondError(c, err, "Failed to fetch discovery statistics")
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
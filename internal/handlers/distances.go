@@ -0,0 +1,60 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mahigadamsetty/Inshorts-task/internal/models"
+)
+
+// DistancesInput is a point and the articles to measure the distance to
+type DistancesInput struct {
+	Latitude   *float64 `json:"latitude"`
+	Longitude  *float64 `json:"longitude"`
+	ArticleIDs []string `json:"article_ids" binding:"required"`
+}
+
+// GetDistances handles POST /geo/distances, returning how far each article is
+// from the client so maps can place them without the articles' coordinates.
+// Without coordinates, the client is located from its IP address when
+// GEOIP_FALLBACK is on. With ?precise=false the client's location is kept at
+// city level.
+func (h *NewsHandler) GetDistances(c *gin.Context) {
+	var input DistancesInput
+	if err := c.ShouldBindJSON(&input); err != nil || len(input.ArticleIDs) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+	if len(input.ArticleIDs) > h.config.GeoDistanceMaxArticles {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("At most %d article_ids are accepted", h.config.GeoDistanceMaxArticles)})
+		return
+	}
+
+	var meta gin.H
+	if input.Latitude == nil && input.Longitude == nil {
+		located, ok := h.locateClient(c)
+		if !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "latitude and longitude are required"})
+			return
+		}
+		input.Latitude, input.Longitude = &located.Latitude, &located.Longitude
+		meta = gin.H{"location": located}
+	}
+	if input.Latitude == nil || input.Longitude == nil || !models.ValidCoordinates(*input.Latitude, *input.Longitude) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "latitude must be between -90 and 90 and longitude between -180 and 180"})
+		return
+	}
+	lat, lon := h.svc.PrivateLocation(*input.Latitude, *input.Longitude, preciseLocation(c))
+
+	distances, missing, unlocated, err := h.svc.ArticleDistances(c.Request.Context(), lat, lon, input.ArticleIDs)
+	if err != nil {
+		respondError(c, err, "Failed to compute distances")
+		return
+	}
+	response := gin.H{"distances": distances, "missing": missing, "unlocated": unlocated}
+	if meta != nil {
+		response["meta"] = meta
+	}
+	c.JSON(http.StatusOK, response)
+}
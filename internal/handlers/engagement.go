@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mahigadamsetty/Inshorts-task/internal/services"
+)
+
+// maxEngagementDays caps how far back /admin/analytics/engagement reaches
+const maxEngagementDays = 365
+
+// GetEngagement handles /admin/analytics/engagement, returning views, clicks
+// and click-through rates per source or category and day over the last days,
+// as JSON or, with format=csv, as a CSV download of the daily rows
+func (h *AdminHandler) GetEngagement(c *gin.Context) {
+	days, err := strconv.Atoi(c.DefaultQuery("days", "30"))
+	if err != nil || days <= 0 {
+		days = 30
+	}
+	if days > maxEngagementDays {
+		days = maxEngagementDays
+	}
+	format := c.DefaultQuery("format", "json")
+	if format != "json" && format != "csv" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be json or csv"})
+		return
+	}
+
+	// Whole UTC days, so the first day isn't partial
+	since := time.Now().UTC().Truncate(24*time.Hour).AddDate(0, 0, 1-days)
+	groupBy := c.DefaultQuery("group_by", services.EngagementBySource)
+	report, err := h.svc.EngagementByGroup(c.Request.Context(), groupBy, since, c.Query("tenant"))
+	if err != nil {
+		respondError(c, err, "Failed to aggregate engagement")
+		return
+	}
+
+	if format == "json" {
+		c.JSON(http.StatusOK, report)
+		return
+	}
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", `attachment; filename="engagement-`+groupBy+`.csv"`)
+	c.Status(http.StatusOK)
+	w := csv.NewWriter(c.Writer)
+	w.Write([]string{"day", groupBy, "views", "clicks", "ctr"})
+	for _, row := range report.Days {
+		w.Write([]string{
+			row.Day,
+			row.Group,
+			strconv.FormatInt(row.Views, 10),
+			strconv.FormatInt(row.Clicks, 10),
+			strconv.FormatFloat(row.CTR, 'f', 4, 64),
+		})
+	}
+	w.Flush()
+}
+
+// GetImpressions handles /admin/analytics/impressions, returning the
+// click-through rates of the sampled impressions of the last days per ranking
+// profile and ranker, and per position
+func (h *AdminHandler) GetImpressions(c *gin.Context) {
+	days, err := strconv.Atoi(c.DefaultQuery("days", "7"))
+	if err != nil || days <= 0 {
+		days = 7
+	}
+	if days > maxEngagementDays {
+		days = maxEngagementDays
+	}
+
+	since := time.Now().AddDate(0, 0, -days)
+	report, err := h.svc.ImpressionReports(c.Request.Context(), since, c.Query("tenant"))
+	if err != nil {
+		respondError(c, err, "Failed to aggregate impressions")
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+// GetPositionBias handles /admin/analytics/position-bias, returning the click
+// model's examination probability per position and the most attractive
+// articles once position bias is removed
+func (h *AdminHandler) GetPositionBias(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if err != nil || limit <= 0 {
+		limit = 20
+	}
+	if limit > 100 {
+		limit = 100
+	}
+
+	report, err := h.svc.GetPositionBias(c.Request.Context(), c.Query("tenant"), limit)
+	if err != nil {
+		respondError(c, err, "Failed to fetch position bias")
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
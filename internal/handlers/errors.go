@@ -0,0 +1,39 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mahigadamsetty/Inshorts-task/internal/apperr"
+)
+
+// respondError writes the status a service error maps to. Not found, invalid
+// filter, conflict, unavailable and cache miss errors carry messages meant for
+// the caller; anything else is logged and answered with message, so internals
+// don't leak.
+func respondError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, apperr.ErrNotFound), errors.Is(err, apperr.ErrCacheMiss):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, apperr.ErrInvalidFilter):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, apperr.ErrTooExpensive):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+	case errors.Is(err, apperr.ErrConflict):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, apperr.ErrUnavailable):
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+	case errors.Is(err, apperr.ErrUpstreamLLM):
+		log.Printf("%s: %v", message, err)
+		c.JSON(http.StatusBadGateway, gin.H{"error": message})
+	case errors.Is(err, context.DeadlineExceeded):
+		log.Printf("%s: %v", message, err)
+		c.JSON(http.StatusGatewayTimeout, gin.H{"error": message})
+	default:
+		log.Printf("%s: %v", message, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+	}
+}
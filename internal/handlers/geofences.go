@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mahigadamsetty/Inshorts-task/internal/models"
+	"github.com/mahigadamsetty/Inshorts-task/internal/services"
+)
+
+// CreateGeofence handles POST /geofences. The webhook secret is only returned here.
+func (h *NewsHandler) CreateGeofence(c *gin.Context) {
+	var input services.GeofenceInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	fence, err := input.Geofence(c.Request.Context(), h.config.GeofenceMaxRadiusKm)
+	if err != nil {
+		respondError(c, err, "Invalid geofence")
+		return
+	}
+
+	if err := h.svc.CreateGeofence(c.Request.Context(), &fence); err != nil {
+		respondError(c, err, "Failed to create geofence")
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"geofence": fence, "secret": fence.Secret})
+}
+
+// ListGeofences handles GET /geofences
+func (h *NewsHandler) ListGeofences(c *gin.Context) {
+	fences, err := h.svc.ListGeofences(c.Request.Context())
+	if err != nil {
+		respondError(c, err, "Failed to fetch geofences")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"geofences": fences, "count": len(fences)})
+}
+
+// GetGeofence handles GET /geofences/:id
+func (h *NewsHandler) GetGeofence(c *gin.Context) {
+	fence, ok := h.loadGeofence(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, fence)
+}
+
+// DeleteGeofence handles DELETE /geofences/:id
+func (h *NewsHandler) DeleteGeofence(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid geofence id"})
+		return
+	}
+
+	if err := h.svc.DeleteGeofence(c.Request.Context(), uint(id)); err != nil {
+		respondError(c, err, "Failed to delete geofence")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// GetGeofenceAlerts handles GET /geofences/:id/alerts, returning recent alerts
+// with their delivery state
+func (h *NewsHandler) GetGeofenceAlerts(c *gin.Context) {
+	fence, ok := h.loadGeofence(c)
+	if !ok {
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if err != nil || limit <= 0 {
+		limit = 20
+	}
+
+	alerts, err := h.svc.ListGeofenceAlerts(c.Request.Context(), fence.ID, limit)
+	if err != nil {
+		respondError(c, err, "Failed to fetch alerts")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"alerts": alerts, "count": len(alerts)})
+}
+
+// loadGeofence fetches the geofence named by the id parameter, writing the error
+// response when it cannot
+func (h *NewsHandler) loadGeofence(c *gin.Context) (*models.Geofence, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid geofence id"})
+		return nil, false
+	}
+
+	fence, err := h.svc.GetGeofence(c.Request.Context(), uint(id))
+	if err != nil {
+		respondError(c, err, "Failed to fetch geofence")
+		return nil, false
+	}
+	return fence, true
+}
@@ -0,0 +1,42 @@
+package handlers
+
+import (
+	"mime"
+	"net/http"
+	"path"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mahigadamsetty/Inshorts-task/internal/services"
+)
+
+// imagePath is where stored preview images are served. Like audio files they
+// are named after a hash of their content, so they never change and need no
+// API key, which image tags can't send.
+const imagePath = "/images/"
+
+// GetImageFile handles /images/:file, serving the preview image stored under
+// that name
+func (h *NewsHandler) GetImageFile(c *gin.Context) {
+	file, err := h.svc.ImageFile(c.Request.Context(), c.Param("file"))
+	if err != nil {
+		respondError(c, err, "Failed to fetch image")
+		return
+	}
+	serveBlob(c, file, c.Param("file"))
+}
+
+// serveBlob sends a stored file that never changes: a redirect when the store
+// links to it directly, its content otherwise
+func serveBlob(c *gin.Context, file *services.BlobFile, name string) {
+	c.Header("Cache-Control", "public, max-age=31536000, immutable")
+	if file.Link != "" {
+		c.Redirect(http.StatusFound, file.Link)
+		return
+	}
+	defer file.Content.Close()
+	contentType := mime.TypeByExtension(path.Ext(name))
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	c.DataFromReader(http.StatusOK, file.Size, contentType, file.Content, nil)
+}
@@ -0,0 +1,118 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mahigadamsetty/Inshorts-task/internal/services"
+)
+
+// ListIngestionSources handles GET /admin/ingestion/sources
+func (h *AdminHandler) ListIngestionSources(c *gin.Context) {
+	sources, err := h.svc.ListIngestionSources(c.Request.Context())
+	if err != nil {
+		respondError(c, err, "Failed to fetch ingestion sources")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"sources": sources, "count": len(sources)})
+}
+
+// CreateIngestionSource handles POST /admin/ingestion/sources
+func (h *AdminHandler) CreateIngestionSource(c *gin.Context) {
+	var input services.IngestionSourceInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	source, err := h.svc.CreateIngestionSource(c.Request.Context(), input)
+	if err != nil {
+		respondError(c, err, "Failed to create ingestion source")
+		return
+	}
+
+	c.JSON(http.StatusCreated, source)
+}
+
+// GetIngestionSource handles GET /admin/ingestion/sources/:id
+func (h *AdminHandler) GetIngestionSource(c *gin.Context) {
+	id, ok := ingestionSourceID(c)
+	if !ok {
+		return
+	}
+
+	source, err := h.svc.GetIngestionSource(c.Request.Context(), id)
+	if err != nil {
+		respondError(c, err, "Failed to fetch ingestion source")
+		return
+	}
+
+	c.JSON(http.StatusOK, source)
+}
+
+// UpdateIngestionSource handles PUT /admin/ingestion/sources/:id, replacing a
+// source's configuration
+func (h *AdminHandler) UpdateIngestionSource(c *gin.Context) {
+	id, ok := ingestionSourceID(c)
+	if !ok {
+		return
+	}
+	var input services.IngestionSourceInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	source, err := h.svc.UpdateIngestionSource(c.Request.Context(), id, input)
+	if err != nil {
+		respondError(c, err, "Failed to update ingestion source")
+		return
+	}
+
+	c.JSON(http.StatusOK, source)
+}
+
+// DeleteIngestionSource handles DELETE /admin/ingestion/sources/:id
+func (h *AdminHandler) DeleteIngestionSource(c *gin.Context) {
+	id, ok := ingestionSourceID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.svc.DeleteIngestionSource(c.Request.Context(), id); err != nil {
+		respondError(c, err, "Failed to delete ingestion source")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// RunIngestionSource handles POST /admin/ingestion/sources/:id/run, running a
+// source right away. A failed run is reported in the source's last_status.
+func (h *AdminHandler) RunIngestionSource(c *gin.Context) {
+	id, ok := ingestionSourceID(c)
+	if !ok {
+		return
+	}
+
+	source, err := h.svc.RunIngestionSource(c.Request.Context(), id)
+	if err != nil {
+		respondError(c, err, "Failed to run ingestion source")
+		return
+	}
+
+	c.JSON(http.StatusOK, source)
+}
+
+// ingestionSourceID parses the id parameter, writing the error response when
+// it is invalid
+func ingestionSourceID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ingestion source id"})
+		return 0, false
+	}
+	return uint(id), true
+}
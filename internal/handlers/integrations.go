@@ -0,0 +1,315 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mahigadamsetty/Inshorts-task/internal/apperr"
+	"github.com/mahigadamsetty/Inshorts-task/internal/config"
+	"github.com/mahigadamsetty/Inshorts-task/internal/models"
+	"github.com/mahigadamsetty/Inshorts-task/internal/services"
+	"github.com/mahigadamsetty/Inshorts-task/internal/tenant"
+)
+
+const (
+	integrationLimit        = 5   // Articles answered per command
+	integrationSummaryChars = 280 // Longest summary shown per article
+	integrationUsage        = "Ask for news in plain words, like `trending near Mumbai` or `technology from last week`."
+	integrationFailed       = "Sorry, the news search failed. Try again in a moment."
+)
+
+// Discord interaction and response types
+const (
+	discordPing               = 1
+	discordApplicationCommand = 2
+	discordPong               = 1
+	discordChannelMessage     = 4
+)
+
+// IntegrationHandler answers the slash commands of the Slack and Discord apps
+// by running their text through the /query pipeline. Chat apps send no API
+// key, so commands are answered as the integrations tenant.
+type IntegrationHandler struct {
+	news   *NewsHandler
+	config *config.Config
+	tenant *tenant.Tenant
+}
+
+func NewIntegrationHandler(cfg *config.Config, news *NewsHandler, t *tenant.Tenant) *IntegrationHandler {
+	return &IntegrationHandler{news: news, config: cfg, tenant: t}
+}
+
+// slackBlock is a Block Kit layout block of a Slack message
+type slackBlock struct {
+	Type      string      `json:"type"`
+	Text      *slackText  `json:"text,omitempty"`
+	Elements  []slackText `json:"elements,omitempty"`
+	Accessory *slackImage `json:"accessory,omitempty"`
+}
+
+type slackText struct {
+	Type string `json:"type"` // mrkdwn or plain_text
+	Text string `json:"text"`
+}
+
+type slackImage struct {
+	Type     string `json:"type"` // image
+	ImageURL string `json:"image_url"`
+	AltText  string `json:"alt_text"`
+}
+
+// SlackMessage is the response to a Slack slash command. Text is the
+// fallback shown in notifications.
+type SlackMessage struct {
+	ResponseType string       `json:"response_type"` // ephemeral, only shown to the user who asked
+	Text         string       `json:"text"`
+	Blocks       []slackBlock `json:"blocks,omitempty"`
+}
+
+// discordInteraction is the part of a Discord interaction commands are read from
+type discordInteraction struct {
+	Type      int    `json:"type"`
+	ChannelID string `json:"channel_id"`
+	Data      struct {
+		Name    string `json:"name"`
+		Options []struct {
+			Name  string      `json:"name"`
+			Value interface{} `json:"value"`
+		} `json:"options"`
+	} `json:"data"`
+	Member *struct {
+		User discordUser `json:"user"`
+	} `json:"member"` // Set in servers
+	User *discordUser `json:"user"` // Set in direct messages
+}
+
+type discordUser struct {
+	ID string `json:"id"`
+}
+
+type discordEmbed struct {
+	Title       string              `json:"title"`
+	URL         string              `json:"url,omitempty"`
+	Description string              `json:"description,omitempty"`
+	Timestamp   string              `json:"timestamp,omitempty"`
+	Footer      *discordEmbedFooter `json:"footer,omitempty"`
+	Thumbnail   *discordEmbedImage  `json:"thumbnail,omitempty"`
+}
+
+type discordEmbedFooter struct {
+	Text string `json:"text"`
+}
+
+type discordEmbedImage struct {
+	URL string `json:"url"`
+}
+
+// DiscordResponse is the response to a Discord interaction
+type DiscordResponse struct {
+	Type int                  `json:"type"`
+	Data *DiscordResponseData `json:"data,omitempty"`
+}
+
+type DiscordResponseData struct {
+	Content         string         `json:"content"`
+	Embeds          []discordEmbed `json:"embeds,omitempty"`
+	AllowedMentions gin.H          `json:"allowed_mentions"`
+}
+
+// SlackCommand handles POST /integrations/slack/command, answering a slash
+// command's text, like "trending near Mumbai", with the matching articles.
+// Follow-ups from the same user in the same channel refine the last command.
+func (h *IntegrationHandler) SlackCommand(c *gin.Context) {
+	// Slack checks the endpoint's certificate with requests carrying no command
+	if c.PostForm("ssl_check") == "1" {
+		c.Status(http.StatusOK)
+		return
+	}
+
+	text := strings.TrimSpace(c.PostForm("text"))
+	if text == "" || strings.EqualFold(text, "help") {
+		c.JSON(http.StatusOK, SlackMessage{ResponseType: "ephemeral", Text: integrationUsage})
+		return
+	}
+	if !h.useTenant(c) {
+		c.JSON(http.StatusOK, SlackMessage{ResponseType: "ephemeral", Text: "Too many requests. Try again in a minute."})
+		return
+	}
+
+	sessionID := "slack:" + c.PostForm("team_id") + ":" + c.PostForm("channel_id") + ":" + c.PostForm("user_id")
+	response, err := h.news.runQuery(c, text, integrationLimit, sessionID)
+	if err != nil {
+		c.JSON(http.StatusOK, SlackMessage{ResponseType: "ephemeral", Text: integrationError(err)})
+		return
+	}
+	c.JSON(http.StatusOK, h.slackMessage(text, response))
+}
+
+// DiscordInteraction handles POST /integrations/discord/interactions,
+// answering Discord's endpoint checks and the app's slash command, whose text
+// is its first string option
+func (h *IntegrationHandler) DiscordInteraction(c *gin.Context) {
+	var interaction discordInteraction
+	if err := c.ShouldBindJSON(&interaction); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid interaction"})
+		return
+	}
+
+	switch interaction.Type {
+	case discordPing:
+		c.JSON(http.StatusOK, DiscordResponse{Type: discordPong})
+		return
+	case discordApplicationCommand:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unsupported interaction type"})
+		return
+	}
+
+	var text string
+	for _, option := range interaction.Data.Options {
+		if value, ok := option.Value.(string); ok {
+			text = strings.TrimSpace(value)
+			break
+		}
+	}
+	if text == "" || strings.EqualFold(text, "help") {
+		c.JSON(http.StatusOK, discordMessage(integrationUsage, nil))
+		return
+	}
+	if !h.useTenant(c) {
+		c.JSON(http.StatusOK, discordMessage("Too many requests. Try again in a minute.", nil))
+		return
+	}
+
+	userID := ""
+	if interaction.Member != nil {
+		userID = interaction.Member.User.ID
+	} else if interaction.User != nil {
+		userID = interaction.User.ID
+	}
+	response, err := h.news.runQuery(c, text, integrationLimit, "discord:"+interaction.ChannelID+":"+userID)
+	if err != nil {
+		c.JSON(http.StatusOK, discordMessage(integrationError(err), nil))
+		return
+	}
+
+	embeds := make([]discordEmbed, 0, len(response.Articles))
+	for _, article := range response.Articles {
+		embed := discordEmbed{
+			Title:       services.ShortenText(article.Title, 256),
+			URL:         h.articleURL(article),
+			Description: services.ShortenText(articleBlurb(article), integrationSummaryChars),
+			Timestamp:   article.PublicationDate.UTC().Format(time.RFC3339),
+			Footer:      &discordEmbedFooter{Text: article.SourceName},
+		}
+		if article.ImageURL != "" {
+			embed.Thumbnail = &discordEmbedImage{URL: article.ImageURL}
+		}
+		embeds = append(embeds, embed)
+	}
+	c.JSON(http.StatusOK, discordMessage(resultsHeading(text, response, "**"), embeds))
+}
+
+// useTenant answers the request as the integrations tenant, reporting false
+// when the tenant is over its rate limit
+func (h *IntegrationHandler) useTenant(c *gin.Context) bool {
+	if !h.tenant.AllowRequest() {
+		return false
+	}
+	c.Request = c.Request.WithContext(tenant.NewContext(c.Request.Context(), h.tenant))
+	return true
+}
+
+// slackMessage formats the articles answering a command as a section per
+// article, with its source and publication date underneath
+func (h *IntegrationHandler) slackMessage(text string, response *Response) SlackMessage {
+	heading := resultsHeading(slackEscape(text), response, "*")
+	message := SlackMessage{
+		ResponseType: "ephemeral",
+		Text:         heading,
+		Blocks:       []slackBlock{{Type: "section", Text: &slackText{Type: "mrkdwn", Text: heading}}},
+	}
+	for _, article := range response.Articles {
+		body := fmt.Sprintf("*<%s|%s>*", slackEscape(h.articleURL(article)), slackEscape(article.Title))
+		if blurb := articleBlurb(article); blurb != "" {
+			body += "\n" + slackEscape(services.ShortenText(blurb, integrationSummaryChars))
+		}
+		section := slackBlock{Type: "section", Text: &slackText{Type: "mrkdwn", Text: body}}
+		if article.ImageURL != "" {
+			section.Accessory = &slackImage{Type: "image", ImageURL: article.ImageURL, AltText: article.Title}
+		}
+		message.Blocks = append(message.Blocks,
+			slackBlock{Type: "divider"},
+			section,
+			slackBlock{Type: "context", Elements: []slackText{{
+				Type: "mrkdwn",
+				Text: slackEscape(article.SourceName) + " · " + article.PublicationDate.UTC().Format("Jan 2, 2006"),
+			}}},
+		)
+	}
+	return message
+}
+
+// articleURL links an article's page on the public site, or the original
+// when there is none
+func (h *IntegrationHandler) articleURL(article models.Article) string {
+	if url := h.config.ArticlePageURL(article.ID); url != "" {
+		return url
+	}
+	return article.URL
+}
+
+// resultsHeading describes the articles answering a command, with the
+// constraints dropped to find them. bold is the chat app's bold marker.
+func resultsHeading(text string, response *Response, bold string) string {
+	if len(response.Articles) == 0 {
+		return "No articles found for " + bold + text + bold + "."
+	}
+	heading := fmt.Sprintf("%s%d articles%s for %s", bold, len(response.Articles), bold, text)
+	if len(response.Articles) == 1 {
+		heading = fmt.Sprintf("%s1 article%s for %s", bold, bold, text)
+	}
+	if len(response.Meta.Relaxations) > 0 {
+		heading += " (relaxed: " + strings.Join(response.Meta.Relaxations, ", ") + ")"
+	}
+	return heading
+}
+
+// articleBlurb is the summary shown under an article's title, or its
+// description until it has one
+func articleBlurb(article models.Article) string {
+	blurb := article.LLMSummary
+	if blurb == "" {
+		blurb = article.Description
+	}
+	return strings.Join(strings.Fields(blurb), " ")
+}
+
+// discordMessage answers an interaction with a message that mentions no one
+func discordMessage(content string, embeds []discordEmbed) DiscordResponse {
+	return DiscordResponse{Type: discordChannelMessage, Data: &DiscordResponseData{
+		Content:         content,
+		Embeds:          embeds,
+		AllowedMentions: gin.H{"parse": []string{}},
+	}}
+}
+
+// integrationError is the reply to a command that failed. Invalid filter
+// errors carry messages meant for the user; anything else is logged.
+func integrationError(err error) string {
+	if errors.Is(err, apperr.ErrInvalidFilter) {
+		return err.Error()
+	}
+	log.Printf("Failed to answer chat command: %v", err)
+	return integrationFailed
+}
+
+// slackEscape escapes the characters Slack reads as markup in message text
+func slackEscape(text string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(text)
+}
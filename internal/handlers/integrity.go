@@ -0,0 +1,30 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mahigadamsetty/Inshorts-task/internal/services"
+)
+
+// CheckIntegrity handles POST /admin/integrity/check, running the integrity
+// checks now and returning their report
+func (h *AdminHandler) CheckIntegrity(c *gin.Context) {
+	report, err := h.svc.CheckIntegrity(c.Request.Context())
+	if err != nil {
+		respondError(c, err, "Failed to check integrity")
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+// GetIntegrityReport handles GET /admin/integrity, returning the report of
+// the latest integrity check
+func (h *AdminHandler) GetIntegrityReport(c *gin.Context) {
+	report, err := services.LastIntegrityReport()
+	if err != nil {
+		respondError(c, err, "Failed to fetch integrity report")
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
@@ -0,0 +1,355 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mahigadamsetty/Inshorts-task/internal/config"
+	"github.com/mahigadamsetty/Inshorts-task/internal/geocode"
+	"github.com/mahigadamsetty/Inshorts-task/internal/tenant"
+)
+
+// mcpProtocolVersions are the Model Context Protocol revisions the server
+// speaks, newest first
+var mcpProtocolVersions = []string{"2025-06-18", "2025-03-26", "2024-11-05"}
+
+// JSON-RPC error codes
+const (
+	rpcParseError     = -32700
+	rpcInvalidRequest = -32600
+	rpcMethodNotFound = -32601
+	rpcInvalidParams  = -32602
+)
+
+const mcpMaxLimit = 20 // Most articles a tool call returns
+
+// mcpTool is a tool listed to MCP clients, answered by the news route at route
+type mcpTool struct {
+	Name        string `json:"name"`
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	InputSchema gin.H  `json:"inputSchema"`
+	route       string
+}
+
+var (
+	mcpLimitSchema = gin.H{"type": "integer", "minimum": 1, "maximum": mcpMaxLimit, "description": "Articles to return (default 5)"}
+	mcpPlaceSchema = gin.H{"type": "string", "description": "A city or region, like Mumbai, instead of lat and lon"}
+)
+
+var mcpTools = []mcpTool{
+	{
+		Name:        "search_news",
+		Title:       "Search news",
+		Description: "Search recent news articles by keywords in their title and description, ranked by relevance and freshness. Returns articles with their summaries.",
+		InputSchema: gin.H{
+			"type": "object",
+			"properties": gin.H{
+				"query":  gin.H{"type": "string", "description": "Keywords to search for"},
+				"limit":  mcpLimitSchema,
+				"cursor": gin.H{"type": "string", "description": "meta.next_cursor of the previous call, for the next page"},
+			},
+			"required": []string{"query"},
+		},
+		route: "/api/v1/news/search",
+	},
+	{
+		Name:        "nearby_news",
+		Title:       "News near a place",
+		Description: "Find news articles about places within a radius of a location, nearest first. Give either lat and lon or place.",
+		InputSchema: gin.H{
+			"type": "object",
+			"properties": gin.H{
+				"lat":    gin.H{"type": "number", "minimum": -90, "maximum": 90},
+				"lon":    gin.H{"type": "number", "minimum": -180, "maximum": 180},
+				"place":  mcpPlaceSchema,
+				"radius": gin.H{"type": "number", "exclusiveMinimum": 0, "description": "Radius in km (default 10, or the place's size)"},
+				"limit":  mcpLimitSchema,
+			},
+		},
+		route: "/api/v1/news/nearby",
+	},
+	{
+		Name:        "trending_news",
+		Title:       "Trending news",
+		Description: "List the articles trending with readers around a location right now. Give either lat and lon or place.",
+		InputSchema: gin.H{
+			"type": "object",
+			"properties": gin.H{
+				"lat":   gin.H{"type": "number", "minimum": -90, "maximum": 90},
+				"lon":   gin.H{"type": "number", "minimum": -180, "maximum": 180},
+				"place": mcpPlaceSchema,
+				"limit": mcpLimitSchema,
+			},
+		},
+		route: "/api/v1/news/trending",
+	},
+}
+
+type rpcRequest struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"` // Absent in notifications
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params"`
+}
+
+type rpcResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  interface{}     `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// mcpContent is a block of a tool result
+type mcpContent struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+type mcpToolResult struct {
+	Content           []mcpContent    `json:"content"`
+	StructuredContent json.RawMessage `json:"structuredContent,omitempty"`
+	IsError           bool            `json:"isError"`
+}
+
+// MCPHandler serves the news tools to AI assistants over the Model Context
+// Protocol's HTTP transport. Calls are answered by the news routes behind the
+// tools, as the caller's tenant, within per-tool rate limits.
+type MCPHandler struct {
+	tools   http.Handler
+	limits  map[string]int
+	limiter *toolLimiter
+}
+
+func NewMCPHandler(cfg *config.Config) *MCPHandler {
+	return &MCPHandler{limits: cfg.MCPToolLimits(), limiter: &toolLimiter{windows: map[string]*toolWindow{}}}
+}
+
+// UseTools sets the routes that answer tool calls
+func (h *MCPHandler) UseTools(routes http.Handler) {
+	h.tools = routes
+}
+
+// Serve handles POST /mcp, answering one JSON-RPC request. Notifications and
+// responses from the client are acknowledged without a body.
+func (h *MCPHandler) Serve(c *gin.Context) {
+	var request rpcRequest
+	if err := json.NewDecoder(c.Request.Body).Decode(&request); err != nil {
+		failure := &rpcError{Code: rpcParseError, Message: "Parse error"}
+		var typeErr *json.UnmarshalTypeError
+		if errors.As(err, &typeErr) {
+			failure = &rpcError{Code: rpcInvalidRequest, Message: "Send one JSON-RPC request per POST"}
+		}
+		c.JSON(http.StatusBadRequest, rpcResponse{JSONRPC: "2.0", ID: json.RawMessage("null"), Error: failure})
+		return
+	}
+	if len(request.ID) == 0 || request.Method == "" {
+		c.Status(http.StatusAccepted)
+		return
+	}
+	if request.JSONRPC != "2.0" {
+		c.JSON(http.StatusOK, rpcResponse{JSONRPC: "2.0", ID: request.ID, Error: &rpcError{Code: rpcInvalidRequest, Message: "jsonrpc must be 2.0"}})
+		return
+	}
+
+	response := rpcResponse{JSONRPC: "2.0", ID: request.ID}
+	switch request.Method {
+	case "initialize":
+		response.Result = h.initialize(request.Params)
+	case "ping":
+		response.Result = gin.H{}
+	case "tools/list":
+		response.Result = gin.H{"tools": mcpTools}
+	case "tools/call":
+		response.Result, response.Error = h.callTool(c, request.Params)
+	default:
+		response.Error = &rpcError{Code: rpcMethodNotFound, Message: "Method not found: " + request.Method}
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// Stream handles GET /mcp. The server sends no messages of its own, so it
+// offers no event stream.
+func (h *MCPHandler) Stream(c *gin.Context) {
+	c.Header("Allow", "POST")
+	c.JSON(http.StatusMethodNotAllowed, gin.H{"error": "Send JSON-RPC requests with POST"})
+}
+
+// initialize agrees on the client's protocol revision when the server speaks
+// it, or the newest the server does
+func (h *MCPHandler) initialize(params json.RawMessage) gin.H {
+	var request struct {
+		ProtocolVersion string `json:"protocolVersion"`
+	}
+	json.Unmarshal(params, &request)
+	version := mcpProtocolVersions[0]
+	for _, supported := range mcpProtocolVersions {
+		if supported == request.ProtocolVersion {
+			version = supported
+		}
+	}
+	return gin.H{
+		"protocolVersion": version,
+		"capabilities":    gin.H{"tools": gin.H{"listChanged": false}},
+		"serverInfo":      gin.H{"name": "inshorts-news", "title": "Inshorts News", "version": "1.0.0"},
+		"instructions":    "Use search_news for topics and keywords, nearby_news for news about a place and trending_news for what readers around a place are reading now.",
+	}
+}
+
+// callTool runs a tool through its route. Failures of the call itself, like
+// invalid arguments, are returned as results flagged isError so the model
+// can correct them; unknown tools are protocol errors.
+func (h *MCPHandler) callTool(c *gin.Context, params json.RawMessage) (interface{}, *rpcError) {
+	var call struct {
+		Name      string                 `json:"name"`
+		Arguments map[string]interface{} `json:"arguments"`
+	}
+	if err := json.Unmarshal(params, &call); err != nil {
+		return nil, &rpcError{Code: rpcInvalidParams, Message: "Invalid params"}
+	}
+	var tool *mcpTool
+	for i := range mcpTools {
+		if mcpTools[i].Name == call.Name {
+			tool = &mcpTools[i]
+		}
+	}
+	if tool == nil {
+		return nil, &rpcError{Code: rpcInvalidParams, Message: "Unknown tool: " + call.Name}
+	}
+
+	if limit, ok := h.limits[tool.Name]; ok {
+		tenantID := tenant.DefaultID
+		if t, ok := tenant.FromContext(c.Request.Context()); ok {
+			tenantID = t.ID
+		}
+		if !h.limiter.allow(tenantID+"/"+tool.Name, limit) {
+			return toolError(fmt.Sprintf("Rate limit of %d %s calls per minute exceeded, try again later", limit, tool.Name)), nil
+		}
+	}
+
+	query, err := toolQuery(tool.Name, call.Arguments)
+	if err != nil {
+		return toolError(err.Error()), nil
+	}
+	req, err := http.NewRequestWithContext(c.Request.Context(), http.MethodGet, tool.route+"?"+query.Encode(), nil)
+	if err != nil {
+		return nil, &rpcError{Code: rpcInvalidParams, Message: "Invalid arguments"}
+	}
+	req.RemoteAddr = c.Request.RemoteAddr
+	req.Header.Set("User-Agent", c.Request.UserAgent())
+	if clientID := c.GetHeader("X-Client-ID"); clientID != "" {
+		req.Header.Set("X-Client-ID", clientID)
+	}
+
+	recorder := &toolRecorder{header: http.Header{}, status: http.StatusOK}
+	h.tools.ServeHTTP(recorder, req)
+	body := recorder.body.Bytes()
+	if recorder.status != http.StatusOK {
+		var failure struct {
+			Error string `json:"error"`
+		}
+		if json.Unmarshal(body, &failure) != nil || failure.Error == "" {
+			failure.Error = http.StatusText(recorder.status)
+		}
+		return toolError(failure.Error), nil
+	}
+	return mcpToolResult{Content: []mcpContent{{Type: "text", Text: string(body)}}, StructuredContent: body}, nil
+}
+
+// toolQuery turns a tool call's arguments into its route's query string,
+// resolving a place to its coordinates
+func toolQuery(name string, arguments map[string]interface{}) (url.Values, error) {
+	query := url.Values{}
+	for key, value := range arguments {
+		switch v := value.(type) {
+		case string:
+			query.Set(key, v)
+		case float64:
+			query.Set(key, strconv.FormatFloat(v, 'f', -1, 64))
+		case bool:
+			query.Set(key, strconv.FormatBool(v))
+		case nil:
+		default:
+			return nil, fmt.Errorf("%s must be a string, number or boolean", key)
+		}
+	}
+
+	if limit, err := strconv.Atoi(query.Get("limit")); err == nil && limit > mcpMaxLimit {
+		query.Set("limit", strconv.Itoa(mcpMaxLimit))
+	}
+	if placeName := query.Get("place"); placeName != "" {
+		query.Del("place")
+		place, ok := geocode.Resolve(placeName)
+		if !ok {
+			return nil, fmt.Errorf("unknown place %q, give lat and lon instead", placeName)
+		}
+		query.Set("lat", strconv.FormatFloat(place.Latitude, 'f', -1, 64))
+		query.Set("lon", strconv.FormatFloat(place.Longitude, 'f', -1, 64))
+		if name == "nearby_news" && query.Get("radius") == "" {
+			query.Set("radius", strconv.FormatFloat(place.RadiusKm, 'f', -1, 64))
+		}
+	}
+	return query, nil
+}
+
+// toolError is a failed tool call's result
+func toolError(message string) mcpToolResult {
+	return mcpToolResult{Content: []mcpContent{{Type: "text", Text: message}}, IsError: true}
+}
+
+// toolRecorder captures the response of the route answering a tool call
+type toolRecorder struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (r *toolRecorder) Header() http.Header         { return r.header }
+func (r *toolRecorder) Write(b []byte) (int, error) { return r.body.Write(b) }
+func (r *toolRecorder) WriteHeader(status int)      { r.status = status }
+
+// toolLimiter counts calls per key in fixed one-minute windows, like tenant
+// rate limits
+type toolLimiter struct {
+	mu      sync.Mutex
+	windows map[string]*toolWindow
+}
+
+type toolWindow struct {
+	start time.Time
+	count int
+}
+
+// allow counts a call against a key's per-minute limit; 0 means unlimited
+func (l *toolLimiter) allow(key string, limit int) bool {
+	if limit <= 0 {
+		return true
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	window, ok := l.windows[key]
+	if !ok || now.Sub(window.start) >= time.Minute {
+		window = &toolWindow{start: now}
+		l.windows[key] = window
+	}
+	if window.count >= limit {
+		return false
+	}
+	window.count++
+	return true
+}
@@ -1,32 +1,51 @@
 package handlers
 
 import (
+	"context"
 	"fmt"
 	"log"
+	"math"
 	"net/http"
-	"net/url"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
-	readability "github.com/go-shiori/go-readability"
+	"github.com/mahigadamsetty/Inshorts-task/internal/apperr"
+	"github.com/mahigadamsetty/Inshorts-task/internal/budget"
 	"github.com/mahigadamsetty/Inshorts-task/internal/config"
-	"github.com/mahigadamsetty/Inshorts-task/internal/db"
+	"github.com/mahigadamsetty/Inshorts-task/internal/degradation"
+	"github.com/mahigadamsetty/Inshorts-task/internal/faults"
+	"github.com/mahigadamsetty/Inshorts-task/internal/geocode"
+	"github.com/mahigadamsetty/Inshorts-task/internal/geoip"
 	"github.com/mahigadamsetty/Inshorts-task/internal/llm"
 	"github.com/mahigadamsetty/Inshorts-task/internal/models"
+	"github.com/mahigadamsetty/Inshorts-task/internal/search"
 	"github.com/mahigadamsetty/Inshorts-task/internal/services"
+	"github.com/mahigadamsetty/Inshorts-task/internal/tenant"
+	"github.com/mahigadamsetty/Inshorts-task/internal/utils"
+	"gorm.io/gorm"
 )
 
 type NewsHandler struct {
-	llmClient *llm.Client
-	config    *config.Config
+	db             *gorm.DB // Answers the queries that list articles directly
+	svc            *services.Services
+	llmClient      llm.Provider
+	fallbackClient llm.Provider // Heuristic-only client used once a tenant's LLM budget is spent
+	summarizer     *services.Summarizer
+	config         *config.Config
+	geoIP          *geoip.DB // Locates requests without coordinates; nil when GEOIP_FALLBACK is off
 }
 
-func NewNewsHandler(cfg *config.Config) *NewsHandler {
+func NewNewsHandler(cfg *config.Config, database *gorm.DB, svc *services.Services, llmClient llm.Provider, summarizer *services.Summarizer, geoIP *geoip.DB) *NewsHandler {
 	return &NewsHandler{
-		llmClient: llm.NewClient(cfg.OpenAIAPIKey, cfg.LLMModel),
-		config:    cfg,
+		db:             database,
+		svc:            svc,
+		llmClient:      llmClient,
+		summarizer:     summarizer,
+		fallbackClient: llm.NewClient("", nil, 0),
+		config:         cfg,
+		geoIP:          geoIP,
 	}
 }
 
@@ -36,10 +55,19 @@ type Response struct {
 }
 
 type Meta struct {
-	Count    int    `json:"count"`
-	Limit    int    `json:"limit"`
-	Endpoint string `json:"endpoint"`
-	Query    string `json:"query,omitempty"`
+	Count       int               `json:"count"`
+	Limit       int               `json:"limit"`
+	Endpoint    string            `json:"endpoint"`
+	Query       string            `json:"query,omitempty"`
+	Location    *geocode.Place    `json:"location,omitempty"`       // Place resolved from a /query request, or located from the client's IP
+	DateRange   *llm.DateRange    `json:"date_range,omitempty"`     // Publication dates understood from a /query request
+	SessionID   string            `json:"session_id,omitempty"`     // Pass back on /query to ask follow-up questions
+	Relaxations []string          `json:"relaxations,omitempty"`    // Constraints /query dropped or widened to find results, in order
+	NextCursor  string            `json:"next_cursor,omitempty"`    // Pass as cursor to get the next /search page
+	Degradation map[string]string `json:"degradation,omitempty"`    // Subsystems that ran in fallback mode
+	Partial     bool              `json:"partial,omitempty"`        // Stages were skipped to answer within the response-time budget
+	Skipped     []string          `json:"skipped_stages,omitempty"` // The stages skipped, in order
+	Seed        *int64            `json:"seed,omitempty"`           // Pass back on /random and /for-you to draw the same feed
 }
 
 // GetByCategory handles /category endpoint
@@ -57,31 +85,47 @@ func (h *NewsHandler) GetByCategory(c *gin.Context) {
 		return
 	}
 
-	database := db.GetDB()
+	filter, err := parseArticleFilter(c)
+	if err != nil {
+		respondError(c, err, "Invalid filter")
+		return
+	}
+	database := h.db.WithContext(c.Request.Context())
 	var articles []models.Article
 
-	// Search for articles containing the category (case-insensitive)
+	// Search for articles in the category or its descendants (case-insensitive)
 	err = database.
-		Where("LOWER(category) LIKE ?", "%"+strings.ToLower(category)+"%").
+		Scopes(filter.Scope).
+		Where(h.svc.CategoryMatch(c.Request.Context(), category)).
 		Order("publication_date DESC").
-		Limit(limit).
+		Order("id").
+		Limit(limit * 3). // Get more to rank properly
 		Find(&articles).Error
 
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch articles"})
+		respondError(c, err, "Failed to fetch articles")
 		return
 	}
 
+	h.svc.AttachSourceMeta(c.Request.Context(), articles)
+
+	// Rank by relevance blended with freshness and source reliability
+	articles = h.rankByFreshness(c, "category", category, articles, limit)
+
+	h.recordImpressions(c, "category", articles)
+	applyExplain(c, articles)
+
 	// Enrich with summaries
-	h.enrichWithSummaries(articles)
+	h.enrichWithSummaries(c, articles)
 
-	c.JSON(http.StatusOK, Response{
+	h.respondWithCoordinates(c, http.StatusOK, &Response{
 		Articles: articles,
 		Meta: Meta{
-			Count:    len(articles),
-			Limit:    limit,
-			Endpoint: "category",
-			Query:    category,
+			Count:       len(articles),
+			Limit:       limit,
+			Endpoint:    "category",
+			Query:       category,
+			Degradation: degradation.Modes(c.Request.Context()),
 		},
 	})
 }
@@ -101,30 +145,46 @@ func (h *NewsHandler) GetBySource(c *gin.Context) {
 		return
 	}
 
-	database := db.GetDB()
+	filter, err := parseArticleFilter(c)
+	if err != nil {
+		respondError(c, err, "Invalid filter")
+		return
+	}
+	database := h.db.WithContext(c.Request.Context())
 	var articles []models.Article
 
 	err = database.
+		Scopes(filter.Scope).
 		Where("LOWER(source_name) = ?", strings.ToLower(source)).
 		Order("publication_date DESC").
-		Limit(limit).
+		Order("id").
+		Limit(limit * 3). // Get more to rank properly
 		Find(&articles).Error
 
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch articles"})
+		respondError(c, err, "Failed to fetch articles")
 		return
 	}
 
+	h.svc.AttachSourceMeta(c.Request.Context(), articles)
+
+	// Rank by relevance blended with freshness and source reliability
+	articles = h.rankByFreshness(c, "source", source, articles, limit)
+
+	h.recordImpressions(c, "source", articles)
+	applyExplain(c, articles)
+
 	// Enrich with summaries
-	h.enrichWithSummaries(articles)
+	h.enrichWithSummaries(c, articles)
 
-	c.JSON(http.StatusOK, Response{
+	h.respondWithCoordinates(c, http.StatusOK, &Response{
 		Articles: articles,
 		Meta: Meta{
-			Count:    len(articles),
-			Limit:    limit,
-			Endpoint: "source",
-			Query:    source,
+			Count:       len(articles),
+			Limit:       limit,
+			Endpoint:    "source",
+			Query:       source,
+			Degradation: degradation.Modes(c.Request.Context()),
 		},
 	})
 }
@@ -132,6 +192,7 @@ func (h *NewsHandler) GetBySource(c *gin.Context) {
 // GetByScore handles /score endpoint
 func (h *NewsHandler) GetByScore(c *gin.Context) {
 	minStr := c.Query("min")
+	by := c.DefaultQuery("by", "original")
 	limitStr := c.DefaultQuery("limit", "5")
 
 	limit, err := strconv.Atoi(limitStr)
@@ -148,35 +209,71 @@ func (h *NewsHandler) GetByScore(c *gin.Context) {
 		}
 	}
 
-	database := db.GetDB()
+	// Rank by the imported score or the recalibrated one
+	scoreColumn := "relevance_score"
+	switch by {
+	case "original":
+	case "computed":
+		scoreColumn = "computed_score"
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "by must be original or computed"})
+		return
+	}
+
+	filter, err := parseArticleFilter(c)
+	if err != nil {
+		respondError(c, err, "Invalid filter")
+		return
+	}
+	database := h.db.WithContext(c.Request.Context())
 	var articles []models.Article
 
 	err = database.
-		Where("relevance_score >= ?", minScore).
-		Order("relevance_score DESC").
-		Limit(limit).
+		Scopes(filter.Scope).
+		Where(scoreColumn+" >= ?", minScore).
+		Order(scoreColumn + " DESC").
+		Order("publication_date DESC").
+		Order("id").
+		Limit(limit * 3). // Get more so the results can be diversified
 		Find(&articles).Error
 
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch articles"})
+		respondError(c, err, "Failed to fetch articles")
 		return
 	}
 
+	h.svc.AttachSourceMeta(c.Request.Context(), articles)
+
+	// Attach score explanations (order already comes from the database)
+	if by == "computed" {
+		articles = services.RankByComputedScore(articles)
+	} else {
+		articles = services.RankByRelevanceScore(articles)
+	}
+	articles = services.Diversify(articles, h.diversityLimits(c))
+	if len(articles) > limit {
+		articles = articles[:limit]
+	}
+	h.recordImpressions(c, "score", articles)
+	applyExplain(c, articles)
+
 	// Enrich with summaries
-	h.enrichWithSummaries(articles)
+	h.enrichWithSummaries(c, articles)
 
-	c.JSON(http.StatusOK, Response{
+	h.respondWithCoordinates(c, http.StatusOK, &Response{
 		Articles: articles,
 		Meta: Meta{
-			Count:    len(articles),
-			Limit:    limit,
-			Endpoint: "score",
-			Query:    minStr,
+			Count:       len(articles),
+			Limit:       limit,
+			Endpoint:    "score",
+			Query:       minStr,
+			Degradation: degradation.Modes(c.Request.Context()),
 		},
 	})
 }
 
-// Search handles /search endpoint
+// Search handles /search endpoint. Results are paginated with the opaque
+// cursor returned as meta.next_cursor.
 func (h *NewsHandler) Search(c *gin.Context) {
 	query := c.Query("query")
 	limitStr := c.DefaultQuery("limit", "5")
@@ -191,54 +288,96 @@ func (h *NewsHandler) Search(c *gin.Context) {
 		return
 	}
 
-	database := db.GetDB()
-	var articles []models.Article
-
-	// Search in title and description
-	searchWords := strings.Split(strings.ToLower(query), " ")
-	filteredWords := filterStopWords(searchWords) // Filter stop words
-	queryBuilder := database.Model(&models.Article{})
-
-	if len(filteredWords) == 0 {
-		filteredWords = searchWords // Fallback to original words if all are stop words
+	// Later pages rank the articles that existed when the first page was served
+	boosts := parseSearchBoosts(c)
+	diversity := h.diversityLimits(c)
+	cursor, err := services.DecodeSearchCursor(c.Query("cursor"))
+	if err != nil || (cursor != nil && (cursor.Query != query || cursor.Boosts != boosts || cursor.Diversity != diversity)) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid cursor"})
+		return
 	}
-
-	for _, word := range filteredWords {
-		if word != "" {
-			searchPattern := "%" + word + "%"
-			queryBuilder = queryBuilder.Or("LOWER(title) LIKE ?", searchPattern).Or("LOWER(description) LIKE ?", searchPattern)
-		}
+	snapshot := time.Now()
+	if cursor != nil {
+		snapshot = cursor.Snapshot
 	}
 
-	err = queryBuilder.
-		Limit(limit * 3). // Get more to rank properly
-		Find(&articles).Error
+	filter, err := parseArticleFilter(c)
+	if err != nil {
+		respondError(c, err, "Invalid filter")
+		return
+	}
+	database := h.db.WithContext(c.Request.Context())
+	var articles []models.Article
 
+	// Search in title and description
+	queryBuilder := database.Model(&models.Article{}).
+		Scopes(filter.Scope).
+		Where(keywordMatch(c.Request.Context(), h.svc, database, query, services.SearchScope{Filter: filter})).
+		Where("created_at <= ?", snapshot)
+
+	err = h.svc.CheckCandidates(queryBuilder, "use more specific terms or add a category, source or date filter")
+	if err == nil {
+		err = queryBuilder.Find(&articles).Error
+	}
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch articles"})
+		respondError(c, err, "Failed to fetch articles")
 		return
 	}
 
-	// Rank by search relevance
-	articles = services.RankBySearchRelevance(articles, query)
+	h.svc.AttachSourceMeta(c.Request.Context(), articles)
+
+	// Rank every match by search relevance as of the snapshot, then cut the page
+	profile := h.rankingProfile()
+	profile.AsOf = snapshot
+	profile.Boosts = &boosts
+	matches := articles
+	relevance := h.svc.TextRelevances(c.Request.Context(), query, matches)
+	articles = services.RankBySearchRelevance(matches, query, profile, relevance)
+	articles = services.Diversify(articles, diversity)
+	articles, next := services.SearchPage(articles, cursor, limit, services.SearchCursor{
+		Snapshot:  snapshot,
+		Query:     query,
+		Boosts:    boosts,
+		Diversity: diversity,
+	})
 
-	// Limit results
-	if len(articles) > limit {
-		articles = articles[:limit]
+	if cursor == nil {
+		h.svc.LogSearch(c.Request.Context(), "search", query, len(articles))
+		h.recordImpressions(c, "search", articles)
+
+		// Compare first pages only, with the same boost parameters over the
+		// candidate's defaults
+		h.shadowRank(c, "search", query, articles, func(shadow services.ShadowRanking) []models.Article {
+			candidateBoosts := searchBoostsFrom(c, shadow.Boosts)
+			shadow.Profile.AsOf = snapshot
+			shadow.Profile.Boosts = &candidateBoosts
+			ranked := services.Diversify(services.RankBySearchRelevance(matches, query, shadow.Profile, relevance), diversity)
+			if len(ranked) > limit {
+				ranked = ranked[:limit]
+			}
+			return ranked
+		})
 	}
 
-	// Enrich with summaries
-	h.enrichWithSummaries(articles)
+	applyExplain(c, articles)
 
-	c.JSON(http.StatusOK, Response{
-		Articles: articles,
-		Meta: Meta{
-			Count:    len(articles),
-			Limit:    limit,
-			Endpoint: "search",
-			Query:    query,
-		},
-	})
+	// Enrich with summaries
+	h.enrichWithSummaries(c, articles)
+
+	skipped := budget.Skipped(c.Request.Context())
+	meta := Meta{
+		Count:       len(articles),
+		Limit:       limit,
+		Endpoint:    "search",
+		Query:       query,
+		Degradation: degradation.Modes(c.Request.Context()),
+		Partial:     len(skipped) > 0,
+		Skipped:     skipped,
+	}
+	if next != nil {
+		meta.NextCursor = next.Encode()
+	}
+	h.respondWithCoordinates(c, http.StatusOK, &Response{Articles: articles, Meta: meta})
 }
 
 // GetNearby handles /nearby endpoint
@@ -248,6 +387,16 @@ func (h *NewsHandler) GetNearby(c *gin.Context) {
 	radiusStr := c.DefaultQuery("radius", "10")
 	limitStr := c.DefaultQuery("limit", "5")
 
+	// Requests without coordinates are located from the client's IP, looking
+	// as far as the location is accurate to
+	located, ok := h.locateClient(c)
+	if ok {
+		latStr, lonStr = formatCoordinate(located.Latitude), formatCoordinate(located.Longitude)
+		if c.Query("radius") == "" && located.RadiusKm > 10 {
+			radiusStr = formatCoordinate(located.RadiusKm)
+		}
+	}
+
 	lat, err := strconv.ParseFloat(latStr, 64)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid latitude"})
@@ -260,6 +409,8 @@ func (h *NewsHandler) GetNearby(c *gin.Context) {
 		return
 	}
 
+	lat, lon = h.svc.PrivateLocation(lat, lon, preciseLocation(c))
+
 	radius, err := strconv.ParseFloat(radiusStr, 64)
 	if err != nil || radius <= 0 {
 		radius = 10
@@ -270,58 +421,217 @@ func (h *NewsHandler) GetNearby(c *gin.Context) {
 		limit = 5
 	}
 
-	database := db.GetDB()
-	var articles []models.Article
-
-	// Haversine formula in SQL to calculate distance
-	// 6371 is the Earth's radius in kilometers
-	haversine := fmt.Sprintf(`
-		(6371 * acos(cos(radians(%f)) * cos(radians(latitude)) *
-		cos(radians(longitude) - radians(%f)) + sin(radians(%f)) *
-		sin(radians(latitude))))
-	`, lat, lon, lat)
-
-	err = database.
-		Select(fmt.Sprintf("*, %s AS distance", haversine)).
-		Where(fmt.Sprintf("%s <= ?", haversine), radius).
-		Order("distance").
-		Limit(limit).
-		Find(&articles).Error
+	// Optionally blend in what is trending around the user
+	var trending services.ScoreLookup
+	switch boost := c.Query("boost"); boost {
+	case "":
+	case "trending":
+		if !simulatedTraffic(c) {
+			return
+		}
+		trending, err = h.svc.TrendingScores(c.Request.Context(), lat, lon, h.config.LocationClusterDegrees)
+		if err != nil {
+			log.Printf("Failed to load trending scores, ranking by distance only: %v", err)
+			degradation.Record(c.Request.Context(), degradation.SubsystemTrending, degradation.ModeFallback)
+		}
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "boost must be trending"})
+		return
+	}
 
+	filter, err := parseArticleFilter(c)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch articles"})
+		respondError(c, err, "Invalid filter")
 		return
 	}
+	database := h.db.WithContext(c.Request.Context()).Scopes(filter.Scope)
+	articles, err := h.findNearby(database, lat, lon, radius, limit, trending, h.diversityLimits(c))
+	if err != nil {
+		respondError(c, err, "Failed to fetch articles")
+		return
+	}
+
+	h.svc.AttachSourceMeta(c.Request.Context(), articles)
+	h.recordImpressions(c, "nearby", articles)
+	applyExplain(c, articles)
 
 	// Enrich with summaries
-	h.enrichWithSummaries(articles)
+	h.enrichWithSummaries(c, articles)
 
-	c.JSON(http.StatusOK, Response{
+	h.respondWithCoordinates(c, http.StatusOK, &Response{
 		Articles: articles,
 		Meta: Meta{
-			Count:    len(articles),
-			Limit:    limit,
-			Endpoint: "nearby",
-			Query:    latStr + "," + lonStr,
+			Count:       len(articles),
+			Limit:       limit,
+			Endpoint:    "nearby",
+			Query:       latStr + "," + lonStr,
+			Location:    located,
+			Degradation: degradation.Modes(c.Request.Context()),
 		},
 	})
 }
 
-// GetTrending handles /trending endpoint
+// GetTrending handles /trending endpoint, for one location or a weighted blend
+// of several given as locations=lat,lon,weight|lat,lon,weight
 func (h *NewsHandler) GetTrending(c *gin.Context) {
 	latStr := c.Query("lat")
 	lonStr := c.Query("lon")
+	locationsStr := c.Query("locations")
 	limitStr := c.DefaultQuery("limit", "5")
+	if !simulatedTraffic(c) {
+		return
+	}
 
-	lat, err := strconv.ParseFloat(latStr, 64)
+	var locations []services.WeightedLocation
+	located, ok := h.locateClient(c)
+	if ok {
+		latStr, lonStr = formatCoordinate(located.Latitude), formatCoordinate(located.Longitude)
+	}
+	query := latStr + "," + lonStr
+	if locationsStr != "" {
+		var err error
+		if locations, err = parseWeightedLocations(locationsStr); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		query = locationsStr
+	} else {
+		lat, err := strconv.ParseFloat(latStr, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid latitude"})
+			return
+		}
+
+		lon, err := strconv.ParseFloat(lonStr, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid longitude"})
+			return
+		}
+		locations = []services.WeightedLocation{{Latitude: lat, Longitude: lon, Weight: 1}}
+	}
+
+	precise := preciseLocation(c)
+	for i := range locations {
+		locations[i].Latitude, locations[i].Longitude = h.svc.PrivateLocation(locations[i].Latitude, locations[i].Longitude, precise)
+	}
+
+	limit, err := strconv.Atoi(limitStr)
+	if err != nil || limit <= 0 {
+		limit = 5
+	}
+
+	filter, err := parseArticleFilter(c)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid latitude"})
+		respondError(c, err, "Invalid filter")
 		return
 	}
 
-	lon, err := strconv.ParseFloat(lonStr, 64)
+	// Get more so the results can be diversified
+	articles, err := h.svc.GetBlendedTrendingArticles(c.Request.Context(), locations, limit*3, h.config.LocationClusterDegrees)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid longitude"})
+		respondError(c, err, "Failed to fetch trending articles")
+		return
+	}
+	articles = filter.Apply(articles)
+	if budget.Spent(c.Request.Context()) {
+		budget.Skip(c.Request.Context(), budget.StageDiversify)
+	} else {
+		articles = services.Diversify(articles, h.diversityLimits(c))
+	}
+	if len(articles) > limit {
+		articles = articles[:limit]
+	}
+
+	h.recordImpressions(c, "trending", articles)
+	applyExplain(c, articles)
+
+	// Enrich with summaries
+	h.enrichWithSummaries(c, articles)
+
+	skipped := budget.Skipped(c.Request.Context())
+	h.respondWithCoordinates(c, http.StatusOK, &Response{
+		Articles: articles,
+		Meta: Meta{
+			Count:       len(articles),
+			Limit:       limit,
+			Endpoint:    "trending",
+			Query:       query,
+			Location:    located,
+			Degradation: degradation.Modes(c.Request.Context()),
+			Partial:     len(skipped) > 0,
+			Skipped:     skipped,
+		},
+	})
+}
+
+// maxCompareLocations caps the locations one /trending/compare request may ask for
+const maxCompareLocations = 5
+
+// maxBlendLocations caps the locations one blended /trending request may ask for
+const maxBlendLocations = 5
+
+// parseWeightedLocations reads lat,lon,weight triples separated by |. A missing
+// weight counts as 1; weights are relative to each other.
+func parseWeightedLocations(locationsStr string) ([]services.WeightedLocation, error) {
+	parts := strings.Split(locationsStr, "|")
+	if len(parts) > maxBlendLocations {
+		return nil, fmt.Errorf("locations must list at most %d lat,lon,weight triples", maxBlendLocations)
+	}
+	locations := make([]services.WeightedLocation, len(parts))
+	for i, part := range parts {
+		fields := strings.Split(part, ",")
+		if len(fields) != 2 && len(fields) != 3 {
+			return nil, fmt.Errorf("Invalid location %q", part)
+		}
+		lat, latErr := strconv.ParseFloat(strings.TrimSpace(fields[0]), 64)
+		lon, lonErr := strconv.ParseFloat(strings.TrimSpace(fields[1]), 64)
+		if latErr != nil || lonErr != nil {
+			return nil, fmt.Errorf("Invalid location %q", part)
+		}
+		weight := 1.0
+		if len(fields) == 3 {
+			var err error
+			if weight, err = strconv.ParseFloat(strings.TrimSpace(fields[2]), 64); err != nil || weight <= 0 {
+				return nil, fmt.Errorf("Invalid weight in location %q", part)
+			}
+		}
+		locations[i] = services.WeightedLocation{Latitude: lat, Longitude: lon, Weight: weight}
+	}
+	return locations, nil
+}
+
+// LocationTrending is one location's column in a trending comparison
+type LocationTrending struct {
+	Latitude  *float64         `json:"latitude,omitempty"` // Left out with the other coordinates of responses
+	Longitude *float64         `json:"longitude,omitempty"`
+	Cluster   string           `json:"cluster"`
+	Articles  []models.Article `json:"articles"`
+	Unique    []string         `json:"unique"` // IDs trending only at this location
+}
+
+type TrendingComparisonResponse struct {
+	Locations []LocationTrending `json:"locations"`
+	Common    []string           `json:"common"` // IDs trending at every location
+	Meta      Meta               `json:"meta"`
+}
+
+// CompareTrending handles /trending/compare endpoint, returning trending lists for
+// several locations side by side with their overlap and differences
+func (h *NewsHandler) CompareTrending(c *gin.Context) {
+	locationsStr := c.Query("locations")
+	limitStr := c.DefaultQuery("limit", "5")
+
+	if locationsStr == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "locations parameter is required"})
+		return
+	}
+	if !simulatedTraffic(c) {
+		return
+	}
+
+	parts := strings.Split(locationsStr, "|")
+	if len(parts) < 2 || len(parts) > maxCompareLocations {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("locations must list between 2 and %d lat,lon pairs", maxCompareLocations)})
 		return
 	}
 
@@ -330,22 +640,68 @@ func (h *NewsHandler) GetTrending(c *gin.Context) {
 		limit = 5
 	}
 
-	articles, err := services.GetTrendingArticles(lat, lon, limit, h.config.LocationClusterDegrees)
+	filter, err := parseArticleFilter(c)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch trending articles"})
+		respondError(c, err, "Invalid filter")
 		return
 	}
+	precise := preciseLocation(c)
+	columns := make([]LocationTrending, len(parts))
+	lists := make([][]models.Article, len(parts))
+	queried := make([]string, len(parts))
+	for i, part := range parts {
+		coords := strings.Split(part, ",")
+		if len(coords) != 2 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid location %q", part)})
+			return
+		}
+		lat, latErr := strconv.ParseFloat(strings.TrimSpace(coords[0]), 64)
+		lon, lonErr := strconv.ParseFloat(strings.TrimSpace(coords[1]), 64)
+		if latErr != nil || lonErr != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid location %q", part)})
+			return
+		}
+		if !models.ValidCoordinates(lat, lon) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid location %q: latitude must be between -90 and 90 and longitude between -180 and 180", part)})
+			return
+		}
+		// Truncated as /trending does, so neither the cluster nor the response
+		// reveals more of the location than is kept
+		lat, lon = h.svc.PrivateLocation(lat, lon, precise)
+		queried[i] = formatCoordinate(lat) + "," + formatCoordinate(lon)
 
-	// Enrich with summaries
-	h.enrichWithSummaries(articles)
+		articles, err := h.svc.GetTrendingArticles(c.Request.Context(), lat, lon, limit, h.config.LocationClusterDegrees)
+		if err != nil {
+			respondError(c, err, "Failed to fetch trending articles")
+			return
+		}
+		articles = filter.Apply(articles)
+		applyExplain(c, articles)
+		h.enrichWithSummaries(c, articles)
+
+		lists[i] = articles
+		columns[i] = LocationTrending{
+			Latitude:  &lat,
+			Longitude: &lon,
+			Cluster:   utils.GetLocationClusterKey(lat, lon, h.config.LocationClusterDegrees),
+			Articles:  articles,
+		}
+	}
 
-	c.JSON(http.StatusOK, Response{
-		Articles: articles,
+	common, unique := services.CompareTrending(lists)
+	for i := range columns {
+		columns[i].Unique = unique[i]
+	}
+
+	h.respondWithCoordinates(c, http.StatusOK, &TrendingComparisonResponse{
+		Locations: columns,
+		Common:    common,
 		Meta: Meta{
-			Count:    len(articles),
-			Limit:    limit,
-			Endpoint: "trending",
-			Query:    latStr + "," + lonStr,
+			Count:       len(columns),
+			Limit:       limit,
+			Endpoint:    "trending/compare",
+			Query:       strings.Join(queried, "|"),
+			Degradation: degradation.Modes(c.Request.Context()),
 		},
 	})
 }
@@ -353,8 +709,6 @@ func (h *NewsHandler) GetTrending(c *gin.Context) {
 // Query handles /query endpoint (LLM-powered)
 func (h *NewsHandler) Query(c *gin.Context) {
 	query := c.Query("query")
-	latStr := c.Query("lat")
-	lonStr := c.Query("lon")
 	limitStr := c.DefaultQuery("limit", "5")
 
 	if query == "" {
@@ -367,202 +721,594 @@ func (h *NewsHandler) Query(c *gin.Context) {
 		limit = 5
 	}
 
-	// Extract intent and entities using LLM
-	result, err := h.llmClient.ExtractIntentAndEntities(query)
+	response, err := h.runQuery(c, query, limit, c.Query("session_id"))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process query"})
+		respondError(c, err, "Failed to process query")
 		return
 	}
+	h.respondWithCoordinates(c, http.StatusOK, response)
+}
+
+// runQuery answers a natural-language query the way /query does, continuing
+// the conversation of sessionID, or a new one when it is empty
+func (h *NewsHandler) runQuery(c *gin.Context, query string, limit int, sessionID string) (*Response, error) {
+	// Reject an invalid filter before spending an LLM call on the query
+	if _, err := parseArticleFilter(c); err != nil {
+		return nil, err
+	}
 
-	fmt.Printf("result : %+v", result)
+	// Extract intent and entities using LLM, falling back to keyword heuristics
+	// when that would run past the response-time budget
+	extractCtx, cancel := budget.WithDeadline(c.Request.Context())
+	result, err := h.llm(c).For(llm.Call{Context: extractCtx}).ExtractIntentAndEntities(query)
+	if extractCtx.Err() != nil {
+		budget.Skip(c.Request.Context(), budget.StageIntent)
+	}
+	cancel()
+	if err != nil {
+		return nil, err
+	}
 
-	// Dispatch to appropriate endpoint based on intent
+	// Follow-ups in a session refine the previous query instead of starting over
+	if sessionID == "" {
+		sessionID = services.NewSessionID()
+	}
+	state := conversationState(query, result)
+	if previous, err := h.svc.GetConversation(c.Request.Context(), sessionID); err == nil && services.IsFollowUp(query) {
+		state = previous.Refine(state)
+	}
+	h.svc.SaveConversation(c.Request.Context(), sessionID, state)
+
+	// Dispatch to appropriate endpoint based on intent, relaxing constraints one
+	// at a time while nothing matches
+	articles, err := h.dispatchQuery(c, state, limit)
+	if err != nil {
+		return nil, err
+	}
+	var relaxations []string
+	for _, relax := range services.QueryRelaxations {
+		if len(articles) > 0 {
+			break
+		}
+		if budget.Spent(c.Request.Context()) {
+			budget.Skip(c.Request.Context(), budget.StageRelaxation)
+			break
+		}
+		relaxed, ok := relax.Apply(state)
+		if !ok {
+			continue
+		}
+		state = relaxed
+		relaxations = append(relaxations, relax.Name)
+		if articles, err = h.dispatchQuery(c, state, limit); err != nil {
+			return nil, err
+		}
+	}
+	endpoint := state.Intent
+
+	h.svc.LogSearch(c.Request.Context(), "query", query, len(articles))
+
+	h.recordImpressions(c, "query", articles)
+	applyExplain(c, articles)
+
+	// Enrich with summaries
+	h.enrichWithSummaries(c, articles)
+
+	skipped := budget.Skipped(c.Request.Context())
+	return &Response{
+		Articles: articles,
+		Meta: Meta{
+			Count:       len(articles),
+			Limit:       limit,
+			Endpoint:    endpoint,
+			Query:       query,
+			Location:    state.Location,
+			DateRange:   state.DateRange,
+			SessionID:   sessionID,
+			Relaxations: relaxations,
+			Degradation: degradation.Modes(c.Request.Context()),
+			Partial:     len(skipped) > 0,
+			Skipped:     skipped,
+		},
+	}, nil
+}
+
+// dispatchQuery runs a /query request's resolved state against the endpoint
+// its intent maps to. Database errors and searches matching too many
+// articles are returned rather than relaxed.
+func (h *NewsHandler) dispatchQuery(c *gin.Context, state services.ConversationState, limit int) ([]models.Article, error) {
 	var articles []models.Article
-	endpoint := result.Intent
+	var err error
 
-	database := db.GetDB()
+	// Limit every dispatched query to the dates, category, source and place
+	// the conversation asks about
+	filter, err := parseArticleFilter(c)
+	if err != nil {
+		return nil, err
+	}
+	if state.DateRange != nil {
+		location, _ := clientLocation(c) // Checked by parseArticleFilter
+		filter.PublishedFrom, filter.PublishedTo = state.DateRange.Bounds(location)
+	}
+	database := h.db.WithContext(c.Request.Context()).Scopes(filter.Scope)
+	if state.Category != "" {
+		database = database.Where(h.svc.CategoryMatch(c.Request.Context(), state.Category))
+	}
+	if state.Source != "" {
+		database = database.Where("LOWER(source_name) LIKE ?", "%"+strings.ToLower(state.Source)+"%")
+	}
+	scope := services.SearchScope{Filter: filter, Category: state.Category, Source: state.Source}
+	if state.Location != nil && state.Intent != llm.IntentNearby {
+		scope.Near = &search.Point{Latitude: state.Location.Latitude, Longitude: state.Location.Longitude}
+		scope.RadiusKm = state.Location.RadiusKm
+		minLat, maxLat, minLon, maxLon := utils.BoundingBox(state.Location.Latitude, state.Location.Longitude, state.Location.RadiusKm)
+		database = database.
+			Scopes(services.HasLocation).
+			Where("latitude BETWEEN ? AND ?", minLat, maxLat).
+			Where("longitude BETWEEN ? AND ?", minLon, maxLon)
+	}
 
-	switch result.Intent {
+	switch state.Intent {
 	case llm.IntentCategory:
-		// Extract category from query or entities
-		category := extractCategory(query, result.Entities)
-		if category != "" {
-			database.
-				Where("LOWER(category) LIKE ?", "%"+strings.ToLower(category)+"%").
+		if state.Category != "" {
+			err = database.
 				Order("publication_date DESC").
-				Limit(limit).
-				Find(&articles)
+				Order("id").
+				Limit(limit * 3).
+				Find(&articles).Error
+			if err != nil {
+				return nil, err
+			}
+			h.svc.AttachSourceMeta(c.Request.Context(), articles)
+			articles = services.RankByFreshness(articles, h.rankingProfile())
 		}
 
 	case llm.IntentSource:
-		// Extract source from query or entities
-		source := extractSource(query, result.Entities)
-		if source != "" {
-			database.
-				Where("LOWER(source_name) LIKE ?", "%"+strings.ToLower(source)+"%").
+		if state.Source != "" {
+			err = database.
 				Order("publication_date DESC").
-				Limit(limit).
-				Find(&articles)
+				Order("id").
+				Limit(limit * 3).
+				Find(&articles).Error
+			if err != nil {
+				return nil, err
+			}
+			h.svc.AttachSourceMeta(c.Request.Context(), articles)
+			articles = services.RankByFreshness(articles, h.rankingProfile())
 		}
 
 	case llm.IntentScore:
-		database.
+		err = database.
 			Where("relevance_score >= ?", 0.7).
 			Order("relevance_score DESC").
-			Limit(limit).
-			Find(&articles)
+			Order("publication_date DESC").
+			Order("id").
+			Limit(limit * 3).
+			Find(&articles).Error
+		if err != nil {
+			return nil, err
+		}
+		h.svc.AttachSourceMeta(c.Request.Context(), articles)
+		articles = services.RankByRelevanceScore(articles)
 
 	case llm.IntentNearby:
-		if latStr != "" && lonStr != "" {
-			lat, _ := strconv.ParseFloat(latStr, 64)
-			lon, _ := strconv.ParseFloat(lonStr, 64)
-
-			database.Find(&articles)
-			articles = services.RankByDistance(articles, lat, lon)
-			if len(articles) > limit {
-				articles = articles[:limit]
+		if state.Location != nil {
+			articles, err = h.findNearby(database, state.Location.Latitude, state.Location.Longitude, state.Location.RadiusKm, limit, nil, h.diversityLimits(c))
+			if err != nil {
+				return nil, err
+			}
+			h.svc.AttachSourceMeta(c.Request.Context(), articles)
+		} else if hasClientLocation(c) {
+			lat, _ := strconv.ParseFloat(c.Query("lat"), 64)
+			lon, _ := strconv.ParseFloat(c.Query("lon"), 64)
+			lat, lon = h.svc.PrivateLocation(lat, lon, preciseLocation(c))
+			radius, err := strconv.ParseFloat(c.DefaultQuery("radius", "10"), 64)
+			if err != nil || radius <= 0 {
+				radius = 10
 			}
+
+			articles, err = h.findNearby(database, lat, lon, radius, limit, nil, h.diversityLimits(c))
+			if err != nil {
+				return nil, err
+			}
+			h.svc.AttachSourceMeta(c.Request.Context(), articles)
 		}
 
 	default: // IntentSearch
-		searchQuery := result.Query
-		if len(result.Entities) > 0 {
-			// If entities are found, use them for a more targeted search.
-			searchQuery = strings.Join(result.Entities, " ")
-		}
-		fmt.Println("Executing search with query:", searchQuery) // Debugging line
-		searchWords := strings.Split(strings.ToLower(searchQuery), " ")
-		filteredWords := filterStopWords(searchWords) // Filter stop words
-		queryBuilder := database.Model(&models.Article{})
+		searchQuery := state.Query
+		queryBuilder := database.Model(&models.Article{}).Where(keywordMatch(c.Request.Context(), h.svc, h.db.WithContext(c.Request.Context()), searchQuery, scope))
 
-		if len(filteredWords) == 0 {
-			filteredWords = searchWords // Fallback to original words if all are stop words
+		if err = queryBuilder.Limit(limit * 3).Find(&articles).Error; err != nil {
+			return nil, err
 		}
+		h.svc.AttachSourceMeta(c.Request.Context(), articles)
+
+		relevance := h.svc.TextRelevances(c.Request.Context(), searchQuery, articles)
+		articles = services.RankBySearchRelevance(articles, searchQuery, h.rankingProfile(), relevance)
+	}
 
-		for _, word := range filteredWords {
-			if word != "" {
-				searchPattern := "%" + word + "%"
-				queryBuilder = queryBuilder.Or("LOWER(title) LIKE ?", searchPattern).Or("LOWER(description) LIKE ?", searchPattern)
+	articles = services.Diversify(articles, h.diversityLimits(c))
+	if len(articles) > limit {
+		articles = articles[:limit]
+	}
+	return articles, nil
+}
+
+// simulatedTraffic applies simulated=include|exclude, counting simulated
+// events in the request's trending or leaving them out whatever the
+// deployment's default. It reports false, having responded, for any other
+// value.
+func simulatedTraffic(c *gin.Context) bool {
+	switch c.Query("simulated") {
+	case "":
+	case "include":
+		c.Request = c.Request.WithContext(services.WithSimulatedTraffic(c.Request.Context(), true))
+	case "exclude":
+		c.Request = c.Request.WithContext(services.WithSimulatedTraffic(c.Request.Context(), false))
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "simulated must be include or exclude"})
+		return false
+	}
+	return true
+}
+
+// preciseLocation reports whether the client allows its coordinates to be used
+// at full precision. precise=false limits them to city level.
+func preciseLocation(c *gin.Context) bool {
+	precise, err := strconv.ParseBool(c.DefaultQuery("precise", "true"))
+	return err != nil || precise
+}
+
+// locateClient locates a request sending no coordinates from the client's IP
+// address, when GEOIP_FALLBACK is on. The place is named after the network's
+// city, and its radius is how accurate the location is.
+func (h *NewsHandler) locateClient(c *gin.Context) (*geocode.Place, bool) {
+	if h.geoIP == nil || c.Query("lat") != "" || c.Query("lon") != "" || c.Query("locations") != "" {
+		return nil, false
+	}
+	location, ok := h.geoIP.Lookup(c.ClientIP())
+	if !ok {
+		return nil, false
+	}
+	return &geocode.Place{
+		Name:      location.City,
+		Latitude:  location.Latitude,
+		Longitude: location.Longitude,
+		RadiusKm:  location.RadiusKm,
+	}, true
+}
+
+// formatCoordinate writes a coordinate as a query parameter would give it
+func formatCoordinate(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
+
+// hasClientLocation reports whether the request carries the client's coordinates
+func hasClientLocation(c *gin.Context) bool {
+	return c.Query("lat") != "" && c.Query("lon") != ""
+}
+
+// conversationState resolves an extraction into the filters a /query request
+// dispatches on. A place named in a nearby query is geocoded; one that can't be
+// resolved is searched for by name instead of falling back to the client's position.
+func conversationState(query string, result *llm.ExtractionResult) services.ConversationState {
+	state := services.ConversationState{
+		Intent:    result.Intent,
+		Query:     result.Query,
+		Entities:  result.Entities,
+		DateRange: result.DateRange,
+	}
+	if len(result.Entities) > 0 {
+		// If entities are found, use them for a more targeted search.
+		state.Query = strings.Join(result.Entities, " ")
+	}
+
+	switch result.Intent {
+	case llm.IntentCategory:
+		state.Category = extractCategory(query, result.Entities)
+	case llm.IntentSource:
+		state.Source = extractSource(query, result.Entities)
+	case llm.IntentNearby:
+		if len(result.Locations) > 0 {
+			if place, ok := geocode.ResolveFirst(result.Locations); ok {
+				state.Location = &place
+			} else {
+				state.Intent = llm.IntentSearch
 			}
 		}
+	}
+	return state
+}
 
-		queryBuilder.Limit(limit * 3).Find(&articles)
+// findNearby returns the articles within radius km of a point, closest first,
+// with distance explanations attached. A bounding box narrows the candidates in
+// SQL and exact distances are computed in memory. With a trending lookup, the
+// articles within radius are instead ranked by distance and trending together.
+// The ranked articles are diversified before the limit is applied. Radii whose
+// box holds too many articles to rank are rejected as too expensive.
+func (h *NewsHandler) findNearby(database *gorm.DB, lat, lon, radius float64, limit int, trending services.ScoreLookup, diversity services.DiversityLimits) ([]models.Article, error) {
+	minLat, maxLat, minLon, maxLon := utils.BoundingBox(lat, lon, radius)
+
+	query := database.Model(&models.Article{}).
+		Scopes(services.HasLocation).
+		Where("latitude BETWEEN ? AND ?", minLat, maxLat).
+		Where("longitude BETWEEN ? AND ?", minLon, maxLon)
+	if err := h.svc.CheckCandidates(query, "use a smaller radius or add a category, source or date filter"); err != nil {
+		return nil, err
+	}
+	var candidates []models.Article
+	if err := query.Find(&candidates).Error; err != nil {
+		return nil, err
+	}
 
-		articles = services.RankBySearchRelevance(articles, searchQuery)
-		if len(articles) > limit {
-			articles = articles[:limit]
+	var articles []models.Article
+	for _, article := range services.RankByDistance(candidates, lat, lon) {
+		if utils.HaversineDistance(lat, lon, article.Latitude, article.Longitude) > radius {
+			break
 		}
+		articles = append(articles, article)
 	}
 
-	// Enrich with summaries
-	h.enrichWithSummaries(articles)
+	if trending != nil {
+		articles = services.RankByDistanceAndTrending(articles, lat, lon, trending)
+	}
+	articles = services.Diversify(articles, diversity)
+	if len(articles) > limit {
+		articles = articles[:limit]
+	}
+	return articles, nil
+}
 
-	c.JSON(http.StatusOK, Response{
-		Articles: articles,
-		Meta: Meta{
-			Count:    len(articles),
-			Limit:    limit,
-			Endpoint: endpoint,
-			Query:    query,
-		},
+// diversityLimits reads the max_per_source and max_per_category parameters,
+// falling back to the configured defaults. 0 disables a limit.
+func (h *NewsHandler) diversityLimits(c *gin.Context) services.DiversityLimits {
+	limits := services.DiversityLimits{
+		MaxPerSource:   h.config.DiversityMaxPerSource,
+		MaxPerCategory: h.config.DiversityMaxPerCategory,
+	}
+	if value, err := strconv.Atoi(c.Query("max_per_source")); err == nil && value >= 0 {
+		limits.MaxPerSource = value
+	}
+	if value, err := strconv.Atoi(c.Query("max_per_category")); err == nil && value >= 0 {
+		limits.MaxPerCategory = value
+	}
+	return limits
+}
+
+// rankByFreshness ranks the candidates of a list endpoint by relevance blended
+// with freshness and source reliability, diversifies them and cuts them to
+// limit. Sampled requests are ranked by the shadow candidate too.
+func (h *NewsHandler) rankByFreshness(c *gin.Context, endpoint, query string, articles []models.Article, limit int) []models.Article {
+	diversity := h.diversityLimits(c)
+	rank := func(profile services.RankingProfile) []models.Article {
+		ranked := services.Diversify(services.RankByFreshness(articles, profile), diversity)
+		if len(ranked) > limit {
+			ranked = ranked[:limit]
+		}
+		return ranked
+	}
+
+	served := rank(h.rankingProfile())
+	h.shadowRank(c, endpoint, query, served, func(shadow services.ShadowRanking) []models.Article {
+		return rank(shadow.Profile)
 	})
+	return served
 }
 
-// enrichWithSummaries adds LLM-generated summaries to articles
-func (h *NewsHandler) enrichWithSummaries(articles []models.Article) {
-	for i := range articles {
-		if articles[i].LLMSummary == "" {
-			var summary string
-			var err error
+// shadowRank logs the served ordering of a sampled request beside the shadow
+// candidate's, which rank computes. The response is not affected.
+func (h *NewsHandler) shadowRank(c *gin.Context, endpoint, query string, served []models.Article, rank func(services.ShadowRanking) []models.Article) {
+	shadow, sampled := services.SampleShadowRanking(c.Request.Context())
+	if !sampled {
+		return
+	}
+	h.svc.LogShadowComparison(c.Request.Context(), shadow, endpoint, query, clientIdentity(c, c.Query("client_id")), served, rank(shadow))
+}
 
-			// Try to get content from URL first
-			if articles[i].URL != "" {
-				content, err := fetchAndParseURL(articles[i].URL)
-				if err == nil && content != "" {
-					summary, err = h.llmClient.GenerateSummary(articles[i].Title, content)
-				} else if err != nil {
-					log.Printf("Failed to fetch or parse URL %s: %v", articles[i].URL, err)
-				}
-			}
+// rankingProfile returns the current tuning weights for the hybrid rankers
+func (h *NewsHandler) rankingProfile() services.RankingProfile {
+	return services.CurrentRankingProfile()
+}
 
-			// Fallback to title and description if URL fetching fails or content is empty
-			if summary == "" {
-				summary, err = h.llmClient.GenerateSummary(articles[i].Title, articles[i].Description)
-			}
+// llm returns the LLM client for a request, cancelled with the request,
+// metering the tokens it uses and falling back to heuristics when the
+// request's tenant has exhausted its LLM budget or monthly token quota
+func (h *NewsHandler) llm(c *gin.Context) llm.Provider {
+	ctx := c.Request.Context()
+	// An unknown tz resolves dates in UTC here; the request's filter rejects it
+	location, _ := clientLocation(c)
+	call := llm.Call{Context: ctx, Report: degradation.FromContext(ctx), Location: location}
+	if t, ok := tenant.FromContext(ctx); ok && (!h.svc.QuotaLeft(ctx, tenant.QuotaLLMTokens) || !t.AllowLLMCall()) {
+		return h.fallbackClient.For(call)
+	}
+	call.Faults = faults.FromContext(ctx)
+	call.CountTokens = func(tokens int) {
+		h.svc.RecordUsage(ctx, tenant.QuotaLLMTokens, int64(tokens))
+	}
+	return h.llmClient.For(call)
+}
 
-			if err == nil {
-				articles[i].LLMSummary = summary
-				// Optionally save to database
-				db.GetDB().Model(&articles[i]).Update("llm_summary", summary)
-			} else {
-				log.Printf("Failed to generate summary for article %s: %v", articles[i].Title, err)
-			}
+// enrichWithSummaries adds LLM-generated summaries to articles, of the length
+// set by summary_length, and links the spoken summaries synthesized so far
+// and the stored preview images. Missing summaries are requested from the
+// background summarizer and the articles marked pending, so the request
+// doesn't wait on the LLM. With INLINE_SUMMARIES they are generated first,
+// within the request's response-time budget if it has one, and only those
+// left out are requested.
+func (h *NewsHandler) enrichWithSummaries(c *gin.Context, articles []models.Article) {
+	length := summaryLength(c)
+	if err := h.svc.ApplySummaryLength(c.Request.Context(), articles, length); err != nil {
+		log.Printf("Failed to load %s summaries: %v", length, err)
+	}
+	h.svc.AttachAudio(c.Request.Context(), articles, audioPath)
+	services.AttachImages(articles, imagePath)
+
+	if h.config.InlineSummaries {
+		h.summarizeInline(c, articles, length)
+	}
+
+	var missing []string
+	for i := range articles {
+		if articles[i].LLMSummary != "" {
+			articles[i].SummaryStatus = models.SummaryReady
+			continue
 		}
+		articles[i].SummaryStatus = models.SummaryPending
+		missing = append(missing, articles[i].ID)
+	}
+	if len(missing) > 0 {
+		h.summarizer.Request(missing, length, h.llm(c))
 	}
 }
 
-func fetchAndParseURL(rawURL string) (string, error) {
-	parsedURL, err := url.Parse(rawURL)
-	if err != nil {
-		return "", fmt.Errorf("failed to parse URL: %w", err)
+// summarizeInline generates the missing summaries of articles within the
+// request's response-time budget if it has one
+func (h *NewsHandler) summarizeInline(c *gin.Context, articles []models.Article, length string) {
+	ctx, cancel := budget.WithDeadline(c.Request.Context())
+	defer cancel()
+	for i := range articles {
+		if articles[i].LLMSummary != "" {
+			continue
+		}
+		// Leave the remaining summaries out once the response-time budget is spent
+		if budget.Spent(c.Request.Context()) {
+			budget.Skip(c.Request.Context(), budget.StageSummaries)
+			return
+		}
+		if err := h.svc.SummarizeArticleLength(ctx, h.llm(c), &articles[i], length); err != nil {
+			log.Printf("Failed to generate summary for article %s: %v", articles[i].Title, err)
+		}
+		if ctx.Err() != nil {
+			// Cut short, so the article got at most the heuristic summary
+			budget.Skip(c.Request.Context(), budget.StageSummaries)
+			return
+		}
 	}
+}
 
-	client := &http.Client{
-		Timeout: 10 * time.Second,
+// summaryLength reads summary_length, defaulting to medium for missing or
+// unknown values
+func summaryLength(c *gin.Context) string {
+	length := strings.ToLower(c.Query("summary_length"))
+	if !llm.ValidSummaryLength(length) {
+		return llm.SummaryMedium
 	}
-	req, err := http.NewRequest("GET", parsedURL.String(), nil)
-	if err != nil {
-		return "", err
+	return length
+}
+
+// keywordMatch builds a grouped OR condition matching any query term in the
+// title or description, so it can be combined safely with other filters. With
+// a search backend the condition is the IDs of the best matches within scope;
+// otherwise terms are matched in the full-text index, or with LIKE without it.
+func keywordMatch(ctx context.Context, svc *services.Services, database *gorm.DB, query string, scope services.SearchScope) *gorm.DB {
+	terms := services.ParseSearchTerms(query)
+	if !terms.Empty() {
+		if ids, ok := svc.SearchCandidates(ctx, terms, scope); ok {
+			return database.Where("id IN ?", ids)
+		}
+		if condition, ok := services.SearchArticles(database, terms); ok {
+			return condition
+		}
 	}
-	// Some sites block default user agents
-	req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/58.0.3029.110 Safari/537.36")
 
-	resp, err := client.Do(req)
-	if err != nil {
-		return "", err
+	condition := database.Where("1 = 0")
+	for _, term := range terms.Substrings() {
+		searchPattern := "%" + term + "%"
+		condition = condition.Or("LOWER(title) LIKE ?", searchPattern).Or("LOWER(description) LIKE ?", searchPattern)
 	}
-	defer resp.Body.Close()
+	return condition
+}
 
-	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("failed to fetch URL: status code %d", resp.StatusCode)
+// recordImpressions records the articles a list endpoint returns as sampled
+// impressions. Call it before applyExplain, so the ranker is still known.
+func (h *NewsHandler) recordImpressions(c *gin.Context, endpoint string, articles []models.Article) {
+	h.svc.RecordImpressions(c.Request.Context(), endpoint, clientIdentity(c, c.Query("client_id")), articles)
+}
+
+// applyExplain keeps score explanations only when the client asked for them
+func applyExplain(c *gin.Context, articles []models.Article) {
+	if explain, _ := strconv.ParseBool(c.Query("explain")); !explain {
+		services.StripExplanations(articles)
+	}
+}
+
+// parseArticleFilter reads the shared result filters from the query string
+func parseArticleFilter(c *gin.Context) (services.ArticleFilter, error) {
+	excludePaywalled, _ := strconv.ParseBool(c.Query("exclude_paywalled"))
+
+	safe := strings.ToLower(c.DefaultQuery("safe", services.SafeModerate))
+	if safe != services.SafeStrict && safe != services.SafeOff {
+		safe = services.SafeModerate
+	}
+
+	minReliability, err := strconv.ParseFloat(c.Query("min_reliability"), 64)
+	if err != nil || minReliability < 0 {
+		minReliability = 0
+	}
+
+	state := strings.ToLower(c.Query("state"))
+	if !services.ValidState(state) {
+		state = ""
 	}
 
-	article, err := readability.FromReader(resp.Body, parsedURL)
+	filter := services.ArticleFilter{
+		ExcludePaywalled: excludePaywalled,
+		Safe:             safe,
+		MinReliability:   minReliability,
+		State:            state,
+	}
+	location, err := clientLocation(c)
 	if err != nil {
-		return "", err
+		return filter, err
 	}
+	if period := strings.ToLower(c.Query("period")); period != "" {
+		var ok bool
+		filter.PublishedFrom, filter.PublishedTo, ok = services.PeriodBounds(period, time.Now().In(location))
+		if !ok {
+			return filter, apperr.InvalidFilterf("period must be %s or %s", services.PeriodToday, services.PeriodThisWeek)
+		}
+	}
+	return filter, nil
+}
 
-	return article.TextContent, nil
+// clientLocation returns the client's timezone: the tz parameter, an IANA name
+// like Asia/Kolkata, or else the timezone of the request's tenant, or UTC.
+// Unknown names are an ErrInvalidFilter.
+func clientLocation(c *gin.Context) (*time.Location, error) {
+	if name := c.Query("tz"); name != "" {
+		location, err := time.LoadLocation(name)
+		if err != nil || name == "Local" {
+			return nil, apperr.InvalidFilterf("unknown timezone %q, expected an IANA name like Asia/Kolkata", name)
+		}
+		return location, nil
+	}
+	if t, ok := tenant.FromContext(c.Request.Context()); ok {
+		return t.Location(), nil
+	}
+	return time.UTC, nil
 }
 
-var stopWords = map[string]struct{}{
-	"a": {}, "about": {}, "above": {}, "after": {}, "again": {}, "against": {}, "all": {}, "am": {}, "an": {}, "and": {}, "any": {}, "are": {}, "as": {}, "at": {},
-	"be": {}, "because": {}, "been": {}, "before": {}, "being": {}, "below": {}, "between": {}, "both": {}, "but": {}, "by": {},
-	"can": {}, "did": {}, "do": {}, "does": {}, "doing": {}, "don": {}, "down": {}, "during": {},
-	"each": {},
-	"few":  {}, "for": {}, "from": {}, "further": {},
-	"had": {}, "has": {}, "have": {}, "having": {}, "he": {}, "her": {}, "here": {}, "hers": {}, "herself": {}, "him": {}, "himself": {}, "his": {}, "how": {},
-	"i": {}, "if": {}, "in": {}, "into": {}, "is": {}, "it": {}, "its": {}, "itself": {},
-	"just": {},
-	"me":   {}, "more": {}, "most": {}, "my": {}, "myself": {},
-	"no": {}, "nor": {}, "not": {},
-	"of": {}, "off": {}, "on": {}, "once": {}, "only": {}, "or": {}, "other": {}, "our": {}, "ours": {}, "ourselves": {}, "out": {}, "over": {}, "own": {},
-	"s": {}, "same": {}, "she": {}, "should": {}, "so": {}, "some": {}, "such": {},
-	"t": {}, "than": {}, "that": {}, "the": {}, "their": {}, "theirs": {}, "them": {}, "themselves": {}, "then": {}, "there": {}, "these": {}, "they": {}, "this": {}, "those": {}, "through": {}, "to": {}, "too": {},
-	"under": {}, "until": {}, "up": {},
-	"very": {},
-	"was":  {}, "we": {}, "were": {}, "what": {}, "when": {}, "where": {}, "which": {}, "while": {}, "who": {}, "whom": {}, "why": {}, "will": {}, "with": {}, "would": {},
-	"you": {}, "your": {}, "yours": {}, "yourself": {}, "yourselves": {},
+// parseSearchBoosts reads the boost_title, boost_desc and boost_recent
+// parameters, keeping the default for missing or invalid values and capping
+// the rest to 0-MaxSearchBoost
+func parseSearchBoosts(c *gin.Context) services.SearchBoosts {
+	return searchBoostsFrom(c, services.DefaultSearchBoosts())
 }
 
-func filterStopWords(words []string) []string {
-	filtered := make([]string, 0, len(words))
-	for _, word := range words {
-		if _, found := stopWords[word]; !found {
-			filtered = append(filtered, word)
+// searchBoostsFrom overrides the given boosts with the request's boost
+// parameters
+func searchBoostsFrom(c *gin.Context, boosts services.SearchBoosts) services.SearchBoosts {
+	params := map[string]*float64{
+		"boost_title":  &boosts.Title,
+		"boost_desc":   &boosts.Description,
+		"boost_recent": &boosts.Recent,
+	}
+	for name, target := range params {
+		if value, err := strconv.ParseFloat(c.Query(name), 64); err == nil && !math.IsNaN(value) {
+			*target = value
 		}
 	}
-	return filtered
+	return boosts.Capped()
 }
 
 // Helper functions
@@ -0,0 +1,67 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListOutbox handles GET /admin/outbox, listing notifications by status, e.g.
+// the dead-lettered ones with status=dead
+func (h *AdminHandler) ListOutbox(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit <= 0 || limit > 500 {
+		limit = 50
+	}
+
+	messages, stats, err := h.svc.ListOutboxMessages(c.Request.Context(), c.Query("status"), c.Query("channel"), limit)
+	if err != nil {
+		respondError(c, err, "Failed to fetch outbox messages")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"messages": messages, "count": len(messages), "stats": stats})
+}
+
+// RetryOutboxMessage handles POST /admin/outbox/:id/retry, giving a dead
+// message a fresh set of attempts
+func (h *AdminHandler) RetryOutboxMessage(c *gin.Context) {
+	id, ok := outboxMessageID(c)
+	if !ok {
+		return
+	}
+
+	message, err := h.svc.RetryOutboxMessage(c.Request.Context(), id)
+	if err != nil {
+		respondError(c, err, "Failed to retry outbox message")
+		return
+	}
+
+	c.JSON(http.StatusOK, message)
+}
+
+// DiscardOutboxMessage handles DELETE /admin/outbox/:id
+func (h *AdminHandler) DiscardOutboxMessage(c *gin.Context) {
+	id, ok := outboxMessageID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.svc.DiscardOutboxMessage(c.Request.Context(), id); err != nil {
+		respondError(c, err, "Failed to discard outbox message")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// outboxMessageID parses the :id parameter, responding 400 when it is invalid
+func outboxMessageID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid message id"})
+		return 0, false
+	}
+	return uint(id), true
+}
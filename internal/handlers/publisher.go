@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mahigadamsetty/Inshorts-task/internal/config"
+	"github.com/mahigadamsetty/Inshorts-task/internal/publisher"
+	"github.com/mahigadamsetty/Inshorts-task/internal/scheduler"
+	"github.com/mahigadamsetty/Inshorts-task/internal/services"
+)
+
+// enrichmentJobs run, in order, after articles are pushed, so they are
+// enriched and searchable without waiting for the jobs' next tick
+var enrichmentJobs = []string{"text-fetch", "content-moderation", "search-index"}
+
+// PublisherHandler serves the API partner publishers push their articles with
+type PublisherHandler struct {
+	svc       *services.Services
+	scheduler *scheduler.Scheduler
+	config    *config.Config
+
+	mu        sync.Mutex
+	enriching bool
+	pending   bool
+}
+
+func NewPublisherHandler(cfg *config.Config, svc *services.Services, sched *scheduler.Scheduler) *PublisherHandler {
+	return &PublisherHandler{svc: svc, scheduler: sched, config: cfg}
+}
+
+// CreateArticle handles POST /publisher/articles, storing a new article of the
+// publisher's source
+func (h *PublisherHandler) CreateArticle(c *gin.Context) {
+	var input services.PublishedArticle
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	p, _ := publisher.FromContext(c.Request.Context())
+	article, err := h.svc.PublishArticle(c.Request.Context(), p.Source, input, h.publishOptions())
+	if err != nil {
+		respondError(c, err, "Failed to create article")
+		return
+	}
+	h.enqueueEnrichment()
+	c.JSON(http.StatusCreated, gin.H{"article": article})
+}
+
+// UpdateArticle handles PUT /publisher/articles/:id, replacing an article of
+// the publisher's source
+func (h *PublisherHandler) UpdateArticle(c *gin.Context) {
+	var input services.PublishedArticle
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	p, _ := publisher.FromContext(c.Request.Context())
+	article, err := h.svc.UpdatePublishedArticle(c.Request.Context(), p.Source, c.Param("id"), input, h.publishOptions())
+	if err != nil {
+		respondError(c, err, "Failed to update article")
+		return
+	}
+	h.enqueueEnrichment()
+	c.JSON(http.StatusOK, gin.H{"article": article})
+}
+
+// WithdrawArticle handles DELETE /publisher/articles/:id, hiding an article of
+// the publisher's source from clients
+func (h *PublisherHandler) WithdrawArticle(c *gin.Context) {
+	p, _ := publisher.FromContext(c.Request.Context())
+	article, err := h.svc.WithdrawArticle(c.Request.Context(), p.Source, c.Param("id"))
+	if err != nil {
+		respondError(c, err, "Failed to withdraw article")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"article": article})
+}
+
+// publishOptions returns the ingestion settings shared with imports
+func (h *PublisherHandler) publishOptions() services.PublishOptions {
+	return services.PublishOptions{
+		ResolveRedirects: h.config.ResolveURLRedirects,
+		ImputeLocations:  h.config.ImputeLocations,
+	}
+}
+
+// enqueueEnrichment runs the enrichment jobs in the background. Pushes during
+// a run start one more run after it rather than one each.
+func (h *PublisherHandler) enqueueEnrichment() {
+	h.mu.Lock()
+	if h.enriching {
+		h.pending = true
+		h.mu.Unlock()
+		return
+	}
+	h.enriching = true
+	h.mu.Unlock()
+
+	go func() {
+		for {
+			for _, name := range enrichmentJobs {
+				if err := h.scheduler.RunNow(context.Background(), name); err != nil {
+					log.Printf("Failed to run %s for pushed articles: %v", name, err)
+				}
+			}
+
+			h.mu.Lock()
+			if !h.pending {
+				h.enriching = false
+				h.mu.Unlock()
+				return
+			}
+			h.pending = false
+			h.mu.Unlock()
+		}
+	}()
+}
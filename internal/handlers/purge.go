@@ -0,0 +1,47 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mahigadamsetty/Inshorts-task/internal/services"
+)
+
+// PurgeArticles handles POST /admin/articles/purge. By default it only counts
+// the articles matching the filters and the records linked to them; with
+// dry_run false and the counted expected_articles it deletes them.
+func (h *AdminHandler) PurgeArticles(c *gin.Context) {
+	var input services.PurgeInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	filter, err := input.Filter()
+	if err != nil {
+		respondError(c, err, "Invalid purge filter")
+		return
+	}
+
+	ctx := c.Request.Context()
+	if input.DryRun == nil || *input.DryRun {
+		records, err := h.svc.CountPurge(ctx, filter)
+		if err != nil {
+			respondError(c, err, "Failed to count articles")
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"dry_run": true, "records": records})
+		return
+	}
+
+	if input.ExpectedArticles == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "expected_articles is required, run a dry run first"})
+		return
+	}
+	records, err := h.svc.PurgeArticles(ctx, filter, *input.ExpectedArticles)
+	if err != nil {
+		respondError(c, err, "Failed to purge articles")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"dry_run": false, "records": records})
+}
@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"bytes"
+	"html/template"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// readableTemplate renders an article for reader modes: its text and a link to
+// the original, without scripts or the publisher's markup
+var readableTemplate = template.Must(template.New("readable").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Title}}</title>
+<link rel="canonical" href="{{.URL}}">
+<style>body{max-width:40em;margin:0 auto;padding:1em;font:1.1em/1.6 Georgia,serif;color:#222}header p,footer{color:#666;font-size:.85em}</style>
+</head>
+<body>
+<article>
+<header>
+<h1>{{.Title}}</h1>
+<p>{{.SourceName}} · <time datetime="{{.PublicationDate.UTC.Format "2006-01-02T15:04:05Z07:00"}}">{{.PublicationDate.UTC.Format "2 Jan 2006"}}</time>{{if .ReadingMinutes}} · {{.ReadingMinutes}} min read{{end}}</p>
+</header>
+{{range .Paragraphs}}<p>{{.}}</p>
+{{end}}<footer><p>{{if not .FullText}}This is an excerpt. {{end}}<a href="{{.URL}}" rel="noopener">Read the original at {{.SourceName}}</a></p></footer>
+</article>
+</body>
+</html>
+`))
+
+// GetReadable handles /:id/readable, returning the article's stored text as
+// clean HTML or, with format=json, as paragraphs
+func (h *NewsHandler) GetReadable(c *gin.Context) {
+	format := c.DefaultQuery("format", "html")
+	if format != "html" && format != "json" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be html or json"})
+		return
+	}
+
+	filter, err := parseArticleFilter(c)
+	if err != nil {
+		respondError(c, err, "Invalid filter")
+		return
+	}
+	readable, err := h.svc.GetReadableArticle(c.Request.Context(), c.Param("id"), filter)
+	if err != nil {
+		respondError(c, err, "Failed to fetch article")
+		return
+	}
+
+	if format == "json" {
+		c.JSON(http.StatusOK, readable)
+		return
+	}
+	var page bytes.Buffer
+	if err := readableTemplate.Execute(&page, readable); err != nil {
+		respondError(c, err, "Failed to render article")
+		return
+	}
+	c.Header("Content-Security-Policy", "default-src 'none'; style-src 'unsafe-inline'")
+	c.Data(http.StatusOK, "text/html; charset=utf-8", page.Bytes())
+}
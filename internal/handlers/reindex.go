@@ -0,0 +1,36 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Reindex handles POST /admin/reindex, rebuilding the embedding index in the background
+func (h *AdminHandler) Reindex(c *gin.Context) {
+	job, err := h.reindexer.Submit(c.Request.Context())
+	if err != nil {
+		respondError(c, err, "Failed to start reindex")
+		return
+	}
+
+	c.JSON(http.StatusAccepted, job)
+}
+
+// GetReindexJob handles /admin/reindex/:id endpoint
+func (h *AdminHandler) GetReindexJob(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid job id"})
+		return
+	}
+
+	job, err := h.svc.GetReindexJob(c.Request.Context(), uint(id))
+	if err != nil {
+		respondError(c, err, "Failed to fetch job")
+		return
+	}
+
+	c.JSON(http.StatusOK, job)
+}
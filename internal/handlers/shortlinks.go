@@ -0,0 +1,148 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mahigadamsetty/Inshorts-task/internal/config"
+	"github.com/mahigadamsetty/Inshorts-task/internal/models"
+	"github.com/mahigadamsetty/Inshorts-task/internal/services"
+	"github.com/mahigadamsetty/Inshorts-task/internal/tenant"
+)
+
+// linkPreviewAgents are fragments of the user agents of crawlers and chat apps
+// that fetch shared links to unfurl them. Their requests are redirected but
+// not counted as clicks.
+var linkPreviewAgents = []string{"bot", "crawler", "spider", "facebookexternalhit", "whatsapp", "skypeuripreview", "embedly", "preview"}
+
+// ShortLinkHandler creates short links to articles, redirects them and reports
+// their clicks
+type ShortLinkHandler struct {
+	svc     *services.Services
+	config  *config.Config
+	tenants *tenant.Registry
+}
+
+func NewShortLinkHandler(cfg *config.Config, svc *services.Services, tenants *tenant.Registry) *ShortLinkHandler {
+	return &ShortLinkHandler{svc: svc, config: cfg, tenants: tenants}
+}
+
+// ShortLinkResponse is a short link with its shareable URL
+type ShortLinkResponse struct {
+	*models.ShortLink
+	ShortURL string `json:"short_url"`
+}
+
+// ShortLinkStatsResponse is a short link's clicks with its shareable URL
+type ShortLinkStatsResponse struct {
+	*services.ShortLinkStats
+	ShortURL string `json:"short_url"`
+}
+
+// Create handles POST /:id/shortlink, returning the article's short link for
+// the channel and campaign in the body, created on the first request
+func (h *ShortLinkHandler) Create(c *gin.Context) {
+	var input services.ShortLinkInput
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&input); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+			return
+		}
+	}
+
+	link, created, err := h.svc.CreateShortLink(c.Request.Context(), c.Param("id"), input)
+	if err != nil {
+		respondError(c, err, "Failed to create short link")
+		return
+	}
+
+	status := http.StatusOK
+	if created {
+		status = http.StatusCreated
+	}
+	c.JSON(status, ShortLinkResponse{ShortLink: link, ShortURL: h.shortURL(c, link.Code)})
+}
+
+// Stats handles /shortlinks/:code, returning a short link's clicks, estimated
+// unique visitors and clicks per day over the last days days (default 30)
+func (h *ShortLinkHandler) Stats(c *gin.Context) {
+	days, err := strconv.Atoi(c.DefaultQuery("days", "30"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid days"})
+		return
+	}
+
+	stats, err := h.svc.GetShortLinkStats(c.Request.Context(), c.Param("code"), days)
+	if err != nil {
+		respondError(c, err, "Failed to fetch short link")
+		return
+	}
+	c.JSON(http.StatusOK, ShortLinkStatsResponse{ShortLinkStats: stats, ShortURL: h.shortURL(c, stats.Code)})
+}
+
+// Follow handles /s/:code, counting the click and redirecting to the article's
+// page on the public site, or to the original when there is none. Links need
+// no API key, so they are resolved as the link's tenant.
+func (h *ShortLinkHandler) Follow(c *gin.Context) {
+	link, err := h.svc.GetShortLink(c.Request.Context(), c.Param("code"))
+	if err != nil {
+		respondError(c, err, "Failed to resolve short link")
+		return
+	}
+	t, ok := h.tenants.Get(link.TenantID)
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Short link not found"})
+		return
+	}
+	ctx := tenant.NewContext(c.Request.Context(), t)
+
+	article, err := h.svc.ShortLinkArticle(ctx, link)
+	if err != nil {
+		respondError(c, err, "Failed to resolve short link")
+		return
+	}
+	if !isLinkPreview(c.Request.UserAgent()) {
+		if err := h.svc.RecordShortLinkClick(ctx, link, clientIdentity(c, "")); err != nil {
+			log.Printf("Failed to record click on short link %s: %v", link.Code, err)
+		}
+	}
+
+	target := h.config.ArticlePageURL(article.ID)
+	if target == "" {
+		target = article.URL
+	}
+	// Temporary, so browsers come back through the link and every click counts
+	c.Header("Cache-Control", "no-store")
+	c.Redirect(http.StatusFound, target)
+}
+
+// shortURL returns the shareable URL of a code: under SHORTLINK_BASE_URL,
+// SITE_URL or, without either, the host the request was sent to
+func (h *ShortLinkHandler) shortURL(c *gin.Context, code string) string {
+	base := h.config.ShortLinkBaseURL
+	if base == "" {
+		base = h.config.SiteURL
+	}
+	if base == "" {
+		scheme := "http"
+		if c.Request.TLS != nil || c.GetHeader("X-Forwarded-Proto") == "https" {
+			scheme = "https"
+		}
+		base = scheme + "://" + c.Request.Host
+	}
+	return strings.TrimRight(base, "/") + "/s/" + code
+}
+
+// isLinkPreview reports whether a user agent is a crawler or link unfurler
+func isLinkPreview(userAgent string) bool {
+	userAgent = strings.ToLower(userAgent)
+	for _, agent := range linkPreviewAgents {
+		if strings.Contains(userAgent, agent) {
+			return true
+		}
+	}
+	return false
+}
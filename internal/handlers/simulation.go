@@ -0,0 +1,67 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mahigadamsetty/Inshorts-task/internal/services"
+)
+
+// SimulationInput toggles the event simulation; fields left out keep their
+// current values. A profile is played in the background once the simulation
+// is enabled.
+type SimulationInput struct {
+	Enabled           *bool  `json:"enabled"`
+	IncludeInTrending *bool  `json:"include_in_trending"`
+	Profile           string `json:"profile"`
+	Seed              *int64 `json:"seed"` // Defaults to a time-based seed
+}
+
+// GetSimulation handles GET /admin/simulation
+func (h *AdminHandler) GetSimulation(c *gin.Context) {
+	c.JSON(http.StatusOK, h.svc.GetSimulationStatus())
+}
+
+// UpdateSimulation handles PUT /admin/simulation, enabling or disabling the
+// event simulation and optionally starting a profile. Disabling it stops
+// every running profile.
+func (h *AdminHandler) UpdateSimulation(c *gin.Context) {
+	var input SimulationInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	var profile services.SimulationProfile
+	if input.Profile != "" {
+		var err error
+		profile, err = services.LoadSimulationProfile(h.config.SimulationProfilesFile, input.Profile)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	if input.Enabled != nil {
+		h.svc.EnableSimulation(*input.Enabled)
+	}
+	if input.IncludeInTrending != nil {
+		h.svc.IncludeSimulatedInTrending(*input.IncludeInTrending)
+	}
+	if input.Profile != "" {
+		seed := time.Now().UnixNano()
+		if input.Seed != nil {
+			seed = *input.Seed
+		}
+		// The profile outlives the request, until it ends or the simulation is disabled
+		err := h.svc.StartEventSimulation(context.Background(), profile, seed)
+		if err != nil {
+			respondError(c, err, "Failed to start simulation profile")
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, h.svc.GetSimulationStatus())
+}
@@ -0,0 +1,151 @@
+package handlers
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mahigadamsetty/Inshorts-task/internal/config"
+	"github.com/mahigadamsetty/Inshorts-task/internal/services"
+	"github.com/mahigadamsetty/Inshorts-task/internal/tenant"
+)
+
+// sitemapNamespace is the XML namespace of the sitemap protocol
+const sitemapNamespace = "http://www.sitemaps.org/schemas/sitemap/0.9"
+
+// sitemapLocation is a <sitemap> entry of the index or a <url> entry of a page
+type sitemapLocation struct {
+	Loc     string `xml:"loc"`
+	LastMod string `xml:"lastmod,omitempty"`
+}
+
+type sitemapIndex struct {
+	XMLName  xml.Name          `xml:"sitemapindex"`
+	Xmlns    string            `xml:"xmlns,attr"`
+	Sitemaps []sitemapLocation `xml:"sitemap"`
+}
+
+type sitemapURLSet struct {
+	XMLName xml.Name          `xml:"urlset"`
+	Xmlns   string            `xml:"xmlns,attr"`
+	URLs    []sitemapLocation `xml:"url"`
+}
+
+// SitemapHandler serves the sitemap and robots.txt of the public site backed
+// by the API. Articles are listed as the site tenant's clients see them.
+type SitemapHandler struct {
+	svc    *services.Services
+	config *config.Config
+	site   *tenant.Tenant
+}
+
+func NewSitemapHandler(cfg *config.Config, svc *services.Services, site *tenant.Tenant) *SitemapHandler {
+	return &SitemapHandler{svc: svc, config: cfg, site: site}
+}
+
+// Index handles /sitemap.xml, listing the sitemap pages
+func (h *SitemapHandler) Index(c *gin.Context) {
+	ctx := tenant.NewContext(c.Request.Context(), h.site)
+	pages, err := h.svc.SitemapPages(ctx, h.pageSize())
+	if err != nil {
+		respondError(c, err, "Failed to build sitemap")
+		return
+	}
+
+	index := sitemapIndex{Xmlns: sitemapNamespace, Sitemaps: make([]sitemapLocation, 0, len(pages))}
+	var lastModified time.Time
+	for _, page := range pages {
+		index.Sitemaps = append(index.Sitemaps, sitemapLocation{
+			Loc:     h.siteURL(fmt.Sprintf("/sitemaps/%d.xml", page.Number)),
+			LastMod: page.LastModified.UTC().Format(time.RFC3339),
+		})
+		if page.LastModified.After(lastModified) {
+			lastModified = page.LastModified
+		}
+	}
+	h.writeXML(c, index, lastModified)
+}
+
+// Page handles /sitemaps/:page, listing the article pages of one sitemap page
+func (h *SitemapHandler) Page(c *gin.Context) {
+	number, err := strconv.Atoi(strings.TrimSuffix(c.Param("page"), ".xml"))
+	if err != nil || number < 1 || !strings.HasSuffix(c.Param("page"), ".xml") {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Sitemap not found"})
+		return
+	}
+
+	ctx := tenant.NewContext(c.Request.Context(), h.site)
+	entries, err := h.svc.SitemapArticles(ctx, number, h.pageSize())
+	if err != nil {
+		respondError(c, err, "Failed to build sitemap")
+		return
+	}
+
+	set := sitemapURLSet{Xmlns: sitemapNamespace, URLs: make([]sitemapLocation, 0, len(entries))}
+	var lastModified time.Time
+	for _, entry := range entries {
+		set.URLs = append(set.URLs, sitemapLocation{
+			Loc:     h.config.ArticlePageURL(entry.ID),
+			LastMod: entry.PublicationDate.UTC().Format(time.RFC3339),
+		})
+		if entry.CreatedAt.After(lastModified) {
+			lastModified = entry.CreatedAt
+		}
+	}
+	h.writeXML(c, set, lastModified)
+}
+
+// Robots handles /robots.txt, pointing crawlers at the sitemap
+func (h *SitemapHandler) Robots(c *gin.Context) {
+	var b strings.Builder
+	b.WriteString("User-agent: *\n")
+	for _, path := range h.config.RobotsDisallow {
+		fmt.Fprintf(&b, "Disallow: %s\n", path)
+	}
+	fmt.Fprintf(&b, "\nSitemap: %s\n", h.siteURL("/sitemap.xml"))
+
+	h.setCacheHeaders(c, time.Time{})
+	c.Data(http.StatusOK, "text/plain; charset=utf-8", []byte(b.String()))
+}
+
+// writeXML sends a sitemap document, or 304 when the client's copy is no older
+// than lastModified
+func (h *SitemapHandler) writeXML(c *gin.Context, document interface{}, lastModified time.Time) {
+	h.setCacheHeaders(c, lastModified)
+	if since, err := http.ParseTime(c.GetHeader("If-Modified-Since")); err == nil && !lastModified.IsZero() && !lastModified.Truncate(time.Second).After(since) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+
+	body, err := xml.Marshal(document)
+	if err != nil {
+		respondError(c, err, "Failed to build sitemap")
+		return
+	}
+	c.Data(http.StatusOK, "application/xml; charset=utf-8", append([]byte(xml.Header), body...))
+}
+
+// setCacheHeaders lets crawlers and CDNs cache a response for the configured time
+func (h *SitemapHandler) setCacheHeaders(c *gin.Context, lastModified time.Time) {
+	c.Header("Cache-Control", fmt.Sprintf("public, max-age=%d", h.config.SitemapCacheSeconds))
+	if !lastModified.IsZero() {
+		c.Header("Last-Modified", lastModified.UTC().Format(http.TimeFormat))
+	}
+}
+
+// siteURL returns the public site's URL of a path
+func (h *SitemapHandler) siteURL(path string) string {
+	return strings.TrimRight(h.config.SiteURL, "/") + path
+}
+
+// pageSize returns the configured URLs per sitemap page, within the protocol's limit
+func (h *SitemapHandler) pageSize() int {
+	if h.config.SitemapPageSize <= 0 || h.config.SitemapPageSize > services.MaxSitemapURLs {
+		return services.MaxSitemapURLs
+	}
+	return h.config.SitemapPageSize
+}
@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mahigadamsetty/Inshorts-task/internal/services"
+)
+
+// ListSources handles /admin/sources endpoint
+func (h *AdminHandler) ListSources(c *gin.Context) {
+	sources, err := h.svc.ListSources(c.Request.Context())
+	if err != nil {
+		respondError(c, err, "Failed to fetch sources")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"sources": sources, "count": len(sources)})
+}
+
+// SaveSource handles PUT /admin/sources/:name, creating or replacing a source's metadata
+func (h *AdminHandler) SaveSource(c *gin.Context) {
+	var input services.SourceInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+	input.Name = c.Param("name")
+
+	source, err := input.Source()
+	if err != nil {
+		respondError(c, err, "Invalid source")
+		return
+	}
+
+	if err := h.svc.SaveSource(c.Request.Context(), &source); err != nil {
+		respondError(c, err, "Failed to save source")
+		return
+	}
+
+	c.JSON(http.StatusOK, source)
+}
+
+// DeleteSource handles DELETE /admin/sources/:name
+func (h *AdminHandler) DeleteSource(c *gin.Context) {
+	if err := h.svc.DeleteSource(c.Request.Context(), c.Param("name")); err != nil {
+		respondError(c, err, "Failed to delete source")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
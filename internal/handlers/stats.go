@@ -0,0 +1,248 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mahigadamsetty/Inshorts-task/internal/models"
+	"github.com/mahigadamsetty/Inshorts-task/internal/services"
+	"github.com/mahigadamsetty/Inshorts-task/internal/tenant"
+)
+
+// EventInput is a client-reported interaction with an article
+type EventInput struct {
+	ArticleID string  `json:"article_id" binding:"required"`
+	EventType string  `json:"event_type"` // view or click, default view
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+	ClientID  string  `json:"client_id"` // Anonymous viewer identifier, hashed into the unique-viewer sketch
+	UserID    string  `json:"user_id"`   // Signed-in user, used like client_id when that is absent
+}
+
+// EventBatchInput is a batch of interactions submitted together
+type EventBatchInput struct {
+	Events []EventInput `json:"events" binding:"required"`
+}
+
+// RejectedEvent is an event of a batch that was not accepted, by its 0-based
+// index in the batch
+type RejectedEvent struct {
+	Index int    `json:"index"`
+	Error string `json:"error"`
+}
+
+// RecordEvent handles POST /events, ingesting a view or click. Without a
+// client_id or user_id, the X-Client-ID header or the caller's IP and user
+// agent identify the viewer. With ?precise=false the location is kept at city
+// level.
+func (h *NewsHandler) RecordEvent(c *gin.Context) {
+	var input EventInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	event, clientID, err := h.newEvent(c, input)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := h.svc.CheckArticle(c.Request.Context(), input.ArticleID); err != nil {
+		respondError(c, err, "Failed to fetch article")
+		return
+	}
+
+	events := []models.Event{event}
+	if !h.queueEvents(c, events, []string{clientID}) {
+		return
+	}
+	event = events[0]
+	event.LimitCoordinates(h.svc.ResponseCoordinates(c.Request.Context()))
+	c.JSON(http.StatusAccepted, event)
+}
+
+// RecordEventBatch handles POST /events/batch, ingesting up to EVENT_BATCH_MAX
+// events at once. Valid events are accepted even when others of the batch are
+// rejected; a batch with none valid is rejected with 400.
+func (h *NewsHandler) RecordEventBatch(c *gin.Context) {
+	var input EventBatchInput
+	if err := c.ShouldBindJSON(&input); err != nil || len(input.Events) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+	if len(input.Events) > h.config.EventBatchMax {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("A batch holds at most %d events", h.config.EventBatchMax)})
+		return
+	}
+
+	articleIDs := make([]string, 0, len(input.Events))
+	for _, in := range input.Events {
+		articleIDs = append(articleIDs, in.ArticleID)
+	}
+	var found []string
+	if err := h.db.WithContext(c.Request.Context()).Model(&models.Article{}).Where("id IN ?", articleIDs).Pluck("id", &found).Error; err != nil {
+		respondError(c, err, "Failed to fetch articles")
+		return
+	}
+	exists := make(map[string]bool, len(found))
+	for _, id := range found {
+		exists[id] = true
+	}
+
+	var events []models.Event
+	var clientIDs []string
+	rejected := []RejectedEvent{}
+	for i, in := range input.Events {
+		event, clientID, err := h.newEvent(c, in)
+		if err == nil && !exists[in.ArticleID] {
+			err = errors.New("Article not found")
+		}
+		if err != nil {
+			rejected = append(rejected, RejectedEvent{Index: i, Error: err.Error()})
+			continue
+		}
+		events = append(events, event)
+		clientIDs = append(clientIDs, clientID)
+	}
+	if len(events) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "No valid events in batch", "rejected": rejected})
+		return
+	}
+
+	if !h.queueEvents(c, events, clientIDs) {
+		return
+	}
+	c.JSON(http.StatusAccepted, gin.H{"accepted": len(events), "rejected": rejected})
+}
+
+// newEvent validates a reported interaction and returns the event to record
+// with the client ID identifying its viewer
+func (h *NewsHandler) newEvent(c *gin.Context, input EventInput) (models.Event, string, error) {
+	if input.ArticleID == "" {
+		return models.Event{}, "", fmt.Errorf("article_id is required")
+	}
+	eventType := models.EventType(input.EventType)
+	switch eventType {
+	case "":
+		eventType = models.EventTypeView
+	case models.EventTypeView, models.EventTypeClick:
+	default:
+		return models.Event{}, "", fmt.Errorf("event_type must be view or click")
+	}
+	if input.Latitude < -90 || input.Latitude > 90 || input.Longitude < -180 || input.Longitude > 180 {
+		return models.Event{}, "", fmt.Errorf("latitude must be between -90 and 90 and longitude between -180 and 180")
+	}
+
+	viewerID := input.ClientID
+	if viewerID == "" {
+		viewerID = input.UserID
+	}
+	event := models.Event{
+		ArticleID: input.ArticleID,
+		EventType: eventType,
+		Viewer:    services.SessionViewer(c.Request.Context(), anonymousID(c, viewerID)),
+	}
+	event.Latitude, event.Longitude = h.svc.PrivateLocation(input.Latitude, input.Longitude, preciseLocation(c))
+	return event, clientIdentity(c, viewerID), nil
+}
+
+// queueEvents hands events to the event queue, responding with 503 when it
+// is full and as for the request quota when they exceed the event quota, and
+// reports whether they were accepted
+func (h *NewsHandler) queueEvents(c *gin.Context, events []models.Event, clientIDs []string) bool {
+	err := h.svc.QueueEvents(c.Request.Context(), events, clientIDs)
+	var quotaErr *tenant.QuotaError
+	if errors.As(err, &quotaErr) {
+		tenant.RespondQuota(c, quotaErr, h.config.QuotaStatus())
+		return false
+	}
+	if errors.Is(err, services.ErrEventQueueFull) {
+		c.Header("Retry-After", strconv.Itoa(h.config.EventFlushInterval))
+	}
+	if err != nil {
+		respondError(c, err, "Failed to record event")
+		return false
+	}
+	return true
+}
+
+// clientIdentity identifies the viewer behind a request: by the client ID it
+// sent, the X-Client-ID header or its IP and user agent
+func clientIdentity(c *gin.Context, clientID string) string {
+	if clientID = anonymousID(c, clientID); clientID == "" {
+		clientID = c.ClientIP() + "|" + c.Request.UserAgent()
+	}
+	return clientID
+}
+
+// anonymousID returns the client-generated ID a request sent, in its body or
+// the X-Client-ID header, or "" when it sent none
+func anonymousID(c *gin.Context, clientID string) string {
+	if clientID == "" {
+		clientID = c.GetHeader("X-Client-ID")
+	}
+	return clientID
+}
+
+// GetStats handles /stats endpoint, returning interaction totals and the most
+// viewed articles, or one article's counters when article_id is given
+func (h *NewsHandler) GetStats(c *gin.Context) {
+	if articleID := c.Query("article_id"); articleID != "" {
+		views, err := h.svc.GetArticleViews(c.Request.Context(), articleID)
+		if err != nil {
+			respondError(c, err, "Failed to fetch article stats")
+			return
+		}
+		c.JSON(http.StatusOK, views)
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "10"))
+	if err != nil || limit <= 0 {
+		limit = 10
+	}
+
+	totals, err := h.svc.GetViewTotals(c.Request.Context())
+	if err != nil {
+		respondError(c, err, "Failed to fetch stats")
+		return
+	}
+	top, err := h.svc.TopViewedArticles(c.Request.Context(), limit)
+	if err != nil {
+		respondError(c, err, "Failed to fetch stats")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"totals": totals, "top_articles": limitArticles(top, h.svc.ResponseCoordinates(c.Request.Context()))})
+}
+
+// maxScoreHistoryDays caps how far back /stats/history reaches
+const maxScoreHistoryDays = 365
+
+// GetScoreHistory handles /stats/history, returning an article's nightly
+// score samples for charting
+func (h *NewsHandler) GetScoreHistory(c *gin.Context) {
+	articleID := c.Query("article_id")
+	if articleID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "article_id parameter is required"})
+		return
+	}
+	days, err := strconv.Atoi(c.DefaultQuery("days", "30"))
+	if err != nil || days <= 0 {
+		days = 30
+	}
+	if days > maxScoreHistoryDays {
+		days = maxScoreHistoryDays
+	}
+
+	history, err := h.svc.ScoreHistoryOf(c.Request.Context(), articleID, time.Now().AddDate(0, 0, -days))
+	if err != nil {
+		respondError(c, err, "Failed to fetch score history")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"article_id": articleID, "days": days, "history": history, "count": len(history)})
+}
@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mahigadamsetty/Inshorts-task/internal/degradation"
+	"github.com/mahigadamsetty/Inshorts-task/internal/models"
+)
+
+type StoriesResponse struct {
+	Stories []models.Story `json:"stories"`
+	Meta    Meta           `json:"meta"`
+}
+
+type StoryResponse struct {
+	Story    models.Story     `json:"story"`
+	Timeline []models.Article `json:"timeline"`
+}
+
+// GetStories handles /stories endpoint, optionally only the stories in one
+// lifecycle state
+func (h *NewsHandler) GetStories(c *gin.Context) {
+	limitStr := c.DefaultQuery("limit", "5")
+
+	limit, err := strconv.Atoi(limitStr)
+	if err != nil || limit <= 0 {
+		limit = 5
+	}
+
+	filter, err := parseArticleFilter(c)
+	if err != nil {
+		respondError(c, err, "Invalid filter")
+		return
+	}
+	stories, err := h.svc.ListStories(c.Request.Context(), filter.State, limit)
+	if err != nil {
+		respondError(c, err, "Failed to fetch stories")
+		return
+	}
+
+	h.respondWithCoordinates(c, http.StatusOK, &StoriesResponse{
+		Stories: stories,
+		Meta: Meta{
+			Count:       len(stories),
+			Limit:       limit,
+			Endpoint:    "stories",
+			Degradation: degradation.Modes(c.Request.Context()),
+		},
+	})
+}
+
+// GetStory handles /stories/:id endpoint, returning the story timeline
+func (h *NewsHandler) GetStory(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid story id"})
+		return
+	}
+
+	filter, err := parseArticleFilter(c)
+	if err != nil {
+		respondError(c, err, "Invalid filter")
+		return
+	}
+	story, articles, err := h.svc.GetStory(c.Request.Context(), uint(id), filter)
+	if err != nil {
+		respondError(c, err, "Failed to fetch story")
+		return
+	}
+
+	// Generate the combined summary on first read
+	if story.Summary == "" && len(articles) > 0 {
+		headlines := make([]string, len(articles))
+		for i, article := range articles {
+			headlines[i] = article.Title
+		}
+		summary, err := h.llm(c).GenerateStorySummary(headlines)
+		if err == nil {
+			story.Summary = summary
+			if err := h.svc.SaveStorySummary(c.Request.Context(), story.ID, summary); err != nil {
+				log.Printf("Failed to save summary for story %d: %v", story.ID, err)
+			}
+		} else {
+			log.Printf("Failed to generate summary for story %d: %v", story.ID, err)
+		}
+	}
+
+	h.respondWithCoordinates(c, http.StatusOK, &StoryResponse{
+		Story:    *story,
+		Timeline: articles,
+	})
+}
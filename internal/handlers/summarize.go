@@ -0,0 +1,65 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mahigadamsetty/Inshorts-task/internal/services"
+)
+
+// SummarizeRequest is the body of POST /admin/summarize
+type SummarizeRequest struct {
+	ArticleIDs []string `json:"article_ids"`
+	Priority   string   `json:"priority"` // low, normal or high (default: normal)
+}
+
+// Summarize handles POST /admin/summarize, queueing articles for summary regeneration
+func (h *AdminHandler) Summarize(c *gin.Context) {
+	var req SummarizeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+	if req.Priority == "" {
+		req.Priority = services.SummaryPriorityNormal
+	}
+	if !services.ValidSummaryPriority(req.Priority) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "priority must be low, normal or high"})
+		return
+	}
+	if len(req.ArticleIDs) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "article_ids is required"})
+		return
+	}
+	if len(req.ArticleIDs) > h.config.SummarizeMaxArticles {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("at most %d article_ids per request", h.config.SummarizeMaxArticles)})
+		return
+	}
+
+	job, err := h.summarizer.Submit(c.Request.Context(), req.ArticleIDs, req.Priority)
+	if err != nil {
+		respondError(c, err, "Failed to submit summary job")
+		return
+	}
+
+	c.JSON(http.StatusAccepted, job)
+}
+
+// GetSummaryJob handles /admin/jobs/:id endpoint
+func (h *AdminHandler) GetSummaryJob(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid job id"})
+		return
+	}
+
+	job, err := h.svc.GetSummaryJob(c.Request.Context(), uint(id))
+	if err != nil {
+		respondError(c, err, "Failed to fetch job")
+		return
+	}
+
+	c.JSON(http.StatusOK, job)
+}
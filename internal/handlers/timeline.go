@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mahigadamsetty/Inshorts-task/internal/degradation"
+	"github.com/mahigadamsetty/Inshorts-task/internal/models"
+	"github.com/mahigadamsetty/Inshorts-task/internal/services"
+)
+
+// maxTimelineArticles caps how many articles a timeline may span
+const maxTimelineArticles = 500
+
+type TimelineResponse struct {
+	Buckets  []services.TimelineBucket `json:"buckets"`
+	Interval string                    `json:"interval"`
+	Meta     Meta                      `json:"meta"`
+}
+
+// GetTimeline handles /timeline endpoint
+func (h *NewsHandler) GetTimeline(c *gin.Context) {
+	query := c.Query("query")
+	interval := c.DefaultQuery("interval", services.IntervalDay)
+	limitStr := c.DefaultQuery("limit", "100")
+	notes, _ := strconv.ParseBool(c.Query("notes"))
+
+	if query == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "query parameter is required"})
+		return
+	}
+
+	if interval != services.IntervalDay && interval != services.IntervalWeek {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "interval must be day or week"})
+		return
+	}
+
+	limit, err := strconv.Atoi(limitStr)
+	if err != nil || limit <= 0 {
+		limit = 100
+	}
+	if limit > maxTimelineArticles {
+		limit = maxTimelineArticles
+	}
+
+	filter, err := parseArticleFilter(c)
+	if err != nil {
+		respondError(c, err, "Invalid filter")
+		return
+	}
+	database := h.db.WithContext(c.Request.Context())
+	var articles []models.Article
+
+	// Take the most recent matches, then lay them out oldest first
+	err = database.Model(&models.Article{}).
+		Scopes(filter.Scope).
+		Where(keywordMatch(c.Request.Context(), h.svc, database, query, services.SearchScope{Filter: filter})).
+		Order("publication_date DESC").
+		Order("id").
+		Limit(limit).
+		Find(&articles).Error
+
+	if err != nil {
+		respondError(c, err, "Failed to fetch articles")
+		return
+	}
+
+	h.svc.AttachSourceMeta(c.Request.Context(), articles)
+
+	for i, j := 0, len(articles)-1; i < j; i, j = i+1, j-1 {
+		articles[i], articles[j] = articles[j], articles[i]
+	}
+
+	buckets := services.BucketArticles(articles, interval)
+
+	// Optionally describe what changed from one bucket to the next
+	if notes {
+		var previous []string
+		for i := range buckets {
+			current := make([]string, len(buckets[i].Articles))
+			for j, article := range buckets[i].Articles {
+				current[j] = article.Title
+			}
+			if note, err := h.llm(c).GenerateChangeNote(previous, current); err == nil {
+				buckets[i].Note = note
+			}
+			previous = current
+		}
+	}
+
+	h.respondWithCoordinates(c, http.StatusOK, &TimelineResponse{
+		Buckets:  buckets,
+		Interval: interval,
+		Meta: Meta{
+			Count:       len(articles),
+			Limit:       limit,
+			Endpoint:    "timeline",
+			Query:       query,
+			Degradation: degradation.Modes(c.Request.Context()),
+		},
+	})
+}
@@ -0,0 +1,83 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mahigadamsetty/Inshorts-task/internal/degradation"
+	"github.com/mahigadamsetty/Inshorts-task/internal/models"
+)
+
+type TopicsResponse struct {
+	Topics []models.Topic `json:"topics"`
+	Meta   Meta           `json:"meta"`
+}
+
+type TopicResponse struct {
+	Topic    models.Topic     `json:"topic"`
+	Articles []models.Article `json:"articles"`
+	Meta     Meta             `json:"meta"`
+}
+
+// GetTopics handles /topics endpoint, listing the largest topics first
+func (h *NewsHandler) GetTopics(c *gin.Context) {
+	limitStr := c.DefaultQuery("limit", "10")
+
+	limit, err := strconv.Atoi(limitStr)
+	if err != nil || limit <= 0 {
+		limit = 10
+	}
+
+	topics, err := h.svc.ListTopics(c.Request.Context(), limit)
+	if err != nil {
+		respondError(c, err, "Failed to fetch topics")
+		return
+	}
+
+	c.JSON(http.StatusOK, TopicsResponse{
+		Topics: topics,
+		Meta: Meta{
+			Count:       len(topics),
+			Limit:       limit,
+			Endpoint:    "topics",
+			Degradation: degradation.Modes(c.Request.Context()),
+		},
+	})
+}
+
+// GetTopic handles /topics/:id endpoint, returning the topic's latest articles
+func (h *NewsHandler) GetTopic(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid topic id"})
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if err != nil || limit <= 0 {
+		limit = 20
+	}
+
+	filter, err := parseArticleFilter(c)
+	if err != nil {
+		respondError(c, err, "Invalid filter")
+		return
+	}
+	topic, articles, err := h.svc.GetTopic(c.Request.Context(), uint(id), filter, limit)
+	if err != nil {
+		respondError(c, err, "Failed to fetch topic")
+		return
+	}
+
+	h.respondWithCoordinates(c, http.StatusOK, &TopicResponse{
+		Topic:    *topic,
+		Articles: articles,
+		Meta: Meta{
+			Count:       len(articles),
+			Limit:       limit,
+			Endpoint:    "topics",
+			Degradation: degradation.Modes(c.Request.Context()),
+		},
+	})
+}
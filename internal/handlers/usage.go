@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mahigadamsetty/Inshorts-task/internal/services"
+	"github.com/mahigadamsetty/Inshorts-task/internal/tenant"
+)
+
+// UsageHandler reports what API keys consumed against their monthly quotas
+type UsageHandler struct {
+	svc *services.Services
+}
+
+func NewUsageHandler(svc *services.Services) *UsageHandler {
+	return &UsageHandler{svc: svc}
+}
+
+// QuotaUsage is the usage of one kind against its monthly quota. Quota and
+// remaining are absent when the quota is unlimited.
+type QuotaUsage struct {
+	Used      int64  `json:"used"`
+	Quota     *int64 `json:"quota,omitempty"`
+	Remaining *int64 `json:"remaining,omitempty"`
+}
+
+// GetUsage handles GET /keys/me/usage, reporting what the caller's API key
+// consumed in the current UTC month, or the one given as month=YYYY-MM, against
+// its quotas
+func (h *UsageHandler) GetUsage(c *gin.Context) {
+	t, ok := tenant.FromContext(c.Request.Context())
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "API key is required"})
+		return
+	}
+	month := tenant.MonthStart(time.Now())
+	if value := c.Query("month"); value != "" {
+		parsed, err := time.Parse("2006-01", value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "month must be formatted as YYYY-MM"})
+			return
+		}
+		month = parsed
+	}
+
+	usage, err := h.svc.GetUsage(c.Request.Context(), t.ID, month)
+	if err != nil {
+		respondError(c, err, "Failed to fetch usage")
+		return
+	}
+	quota := func(kind string, used int64) QuotaUsage {
+		result := QuotaUsage{Used: used}
+		if limit := int64(t.Quota(kind)); limit > 0 {
+			remaining := limit - used
+			if remaining < 0 {
+				remaining = 0
+			}
+			result.Quota, result.Remaining = &limit, &remaining
+		}
+		return result
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"tenant":                t.ID,
+		"month":                 month.Format("2006-01"),
+		"resets_at":             month.AddDate(0, 1, 0),
+		"requests":              quota(tenant.QuotaRequests, usage.Requests),
+		"llm_tokens":            quota(tenant.QuotaLLMTokens, usage.LLMTokens),
+		"events":                quota(tenant.QuotaEvents, usage.Events),
+		"rate_limit_per_minute": t.RateLimitPerMinute,
+		"llm_daily_budget":      t.LLMDailyBudget,
+	})
+}
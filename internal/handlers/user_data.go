@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mahigadamsetty/Inshorts-task/internal/models"
+	"github.com/mahigadamsetty/Inshorts-task/internal/services"
+)
+
+type UserDataHandler struct {
+	svc      *services.Services
+	requests *services.DataRequests
+}
+
+func NewUserDataHandler(svc *services.Services, requests *services.DataRequests) *UserDataHandler {
+	return &UserDataHandler{svc: svc, requests: requests}
+}
+
+// ExportData handles GET /users/me/export, starting an export of the data
+// linked to the caller's API key. The secret signing the webhook and the
+// download is only returned here.
+func (h *UserDataHandler) ExportData(c *gin.Context) {
+	h.submit(c, models.DataRequestExport)
+}
+
+// DeleteData handles DELETE /users/me/data, starting a deletion of the data
+// linked to the caller's API key. The webhook secret is only returned here.
+func (h *UserDataHandler) DeleteData(c *gin.Context) {
+	h.submit(c, models.DataRequestDelete)
+}
+
+// submit starts a data request with the webhook_url and secret parameters
+func (h *UserDataHandler) submit(c *gin.Context, kind string) {
+	request, err := h.requests.Submit(c.Request.Context(), kind, c.Query("webhook_url"), c.Query("secret"))
+	if err != nil {
+		respondError(c, err, "Failed to submit request")
+		return
+	}
+
+	response := gin.H{"request": request}
+	if request.Secret != "" {
+		response["secret"] = request.Secret
+	}
+	c.JSON(http.StatusAccepted, response)
+}
+
+// GetDataRequest handles /users/me/requests/:id endpoint
+func (h *UserDataHandler) GetDataRequest(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request id"})
+		return
+	}
+
+	request, err := h.svc.GetDataRequest(c.Request.Context(), uint(id))
+	if err != nil {
+		respondError(c, err, "Failed to fetch request")
+		return
+	}
+
+	c.JSON(http.StatusOK, request)
+}
+
+// DownloadExport handles /users/me/requests/:id/download, returning the file
+// of a completed export signed with the request's secret
+func (h *UserDataHandler) DownloadExport(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request id"})
+		return
+	}
+
+	file, err := h.requests.ExportFile(c.Request.Context(), uint(id), c.Writer.Header())
+	if err != nil {
+		respondError(c, err, "Failed to fetch export")
+		return
+	}
+	defer file.Close()
+
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="export-%d.json"`, id))
+	c.DataFromReader(http.StatusOK, -1, "application/json", file, nil)
+}
@@ -0,0 +1,108 @@
+package hll
+
+import (
+	"math"
+	"strconv"
+	"testing"
+)
+
+// tolerance is three standard errors of a sketch
+const tolerance = 3 * 1.04 / 32
+
+func addRange(s Sketch, from, to int) {
+	for i := from; i < to; i++ {
+		s.Add(Hash("user", strconv.Itoa(i)))
+	}
+}
+
+func within(estimate uint64, want int) bool {
+	if want == 0 {
+		return estimate == 0
+	}
+	return math.Abs(float64(estimate)-float64(want))/float64(want) <= tolerance
+}
+
+func TestEstimate(t *testing.T) {
+	tests := []struct {
+		name     string
+		distinct int
+		repeats  int
+	}{
+		{"empty", 0, 1},
+		{"one", 1, 1},
+		{"repeated item", 1, 50},
+		{"few", 10, 1},
+		{"hundred", 100, 3},
+		{"thousand", 1000, 1},
+		{"beyond linear counting", 10000, 2},
+		{"many", 200000, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := New()
+			for r := 0; r < tt.repeats; r++ {
+				addRange(s, 0, tt.distinct)
+			}
+			if got := s.Estimate(); !within(got, tt.distinct) {
+				t.Errorf("Estimate() = %d, want about %d", got, tt.distinct)
+			}
+		})
+	}
+}
+
+func TestMerge(t *testing.T) {
+	tests := []struct {
+		name          string
+		first, second [2]int // Item ranges added to each sketch
+		union         int
+	}{
+		{"disjoint", [2]int{0, 1000}, [2]int{1000, 2000}, 2000},
+		{"overlapping", [2]int{0, 3000}, [2]int{2000, 5000}, 5000},
+		{"contained", [2]int{0, 5000}, [2]int{100, 200}, 5000},
+		{"into empty", [2]int{0, 0}, [2]int{0, 800}, 800},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			first, second := New(), New()
+			addRange(first, tt.first[0], tt.first[1])
+			addRange(second, tt.second[0], tt.second[1])
+			first.Merge(second)
+			if got := first.Estimate(); !within(got, tt.union) {
+				t.Errorf("Estimate() after Merge = %d, want about %d", got, tt.union)
+			}
+		})
+	}
+}
+
+func TestInvalidSketch(t *testing.T) {
+	tests := []struct {
+		name   string
+		sketch Sketch
+	}{
+		{"nil", nil},
+		{"short", make(Sketch, registers/2)},
+		{"long", make(Sketch, registers+1)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.sketch.Valid() {
+				t.Errorf("Valid() = true for %d registers", len(tt.sketch))
+			}
+			if got := tt.sketch.Estimate(); got != 0 {
+				t.Errorf("Estimate() = %d, want 0", got)
+			}
+
+			// Merging a sketch of the wrong size leaves a valid one unchanged
+			s := New()
+			addRange(s, 0, 100)
+			before := s.Estimate()
+			s.Merge(tt.sketch)
+			if got := s.Estimate(); got != before {
+				t.Errorf("Estimate() after Merge = %d, want %d", got, before)
+			}
+		})
+	}
+}
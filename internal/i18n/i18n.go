@@ -0,0 +1,179 @@
+// Package i18n translates the strings the API shows to people — category
+// names, intent labels and error messages — into the languages of its
+// embedded message catalogs, so non-English clients don't need their own
+// translations. English is the source language: strings are looked up by
+// their English text, and anything without a translation is returned as is.
+package i18n
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Source is the language of the strings the server produces
+const Source = "en"
+
+//go:embed catalogs/*.json
+var catalogFiles embed.FS
+
+// Catalog holds the translations of one language
+type Catalog struct {
+	Language   string            `json:"language"`   // Base language tag, like "hi"
+	Name       string            `json:"name"`       // The language's own name for itself
+	Categories map[string]string `json:"categories"` // By lowercased category name
+	Intents    map[string]string `json:"intents"`    // By intent or endpoint name
+	Messages   map[string]string `json:"messages"`   // By English message; see pattern
+
+	patterns []pattern
+}
+
+// pattern matches messages formatted from a key with verbs like %d or %q.
+// The translation refers to the formatted values as {1}, {2}, ... in order.
+type pattern struct {
+	match       *regexp.Regexp
+	translation string
+}
+
+// verbPattern finds the verbs of a message format
+var verbPattern = regexp.MustCompile(`%[-+# 0-9.]*[dsqvgf]`)
+
+// placeholderPattern finds the values referred to by a translation
+var placeholderPattern = regexp.MustCompile(`\{([1-9])\}`)
+
+// catalogs are the embedded catalogs by language
+var catalogs = mustLoad()
+
+func mustLoad() map[string]*Catalog {
+	loaded, err := load()
+	if err != nil {
+		panic(err)
+	}
+	return loaded
+}
+
+func load() (map[string]*Catalog, error) {
+	files, err := catalogFiles.ReadDir("catalogs")
+	if err != nil {
+		return nil, err
+	}
+	loaded := make(map[string]*Catalog, len(files))
+	for _, file := range files {
+		data, err := catalogFiles.ReadFile(path.Join("catalogs", file.Name()))
+		if err != nil {
+			return nil, err
+		}
+		var catalog Catalog
+		if err := json.Unmarshal(data, &catalog); err != nil {
+			return nil, fmt.Errorf("catalog %s: %w", file.Name(), err)
+		}
+		// Patterns are tried in order of their formats, so lookups don't vary
+		// from run to run
+		formats := make([]string, 0, len(catalog.Messages))
+		for message := range catalog.Messages {
+			if verbPattern.MatchString(message) {
+				formats = append(formats, message)
+			}
+		}
+		sort.Strings(formats)
+		for _, message := range formats {
+			translation := catalog.Messages[message]
+			literals := verbPattern.Split(message, -1)
+			for i := range literals {
+				literals[i] = regexp.QuoteMeta(literals[i])
+			}
+			catalog.patterns = append(catalog.patterns, pattern{
+				match:       regexp.MustCompile("^" + strings.Join(literals, "(.+?)") + "$"),
+				translation: translation,
+			})
+		}
+		loaded[catalog.Language] = &catalog
+	}
+	return loaded, nil
+}
+
+// Languages lists the languages responses can be translated into, the source
+// language first
+func Languages() []string {
+	languages := make([]string, 0, len(catalogs))
+	for language := range catalogs {
+		languages = append(languages, language)
+	}
+	sort.Strings(languages)
+	return append([]string{Source}, languages...)
+}
+
+// Negotiate picks the language of a response from an Accept-Language header,
+// like "hi-IN,hi;q=0.9,en;q=0.8": the most preferred one with a catalog, or
+// the source language. Regions are ignored.
+func Negotiate(acceptLanguage string) string {
+	best, bestQuality := Source, 0.0
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		quality := 1.0
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				continue
+			}
+			quality = parsed
+		}
+		base, _, _ := strings.Cut(strings.ToLower(strings.TrimSpace(tag)), "-")
+		if _, ok := catalogs[base]; (ok || base == Source) && quality > bestQuality {
+			best, bestQuality = base, quality
+		}
+	}
+	return best
+}
+
+// Category returns a category's name in a language
+func Category(language, name string) string {
+	if catalog, ok := catalogs[language]; ok {
+		if translation, ok := catalog.Categories[strings.ToLower(strings.TrimSpace(name))]; ok {
+			return translation
+		}
+	}
+	return name
+}
+
+// Intent returns the label of a /query intent or endpoint in a language, and
+// false when it has none
+func Intent(language, intent string) (string, bool) {
+	if catalog, ok := catalogs[language]; ok {
+		label, ok := catalog.Intents[intent]
+		return label, ok
+	}
+	return "", false
+}
+
+// Message translates a message into a language. Messages formatted with
+// values are matched against their format, and the values are translated
+// too when they are messages themselves.
+func Message(language, message string) string {
+	catalog, ok := catalogs[language]
+	if !ok {
+		return message
+	}
+	if translation, ok := catalog.Messages[message]; ok {
+		return translation
+	}
+	for _, p := range catalog.patterns {
+		values := p.match.FindStringSubmatch(message)
+		if values == nil {
+			continue
+		}
+		return placeholderPattern.ReplaceAllStringFunc(p.translation, func(placeholder string) string {
+			i := int(placeholder[1] - '0')
+			if i >= len(values) {
+				return placeholder
+			}
+			return Message(language, values[i])
+		})
+	}
+	return message
+}
@@ -0,0 +1,82 @@
+package llm
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// AnthropicBaseURL is the Anthropic API endpoint
+const AnthropicBaseURL = "https://api.anthropic.com/v1"
+
+// anthropicVersion is the version of the Messages API requests are written for
+const anthropicVersion = "2023-06-01"
+
+// anthropicMaxTokens limits answers when the client sets no limit, which the
+// Messages API requires
+const anthropicMaxTokens = 1024
+
+type anthropicRequest struct {
+	Model     string    `json:"model"`
+	System    string    `json:"system,omitempty"`
+	Messages  []Message `json:"messages"`
+	MaxTokens int       `json:"max_tokens"`
+}
+
+type anthropicResponse struct {
+	Content []struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	} `json:"content"`
+	Usage struct {
+		InputTokens  int `json:"input_tokens"`
+		OutputTokens int `json:"output_tokens"`
+	} `json:"usage"`
+}
+
+// anthropicChat speaks the Anthropic Messages API
+type anthropicChat struct{}
+
+func (anthropicChat) request(c *Client, model ModelEndpoint, systemPrompt, userPrompt string) (*http.Request, error) {
+	maxTokens := c.maxTokens
+	if maxTokens == 0 {
+		maxTokens = anthropicMaxTokens
+	}
+	jsonData, err := json.Marshal(anthropicRequest{
+		Model:     model.Name,
+		System:    systemPrompt,
+		Messages:  []Message{{Role: "user", Content: userPrompt}},
+		MaxTokens: maxTokens,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := c.jsonRequest(model.BaseURL+"/messages", jsonData)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("x-api-key", c.apiKey)
+	req.Header.Set("anthropic-version", anthropicVersion)
+	return req, nil
+}
+
+func (anthropicChat) decode(body []byte) (string, int, error) {
+	var resp anthropicResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return "", 0, err
+	}
+	tokens := resp.Usage.InputTokens + resp.Usage.OutputTokens
+
+	var content strings.Builder
+	for _, block := range resp.Content {
+		if block.Type == "text" {
+			content.WriteString(block.Text)
+		}
+	}
+	if content.Len() == 0 {
+		return "", tokens, fmt.Errorf("anthropic response contained no text")
+	}
+	return content.String(), tokens, nil
+}
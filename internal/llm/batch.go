@@ -0,0 +1,95 @@
+package llm
+
+import (
+	"fmt"
+	"strings"
+)
+
+// BatchIntentGroupSize is how many queries are sent to the model in one prompt
+const BatchIntentGroupSize = 25
+
+var validIntents = map[string]bool{
+	IntentCategory: true,
+	IntentSource:   true,
+	IntentSearch:   true,
+	IntentNearby:   true,
+	IntentScore:    true,
+}
+
+// batchExtraction is one entry of the model's answer to a grouped prompt
+type batchExtraction struct {
+	Index     int      `json:"index"`
+	Intent    string   `json:"intent"`
+	Entities  []string `json:"entities"`
+	Locations []string `json:"locations"`
+}
+
+// ExtractIntentsBatch extracts the intent, entities and locations of many
+// queries, sending them to the model in groups instead of one request each.
+// It is meant for offline analytics, so date ranges are not resolved. Queries
+// the model skips or answers with an unknown intent get the heuristic result.
+// Results are in the order of the queries.
+func (c *Client) ExtractIntentsBatch(queries []string) []ExtractionResult {
+	results := make([]ExtractionResult, len(queries))
+	for start := 0; start < len(queries); start += BatchIntentGroupSize {
+		end := start + BatchIntentGroupSize
+		if end > len(queries) {
+			end = len(queries)
+		}
+		c.extractIntentGroup(queries[start:end], results[start:end])
+	}
+	return results
+}
+
+// extractIntentGroup fills results for one group of queries
+func (c *Client) extractIntentGroup(queries []string, results []ExtractionResult) {
+	answered := make([]bool, len(queries))
+	if c.Enabled() {
+		var lines strings.Builder
+		for i, query := range queries {
+			fmt.Fprintf(&lines, "%d. %s\n", i+1, strings.ReplaceAll(query, "\n", " "))
+		}
+
+		prompt := fmt.Sprintf(`For each numbered news search query below, extract:
+1. Intent: one of [category, source, search, nearby, score]
+2. Entities: relevant people, organizations, locations, or events
+3. Locations: place names mentioned (cities, states, countries), most specific first
+
+Queries:
+%s
+Respond with a JSON array containing one object per query:
+[{"index": 1, "intent": "<intent_type>", "entities": ["entity1"], "locations": ["place1"]}]
+
+Intent guidelines:
+- "category" if asking about a specific news category (technology, sports, etc.)
+- "source" if asking about a specific news source or publication
+- "nearby" if asking about news near or in a location
+- "score" if asking about high-quality or important news
+- "search" for general keyword searches`, lines.String())
+
+		content, err := c.chatCompletion("You are a news query analyzer. Always respond with valid JSON.", prompt)
+		var extractions []batchExtraction
+		if err == nil && decodeJSONContent(content, &extractions) == nil {
+			for _, e := range extractions {
+				i := e.Index - 1
+				if i < 0 || i >= len(queries) || answered[i] || !validIntents[e.Intent] {
+					continue
+				}
+				results[i] = ExtractionResult{
+					Intent:    e.Intent,
+					Entities:  e.Entities,
+					Locations: e.Locations,
+					Query:     queries[i],
+				}
+				answered[i] = true
+			}
+		}
+	}
+
+	for i, query := range queries {
+		if !answered[i] {
+			result, _ := c.fallbackExtraction(query)
+			results[i] = *result
+		}
+	}
+}
@@ -0,0 +1,165 @@
+package llm
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DateLayout is the format of DateRange bounds
+const DateLayout = "2006-01-02"
+
+// DateRange is a publication date range taken from a query. Both bounds are
+// inclusive calendar days in the client's timezone; an empty bound leaves that
+// side open.
+type DateRange struct {
+	From string `json:"from,omitempty"`
+	To   string `json:"to,omitempty"`
+}
+
+// Bounds returns the range as a half-open interval [from, to) in UTC, with
+// the days starting at midnight in the client's timezone. Zero times mean the
+// side is open.
+func (r *DateRange) Bounds(location *time.Location) (from, to time.Time) {
+	if r == nil {
+		return
+	}
+	if t, err := time.ParseInLocation(DateLayout, r.From, location); err == nil {
+		from = t.UTC()
+	}
+	if t, err := time.ParseInLocation(DateLayout, r.To, location); err == nil {
+		to = t.AddDate(0, 0, 1).UTC()
+	}
+	return
+}
+
+// Widen returns a copy of the range with each bound moved out by the given
+// number of days
+func (r *DateRange) Widen(days int) *DateRange {
+	if r == nil {
+		return nil
+	}
+	widened := *r
+	if t, err := time.Parse(DateLayout, r.From); err == nil {
+		widened.From = t.AddDate(0, 0, -days).Format(DateLayout)
+	}
+	if t, err := time.Parse(DateLayout, r.To); err == nil {
+		widened.To = t.AddDate(0, 0, days).Format(DateLayout)
+	}
+	return &widened
+}
+
+// valid reports whether the range has at least one well-formed bound and the
+// bounds are in order
+func (r *DateRange) valid() bool {
+	if r == nil || (r.From == "" && r.To == "") {
+		return false
+	}
+	from, fromErr := time.Parse(DateLayout, r.From)
+	to, toErr := time.Parse(DateLayout, r.To)
+	if (r.From != "" && fromErr != nil) || (r.To != "" && toErr != nil) {
+		return false
+	}
+	return r.From == "" || r.To == "" || !to.Before(from)
+}
+
+var (
+	lastNPattern = regexp.MustCompile(`\b(?:last|past) (\d+) (day|week|month|year)s?\b`)
+	sincePattern = regexp.MustCompile(`\bsince (\d{4}-\d{2}-\d{2}|[a-z]+)(?: (\d{4}))?\b`)
+	inPattern    = regexp.MustCompile(`\b(?:in|during) ([a-z]+)(?: (\d{4}))?\b`)
+)
+
+// parseDateRange recognizes common relative date expressions ("yesterday",
+// "last week", "past 3 days", "since March", "in March 2025") relative to now.
+// Returns nil when the query has none.
+func parseDateRange(query string, now time.Time) *DateRange {
+	q := strings.ToLower(query)
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	span := func(from, to time.Time) *DateRange {
+		return &DateRange{From: from.Format(DateLayout), To: to.Format(DateLayout)}
+	}
+	weekStart := today.AddDate(0, 0, -((int(today.Weekday()) + 6) % 7)) // Monday
+	monthStart := time.Date(today.Year(), today.Month(), 1, 0, 0, 0, 0, time.UTC)
+	yearStart := time.Date(today.Year(), 1, 1, 0, 0, 0, 0, time.UTC)
+
+	if m := lastNPattern.FindStringSubmatch(q); m != nil {
+		n, _ := strconv.Atoi(m[1])
+		switch m[2] {
+		case "day":
+			return span(today.AddDate(0, 0, -n), today)
+		case "week":
+			return span(today.AddDate(0, 0, -7*n), today)
+		case "month":
+			return span(today.AddDate(0, -n, 0), today)
+		case "year":
+			return span(today.AddDate(-n, 0, 0), today)
+		}
+	}
+
+	if m := sincePattern.FindStringSubmatch(q); m != nil {
+		if t, err := time.Parse(DateLayout, m[1]); err == nil {
+			return &DateRange{From: t.Format(DateLayout)}
+		}
+		if m[1] == "yesterday" {
+			return &DateRange{From: today.AddDate(0, 0, -1).Format(DateLayout)}
+		}
+		if start, ok := monthStartFor(m[1], m[2], today); ok {
+			return &DateRange{From: start.Format(DateLayout)}
+		}
+	}
+
+	switch {
+	case strings.Contains(q, "today"):
+		return span(today, today)
+	case strings.Contains(q, "yesterday"):
+		return span(today.AddDate(0, 0, -1), today.AddDate(0, 0, -1))
+	case strings.Contains(q, "this week"):
+		return span(weekStart, today)
+	case strings.Contains(q, "last week"):
+		return span(weekStart.AddDate(0, 0, -7), weekStart.AddDate(0, 0, -1))
+	case strings.Contains(q, "this month"):
+		return span(monthStart, today)
+	case strings.Contains(q, "last month"):
+		return span(monthStart.AddDate(0, -1, 0), monthStart.AddDate(0, 0, -1))
+	case strings.Contains(q, "this year"):
+		return span(yearStart, today)
+	case strings.Contains(q, "last year"):
+		return span(yearStart.AddDate(-1, 0, 0), yearStart.AddDate(0, 0, -1))
+	}
+
+	for _, m := range inPattern.FindAllStringSubmatch(q, -1) {
+		if start, ok := monthStartFor(m[1], m[2], today); ok {
+			return span(start, start.AddDate(0, 1, -1))
+		}
+	}
+
+	return nil
+}
+
+// monthStartFor returns the first day of a named month. Without a year it is the
+// most recent such month that has started.
+func monthStartFor(name, year string, today time.Time) (time.Time, bool) {
+	month, ok := parseMonth(name)
+	if !ok {
+		return time.Time{}, false
+	}
+	y := today.Year()
+	if year != "" {
+		y, _ = strconv.Atoi(year)
+	} else if month > today.Month() {
+		y--
+	}
+	return time.Date(y, month, 1, 0, 0, 0, 0, time.UTC), true
+}
+
+// parseMonth matches full and three-letter month names
+func parseMonth(name string) (time.Month, bool) {
+	for m := time.January; m <= time.December; m++ {
+		full := strings.ToLower(m.String())
+		if name == full || (len(name) == 3 && strings.HasPrefix(full, name)) {
+			return m, true
+		}
+	}
+	return 0, false
+}
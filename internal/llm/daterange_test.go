@@ -0,0 +1,100 @@
+package llm
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseDateRange(t *testing.T) {
+	// A Friday
+	now := time.Date(2026, time.October, 16, 14, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name  string
+		query string
+		want  *DateRange
+	}{
+		{"today", "Top stories today", &DateRange{From: "2026-10-16", To: "2026-10-16"}},
+		{"yesterday", "what happened yesterday", &DateRange{From: "2026-10-15", To: "2026-10-15"}},
+		{"this week", "cricket this week", &DateRange{From: "2026-10-12", To: "2026-10-16"}},
+		{"last week", "cricket last week", &DateRange{From: "2026-10-05", To: "2026-10-11"}},
+		{"this month", "markets this month", &DateRange{From: "2026-10-01", To: "2026-10-16"}},
+		{"last month", "markets last month", &DateRange{From: "2026-09-01", To: "2026-09-30"}},
+		{"this year", "elections this year", &DateRange{From: "2026-01-01", To: "2026-10-16"}},
+		{"last year", "elections last year", &DateRange{From: "2025-01-01", To: "2025-12-31"}},
+		{"past days", "floods in the past 3 days", &DateRange{From: "2026-10-13", To: "2026-10-16"}},
+		{"last weeks", "last 2 weeks of tech news", &DateRange{From: "2026-10-02", To: "2026-10-16"}},
+		{"last month singular", "last 1 month", &DateRange{From: "2026-09-16", To: "2026-10-16"}},
+		{"since date", "since 2026-09-01", &DateRange{From: "2026-09-01"}},
+		{"since yesterday", "since yesterday", &DateRange{From: "2026-10-15"}},
+		{"since month", "since march", &DateRange{From: "2026-03-01"}},
+		{"since later month", "since december", &DateRange{From: "2025-12-01"}},
+		{"in month and year", "budget in March 2025", &DateRange{From: "2025-03-01", To: "2025-03-31"}},
+		{"during short month", "during feb", &DateRange{From: "2026-02-01", To: "2026-02-28"}},
+		{"in later month", "in november", &DateRange{From: "2025-11-01", To: "2025-11-30"}},
+		{"place not month", "tech news in india", nil},
+		{"no date", "latest startup funding", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := parseDateRange(tt.query, now)
+			if (got == nil) != (tt.want == nil) || (got != nil && *got != *tt.want) {
+				t.Errorf("parseDateRange(%q) = %+v, want %+v", tt.query, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDateRangeValid(t *testing.T) {
+	tests := []struct {
+		name  string
+		r     *DateRange
+		valid bool
+	}{
+		{"nil", nil, false},
+		{"empty", &DateRange{}, false},
+		{"from only", &DateRange{From: "2026-10-01"}, true},
+		{"to only", &DateRange{To: "2026-10-01"}, true},
+		{"single day", &DateRange{From: "2026-10-01", To: "2026-10-01"}, true},
+		{"reversed", &DateRange{From: "2026-10-02", To: "2026-10-01"}, false},
+		{"malformed", &DateRange{From: "October 1"}, false},
+		{"impossible day", &DateRange{To: "2026-02-30"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.r.valid(); got != tt.valid {
+				t.Errorf("%+v.valid() = %v, want %v", tt.r, got, tt.valid)
+			}
+		})
+	}
+}
+
+func TestDateRangeBounds(t *testing.T) {
+	kolkata := time.FixedZone("IST", 5*60*60+30*60)
+
+	tests := []struct {
+		name     string
+		r        *DateRange
+		location *time.Location
+		from, to time.Time
+	}{
+		{"nil", nil, time.UTC, time.Time{}, time.Time{}},
+		{"utc day", &DateRange{From: "2026-10-16", To: "2026-10-16"}, time.UTC,
+			time.Date(2026, time.October, 16, 0, 0, 0, 0, time.UTC), time.Date(2026, time.October, 17, 0, 0, 0, 0, time.UTC)},
+		{"client timezone", &DateRange{From: "2026-10-16", To: "2026-10-16"}, kolkata,
+			time.Date(2026, time.October, 15, 18, 30, 0, 0, time.UTC), time.Date(2026, time.October, 16, 18, 30, 0, 0, time.UTC)},
+		{"open end", &DateRange{From: "2026-10-16"}, time.UTC,
+			time.Date(2026, time.October, 16, 0, 0, 0, 0, time.UTC), time.Time{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			from, to := tt.r.Bounds(tt.location)
+			if !from.Equal(tt.from) || !to.Equal(tt.to) {
+				t.Errorf("Bounds() = %v, %v, want %v, %v", from, to, tt.from, tt.to)
+			}
+		})
+	}
+}
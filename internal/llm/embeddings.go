@@ -0,0 +1,198 @@
+package llm
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"math"
+	"net/http"
+	"strings"
+	"unicode"
+
+	"github.com/mahigadamsetty/Inshorts-task/internal/degradation"
+	"github.com/mahigadamsetty/Inshorts-task/internal/scrub"
+)
+
+// Embedding models. Vectors from different models are not comparable, so each
+// stored vector records the model that produced it.
+const (
+	OpenAIEmbeddingModel = "text-embedding-3-small"
+	HashedEmbeddingModel = "hashed-bow-256"
+	hashedEmbeddingDims  = 256
+)
+
+type embeddingRequest struct {
+	Model string   `json:"model"`
+	Input []string `json:"input"`
+}
+
+type embeddingResponse struct {
+	Data []struct {
+		Index     int       `json:"index"`
+		Embedding []float32 `json:"embedding"`
+	} `json:"data"`
+}
+
+// EmbeddingModel returns the model Embed uses when nothing fails
+func (c *Client) EmbeddingModel() string {
+	if !c.embeds() {
+		return HashedEmbeddingModel
+	}
+	return OpenAIEmbeddingModel
+}
+
+// embeds reports whether the client asks the API for embeddings. Only the
+// OpenAI provider serves them.
+func (c *Client) embeds() bool {
+	return c.apiKey != "" && c.api.Provider == ProviderOpenAI
+}
+
+// Embed returns one vector per text along with the model that produced them.
+// Without an OpenAI API key, or when the request fails, texts are embedded as
+// hashed bag-of-words vectors.
+func (c *Client) Embed(texts []string) ([][]float32, string, error) {
+	if len(texts) == 0 {
+		return nil, c.EmbeddingModel(), nil
+	}
+	if !c.embeds() {
+		return c.fallbackEmbeddings(texts), HashedEmbeddingModel, nil
+	}
+
+	vectors, err := c.openAIEmbeddings(texts)
+	if err != nil {
+		return c.fallbackEmbeddings(texts), HashedEmbeddingModel, nil
+	}
+	return vectors, OpenAIEmbeddingModel, nil
+}
+
+// openAIEmbeddings requests embeddings for a batch of texts
+func (c *Client) openAIEmbeddings(texts []string) ([][]float32, error) {
+	jsonData, err := json.Marshal(embeddingRequest{Model: OpenAIEmbeddingModel, Input: scrub.Strings(texts)})
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := c.newRequest(c.api.BaseURL+"/embeddings", jsonData)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("openai embeddings request failed: status code %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	var embResp embeddingResponse
+	if err := json.Unmarshal(body, &embResp); err != nil {
+		return nil, err
+	}
+	if len(embResp.Data) != len(texts) {
+		return nil, fmt.Errorf("openai returned %d embeddings for %d inputs", len(embResp.Data), len(texts))
+	}
+
+	vectors := make([][]float32, len(texts))
+	for _, item := range embResp.Data {
+		if item.Index < 0 || item.Index >= len(texts) {
+			return nil, fmt.Errorf("openai returned embedding for unknown input %d", item.Index)
+		}
+		vectors[item.Index] = item.Embedding
+	}
+	return vectors, nil
+}
+
+// fallbackEmbeddings hashes each text's words into a fixed-size unit vector
+func (c *Client) fallbackEmbeddings(texts []string) [][]float32 {
+	c.report.Record(degradation.SubsystemEmbeddings, degradation.ModeFallback)
+	vectors := make([][]float32, len(texts))
+	for i, text := range texts {
+		vectors[i] = hashedEmbedding(text)
+	}
+	return vectors
+}
+
+// hashedEmbedding maps words to signed buckets with the hashing trick. Short
+// words are skipped since they are mostly stop words.
+func hashedEmbedding(text string) []float32 {
+	vector := make([]float32, hashedEmbeddingDims)
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for _, word := range words {
+		if len(word) < 4 {
+			continue
+		}
+		h := fnv.New32a()
+		h.Write([]byte(word))
+		sum := h.Sum32()
+		sign := float32(1)
+		if sum&(1<<31) != 0 {
+			sign = -1
+		}
+		vector[sum%hashedEmbeddingDims] += sign
+	}
+
+	var norm float64
+	for _, v := range vector {
+		norm += float64(v) * float64(v)
+	}
+	if norm > 0 {
+		scale := float32(1 / math.Sqrt(norm))
+		for i := range vector {
+			vector[i] *= scale
+		}
+	}
+	return vector
+}
+
+// GenerateTopicLabel names a topic from representative headlines and its most
+// frequent keywords
+func (c *Client) GenerateTopicLabel(headlines, keywords []string) (string, error) {
+	if !c.Enabled() {
+		return c.fallbackTopicLabel(keywords), nil
+	}
+
+	prompt := fmt.Sprintf(`The following headlines belong to one news topic:
+
+%s
+
+Frequent keywords: %s
+
+Reply with a short topic name of 2-5 words, without quotes or trailing punctuation.`, "- "+strings.Join(headlines, "\n- "), strings.Join(keywords, ", "))
+
+	content, err := c.chatCompletion("You are a news editor naming topic pages. Be concise.", prompt)
+	if err != nil {
+		return c.fallbackTopicLabel(keywords), nil
+	}
+
+	label := strings.Trim(strings.TrimSpace(content), `"'.`)
+	if label == "" {
+		return c.fallbackTopicLabel(keywords), nil
+	}
+	return label, nil
+}
+
+// fallbackTopicLabel joins the top keywords, capitalized
+func (c *Client) fallbackTopicLabel(keywords []string) string {
+	c.report.Record(degradation.SubsystemLLMSummary, degradation.ModeFallback)
+	if len(keywords) > 3 {
+		keywords = keywords[:3]
+	}
+	words := make([]string, len(keywords))
+	for i, keyword := range keywords {
+		runes := []rune(keyword)
+		runes[0] = unicode.ToUpper(runes[0])
+		words[i] = string(runes)
+	}
+	return strings.Join(words, ", ")
+}
@@ -0,0 +1,81 @@
+package llm
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// GeminiBaseURL is the Google Gemini API endpoint
+const GeminiBaseURL = "https://generativelanguage.googleapis.com/v1beta"
+
+type geminiContent struct {
+	Role  string       `json:"role,omitempty"`
+	Parts []geminiPart `json:"parts"`
+}
+
+type geminiPart struct {
+	Text string `json:"text"`
+}
+
+type geminiRequest struct {
+	SystemInstruction *geminiContent  `json:"systemInstruction,omitempty"`
+	Contents          []geminiContent `json:"contents"`
+	GenerationConfig  struct {
+		MaxOutputTokens int `json:"maxOutputTokens,omitempty"`
+	} `json:"generationConfig"`
+}
+
+type geminiResponse struct {
+	Candidates []struct {
+		Content geminiContent `json:"content"`
+	} `json:"candidates"`
+	UsageMetadata struct {
+		TotalTokenCount int `json:"totalTokenCount"`
+	} `json:"usageMetadata"`
+}
+
+// geminiChat speaks the generateContent method of the Gemini API
+type geminiChat struct{}
+
+func (geminiChat) request(c *Client, model ModelEndpoint, systemPrompt, userPrompt string) (*http.Request, error) {
+	reqBody := geminiRequest{
+		SystemInstruction: &geminiContent{Parts: []geminiPart{{Text: systemPrompt}}},
+		Contents:          []geminiContent{{Role: "user", Parts: []geminiPart{{Text: userPrompt}}}},
+	}
+	reqBody.GenerationConfig.MaxOutputTokens = c.maxTokens
+	jsonData, err := json.Marshal(reqBody)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := c.jsonRequest(model.BaseURL+"/models/"+url.PathEscape(model.Name)+":generateContent", jsonData)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("x-goog-api-key", c.apiKey)
+	return req, nil
+}
+
+func (geminiChat) decode(body []byte) (string, int, error) {
+	var resp geminiResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return "", 0, err
+	}
+	tokens := resp.UsageMetadata.TotalTokenCount
+
+	// Blocked prompts come back without candidates
+	if len(resp.Candidates) == 0 {
+		return "", tokens, fmt.Errorf("gemini response contained no candidates")
+	}
+	var content strings.Builder
+	for _, part := range resp.Candidates[0].Content.Parts {
+		content.WriteString(part.Text)
+	}
+	if content.Len() == 0 {
+		return "", tokens, fmt.Errorf("gemini response contained no text")
+	}
+	return content.String(), tokens, nil
+}
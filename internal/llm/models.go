@@ -0,0 +1,123 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// OpenAIBaseURL is the OpenAI API endpoint
+const OpenAIBaseURL = "https://api.openai.com/v1"
+
+// APIOptions describe the API that clients talk to
+type APIOptions struct {
+	Provider     string // One of Providers(), openai when empty
+	BaseURL      string // Used for embeddings and for chain entries that don't name an endpoint, the provider's own when empty
+	Organization string // Sent as OpenAI-Organization when set
+	Project      string // Sent as OpenAI-Project when set
+	APIVersion   string // For Azure OpenAI: sent as the api-version query parameter, with the key in an api-key header
+}
+
+// resolve checks the provider and fills in the defaults: the OpenAI provider,
+// and the provider's public API for an empty base URL
+func (options APIOptions) resolve() (APIOptions, error) {
+	if options.Provider == "" {
+		options.Provider = ProviderOpenAI
+	}
+	options.Provider = strings.ToLower(options.Provider)
+	provider, ok := providers[options.Provider]
+	if !ok {
+		return options, fmt.Errorf("unknown LLM provider %q, expected one of %s", options.Provider, strings.Join(Providers(), ", "))
+	}
+	options.BaseURL = strings.TrimRight(options.BaseURL, "/")
+	if options.BaseURL == "" {
+		options.BaseURL = provider.baseURL
+	}
+	return options, nil
+}
+
+// ModelEndpoint is one entry of the model fallback chain: a model name and the
+// endpoint of the provider's API serving it
+type ModelEndpoint struct {
+	Name    string
+	BaseURL string
+}
+
+// ParseModelChain parses chain entries like "gpt-4o-mini" or, for a local
+// OpenAI-compatible server, "llama3@http://localhost:11434/v1". Entries that
+// don't name an endpoint are served from baseURL. Empty entries are skipped.
+func ParseModelChain(entries []string, baseURL string) []ModelEndpoint {
+	chain := make([]ModelEndpoint, 0, len(entries))
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		endpoint := ModelEndpoint{Name: entry, BaseURL: baseURL}
+		if i := strings.Index(entry, "@"); i > 0 {
+			endpoint.Name = entry[:i]
+			endpoint.BaseURL = strings.TrimRight(entry[i+1:], "/")
+		}
+		chain = append(chain, endpoint)
+	}
+	return chain
+}
+
+// ModelUsage counts the chat requests sent to one model
+type ModelUsage struct {
+	Model        string  `json:"model"`
+	Requests     int64   `json:"requests"`
+	Successes    int64   `json:"successes"`
+	Errors       int64   `json:"errors"`
+	SLOTimeouts  int64   `json:"slo_timeouts"` // Requests abandoned for exceeding the latency SLO, also counted in errors
+	AvgLatencyMs float64 `json:"avg_latency_ms"`
+
+	totalLatency time.Duration
+}
+
+var (
+	usageMu sync.Mutex
+	usage   = map[string]*ModelUsage{}
+)
+
+// recordUsage adds one request's outcome to its model's usage
+func recordUsage(model string, latency time.Duration, err error) {
+	usageMu.Lock()
+	defer usageMu.Unlock()
+
+	u, ok := usage[model]
+	if !ok {
+		u = &ModelUsage{Model: model}
+		usage[model] = u
+	}
+	u.Requests++
+	u.totalLatency += latency
+	u.AvgLatencyMs = float64(u.totalLatency) / float64(time.Millisecond) / float64(u.Requests)
+	if err == nil {
+		u.Successes++
+		return
+	}
+	u.Errors++
+	if errors.Is(err, context.DeadlineExceeded) {
+		u.SLOTimeouts++
+	}
+}
+
+// Usage returns per-model usage since the process started, ordered by model name
+func Usage() []ModelUsage {
+	usageMu.Lock()
+	defer usageMu.Unlock()
+
+	result := make([]ModelUsage, 0, len(usage))
+	for _, u := range usage {
+		result = append(result, *u)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Model < result[j].Model
+	})
+	return result
+}
@@ -0,0 +1,116 @@
+package llm
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Content ratings, ordered from least to most restricted
+const (
+	RatingSafe      = "safe"
+	RatingSensitive = "sensitive"
+	RatingExplicit  = "explicit"
+)
+
+// Safety tags attached to moderated articles
+const (
+	TagViolence = "violence"
+	TagAdult    = "adult"
+)
+
+type ModerationResult struct {
+	Rating string   `json:"rating"`
+	Tags   []string `json:"tags"`
+}
+
+// Keyword lists used when the LLM is unavailable. Graphic terms mark an article
+// explicit, mild terms only mark it sensitive.
+var (
+	graphicViolenceKeywords = []string{"beheaded", "beheading", "decapitated", "dismembered", "mutilated", "massacre", "gore", "graphic footage", "graphic video", "bloodbath"}
+	violenceKeywords        = []string{"killed", "murder", "shooting", "stabbed", "stabbing", "bombing", "terror attack", "assault", "gunman", "dead bodies"}
+	explicitAdultKeywords   = []string{"porn", "pornography", "explicit video", "nude photos", "sex tape", "nsfw"}
+	adultKeywords           = []string{"sexual", "sex scandal", "nudity", "adult content", "strip club", "escort"}
+)
+
+// ModerateContent classifies an article for graphic violence and adult content
+func (c *Client) ModerateContent(title, description string) (*ModerationResult, error) {
+	if !c.Enabled() {
+		return c.fallbackModeration(title, description), nil
+	}
+
+	prompt := fmt.Sprintf(`Classify the following news article for content safety.
+
+Title: %s
+Description: %s
+
+Respond in JSON format:
+{
+  "rating": "<safe|sensitive|explicit>",
+  "tags": ["violence", "adult"]
+}
+
+Rating guidelines:
+- "explicit" for graphic violence, gore or sexually explicit content
+- "sensitive" for non-graphic reports of violence, crime or sexual topics
+- "safe" for everything else
+Only include tags that apply.`, title, description)
+
+	content, err := c.chatCompletion("You are a content moderator for a news app. Always respond with valid JSON.", prompt)
+	if err != nil {
+		return c.fallbackModeration(title, description), nil
+	}
+
+	var result ModerationResult
+	if err := decodeJSONContent(content, &result); err != nil {
+		return c.fallbackModeration(title, description), nil
+	}
+
+	switch result.Rating {
+	case RatingSafe, RatingSensitive, RatingExplicit:
+		return &result, nil
+	default:
+		return c.fallbackModeration(title, description), nil
+	}
+}
+
+// fallbackModeration classifies content with keyword lists when the LLM is not available
+func (c *Client) fallbackModeration(title, description string) *ModerationResult {
+	text := strings.ToLower(title + " " + description)
+	result := &ModerationResult{Rating: RatingSafe, Tags: []string{}}
+
+	escalate := func(rating, tag string) {
+		if rating == RatingExplicit || result.Rating == RatingSafe {
+			result.Rating = rating
+		}
+		for _, existing := range result.Tags {
+			if existing == tag {
+				return
+			}
+		}
+		result.Tags = append(result.Tags, tag)
+	}
+
+	if containsAny(text, graphicViolenceKeywords) {
+		escalate(RatingExplicit, TagViolence)
+	} else if containsAny(text, violenceKeywords) {
+		escalate(RatingSensitive, TagViolence)
+	}
+
+	if containsAny(text, explicitAdultKeywords) {
+		escalate(RatingExplicit, TagAdult)
+	} else if containsAny(text, adultKeywords) {
+		escalate(RatingSensitive, TagAdult)
+	}
+
+	return result
+}
+
+// containsAny reports whether text contains any of the keywords
+func containsAny(text string, keywords []string) bool {
+	for _, keyword := range keywords {
+		if strings.Contains(text, keyword) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,67 @@
+package llm
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// OllamaBaseURL is where a local Ollama server listens by default
+const OllamaBaseURL = "http://localhost:11434"
+
+type ollamaRequest struct {
+	Model    string    `json:"model"`
+	Messages []Message `json:"messages"`
+	Stream   bool      `json:"stream"`
+	Options  struct {
+		NumPredict int `json:"num_predict,omitempty"`
+	} `json:"options"`
+}
+
+type ollamaResponse struct {
+	Message struct {
+		Content string `json:"content"`
+	} `json:"message"`
+	PromptEvalCount int `json:"prompt_eval_count"`
+	EvalCount       int `json:"eval_count"`
+}
+
+// ollamaChat speaks the chat API of an Ollama server. It needs no key; one
+// that is configured is sent as a bearer token, for servers behind a proxy.
+type ollamaChat struct{}
+
+func (ollamaChat) request(c *Client, model ModelEndpoint, systemPrompt, userPrompt string) (*http.Request, error) {
+	reqBody := ollamaRequest{
+		Model: model.Name,
+		Messages: []Message{
+			{Role: "system", Content: systemPrompt},
+			{Role: "user", Content: userPrompt},
+		},
+	}
+	reqBody.Options.NumPredict = c.maxTokens
+	jsonData, err := json.Marshal(reqBody)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := c.jsonRequest(model.BaseURL+"/api/chat", jsonData)
+	if err != nil {
+		return nil, err
+	}
+	if c.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	}
+	return req, nil
+}
+
+func (ollamaChat) decode(body []byte) (string, int, error) {
+	var resp ollamaResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return "", 0, err
+	}
+	tokens := resp.PromptEvalCount + resp.EvalCount
+	if resp.Message.Content == "" {
+		return "", tokens, fmt.Errorf("ollama response contained no message")
+	}
+	return resp.Message.Content, tokens, nil
+}
@@ -1,13 +1,20 @@
 package llm
 
 import (
-	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"strings"
 	"time"
+
+	"github.com/mahigadamsetty/Inshorts-task/internal/apperr"
+	"github.com/mahigadamsetty/Inshorts-task/internal/degradation"
+	"github.com/mahigadamsetty/Inshorts-task/internal/faults"
+	"github.com/mahigadamsetty/Inshorts-task/internal/scrub"
 )
 
 // Intent types
@@ -20,20 +27,32 @@ const (
 )
 
 type Client struct {
-	apiKey string
-	model  string
-	client *http.Client
+	apiKey     string
+	api        APIOptions
+	queue      *RequestQueue
+	models     []ModelEndpoint // Tried in order until one answers
+	latencySLO time.Duration   // Per-attempt limit before moving down the chain, 0 for none
+	client     *http.Client
+	report     *degradation.Report // Receives fallback notices for the current request, may be nil
+	faults     *faults.Faults      // Failures injected into the current request, may be nil
+	ctx        context.Context     // Cancels queued and in-flight requests, may be nil
+	maxTokens  int                 // Completion token limit, 0 for the model's own
+	location   *time.Location      // Timezone relative dates are resolved in, UTC when nil
+	tokens     func(tokens int)    // Receives the tokens each answered request used, may be nil
 }
 
 type ExtractionResult struct {
-	Intent   string   `json:"intent"`
-	Entities []string `json:"entities"`
-	Query    string   `json:"query"`
+	Intent    string   `json:"intent"`
+	Entities  []string `json:"entities"`
+	Locations []string   `json:"locations"` // Place names mentioned in the query, most specific first
+	DateRange *DateRange `json:"date_range"` // Publication dates the query asks about, nil if none
+	Query     string     `json:"query"`
 }
 
 type OpenAIRequest struct {
-	Model    string    `json:"model"`
-	Messages []Message `json:"messages"`
+	Model     string    `json:"model"`
+	Messages  []Message `json:"messages"`
+	MaxTokens int       `json:"max_tokens,omitempty"`
 }
 
 type Message struct {
@@ -47,19 +66,109 @@ type OpenAIResponse struct {
 			Content string `json:"content"`
 		} `json:"message"`
 	} `json:"choices"`
+	Usage struct {
+		TotalTokens int `json:"total_tokens"`
+	} `json:"usage"`
+}
+
+// ClientOptions configure a client
+type ClientOptions struct {
+	APIKey     string
+	Models     []string      // Tried in turn, see ParseModelChain for the entry format
+	LatencySLO time.Duration // Per-attempt limit before moving down the chain, 0 for none
+	API        APIOptions    // The provider's public API when zero
+	Queue      *RequestQueue // Paces requests with every client sharing it; unlimited when nil
 }
 
-func NewClient(apiKey, model string) *Client {
+// New creates a client talking to the configured provider, or to another
+// endpoint of it, such as a gateway, an Azure deployment or cmd/llmstub for
+// the OpenAI API. It fails for an unknown provider.
+func New(options ClientOptions) (*Client, error) {
+	api, err := options.API.resolve()
+	if err != nil {
+		return nil, err
+	}
+	queue := options.Queue
+	if queue == nil {
+		queue = NewRequestQueue(0, 0, 0)
+	}
 	return &Client{
-		apiKey: apiKey,
-		model:  model,
-		client: &http.Client{Timeout: 30 * time.Second},
+		apiKey:     options.APIKey,
+		api:        api,
+		queue:      queue,
+		models:     ParseModelChain(options.Models, api.BaseURL),
+		latencySLO: options.LatencySLO,
+		client:     &http.Client{Timeout: 30 * time.Second},
+	}, nil
+}
+
+// NewClient creates a client of the OpenAI public API with its own unlimited
+// queue, that tries each model of the chain in turn, giving each attempt up
+// to latencySLO
+func NewClient(apiKey string, models []string, latencySLO time.Duration) *Client {
+	// The OpenAI provider is always known
+	client, _ := New(ClientOptions{APIKey: apiKey, Models: models, LatencySLO: latencySLO})
+	return client
+}
+
+// WithReport returns a copy of the client that records fallbacks on the given report
+func (c *Client) WithReport(report *degradation.Report) *Client {
+	clone := *c
+	clone.report = report
+	return &clone
+}
+
+// WithFaults returns a copy of the client that fails its OpenAI requests as
+// the injected faults say
+func (c *Client) WithFaults(f *faults.Faults) *Client {
+	clone := *c
+	clone.faults = f
+	return &clone
+}
+
+// WithLocation returns a copy of the client resolving relative dates, like
+// "today", in the client's timezone
+func (c *Client) WithLocation(location *time.Location) *Client {
+	clone := *c
+	clone.location = location
+	return &clone
+}
+
+// WithTokenCounter returns a copy of the client reporting the tokens each
+// answered chat request used to count, for metering
+func (c *Client) WithTokenCounter(count func(tokens int)) *Client {
+	clone := *c
+	clone.tokens = count
+	return &clone
+}
+
+// now returns the current time in the client's timezone
+func (c *Client) now() time.Time {
+	if c.location == nil {
+		return time.Now().UTC()
+	}
+	return time.Now().In(c.location)
+}
+
+// WithContext returns a copy of the client whose requests are cancelled with
+// ctx, so an abandoned HTTP request or job stops waiting on the API
+func (c *Client) WithContext(ctx context.Context) *Client {
+	clone := *c
+	clone.ctx = ctx
+	return &clone
+}
+
+// context returns the client's context, or the background context without one
+func (c *Client) context() context.Context {
+	if c.ctx == nil {
+		return context.Background()
 	}
+	return c.ctx
 }
 
 // ExtractIntentAndEntities extracts intent and entities from a natural language query
 func (c *Client) ExtractIntentAndEntities(query string) (*ExtractionResult, error) {
-	if c.apiKey == "" {
+	if !c.Enabled() {
 		// Fallback to heuristic extraction
 		return c.fallbackExtraction(query)
 	}
@@ -67,7 +176,9 @@ func (c *Client) ExtractIntentAndEntities(query string) (*ExtractionResult, erro
 	prompt := fmt.Sprintf(`Analyze the following news query and extract:
 1. Intent: one of [category, source, search, nearby, score]
 2. Entities: list of relevant people, organizations, locations, or events
-3. The main search query
+3. Locations: place names mentioned (cities, states, countries), most specific first
+4. Date range: publication dates the query refers to ("yesterday", "last week", "since March"), as inclusive YYYY-MM-DD bounds; leave a bound empty if open, or use null if no dates are mentioned. Today is %s.
+5. The main search query
 
 Query: %s
 
@@ -75,90 +186,173 @@ Respond in JSON format:
 {
   "intent": "<intent_type>",
   "entities": ["entity1", "entity2"],
+  "locations": ["place1"],
+  "date_range": {"from": "YYYY-MM-DD", "to": "YYYY-MM-DD"},
   "query": "<extracted_query>"
 }
 
 Intent guidelines:
 - "category" if asking about a specific news category (technology, sports, etc.)
 - "source" if asking about a specific news source or publication
-- "nearby" if asking about news near a location
+- "nearby" if asking about news near or in a location
 - "score" if asking about high-quality or important news
-- "search" for general keyword searches`, query)
+- "search" for general keyword searches`, c.now().Format(DateLayout), query)
 
-	reqBody := OpenAIRequest{
-		Model: c.model,
-		Messages: []Message{
-			{Role: "system", Content: "You are a news query analyzer. Always respond with valid JSON."},
-			{Role: "user", Content: prompt},
-		},
+	content, err := c.chatCompletion("You are a news query analyzer. Always respond with valid JSON.", prompt)
+	if err != nil {
+		return c.fallbackExtraction(query)
 	}
 
-	jsonData, err := json.Marshal(reqBody)
-	if err != nil {
+	var result ExtractionResult
+	if err := decodeJSONContent(content, &result); err != nil {
 		return c.fallbackExtraction(query)
 	}
 
-	req, err := http.NewRequest("POST", "https://api.openai.com/v1/chat/completions", bytes.NewBuffer(jsonData))
+	return normalizeExtraction(&result, query, c.now()), nil
+}
+
+// normalizeExtraction replaces a malformed model date range with the heuristic parse
+func normalizeExtraction(result *ExtractionResult, query string, now time.Time) *ExtractionResult {
+	if result.DateRange != nil && !result.DateRange.valid() {
+		result.DateRange = parseDateRange(query, now)
+	}
+	return result
+}
+
+// chatCompletion sends a system and user prompt to each model of the chain until
+// one answers and returns the raw content of its first choice. Fails with an
+// ErrUpstreamLLM wrapping the last model's error.
+func (c *Client) chatCompletion(systemPrompt, userPrompt string) (string, error) {
+	systemPrompt, userPrompt = scrub.String(systemPrompt), scrub.String(userPrompt)
+	lastErr := fmt.Errorf("no models configured")
+	for _, model := range c.models {
+		start := time.Now()
+		content, err := c.chatCompletionWith(model, systemPrompt, userPrompt)
+		recordUsage(model.Name, time.Since(start), err)
+		if err == nil {
+			return content, nil
+		}
+		lastErr = fmt.Errorf("%s: %w", model.Name, err)
+		if errors.Is(err, ErrQueueTimeout) {
+			break // The queue is shared, so the next model would wait just as long
+		}
+		if c.context().Err() != nil {
+			break // Nobody is waiting for the answer anymore
+		}
+	}
+	return "", apperr.UpstreamLLM(lastErr)
+}
+
+// newRequest builds a JSON POST to the OpenAI API, authenticated and tagged
+// with the configured organization, project and API version
+func (c *Client) newRequest(endpoint string, body []byte) (*http.Request, error) {
+	if c.api.APIVersion != "" {
+		endpoint += "?api-version=" + url.QueryEscape(c.api.APIVersion)
+	}
+	req, err := c.jsonRequest(endpoint, body)
 	if err != nil {
-		return c.fallbackExtraction(query)
+		return nil, err
 	}
 
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	if c.api.APIVersion != "" {
+		req.Header.Set("api-key", c.apiKey)
+	} else {
+		req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	}
+	if c.api.Organization != "" {
+		req.Header.Set("OpenAI-Organization", c.api.Organization)
+	}
+	if c.api.Project != "" {
+		req.Header.Set("OpenAI-Project", c.api.Project)
+	}
+	return req, nil
+}
 
-	resp, err := c.client.Do(req)
+// chatCompletionWith sends the prompts to one model of the chain, in the
+// provider's wire format
+func (c *Client) chatCompletionWith(model ModelEndpoint, systemPrompt, userPrompt string) (string, error) {
+	api := providers[c.api.Provider].chat
+	req, err := api.request(c, model, systemPrompt, userPrompt)
 	if err != nil {
-		return c.fallbackExtraction(query)
+		return "", err
+	}
+
+	resp, err := c.do(req)
+	if err != nil {
+		return "", err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return c.fallbackExtraction(query)
+		return "", fmt.Errorf("chat completion request failed: status code %d", resp.StatusCode)
 	}
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return c.fallbackExtraction(query)
+		return "", err
+	}
+
+	content, tokens, err := api.decode(body)
+	if c.tokens != nil && tokens > 0 {
+		c.tokens(tokens)
+	}
+	return content, err
+}
+
+// openAIChat speaks the chat completions API of OpenAI and compatible servers
+type openAIChat struct{}
+
+func (openAIChat) request(c *Client, model ModelEndpoint, systemPrompt, userPrompt string) (*http.Request, error) {
+	jsonData, err := json.Marshal(OpenAIRequest{
+		Model: model.Name,
+		Messages: []Message{
+			{Role: "system", Content: systemPrompt},
+			{Role: "user", Content: userPrompt},
+		},
+		MaxTokens: c.maxTokens,
+	})
+	if err != nil {
+		return nil, err
 	}
+	return c.newRequest(model.BaseURL+"/chat/completions", jsonData)
+}
 
+func (openAIChat) decode(body []byte) (string, int, error) {
 	var openAIResp OpenAIResponse
 	if err := json.Unmarshal(body, &openAIResp); err != nil {
-		return c.fallbackExtraction(query)
+		return "", 0, err
 	}
-
 	if len(openAIResp.Choices) == 0 {
-		return c.fallbackExtraction(query)
+		return "", openAIResp.Usage.TotalTokens, fmt.Errorf("openai response contained no choices")
 	}
+	return openAIResp.Choices[0].Message.Content, openAIResp.Usage.TotalTokens, nil
+}
 
-	content := openAIResp.Choices[0].Message.Content
-	
-	// Try to extract JSON from the response
-	var result ExtractionResult
-	if err := json.Unmarshal([]byte(content), &result); err != nil {
-		// Try to find JSON in markdown code blocks
-		if start := strings.Index(content, "```json"); start != -1 {
-			start += 7
-			if end := strings.Index(content[start:], "```"); end != -1 {
-				jsonStr := content[start : start+end]
-				if err := json.Unmarshal([]byte(jsonStr), &result); err == nil {
-					return &result, nil
-				}
-			}
+// decodeJSONContent unmarshals model output that may be wrapped in a markdown code block
+func decodeJSONContent(content string, v interface{}) error {
+	if err := json.Unmarshal([]byte(content), v); err == nil {
+		return nil
+	}
+	if start := strings.Index(content, "```json"); start != -1 {
+		start += 7
+		if end := strings.Index(content[start:], "```"); end != -1 {
+			return json.Unmarshal([]byte(content[start:start+end]), v)
 		}
-		return c.fallbackExtraction(query)
 	}
-
-	return &result, nil
+	return fmt.Errorf("no JSON object found in model output")
 }
 
 // fallbackExtraction provides heuristic extraction when LLM is not available
 func (c *Client) fallbackExtraction(query string) (*ExtractionResult, error) {
+	c.report.Record(degradation.SubsystemIntent, degradation.ModeHeuristic)
 	lowerQuery := strings.ToLower(query)
 	
 	result := &ExtractionResult{
-		Intent:   IntentSearch,
-		Entities: extractEntities(query),
-		Query:    query,
+		Intent:    IntentSearch,
+		Entities:  extractEntities(query),
+		Locations: extractLocations(query),
+		DateRange: parseDateRange(query, c.now()),
+		Query:     query,
 	}
 
 	// Detect intent based on keywords
@@ -189,6 +383,10 @@ func extractEntities(query string) []string {
 	for _, word := range words {
 		// Skip common words - check if first letter is uppercase
 		if len(word) > 3 && word[0] >= 'A' && word[0] <= 'Z' {
+			// Month names belong to the date range, not the search terms
+			if _, isMonth := parseMonth(strings.ToLower(word)); isMonth {
+				continue
+			}
 			entities = append(entities, word)
 		}
 	}
@@ -196,6 +394,41 @@ func extractEntities(query string) []string {
 	return entities
 }
 
+// locationPrepositions introduce a place name in heuristic location extraction
+var locationPrepositions = map[string]bool{"in": true, "near": true, "around": true, "at": true}
+
+// extractLocations returns the capitalized phrases following a location
+// preposition, e.g. "Palo Alto" in "news near Palo Alto"
+func extractLocations(query string) []string {
+	words := strings.Fields(query)
+	locations := []string{}
+
+	for i := 0; i < len(words); i++ {
+		if !locationPrepositions[strings.ToLower(words[i])] {
+			continue
+		}
+		var phrase []string
+		for j := i + 1; j < len(words); j++ {
+			word := strings.Trim(words[j], ".,;:!?'\"")
+			if word == "" || word[0] < 'A' || word[0] > 'Z' {
+				break
+			}
+			if _, isMonth := parseMonth(strings.ToLower(word)); isMonth {
+				break
+			}
+			phrase = append(phrase, word)
+			if strings.ContainsAny(words[j], ".,;:!?") {
+				break
+			}
+		}
+		if len(phrase) > 0 {
+			locations = append(locations, strings.Join(phrase, " "))
+		}
+	}
+
+	return locations
+}
+
 // containsCategory checks if query contains a news category
 func containsCategory(query string) bool {
 	categories := []string{
@@ -211,75 +444,151 @@ func containsCategory(query string) bool {
 	return false
 }
 
+// Summary lengths, for surfaces from push notifications to article pages.
+// Medium is the summary stored on the article.
+const (
+	SummaryShort  = "short"
+	SummaryMedium = "medium"
+	SummaryLong   = "long"
+)
+
+// summaryStyle is how summaries of one length are asked for and truncated
+type summaryStyle struct {
+	instruction   string // How long the summary should be, for the prompt
+	maxTokens     int
+	fallbackChars int // Description characters kept by the heuristic summary
+}
+
+var summaryStyles = map[string]summaryStyle{
+	SummaryShort:  {"one sentence of at most 20 words", 40, 60},
+	SummaryMedium: {"1-2 concise sentences", 100, 150},
+	SummaryLong:   {"a paragraph of 4-5 sentences", 250, 400},
+}
+
+// ValidSummaryLength reports whether a summary length is known
+func ValidSummaryLength(length string) bool {
+	_, ok := summaryStyles[length]
+	return ok
+}
+
 // GenerateSummary generates a summary for an article
 func (c *Client) GenerateSummary(title, description string) (string, error) {
-	if c.apiKey == "" {
+	return c.GenerateSummaryOfLength(title, description, SummaryMedium)
+}
+
+// GenerateSummaryOfLength generates a summary for an article of the given
+// length; unknown lengths are treated as medium
+func (c *Client) GenerateSummaryOfLength(title, description, length string) (string, error) {
+	style, ok := summaryStyles[length]
+	if !ok {
+		style = summaryStyles[SummaryMedium]
+	}
+	if !c.Enabled() {
 		// Fallback to a simple summary
-		return c.fallbackSummary(title, description), nil
+		return c.fallbackSummary(title, description, style.fallbackChars), nil
 	}
 
-	prompt := fmt.Sprintf(`Summarize the following news article in 1-2 concise sentences:
+	prompt := fmt.Sprintf(`Summarize the following news article in %s:
 
 Title: %s
 Description: %s
 
-Summary:`, title, description)
+Summary:`, style.instruction, title, description)
 
-	reqBody := OpenAIRequest{
-		Model: c.model,
-		Messages: []Message{
-			{Role: "system", Content: "You are a news summarizer. Provide concise 1-2 sentence summaries."},
-			{Role: "user", Content: prompt},
-		},
+	limited := *c
+	limited.maxTokens = style.maxTokens
+	content, err := limited.chatCompletion("You are a news summarizer. Provide summaries of exactly the requested length.", prompt)
+	if err != nil {
+		return c.fallbackSummary(title, description, style.fallbackChars), nil
 	}
 
-	jsonData, err := json.Marshal(reqBody)
-	if err != nil {
-		return c.fallbackSummary(title, description), nil
+	return strings.TrimSpace(content), nil
+}
+
+// fallbackSummary provides a simple summary when LLM is not available, keeping
+// up to chars characters of the description
+func (c *Client) fallbackSummary(title, description string, chars int) string {
+	c.report.Record(degradation.SubsystemLLMSummary, degradation.ModeFallback)
+	// Truncate description and add title context
+	summary := description
+	if len(summary) > chars {
+		summary = summary[:chars] + "..."
 	}
+	return fmt.Sprintf("This article about '%s' reports that %s", title, strings.ToLower(summary))
+}
 
-	req, err := http.NewRequest("POST", "https://api.openai.com/v1/chat/completions", bytes.NewBuffer(jsonData))
-	if err != nil {
-		return c.fallbackSummary(title, description), nil
+// GenerateStorySummary generates a combined summary for a group of articles covering one event.
+// Headlines are expected in chronological order.
+func (c *Client) GenerateStorySummary(headlines []string) (string, error) {
+	if !c.Enabled() {
+		return c.fallbackStorySummary(headlines), nil
 	}
 
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	prompt := fmt.Sprintf(`The following headlines, in chronological order, all cover the same developing news story.
+Write a 2-3 sentence summary of the story so far, highlighting how it developed:
 
-	resp, err := c.client.Do(req)
+%s
+
+Summary:`, "- "+strings.Join(headlines, "\n- "))
+
+	content, err := c.chatCompletion("You are a news editor. Provide concise summaries of developing stories.", prompt)
 	if err != nil {
-		return c.fallbackSummary(title, description), nil
+		return c.fallbackStorySummary(headlines), nil
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return c.fallbackSummary(title, description), nil
+	return strings.TrimSpace(content), nil
+}
+
+// fallbackStorySummary describes a story from its first and latest headlines
+func (c *Client) fallbackStorySummary(headlines []string) string {
+	c.report.Record(degradation.SubsystemLLMSummary, degradation.ModeFallback)
+	if len(headlines) == 0 {
+		return ""
 	}
+	if len(headlines) == 1 {
+		return headlines[0]
+	}
+	return fmt.Sprintf("This story began with '%s' and most recently reported '%s' across %d articles.",
+		headlines[0], headlines[len(headlines)-1], len(headlines))
+}
 
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return c.fallbackSummary(title, description), nil
+// GenerateChangeNote describes what changed in a story between two periods, given the
+// headlines of the previous and current period
+func (c *Client) GenerateChangeNote(previous, current []string) (string, error) {
+	if !c.Enabled() {
+		return c.fallbackChangeNote(previous, current), nil
 	}
 
-	var openAIResp OpenAIResponse
-	if err := json.Unmarshal(body, &openAIResp); err != nil {
-		return c.fallbackSummary(title, description), nil
+	previousText := "(none - this is the first period)"
+	if len(previous) > 0 {
+		previousText = "- " + strings.Join(previous, "\n- ")
 	}
 
-	if len(openAIResp.Choices) == 0 {
-		return c.fallbackSummary(title, description), nil
+	prompt := fmt.Sprintf(`Headlines from the previous period:
+%s
+
+Headlines from the current period:
+%s
+
+In one sentence, describe what changed or is new in the current period compared to the previous one.`, previousText, "- "+strings.Join(current, "\n- "))
+
+	content, err := c.chatCompletion("You are a news editor tracking how stories evolve. Be concise and factual.", prompt)
+	if err != nil {
+		return c.fallbackChangeNote(previous, current), nil
 	}
 
-	summary := strings.TrimSpace(openAIResp.Choices[0].Message.Content)
-	return summary, nil
+	return strings.TrimSpace(content), nil
 }
 
-// fallbackSummary provides a simple summary when LLM is not available
-func (c *Client) fallbackSummary(title, description string) string {
-	// Truncate description to first 150 characters and add title context
-	summary := description
-	if len(summary) > 150 {
-		summary = summary[:150] + "..."
+// fallbackChangeNote reports the period volume and its latest headline
+func (c *Client) fallbackChangeNote(previous, current []string) string {
+	c.report.Record(degradation.SubsystemLLMSummary, degradation.ModeFallback)
+	if len(current) == 0 {
+		return ""
 	}
-	return fmt.Sprintf("This article about '%s' reports that %s", title, strings.ToLower(summary))
+	latest := current[len(current)-1]
+	if len(previous) == 0 {
+		return fmt.Sprintf("Coverage began with %d articles, latest: '%s'.", len(current), latest)
+	}
+	return fmt.Sprintf("%d articles (previously %d), latest: '%s'.", len(current), len(previous), latest)
 }
@@ -0,0 +1,124 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/mahigadamsetty/Inshorts-task/internal/degradation"
+	"github.com/mahigadamsetty/Inshorts-task/internal/faults"
+)
+
+// LLM providers a client can talk to
+const (
+	ProviderOpenAI    = "openai"
+	ProviderAnthropic = "anthropic"
+	ProviderGemini    = "gemini"
+	ProviderOllama    = "ollama"
+)
+
+// Provider is what the news handlers, the summarizer and speech synthesis ask
+// of a language model. *Client implements it over the chat API of whichever
+// provider is configured, falling back to heuristics when the model can't
+// answer; another backend, or a fake in tests, can stand in for it.
+type Provider interface {
+	// For returns the provider answering the calls of one request or job
+	For(call Call) Provider
+	// ProviderName returns the API answering, like openai
+	ProviderName() string
+	ExtractIntentAndEntities(query string) (*ExtractionResult, error)
+	GenerateSummary(title, description string) (string, error)
+	GenerateSummaryOfLength(title, description, length string) (string, error)
+	GenerateStorySummary(headlines []string) (string, error)
+	GenerateChangeNote(previous, current []string) (string, error)
+	Speech(model, voice, format, text string) ([]byte, error)
+}
+
+var _ Provider = (*Client)(nil)
+
+// Call scopes a provider to one request or job. Fields left zero keep the
+// provider's own.
+type Call struct {
+	Context     context.Context     // Cancels the API requests of an abandoned request or job
+	Report      *degradation.Report // Records the fallbacks taken
+	Faults      *faults.Faults      // Fails API requests as injected
+	Location    *time.Location      // Timezone relative dates, like "today", resolve in
+	CountTokens func(tokens int)    // Meters the tokens each answer used
+}
+
+// For returns a copy of the client set up by the With methods for the call
+func (c *Client) For(call Call) Provider {
+	clone := *c
+	if call.Context != nil {
+		clone.ctx = call.Context
+	}
+	if call.Report != nil {
+		clone.report = call.Report
+	}
+	if call.Faults != nil {
+		clone.faults = call.Faults
+	}
+	if call.Location != nil {
+		clone.location = call.Location
+	}
+	if call.CountTokens != nil {
+		clone.tokens = call.CountTokens
+	}
+	return &clone
+}
+
+// chatAPI is the wire format of a provider's chat endpoint
+type chatAPI interface {
+	// request builds the request asking a model of the chain to answer the prompts
+	request(c *Client, model ModelEndpoint, systemPrompt, userPrompt string) (*http.Request, error)
+	// decode returns the answer of a successful response and the tokens it used
+	decode(body []byte) (content string, tokens int, err error)
+}
+
+// provider is a supported LLM API
+type provider struct {
+	baseURL  string // Public endpoint, used when no base URL is configured
+	needsKey bool   // Without a key, clients use heuristics instead
+	chat     chatAPI
+}
+
+var providers = map[string]provider{
+	ProviderOpenAI:    {baseURL: OpenAIBaseURL, needsKey: true, chat: openAIChat{}},
+	ProviderAnthropic: {baseURL: AnthropicBaseURL, needsKey: true, chat: anthropicChat{}},
+	ProviderGemini:    {baseURL: GeminiBaseURL, needsKey: true, chat: geminiChat{}},
+	ProviderOllama:    {baseURL: OllamaBaseURL, needsKey: false, chat: ollamaChat{}},
+}
+
+// Providers lists the supported LLM providers
+func Providers() []string {
+	names := make([]string, 0, len(providers))
+	for name := range providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ProviderName returns the provider the client talks to
+func (c *Client) ProviderName() string {
+	return c.api.Provider
+}
+
+// Enabled reports whether the client asks a model, rather than answering
+// with heuristics: it has a model chain and, unless the provider runs
+// locally, an API key
+func (c *Client) Enabled() bool {
+	return len(c.models) > 0 && (c.apiKey != "" || !providers[c.api.Provider].needsKey)
+}
+
+// jsonRequest builds a JSON POST cancelled with the client's context
+func (c *Client) jsonRequest(endpoint string, body []byte) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(c.context(), "POST", endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	return req, nil
+}
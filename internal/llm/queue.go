@@ -0,0 +1,178 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mahigadamsetty/Inshorts-task/internal/faults"
+)
+
+// ErrQueueTimeout is returned when a request waits longer than the queue allows
+var ErrQueueTimeout = errors.New("llm request queue wait exceeded")
+
+// defaultRateLimitPause is used when a 429 response has no usable Retry-After
+const defaultRateLimitPause = 5 * time.Second
+
+// RequestQueue paces the outgoing requests of every client sharing it: at most
+// maxConcurrent in flight, started no faster than the requests-per-minute
+// budget allows, in arrival order
+type RequestQueue struct {
+	slots    chan struct{} // nil when concurrency is unlimited
+	interval time.Duration // Minimum spacing between request starts, 0 for no pacing
+	maxWait  time.Duration // Longest a request may wait for its turn, 0 to wait indefinitely
+
+	mu   sync.Mutex
+	next time.Time // Earliest start time for the next request
+}
+
+// NewRequestQueue creates a queue. Non-positive limits disable that limit.
+func NewRequestQueue(maxConcurrent, requestsPerMinute int, maxWait time.Duration) *RequestQueue {
+	q := &RequestQueue{maxWait: maxWait}
+	if maxConcurrent > 0 {
+		q.slots = make(chan struct{}, maxConcurrent)
+	}
+	if requestsPerMinute > 0 {
+		q.interval = time.Minute / time.Duration(requestsPerMinute)
+	}
+	return q
+}
+
+// acquire waits for a concurrency slot and a pacing slot, returning a function
+// that frees the concurrency slot. Gives up with ctx's error once it is done.
+func (q *RequestQueue) acquire(ctx context.Context) (func(), error) {
+	var deadline <-chan time.Time
+	var deadlineAt time.Time
+	if q.maxWait > 0 {
+		deadlineAt = time.Now().Add(q.maxWait)
+		timer := time.NewTimer(q.maxWait)
+		defer timer.Stop()
+		deadline = timer.C
+	}
+
+	release := func() {}
+	if q.slots != nil {
+		select {
+		case q.slots <- struct{}{}:
+		case <-deadline:
+			return nil, ErrQueueTimeout
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		var once sync.Once
+		release = func() { once.Do(func() { <-q.slots }) }
+	}
+
+	q.mu.Lock()
+	start := time.Now()
+	if q.next.After(start) {
+		start = q.next
+	}
+	if !deadlineAt.IsZero() && start.After(deadlineAt) {
+		q.mu.Unlock()
+		release()
+		return nil, ErrQueueTimeout
+	}
+	q.next = start.Add(q.interval)
+	q.mu.Unlock()
+
+	wait := time.NewTimer(time.Until(start))
+	defer wait.Stop()
+	select {
+	case <-wait.C:
+	case <-ctx.Done():
+		release()
+		return nil, ctx.Err()
+	}
+	return release, nil
+}
+
+// pause holds back every queued request for d, after the API reports a rate limit
+func (q *RequestQueue) pause(d time.Duration) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if resume := time.Now().Add(d); resume.After(q.next) {
+		q.next = resume
+	}
+}
+
+// retryAfter reads the Retry-After header of a rate-limited response
+func retryAfter(resp *http.Response) time.Duration {
+	if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	return defaultRateLimitPause
+}
+
+// releasingBody frees the request's queue slot once the response body is closed
+type releasingBody struct {
+	io.ReadCloser
+	release func()
+}
+
+func (b *releasingBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.release()
+	return err
+}
+
+// do sends a request through the client's queue. The concurrency slot is held
+// until the response body is closed. The client's latency SLO applies from the
+// moment the request leaves the queue until its body is closed.
+func (c *Client) do(req *http.Request) (*http.Response, error) {
+	if resp, injected, err := c.injectFault(); injected {
+		return resp, err
+	}
+
+	queue := c.queue
+	acquired, err := queue.acquire(req.Context())
+	if err != nil {
+		return nil, err
+	}
+	release := acquired
+	if c.latencySLO > 0 {
+		ctx, cancel := context.WithTimeout(req.Context(), c.latencySLO)
+		req = req.WithContext(ctx)
+		release = func() {
+			cancel()
+			acquired()
+		}
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		release()
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusTooManyRequests {
+		queue.pause(retryAfter(resp))
+	}
+	resp.Body = &releasingBody{ReadCloser: resp.Body, release: release}
+	return resp, nil
+}
+
+// injectFault fails a request as the client's injected faults say, without
+// touching the shared queue or the network
+func (c *Client) injectFault() (*http.Response, bool, error) {
+	if c.faults == nil {
+		return nil, false, nil
+	}
+	switch c.faults.LLM {
+	case faults.LLMRateLimited:
+		resp := &http.Response{
+			StatusCode: http.StatusTooManyRequests,
+			Header:     http.Header{"Retry-After": []string{"1"}},
+			Body:       io.NopCloser(strings.NewReader("")),
+		}
+		return resp, true, nil
+	case faults.LLMTimeout:
+		return nil, true, fmt.Errorf("injected llm timeout: %w", context.DeadlineExceeded)
+	}
+	return nil, false, nil
+}
@@ -0,0 +1,65 @@
+package llm
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// MaxSpeechInput is the longest text, in characters, the speech endpoint reads
+const MaxSpeechInput = 4096
+
+// ErrNoAPIKey is returned by requests that have no heuristic fallback
+var ErrNoAPIKey = errors.New("no OpenAI API key configured")
+
+type speechRequest struct {
+	Model          string `json:"model"`
+	Input          string `json:"input"`
+	Voice          string `json:"voice"`
+	ResponseFormat string `json:"response_format"`
+}
+
+// Speech reads text aloud with a text-to-speech model and voice, returning the
+// audio in the given format, e.g. mp3. Text past MaxSpeechInput characters is
+// cut off. Only the OpenAI provider serves speech.
+func (c *Client) Speech(model, voice, format, text string) ([]byte, error) {
+	if c.api.Provider != ProviderOpenAI {
+		return nil, fmt.Errorf("the %s LLM provider has no speech endpoint", c.api.Provider)
+	}
+	if c.apiKey == "" {
+		return nil, ErrNoAPIKey
+	}
+	if runes := []rune(text); len(runes) > MaxSpeechInput {
+		text = string(runes[:MaxSpeechInput])
+	}
+
+	jsonData, err := json.Marshal(speechRequest{Model: model, Input: text, Voice: voice, ResponseFormat: format})
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := c.newRequest(c.api.BaseURL+"/audio/speech", jsonData)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("openai speech request failed: status code %d", resp.StatusCode)
+	}
+	audio, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if len(audio) == 0 {
+		return nil, fmt.Errorf("openai speech request returned no audio")
+	}
+	return audio, nil
+}
@@ -0,0 +1,268 @@
+// Package llmstub serves canned OpenAI chat completion, embedding and speech
+// responses so integration tests and local development run without real API keys.
+//
+// Each chat request is identified by a fingerprint of its prompts. A fixture
+// with that fingerprint answers it verbatim; otherwise the stub gives a
+// generic answer shaped for the prompt it recognizes (intent extraction,
+// summaries, moderation, topic labels). The fingerprint is returned in the
+// X-Stub-Fingerprint header so unmatched requests can be turned into fixtures.
+//
+// In tests, serve it with httptest and point the client at it:
+//
+//	server := httptest.NewServer(llmstub.NewHandler(fixtures))
+//	defer server.Close()
+//	client, _ := llm.New(llm.ClientOptions{APIKey: "stub", Models: []string{"gpt-4o-mini"}, API: llm.APIOptions{BaseURL: server.URL}})
+package llmstub
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// FingerprintHeader carries the fingerprint of a chat request in the response
+const FingerprintHeader = "X-Stub-Fingerprint"
+
+// embeddingDims is the size of the stub's embedding vectors
+const embeddingDims = 64
+
+// Speech is served as silent MPEG-1 Layer III frames at 128 kbps and 44.1 kHz,
+// 1152 samples each, read at a typical speaking rate
+const (
+	silentFrameSize       = 417
+	silentFramesPerSecond = 38
+	speechWordsPerMinute  = 150
+)
+
+// silentFrameHeader starts every silent frame; the rest of the frame is zeros
+var silentFrameHeader = []byte{0xFF, 0xFB, 0x90, 0x64}
+
+// Fixtures maps request fingerprints to the content the stub answers with
+type Fixtures map[string]string
+
+// LoadFixtures reads fixtures from a JSON object of fingerprint -> content
+func LoadFixtures(path string) (Fixtures, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var fixtures Fixtures
+	if err := json.Unmarshal(data, &fixtures); err != nil {
+		return nil, fmt.Errorf("invalid fixtures %s: %w", path, err)
+	}
+	return fixtures, nil
+}
+
+// datePattern matches the dates prompts embed, like "Today is 2025-01-31"
+var datePattern = regexp.MustCompile(`\d{4}-\d{2}-\d{2}`)
+
+// Fingerprint identifies a chat request by its system and user prompts. Dates
+// are masked so fingerprints don't change from one day to the next.
+func Fingerprint(systemPrompt, userPrompt string) string {
+	sum := sha256.Sum256([]byte(datePattern.ReplaceAllString(systemPrompt+"\n"+userPrompt, "YYYY-MM-DD")))
+	return hex.EncodeToString(sum[:16])
+}
+
+type chatRequest struct {
+	Model    string `json:"model"`
+	Messages []struct {
+		Role    string `json:"role"`
+		Content string `json:"content"`
+	} `json:"messages"`
+}
+
+type embeddingRequest struct {
+	Input []string `json:"input"`
+}
+
+type speechRequest struct {
+	Input string `json:"input"`
+}
+
+// NewHandler returns an http.Handler serving the OpenAI chat completion,
+// embedding and speech endpoints, under any base path
+func NewHandler(fixtures Fixtures) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/chat/completions"):
+			serveChat(w, r, fixtures)
+		case r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/embeddings"):
+			serveEmbeddings(w, r)
+		case r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/audio/speech"):
+			serveSpeech(w, r)
+		default:
+			writeJSON(w, http.StatusNotFound, map[string]any{"error": map[string]string{"message": "not served by the stub"}})
+		}
+	})
+}
+
+// serveChat answers a chat completion from the fixtures or a generic answer
+func serveChat(w http.ResponseWriter, r *http.Request, fixtures Fixtures) {
+	var req chatRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": map[string]string{"message": err.Error()}})
+		return
+	}
+	var systemPrompt, userPrompt string
+	for _, message := range req.Messages {
+		switch message.Role {
+		case "system":
+			systemPrompt = message.Content
+		case "user":
+			userPrompt = message.Content
+		}
+	}
+
+	fingerprint := Fingerprint(systemPrompt, userPrompt)
+	content, ok := fixtures[fingerprint]
+	if !ok {
+		content = genericAnswer(systemPrompt, userPrompt)
+	}
+
+	w.Header().Set(FingerprintHeader, fingerprint)
+	writeJSON(w, http.StatusOK, map[string]any{
+		"id":     "stub-" + fingerprint,
+		"object": "chat.completion",
+		"model":  req.Model,
+		"choices": []map[string]any{{
+			"index":         0,
+			"message":       map[string]string{"role": "assistant", "content": content},
+			"finish_reason": "stop",
+		}},
+		"usage": stubUsage(systemPrompt+userPrompt, content),
+	})
+}
+
+// stubUsage estimates token counts at about four characters a token, so
+// clients metering tokens see plausible numbers
+func stubUsage(prompt, completion string) map[string]int {
+	promptTokens, completionTokens := len(prompt)/4+1, len(completion)/4+1
+	return map[string]int{"prompt_tokens": promptTokens, "completion_tokens": completionTokens, "total_tokens": promptTokens + completionTokens}
+}
+
+// genericAnswer shapes a valid answer for the prompts the client sends
+func genericAnswer(systemPrompt, userPrompt string) string {
+	switch {
+	case strings.Contains(systemPrompt, "query analyzer") && strings.Contains(userPrompt, "JSON array"):
+		var items []map[string]any
+		for i := range numberedLines(userPrompt) {
+			items = append(items, map[string]any{"index": i + 1, "intent": "search", "entities": []string{}, "locations": []string{}})
+		}
+		return mustJSON(items)
+	case strings.Contains(systemPrompt, "query analyzer"):
+		return mustJSON(map[string]any{
+			"intent":     "search",
+			"entities":   []string{},
+			"locations":  []string{},
+			"date_range": nil,
+			"query":      field(userPrompt, "Query"),
+		})
+	case strings.Contains(systemPrompt, "content moderator"):
+		return `{"rating": "safe", "tags": []}`
+	case strings.Contains(systemPrompt, "naming topic pages"):
+		return "Stub Topic"
+	case strings.Contains(systemPrompt, "summarizer"):
+		return "Stub summary: " + field(userPrompt, "Title")
+	default:
+		return "Stub response."
+	}
+}
+
+// field returns the value of a "Name: value" line of a prompt
+func field(prompt, name string) string {
+	for _, line := range strings.Split(prompt, "\n") {
+		if value, ok := strings.CutPrefix(line, name+": "); ok {
+			return strings.TrimSpace(value)
+		}
+	}
+	return ""
+}
+
+// numberedLines returns the "1. ..." lines following the "Queries:" line of a
+// batch prompt
+func numberedLines(prompt string) []string {
+	_, list, _ := strings.Cut(prompt, "Queries:\n")
+	var lines []string
+	for _, line := range strings.Split(list, "\n") {
+		value, ok := strings.CutPrefix(line, fmt.Sprintf("%d. ", len(lines)+1))
+		if !ok {
+			break
+		}
+		lines = append(lines, value)
+	}
+	return lines
+}
+
+// serveEmbeddings answers with deterministic unit vectors derived from each input
+func serveEmbeddings(w http.ResponseWriter, r *http.Request) {
+	var req embeddingRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": map[string]string{"message": err.Error()}})
+		return
+	}
+	data := make([]map[string]any, len(req.Input))
+	for i, text := range req.Input {
+		data[i] = map[string]any{"object": "embedding", "index": i, "embedding": stubEmbedding(text)}
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"object": "list", "data": data})
+}
+
+// stubEmbedding expands the hash of a text into a unit vector
+func stubEmbedding(text string) []float32 {
+	vector := make([]float32, embeddingDims)
+	var norm float64
+	for i := range vector {
+		sum := sha256.Sum256([]byte(fmt.Sprintf("%d|%s", i/8, text)))
+		v := float64(int32(binary.BigEndian.Uint32(sum[(i%8)*4:]))) / math.MaxInt32
+		vector[i] = float32(v)
+		norm += v * v
+	}
+	if norm > 0 {
+		scale := float32(1 / math.Sqrt(norm))
+		for i := range vector {
+			vector[i] *= scale
+		}
+	}
+	return vector
+}
+
+// serveSpeech answers with silent MP3 audio lasting about as long as reading
+// the input aloud would
+func serveSpeech(w http.ResponseWriter, r *http.Request) {
+	var req speechRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": map[string]string{"message": err.Error()}})
+		return
+	}
+	seconds := len(strings.Fields(req.Input)) * 60 / speechWordsPerMinute
+	if seconds < 1 {
+		seconds = 1
+	}
+	frame := make([]byte, silentFrameSize)
+	copy(frame, silentFrameHeader)
+	w.Header().Set("Content-Type", "audio/mpeg")
+	for i := 0; i < seconds*silentFramesPerSecond; i++ {
+		w.Write(frame)
+	}
+}
+
+func mustJSON(v any) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		panic(err)
+	}
+	return string(data)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
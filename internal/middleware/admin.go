@@ -0,0 +1,24 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Admin restricts a route group to callers presenting the admin API key.
+// Admin routes are disabled entirely when no key is configured.
+func Admin(adminKey string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if adminKey == "" {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Admin API is disabled"})
+			return
+		}
+		if subtle.ConstantTimeCompare([]byte(APIKey(c)), []byte(adminKey)) != 1 {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid admin API key"})
+			return
+		}
+		c.Next()
+	}
+}
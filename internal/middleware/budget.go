@@ -0,0 +1,19 @@
+package middleware
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mahigadamsetty/Inshorts-task/internal/budget"
+)
+
+// Budget gives requests to the routes in budgets a response-time budget, by
+// route pattern. Routes without a positive budget run every stage.
+func Budget(budgets map[string]time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if d := budgets[c.FullPath()]; d > 0 {
+			c.Request = c.Request.WithContext(budget.NewContext(c.Request.Context(), d))
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,141 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// compactRequested reports whether the client asked for smaller payloads: by
+// sending Save-Data, device=mobile, or, without a device hint, the mobile
+// client hint. device=desktop only overrides the mobile client hint.
+func compactRequested(c *gin.Context) bool {
+	if strings.EqualFold(c.GetHeader("Save-Data"), "on") {
+		return true
+	}
+	switch strings.ToLower(c.Request.URL.Query().Get("device")) {
+	case "mobile":
+		return true
+	case "desktop":
+		return false
+	}
+	return c.GetHeader("Sec-CH-UA-Mobile") == "?1"
+}
+
+// ClientHints shrinks the JSON responses of the routes in limits, by route
+// pattern, for clients that ask for smaller payloads: articles lose their
+// description, summaries are cut to summaryChars (0 keeps them whole) and the
+// route's limit applies when the request sets none (0 keeps the handler's
+// default). Other routes and error responses pass through untouched.
+func ClientHints(limits map[string]int, summaryChars int) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		limit, ok := limits[c.FullPath()]
+		if !ok {
+			c.Next()
+			return
+		}
+		c.Writer.Header().Add("Vary", "Save-Data, Sec-CH-UA-Mobile")
+		if !compactRequested(c) {
+			c.Next()
+			return
+		}
+
+		// Read from the URL rather than c.Query, whose cache would keep the
+		// handler from seeing the added limit
+		if query := c.Request.URL.Query(); limit > 0 && query.Get("limit") == "" {
+			query.Set("limit", strconv.Itoa(limit))
+			c.Request.URL.RawQuery = query.Encode()
+		}
+
+		writer := &bufferedWriter{ResponseWriter: c.Writer}
+		c.Writer = writer
+		c.Next()
+		c.Writer = writer.ResponseWriter
+
+		// Nothing written leaves the response to later middleware, like the
+		// timeout's 504
+		body := writer.body.Bytes()
+		if len(body) == 0 {
+			return
+		}
+		if writer.Status() == http.StatusOK && strings.HasPrefix(writer.Header().Get("Content-Type"), "application/json") {
+			if compacted, err := compactJSON(body, summaryChars); err == nil {
+				body = compacted
+			}
+		}
+		c.Writer.Write(body)
+	}
+}
+
+// bufferedWriter holds back the body so it can be rewritten before sending
+type bufferedWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *bufferedWriter) Write(data []byte) (int, error) {
+	return w.body.Write(data)
+}
+
+func (w *bufferedWriter) WriteString(s string) (int, error) {
+	return w.body.WriteString(s)
+}
+
+// compactJSON drops the description and shortens the summary of every article
+// in an "articles" list, at any depth
+func compactJSON(body []byte, summaryChars int) ([]byte, error) {
+	var value interface{}
+	if err := json.Unmarshal(body, &value); err != nil {
+		return nil, err
+	}
+	compactValue(value, summaryChars)
+	return json.Marshal(value)
+}
+
+// compactValue walks a decoded JSON value, shrinking articles in place
+func compactValue(value interface{}, summaryChars int) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, child := range v {
+			if articles, ok := child.([]interface{}); ok && key == "articles" {
+				for _, article := range articles {
+					if fields, ok := article.(map[string]interface{}); ok {
+						compactArticle(fields, summaryChars)
+					}
+				}
+				continue
+			}
+			compactValue(child, summaryChars)
+		}
+	case []interface{}:
+		for _, child := range v {
+			compactValue(child, summaryChars)
+		}
+	}
+}
+
+// compactArticle drops an article's description and shortens its summary
+func compactArticle(article map[string]interface{}, summaryChars int) {
+	delete(article, "description")
+	if summary, ok := article["llm_summary"].(string); ok && summaryChars > 0 {
+		article["llm_summary"] = shorten(summary, summaryChars)
+	}
+}
+
+// shorten cuts text to at most limit characters at a word boundary, marking
+// the cut with an ellipsis
+func shorten(text string, limit int) string {
+	runes := []rune(text)
+	if len(runes) <= limit {
+		return text
+	}
+	cut := string(runes[:limit])
+	if space := strings.LastIndex(cut, " "); space > 0 {
+		cut = cut[:space]
+	}
+	return strings.TrimRight(cut, " ,;:.") + "…"
+}
@@ -0,0 +1,15 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/mahigadamsetty/Inshorts-task/internal/degradation"
+)
+
+// Degradation attaches a degradation report to the request context so handlers,
+// services and the LLM client can record subsystems that fell back
+func Degradation() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Request = c.Request.WithContext(degradation.NewContext(c.Request.Context()))
+		c.Next()
+	}
+}
@@ -0,0 +1,28 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mahigadamsetty/Inshorts-task/internal/faults"
+)
+
+// Faults injects the failures named in the X-Inject-Fault header into the
+// request context, so fallback paths can be exercised end to end. Only install
+// it outside production.
+func Faults() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		value := c.GetHeader(faults.Header)
+		if value == "" {
+			c.Next()
+			return
+		}
+		injected, err := faults.Parse(value)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Invalid " + faults.Header + " header: " + err.Error()})
+			return
+		}
+		c.Request = c.Request.WithContext(faults.NewContext(c.Request.Context(), injected))
+		c.Next()
+	}
+}
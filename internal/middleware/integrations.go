@@ -0,0 +1,97 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	maxSignatureAge    = 5 * time.Minute // Older signed requests are rejected as replays
+	maxIntegrationBody = 64 << 10
+)
+
+// SlackSignature restricts a route to requests signed by Slack with the app's
+// signing secret. The route is disabled when no secret is configured.
+func SlackSignature(secret string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if secret == "" {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Slack integration is disabled"})
+			return
+		}
+		timestamp := c.GetHeader("X-Slack-Request-Timestamp")
+		body, ok := signedBody(c, timestamp)
+		if !ok {
+			return
+		}
+
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write([]byte("v0:" + timestamp + ":"))
+		mac.Write(body)
+		expected := "v0=" + hex.EncodeToString(mac.Sum(nil))
+		if !hmac.Equal([]byte(c.GetHeader("X-Slack-Signature")), []byte(expected)) {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid request signature"})
+			return
+		}
+		c.Next()
+	}
+}
+
+// DiscordSignature restricts a route to interactions signed by Discord,
+// verified with the app's public key. The route is disabled when no key is
+// configured.
+func DiscordSignature(publicKey ed25519.PublicKey) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if publicKey == nil {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Discord integration is disabled"})
+			return
+		}
+		timestamp := c.GetHeader("X-Signature-Timestamp")
+		body, ok := signedBody(c, timestamp)
+		if !ok {
+			return
+		}
+
+		signature, err := hex.DecodeString(c.GetHeader("X-Signature-Ed25519"))
+		if err != nil || !ed25519.Verify(publicKey, append([]byte(timestamp), body...), signature) {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid request signature"})
+			return
+		}
+		c.Next()
+	}
+}
+
+// signedBody reads the body of a signed request, leaving it in place for the
+// handler, after checking the signature's Unix timestamp is recent. It aborts
+// the request and returns false otherwise.
+func signedBody(c *gin.Context, timestamp string) ([]byte, bool) {
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Missing request signature"})
+		return nil, false
+	}
+	if age := time.Since(time.Unix(seconds, 0)); age > maxSignatureAge || age < -maxSignatureAge {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Request signature expired"})
+		return nil, false
+	}
+
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, maxIntegrationBody+1))
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Failed to read request body"})
+		return nil, false
+	}
+	if len(body) > maxIntegrationBody {
+		c.AbortWithStatusJSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Request body too large"})
+		return nil, false
+	}
+	c.Request.Body = io.NopCloser(bytes.NewReader(body))
+	return body, true
+}
@@ -0,0 +1,28 @@
+package middleware
+
+import (
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mahigadamsetty/Inshorts-task/internal/degradation"
+)
+
+// MaxLimit lowers a limit parameter above max to max before the handler reads
+// it, reporting `limit: capped` in the request's degradation report, so one
+// request can't rank and summarize thousands of articles. Routes in exempt,
+// by pattern, keep their own caps. 0 disables the cap.
+func MaxLimit(max int, exempt ...string) gin.HandlerFunc {
+	skip := make(map[string]bool, len(exempt))
+	for _, route := range exempt {
+		skip[route] = true
+	}
+	return func(c *gin.Context) {
+		query := c.Request.URL.Query()
+		if limit, err := strconv.Atoi(query.Get("limit")); err == nil && max > 0 && limit > max && !skip[c.FullPath()] {
+			query.Set("limit", strconv.Itoa(max))
+			c.Request.URL.RawQuery = query.Encode()
+			degradation.Record(c.Request.Context(), degradation.SubsystemLimit, degradation.ModeCapped)
+		}
+		c.Next()
+	}
+}
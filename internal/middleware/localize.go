@@ -0,0 +1,119 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mahigadamsetty/Inshorts-task/internal/i18n"
+)
+
+// Localize translates JSON responses for clients whose Accept-Language
+// prefers a language with a message catalog: the error message is
+// translated, articles get their categories' names as category_labels,
+// category counts get a label, and the meta of list responses gets an
+// endpoint_label naming the endpoint or /query intent. Field values clients
+// filter on are left alone. English responses pass through untouched.
+func Localize() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Writer.Header().Add("Vary", "Accept-Language")
+		language := i18n.Negotiate(c.GetHeader("Accept-Language"))
+		c.Header("Content-Language", language)
+		if language == i18n.Source {
+			c.Next()
+			return
+		}
+
+		writer := &bufferedWriter{ResponseWriter: c.Writer}
+		c.Writer = writer
+		c.Next()
+		c.Writer = writer.ResponseWriter
+
+		// Nothing written leaves the response to later middleware, like the
+		// timeout's 504
+		body := writer.body.Bytes()
+		if len(body) == 0 {
+			return
+		}
+		if strings.HasPrefix(writer.Header().Get("Content-Type"), "application/json") {
+			if localized, err := localizeJSON(body, language); err == nil {
+				body = localized
+			}
+		}
+		c.Writer.Write(body)
+	}
+}
+
+// localizeJSON adds the labels of a response in a language and translates
+// its error message
+func localizeJSON(body []byte, language string) ([]byte, error) {
+	// Numbers are kept as written, so IDs and counts don't turn into floats
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+	if fields, ok := value.(map[string]interface{}); ok {
+		if message, ok := fields["error"].(string); ok {
+			fields["error"] = i18n.Message(language, message)
+		}
+	}
+	localizeValue(value, language)
+	return json.Marshal(value)
+}
+
+// localizeValue walks a decoded JSON value, labelling articles, category
+// counts and meta in place
+func localizeValue(value interface{}, language string) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, child := range v {
+			switch key {
+			case "articles":
+				for _, article := range asObjects(child) {
+					if categories, ok := article["category"].([]interface{}); ok {
+						labels := make([]interface{}, len(categories))
+						for i, category := range categories {
+							name, _ := category.(string)
+							labels[i] = i18n.Category(language, name)
+						}
+						article["category_labels"] = labels
+					}
+				}
+			case "categories":
+				for _, category := range asObjects(child) {
+					if name, ok := category["name"].(string); ok {
+						category["label"] = i18n.Category(language, name)
+					}
+				}
+			case "meta":
+				if meta, ok := child.(map[string]interface{}); ok {
+					if endpoint, ok := meta["endpoint"].(string); ok {
+						if label, ok := i18n.Intent(language, endpoint); ok {
+							meta["endpoint_label"] = label
+						}
+					}
+				}
+			}
+			localizeValue(child, language)
+		}
+	case []interface{}:
+		for _, child := range v {
+			localizeValue(child, language)
+		}
+	}
+}
+
+// asObjects returns the objects of a JSON list
+func asObjects(value interface{}) []map[string]interface{} {
+	list, _ := value.([]interface{})
+	objects := make([]map[string]interface{}, 0, len(list))
+	for _, item := range list {
+		if object, ok := item.(map[string]interface{}); ok {
+			objects = append(objects, object)
+		}
+	}
+	return objects
+}
@@ -0,0 +1,80 @@
+package middleware
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mahigadamsetty/Inshorts-task/internal/platform"
+	"github.com/mahigadamsetty/Inshorts-task/internal/scrub"
+	"github.com/mahigadamsetty/Inshorts-task/internal/utils"
+)
+
+// coordinateParams are the query parameters carrying user coordinates
+var coordinateParams = []string{"lat", "lon"}
+
+// Logger logs each request like gin's default logger, plus the client
+// platform, with user coordinates in the query string truncated to the given
+// decimal places and PII scrubbed from the other parameters. Negative
+// decimals log coordinates as given.
+func Logger(decimals int) gin.HandlerFunc {
+	return gin.LoggerWithFormatter(func(param gin.LogFormatterParams) string {
+		if param.Latency > time.Minute {
+			param.Latency = param.Latency.Truncate(time.Second)
+		}
+		return fmt.Sprintf("[GIN] %v | %3d | %13v | %15s | %-11s | %-7s %#v\n%s",
+			param.TimeStamp.Format("2006/01/02 - 15:04:05"),
+			param.StatusCode,
+			param.Latency,
+			param.ClientIP,
+			platform.FromContext(param.Request.Context()),
+			param.Method,
+			redactQuery(param.Path, decimals),
+			param.ErrorMessage,
+		)
+	})
+}
+
+// redactQuery truncates the coordinate parameters of a logged path and scrubs
+// PII from the other parameters, which the log writer can't match while they
+// are still URL-encoded
+func redactQuery(path string, decimals int) string {
+	u, err := url.Parse(path)
+	if err != nil || u.RawQuery == "" {
+		return path
+	}
+	query := u.Query()
+	changed := false
+	for name, values := range query {
+		for i, value := range values {
+			redacted := scrub.String(value)
+			if isCoordinateParam(name) && decimals >= 0 {
+				if v, err := strconv.ParseFloat(value, 64); err == nil {
+					redacted = strconv.FormatFloat(utils.TruncateCoordinate(v, decimals), 'f', -1, 64)
+				} else {
+					redacted = "redacted"
+				}
+			}
+			if redacted != value {
+				values[i] = redacted
+				changed = true
+			}
+		}
+	}
+	if !changed {
+		return path
+	}
+	u.RawQuery = query.Encode()
+	return u.String()
+}
+
+func isCoordinateParam(name string) bool {
+	for _, param := range coordinateParams {
+		if name == param {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,15 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/mahigadamsetty/Inshorts-task/internal/platform"
+)
+
+// Platform stores the client platform of the request in its context, for
+// the events, logs and ranking experiments it leads to
+func Platform() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Request = c.Request.WithContext(platform.NewContext(c.Request.Context(), platform.Detect(c.Request)))
+		c.Next()
+	}
+}
@@ -0,0 +1,37 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mahigadamsetty/Inshorts-task/internal/publisher"
+	"github.com/mahigadamsetty/Inshorts-task/internal/tenant"
+)
+
+// Publisher restricts a route group to partner publishers presenting their
+// API key, enforces the publisher's rate limit and stores the publisher and
+// its tenant in the request context
+func Publisher(registry *publisher.Registry, tenants *tenant.Registry) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		apiKey := APIKey(c)
+		if apiKey == "" {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Publisher API key is required"})
+			return
+		}
+		p, ok := registry.Lookup(apiKey)
+		if !ok {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid publisher API key"})
+			return
+		}
+		if !p.AllowRequest() {
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"error": "Rate limit exceeded"})
+			return
+		}
+
+		// The tenant was checked when the publishers were loaded
+		t, _ := tenants.Get(p.TenantID)
+		ctx := tenant.NewContext(c.Request.Context(), t)
+		c.Request = c.Request.WithContext(publisher.NewContext(ctx, p))
+		c.Next()
+	}
+}
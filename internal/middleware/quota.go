@@ -0,0 +1,28 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"log"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mahigadamsetty/Inshorts-task/internal/tenant"
+)
+
+// Quota counts each request against its tenant's monthly request quota with
+// meter, and refuses requests once the quota is spent with status, 402 or
+// 429. Place it after Tenant.
+func Quota(meter func(ctx context.Context) error, status int) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		err := meter(c.Request.Context())
+		var quotaErr *tenant.QuotaError
+		if errors.As(err, &quotaErr) {
+			tenant.RespondQuota(c, quotaErr, status)
+			return
+		}
+		if err != nil {
+			log.Printf("Failed to meter request: %v", err)
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,31 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Reads reports the successful GET requests of clients that send an anonymous
+// ID, in the X-Client-ID header or the client_id parameter, to record. The
+// endpoint is the route below the group, like category or stories/:id.
+// Requests without an ID are not recorded.
+func Reads(group string, record func(ctx context.Context, endpoint, anonymousID string)) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		if c.Request.Method != http.MethodGet || c.Writer.Status() != http.StatusOK {
+			return
+		}
+		anonymousID := c.GetHeader("X-Client-ID")
+		if anonymousID == "" {
+			anonymousID = c.Request.URL.Query().Get("client_id")
+		}
+		if anonymousID == "" {
+			return
+		}
+		record(c.Request.Context(), strings.TrimPrefix(c.FullPath(), group+"/"), anonymousID)
+	}
+}
@@ -0,0 +1,100 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mahigadamsetty/Inshorts-task/internal/models"
+)
+
+// unrecordedHeaders are the request headers left out of recordings.
+// Recordings hold responses before localization, so replays go without
+// Accept-Language too.
+var unrecordedHeaders = map[string]bool{
+	"Authorization":   true,
+	"X-Api-Key":       true,
+	"Cookie":          true,
+	"Connection":      true,
+	"Accept-Language": true,
+}
+
+// Recorder captures a sampleRate share of GET requests with their JSON
+// responses and passes them to save once the response is sent, so they can be
+// replayed against another build. Coordinates in the query are truncated to
+// decimals places as in request logs, and responses over maxBytes are not
+// recorded.
+func Recorder(sampleRate float64, maxBytes, decimals int, save func(ctx context.Context, recording *models.RecordedRequest)) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if sampleRate <= 0 || c.Request.Method != http.MethodGet || rand.Float64() >= sampleRate {
+			c.Next()
+			return
+		}
+
+		writer := &recordingWriter{ResponseWriter: c.Writer, limit: maxBytes}
+		c.Writer = writer
+		start := time.Now()
+		c.Next()
+		c.Writer = writer.ResponseWriter
+
+		contentType := writer.Header().Get("Content-Type")
+		if writer.overflow || !strings.HasPrefix(contentType, "application/json") {
+			return
+		}
+		target, err := url.Parse(redactQuery(c.Request.URL.RequestURI(), decimals))
+		if err != nil {
+			return
+		}
+		headers := make(map[string]string)
+		for name, values := range c.Request.Header {
+			if !unrecordedHeaders[name] && len(values) > 0 {
+				headers[name] = values[0]
+			}
+		}
+		save(c.Request.Context(), &models.RecordedRequest{
+			Method:      c.Request.Method,
+			Path:        target.Path,
+			Query:       target.RawQuery,
+			Headers:     headers,
+			Status:      writer.Status(),
+			ContentType: contentType,
+			Body:        writer.body.String(),
+			DurationMs:  time.Since(start).Milliseconds(),
+		})
+	}
+}
+
+// recordingWriter copies the body to a buffer as it is sent, up to a limit
+type recordingWriter struct {
+	gin.ResponseWriter
+	body     bytes.Buffer
+	limit    int
+	overflow bool
+}
+
+func (w *recordingWriter) Write(data []byte) (int, error) {
+	w.capture(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *recordingWriter) WriteString(s string) (int, error) {
+	w.capture([]byte(s))
+	return w.ResponseWriter.WriteString(s)
+}
+
+func (w *recordingWriter) capture(data []byte) {
+	if w.overflow {
+		return
+	}
+	if w.body.Len()+len(data) > w.limit {
+		w.overflow = true
+		w.body.Reset()
+		return
+	}
+	w.body.Write(data)
+}
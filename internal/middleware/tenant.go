@@ -0,0 +1,52 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mahigadamsetty/Inshorts-task/internal/tenant"
+)
+
+// Tenant resolves the caller's tenant from its API key, enforces the tenant's
+// rate limit and stores the tenant in the request context. Requests without a
+// key use the default tenant unless requireKey is set.
+func Tenant(registry *tenant.Registry, requireKey bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		apiKey := APIKey(c)
+
+		var t *tenant.Tenant
+		if apiKey == "" {
+			if requireKey {
+				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "API key is required"})
+				return
+			}
+			t = registry.Default()
+		} else {
+			var ok bool
+			if t, ok = registry.Lookup(apiKey); !ok {
+				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid API key"})
+				return
+			}
+		}
+
+		if !t.AllowRequest() {
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"error": "Rate limit exceeded"})
+			return
+		}
+
+		c.Request = c.Request.WithContext(tenant.NewContext(c.Request.Context(), t))
+		c.Next()
+	}
+}
+
+// APIKey reads the API key from the X-API-Key header or a bearer token
+func APIKey(c *gin.Context) string {
+	if key := c.GetHeader("X-API-Key"); key != "" {
+		return key
+	}
+	if auth := c.GetHeader("Authorization"); strings.HasPrefix(auth, "Bearer ") {
+		return strings.TrimPrefix(auth, "Bearer ")
+	}
+	return ""
+}
@@ -0,0 +1,42 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Timeout gives every request a deadline, cancelling its database queries, LLM
+// calls and fetches once it passes or the client disconnects. Routes are
+// matched on their pattern, like /api/v1/news/stories/:id; an override for a
+// group prefix covers all of its routes and the longest match wins. A
+// non-positive duration leaves those requests without a deadline.
+func Timeout(defaultTimeout time.Duration, overrides map[string]time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		timeout := defaultTimeout
+		matched := ""
+		route := c.FullPath()
+		for prefix, override := range overrides {
+			if (route == prefix || strings.HasPrefix(route, prefix+"/")) && len(prefix) > len(matched) {
+				matched, timeout = prefix, override
+			}
+		}
+		if timeout <= 0 {
+			c.Next()
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+		defer cancel()
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) && !c.Writer.Written() {
+			c.JSON(http.StatusGatewayTimeout, gin.H{"error": "Request timed out"})
+		}
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"time"
 
+	"github.com/mahigadamsetty/Inshorts-task/internal/utils"
 	"gorm.io/gorm"
 )
 
@@ -27,28 +28,151 @@ func (a *StringArray) Scan(value interface{}) error {
 	return json.Unmarshal(bytes, a)
 }
 
+// Access values describe whether an article's full text could be fetched
+const (
+	AccessOpen        = "open"
+	AccessPaywalled   = "paywalled"
+	AccessConsentWall = "consent_wall"
+)
+
+// Summary statuses of the articles endpoints return
+const (
+	SummaryReady   = "ready"
+	SummaryPending = "pending" // Queued for the background summarizer
+)
+
+// Extraction strategies, in the order they are tried on a fetched page
+const (
+	ExtractionReadability     = "readability"
+	ExtractionMetaDescription = "meta_description" // og:description or the description meta tag
+	ExtractionParagraphs      = "paragraphs"       // Prose paragraphs outside navigation and boilerplate
+	ExtractionDescription     = "description"      // The article's own description, when nothing better was found
+)
+
+// Location sources describe where an article's coordinates came from
+const (
+	LocationImported = "imported" // Valid coordinates from the import file
+	LocationImputed  = "imputed"  // Geocoded from a place named in the title or description
+	LocationMissing  = "missing"  // No usable coordinates, so left out of geo endpoints
+)
+
 // Article represents a news article
 type Article struct {
-	ID              string      `gorm:"primaryKey" json:"id"`
-	Title           string      `gorm:"index" json:"title"`
-	Description     string      `json:"description"`
-	URL             string      `json:"url"`
-	PublicationDate time.Time   `gorm:"index" json:"publication_date"`
-	SourceName      string      `gorm:"index" json:"source_name"`
-	Category        StringArray `gorm:"type:text" json:"category"`
-	RelevanceScore  float64     `gorm:"index" json:"relevance_score"`
-	Latitude        float64     `json:"latitude"`
-	Longitude       float64     `json:"longitude"`
-	LLMSummary      string      `json:"llm_summary,omitempty"`
-	TrendingScore   float64     `gorm:"-" json:"trending_score,omitempty"` // Ignored by GORM, used for API response
-	CreatedAt       time.Time   `json:"-"`
-	UpdatedAt       time.Time   `json:"-"`
+	ID                 string            `gorm:"primaryKey" json:"id"`
+	Title              string            `gorm:"index" json:"title"`
+	Description        string            `json:"description"`
+	URL                string            `json:"url"`
+	CanonicalURL       *string           `gorm:"uniqueIndex:idx_articles_dedup,priority:2" json:"-"` // Normalized URL, nil when the URL is empty or invalid
+	TitleKey           string            `gorm:"uniqueIndex:idx_articles_dedup,priority:3" json:"-"` // Normalized title; many articles link the same page, e.g. a live blog
+	PublicationDate    time.Time         `gorm:"index" json:"publication_date"`
+	SourceName         string            `gorm:"index" json:"source_name"`
+	Category           StringArray       `gorm:"type:text" json:"category"`
+	RelevanceScore     float64           `gorm:"index" json:"relevance_score"`          // Original score from the import file
+	ComputedScore      float64           `gorm:"index" json:"computed_score,omitempty"` // Recalibrated from engagement, source and recency
+	ScoreComputedAt    *time.Time        `json:"-"`
+	Latitude           float64           `json:"latitude"`
+	Longitude          float64           `json:"longitude"`
+	LocationSource     string            `gorm:"index" json:"location_source,omitempty"` // Empty for articles imported before validation
+	LLMSummary         string            `json:"llm_summary,omitempty"`
+	SummaryStale       bool              `gorm:"index" json:"summary_stale,omitempty"` // The text changed since the summary was written
+	SummaryShort       string            `json:"-"`                                    // Cached short summary, cleared when the text changes
+	SummaryLong        string            `json:"-"`                                    // Cached long summary, cleared when the text changes
+	Access             string            `gorm:"index" json:"access,omitempty"`        // Empty until the URL has been fetched
+	ImageURL           string            `json:"image_url,omitempty"`                  // The page's og:image or twitter:image, stored at fetch
+	ImageBlobURL       string            `json:"-"`                                    // Stored copy of the image, see STORE_ARTICLE_IMAGES
+	TextContent        string            `gorm:"type:text" json:"-"`                   // Readable text of the URL, stored at ingest
+	TextBlobURL        string            `json:"-"`                                    // Blob the readable text is kept in instead, see STORE_ARTICLE_TEXT
+	FetchedAt          *time.Time        `gorm:"index" json:"-"`                       // Set once the URL has been fetched, even if it failed
+	Extraction         string            `gorm:"index" json:"-"`                       // Strategy that produced TextContent
+	ExtractionQuality  float64           `gorm:"index" json:"-"`                       // 0-1, low scores are retried with another strategy
+	ExtractionAttempts int               `json:"-"`
+	ContentHash        string            `json:"-"`                                     // SHA-256 of TextContent, compared on re-fetch
+	ContentRating      string            `gorm:"index" json:"content_rating,omitempty"` // Empty until moderated
+	SafetyTags         StringArray       `gorm:"type:text" json:"safety_tags,omitempty"`
+	StoryID            *uint             `gorm:"index" json:"story_id,omitempty"`
+	State              string            `gorm:"index" json:"state,omitempty"` // Lifecycle state, its story's when in one
+	StateChangedAt     *time.Time        `json:"state_changed_at,omitempty"`
+	TopicID            *uint             `gorm:"index" json:"topic_id,omitempty"`
+	TenantID           string            `gorm:"index;uniqueIndex:idx_articles_dedup,priority:1;not null;default:default" json:"-"`
+	VisibleFrom        *time.Time        `gorm:"index" json:"visible_from,omitempty"`  // Embargo: hidden from clients until then
+	ExpiresAt          *time.Time        `gorm:"index" json:"expires_at,omitempty"`    // Hidden from clients from then on
+	TrendingScore      float64           `gorm:"-" json:"trending_score,omitempty"`    // Ignored by GORM, used for API response
+	TrendingRank       int               `gorm:"-" json:"trending_rank,omitempty"`     // 1-based position in its cluster's trending list, only set by the trending endpoints
+	TrendingCluster    string            `gorm:"-" json:"trending_cluster,omitempty"`  // Location cluster the trending list was computed for
+	Explanation        *ScoreExplanation `gorm:"-" json:"score_explanation,omitempty"` // Only returned with ?explain=true
+	SourceMeta         *Source           `gorm:"-" json:"source_meta,omitempty"`
+	Views              *ArticleViews     `gorm:"-" json:"views,omitempty"`          // Only returned by /stats and the admin archive search
+	AudioURL           string            `gorm:"-" json:"audio_url,omitempty"`      // Spoken summary, once synthesized
+	SummaryStatus      string            `gorm:"-" json:"summary_status,omitempty"` // Whether llm_summary is ready or still being generated
+	CreatedAt          time.Time         `json:"-"`
+	UpdatedAt          time.Time         `json:"-"`
+
+	coordinates *utils.CoordinateLimit // How MarshalJSON gives out the coordinates, nil for as stored
+}
+
+// ScoreExplanation breaks down how the ranking framework scored an article.
+// Components that did not take part in the ranking are omitted.
+type ScoreExplanation struct {
+	Ranker       string             `json:"ranker"`
+	TextMatch    *float64           `json:"text_match,omitempty"`
+	Relevance    *float64           `json:"relevance,omitempty"`
+	Recency      *float64           `json:"recency_factor,omitempty"`
+	Reliability  *float64           `json:"source_reliability,omitempty"`
+	ReadMinutes  *float64           `json:"read_minutes,omitempty"`
+	DistanceKm   *float64           `json:"distance_km,omitempty"`
+	GeoRelevance *float64           `json:"geo_relevance,omitempty"`
+	Trending     *float64           `json:"trending,omitempty"`
+	Weights      map[string]float64 `json:"weights,omitempty"`
+	Final        float64            `json:"final"`
 }
 
 func (Article) TableName() string {
 	return "articles"
 }
 
+// HasLocation reports whether the article's coordinates can be used for geo
+// ranking: in range, not the 0,0 placeholder and not flagged missing
+func (a Article) HasLocation() bool {
+	return a.LocationSource != LocationMissing && ValidCoordinates(a.Latitude, a.Longitude)
+}
+
+// Visible reports whether clients may see the article at now: past its
+// embargo and not expired
+func (a Article) Visible(now time.Time) bool {
+	return (a.VisibleFrom == nil || !a.VisibleFrom.After(now)) && (a.ExpiresAt == nil || a.ExpiresAt.After(now))
+}
+
+// LimitCoordinates makes the article's JSON give out its coordinates as limit
+// says. Nil gives them as stored.
+func (a *Article) LimitCoordinates(limit *utils.CoordinateLimit) {
+	a.coordinates = limit
+}
+
+// MarshalJSON encodes the article with its coordinates limited, see
+// LimitCoordinates
+func (a Article) MarshalJSON() ([]byte, error) {
+	type article Article // The fields, without this method
+	if a.coordinates == nil || !a.coordinates.Limits() {
+		return json.Marshal(article(a))
+	}
+	latitude, longitude := a.coordinates.Apply(a.Latitude, a.Longitude)
+	if latitude == nil {
+		return json.Marshal(struct {
+			article
+			Latitude  *float64 `json:"latitude,omitempty"`
+			Longitude *float64 `json:"longitude,omitempty"`
+		}{article: article(a)})
+	}
+	a.Latitude, a.Longitude = *latitude, *longitude
+	return json.Marshal(article(a))
+}
+
+// ValidCoordinates reports whether a point is in range and not 0,0, which
+// importers write when they have no location
+func ValidCoordinates(lat, lon float64) bool {
+	return lat >= -90 && lat <= 90 && lon >= -180 && lon <= 180 && (lat != 0 || lon != 0)
+}
+
 // BeforeCreate hook to set timestamps
 func (a *Article) BeforeCreate(tx *gorm.DB) error {
 	now := time.Now()
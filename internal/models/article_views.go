@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// ArticleViews holds an article's interaction counters. Unique viewers are
+// estimated from a HyperLogLog sketch of hashed client identifiers, so no
+// identifier is ever stored.
+type ArticleViews struct {
+	ArticleID     string    `gorm:"primaryKey" json:"article_id"`
+	Views         int64     `json:"views"`
+	Clicks        int64     `json:"clicks"`
+	UniqueViewers int64     `gorm:"index" json:"unique_viewers"`
+	Sketch        []byte    `json:"-"`
+	TenantID      string    `gorm:"index;not null;default:default" json:"-"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+func (ArticleViews) TableName() string {
+	return "article_views"
+}
@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// ArticleAudio is the spoken version of an article's summary. Files are named
+// after a hash of the voice and the text, so articles with the same summary
+// share one.
+type ArticleAudio struct {
+	ArticleID       string `gorm:"primaryKey"`
+	File            string `gorm:"index"` // Name of the file in the audio directory
+	SummaryHash     string // Hash of the voice and summary it was synthesized from
+	Voice           string
+	Bytes           int64
+	DurationSeconds float64 // Estimated from the summary's length
+	UpdatedAt       time.Time
+}
+
+func (ArticleAudio) TableName() string {
+	return "article_audio"
+}
@@ -0,0 +1,17 @@
+package models
+
+import (
+	"time"
+)
+
+// CacheEntry is a value of the database cache store, shared by every replica
+type CacheEntry struct {
+	CacheKey  string `gorm:"primaryKey"`
+	Value     []byte `gorm:"type:blob"`
+	StoredAt  time.Time
+	ExpiresAt time.Time `gorm:"index"`
+}
+
+func (CacheEntry) TableName() string {
+	return "cache_entries"
+}
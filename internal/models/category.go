@@ -0,0 +1,27 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// Category is a node of the category hierarchy, like cricket under sports.
+// Categories are shared by every tenant. Articles keep the names they were
+// stored with; a category filter matches its own name and aliases and those
+// of its descendants.
+type Category struct {
+	Key       string      `gorm:"primaryKey" json:"-"` // Lowercased name
+	Name      string      `json:"name"`
+	Parent    string      `gorm:"index" json:"parent,omitempty"` // Key of the parent, empty for top-level categories
+	Aliases   StringArray `gorm:"type:text" json:"aliases"`      // Other names stored articles and imports use for it, lowercased
+	UpdatedAt time.Time   `json:"updated_at"`
+}
+
+func (Category) TableName() string {
+	return "categories"
+}
+
+// CategoryKey normalises a category name for lookups
+func CategoryKey(name string) string {
+	return strings.ToLower(strings.TrimSpace(name))
+}
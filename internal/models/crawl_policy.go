@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+// CrawlPolicy holds the politeness settings used when fetching a source's
+// pages. Zero values fall back to the crawler defaults.
+type CrawlPolicy struct {
+	SourceKey     string      `gorm:"primaryKey" json:"-"` // SourceKey of the source name, matched against articles.source_name
+	SourceName    string      `json:"source_name"`
+	UserAgent     string      `json:"user_agent,omitempty"`
+	CrawlDelayMs  int         `json:"crawl_delay_ms,omitempty"` // Minimum time between fetch starts; robots.txt Crawl-delay wins if longer
+	MaxConcurrent int         `json:"max_concurrent,omitempty"`
+	DisallowPaths StringArray `gorm:"type:text" json:"disallow_paths,omitempty"` // Path prefixes never fetched, on top of robots.txt
+	UpdatedAt     time.Time   `json:"updated_at"`
+}
+
+func (CrawlPolicy) TableName() string {
+	return "crawl_policies"
+}
@@ -0,0 +1,40 @@
+package models
+
+import (
+	"time"
+)
+
+// Data request kinds
+const (
+	DataRequestExport = "export"
+	DataRequestDelete = "delete"
+)
+
+// Data request statuses
+const (
+	DataRequestRunning     = "running"
+	DataRequestCompleted   = "completed"
+	DataRequestFailed      = "failed"
+	DataRequestInterrupted = "interrupted"
+)
+
+// DataRequest tracks an export or deletion of the data linked to an API key
+type DataRequest struct {
+	ID                 uint             `gorm:"primaryKey" json:"id"`
+	Kind               string           `json:"kind"`
+	Status             string           `gorm:"index" json:"status"`
+	Records            map[string]int64 `gorm:"serializer:json" json:"records,omitempty"` // Rows exported or deleted per kind of data
+	Error              string           `json:"error,omitempty"`
+	ExportPath         string           `json:"-"`
+	WebhookURL         string           `json:"webhook_url,omitempty"`
+	Secret             string           `json:"-"`
+	WebhookDeliveredAt *time.Time       `json:"webhook_delivered_at,omitempty"`
+	WebhookError       string           `json:"webhook_error,omitempty"`
+	TenantID           string           `gorm:"index;uniqueIndex:idx_data_requests_running,where:status = 'running';not null;default:default" json:"-"` // One running request per tenant
+	CreatedAt          time.Time        `json:"created_at"`
+	FinishedAt         *time.Time       `json:"finished_at,omitempty"`
+}
+
+func (DataRequest) TableName() string {
+	return "data_requests"
+}
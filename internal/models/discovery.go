@@ -0,0 +1,37 @@
+package models
+
+import "time"
+
+// DiscoveryArm is one article as an arm of the discovery feed's bandit: how
+// often the feed served it and how often that was clicked
+type DiscoveryArm struct {
+	ArticleID    string     `gorm:"primaryKey" json:"article_id"`
+	Pulls        int64      `json:"pulls"`
+	Clicks       int64      `json:"clicks"`
+	LastPulledAt *time.Time `json:"last_pulled_at,omitempty"`
+	TenantID     string     `gorm:"index;not null;default:default" json:"-"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+}
+
+func (DiscoveryArm) TableName() string {
+	return "discovery_arms"
+}
+
+// DiscoveryPull is an article the discovery feed served, kept to attribute
+// the viewer's click to it
+type DiscoveryPull struct {
+	ID        uint       `gorm:"primaryKey" json:"id"`
+	ArticleID string     `gorm:"index" json:"article_id"`
+	Endpoint  string     `json:"endpoint"`                                       // random or for-you
+	Position  int        `json:"position"`                                       // 1-based
+	Policy    string     `json:"policy"`                                         // thompson or epsilon_greedy
+	Choice    string     `json:"choice"`                                         // explore, exploit or sample, see the services package
+	Viewer    string     `gorm:"index:idx_discovery_viewer,priority:1" json:"-"` // Hashed client identifier, to attribute clicks
+	ClickedAt *time.Time `json:"clicked_at,omitempty"`                           // First click by the viewer within the attribution window
+	TenantID  string     `gorm:"index;not null;default:default" json:"-"`
+	CreatedAt time.Time  `gorm:"index;index:idx_discovery_viewer,priority:2" json:"created_at"`
+}
+
+func (DiscoveryPull) TableName() string {
+	return "discovery_pulls"
+}
@@ -1,8 +1,10 @@
 package models
 
 import (
+	"encoding/json"
 	"time"
 
+	"github.com/mahigadamsetty/Inshorts-task/internal/utils"
 	"gorm.io/gorm"
 )
 
@@ -14,7 +16,8 @@ const (
 	EventTypeClick EventType = "click"
 )
 
-// Event represents a simulated user interaction with an article
+// Event represents a user interaction with an article, reported by a client
+// or written by the event simulator
 type Event struct {
 	ID         uint      `gorm:"primaryKey" json:"id"`
 	ArticleID  string    `gorm:"index" json:"article_id"`
@@ -22,13 +25,45 @@ type Event struct {
 	Latitude   float64   `json:"latitude"`
 	Longitude  float64   `json:"longitude"`
 	Timestamp  time.Time `gorm:"index" json:"timestamp"`
+	Viewer     string    `gorm:"index" json:"-"` // Hashed anonymous client ID, empty when the client sent none
+	SessionID  *uint     `gorm:"index" json:"session_id,omitempty"` // Set once the event is stitched into a session
+	Platform   string    `gorm:"index;not null;default:unknown" json:"platform"` // Client platform, see the platform package
+	Simulated  bool      `gorm:"index;not null;default:false" json:"simulated"` // Written by the event simulator, not a client
+	TenantID   string    `gorm:"index;not null;default:default" json:"-"`
 	CreatedAt  time.Time `json:"-"`
+
+	coordinates *utils.CoordinateLimit // How MarshalJSON gives out the coordinates, nil for as stored
 }
 
 func (Event) TableName() string {
 	return "events"
 }
 
+// LimitCoordinates makes the event's JSON give out its coordinates as limit
+// says. Nil gives them as stored.
+func (e *Event) LimitCoordinates(limit *utils.CoordinateLimit) {
+	e.coordinates = limit
+}
+
+// MarshalJSON encodes the event with its coordinates limited, see
+// LimitCoordinates
+func (e Event) MarshalJSON() ([]byte, error) {
+	type event Event // The fields, without this method
+	if e.coordinates == nil || !e.coordinates.Limits() {
+		return json.Marshal(event(e))
+	}
+	latitude, longitude := e.coordinates.Apply(e.Latitude, e.Longitude)
+	if latitude == nil {
+		return json.Marshal(struct {
+			event
+			Latitude  *float64 `json:"latitude,omitempty"`
+			Longitude *float64 `json:"longitude,omitempty"`
+		}{event: event(e)})
+	}
+	e.Latitude, e.Longitude = *latitude, *longitude
+	return json.Marshal(event(e))
+}
+
 // BeforeCreate hook to set timestamps
 func (e *Event) BeforeCreate(tx *gorm.DB) error {
 	if e.Timestamp.IsZero() {
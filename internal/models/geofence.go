@@ -0,0 +1,110 @@
+package models
+
+import (
+	"time"
+
+	"github.com/mahigadamsetty/Inshorts-task/internal/utils"
+	"gorm.io/gorm"
+)
+
+// Geofence shapes
+const (
+	FenceCircle   = "circle"
+	FencePolygon  = "polygon"
+	FenceAnywhere = "anywhere" // Matches articles with or without a location, for Telegram category subscriptions
+)
+
+// Geofence is a client-registered area that triggers webhook alerts when
+// matching articles land inside it or reading activity there spikes. Fences
+// with a Telegram chat are subscriptions made through the bot, alerted in the
+// chat instead.
+type Geofence struct {
+	ID             uint         `gorm:"primaryKey" json:"id"`
+	Name           string       `json:"name"`
+	Shape          string       `json:"shape"`
+	Latitude       float64      `json:"latitude"` // Circle center, or the polygon's vertex mean
+	Longitude      float64      `json:"longitude"`
+	RadiusKm       float64      `json:"radius_km,omitempty"`
+	Polygon        [][2]float64 `gorm:"serializer:json" json:"polygon,omitempty"` // [lat, lon] vertices
+	Category       string       `json:"category,omitempty"`
+	Source         string       `json:"source,omitempty"`
+	Keywords       StringArray  `gorm:"type:text" json:"keywords,omitempty"` // Any one must appear in the title or description
+	MinReliability float64      `json:"min_reliability,omitempty"`
+	SpikeThreshold int          `json:"spike_threshold,omitempty"` // Fewest events in a window that count as a spike, 0 disables spike alerts
+	WebhookURL     string       `json:"webhook_url,omitempty"`
+	TelegramChatID int64        `gorm:"index" json:"telegram_chat_id,omitempty"`
+	Secret         string       `json:"-"`
+	CheckedAt      time.Time    `json:"-"` // Articles created after this have not been evaluated yet
+	TenantID       string       `gorm:"index;not null;default:default" json:"-"`
+	CreatedAt      time.Time    `json:"created_at"`
+	UpdatedAt      time.Time    `json:"-"`
+}
+
+func (Geofence) TableName() string {
+	return "geofences"
+}
+
+// BeforeCreate hook to set timestamps
+func (g *Geofence) BeforeCreate(tx *gorm.DB) error {
+	now := time.Now()
+	g.CreatedAt = now
+	g.UpdatedAt = now
+	return nil
+}
+
+// Contains reports whether a point lies inside the fence
+func (g *Geofence) Contains(lat, lon float64) bool {
+	switch g.Shape {
+	case FenceAnywhere:
+		return true
+	case FencePolygon:
+		return utils.PointInPolygon(lat, lon, g.Polygon)
+	}
+	return utils.HaversineDistance(g.Latitude, g.Longitude, lat, lon) <= g.RadiusKm
+}
+
+// Bounds returns a latitude and longitude box enclosing the fence
+func (g *Geofence) Bounds() (minLat, maxLat, minLon, maxLon float64) {
+	switch g.Shape {
+	case FenceAnywhere:
+		return -90, 90, -180, 180
+	case FencePolygon:
+		return utils.PolygonBounds(g.Polygon)
+	}
+	return utils.BoundingBox(g.Latitude, g.Longitude, g.RadiusKm)
+}
+
+// Alert kinds
+const (
+	AlertNewArticle    = "new_article"
+	AlertTrendingSpike = "trending_spike"
+)
+
+// Alert delivery statuses
+const (
+	AlertPending   = "pending"
+	AlertDelivered = "delivered"
+	AlertFailed    = "failed"
+)
+
+// GeofenceAlert is one notification raised by a geofence and its delivery state
+type GeofenceAlert struct {
+	ID          uint       `gorm:"primaryKey" json:"id"`
+	GeofenceID  uint       `gorm:"uniqueIndex:idx_geofence_alert_dedupe" json:"geofence_id"`
+	DedupeKey   string     `gorm:"uniqueIndex:idx_geofence_alert_dedupe" json:"-"` // Keeps one alert per article, or per spike window
+	Kind        string     `json:"kind"`
+	ArticleID   string     `json:"article_id,omitempty"` // For spikes, the most read article in the window
+	EventCount  int        `json:"event_count,omitempty"`
+	Baseline    float64    `json:"baseline,omitempty"` // Average events per window before the spike
+	Status      string     `gorm:"index" json:"status"`
+	Attempts    int        `json:"attempts"`
+	LastError   string     `json:"last_error,omitempty"`
+	DeliveredAt *time.Time `json:"delivered_at,omitempty"`
+	TenantID    string     `gorm:"index;not null;default:default" json:"-"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"-"`
+}
+
+func (GeofenceAlert) TableName() string {
+	return "geofence_alerts"
+}
@@ -0,0 +1,53 @@
+package models
+
+import "time"
+
+// Impression records an article returned in a list response, sampled, with
+// where it was shown and the ranking that put it there
+type Impression struct {
+	ID        uint       `gorm:"primaryKey" json:"id"`
+	ArticleID string     `gorm:"index" json:"article_id"`
+	Endpoint  string     `json:"endpoint"`
+	Position  int        `json:"position"`                                        // 1-based
+	Ranker    string     `json:"ranker,omitempty"`                                // Empty when the response carried no score explanation
+	Profile   string     `gorm:"index" json:"profile"`                            // Name of the live ranking weights
+	Viewer    string     `gorm:"index:idx_impression_viewer,priority:1" json:"-"` // Hashed client identifier, to attribute clicks
+	ClickedAt *time.Time `json:"clicked_at,omitempty"`                            // First click by the viewer within the attribution window
+	Platform  string     `gorm:"index;not null;default:unknown" json:"platform"`  // Client platform the article was shown on
+	TenantID  string     `gorm:"index;not null;default:default" json:"-"`
+	CreatedAt time.Time  `gorm:"index;index:idx_impression_viewer,priority:2" json:"created_at"`
+}
+
+func (Impression) TableName() string {
+	return "impressions"
+}
+
+// PositionBias is the click model's estimate of how likely articles shown at
+// a position are examined, relative to the most examined position. Estimated
+// over every tenant's impressions.
+type PositionBias struct {
+	Position    int       `gorm:"primaryKey" json:"position"`
+	Examination float64   `json:"examination"` // 0-1, 1 for the most examined position
+	Impressions int64     `json:"impressions"`
+	Clicks      int64     `json:"clicks"`
+	EstimatedAt time.Time `json:"estimated_at"`
+}
+
+func (PositionBias) TableName() string {
+	return "position_bias"
+}
+
+// ArticleAttractiveness is an article's click-through rate with position bias
+// removed: its clicks over the impressions it was expected to be examined in
+type ArticleAttractiveness struct {
+	ArticleID    string    `gorm:"primaryKey" json:"article_id"`
+	Score        float64   `json:"score"`        // Clicks per expected examination
+	Examinations float64   `json:"examinations"` // Impressions weighted by the examination of their position
+	Clicks       int64     `json:"clicks"`
+	TenantID     string    `gorm:"index;not null;default:default" json:"-"`
+	EstimatedAt  time.Time `json:"estimated_at"`
+}
+
+func (ArticleAttractiveness) TableName() string {
+	return "article_attractiveness"
+}
@@ -0,0 +1,40 @@
+package models
+
+import "time"
+
+// Ingestion source kinds
+const (
+	IngestionRSS     = "rss"     // An RSS 2.0 or Atom feed
+	IngestionNewsAPI = "newsapi" // A NewsAPI /v2/everything query
+	IngestionCrawl   = "crawl"   // A list of article pages, read for their title and description
+)
+
+// IngestionSource is a feed, query or crawl list the ingestion job pulls new
+// articles from on its own schedule
+type IngestionSource struct {
+	ID         uint        `gorm:"primaryKey" json:"id"`
+	Name       string      `gorm:"uniqueIndex" json:"name"`
+	Kind       string      `json:"kind"`
+	URL        string      `json:"url,omitempty"`                   // Feed URL of rss sources
+	Query      string      `json:"query,omitempty"`                 // Search query of newsapi sources
+	URLs       StringArray `gorm:"type:text" json:"urls,omitempty"` // Pages of crawl sources
+	SourceName string      `json:"source_name,omitempty"`           // Stored as the articles' source; newsapi sources default to each article's
+	TenantID   string      `gorm:"not null;default:default" json:"tenant"`
+	Category   StringArray `gorm:"type:text" json:"category"` // Given to every ingested article
+	Schedule   string      `json:"schedule"`                  // Cron expression, descriptor or @every interval
+	Enabled    bool        `gorm:"index" json:"enabled"`
+
+	LastRunAt    *time.Time `json:"last_run_at,omitempty"`
+	LastStatus   string     `json:"last_status,omitempty"` // JobStatusSuccess or JobStatusFailed
+	LastError    string     `json:"last_error,omitempty"`
+	LastIngested int        `json:"last_ingested"`         // New articles stored by the last run
+	LastSkipped  int        `json:"last_skipped"`          // Items of the last run already stored or invalid
+	NextRunAt    *time.Time `json:"next_run_at,omitempty"` // Nil while disabled
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func (IngestionSource) TableName() string {
+	return "ingestion_sources"
+}
@@ -0,0 +1,41 @@
+package models
+
+import (
+	"time"
+)
+
+// Job run statuses
+const (
+	JobStatusRunning = "running"
+	JobStatusSuccess = "success"
+	JobStatusFailed  = "failed"
+)
+
+// JobLock is a lease held by the replica currently running a scheduled job,
+// and the job's next activation across replicas
+type JobLock struct {
+	Name      string     `gorm:"primaryKey" json:"name"`
+	Owner     string     `json:"owner"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	NextRunAt *time.Time `json:"next_run_at,omitempty"` // Activations before it already ran on some replica
+}
+
+func (JobLock) TableName() string {
+	return "job_locks"
+}
+
+// JobRun records one execution of a scheduled job
+type JobRun struct {
+	ID         uint       `gorm:"primaryKey" json:"id"`
+	JobName    string     `gorm:"index" json:"job_name"`
+	Owner      string     `json:"owner"`
+	Status     string     `json:"status"`
+	Error      string     `json:"error,omitempty"`
+	StartedAt  time.Time  `gorm:"index" json:"started_at"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+	DurationMs int64      `json:"duration_ms"`
+}
+
+func (JobRun) TableName() string {
+	return "job_runs"
+}
@@ -0,0 +1,49 @@
+package models
+
+import (
+	"time"
+)
+
+// Outbox channels
+const (
+	ChannelWebhook  = "webhook"
+	ChannelTelegram = "telegram"
+)
+
+// Outbox message statuses
+const (
+	OutboxPending   = "pending"
+	OutboxDelivered = "delivered"
+	OutboxDead      = "dead" // Out of attempts, kept until retried or discarded
+)
+
+// Outbox subjects, the records a message notifies of
+const (
+	SubjectGeofenceAlert = "geofence_alert"
+	SubjectDataRequest   = "data_request"
+)
+
+// OutboxMessage is a notification written in the same transaction as the
+// change it announces, and delivered from there by the outbox dispatcher
+type OutboxMessage struct {
+	ID            uint              `gorm:"primaryKey" json:"id"`
+	Channel       string            `gorm:"index" json:"channel"`
+	Subject       string            `gorm:"index:idx_outbox_subject" json:"subject"`
+	SubjectID     uint              `gorm:"index:idx_outbox_subject" json:"subject_id"`
+	Target        string            `json:"target"` // Webhook URL, or Telegram chat ID
+	Headers       map[string]string `gorm:"serializer:json" json:"headers,omitempty"`
+	Body          string            `gorm:"type:text" json:"body"`
+	Secret        string            `json:"-"` // Signs webhook deliveries when set
+	Status        string            `gorm:"index:idx_outbox_due" json:"status"`
+	Attempts      int               `json:"attempts"`
+	NextAttemptAt time.Time         `gorm:"index:idx_outbox_due" json:"next_attempt_at"`
+	LastError     string            `json:"last_error,omitempty"`
+	DeliveredAt   *time.Time        `json:"delivered_at,omitempty"`
+	TenantID      string            `gorm:"index;not null;default:default" json:"tenant_id"`
+	CreatedAt     time.Time         `json:"created_at"`
+	UpdatedAt     time.Time         `json:"updated_at"`
+}
+
+func (OutboxMessage) TableName() string {
+	return "outbox_messages"
+}
@@ -0,0 +1,32 @@
+package models
+
+import "time"
+
+// ArticlePopularity is an article's decayed popularity: each unique viewer
+// counts in full when first seen and halves in weight every popularity
+// half-life afterwards, so past spikes fade
+type ArticlePopularity struct {
+	ArticleID string    `gorm:"primaryKey"`
+	Score     float64   // Decayed unique viewers as of DecayedAt
+	Viewers   int64     // Unique viewers already folded into Score
+	TenantID  string    `gorm:"index;not null;default:default"`
+	DecayedAt time.Time // When Score was last decayed
+}
+
+func (ArticlePopularity) TableName() string {
+	return "article_popularity"
+}
+
+// ScoreHistory is a nightly sample of an article's scores, for charting
+type ScoreHistory struct {
+	ID            uint      `gorm:"primaryKey" json:"-"`
+	ArticleID     string    `gorm:"index:idx_score_history_article,priority:1" json:"-"`
+	ComputedScore float64   `json:"computed_score"`
+	Popularity    float64   `json:"popularity"`
+	TenantID      string    `gorm:"index;not null;default:default" json:"-"`
+	RecordedAt    time.Time `gorm:"index;index:idx_score_history_article,priority:2" json:"recorded_at"`
+}
+
+func (ScoreHistory) TableName() string {
+	return "score_history"
+}
@@ -0,0 +1,25 @@
+package models
+
+import (
+	"time"
+)
+
+// RecordedRequest is a sampled API request and the response it got, kept so
+// `newsd replay` can re-issue it against another build and diff the outputs
+type RecordedRequest struct {
+	ID          uint              `gorm:"primaryKey" json:"id"`
+	Method      string            `json:"method"`
+	Path        string            `gorm:"index" json:"path"`
+	Query       string            `json:"query,omitempty"`                          // Raw query string, coordinates truncated as in request logs
+	Headers     map[string]string `gorm:"serializer:json" json:"headers,omitempty"` // Without credentials or cookies
+	Status      int               `json:"status"`
+	ContentType string            `json:"content_type"`
+	Body        string            `gorm:"type:text" json:"body"`
+	DurationMs  int64             `json:"duration_ms"`
+	TenantID    string            `gorm:"index;not null;default:default" json:"tenant_id"`
+	CreatedAt   time.Time         `gorm:"index" json:"created_at"`
+}
+
+func (RecordedRequest) TableName() string {
+	return "recorded_requests"
+}
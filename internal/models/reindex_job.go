@@ -0,0 +1,30 @@
+package models
+
+import (
+	"time"
+)
+
+// Reindex job statuses
+const (
+	ReindexJobRunning     = "running"
+	ReindexJobCompleted   = "completed"
+	ReindexJobFailed      = "failed"
+	ReindexJobInterrupted = "interrupted"
+)
+
+// ReindexJob tracks a rebuild of the article embedding index. The new index is
+// built in a shadow table and swapped in once complete.
+type ReindexJob struct {
+	ID         uint       `gorm:"primaryKey" json:"id"`
+	Status     string     `gorm:"index" json:"status"`
+	Model      string     `json:"model"` // Embedding model the index is rebuilt with
+	Total      int        `json:"total"`
+	Processed  int        `json:"processed"`
+	Error      string     `json:"error,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+}
+
+func (ReindexJob) TableName() string {
+	return "reindex_jobs"
+}
@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+// SearchLog records one free-text query made against the API, kept for
+// offline analytics
+type SearchLog struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	Query       string    `json:"query"`
+	Endpoint    string    `gorm:"index" json:"endpoint"` // search or query
+	ResultCount int       `json:"result_count"`
+	Platform    string    `gorm:"index;not null;default:unknown" json:"platform"`
+	TenantID    string    `gorm:"index;not null;default:default" json:"-"`
+	CreatedAt   time.Time `gorm:"index" json:"created_at"`
+}
+
+func (SearchLog) TableName() string {
+	return "search_logs"
+}
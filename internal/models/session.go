@@ -0,0 +1,38 @@
+package models
+
+import "time"
+
+// Read is a successful news request from a client that sent an anonymous ID,
+// so sessions include browsing that led to no event
+type Read struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	Viewer    string    `gorm:"index" json:"-"` // Hashed anonymous client ID
+	Endpoint  string    `json:"endpoint"`       // Route below /api/v1/news, like category
+	SessionID *uint     `gorm:"index" json:"session_id,omitempty"`
+	TenantID  string    `gorm:"index;not null;default:default" json:"-"`
+	CreatedAt time.Time `gorm:"index" json:"created_at"`
+}
+
+func (Read) TableName() string {
+	return "reads"
+}
+
+// Session is a run of one anonymous client's events and reads with no gap
+// longer than the inactivity window between them
+type Session struct {
+	ID             uint      `gorm:"primaryKey" json:"id"`
+	Viewer         string    `gorm:"index" json:"-"`
+	StartedAt      time.Time `gorm:"index" json:"started_at"`
+	LastActivityAt time.Time `json:"last_activity_at"`
+	Events         int       `json:"events"`
+	Views          int       `json:"views"`
+	Clicks         int       `json:"clicks"`
+	Reads          int       `json:"reads"`
+	Articles       int       `json:"articles"` // Distinct articles viewed or clicked
+	TenantID       string    `gorm:"index;not null;default:default" json:"-"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+func (Session) TableName() string {
+	return "sessions"
+}
@@ -0,0 +1,39 @@
+package models
+
+import "time"
+
+// ShadowComparison records the orderings of one sampled request: the live
+// ranking that was served and the shadow candidate's ranking of the same
+// articles, which was not
+type ShadowComparison struct {
+	ID        uint        `gorm:"primaryKey" json:"id"`
+	Candidate string      `gorm:"index" json:"candidate"`
+	Endpoint  string      `json:"endpoint"` // category, source or search
+	Query     string      `json:"query"`
+	Viewer    string      `gorm:"index:idx_shadow_viewer,priority:1" json:"-"` // Hashed client identifier, to attribute clicks
+	Live      StringArray `gorm:"type:text" json:"live"`                       // Article IDs in served order
+	Shadow    StringArray `gorm:"type:text" json:"shadow"`                     // Article IDs in the candidate's order
+	Platform  string      `gorm:"index;not null;default:unknown" json:"platform"`
+	TenantID  string      `gorm:"index;not null;default:default" json:"-"`
+	CreatedAt time.Time   `gorm:"index;index:idx_shadow_viewer,priority:2" json:"created_at"`
+}
+
+func (ShadowComparison) TableName() string {
+	return "shadow_comparisons"
+}
+
+// ShadowClick is a click on an article of a shadow comparison, with the
+// article's position in either ordering
+type ShadowClick struct {
+	ID             uint      `gorm:"primaryKey" json:"-"`
+	ComparisonID   uint      `gorm:"index" json:"comparison_id"`
+	ArticleID      string    `json:"article_id"`
+	LivePosition   int       `json:"live_position"`   // 1-based
+	ShadowPosition int       `json:"shadow_position"` // 1-based, 0 when the candidate did not return the article
+	TenantID       string    `gorm:"index;not null;default:default" json:"-"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+func (ShadowClick) TableName() string {
+	return "shadow_clicks"
+}
@@ -0,0 +1,35 @@
+package models
+
+import "time"
+
+// ShortLink is a short code redirecting to an article, created to track the
+// shares of a channel such as a push or email digest
+type ShortLink struct {
+	Code           string     `gorm:"primaryKey" json:"code"`
+	ArticleID      string     `gorm:"index" json:"article_id"`
+	Channel        string     `gorm:"index" json:"channel,omitempty"` // e.g. push, email
+	Campaign       string     `gorm:"index" json:"campaign,omitempty"`
+	Clicks         int64      `json:"clicks"`
+	UniqueVisitors int64      `json:"unique_visitors"` // Estimated from Sketch
+	Sketch         []byte     `json:"-"`               // HyperLogLog sketch of hashed visitors
+	LastClickedAt  *time.Time `json:"last_clicked_at,omitempty"`
+	TenantID       string     `gorm:"index;not null;default:default" json:"-"`
+	CreatedAt      time.Time  `json:"created_at"`
+}
+
+func (ShortLink) TableName() string {
+	return "short_links"
+}
+
+// ShortLinkClicks counts a short link's clicks on one UTC day
+type ShortLinkClicks struct {
+	Code      string `gorm:"primaryKey" json:"code"`
+	Day       string `gorm:"primaryKey" json:"date"` // YYYY-MM-DD
+	Clicks    int64  `json:"clicks"`
+	ArticleID string `gorm:"index" json:"-"` // The link's, so purges find the counts
+	TenantID  string `gorm:"index;not null;default:default" json:"-"`
+}
+
+func (ShortLinkClicks) TableName() string {
+	return "short_link_clicks"
+}
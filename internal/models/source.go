@@ -0,0 +1,33 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// Reliability tiers for news sources
+const (
+	TierHigh          = "high"
+	TierMedium        = "medium"
+	TierLow           = "low"
+	TierUserGenerated = "user_generated"
+)
+
+// Source holds editorial metadata about a news source
+type Source struct {
+	Key             string    `gorm:"primaryKey" json:"-"` // Lowercased source name, matched against articles.source_name
+	Name            string    `json:"name"`
+	ReliabilityTier string    `gorm:"index" json:"reliability_tier"`
+	Reliability     float64   `gorm:"index" json:"reliability"` // 0-1
+	Bias            string    `json:"bias"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
+
+func (Source) TableName() string {
+	return "sources"
+}
+
+// SourceKey normalises a source name for lookups
+func SourceKey(name string) string {
+	return strings.ToLower(strings.TrimSpace(name))
+}
@@ -0,0 +1,72 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/mahigadamsetty/Inshorts-task/internal/utils"
+	"gorm.io/gorm"
+)
+
+// Lifecycle states of stories and articles, from event velocity and recency
+const (
+	StateBreaking   = "breaking"
+	StateDeveloping = "developing"
+	StateStale      = "stale"
+)
+
+// Story groups articles that report on the same real-world event
+type Story struct {
+	ID             uint       `gorm:"primaryKey" json:"id"`
+	Title          string     `json:"title"`
+	Summary        string     `json:"summary,omitempty"`
+	ArticleCount   int        `gorm:"index" json:"article_count"`
+	FirstPublished time.Time  `json:"first_published"`
+	LastPublished  time.Time  `gorm:"index" json:"last_published"`
+	State          string     `gorm:"index" json:"state,omitempty"` // Empty until classified
+	StateChangedAt *time.Time `json:"state_changed_at,omitempty"`
+	Latitude       float64    `json:"latitude"`
+	Longitude      float64    `json:"longitude"`
+	TenantID       string     `gorm:"index;not null;default:default" json:"-"`
+	CreatedAt      time.Time  `json:"-"`
+	UpdatedAt      time.Time  `json:"-"`
+
+	coordinates *utils.CoordinateLimit // How MarshalJSON gives out the coordinates, nil for as stored
+}
+
+func (Story) TableName() string {
+	return "stories"
+}
+
+// LimitCoordinates makes the story's JSON give out its coordinates as limit
+// says. Nil gives them as stored.
+func (s *Story) LimitCoordinates(limit *utils.CoordinateLimit) {
+	s.coordinates = limit
+}
+
+// MarshalJSON encodes the story with its coordinates limited, see
+// LimitCoordinates
+func (s Story) MarshalJSON() ([]byte, error) {
+	type story Story // The fields, without this method
+	if s.coordinates == nil || !s.coordinates.Limits() {
+		return json.Marshal(story(s))
+	}
+	latitude, longitude := s.coordinates.Apply(s.Latitude, s.Longitude)
+	if latitude == nil {
+		return json.Marshal(struct {
+			story
+			Latitude  *float64 `json:"latitude,omitempty"`
+			Longitude *float64 `json:"longitude,omitempty"`
+		}{story: story(s)})
+	}
+	s.Latitude, s.Longitude = *latitude, *longitude
+	return json.Marshal(story(s))
+}
+
+// BeforeCreate hook to set timestamps
+func (s *Story) BeforeCreate(tx *gorm.DB) error {
+	now := time.Now()
+	s.CreatedAt = now
+	s.UpdatedAt = now
+	return nil
+}
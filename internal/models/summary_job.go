@@ -0,0 +1,31 @@
+package models
+
+import (
+	"time"
+)
+
+// Summary job statuses
+const (
+	SummaryJobQueued      = "queued"
+	SummaryJobRunning     = "running"
+	SummaryJobCompleted   = "completed"
+	SummaryJobInterrupted = "interrupted"
+)
+
+// SummaryJob tracks a bulk request to regenerate article summaries
+type SummaryJob struct {
+	ID         uint       `gorm:"primaryKey" json:"id"`
+	Status     string     `gorm:"index" json:"status"`
+	Priority   string     `json:"priority"`
+	Total      int        `json:"total"`
+	Succeeded  int        `json:"succeeded"`
+	Failed     int        `json:"failed"`
+	Failures   []string   `gorm:"serializer:json" json:"failures,omitempty"` // "<article id>: <error>" per failed article
+	CreatedAt  time.Time  `json:"created_at"`
+	StartedAt  *time.Time `json:"started_at,omitempty"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+}
+
+func (SummaryJob) TableName() string {
+	return "summary_jobs"
+}
@@ -0,0 +1,45 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Topic is an automatically discovered group of articles on a common subject,
+// built by clustering article embeddings
+type Topic struct {
+	ID            uint        `gorm:"primaryKey" json:"id"`
+	Label         string      `json:"label"`
+	Keywords      StringArray `gorm:"type:text" json:"keywords"`
+	ArticleCount  int         `gorm:"index" json:"article_count"`
+	LastPublished time.Time   `gorm:"index" json:"last_published"`
+	TenantID      string      `gorm:"index;not null;default:default" json:"-"`
+	CreatedAt     time.Time   `json:"-"`
+	UpdatedAt     time.Time   `json:"-"`
+}
+
+func (Topic) TableName() string {
+	return "topics"
+}
+
+// BeforeCreate hook to set timestamps
+func (t *Topic) BeforeCreate(tx *gorm.DB) error {
+	now := time.Now()
+	t.CreatedAt = now
+	t.UpdatedAt = now
+	return nil
+}
+
+// ArticleEmbedding stores the vector used to cluster an article into topics
+type ArticleEmbedding struct {
+	ArticleID   string    `gorm:"primaryKey"`
+	Model       string    `gorm:"index"`
+	Vector      []float32 `gorm:"serializer:json"`
+	ContentHash string    // The article's content hash when it was embedded
+	UpdatedAt   time.Time
+}
+
+func (ArticleEmbedding) TableName() string {
+	return "article_embeddings"
+}
@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// APIUsage is what one tenant's API key consumed in a UTC month
+type APIUsage struct {
+	TenantID  string    `gorm:"primaryKey" json:"tenant"`
+	Month     string    `gorm:"primaryKey" json:"month"` // YYYY-MM
+	Requests  int64     `json:"requests"`
+	LLMTokens int64     `json:"llm_tokens"`
+	Events    int64     `json:"events"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func (APIUsage) TableName() string {
+	return "api_usage"
+}
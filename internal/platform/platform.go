@@ -0,0 +1,118 @@
+// Package platform identifies the kind of client a request comes from — one
+// of the apps, a mobile or desktop browser, or a bot — so events, logs and
+// ranking experiments can be broken down by it.
+package platform
+
+import (
+	"context"
+	"net/http"
+	"strings"
+)
+
+// Platforms requests are attributed to
+const (
+	IOS        = "ios"
+	Android    = "android"
+	MobileWeb  = "mobile_web"
+	DesktopWeb = "desktop_web"
+	Bot        = "bot"
+	Unknown    = "unknown"
+)
+
+// All lists the platforms
+var All = []string{IOS, Android, MobileWeb, DesktopWeb, Bot, Unknown}
+
+// Header is the request header clients declare their platform in
+const Header = "X-Client-Platform"
+
+// declared maps the values clients send in Header to platforms. "web" is
+// told apart into mobile and desktop by the user agent.
+var declared = map[string]string{
+	"ios":         IOS,
+	"iphone":      IOS,
+	"ipad":        IOS,
+	"ipados":      IOS,
+	"android":     Android,
+	"mobile_web":  MobileWeb,
+	"mobile-web":  MobileWeb,
+	"mweb":        MobileWeb,
+	"desktop_web": DesktopWeb,
+	"desktop-web": DesktopWeb,
+	"desktop":     DesktopWeb,
+	"bot":         Bot,
+}
+
+// botAgents are user agent fragments of crawlers, link unfurlers and scripts
+var botAgents = []string{"bot", "crawler", "spider", "slurp", "facebookexternalhit", "embedly", "curl/", "wget/", "python-requests", "go-http-client"}
+
+// appAgents are user agent fragments of the HTTP stacks native apps send
+// with, for apps that don't declare their platform
+var appAgents = map[string]string{"cfnetwork": IOS, "okhttp": Android, "dalvik": Android}
+
+// Valid reports whether a value is a known platform
+func Valid(value string) bool {
+	for _, p := range All {
+		if value == p {
+			return true
+		}
+	}
+	return false
+}
+
+// Detect returns the platform of a request: the one it declares in Header,
+// or else the one its user agent and the Sec-CH-UA-Mobile client hint point
+// to
+func Detect(r *http.Request) string {
+	userAgent := strings.ToLower(r.UserAgent())
+	mobileHint := r.Header.Get("Sec-CH-UA-Mobile") == "?1"
+	value := strings.ToLower(strings.TrimSpace(r.Header.Get(Header)))
+	if value == "web" {
+		return web(userAgent, mobileHint)
+	}
+	if p, ok := declared[value]; ok {
+		return p
+	}
+
+	if userAgent == "" {
+		return Unknown
+	}
+	for _, agent := range botAgents {
+		if strings.Contains(userAgent, agent) {
+			return Bot
+		}
+	}
+	if !strings.Contains(userAgent, "mozilla") {
+		for agent, p := range appAgents {
+			if strings.Contains(userAgent, agent) {
+				return p
+			}
+		}
+		return Unknown
+	}
+	return web(userAgent, mobileHint)
+}
+
+// web tells a mobile browser from a desktop one
+func web(userAgent string, mobileHint bool) string {
+	if mobileHint || strings.Contains(userAgent, "mobi") || strings.Contains(userAgent, "android") {
+		return MobileWeb
+	}
+	return DesktopWeb
+}
+
+type contextKey struct{}
+
+// NewContext returns a context carrying the platform of a request
+func NewContext(ctx context.Context, p string) context.Context {
+	return context.WithValue(ctx, contextKey{}, p)
+}
+
+// FromContext returns the platform stored in the context, or Unknown
+func FromContext(ctx context.Context) string {
+	if ctx != nil {
+		if p, ok := ctx.Value(contextKey{}).(string); ok && p != "" {
+			return p
+		}
+	}
+	return Unknown
+}
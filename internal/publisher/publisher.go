@@ -0,0 +1,119 @@
+// Package publisher resolves the API keys partner publishers push their own
+// articles with. Each key is bound to one source of one tenant.
+package publisher
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mahigadamsetty/Inshorts-task/internal/tenant"
+)
+
+// Publisher is a partner allowed to create, update and withdraw the articles
+// of its source
+type Publisher struct {
+	ID                 string `json:"id"`
+	Source             string `json:"source"` // Source name its articles are stored under
+	TenantID           string `json:"tenant"` // Default tenant when empty
+	APIKey             string `json:"api_key"`
+	RateLimitPerMinute int    `json:"rate_limit_per_minute"` // 0 means unlimited
+
+	mu          sync.Mutex
+	windowStart time.Time
+	windowCount int
+}
+
+// Registry resolves API keys to publishers
+type Registry struct {
+	byKey map[string]*Publisher
+}
+
+type contextKey struct{}
+
+// LoadRegistry reads publisher definitions from a JSON file. Every publisher's
+// tenant must exist in tenants, and keys may not be tenant keys too. An empty
+// path yields a registry without publishers.
+func LoadRegistry(path string, tenants *tenant.Registry) (*Registry, error) {
+	registry := &Registry{byKey: make(map[string]*Publisher)}
+	if path == "" {
+		return registry, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read publishers file: %w", err)
+	}
+
+	var publishers []*Publisher
+	if err := json.Unmarshal(data, &publishers); err != nil {
+		return nil, fmt.Errorf("failed to parse publishers file: %w", err)
+	}
+
+	for _, p := range publishers {
+		p.Source = strings.TrimSpace(p.Source)
+		if p.ID == "" || p.Source == "" || p.APIKey == "" {
+			return nil, fmt.Errorf("publisher entries require id, source and api_key")
+		}
+		if p.TenantID == "" {
+			p.TenantID = tenant.DefaultID
+		}
+		if _, ok := tenants.Get(p.TenantID); !ok {
+			return nil, fmt.Errorf("publisher %s belongs to unknown tenant %s", p.ID, p.TenantID)
+		}
+		if _, exists := registry.byKey[p.APIKey]; exists {
+			return nil, fmt.Errorf("duplicate api_key for publisher %s", p.ID)
+		}
+		if _, exists := tenants.Lookup(p.APIKey); exists {
+			return nil, fmt.Errorf("api_key of publisher %s is a tenant key", p.ID)
+		}
+		registry.byKey[p.APIKey] = p
+	}
+
+	return registry, nil
+}
+
+// Lookup returns the publisher owning an API key
+func (r *Registry) Lookup(apiKey string) (*Publisher, bool) {
+	p, ok := r.byKey[apiKey]
+	return p, ok
+}
+
+// AllowRequest counts a request against the publisher's per-minute rate limit
+func (p *Publisher) AllowRequest() bool {
+	if p.RateLimitPerMinute <= 0 {
+		return true
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(p.windowStart) >= time.Minute {
+		p.windowStart = now
+		p.windowCount = 0
+	}
+	if p.windowCount >= p.RateLimitPerMinute {
+		return false
+	}
+	p.windowCount++
+	return true
+}
+
+// NewContext returns a context carrying the publisher
+func NewContext(ctx context.Context, p *Publisher) context.Context {
+	return context.WithValue(ctx, contextKey{}, p)
+}
+
+// FromContext returns the publisher stored in the context, if any
+func FromContext(ctx context.Context) (*Publisher, bool) {
+	if ctx == nil {
+		return nil, false
+	}
+	p, ok := ctx.Value(contextKey{}).(*Publisher)
+	return p, ok && p != nil
+}
@@ -0,0 +1,172 @@
+// Package replay re-issues recorded requests against a server, usually a new
+// build, and compares its JSON responses with the recorded ones, so ranking
+// and handler refactors can be checked against real traffic.
+package replay
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/mahigadamsetty/Inshorts-task/internal/models"
+)
+
+// maxBodyBytes bounds the replayed responses read
+const maxBodyBytes = 16 << 20
+
+// Options configure a replay
+type Options struct {
+	Target   string            // Base URL of the server to replay against, e.g. http://localhost:8081
+	Keys     map[string]string // API key to send per tenant ID; requests of other tenants go without one
+	Ignore   map[string]bool   // JSON keys left out of the comparison wherever they appear, e.g. session_id
+	MaxDiffs int               // Differences reported per request, 0 for all
+	Client   *http.Client
+}
+
+// Result is the outcome of replaying one request
+type Result struct {
+	Recording models.RecordedRequest
+	Status    int      // Status of the replayed response
+	Diffs     []string // Differences from the recorded response, empty when they match
+	Err       error    // Set when the request could not be replayed
+}
+
+// Replay re-issues a recorded request against the target and compares the
+// response with the recorded one
+func Replay(ctx context.Context, recording models.RecordedRequest, options Options) Result {
+	result := Result{Recording: recording}
+	target := strings.TrimRight(options.Target, "/") + recording.Path
+	if recording.Query != "" {
+		target += "?" + recording.Query
+	}
+	req, err := http.NewRequestWithContext(ctx, recording.Method, target, nil)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	for name, value := range recording.Headers {
+		req.Header.Set(name, value)
+	}
+	if key := options.Keys[recording.TenantID]; key != "" {
+		req.Header.Set("X-API-Key", key)
+	}
+
+	client := options.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxBodyBytes))
+	if err != nil {
+		result.Err = err
+		return result
+	}
+
+	result.Status = resp.StatusCode
+	if resp.StatusCode != recording.Status {
+		result.Diffs = append(result.Diffs, fmt.Sprintf("status: %d != %d", recording.Status, resp.StatusCode))
+	}
+	result.Diffs = append(result.Diffs, Diff([]byte(recording.Body), body, options.Ignore)...)
+	if options.MaxDiffs > 0 && len(result.Diffs) > options.MaxDiffs {
+		more := len(result.Diffs) - options.MaxDiffs
+		result.Diffs = append(result.Diffs[:options.MaxDiffs], fmt.Sprintf("... and %d more", more))
+	}
+	return result
+}
+
+// Diff compares two JSON documents and describes each difference with its
+// path, like "$.articles[0].id: "a" != "b"". Keys in ignore are skipped at any
+// depth. Bodies that aren't JSON are compared byte for byte.
+func Diff(recorded, replayed []byte, ignore map[string]bool) []string {
+	var want, got interface{}
+	if decode(recorded, &want) != nil || decode(replayed, &got) != nil {
+		if bytes.Equal(recorded, replayed) {
+			return nil
+		}
+		return []string{"$: bodies differ"}
+	}
+	var diffs []string
+	compare("$", want, got, ignore, &diffs)
+	return diffs
+}
+
+// decode parses JSON keeping numbers as written, so 1 and 1.0 differ like
+// their encodings do
+func decode(data []byte, v interface{}) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	return decoder.Decode(v)
+}
+
+func compare(path string, want, got interface{}, ignore map[string]bool, diffs *[]string) {
+	switch w := want.(type) {
+	case map[string]interface{}:
+		g, ok := got.(map[string]interface{})
+		if !ok {
+			break
+		}
+		keys := make([]string, 0, len(w)+len(g))
+		for key := range w {
+			keys = append(keys, key)
+		}
+		for key := range g {
+			if _, seen := w[key]; !seen {
+				keys = append(keys, key)
+			}
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			if ignore[key] {
+				continue
+			}
+			wv, inWant := w[key]
+			gv, inGot := g[key]
+			switch {
+			case !inGot:
+				*diffs = append(*diffs, fmt.Sprintf("%s.%s: missing, was %s", path, key, show(wv)))
+			case !inWant:
+				*diffs = append(*diffs, fmt.Sprintf("%s.%s: added %s", path, key, show(gv)))
+			default:
+				compare(path+"."+key, wv, gv, ignore, diffs)
+			}
+		}
+		return
+	case []interface{}:
+		g, ok := got.([]interface{})
+		if !ok {
+			break
+		}
+		for i := 0; i < len(w) && i < len(g); i++ {
+			compare(fmt.Sprintf("%s[%d]", path, i), w[i], g[i], ignore, diffs)
+		}
+		if len(w) != len(g) {
+			*diffs = append(*diffs, fmt.Sprintf("%s: length %d != %d", path, len(w), len(g)))
+		}
+		return
+	default:
+		if reflect.DeepEqual(want, got) {
+			return
+		}
+	}
+	*diffs = append(*diffs, fmt.Sprintf("%s: %s != %s", path, show(want), show(got)))
+}
+
+// show renders a value for a difference, shortening long ones
+func show(v interface{}) string {
+	data, _ := json.Marshal(v)
+	if len(data) > 80 {
+		return string(data[:77]) + "..."
+	}
+	return string(data)
+}
@@ -5,36 +5,227 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/mahigadamsetty/Inshorts-task/internal/config"
 	"github.com/mahigadamsetty/Inshorts-task/internal/handlers"
+	"github.com/mahigadamsetty/Inshorts-task/internal/middleware"
+	"github.com/mahigadamsetty/Inshorts-task/internal/publisher"
+	"github.com/mahigadamsetty/Inshorts-task/internal/services"
+	"github.com/mahigadamsetty/Inshorts-task/internal/tenant"
 )
 
-func SetupRouter(cfg *config.Config) *gin.Engine {
+// Handlers are the request handlers the routes are served by
+type Handlers struct {
+	News         *handlers.NewsHandler
+	Admin        *handlers.AdminHandler
+	UserData     *handlers.UserDataHandler
+	Publisher    *handlers.PublisherHandler
+	ShortLinks   *handlers.ShortLinkHandler
+	Integrations *handlers.IntegrationHandler
+	MCP          *handlers.MCPHandler
+	Usage        *handlers.UsageHandler
+	Sitemap      *handlers.SitemapHandler // Nil unless a public site is configured
+}
+
+func SetupRouter(cfg *config.Config, tenants *tenant.Registry, publishers *publisher.Registry, svc *services.Services, h Handlers) *gin.Engine {
 	// Set Gin to release mode
 	gin.SetMode(gin.ReleaseMode)
 	
-	r := gin.Default()
+	r := gin.New()
+	r.Use(middleware.Logger(cfg.LocationPrecision), middleware.Platform(), gin.Recovery())
+	
+	// Cancel work for requests past their route's deadline or abandoned by the client
+	r.Use(middleware.Timeout(cfg.RouteTimeouts()))
+	
+	// Let resilience tests inject failures per request outside production
+	if cfg.FaultInjectionEnabled() {
+		r.Use(middleware.Faults())
+	}
 	
 	// CORS middleware - allow all for demo
 	r.Use(cors.New(cors.Config{
 		AllowAllOrigins:  true,
 		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization"},
+		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization", "X-API-Key", "X-Client-Platform"},
 		ExposeHeaders:    []string{"Content-Length"},
 		AllowCredentials: true,
 	}))
 	
-	// Initialize handlers
-	newsHandler := handlers.NewNewsHandler(cfg)
+	// Sitemap and robots.txt of the public site, for search engines
+	if h.Sitemap != nil {
+		r.GET("/robots.txt", h.Sitemap.Robots)
+		r.GET("/sitemap.xml", h.Sitemap.Index)
+		r.GET("/sitemaps/:page", h.Sitemap.Page)
+	}
+	
+	// Spoken summaries, linked from articles and audio briefings
+	r.GET("/audio/:file", h.News.GetAudioFile)
+	
+	// Stored copies of preview images, linked from articles
+	r.GET("/images/:file", h.News.GetImageFile)
+	
+	// Short links shared in push and email digests, followed without an API key
+	r.GET("/s/:code", h.ShortLinks.Follow)
+	
+	// Slash commands of the Slack and Discord apps, signed by the chat apps
+	// instead of carrying an API key
+	discordKey, _ := cfg.DiscordKey()
+	integrations := r.Group("/integrations")
+	integrations.Use(middleware.Degradation(), middleware.Budget(cfg.ResponseBudgets()))
+	{
+		integrations.POST("/slack/command", middleware.SlackSignature(cfg.SlackSigningSecret), h.Integrations.SlackCommand)
+		integrations.POST("/discord/interactions", middleware.DiscordSignature(discordKey), h.Integrations.DiscordInteraction)
+	}
+	
+	// Model Context Protocol server exposing news tools to AI assistants. Tool
+	// calls are answered by the news routes below on an engine of their own, as
+	// the tenant of the key presented to /mcp.
+	tools := gin.New()
+	tools.Use(gin.Recovery(), middleware.Degradation(), middleware.MaxLimit(cfg.QueryMaxLimit), middleware.Budget(cfg.ResponseBudgets()))
+	tools.GET("/api/v1/news/search", h.News.Search)
+	tools.GET("/api/v1/news/nearby", h.News.GetNearby)
+	tools.GET("/api/v1/news/trending", h.News.GetTrending)
+	h.MCP.UseTools(tools)
+	mcp := r.Group("/mcp")
+	mcp.Use(middleware.Tenant(tenants, true), middleware.Quota(svc.MeterRequest, cfg.QuotaStatus()))
+	{
+		mcp.POST("", h.MCP.Serve)
+		mcp.GET("", h.MCP.Stream)
+	}
 	
 	// API v1 routes
 	v1 := r.Group("/api/v1/news")
+	v1.Use(middleware.Localize(), middleware.Tenant(tenants, cfg.RequireAPIKey), middleware.Quota(svc.MeterRequest, cfg.QuotaStatus()),
+		middleware.Recorder(cfg.RecordSampleRate, cfg.RecordMaxBytes, cfg.LocationPrecision, svc.RecordRequest),
+		middleware.Degradation(), middleware.MaxLimit(cfg.QueryMaxLimit, "/api/v1/news/timeline"), middleware.Budget(cfg.ResponseBudgets()),
+		middleware.ClientHints(cfg.CompactLimits(), cfg.CompactSummaryChars),
+		middleware.Reads("/api/v1/news", svc.RecordRead))
+	{
+		v1.GET("/category", h.News.GetByCategory)
+		v1.GET("/categories", h.News.GetCategoryCounts)
+		v1.GET("/source", h.News.GetBySource)
+		v1.GET("/score", h.News.GetByScore)
+		v1.GET("/search", h.News.Search)
+		v1.GET("/nearby", h.News.GetNearby)
+		v1.GET("/trending", h.News.GetTrending)
+		v1.GET("/trending/compare", h.News.CompareTrending)
+		v1.GET("/random", h.News.GetRandom)
+		v1.GET("/for-you", h.News.GetForYou)
+		v1.GET("/query", h.News.Query)
+		v1.GET("/stories", h.News.GetStories)
+		v1.GET("/stories/:id", h.News.GetStory)
+		v1.GET("/topics", h.News.GetTopics)
+		v1.GET("/topics/:id", h.News.GetTopic)
+		v1.GET("/timeline", h.News.GetTimeline)
+		v1.GET("/audio/briefing", h.News.GetAudioBriefing)
+		v1.POST("/events", h.News.RecordEvent)
+		v1.POST("/events/batch", h.News.RecordEventBatch)
+		v1.GET("/stats", h.News.GetStats)
+		v1.GET("/stats/history", h.News.GetScoreHistory)
+		v1.GET("/:id/readable", h.News.GetReadable)
+		v1.GET("/:id/card", h.News.GetCard)
+		v1.POST("/:id/shortlink", h.ShortLinks.Create)
+		v1.GET("/shortlinks/:code", h.ShortLinks.Stats)
+	}
+	
+	// Geofence alert subscriptions
+	geofences := r.Group("/api/v1/geofences")
+	geofences.Use(middleware.Tenant(tenants, cfg.RequireAPIKey), middleware.Quota(svc.MeterRequest, cfg.QuotaStatus()))
+	{
+		geofences.POST("", h.News.CreateGeofence)
+		geofences.GET("", h.News.ListGeofences)
+		geofences.GET("/:id", h.News.GetGeofence)
+		geofences.DELETE("/:id", h.News.DeleteGeofence)
+		geofences.GET("/:id/alerts", h.News.GetGeofenceAlerts)
+	}
+	
+	// Distances to articles for map clients, computed without exposing the
+	// articles' coordinates
+	geo := r.Group("/api/v1/geo")
+	geo.Use(middleware.Tenant(tenants, cfg.RequireAPIKey), middleware.Quota(svc.MeterRequest, cfg.QuotaStatus()))
+	{
+		geo.POST("/distances", h.News.GetDistances)
+	}
+	
+	// Analytics over the tenant's articles and searches
+	analytics := r.Group("/api/v1/analytics")
+	analytics.Use(middleware.Tenant(tenants, cfg.RequireAPIKey), middleware.Quota(svc.MeterRequest, cfg.QuotaStatus()))
+	{
+		analytics.GET("/terms", h.News.GetRisingTerms)
+		analytics.GET("/heatmap", h.News.GetHeatmap)
+		analytics.GET("/sessions", h.News.GetSessionMetrics)
+	}
+	
+	// Export and deletion of the data linked to an API key, so a key is required
+	users := r.Group("/api/v1/users/me")
+	users.Use(middleware.Tenant(tenants, true), middleware.Quota(svc.MeterRequest, cfg.QuotaStatus()))
+	{
+		users.GET("/export", h.UserData.ExportData)
+		users.DELETE("/data", h.UserData.DeleteData)
+		users.GET("/requests/:id", h.UserData.GetDataRequest)
+		users.GET("/requests/:id/download", h.UserData.DownloadExport)
+	}
+	
+	// Usage and quotas of the caller's API key, which don't count towards them
+	keys := r.Group("/api/v1/keys/me")
+	keys.Use(middleware.Tenant(tenants, true))
+	{
+		keys.GET("/usage", h.Usage.GetUsage)
+	}
+	
+	// Partner publishers pushing the articles of their source
+	publishing := r.Group("/api/v1/publisher")
+	publishing.Use(middleware.Publisher(publishers, tenants))
+	{
+		publishing.POST("/articles", h.Publisher.CreateArticle)
+		publishing.PUT("/articles/:id", h.Publisher.UpdateArticle)
+		publishing.DELETE("/articles/:id", h.Publisher.WithdrawArticle)
+	}
+	
+	// Admin routes
+	admin := r.Group("/api/v1/admin")
+	admin.Use(middleware.Admin(cfg.AdminAPIKey))
 	{
-		v1.GET("/category", newsHandler.GetByCategory)
-		v1.GET("/source", newsHandler.GetBySource)
-		v1.GET("/score", newsHandler.GetByScore)
-		v1.GET("/search", newsHandler.Search)
-		v1.GET("/nearby", newsHandler.GetNearby)
-		v1.GET("/trending", newsHandler.GetTrending)
-		v1.GET("/query", newsHandler.Query)
+		admin.GET("/scheduler/jobs", h.Admin.ListJobs)
+		admin.GET("/scheduler/jobs/:name/runs", h.Admin.GetJobRuns)
+		admin.POST("/scheduler/jobs/:name/run", h.Admin.RunJob)
+		admin.GET("/categories", h.Admin.ListCategories)
+		admin.PUT("/categories/:name", h.Admin.SaveCategory)
+		admin.DELETE("/categories/:name", h.Admin.DeleteCategory)
+		admin.POST("/categories/migrate", h.Admin.MigrateCategories)
+		admin.GET("/sources", h.Admin.ListSources)
+		admin.PUT("/sources/:name", h.Admin.SaveSource)
+		admin.DELETE("/sources/:name", h.Admin.DeleteSource)
+		admin.GET("/sources/:name/crawl", h.Admin.GetCrawlPolicy)
+		admin.PUT("/sources/:name/crawl", h.Admin.SaveCrawlPolicy)
+		admin.DELETE("/sources/:name/crawl", h.Admin.DeleteCrawlPolicy)
+		admin.GET("/crawl-policies", h.Admin.ListCrawlPolicies)
+		admin.GET("/ingestion/sources", h.Admin.ListIngestionSources)
+		admin.POST("/ingestion/sources", h.Admin.CreateIngestionSource)
+		admin.GET("/ingestion/sources/:id", h.Admin.GetIngestionSource)
+		admin.PUT("/ingestion/sources/:id", h.Admin.UpdateIngestionSource)
+		admin.DELETE("/ingestion/sources/:id", h.Admin.DeleteIngestionSource)
+		admin.POST("/ingestion/sources/:id/run", h.Admin.RunIngestionSource)
+		admin.POST("/summarize", h.Admin.Summarize)
+		admin.GET("/jobs/:id", h.Admin.GetSummaryJob)
+		admin.POST("/reindex", h.Admin.Reindex)
+		admin.GET("/reindex/:id", h.Admin.GetReindexJob)
+		admin.GET("/archive/search", h.Admin.SearchArchive)
+		admin.PATCH("/news/:id", h.Admin.EditArticle)
+		admin.POST("/articles/purge", h.Admin.PurgeArticles)
+		admin.GET("/integrity", h.Admin.GetIntegrityReport)
+		admin.POST("/integrity/check", h.Admin.CheckIntegrity)
+		admin.GET("/outbox", h.Admin.ListOutbox)
+		admin.POST("/outbox/:id/retry", h.Admin.RetryOutboxMessage)
+		admin.DELETE("/outbox/:id", h.Admin.DiscardOutboxMessage)
+		admin.GET("/llm/usage", h.Admin.GetLLMUsage)
+		admin.GET("/pii/scrubbed", h.Admin.GetScrubbedCounts)
+		admin.GET("/tuning", h.Admin.GetTuning)
+		admin.POST("/tuning/reload", h.Admin.ReloadTuning)
+		admin.GET("/simulation", h.Admin.GetSimulation)
+		admin.PUT("/simulation", h.Admin.UpdateSimulation)
+		admin.GET("/ranking/shadow", h.Admin.GetShadowRanking)
+		admin.GET("/analytics/engagement", h.Admin.GetEngagement)
+		admin.GET("/analytics/impressions", h.Admin.GetImpressions)
+		admin.GET("/analytics/position-bias", h.Admin.GetPositionBias)
+		admin.GET("/analytics/discovery", h.Admin.GetDiscovery)
 	}
 	
 	// Health check
@@ -0,0 +1,73 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseNext(t *testing.T) {
+	// A Friday
+	after := time.Date(2026, time.October, 16, 10, 7, 30, 0, time.UTC)
+
+	tests := []struct {
+		name string
+		spec string
+		want time.Time
+	}{
+		{"every quarter hour", "*/15 * * * *", time.Date(2026, time.October, 16, 10, 15, 0, 0, time.UTC)},
+		{"step from offset", "5/20 * * * *", time.Date(2026, time.October, 16, 10, 25, 0, 0, time.UTC)},
+		{"list of hours", "0 9,17 * * *", time.Date(2026, time.October, 16, 17, 0, 0, 0, time.UTC)},
+		{"weekdays skip the weekend", "30 9 * * 1-5", time.Date(2026, time.October, 19, 9, 30, 0, 0, time.UTC)},
+		{"seven is Sunday", "0 0 * * 7", time.Date(2026, time.October, 18, 0, 0, 0, 0, time.UTC)},
+		{"day of month", "0 12 1 * *", time.Date(2026, time.November, 1, 12, 0, 0, 0, time.UTC)},
+		{"day of month or day of week", "0 0 13 * 5", time.Date(2026, time.October, 23, 0, 0, 0, 0, time.UTC)},
+		{"hourly", "@hourly", time.Date(2026, time.October, 16, 11, 0, 0, 0, time.UTC)},
+		{"daily", "@daily", time.Date(2026, time.October, 17, 0, 0, 0, 0, time.UTC)},
+		{"weekly", "@weekly", time.Date(2026, time.October, 18, 0, 0, 0, 0, time.UTC)},
+		{"monthly", "@monthly", time.Date(2026, time.November, 1, 0, 0, 0, 0, time.UTC)},
+		{"yearly", "@yearly", time.Date(2027, time.January, 1, 0, 0, 0, 0, time.UTC)},
+		{"interval", "@every 90s", time.Date(2026, time.October, 16, 10, 9, 0, 0, time.UTC)},
+		{"never matches", "0 0 30 2 *", time.Time{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schedule, err := Parse(tt.spec)
+			if err != nil {
+				t.Fatalf("Parse(%q) returned error: %v", tt.spec, err)
+			}
+			if got := schedule.Next(after); !got.Equal(tt.want) {
+				t.Errorf("Parse(%q).Next(%v) = %v, want %v", tt.spec, after, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		spec string
+	}{
+		{"empty", ""},
+		{"too few fields", "* * * *"},
+		{"too many fields", "* * * * * *"},
+		{"minute out of range", "60 * * * *"},
+		{"day of month out of range", "0 0 32 * *"},
+		{"month out of range", "0 0 1 13 *"},
+		{"day of week out of range", "0 0 * * 8"},
+		{"reversed range", "5-1 * * * *"},
+		{"zero step", "*/0 * * * *"},
+		{"not a number", "a * * * *"},
+		{"unknown descriptor", "@fortnightly"},
+		{"bad interval", "@every soon"},
+		{"negative interval", "@every -1m"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Parse(tt.spec); err == nil {
+				t.Errorf("Parse(%q) returned no error", tt.spec)
+			}
+		})
+	}
+}
@@ -0,0 +1,376 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/mahigadamsetty/Inshorts-task/internal/apperr"
+	"github.com/mahigadamsetty/Inshorts-task/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	// defaultTimeout bounds a run of a job
+	defaultTimeout = 30 * time.Minute
+	// leaseTTL bounds how long a crashed replica can block a job. Running jobs
+	// renew their lease every leaseRenewal.
+	leaseTTL     = 2 * time.Minute
+	leaseRenewal = 30 * time.Second
+)
+
+// errLockHeld means another run of the job is in progress, here or on another replica
+var errLockHeld = errors.New("job lock held by another instance")
+
+// errNotDue means another replica already ran the job's activation
+var errNotDue = errors.New("job already ran for this activation")
+
+// JobFunc is the work performed by a scheduled job
+type JobFunc func(ctx context.Context) error
+
+// Job is a registered scheduled job
+type Job struct {
+	Name     string
+	Spec     string
+	schedule Schedule
+	run      JobFunc
+	timeout  time.Duration
+
+	mu      sync.Mutex
+	running bool
+	nextRun time.Time
+}
+
+// JobStatus describes a job for the admin API
+type JobStatus struct {
+	Name    string          `json:"name"`
+	Spec    string          `json:"schedule"`
+	Running bool            `json:"running"`
+	NextRun time.Time       `json:"next_run"`
+	LastRun *models.JobRun  `json:"last_run,omitempty"`
+	Lock    *models.JobLock `json:"lock,omitempty"`
+}
+
+// Scheduler runs registered jobs on their schedules. Jobs take a database lease
+// before running, so only one replica runs a given job at a time, and record
+// their next activation with it, so each activation runs on one replica only.
+type Scheduler struct {
+	db       *gorm.DB
+	instance string
+	jobs     map[string]*Job
+	mu       sync.RWMutex
+	started  bool
+}
+
+// New creates a scheduler that stores locks and run history in the database
+func New(database *gorm.DB) *Scheduler {
+	host, _ := os.Hostname()
+	return &Scheduler{
+		db:       database,
+		instance: fmt.Sprintf("%s-%d", host, os.Getpid()),
+		jobs:     make(map[string]*Job),
+	}
+}
+
+// Register adds a job with a cron expression, descriptor (@daily) or @every interval
+func (s *Scheduler) Register(name, spec string, run JobFunc) error {
+	schedule, err := Parse(spec)
+	if err != nil {
+		return fmt.Errorf("job %s: %w", name, err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.jobs[name]; exists {
+		return fmt.Errorf("job %s is already registered", name)
+	}
+	if s.started {
+		return fmt.Errorf("job %s registered after scheduler start", name)
+	}
+
+	s.jobs[name] = &Job{
+		Name:     name,
+		Spec:     spec,
+		schedule: schedule,
+		run:      run,
+		timeout:  defaultTimeout,
+	}
+	return nil
+}
+
+// Start launches every registered job. Jobs stop when ctx is cancelled.
+func (s *Scheduler) Start(ctx context.Context) {
+	s.mu.Lock()
+	s.started = true
+	jobs := make([]*Job, 0, len(s.jobs))
+	for _, job := range s.jobs {
+		jobs = append(jobs, job)
+	}
+	s.mu.Unlock()
+
+	for _, job := range jobs {
+		go s.loop(ctx, job)
+	}
+}
+
+// RunNow runs a job immediately, subject to the same locking as scheduled runs
+func (s *Scheduler) RunNow(ctx context.Context, name string) error {
+	job, err := s.job(name)
+	if err != nil {
+		return err
+	}
+	return s.execute(ctx, job, time.Time{}, false)
+}
+
+// RunIfDue runs a job immediately unless a replica already ran its latest
+// activation, e.g. a startup pass when another replica is already up. It
+// leaves the next activation as it is. Skipped runs are not an error.
+func (s *Scheduler) RunIfDue(ctx context.Context, name string) error {
+	job, err := s.job(name)
+	if err != nil {
+		return err
+	}
+	err = s.execute(ctx, job, time.Now(), false)
+	if errors.Is(err, errNotDue) || errors.Is(err, errLockHeld) {
+		return nil
+	}
+	return err
+}
+
+// Lookup returns an apperr.ErrNotFound unless a job is registered under name
+func (s *Scheduler) Lookup(name string) error {
+	_, err := s.job(name)
+	return err
+}
+
+// job looks up a registered job
+func (s *Scheduler) job(name string) (*Job, error) {
+	s.mu.RLock()
+	job, ok := s.jobs[name]
+	s.mu.RUnlock()
+	if !ok {
+		return nil, apperr.NotFoundf("Job %s not found", name)
+	}
+	return job, nil
+}
+
+// Status lists all jobs with their latest run and current lock
+func (s *Scheduler) Status() []JobStatus {
+	s.mu.RLock()
+	jobs := make([]*Job, 0, len(s.jobs))
+	for _, job := range s.jobs {
+		jobs = append(jobs, job)
+	}
+	s.mu.RUnlock()
+
+	sort.Slice(jobs, func(i, j int) bool {
+		return jobs[i].Name < jobs[j].Name
+	})
+
+	statuses := make([]JobStatus, len(jobs))
+	for i, job := range jobs {
+		job.mu.Lock()
+		statuses[i] = JobStatus{
+			Name:    job.Name,
+			Spec:    job.Spec,
+			Running: job.running,
+			NextRun: job.nextRun,
+		}
+		job.mu.Unlock()
+
+		var lastRun models.JobRun
+		if err := s.db.Where("job_name = ?", job.Name).Order("started_at DESC").First(&lastRun).Error; err == nil {
+			statuses[i].LastRun = &lastRun
+		}
+		var lock models.JobLock
+		if err := s.db.Where("name = ? AND expires_at > ?", job.Name, time.Now()).First(&lock).Error; err == nil {
+			statuses[i].Lock = &lock
+		}
+	}
+	return statuses
+}
+
+// History returns the most recent runs of a job
+func (s *Scheduler) History(name string, limit int) ([]models.JobRun, error) {
+	var runs []models.JobRun
+	err := s.db.Where("job_name = ?", name).Order("started_at DESC").Limit(limit).Find(&runs).Error
+	return runs, err
+}
+
+// loop waits for each scheduled activation of a job and runs it
+func (s *Scheduler) loop(ctx context.Context, job *Job) {
+	for {
+		next := job.schedule.Next(time.Now())
+		if next.IsZero() {
+			log.Printf("Scheduler: job %s has no future activation", job.Name)
+			return
+		}
+
+		job.mu.Lock()
+		job.nextRun = next
+		job.mu.Unlock()
+
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		err := s.execute(ctx, job, next, true)
+		if err != nil && !errors.Is(err, errLockHeld) && !errors.Is(err, errNotDue) {
+			log.Printf("Scheduler: job %s failed: %v", job.Name, err)
+		}
+	}
+}
+
+// execute runs a job once while holding its lock and records the run. A run
+// for an activation is skipped when a replica already ran it, and moves the
+// next activation past it when advance is set; a zero activation runs the job
+// regardless.
+func (s *Scheduler) execute(ctx context.Context, job *Job, activation time.Time, advance bool) error {
+	job.mu.Lock()
+	if job.running {
+		job.mu.Unlock()
+		return errLockHeld
+	}
+	job.running = true
+	job.mu.Unlock()
+
+	defer func() {
+		job.mu.Lock()
+		job.running = false
+		job.mu.Unlock()
+	}()
+
+	if err := s.acquire(job, activation, advance); err != nil {
+		return err
+	}
+	defer s.release(job.Name)
+
+	run := models.JobRun{
+		JobName:   job.Name,
+		Owner:     s.instance,
+		Status:    models.JobStatusRunning,
+		StartedAt: time.Now(),
+	}
+	if err := s.db.Create(&run).Error; err != nil {
+		log.Printf("Scheduler: failed to record run of %s: %v", job.Name, err)
+	}
+
+	jobCtx, cancel := context.WithTimeout(ctx, job.timeout)
+	renewed := make(chan struct{})
+	go func() {
+		s.renew(jobCtx, job.Name, cancel)
+		close(renewed)
+	}()
+	runErr := job.run(jobCtx)
+	cancel()
+	<-renewed
+
+	finished := time.Now()
+	run.FinishedAt = &finished
+	run.DurationMs = finished.Sub(run.StartedAt).Milliseconds()
+	run.Status = models.JobStatusSuccess
+	if runErr != nil {
+		run.Status = models.JobStatusFailed
+		run.Error = runErr.Error()
+	}
+	if run.ID != 0 {
+		s.db.Save(&run)
+	}
+
+	return runErr
+}
+
+// acquire takes the job lease if it is free, expired or already ours. For an
+// activation a replica already ran it returns errNotDue instead, and with
+// advance it moves the job's next activation past this one.
+func (s *Scheduler) acquire(job *Job, activation time.Time, advance bool) error {
+	now := time.Now()
+	expires := now.Add(leaseTTL)
+	updates := map[string]interface{}{"owner": s.instance, "expires_at": expires}
+	lock := models.JobLock{Name: job.Name, Owner: s.instance, ExpiresAt: expires}
+	if advance {
+		following := job.schedule.Next(activation)
+		updates["next_run_at"] = following
+		lock.NextRunAt = &following
+	}
+
+	// Create the lock row if it doesn't exist yet
+	result := s.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&lock)
+	if result.Error != nil {
+		return fmt.Errorf("failed to acquire lock: %w", result.Error)
+	}
+	if result.RowsAffected == 1 {
+		return nil
+	}
+
+	// Otherwise take it over only when free, and due for scheduled runs
+	query := s.db.Model(&models.JobLock{}).Where("name = ? AND (expires_at < ? OR owner = ?)", job.Name, now, s.instance)
+	if !activation.IsZero() {
+		query = query.Where("next_run_at IS NULL OR next_run_at <= ?", activation)
+	}
+	result = query.Updates(updates)
+	if result.Error != nil {
+		return fmt.Errorf("failed to acquire lock: %w", result.Error)
+	}
+	if result.RowsAffected == 1 {
+		return nil
+	}
+	if activation.IsZero() {
+		return errLockHeld
+	}
+
+	// Tell a held lease from an activation that already ran
+	var held int64
+	if err := s.db.Model(&models.JobLock{}).Where("name = ? AND expires_at >= ? AND owner <> ?", job.Name, now, s.instance).Count(&held).Error; err == nil && held > 0 {
+		return errLockHeld
+	}
+	return errNotDue
+}
+
+// renew extends the job lease while the job runs, until ctx is done. When the
+// lease was taken over, e.g. after this replica stalled past its expiry, the
+// run is cancelled so two replicas don't run the job at once.
+func (s *Scheduler) renew(ctx context.Context, name string, cancel context.CancelFunc) {
+	ticker := time.NewTicker(leaseRenewal)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		result := s.db.Model(&models.JobLock{}).
+			Where("name = ? AND owner = ?", name, s.instance).
+			Update("expires_at", time.Now().Add(leaseTTL))
+		if result.Error != nil {
+			log.Printf("Scheduler: failed to renew lock for %s: %v", name, result.Error)
+			continue
+		}
+		if result.RowsAffected == 0 {
+			log.Printf("Scheduler: lost lock for %s, cancelling the run", name)
+			cancel()
+			return
+		}
+	}
+}
+
+// release gives up the job lease, keeping the row for its next activation
+func (s *Scheduler) release(name string) {
+	err := s.db.Model(&models.JobLock{}).
+		Where("name = ? AND owner = ?", name, s.instance).
+		Update("expires_at", time.Now()).Error
+	if err != nil {
+		log.Printf("Scheduler: failed to release lock for %s: %v", name, err)
+	}
+}
@@ -0,0 +1,163 @@
+package scrub
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Built-in pattern names. Matches are replaced by the name in brackets, e.g.
+// "[email]", after the text of the pattern's first capture group, if any.
+const (
+	PatternEmail       = "email"
+	PatternPhone       = "phone"
+	PatternCoordinates = "coordinates"
+)
+
+// defaultPatterns catch emails, phone numbers written with a leading + or in
+// space or dash separated digit groups (dots would catch IP addresses), and coordinates precise enough to locate a person:
+// decimal pairs with 4+ decimals or lat/lon parameters, whose key is kept
+var defaultPatterns = map[string]string{
+	PatternEmail:       `[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`,
+	PatternPhone:       `\+\d{1,3}(?:[\s\-]?\d{2,5}){2,3}\b|(?:\(\d{2,4}\)[\s\-]?|\b\d{2,5}[\s\-])\d{3,5}[\s\-]?\d{3,5}\b`,
+	PatternCoordinates: `-?\d{1,3}\.\d{4,}\s*,\s*-?\d{1,3}\.\d{4,}|((?i:\b(?:lat|lon|lng|latitude|longitude)\b["']?\s*[=:]\s*["']?))-?\d{1,3}\.\d{4,}`,
+}
+
+type pattern struct {
+	name        string
+	re          *regexp.Regexp
+	replacement string // Template keeping the first capture group before the marker
+}
+
+var (
+	mu       sync.RWMutex
+	patterns = mustCompile(defaultPatterns)
+	counts   = map[string]int64{}
+)
+
+// Configure replaces the active patterns with the built-in ones overlaid by a
+// JSON object of name -> regular expression. A custom pattern named after a
+// built-in one replaces it, and an empty expression disables it. The text of
+// a pattern's first capture group is kept, so a pattern can match context,
+// like a parameter name, that isn't itself sensitive.
+func Configure(custom string) error {
+	merged := make(map[string]string, len(defaultPatterns))
+	for name, expr := range defaultPatterns {
+		merged[name] = expr
+	}
+	if custom != "" {
+		var extra map[string]string
+		if err := json.Unmarshal([]byte(custom), &extra); err != nil {
+			return fmt.Errorf("invalid PII patterns: %w", err)
+		}
+		for name, expr := range extra {
+			if expr == "" {
+				delete(merged, name)
+				continue
+			}
+			merged[name] = expr
+		}
+	}
+
+	compiled, err := compile(merged)
+	if err != nil {
+		return err
+	}
+	mu.Lock()
+	patterns = compiled
+	mu.Unlock()
+	return nil
+}
+
+// compile builds the pattern list in name order so scrubbing is deterministic
+func compile(exprs map[string]string) ([]pattern, error) {
+	names := make([]string, 0, len(exprs))
+	for name := range exprs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	compiled := make([]pattern, 0, len(names))
+	for _, name := range names {
+		re, err := regexp.Compile(exprs[name])
+		if err != nil {
+			return nil, fmt.Errorf("invalid PII pattern %q: %w", name, err)
+		}
+		replacement := "${1}[" + strings.ReplaceAll(name, "$", "$$") + "]"
+		compiled = append(compiled, pattern{name: name, re: re, replacement: replacement})
+	}
+	return compiled, nil
+}
+
+func mustCompile(exprs map[string]string) []pattern {
+	compiled, err := compile(exprs)
+	if err != nil {
+		panic(err)
+	}
+	return compiled
+}
+
+// String replaces every match of the active patterns and counts the replacements
+func String(s string) string {
+	mu.RLock()
+	active := patterns
+	mu.RUnlock()
+
+	found := map[string]int64{}
+	for _, p := range active {
+		if n := len(p.re.FindAllStringIndex(s, -1)); n > 0 {
+			found[p.name] += int64(n)
+			s = p.re.ReplaceAllString(s, p.replacement)
+		}
+	}
+	if len(found) > 0 {
+		mu.Lock()
+		for name, n := range found {
+			counts[name] += n
+		}
+		mu.Unlock()
+	}
+	return s
+}
+
+// Strings scrubs each string of a slice into a new slice
+func Strings(values []string) []string {
+	scrubbed := make([]string, len(values))
+	for i, v := range values {
+		scrubbed[i] = String(v)
+	}
+	return scrubbed
+}
+
+// Counts returns the number of items scrubbed per pattern since the process started
+func Counts() map[string]int64 {
+	mu.RLock()
+	defer mu.RUnlock()
+	result := make(map[string]int64, len(counts))
+	for name, n := range counts {
+		result[name] = n
+	}
+	return result
+}
+
+// writer scrubs everything written through it. Loggers write whole lines per
+// call, so matches are never split across writes.
+type writer struct {
+	w io.Writer
+}
+
+// NewWriter wraps a log destination so nothing sensitive reaches it
+func NewWriter(w io.Writer) io.Writer {
+	return &writer{w: w}
+}
+
+func (w *writer) Write(p []byte) (int, error) {
+	if _, err := io.WriteString(w.w, String(string(p))); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
@@ -0,0 +1,96 @@
+package scrub
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestString(t *testing.T) {
+	if err := Configure(""); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"email", "contact jane.doe+news@example.co.in today", "contact [email] today"},
+		{"international phone", "call +91 98765 43210 now", "call [phone] now"},
+		{"dashed phone", "office 080-2345-6789", "office [phone]"},
+		{"bracketed area code", "dial (080) 2345 6789", "dial [phone]"},
+		{"ip address", "client 10.0.0.1 connected", "client 10.0.0.1 connected"},
+		{"plain numbers", "In 2024 1500 people attended", "In 2024 1500 people attended"},
+		{"coordinate pair", "near 12.9716, 77.5946", "near [coordinates]"},
+		{"coordinate parameters", "/nearby?lat=12.9716&lon=-77.5946", "/nearby?lat=[coordinates]&lon=[coordinates]"},
+		{"coordinate json", `{"latitude": 12.97161}`, `{"latitude": [coordinates]}`},
+		{"quoted coordinate", `lng="77.59461"`, `lng="[coordinates]"`},
+		{"coarse coordinates", "near 12.97, 77.59", "near 12.97, 77.59"},
+		{"several", "mail a@b.io or +1 415 555 0100", "mail [email] or [phone]"},
+		{"nothing", "sports news", "sports news"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := String(tt.input); got != tt.want {
+				t.Errorf("String(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConfigure(t *testing.T) {
+	defer Configure("")
+
+	tests := []struct {
+		name    string
+		custom  string
+		input   string
+		want    string
+		wantErr bool
+	}{
+		{"built-in", "", "mail a@b.io", "mail [email]", false},
+		{"added pattern", `{"aadhaar": "\\b\\d{4} \\d{4} \\d{4}\\b"}`, "id 1234 5678 9012", "id [aadhaar]", false},
+		{"replaced pattern", `{"email": "@\\w+"}`, "mail a@b.io", "mail a[email].io", false},
+		{"disabled pattern", `{"phone": ""}`, "call +91 98765 43210", "call +91 98765 43210", false},
+		{"kept capture group", `{"token": "(token=)\\w+"}`, "?token=abc123", "?token=[token]", false},
+		{"invalid json", `{"phone":`, "", "", true},
+		{"invalid expression", `{"broken": "("}`, "", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			Configure("")
+			err := Configure(tt.custom)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Configure(%q) error = %v, want error %v", tt.custom, err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if got := String(tt.input); got != tt.want {
+				t.Errorf("String(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWriter(t *testing.T) {
+	if err := Configure(""); err != nil {
+		t.Fatal(err)
+	}
+	before := Counts()[PatternEmail]
+
+	var out bytes.Buffer
+	line := "login failed for jane@example.com\n"
+	n, err := NewWriter(&out).Write([]byte(line))
+	if err != nil || n != len(line) {
+		t.Fatalf("Write() = %d, %v, want %d, nil", n, err, len(line))
+	}
+	if got, want := out.String(), "login failed for [email]\n"; got != want {
+		t.Errorf("wrote %q, want %q", got, want)
+	}
+	if got := Counts()[PatternEmail] - before; got != 1 {
+		t.Errorf("email count grew by %d, want 1", got)
+	}
+}
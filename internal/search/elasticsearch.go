@@ -0,0 +1,399 @@
+package search
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/mahigadamsetty/Inshorts-task/internal/llm"
+	"github.com/mahigadamsetty/Inshorts-task/internal/models"
+)
+
+// Defaults of the elasticsearch backend
+const (
+	ElasticsearchURL   = "http://localhost:9200"
+	ElasticsearchIndex = "articles"
+)
+
+func init() {
+	// OpenSearch answers the same index, bulk and search APIs
+	Register("elasticsearch", newElasticsearch)
+	Register("opensearch", newElasticsearch)
+}
+
+// indexMapping keeps keyword fields lowercased so category and source filters
+// match regardless of case, and analyzes titles and descriptions as English
+const indexMapping = `{
+  "settings": {
+    "analysis": {
+      "normalizer": {
+        "lowercase": {"type": "custom", "filter": ["lowercase"]}
+      }
+    }
+  },
+  "mappings": {
+    "dynamic": "strict",
+    "properties": {
+      "tenant_id":        {"type": "keyword"},
+      "title":            {"type": "text", "analyzer": "english"},
+      "description":      {"type": "text", "analyzer": "english"},
+      "category":         {"type": "keyword", "normalizer": "lowercase"},
+      "source_name":      {"type": "keyword", "normalizer": "lowercase"},
+      "publication_date": {"type": "date"},
+      "location":         {"type": "geo_point"},
+      "access":           {"type": "keyword"},
+      "content_rating":   {"type": "keyword"},
+      "state":            {"type": "keyword"},
+      "visible_from":     {"type": "date"},
+      "expires_at":       {"type": "date"},
+      "updated_at":       {"type": "date"}
+    }
+  }
+}`
+
+// elasticsearch keeps documents in one index of an Elasticsearch or
+// OpenSearch cluster, talking to its REST API
+type elasticsearch struct {
+	baseURL  string
+	index    string
+	username string
+	password string
+	http     *http.Client
+}
+
+func newElasticsearch(options Options) (Backend, error) {
+	b := &elasticsearch{
+		baseURL:  strings.TrimRight(options.URL, "/"),
+		index:    options.Index,
+		username: options.Username,
+		password: options.Password,
+		http:     &http.Client{Timeout: 30 * time.Second},
+	}
+	if b.baseURL == "" {
+		b.baseURL = ElasticsearchURL
+	}
+	if b.index == "" {
+		b.index = ElasticsearchIndex
+	}
+	if _, err := url.Parse(b.baseURL); err != nil {
+		return nil, fmt.Errorf("invalid search URL: %w", err)
+	}
+	return b, nil
+}
+
+// esDocument is a Document as stored in the index
+type esDocument struct {
+	TenantID        string     `json:"tenant_id"`
+	Title           string     `json:"title"`
+	Description     string     `json:"description"`
+	Category        []string   `json:"category"`
+	SourceName      string     `json:"source_name"`
+	PublicationDate time.Time  `json:"publication_date"`
+	Location        *esPoint   `json:"location,omitempty"`
+	Access          string     `json:"access,omitempty"`
+	ContentRating   string     `json:"content_rating,omitempty"`
+	State           string     `json:"state,omitempty"`
+	VisibleFrom     *time.Time `json:"visible_from,omitempty"`
+	ExpiresAt       *time.Time `json:"expires_at,omitempty"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+}
+
+type esPoint struct {
+	Lat float64 `json:"lat"`
+	Lon float64 `json:"lon"`
+}
+
+// query is a clause of the query DSL
+type query = map[string]interface{}
+
+func (b *elasticsearch) EnsureIndex(ctx context.Context) (bool, error) {
+	status, _, err := b.do(ctx, "HEAD", "/"+b.index, "", nil)
+	if err != nil {
+		return false, err
+	}
+	if status == http.StatusOK {
+		return false, nil
+	}
+	if status != http.StatusNotFound {
+		return false, fmt.Errorf("checking search index %s returned status %d", b.index, status)
+	}
+	if err := b.expect(ctx, "PUT", "/"+b.index, "application/json", []byte(indexMapping), nil); err != nil {
+		return false, fmt.Errorf("creating search index %s: %w", b.index, err)
+	}
+	return true, nil
+}
+
+func (b *elasticsearch) Index(ctx context.Context, docs []Document) error {
+	if len(docs) == 0 {
+		return nil
+	}
+	var body bytes.Buffer
+	encoder := json.NewEncoder(&body)
+	for _, doc := range docs {
+		stored := esDocument{
+			TenantID:        doc.TenantID,
+			Title:           doc.Title,
+			Description:     doc.Description,
+			Category:        doc.Category,
+			SourceName:      doc.SourceName,
+			PublicationDate: doc.PublicationDate.UTC(),
+			Access:          doc.Access,
+			ContentRating:   doc.ContentRating,
+			State:           doc.State,
+			VisibleFrom:     doc.VisibleFrom,
+			ExpiresAt:       doc.ExpiresAt,
+			UpdatedAt:       doc.UpdatedAt.UTC(),
+		}
+		if doc.Location != nil {
+			stored.Location = &esPoint{Lat: doc.Location.Latitude, Lon: doc.Location.Longitude}
+		}
+		if err := encoder.Encode(query{"index": query{"_id": doc.ID}}); err != nil {
+			return err
+		}
+		if err := encoder.Encode(stored); err != nil {
+			return err
+		}
+	}
+	return b.bulk(ctx, body.Bytes())
+}
+
+func (b *elasticsearch) Delete(ctx context.Context, ids []string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	var body bytes.Buffer
+	encoder := json.NewEncoder(&body)
+	for _, id := range ids {
+		if err := encoder.Encode(query{"delete": query{"_id": id}}); err != nil {
+			return err
+		}
+	}
+	return b.bulk(ctx, body.Bytes())
+}
+
+// bulk sends newline-delimited actions to the index's bulk API. The API
+// answers 200 even when actions fail, so the items are checked too; deleting
+// a missing document is not a failure.
+func (b *elasticsearch) bulk(ctx context.Context, body []byte) error {
+	var response struct {
+		Errors bool `json:"errors"`
+		Items  []map[string]struct {
+			ID     string          `json:"_id"`
+			Status int             `json:"status"`
+			Error  json.RawMessage `json:"error"`
+		} `json:"items"`
+	}
+	if err := b.expect(ctx, "POST", "/"+b.index+"/_bulk", "application/x-ndjson", body, &response); err != nil {
+		return fmt.Errorf("bulk indexing: %w", err)
+	}
+	if !response.Errors {
+		return nil
+	}
+	failed := 0
+	var first string
+	for _, item := range response.Items {
+		for action, result := range item {
+			if result.Status < 300 || (action == "delete" && result.Status == http.StatusNotFound) {
+				continue
+			}
+			if failed == 0 {
+				first = fmt.Sprintf("%s %s: %s", action, result.ID, result.Error)
+			}
+			failed++
+		}
+	}
+	if failed == 0 {
+		return nil
+	}
+	return fmt.Errorf("bulk indexing failed for %d documents, first %s", failed, first)
+}
+
+func (b *elasticsearch) Search(ctx context.Context, q Query) ([]string, error) {
+	body, err := json.Marshal(query{
+		"size":             q.Limit,
+		"_source":          false,
+		"track_total_hits": false,
+		"query":            translate(q),
+	})
+	if err != nil {
+		return nil, err
+	}
+	var response struct {
+		Hits struct {
+			Hits []struct {
+				ID string `json:"_id"`
+			} `json:"hits"`
+		} `json:"hits"`
+	}
+	if err := b.expect(ctx, "POST", "/"+b.index+"/_search", "application/json", body, &response); err != nil {
+		return nil, fmt.Errorf("searching: %w", err)
+	}
+	ids := make([]string, len(response.Hits.Hits))
+	for i, hit := range response.Hits.Hits {
+		ids[i] = hit.ID
+	}
+	return ids, nil
+}
+
+func (b *elasticsearch) LastUpdated(ctx context.Context) (time.Time, error) {
+	body := []byte(`{"size": 0, "aggs": {"last_updated": {"max": {"field": "updated_at"}}}}`)
+	var response struct {
+		Aggregations struct {
+			LastUpdated struct {
+				Value *float64 `json:"value"` // Epoch milliseconds, null for an empty index
+			} `json:"last_updated"`
+		} `json:"aggregations"`
+	}
+	if err := b.expect(ctx, "POST", "/"+b.index+"/_search", "application/json", body, &response); err != nil {
+		return time.Time{}, fmt.Errorf("reading last indexed update: %w", err)
+	}
+	if response.Aggregations.LastUpdated.Value == nil {
+		return time.Time{}, nil
+	}
+	return time.UnixMilli(int64(*response.Aggregations.LastUpdated.Value)), nil
+}
+
+// translate turns a query into the query DSL: the words, prefixes and phrases
+// are scored against title and description, with titles weighing double, and
+// every filter is a non-scoring clause
+func translate(q Query) query {
+	var filters, exclusions []query
+	if q.TenantID != "" {
+		filters = append(filters, query{"term": query{"tenant_id": q.TenantID}})
+	}
+	if !q.VisibleAt.IsZero() {
+		filters = append(filters,
+			anyOf(missing("visible_from"), query{"range": query{"visible_from": query{"lte": q.VisibleAt.UTC()}}}),
+			anyOf(missing("expires_at"), query{"range": query{"expires_at": query{"gt": q.VisibleAt.UTC()}}}),
+		)
+	}
+	if q.ExcludePaywalled {
+		exclusions = append(exclusions, query{"terms": query{"access": []string{models.AccessPaywalled, models.AccessConsentWall}}})
+	}
+	if q.SafeStrict {
+		filters = append(filters, query{"term": query{"content_rating": llm.RatingSafe}})
+	} else if q.ExcludeExplicit {
+		exclusions = append(exclusions, query{"term": query{"content_rating": llm.RatingExplicit}})
+	}
+	published := query{}
+	if !q.PublishedFrom.IsZero() {
+		published["gte"] = q.PublishedFrom.UTC()
+	}
+	if !q.PublishedTo.IsZero() {
+		published["lt"] = q.PublishedTo.UTC()
+	}
+	if len(published) > 0 {
+		filters = append(filters, query{"range": query{"publication_date": published}})
+	}
+	if q.State != "" {
+		filters = append(filters, query{"term": query{"state": q.State}})
+	}
+	if len(q.Categories) > 0 {
+		matches := make([]query, len(q.Categories))
+		for i, category := range q.Categories {
+			matches[i] = contains("category", category)
+		}
+		filters = append(filters, anyOf(matches...))
+	}
+	if q.Source != "" {
+		filters = append(filters, contains("source_name", q.Source))
+	}
+	if q.Near != nil && q.RadiusKm > 0 {
+		filters = append(filters, query{"geo_distance": query{
+			"distance": fmt.Sprintf("%gkm", q.RadiusKm),
+			"location": query{"lat": q.Near.Latitude, "lon": q.Near.Longitude},
+		}})
+	}
+
+	fields := []string{"title^2", "description"}
+	var words []string
+	var matches []query
+	for _, word := range q.Words {
+		if prefix := strings.TrimSuffix(word, "*"); prefix != word {
+			matches = append(matches, query{"multi_match": query{"query": prefix, "type": "phrase_prefix", "fields": fields}})
+		} else {
+			words = append(words, word)
+		}
+	}
+	if len(words) > 0 {
+		matches = append(matches, query{"multi_match": query{"query": strings.Join(words, " "), "fields": fields}})
+	}
+	for _, phrase := range q.Phrases {
+		matches = append(matches, query{"multi_match": query{"query": phrase, "type": "phrase", "fields": fields}})
+	}
+
+	clauses := query{"must": anyOf(matches...)}
+	if len(filters) > 0 {
+		clauses["filter"] = filters
+	}
+	if len(exclusions) > 0 {
+		clauses["must_not"] = exclusions
+	}
+	return query{"bool": clauses}
+}
+
+// anyOf matches documents matching any of the clauses
+func anyOf(clauses ...query) query {
+	return query{"bool": query{"should": clauses, "minimum_should_match": 1}}
+}
+
+// missing matches documents without a value for the field
+func missing(field string) query {
+	return query{"bool": query{"must_not": query{"exists": query{"field": field}}}}
+}
+
+// contains matches documents whose keyword field contains text, like the
+// LIKE '%text%' filters of the database queries
+func contains(field, text string) query {
+	escaped := strings.NewReplacer(`\`, `\\`, "*", `\*`, "?", `\?`).Replace(strings.ToLower(text))
+	return query{"wildcard": query{field: query{"value": "*" + escaped + "*"}}}
+}
+
+// expect sends a request and decodes its JSON response into result, failing
+// on statuses other than 2xx
+func (b *elasticsearch) expect(ctx context.Context, method, path, contentType string, body []byte, result interface{}) error {
+	status, response, err := b.do(ctx, method, path, contentType, body)
+	if err != nil {
+		return err
+	}
+	if status < 200 || status > 299 {
+		if len(response) > 300 {
+			response = response[:300]
+		}
+		return fmt.Errorf("%s %s returned status %d: %s", method, path, status, bytes.TrimSpace(response))
+	}
+	if result == nil {
+		return nil
+	}
+	return json.Unmarshal(response, result)
+}
+
+// do sends a request to the cluster, returning its status and body
+func (b *elasticsearch) do(ctx context.Context, method, path, contentType string, body []byte) (int, []byte, error) {
+	req, err := http.NewRequestWithContext(ctx, method, b.baseURL+path, bytes.NewReader(body))
+	if err != nil {
+		return 0, nil, err
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if b.username != "" {
+		req.SetBasicAuth(b.username, b.password)
+	}
+	resp, err := b.http.Do(req)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer resp.Body.Close()
+	response, err := io.ReadAll(io.LimitReader(resp.Body, 32<<20))
+	if err != nil {
+		return 0, nil, err
+	}
+	return resp.StatusCode, response, nil
+}
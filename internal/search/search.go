@@ -0,0 +1,122 @@
+// Package search finds articles matching keyword queries through pluggable
+// search engines, for deployments whose corpus outgrows LIKE matching in
+// SQLite. Backends register a factory under a name, and the server creates
+// the one SEARCH_BACKEND names at startup. The database stays the source of
+// truth: backends hold a copy of the searchable fields and return article IDs,
+// which are loaded and ranked from the database.
+package search
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Document is the searchable copy of an article
+type Document struct {
+	ID              string
+	TenantID        string
+	Title           string
+	Description     string
+	Category        []string
+	SourceName      string
+	PublicationDate time.Time
+	Location        *Point // Nil for articles without usable coordinates
+	Access          string
+	ContentRating   string
+	State           string
+	VisibleFrom     *time.Time
+	ExpiresAt       *time.Time
+	UpdatedAt       time.Time // The article's, so indexing can resume where it stopped
+}
+
+// Point is a latitude and longitude in degrees
+type Point struct {
+	Latitude  float64
+	Longitude float64
+}
+
+// Query is a keyword query with the filters of the endpoint serving it. Zero
+// fields don't filter.
+type Query struct {
+	Words            []string // Matched against title and description, any word or phrase matching; a trailing * matches as a prefix
+	Phrases          []string // Matched as consecutive words
+	TenantID         string
+	VisibleAt        time.Time // Keep articles past their embargo and not expired at this time
+	ExcludePaywalled bool
+	SafeStrict       bool // Keep only articles rated safe
+	ExcludeExplicit  bool // Drop articles rated explicit
+	PublishedFrom    time.Time
+	PublishedTo      time.Time // Exclusive
+	State            string
+	Categories       []string // Substrings of a category, case-insensitive, any matching
+	Source           string   // Substring of the source name, case-insensitive
+	Near             *Point
+	RadiusKm         float64 // Around Near
+	Limit            int     // Most IDs returned, best matches first
+}
+
+// Backend indexes articles and answers keyword queries
+type Backend interface {
+	// EnsureIndex creates the index with its mapping unless it exists,
+	// reporting whether it was created
+	EnsureIndex(ctx context.Context) (bool, error)
+	// Index adds or replaces documents in bulk
+	Index(ctx context.Context, docs []Document) error
+	// Delete removes documents by article ID; missing ones are ignored
+	Delete(ctx context.Context, ids []string) error
+	// Search returns the IDs of the documents matching the query
+	Search(ctx context.Context, query Query) ([]string, error)
+	// LastUpdated is the latest UpdatedAt indexed, zero for an empty index
+	LastUpdated(ctx context.Context) (time.Time, error)
+}
+
+// Options configure a backend. Backends ignore the options they don't use.
+type Options struct {
+	URL      string
+	Index    string
+	Username string
+	Password string
+}
+
+// Factory creates a backend from its options
+type Factory func(options Options) (Backend, error)
+
+var (
+	mu        sync.RWMutex
+	factories = map[string]Factory{}
+)
+
+// Register makes a backend available under a name, replacing any registered
+// under the same name
+func Register(name string, factory Factory) {
+	mu.Lock()
+	defer mu.Unlock()
+	factories[strings.ToLower(name)] = factory
+}
+
+// New creates the backend registered under name
+func New(name string, options Options) (Backend, error) {
+	mu.RLock()
+	factory, ok := factories[strings.ToLower(name)]
+	mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown search backend %q, expected one of %s", name, strings.Join(Names(), ", "))
+	}
+	return factory(options)
+}
+
+// Names lists the registered backends
+func Names() []string {
+	mu.RLock()
+	defer mu.RUnlock()
+	names := make([]string, 0, len(factories))
+	for name := range factories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
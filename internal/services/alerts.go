@@ -0,0 +1,122 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/mahigadamsetty/Inshorts-task/internal/models"
+	"gorm.io/gorm"
+)
+
+// AlertPayload is the JSON body posted to a geofence's webhook
+type AlertPayload struct {
+	AlertID      uint            `json:"alert_id"`
+	Kind         string          `json:"kind"`
+	GeofenceID   uint            `json:"geofence_id"`
+	GeofenceName string          `json:"geofence_name"`
+	Article      *models.Article `json:"article,omitempty"`
+	EventCount   int             `json:"event_count,omitempty"`
+	Baseline     float64         `json:"baseline,omitempty"`
+	TriggeredAt  time.Time       `json:"triggered_at"`
+}
+
+// enqueueAlert writes the outbox message delivering a new alert, to the
+// fence's webhook or, for subscriptions made through the Telegram bot, its
+// chat, as part of tx
+func (s *Services) enqueueAlert(tx *gorm.DB, fence *models.Geofence, alert *models.GeofenceAlert) error {
+	payload := AlertPayload{
+		AlertID:      alert.ID,
+		Kind:         alert.Kind,
+		GeofenceID:   fence.ID,
+		GeofenceName: fence.Name,
+		EventCount:   alert.EventCount,
+		Baseline:     alert.Baseline,
+		TriggeredAt:  alert.CreatedAt,
+	}
+	if alert.ArticleID != "" {
+		var article models.Article
+		if err := tx.Where("id = ?", alert.ArticleID).First(&article).Error; err == nil {
+			article.LimitCoordinates(s.tenantCoordinates(fence.TenantID))
+			payload.Article = &article
+		}
+	}
+
+	message := &models.OutboxMessage{
+		Subject:   models.SubjectGeofenceAlert,
+		SubjectID: alert.ID,
+		TenantID:  fence.TenantID,
+	}
+	if fence.TelegramChatID != 0 {
+		message.Channel = models.ChannelTelegram
+		message.Target = strconv.FormatInt(fence.TelegramChatID, 10)
+		message.Body = telegramAlertText(payload)
+	} else {
+		body, err := json.Marshal(payload)
+		if err != nil {
+			return err
+		}
+		message.Channel = models.ChannelWebhook
+		message.Target = fence.WebhookURL
+		message.Headers = map[string]string{"X-Alert-ID": fmt.Sprint(alert.ID)}
+		message.Body = string(body)
+		message.Secret = fence.Secret
+	}
+	return EnqueueOutbox(tx, message)
+}
+
+// mirrorAlertDelivery copies a message's delivery state to its alert
+func mirrorAlertDelivery(tx *gorm.DB, message *models.OutboxMessage) error {
+	status := models.AlertPending
+	switch message.Status {
+	case models.OutboxDelivered:
+		status = models.AlertDelivered
+	case models.OutboxDead:
+		status = models.AlertFailed
+	}
+	return tx.Model(&models.GeofenceAlert{}).Where("id = ?", message.SubjectID).Updates(map[string]interface{}{
+		"status":       status,
+		"attempts":     message.Attempts,
+		"last_error":   message.LastError,
+		"delivered_at": message.DeliveredAt,
+	}).Error
+}
+
+// enqueueLegacyAlerts writes outbox messages for pending alerts raised before
+// alerts were delivered through the outbox
+func (s *Services) enqueueLegacyAlerts(ctx context.Context) error {
+	database := s.db.WithContext(ctx)
+	var alerts []models.GeofenceAlert
+	err := database.
+		Where("status = ?", models.AlertPending).
+		Where("NOT EXISTS (SELECT 1 FROM outbox_messages WHERE subject = ? AND subject_id = geofence_alerts.id)", models.SubjectGeofenceAlert).
+		Order("id").
+		Find(&alerts).Error
+	if err != nil || len(alerts) == 0 {
+		return err
+	}
+
+	err = database.Transaction(func(tx *gorm.DB) error {
+		for i := range alerts {
+			var fence models.Geofence
+			if err := tx.First(&fence, alerts[i].GeofenceID).Error; err != nil {
+				// The fence was deleted after the alert was raised
+				err = tx.Model(&alerts[i]).Updates(map[string]interface{}{"status": models.AlertFailed, "last_error": "geofence no longer exists"}).Error
+				if err != nil {
+					return err
+				}
+				continue
+			}
+			if err := s.enqueueAlert(tx, &fence, &alerts[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err == nil {
+		WakeOutbox()
+	}
+	return err
+}
@@ -0,0 +1,123 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/mahigadamsetty/Inshorts-task/internal/apperr"
+	"github.com/mahigadamsetty/Inshorts-task/internal/db"
+	"github.com/mahigadamsetty/Inshorts-task/internal/models"
+)
+
+// ArticleEdit is an editor's correction of an article. Fields left out are
+// kept as stored.
+type ArticleEdit struct {
+	Title       *string   `json:"title"`
+	Description *string   `json:"description"`
+	Category    *[]string `json:"category"`
+	Latitude    *float64  `json:"latitude"` // Both or neither
+	Longitude   *float64  `json:"longitude"`
+}
+
+// validate checks an edit, returning an ErrInvalidFilter describing the
+// first problem
+func (e *ArticleEdit) validate() error {
+	if e.Title == nil && e.Description == nil && e.Category == nil && e.Latitude == nil && e.Longitude == nil {
+		return apperr.InvalidFilterf("nothing to edit; set title, description, category or latitude and longitude")
+	}
+	if e.Title != nil {
+		title := strings.TrimSpace(*e.Title)
+		switch {
+		case title == "":
+			return apperr.InvalidFilterf("title must not be empty")
+		case len(title) > maxPublishedTitle:
+			return apperr.InvalidFilterf("title must be at most %d bytes", maxPublishedTitle)
+		}
+		e.Title = &title
+	}
+	if e.Description != nil {
+		description := strings.TrimSpace(*e.Description)
+		if len(description) > maxPublishedDescription {
+			return apperr.InvalidFilterf("description must be at most %d bytes", maxPublishedDescription)
+		}
+		e.Description = &description
+	}
+	if e.Category != nil {
+		if len(*e.Category) > maxPublishedCategories {
+			return apperr.InvalidFilterf("at most %d categories are allowed", maxPublishedCategories)
+		}
+		categories := make([]string, 0, len(*e.Category))
+		for _, category := range *e.Category {
+			if category = strings.TrimSpace(category); category == "" {
+				return apperr.InvalidFilterf("categories must not be empty")
+			}
+			categories = append(categories, category)
+		}
+		e.Category = &categories
+	}
+	if (e.Latitude == nil) != (e.Longitude == nil) {
+		return apperr.InvalidFilterf("latitude and longitude must be given together")
+	}
+	if e.Latitude != nil && !models.ValidCoordinates(*e.Latitude, *e.Longitude) {
+		return apperr.InvalidFilterf("latitude %g and longitude %g are not valid coordinates", *e.Latitude, *e.Longitude)
+	}
+	return nil
+}
+
+// EditArticle applies an editor's correction to an article of any tenant and
+// drops what was derived from the old values: trending caches are
+// invalidated, and new text resets the content rating, marks the summary
+// stale and removes the embedding, so moderation, the summarizer and topic
+// clustering redo them. The search index picks the change up on its next run.
+func (s *Services) EditArticle(ctx context.Context, id string, edit ArticleEdit) (*models.Article, error) {
+	database := s.db.WithContext(db.IncludeHidden(ctx))
+	if database == nil {
+		return nil, fmt.Errorf("database not initialized")
+	}
+	if err := edit.validate(); err != nil {
+		return nil, err
+	}
+	var article models.Article
+	if err := database.Where("id = ?", id).First(&article).Error; err != nil {
+		return nil, apperr.NotFound(err, "Article")
+	}
+
+	textChanged := false
+	if edit.Title != nil && *edit.Title != article.Title {
+		article.Title = *edit.Title
+		article.TitleKey = TitleKey(article.Title)
+		textChanged = true
+	}
+	if edit.Description != nil && *edit.Description != article.Description {
+		article.Description = *edit.Description
+		textChanged = true
+	}
+	if edit.Category != nil {
+		article.Category = models.StringArray(s.CanonicalCategories(ctx, *edit.Category))
+	}
+	if edit.Latitude != nil {
+		article.Latitude, article.Longitude = *edit.Latitude, *edit.Longitude
+		ValidateLocation(&article, false)
+	}
+	if err := checkDuplicate(database, &article); err != nil {
+		return nil, err
+	}
+	if textChanged {
+		article.ContentRating = ""
+		article.SafetyTags = nil
+		article.SummaryStale = article.LLMSummary != ""
+		article.SummaryShort, article.SummaryLong = "", ""
+	}
+
+	if err := database.Save(&article).Error; err != nil {
+		return nil, err
+	}
+	if textChanged {
+		if err := database.Where("article_id = ?", article.ID).Delete(&models.ArticleEmbedding{}).Error; err != nil {
+			return nil, err
+		}
+	}
+	s.InvalidateTrendingCache(ctx)
+	return &article, nil
+}
@@ -0,0 +1,247 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"mime"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/mahigadamsetty/Inshorts-task/internal/apperr"
+	"github.com/mahigadamsetty/Inshorts-task/internal/blob"
+	"github.com/mahigadamsetty/Inshorts-task/internal/models"
+	"github.com/mahigadamsetty/Inshorts-task/internal/tts"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// speechWordsPerMinute estimates how long a spoken summary lasts
+const speechWordsPerMinute = 150
+
+// audioFilePattern matches the names audio files are stored under: the hash
+// of the voice and summary, and the format's extension
+var audioFilePattern = regexp.MustCompile(`^[0-9a-f]{64}\.[a-z0-9]{1,5}$`)
+
+// AudioOptions are the provider that reads summaries aloud and the store
+// their files are kept in. A nil provider disables summary audio.
+type AudioOptions struct {
+	Provider tts.Provider
+	Store    blob.Store
+}
+
+// AudioResult counts the work of an audio pass
+type AudioResult struct {
+	Synthesized int `json:"synthesized"`
+	Failed      int `json:"failed"`
+	Removed     int `json:"removed"` // Files no article uses any more
+}
+
+// SynthesizeSummaries reads up to limit summaries aloud that have no audio
+// yet, or whose summary or voice changed since, newest articles first. Stale
+// summaries wait until they are rewritten. Files no article uses any more,
+// after a summary changed or articles were purged, are removed.
+func (s *Services) SynthesizeSummaries(ctx context.Context, limit int) (*AudioResult, error) {
+	result := &AudioResult{}
+	if s.audio.Provider == nil {
+		return result, nil
+	}
+	database := s.db.WithContext(ctx)
+	if database == nil {
+		return nil, fmt.Errorf("database not initialized")
+	}
+	var candidates []struct {
+		ID          string
+		LLMSummary  string
+		SummaryHash string
+	}
+	err := database.Model(&models.Article{}).
+		Select("articles.id, articles.llm_summary, article_audio.summary_hash").
+		Joins("LEFT JOIN article_audio ON article_audio.article_id = articles.id").
+		Where("articles.llm_summary <> '' AND articles.summary_stale = ?", false).
+		Order("articles.publication_date DESC").
+		Order("articles.id").
+		Scan(&candidates).Error
+	if err != nil {
+		return nil, err
+	}
+
+	voice := s.audio.Provider.Voice()
+	var lastErr error
+	for _, candidate := range candidates {
+		if result.Synthesized+result.Failed >= limit || ctx.Err() != nil {
+			break
+		}
+		hash := summaryAudioHash(voice, candidate.LLMSummary)
+		if hash == candidate.SummaryHash {
+			continue
+		}
+		if err := s.synthesizeSummary(ctx, database, candidate.ID, candidate.LLMSummary, hash); err != nil {
+			log.Printf("Failed to synthesize audio for article %s: %v", candidate.ID, err)
+			result.Failed++
+			lastErr = err
+			continue
+		}
+		result.Synthesized++
+	}
+	if result.Failed > 0 && result.Synthesized == 0 {
+		return result, fmt.Errorf("audio synthesis failed for %d summaries: %w", result.Failed, lastErr)
+	}
+
+	removed, err := s.removeUnusedAudio(ctx, database)
+	result.Removed = removed
+	return result, err
+}
+
+// synthesizeSummary stores the audio of an article's summary, reusing the
+// file of another article with the same summary
+func (s *Services) synthesizeSummary(ctx context.Context, database *gorm.DB, id, summary, hash string) error {
+	audioProvider, audioStore := s.audio.Provider, s.audio.Store
+	name := hash + "." + audioProvider.Format()
+	object, err := audioStore.Stat(ctx, audioStore.URL(name))
+	if errors.Is(err, blob.ErrNotExist) {
+		var audio []byte
+		if audio, err = audioProvider.Synthesize(ctx, summary); err != nil {
+			return err
+		}
+		contentType := mime.TypeByExtension("." + audioProvider.Format())
+		if contentType == "" {
+			contentType = "audio/" + audioProvider.Format()
+		}
+		if _, err = audioStore.Put(ctx, name, bytes.NewReader(audio), int64(len(audio)), contentType); err != nil {
+			return err
+		}
+		object.Size = int64(len(audio))
+	}
+	if err != nil {
+		return err
+	}
+
+	minutes := float64(len(strings.Fields(summary))) / speechWordsPerMinute
+	record := models.ArticleAudio{
+		ArticleID:       id,
+		File:            name,
+		SummaryHash:     hash,
+		Voice:           audioProvider.Voice(),
+		Bytes:           object.Size,
+		DurationSeconds: math.Round(minutes*60*10) / 10,
+	}
+	return database.Clauses(clause.OnConflict{UpdateAll: true}).Create(&record).Error
+}
+
+// removeUnusedAudio deletes the audio files no article refers to
+func (s *Services) removeUnusedAudio(ctx context.Context, database *gorm.DB) (int, error) {
+	audioStore := s.audio.Store
+	var used []string
+	if err := database.Model(&models.ArticleAudio{}).Distinct("file").Pluck("file", &used).Error; err != nil {
+		return 0, err
+	}
+	keep := make(map[string]bool, len(used))
+	for _, name := range used {
+		keep[name] = true
+	}
+
+	objects, err := audioStore.List(ctx)
+	if err != nil {
+		return 0, err
+	}
+	removed := 0
+	for _, object := range objects {
+		if !audioFilePattern.MatchString(object.Name) || keep[object.Name] {
+			continue
+		}
+		if err := audioStore.Delete(ctx, object.URL); err != nil {
+			return removed, err
+		}
+		removed++
+	}
+	return removed, nil
+}
+
+// summaryAudioHash identifies the audio of a summary read in a voice
+func summaryAudioHash(voice, summary string) string {
+	sum := sha256.Sum256([]byte(voice + "\n" + summary))
+	return hex.EncodeToString(sum[:])
+}
+
+// AttachAudio sets the audio URL of the articles whose summary has been read
+// aloud, as the file's name under baseURL. Audio reads the stored summary,
+// whatever summary length was asked for.
+func (s *Services) AttachAudio(ctx context.Context, articles []models.Article, baseURL string) {
+	if s.audio.Provider == nil || len(articles) == 0 {
+		return
+	}
+	var records []models.ArticleAudio
+	err := s.db.WithContext(ctx).Select("article_id, file").
+		Where("article_id IN ?", []string(articleIDs(articles))).
+		Find(&records).Error
+	if err != nil {
+		log.Printf("Failed to load summary audio: %v", err)
+		return
+	}
+	files := make(map[string]string, len(records))
+	for _, record := range records {
+		files[record.ArticleID] = record.File
+	}
+	for i := range articles {
+		if file, ok := files[articles[i].ID]; ok {
+			articles[i].AudioURL = baseURL + file
+		}
+	}
+}
+
+// BriefingItem is one spoken summary of an audio briefing
+type BriefingItem struct {
+	ID              string    `json:"id"`
+	Title           string    `json:"title"`
+	SourceName      string    `json:"source_name"`
+	PublicationDate time.Time `json:"publication_date"`
+	Summary         string    `json:"summary"`
+	File            string    `json:"-"`
+	AudioURL        string    `json:"audio_url"`
+	DurationSeconds float64   `json:"duration_seconds"`
+}
+
+// AudioBriefing returns the newest articles of a category, matched as by the
+// category endpoint, whose summaries have been read aloud, in playing order
+func (s *Services) AudioBriefing(ctx context.Context, category string, filter ArticleFilter, limit int, baseURL string) ([]BriefingItem, error) {
+	database := s.db.WithContext(ctx)
+	if database == nil {
+		return nil, fmt.Errorf("database not initialized")
+	}
+	if category = strings.TrimSpace(category); category == "" {
+		return nil, apperr.InvalidFilterf("category is required")
+	}
+
+	items := []BriefingItem{}
+	err := database.Model(&models.Article{}).
+		Select("articles.id, articles.title, articles.source_name, articles.publication_date, articles.llm_summary AS summary, article_audio.file, article_audio.duration_seconds").
+		Joins("JOIN article_audio ON article_audio.article_id = articles.id").
+		Scopes(filter.Scope).
+		Where(s.CategoryMatch(ctx, category)).
+		Order("articles.publication_date DESC").
+		Order("articles.id").
+		Limit(limit).
+		Scan(&items).Error
+	if err != nil {
+		return nil, err
+	}
+	for i := range items {
+		items[i].AudioURL = baseURL + items[i].File
+	}
+	return items, nil
+}
+
+// AudioFile opens a stored audio file by name for serving
+func (s *Services) AudioFile(ctx context.Context, name string) (*BlobFile, error) {
+	if s.audio.Provider == nil || !audioFilePattern.MatchString(name) {
+		return nil, apperr.NotFoundf("Audio file not found")
+	}
+	return openBlob(ctx, s.audio.Store, s.audio.Store.URL(name), "Audio file")
+}
@@ -0,0 +1,276 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/mahigadamsetty/Inshorts-task/internal/apperr"
+	"github.com/mahigadamsetty/Inshorts-task/internal/blob"
+	"github.com/mahigadamsetty/Inshorts-task/internal/models"
+)
+
+const (
+	maxArticleTextBytes = 8 << 20
+	maxImageBytes       = 5 << 20
+	blobCleanupGrace    = time.Hour // Younger blobs may belong to a write not committed yet
+)
+
+// imageTypes are the preview image formats that are copied, by content type.
+// SVG is left out since it can carry scripts.
+var imageTypes = map[string]string{
+	"image/jpeg": "jpg",
+	"image/png":  "png",
+	"image/gif":  "gif",
+	"image/webp": "webp",
+	"image/avif": "avif",
+}
+
+// imageFilePattern matches the names images are stored under: the hash of
+// their content and the format's extension
+var imageFilePattern = regexp.MustCompile(`^[0-9a-f]{64}\.(jpg|png|gif|webp|avif)$`)
+
+// BlobStores are the stores article text and preview images are kept in.
+// Text already moved to a store is read from it even when StoreText is off.
+type BlobStores struct {
+	Text        blob.Store
+	Images      blob.Store
+	StoreText   bool // Keep fetched text in Text instead of the articles table
+	StoreImages bool // Copy preview images to Images
+}
+
+// BlobFile is a stored file to serve: a link to send the client to, or the
+// content to stream when the store has no links
+type BlobFile struct {
+	Link    string
+	Content io.ReadCloser
+	Size    int64
+}
+
+// openBlob opens a blob for serving, reporting missing ones as not found
+func openBlob(ctx context.Context, store blob.Store, ref, what string) (*BlobFile, error) {
+	object, err := store.Stat(ctx, ref)
+	if errors.Is(err, blob.ErrNotExist) {
+		return nil, apperr.NotFoundf("%s not found", what)
+	}
+	if err != nil {
+		return nil, err
+	}
+	link, err := store.Link(ctx, ref)
+	if err != nil || link != "" {
+		return &BlobFile{Link: link, Size: object.Size}, err
+	}
+	content, err := store.Open(ctx, ref)
+	if errors.Is(err, blob.ErrNotExist) {
+		return nil, apperr.NotFoundf("%s not found", what)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &BlobFile{Content: content, Size: object.Size}, nil
+}
+
+// articleText returns an article's fetched text, reading it from the text
+// store when it was moved there. A text that can't be read is logged and
+// treated as missing, so callers fall back to the description.
+func (s *Services) articleText(ctx context.Context, article models.Article) string {
+	if article.TextContent != "" || article.TextBlobURL == "" || s.blobs.Text == nil {
+		return article.TextContent
+	}
+	text, err := blob.ReadAll(ctx, s.blobs.Text, article.TextBlobURL, maxArticleTextBytes)
+	if err != nil {
+		log.Printf("Failed to read the text of article %s: %v", article.ID, err)
+		return ""
+	}
+	return string(text)
+}
+
+// textUpdates are the column updates storing an article's fetched text: in
+// the text store when it is enabled, falling back to the articles table when
+// the store fails
+func (s *Services) textUpdates(ctx context.Context, id, text string) map[string]interface{} {
+	if s.blobs.StoreText {
+		ref, err := s.blobs.Text.Put(ctx, url.PathEscape(id)+".txt", strings.NewReader(text), int64(len(text)), "text/plain; charset=utf-8")
+		if err == nil {
+			return map[string]interface{}{"text_blob_url": ref, "text_content": ""}
+		}
+		log.Printf("Failed to store the text of article %s, keeping it in the database: %v", id, err)
+	}
+	return map[string]interface{}{"text_content": text, "text_blob_url": ""}
+}
+
+// OffloadArticleTexts moves up to limit fetched texts still held in the
+// articles table to the text store, when it is enabled
+func (s *Services) OffloadArticleTexts(ctx context.Context, limit int) (int, error) {
+	if !s.blobs.StoreText {
+		return 0, nil
+	}
+	database := s.db.WithContext(ctx)
+	if database == nil {
+		return 0, fmt.Errorf("database not initialized")
+	}
+	var articles []models.Article
+	err := database.Select("id, text_content").Where("text_content <> ''").Order("id").Limit(limit).Find(&articles).Error
+	if err != nil {
+		return 0, err
+	}
+	moved := 0
+	for _, article := range articles {
+		updates := s.textUpdates(ctx, article.ID, article.TextContent)
+		if updates["text_blob_url"] == "" {
+			return moved, fmt.Errorf("text store failed")
+		}
+		if err := database.Model(&models.Article{ID: article.ID}).UpdateColumns(updates).Error; err != nil {
+			return moved, err
+		}
+		moved++
+	}
+	return moved, nil
+}
+
+// storedImage returns the URL of the stored copy of an article's preview
+// image, downloading it through the crawler unless it is the image already
+// stored. Failures are logged and keep the previous copy when the image did
+// not change.
+func (s *Services) storedImage(ctx context.Context, article models.Article, imageURL string) string {
+	if imageURL == "" {
+		return ""
+	}
+	if imageURL == article.ImageURL && article.ImageBlobURL != "" {
+		return article.ImageBlobURL
+	}
+	ref, err := s.storeImage(ctx, article.SourceName, imageURL)
+	if err != nil {
+		log.Printf("Failed to store image %s of article %s: %v", imageURL, article.ID, err)
+		if imageURL == article.ImageURL {
+			return article.ImageBlobURL
+		}
+		return ""
+	}
+	return ref
+}
+
+// storeImage downloads an image and stores it under the hash of its content,
+// so articles sharing an image share its blob
+func (s *Services) storeImage(ctx context.Context, sourceName, imageURL string) (string, error) {
+	target, err := url.Parse(imageURL)
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") {
+		return "", fmt.Errorf("not an http or https URL")
+	}
+	resp, err := s.crawler.Fetch(ctx, sourceName, target)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("status code %d", resp.StatusCode)
+	}
+	contentType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	ext, ok := imageTypes[contentType]
+	if !ok {
+		return "", fmt.Errorf("unsupported content type %q", contentType)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxImageBytes+1))
+	if err != nil {
+		return "", err
+	}
+	if len(data) > maxImageBytes {
+		return "", fmt.Errorf("larger than %d bytes", maxImageBytes)
+	}
+
+	sum := sha256.Sum256(data)
+	name := hex.EncodeToString(sum[:]) + "." + ext
+	imageStore := s.blobs.Images
+	if _, err := imageStore.Stat(ctx, imageStore.URL(name)); err == nil {
+		return imageStore.URL(name), nil
+	}
+	return imageStore.Put(ctx, name, bytes.NewReader(data), int64(len(data)), contentType)
+}
+
+// AttachImages points the image URL of articles with a stored preview image
+// at their copy, as the file's name under baseURL
+func AttachImages(articles []models.Article, baseURL string) {
+	for i := range articles {
+		if articles[i].ImageBlobURL != "" {
+			articles[i].ImageURL = baseURL + path.Base(articles[i].ImageBlobURL)
+		}
+	}
+}
+
+// ImageFile opens a stored preview image by name for serving
+func (s *Services) ImageFile(ctx context.Context, name string) (*BlobFile, error) {
+	if s.blobs.Images == nil || !imageFilePattern.MatchString(name) {
+		return nil, apperr.NotFoundf("Image not found")
+	}
+	return openBlob(ctx, s.blobs.Images, s.blobs.Images.URL(name), "Image")
+}
+
+// BlobCleanupResult counts the blobs a cleanup removed
+type BlobCleanupResult struct {
+	Texts  int `json:"texts"`
+	Images int `json:"images"`
+}
+
+// CleanupBlobs removes stored texts and images no article refers to any
+// more, after articles were purged or merged or their image changed
+func (s *Services) CleanupBlobs(ctx context.Context) (*BlobCleanupResult, error) {
+	result := &BlobCleanupResult{}
+	database := s.db.WithContext(ctx)
+	if database == nil {
+		return nil, fmt.Errorf("database not initialized")
+	}
+	var err error
+	if s.blobs.Text != nil {
+		if result.Texts, err = s.removeUnreferenced(ctx, s.blobs.Text, "text_blob_url"); err != nil {
+			return result, err
+		}
+	}
+	if s.blobs.Images != nil {
+		if result.Images, err = s.removeUnreferenced(ctx, s.blobs.Images, "image_blob_url"); err != nil {
+			return result, err
+		}
+	}
+	return result, nil
+}
+
+// removeUnreferenced deletes the blobs of a store that no article's column
+// refers to, sparing recent ones
+func (s *Services) removeUnreferenced(ctx context.Context, store blob.Store, column string) (int, error) {
+	objects, err := store.List(ctx)
+	if err != nil || len(objects) == 0 {
+		return 0, err
+	}
+	var used []string
+	if err := s.db.WithContext(ctx).Model(&models.Article{}).Where(column+" <> ''").Distinct(column).Pluck(column, &used).Error; err != nil {
+		return 0, err
+	}
+	keep := make(map[string]bool, len(used))
+	for _, ref := range used {
+		keep[ref] = true
+	}
+
+	removed := 0
+	cutoff := time.Now().Add(-blobCleanupGrace)
+	for _, object := range objects {
+		if keep[object.URL] || object.Modified.After(cutoff) {
+			continue
+		}
+		if err := store.Delete(ctx, object.URL); err != nil {
+			return removed, err
+		}
+		removed++
+	}
+	return removed, nil
+}
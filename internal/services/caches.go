@@ -0,0 +1,38 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/mahigadamsetty/Inshorts-task/internal/cache"
+)
+
+// getCached decodes the value cached under key into v when one younger than
+// maxAge is there. Failures of the store are logged and count as misses, so
+// callers recompute.
+func getCached(ctx context.Context, store cache.Store, key string, maxAge time.Duration, v interface{}) bool {
+	err := cache.GetValue(ctx, store, key, maxAge, v)
+	if err != nil && !errors.Is(err, cache.ErrMiss) {
+		log.Printf("Failed to read cache entry %s: %v", key, err)
+	}
+	return err == nil
+}
+
+// setCached caches v under key until keep has passed, logging failures
+func setCached(ctx context.Context, store cache.Store, key string, v interface{}, keep time.Duration) {
+	if err := cache.SetValue(ctx, store, key, v, keep); err != nil {
+		log.Printf("Failed to write cache entry %s: %v", key, err)
+	}
+}
+
+// dropCached removes every entry whose key starts with prefix and returns how
+// many were removed
+func dropCached(ctx context.Context, store cache.Store, prefix string) int {
+	dropped, err := store.DeletePrefix(ctx, prefix)
+	if err != nil {
+		log.Printf("Failed to drop cache entries %s*: %v", prefix, err)
+	}
+	return dropped
+}
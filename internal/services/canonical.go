@@ -0,0 +1,271 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"unicode"
+
+	"github.com/mahigadamsetty/Inshorts-task/internal/hll"
+	"github.com/mahigadamsetty/Inshorts-task/internal/models"
+	"gorm.io/gorm"
+)
+
+// trackingParams are query parameters that identify a campaign or click rather
+// than a page. Parameters starting with utm_ are dropped too.
+var trackingParams = map[string]bool{
+	"fbclid": true, "gclid": true, "dclid": true, "msclkid": true, "yclid": true, "igshid": true,
+	"mc_cid": true, "mc_eid": true, "_ga": true, "ref": true, "ref_src": true, "cmpid": true, "ito": true,
+}
+
+// CanonicalURL normalizes an article URL so the same page imported from
+// different dumps or feeds compares equal: the scheme and host are lowercased,
+// default ports, fragments, tracking parameters and trailing slashes are
+// dropped, and the remaining parameters are sorted.
+func CanonicalURL(raw string) (string, error) {
+	parsed, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil {
+		return "", err
+	}
+	parsed.Scheme = strings.ToLower(parsed.Scheme)
+	if (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return "", fmt.Errorf("not an http(s) URL: %q", raw)
+	}
+
+	host := strings.ToLower(parsed.Hostname())
+	if port := parsed.Port(); port != "" && !(parsed.Scheme == "http" && port == "80") && !(parsed.Scheme == "https" && port == "443") {
+		host += ":" + port
+	}
+	parsed.Host = host
+	parsed.User = nil
+	parsed.Fragment, parsed.RawFragment = "", ""
+
+	query := parsed.Query()
+	for name := range query {
+		if trackingParams[strings.ToLower(name)] || strings.HasPrefix(strings.ToLower(name), "utm_") {
+			query.Del(name)
+		}
+	}
+	parsed.RawQuery = query.Encode() // Sorted by name
+
+	if len(parsed.Path) > 1 {
+		parsed.Path = strings.TrimRight(parsed.Path, "/")
+		parsed.RawPath = ""
+	}
+	if parsed.Path == "" {
+		parsed.Path = "/"
+	}
+	return parsed.String(), nil
+}
+
+// CanonicalArticleURL returns the canonical form of an article's URL, first
+// following its redirects through the crawler when resolve is set. A URL that
+// fails to resolve is canonicalized as given. Returns nil for empty or
+// invalid URLs, which are never deduplicated.
+func (s *Services) CanonicalArticleURL(ctx context.Context, sourceName, raw string, resolve bool) *string {
+	if strings.TrimSpace(raw) == "" {
+		return nil
+	}
+	if resolve {
+		if target, err := url.Parse(raw); err == nil {
+			if final, err := s.crawler.Resolve(ctx, sourceName, target); err == nil {
+				raw = final.String()
+			}
+		}
+	}
+	canonical, err := CanonicalURL(raw)
+	if err != nil {
+		return nil
+	}
+	return &canonical
+}
+
+// TitleKey normalizes a title for duplicate detection: lowercased words and
+// numbers, without punctuation
+func TitleKey(title string) string {
+	return strings.Join(strings.FieldsFunc(strings.ToLower(title), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}), " ")
+}
+
+// DedupKey identifies an article for deduplication: its canonical URL and
+// normalized title. The URL alone isn't enough, since many articles link the
+// same page, like a channel or a live blog. Returns "" without a canonical URL.
+func DedupKey(article models.Article) string {
+	if article.CanonicalURL == nil {
+		return ""
+	}
+	return *article.CanonicalURL + "\x00" + article.TitleKey
+}
+
+// DedupReport summarizes a deduplication backfill
+type DedupReport struct {
+	Canonicalized int `json:"canonicalized"` // Articles given a canonical URL
+	Groups        int `json:"groups"`        // Canonical URL and title pairs shared by more than one article
+	Merged        int `json:"merged"`        // Duplicate articles merged away
+}
+
+// DedupArticles gives every article without one a canonical URL and title key,
+// and merges articles of the same tenant that share both. The earliest
+// ingested article of a group survives; see mergeArticles for what it takes
+// from the others. With dryRun the report is computed without changing
+// anything.
+func (s *Services) DedupArticles(ctx context.Context, resolve, dryRun bool) (DedupReport, error) {
+	var report DedupReport
+	database := s.db.WithContext(ctx)
+	if database == nil {
+		return report, fmt.Errorf("database not initialized")
+	}
+
+	var articles []models.Article
+	if err := database.Order("created_at").Order("id").Find(&articles).Error; err != nil {
+		return report, err
+	}
+
+	// Group by tenant and dedup key, keeping ingestion order
+	groups := map[string][]*models.Article{}
+	var keys []string
+	canonicalized := map[string]bool{}
+	for i := range articles {
+		article := &articles[i]
+		if article.CanonicalURL == nil {
+			article.CanonicalURL = s.CanonicalArticleURL(ctx, article.SourceName, article.URL, resolve)
+			if article.CanonicalURL == nil {
+				continue
+			}
+			article.TitleKey = TitleKey(article.Title)
+			canonicalized[article.ID] = true
+			report.Canonicalized++
+		}
+		key := article.TenantID + "\x00" + DedupKey(*article)
+		if _, ok := groups[key]; !ok {
+			keys = append(keys, key)
+		}
+		groups[key] = append(groups[key], article)
+	}
+
+	for _, key := range keys {
+		group := groups[key]
+		if len(group) > 1 {
+			report.Groups++
+			report.Merged += len(group) - 1
+		}
+	}
+	if dryRun {
+		return report, nil
+	}
+
+	err := database.Transaction(func(tx *gorm.DB) error {
+		// Merge first so setting canonical URLs can't collide with a duplicate
+		for _, key := range keys {
+			if group := groups[key]; len(group) > 1 {
+				if err := mergeArticles(tx, group[0], group[1:]); err != nil {
+					return fmt.Errorf("merging duplicates of %s: %w", group[0].ID, err)
+				}
+			}
+		}
+		for _, key := range keys {
+			survivor := groups[key][0]
+			if len(groups[key]) == 1 && !canonicalized[survivor.ID] {
+				continue
+			}
+			if err := tx.Model(survivor).Select("canonical_url", "title_key", "category", "relevance_score", "latitude", "longitude", "location_source").Updates(survivor).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return DedupReport{}, err
+	}
+	var merged []string
+	for _, key := range keys {
+		for _, duplicate := range groups[key][1:] {
+			merged = append(merged, duplicate.ID)
+		}
+	}
+	s.removeFromSearch(ctx, merged)
+	return report, nil
+}
+
+// mergeArticles folds duplicates into the surviving article: categories are
+// combined, the highest relevance score and the first usable location are
+// kept, and events, view counters and alerts move to the survivor. The
+// duplicates are then deleted with their embeddings, popularity and score
+// history; stories and topics are rebuilt by their clustering jobs.
+func mergeArticles(tx *gorm.DB, survivor *models.Article, duplicates []*models.Article) error {
+	ids := make([]string, len(duplicates))
+	categories := map[string]bool{}
+	for _, category := range survivor.Category {
+		categories[category] = true
+	}
+	for i, duplicate := range duplicates {
+		ids[i] = duplicate.ID
+		for _, category := range duplicate.Category {
+			if !categories[category] {
+				categories[category] = true
+				survivor.Category = append(survivor.Category, category)
+			}
+		}
+		survivor.RelevanceScore = max(survivor.RelevanceScore, duplicate.RelevanceScore)
+		if !survivor.HasLocation() && duplicate.HasLocation() {
+			survivor.Latitude, survivor.Longitude = duplicate.Latitude, duplicate.Longitude
+			survivor.LocationSource = duplicate.LocationSource
+		}
+	}
+
+	if err := tx.Model(&models.Event{}).Where("article_id IN ?", ids).Update("article_id", survivor.ID).Error; err != nil {
+		return err
+	}
+	if err := tx.Model(&models.GeofenceAlert{}).Where("article_id IN ?", ids).Update("article_id", survivor.ID).Error; err != nil {
+		return err
+	}
+	if err := mergeViewCounts(tx, survivor, ids); err != nil {
+		return err
+	}
+	if err := tx.Where("article_id IN ?", ids).Delete(&models.ArticleEmbedding{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("article_id IN ?", ids).Delete(&models.ArticlePopularity{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("article_id IN ?", ids).Delete(&models.ScoreHistory{}).Error; err != nil {
+		return err
+	}
+	return tx.Where("id IN ?", ids).Delete(&models.Article{}).Error
+}
+
+// mergeViewCounts adds the duplicates' counters and viewer sketches to the
+// survivor's and deletes theirs
+func mergeViewCounts(tx *gorm.DB, survivor *models.Article, ids []string) error {
+	var counters []models.ArticleViews
+	if err := tx.Where("article_id IN ?", ids).Find(&counters).Error; err != nil {
+		return err
+	}
+	if len(counters) == 0 {
+		return nil
+	}
+
+	merged := models.ArticleViews{ArticleID: survivor.ID, TenantID: survivor.TenantID}
+	if err := tx.Where("article_id = ?", survivor.ID).Limit(1).Find(&merged).Error; err != nil {
+		return err
+	}
+	sketch := hll.Sketch(merged.Sketch)
+	if !sketch.Valid() {
+		sketch = hll.New()
+	}
+	for _, counter := range counters {
+		merged.Views += counter.Views
+		merged.Clicks += counter.Clicks
+		if other := hll.Sketch(counter.Sketch); other.Valid() {
+			sketch.Merge(other)
+		}
+	}
+	merged.Sketch = sketch
+	merged.UniqueViewers = int64(sketch.Estimate())
+	if err := tx.Save(&merged).Error; err != nil {
+		return err
+	}
+	return tx.Where("article_id IN ?", ids).Delete(&models.ArticleViews{}).Error
+}
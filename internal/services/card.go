@@ -0,0 +1,81 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mahigadamsetty/Inshorts-task/internal/apperr"
+	"github.com/mahigadamsetty/Inshorts-task/internal/models"
+)
+
+// cardDescriptionChars is the longest description a preview card carries;
+// unfurlers cut longer ones anyway
+const cardDescriptionChars = 200
+
+// ArticleCard is the preview of an article shown when a link to it is shared
+type ArticleCard struct {
+	ID              string    `json:"id"`
+	Title           string    `json:"title"`
+	Description     string    `json:"description"` // The summary, or the article's description until it has one
+	Image           string    `json:"image,omitempty"`
+	URL             string    `json:"url"`           // The shared page: the site's page of the article, or the original
+	CanonicalURL    string    `json:"canonical_url"` // The original article
+	SiteName        string    `json:"site_name"`
+	PublicationDate time.Time `json:"publication_date"`
+}
+
+// GetArticleCard returns the preview card of an article of the tenant carried
+// by ctx, if it passes the filter. pageURL is the page the card is shared
+// for, the original article when empty. It never waits on the LLM: articles
+// without a current summary are described by their description.
+func (s *Services) GetArticleCard(ctx context.Context, id string, filter ArticleFilter, pageURL string) (*ArticleCard, error) {
+	database := s.db.WithContext(ctx)
+	if database == nil {
+		return nil, fmt.Errorf("database not initialized")
+	}
+
+	var article models.Article
+	if err := database.Where("id = ?", id).First(&article).Error; err != nil {
+		return nil, apperr.NotFound(err, "Article")
+	}
+	articles := []models.Article{article}
+	s.AttachSourceMeta(ctx, articles)
+	if !filter.Match(articles[0]) {
+		return nil, apperr.NotFoundf("Article not found")
+	}
+
+	description := article.Description
+	if article.LLMSummary != "" && !article.SummaryStale {
+		description = article.LLMSummary
+	}
+	card := &ArticleCard{
+		ID:              article.ID,
+		Title:           article.Title,
+		Description:     ShortenText(strings.Join(strings.Fields(description), " "), cardDescriptionChars),
+		Image:           article.ImageURL,
+		URL:             pageURL,
+		CanonicalURL:    article.URL,
+		SiteName:        article.SourceName,
+		PublicationDate: article.PublicationDate,
+	}
+	if card.URL == "" {
+		card.URL = article.URL
+	}
+	return card, nil
+}
+
+// ShortenText cuts text to at most limit characters at a word boundary,
+// marking the cut with an ellipsis
+func ShortenText(text string, limit int) string {
+	runes := []rune(text)
+	if len(runes) <= limit {
+		return text
+	}
+	cut := string(runes[:limit])
+	if space := strings.LastIndex(cut, " "); space > 0 {
+		cut = cut[:space]
+	}
+	return strings.TrimRight(cut, " ,;:.") + "…"
+}
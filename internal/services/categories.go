@@ -0,0 +1,569 @@
+package services
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mahigadamsetty/Inshorts-task/internal/apperr"
+	"github.com/mahigadamsetty/Inshorts-task/internal/db"
+	"github.com/mahigadamsetty/Inshorts-task/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+//go:embed data/categories.json
+var bundledCategories []byte
+
+// categoryTreeTTL is how long a loaded hierarchy is used before it is read
+// again, so edits made through another replica show up
+const categoryTreeTTL = time.Minute
+
+// Limits on the categories of the hierarchy
+const (
+	maxCategoryName    = 64
+	maxCategoryAliases = 20
+)
+
+// CategoryInput describes a category as given in the bundled dataset or
+// through the admin API. Parent is the name or alias of the parent, empty for
+// a top-level category.
+type CategoryInput struct {
+	Name    string   `json:"name"`
+	Parent  string   `json:"parent"`
+	Aliases []string `json:"aliases"`
+}
+
+// Category validates the input on its own and builds the stored category.
+// Whether the parent exists is checked when it is saved.
+func (in CategoryInput) Category() (models.Category, error) {
+	category := models.Category{
+		Key:     models.CategoryKey(in.Name),
+		Name:    strings.TrimSpace(in.Name),
+		Parent:  models.CategoryKey(in.Parent),
+		Aliases: models.StringArray{},
+	}
+	switch {
+	case category.Key == "":
+		return category, apperr.InvalidFilterf("category name is required")
+	case len(category.Name) > maxCategoryName:
+		return category, apperr.InvalidFilterf("category names are at most %d characters", maxCategoryName)
+	case category.Parent == category.Key:
+		return category, apperr.InvalidFilterf("a category can't be its own parent")
+	case len(in.Aliases) > maxCategoryAliases:
+		return category, apperr.InvalidFilterf("at most %d aliases are allowed", maxCategoryAliases)
+	}
+
+	seen := map[string]bool{category.Key: true}
+	for _, alias := range in.Aliases {
+		key := models.CategoryKey(alias)
+		if key == "" || len(key) > maxCategoryName {
+			return category, apperr.InvalidFilterf("aliases must be 1-%d characters", maxCategoryName)
+		}
+		if !seen[key] {
+			seen[key] = true
+			category.Aliases = append(category.Aliases, key)
+		}
+	}
+	return category, nil
+}
+
+// SeedCategories inserts the bundled category hierarchy. Categories already
+// in the table are left alone so admin edits survive restarts. Returns the
+// number inserted.
+func (s *Services) SeedCategories(ctx context.Context) (int, error) {
+	var inputs []CategoryInput
+	if err := json.Unmarshal(bundledCategories, &inputs); err != nil {
+		return 0, fmt.Errorf("failed to parse bundled categories: %w", err)
+	}
+
+	categories := make([]models.Category, len(inputs))
+	for i, in := range inputs {
+		category, err := in.Category()
+		if err != nil {
+			return 0, fmt.Errorf("bundled category %q: %w", in.Name, err)
+		}
+		categories[i] = category
+	}
+
+	result := s.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(&categories)
+	if result.Error != nil {
+		return 0, result.Error
+	}
+	if result.RowsAffected > 0 {
+		forgetCategoryTree()
+	}
+	return int(result.RowsAffected), nil
+}
+
+// categoryTree is the category hierarchy indexed for lookups
+type categoryTree struct {
+	nodes    map[string]*models.Category // By key
+	aliases  map[string]string           // Alias to key
+	children map[string][]string         // Parent key to child keys, sorted
+}
+
+func newCategoryTree(categories []models.Category) *categoryTree {
+	t := &categoryTree{
+		nodes:    make(map[string]*models.Category, len(categories)),
+		aliases:  make(map[string]string),
+		children: make(map[string][]string),
+	}
+	for i := range categories {
+		category := &categories[i]
+		t.nodes[category.Key] = category
+		for _, alias := range category.Aliases {
+			t.aliases[alias] = category.Key
+		}
+	}
+	for key, category := range t.nodes {
+		// Categories whose parent was removed outside the API are top-level
+		if _, ok := t.nodes[category.Parent]; !ok {
+			category.Parent = ""
+		}
+		t.children[category.Parent] = append(t.children[category.Parent], key)
+	}
+	for _, keys := range t.children {
+		sort.Strings(keys)
+	}
+	return t
+}
+
+// resolve returns the key of the category a name or alias refers to
+func (t *categoryTree) resolve(name string) (string, bool) {
+	key := models.CategoryKey(name)
+	if _, ok := t.nodes[key]; ok {
+		return key, true
+	}
+	key, ok := t.aliases[key]
+	return key, ok
+}
+
+// level returns the depth of a category, 1 for top-level ones
+func (t *categoryTree) level(key string) int {
+	level := 0
+	for seen := map[string]bool{}; key != "" && !seen[key]; key = t.nodes[key].Parent {
+		seen[key] = true
+		level++
+	}
+	return level
+}
+
+// ancestorAt returns the ancestor of a category at a level, or the category
+// itself when it is at that level. ok is false for categories above it.
+func (t *categoryTree) ancestorAt(key string, level int) (string, bool) {
+	for depth := t.level(key); depth > level; depth-- {
+		key = t.nodes[key].Parent
+	}
+	return key, t.level(key) == level
+}
+
+// subtree returns the keys of a category and all its descendants
+func (t *categoryTree) subtree(key string) []string {
+	keys := []string{key}
+	for i := 0; i < len(keys); i++ {
+		keys = append(keys, t.children[keys[i]]...)
+	}
+	return keys
+}
+
+// names returns the lowercased name and aliases of a category
+func (t *categoryTree) names(key string) []string {
+	return append([]string{key}, t.nodes[key].Aliases...)
+}
+
+// categoryCache holds the hierarchy last read from the database
+var categoryCache struct {
+	sync.Mutex
+	tree     *categoryTree
+	loadedAt time.Time
+}
+
+// readCategoryTree reads the hierarchy from the database
+func (s *Services) readCategoryTree(ctx context.Context) (*categoryTree, error) {
+	database := s.db.WithContext(ctx)
+	if database == nil {
+		return nil, fmt.Errorf("database not initialized")
+	}
+	var categories []models.Category
+	if err := database.Find(&categories).Error; err != nil {
+		return nil, err
+	}
+	return newCategoryTree(categories), nil
+}
+
+// cachedCategoryTree returns the hierarchy, reading it again once it is
+// older than categoryTreeTTL
+func (s *Services) cachedCategoryTree(ctx context.Context) (*categoryTree, error) {
+	categoryCache.Lock()
+	defer categoryCache.Unlock()
+	if categoryCache.tree != nil && time.Since(categoryCache.loadedAt) < categoryTreeTTL {
+		return categoryCache.tree, nil
+	}
+	tree, err := s.readCategoryTree(ctx)
+	if err != nil {
+		return nil, err
+	}
+	categoryCache.tree, categoryCache.loadedAt = tree, time.Now()
+	return tree, nil
+}
+
+// forgetCategoryTree makes the next lookup read the hierarchy again
+func forgetCategoryTree() {
+	categoryCache.Lock()
+	categoryCache.tree = nil
+	categoryCache.Unlock()
+}
+
+// CategoryTerms returns the lowercased names an article's categories are
+// matched against for a category filter: the name given, and the names and
+// aliases of the category it refers to and its descendants. Names outside
+// the hierarchy, or a hierarchy that can't be read, match only themselves.
+func (s *Services) CategoryTerms(ctx context.Context, name string) []string {
+	terms := []string{models.CategoryKey(name)}
+	tree, err := s.cachedCategoryTree(ctx)
+	if err != nil {
+		log.Printf("Failed to load categories, matching %q alone: %v", name, err)
+		return terms
+	}
+	key, ok := tree.resolve(name)
+	if !ok {
+		return terms
+	}
+	seen := map[string]bool{terms[0]: true}
+	for _, descendant := range tree.subtree(key) {
+		for _, term := range tree.names(descendant) {
+			if !seen[term] {
+				seen[term] = true
+				terms = append(terms, term)
+			}
+		}
+	}
+	return terms
+}
+
+// CategoryMatch is the condition of a category filter: articles with a
+// category containing any of the category's terms, case-insensitive, so
+// sports includes cricket and IPL articles
+func (s *Services) CategoryMatch(ctx context.Context, name string) clause.Expression {
+	terms := s.CategoryTerms(ctx, name)
+	conditions := make([]clause.Expression, len(terms))
+	for i, term := range terms {
+		conditions[i] = clause.Expr{SQL: "LOWER(category) LIKE ?", Vars: []interface{}{"%" + term + "%"}}
+	}
+	return clause.Or(conditions...)
+}
+
+// CanonicalCategories maps the categories of an article being stored to the
+// hierarchy's names, so aliases like IPL_2025 are stored as IPL. Categories
+// outside the hierarchy are kept as given; duplicates are dropped.
+func (s *Services) CanonicalCategories(ctx context.Context, names []string) []string {
+	tree, err := s.cachedCategoryTree(ctx)
+	if err != nil {
+		log.Printf("Failed to load categories, storing them as given: %v", err)
+		return names
+	}
+	return tree.canonical(names)
+}
+
+func (t *categoryTree) canonical(names []string) []string {
+	canonical := make([]string, 0, len(names))
+	seen := make(map[string]bool, len(names))
+	for _, name := range names {
+		if key, ok := t.resolve(name); ok {
+			name = t.nodes[key].Name
+		}
+		if key := models.CategoryKey(name); key != "" && !seen[key] {
+			seen[key] = true
+			canonical = append(canonical, strings.TrimSpace(name))
+		}
+	}
+	return canonical
+}
+
+// CategoryNode is a category with its place in the hierarchy
+type CategoryNode struct {
+	models.Category
+	Level    int      `json:"level"`
+	Children []string `json:"children,omitempty"` // Names of the child categories
+}
+
+// ListCategories returns the hierarchy, top-level categories first and each
+// level ordered by name
+func (s *Services) ListCategories(ctx context.Context) ([]CategoryNode, error) {
+	tree, err := s.readCategoryTree(ctx)
+	if err != nil {
+		return nil, err
+	}
+	nodes := make([]CategoryNode, 0, len(tree.nodes))
+	for key, category := range tree.nodes {
+		node := CategoryNode{Category: *category, Level: tree.level(key)}
+		for _, child := range tree.children[key] {
+			node.Children = append(node.Children, tree.nodes[child].Name)
+		}
+		nodes = append(nodes, node)
+	}
+	sort.Slice(nodes, func(i, j int) bool {
+		if nodes[i].Level != nodes[j].Level {
+			return nodes[i].Level < nodes[j].Level
+		}
+		return nodes[i].Key < nodes[j].Key
+	})
+	return nodes, nil
+}
+
+// SaveCategory creates or replaces a category. The parent must exist and not
+// be one of the category's descendants, and aliases must not name another
+// category.
+func (s *Services) SaveCategory(ctx context.Context, in CategoryInput) (*models.Category, error) {
+	category, err := in.Category()
+	if err != nil {
+		return nil, err
+	}
+	tree, err := s.readCategoryTree(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if category.Parent != "" {
+		parent, ok := tree.resolve(category.Parent)
+		if !ok {
+			return nil, apperr.InvalidFilterf("parent category %q doesn't exist", in.Parent)
+		}
+		if _, exists := tree.nodes[category.Key]; exists {
+			for _, descendant := range tree.subtree(category.Key) {
+				if descendant == parent {
+					return nil, apperr.InvalidFilterf("%s is a descendant of %s and can't be its parent", tree.nodes[parent].Name, category.Name)
+				}
+			}
+		}
+		category.Parent = parent
+	}
+	if owner, ok := tree.aliases[category.Key]; ok && owner != category.Key {
+		return nil, apperr.InvalidFilterf("%s is an alias of %s", category.Name, tree.nodes[owner].Name)
+	}
+	for _, alias := range category.Aliases {
+		if _, ok := tree.nodes[alias]; ok {
+			return nil, apperr.InvalidFilterf("alias %q is the name of a category", alias)
+		}
+		if owner, ok := tree.aliases[alias]; ok && owner != category.Key {
+			return nil, apperr.InvalidFilterf("alias %q already belongs to %s", alias, tree.nodes[owner].Name)
+		}
+	}
+
+	if err := s.db.WithContext(ctx).Save(&category).Error; err != nil {
+		return nil, err
+	}
+	forgetCategoryTree()
+	return &category, nil
+}
+
+// DeleteCategory removes a category. Its children move up to its parent.
+// Articles keep the category's name.
+func (s *Services) DeleteCategory(ctx context.Context, name string) error {
+	database := s.db.WithContext(ctx)
+	if database == nil {
+		return fmt.Errorf("database not initialized")
+	}
+	err := database.Transaction(func(tx *gorm.DB) error {
+		var category models.Category
+		result := tx.Where("key = ?", models.CategoryKey(name)).Limit(1).Find(&category)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return apperr.NotFoundf("Category not found")
+		}
+		if err := tx.Model(&models.Category{}).Where("parent = ?", category.Key).Update("parent", category.Parent).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&category).Error
+	})
+	if err == nil {
+		forgetCategoryTree()
+	}
+	return err
+}
+
+// CategoryCount is the number of articles in a category or its descendants
+type CategoryCount struct {
+	Name   string `json:"name"`
+	Parent string `json:"parent,omitempty"` // Key of the parent category
+	Level  int    `json:"level"`
+	Count  int64  `json:"count"`
+}
+
+// CategoryFacets counts the articles matching filter per category, rolling
+// descendants up into their ancestor: per child of parent when one is given,
+// and per category at level otherwise. An article is counted once per
+// category even if it carries several of its descendants. At level 1,
+// categories outside the hierarchy are counted as top-level ones. Returns the
+// counts, most articles first, and the level counted.
+func (s *Services) CategoryFacets(ctx context.Context, filter ArticleFilter, parent string, level int) ([]CategoryCount, int, error) {
+	database := s.db.WithContext(ctx)
+	if database == nil {
+		return nil, 0, fmt.Errorf("database not initialized")
+	}
+	tree, err := s.cachedCategoryTree(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// Map each name and alias to the category it is counted under
+	buckets := make(map[string]string)
+	if parent != "" {
+		parentKey, ok := tree.resolve(parent)
+		if !ok {
+			return nil, 0, apperr.InvalidFilterf("unknown category %q", parent)
+		}
+		level = tree.level(parentKey) + 1
+		for _, child := range tree.children[parentKey] {
+			for _, key := range tree.subtree(child) {
+				for _, name := range tree.names(key) {
+					buckets[name] = child
+				}
+			}
+		}
+	} else {
+		for key := range tree.nodes {
+			if ancestor, ok := tree.ancestorAt(key, level); ok {
+				for _, name := range tree.names(key) {
+					buckets[name] = ancestor
+				}
+			}
+		}
+	}
+	counts := []CategoryCount{}
+	if len(buckets) == 0 && (parent != "" || level > 1) {
+		return counts, level, nil
+	}
+
+	values := make([]string, 0, len(buckets))
+	args := make([]interface{}, 0, 2*len(buckets))
+	for name, bucket := range buckets {
+		values = append(values, "SELECT ? AS term, ? AS bucket")
+		args = append(args, name, bucket)
+	}
+	join, bucket := "JOIN", "rollup.bucket"
+	if parent == "" && level == 1 {
+		join, bucket = "LEFT JOIN", "COALESCE(rollup.bucket, LOWER(TRIM(tags.value)))"
+	}
+	if len(values) == 0 {
+		values = append(values, "SELECT NULL AS term, NULL AS bucket")
+	}
+
+	scoped := database.Model(&models.Article{}).
+		Scopes(filter.Scope).
+		Select("articles.id, CAST(articles.category AS TEXT) AS category").
+		Where("json_valid(CAST(articles.category AS TEXT))")
+	var rows []struct {
+		Facet string
+		Count int64
+	}
+	err = database.Table("(?) AS scoped, json_each(scoped.category) AS tags", scoped).
+		Joins(join+" ("+strings.Join(values, " UNION ALL ")+") AS rollup ON rollup.term = LOWER(TRIM(tags.value))", args...).
+		Select(bucket + " AS facet, COUNT(DISTINCT scoped.id) AS count").
+		Where("TRIM(tags.value) <> ''").
+		Group("facet").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	for _, row := range rows {
+		count := CategoryCount{Name: row.Facet, Level: level, Count: row.Count}
+		if category, ok := tree.nodes[row.Facet]; ok {
+			count.Name, count.Parent = category.Name, category.Parent
+		}
+		counts = append(counts, count)
+	}
+	sort.Slice(counts, func(i, j int) bool {
+		if counts[i].Count != counts[j].Count {
+			return counts[i].Count > counts[j].Count
+		}
+		return counts[i].Name < counts[j].Name
+	})
+	return counts, level, nil
+}
+
+// CategoryMigration reports the changes of MigrateCategories
+type CategoryMigration struct {
+	Added     []string `json:"added"`     // Flat categories of stored articles added as top-level categories
+	Rewritten int      `json:"rewritten"` // Articles whose categories were renamed to the hierarchy's names
+}
+
+// MigrateCategories brings the categories of stored articles, of every
+// tenant, into the hierarchy: names it doesn't know are added as top-level
+// categories, to be moved under a parent through the admin API, and aliases
+// are rewritten to the names they stand for. With dryRun nothing is changed.
+func (s *Services) MigrateCategories(ctx context.Context, dryRun bool) (*CategoryMigration, error) {
+	database := s.db.WithContext(db.IncludeHidden(ctx))
+	if database == nil {
+		return nil, fmt.Errorf("database not initialized")
+	}
+	tree, err := s.readCategoryTree(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	err = database.Raw("SELECT DISTINCT TRIM(tags.value) FROM articles, json_each(CAST(articles.category AS TEXT)) AS tags " +
+		"WHERE json_valid(CAST(articles.category AS TEXT)) AND TRIM(tags.value) <> '' ORDER BY 1").
+		Scan(&names).Error
+	if err != nil {
+		return nil, err
+	}
+	migration := &CategoryMigration{Added: []string{}}
+	var added []models.Category
+	for _, name := range names {
+		if _, ok := tree.resolve(name); ok {
+			continue
+		}
+		category, err := CategoryInput{Name: name}.Category()
+		if err != nil {
+			log.Printf("Skipping category %q of stored articles: %v", name, err)
+			continue
+		}
+		tree.nodes[category.Key] = &category
+		added = append(added, category)
+		migration.Added = append(migration.Added, category.Name)
+	}
+	if !dryRun && len(added) > 0 {
+		if err := database.Clauses(clause.OnConflict{DoNothing: true}).Create(&added).Error; err != nil {
+			return nil, err
+		}
+		forgetCategoryTree()
+	}
+
+	var articles []models.Article
+	err = database.Model(&models.Article{}).Select("id", "category").
+		FindInBatches(&articles, 500, func(batch *gorm.DB, _ int) error {
+			for _, article := range articles {
+				canonical := tree.canonical(article.Category)
+				if strings.Join(canonical, "\x00") == strings.Join(article.Category, "\x00") {
+					continue
+				}
+				migration.Rewritten++
+				if dryRun {
+					continue
+				}
+				if err := database.Model(&models.Article{ID: article.ID}).Update("category", models.StringArray(canonical)).Error; err != nil {
+					return err
+				}
+			}
+			return nil
+		}).Error
+	if err != nil {
+		return nil, err
+	}
+	if !dryRun && migration.Rewritten > 0 {
+		s.InvalidateTrendingCache(ctx)
+	}
+	return migration, nil
+}
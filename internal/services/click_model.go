@@ -0,0 +1,219 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/mahigadamsetty/Inshorts-task/internal/models"
+	"gorm.io/gorm"
+)
+
+// Iterations of the click model's expectation-maximization, and the change in
+// every parameter below which it stops early
+const (
+	clickModelIterations = 100
+	clickModelTolerance  = 1e-6
+)
+
+// clickModelFloor keeps probabilities away from 0 so the estimates can recover
+const clickModelFloor = 1e-6
+
+// impressionCell counts the impressions and clicks of one article at one
+// position
+type impressionCell struct {
+	ArticleID   string
+	TenantID    string
+	Position    int
+	Impressions int64
+	Clicks      int64
+}
+
+// ClickModelResult summarizes a run of the click model
+type ClickModelResult struct {
+	Positions int `json:"positions"`
+	Articles  int `json:"articles"`
+}
+
+// EstimateClickModel fits a position-based click model to the impressions of
+// the last window: a click needs the article to be examined, which depends
+// only on its position, and to be attractive, which depends only on the
+// article. The examination probabilities are stored in position_bias and each
+// article's clicks over its expected examinations in article_attractiveness,
+// replacing the previous estimates. Without any click there is nothing to fit
+// and the previous estimates are kept.
+func (s *Services) EstimateClickModel(ctx context.Context, window time.Duration) (*ClickModelResult, error) {
+	database := s.db.WithContext(ctx)
+	if database == nil {
+		return nil, fmt.Errorf("database not initialized")
+	}
+
+	var cells []impressionCell
+	err := database.Model(&models.Impression{}).
+		Select("article_id, tenant_id, position, COUNT(*) AS impressions, "+
+			"SUM(CASE WHEN clicked_at IS NOT NULL THEN 1 ELSE 0 END) AS clicks").
+		Where("created_at >= ?", time.Now().Add(-window)).
+		Group("article_id, tenant_id, position").
+		Scan(&cells).Error
+	if err != nil {
+		return nil, err
+	}
+	result := &ClickModelResult{}
+	var clicks int64
+	for _, cell := range cells {
+		clicks += cell.Clicks
+	}
+	if clicks == 0 {
+		return result, nil
+	}
+
+	examination := fitPositionBias(cells)
+
+	now := time.Now()
+	bias := make(map[int]*models.PositionBias)
+	attractiveness := make(map[string]*models.ArticleAttractiveness)
+	for _, cell := range cells {
+		if bias[cell.Position] == nil {
+			bias[cell.Position] = &models.PositionBias{Position: cell.Position, Examination: examination[cell.Position], EstimatedAt: now}
+		}
+		bias[cell.Position].Impressions += cell.Impressions
+		bias[cell.Position].Clicks += cell.Clicks
+
+		if attractiveness[cell.ArticleID] == nil {
+			attractiveness[cell.ArticleID] = &models.ArticleAttractiveness{ArticleID: cell.ArticleID, TenantID: cell.TenantID, EstimatedAt: now}
+		}
+		attractiveness[cell.ArticleID].Examinations += float64(cell.Impressions) * examination[cell.Position]
+		attractiveness[cell.ArticleID].Clicks += cell.Clicks
+	}
+
+	err = database.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("1 = 1").Delete(&models.PositionBias{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("1 = 1").Delete(&models.ArticleAttractiveness{}).Error; err != nil {
+			return err
+		}
+		for _, position := range bias {
+			if err := tx.Create(position).Error; err != nil {
+				return err
+			}
+		}
+		for _, article := range attractiveness {
+			if article.Examinations > 0 {
+				article.Score = float64(article.Clicks) / article.Examinations
+			}
+			if err := tx.Create(article).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result.Positions = len(bias)
+	result.Articles = len(attractiveness)
+	return result, nil
+}
+
+// fitPositionBias estimates the examination probability of each position by
+// expectation-maximization over the impression cells, relative to the most
+// examined position. An impression without a click was either not examined or
+// examined and found unattractive, in proportions the current estimates give.
+func fitPositionBias(cells []impressionCell) map[int]float64 {
+	examination := make(map[int]float64)
+	attractiveness := make(map[string]float64)
+	for _, cell := range cells {
+		examination[cell.Position] = 0.5
+		attractiveness[cell.ArticleID] = 0.5
+	}
+
+	for i := 0; i < clickModelIterations; i++ {
+		examined := make(map[int]float64)
+		attracted := make(map[string]float64)
+		positionImpressions := make(map[int]float64)
+		articleImpressions := make(map[string]float64)
+		for _, cell := range cells {
+			e, a := examination[cell.Position], attractiveness[cell.ArticleID]
+			skipped := float64(cell.Impressions - cell.Clicks)
+			unclicked := 1 - e*a
+			examined[cell.Position] += float64(cell.Clicks) + skipped*e*(1-a)/unclicked
+			attracted[cell.ArticleID] += float64(cell.Clicks) + skipped*(1-e)*a/unclicked
+			positionImpressions[cell.Position] += float64(cell.Impressions)
+			articleImpressions[cell.ArticleID] += float64(cell.Impressions)
+		}
+
+		change := 0.0
+		for position, count := range positionImpressions {
+			next := clampProbability(examined[position] / count)
+			change = math.Max(change, math.Abs(next-examination[position]))
+			examination[position] = next
+		}
+		for articleID, count := range articleImpressions {
+			next := clampProbability(attracted[articleID] / count)
+			change = math.Max(change, math.Abs(next-attractiveness[articleID]))
+			attractiveness[articleID] = next
+		}
+		if change < clickModelTolerance {
+			break
+		}
+	}
+
+	var most float64
+	for _, e := range examination {
+		most = math.Max(most, e)
+	}
+	for position := range examination {
+		examination[position] /= most
+	}
+	return examination
+}
+
+// clampProbability keeps an estimate within [clickModelFloor, 1 - clickModelFloor]
+func clampProbability(p float64) float64 {
+	return math.Min(math.Max(p, clickModelFloor), 1-clickModelFloor)
+}
+
+// debiasedEngagement returns each article's clicks and expected examinations
+// from the click model, in place of views, or nothing before it has run
+func debiasedEngagement(database *gorm.DB) ([]articleEngagement, error) {
+	var engagement []articleEngagement
+	err := database.Model(&models.ArticleAttractiveness{}).
+		Select("article_id, examinations AS views, clicks").
+		Scan(&engagement).Error
+	return engagement, err
+}
+
+// PositionBiasReport is the click model's latest estimates
+type PositionBiasReport struct {
+	Positions   []models.PositionBias          `json:"positions"`    // By position
+	Articles    []models.ArticleAttractiveness `json:"articles"`     // Most attractive first
+	EstimatedAt *time.Time                     `json:"estimated_at"` // Nil before the model has run
+}
+
+// GetPositionBias returns the stored examination probabilities and the limit
+// most attractive articles. An empty tenantID covers every tenant's articles.
+func (s *Services) GetPositionBias(ctx context.Context, tenantID string, limit int) (*PositionBiasReport, error) {
+	database := s.db.WithContext(ctx)
+	if database == nil {
+		return nil, fmt.Errorf("database not initialized")
+	}
+
+	report := &PositionBiasReport{}
+	if err := database.Order("position").Find(&report.Positions).Error; err != nil {
+		return nil, err
+	}
+	query := database.Order("score DESC, article_id").Limit(limit)
+	if tenantID != "" {
+		query = query.Where("tenant_id = ?", tenantID)
+	}
+	if err := query.Find(&report.Articles).Error; err != nil {
+		return nil, err
+	}
+	if len(report.Positions) > 0 {
+		report.EstimatedAt = &report.Positions[0].EstimatedAt
+	}
+	return report, nil
+}
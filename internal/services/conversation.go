@@ -0,0 +1,133 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"strings"
+	"time"
+
+	"github.com/mahigadamsetty/Inshorts-task/internal/apperr"
+	"github.com/mahigadamsetty/Inshorts-task/internal/cache"
+	"github.com/mahigadamsetty/Inshorts-task/internal/degradation"
+	"github.com/mahigadamsetty/Inshorts-task/internal/faults"
+	"github.com/mahigadamsetty/Inshorts-task/internal/geocode"
+	"github.com/mahigadamsetty/Inshorts-task/internal/llm"
+	"github.com/mahigadamsetty/Inshorts-task/internal/tenant"
+)
+
+// ConversationState is what a /query session remembers about its previous query
+type ConversationState struct {
+	Intent    string
+	Query     string
+	Entities  []string
+	Category  string
+	Source    string
+	Location  *geocode.Place
+	DateRange *llm.DateRange
+	UpdatedAt time.Time
+}
+
+// ConversationCache stores short-lived conversation state by session, in a
+// cache store that may be shared with other replicas
+type ConversationCache struct {
+	store cache.Store
+	ttl   time.Duration
+}
+
+// conversationPrefix is the key prefix of sessions in the cache store
+const conversationPrefix = "conversation|"
+
+// NewConversationCache creates a conversation cache whose sessions expire
+// after ttl seconds without a query, kept in store
+func NewConversationCache(ttl int, store cache.Store) *ConversationCache {
+	return &ConversationCache{
+		store: store,
+		ttl:   time.Duration(ttl) * time.Second,
+	}
+}
+
+// sessionKey namespaces a session ID by the tenant carried by ctx
+func sessionKey(ctx context.Context, sessionID string) string {
+	return conversationPrefix + tenant.IDFromContext(ctx) + "|" + sessionID
+}
+
+// GetConversation returns the state of an unexpired session, or an
+// ErrCacheMiss when the session is unknown, expired or the cache is down
+func (s *Services) GetConversation(ctx context.Context, sessionID string) (ConversationState, error) {
+	if s.conversations == nil || sessionID == "" {
+		return ConversationState{}, apperr.ErrCacheMiss
+	}
+	if faults.CacheDown(ctx) {
+		degradation.Record(ctx, degradation.SubsystemCache, degradation.ModeBypassed)
+		return ConversationState{}, apperr.ErrCacheMiss
+	}
+
+	var state ConversationState
+	if !getCached(ctx, s.conversations.store, sessionKey(ctx, sessionID), s.conversations.ttl, &state) {
+		return ConversationState{}, apperr.ErrCacheMiss
+	}
+	return state, nil
+}
+
+// SaveConversation stores the state of a session, restarting its TTL
+func (s *Services) SaveConversation(ctx context.Context, sessionID string, state ConversationState) {
+	if s.conversations == nil || sessionID == "" || faults.CacheDown(ctx) {
+		return
+	}
+	state.UpdatedAt = time.Now()
+	setCached(ctx, s.conversations.store, sessionKey(ctx, sessionID), state, s.conversations.ttl)
+}
+
+// PurgeConversations drops every session of the tenant carried by ctx and
+// returns how many were dropped
+func (s *Services) PurgeConversations(ctx context.Context) int {
+	if s.conversations == nil {
+		return 0
+	}
+	return dropCached(ctx, s.conversations.store, sessionKey(ctx, ""))
+}
+
+// NewSessionID returns a random session identifier
+func NewSessionID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// followUpPrefixes mark a query that refines the previous one rather than starting over
+var followUpPrefixes = []string{
+	"only ", "just ", "also ", "and ", "but ", "now ", "same ", "what about ", "how about ",
+	"from ", "in ", "near ", "since ", "filter ", "narrow ", "limit to ",
+}
+
+// IsFollowUp reports whether a query reads as a refinement of the previous one,
+// like "only from BBC" or "what about last week"
+func IsFollowUp(query string) bool {
+	q := strings.ToLower(strings.TrimSpace(query)) + " "
+	for _, prefix := range followUpPrefixes {
+		if strings.HasPrefix(q, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// Refine applies the filters named in a follow-up to the previous state. The
+// previous intent and search terms are kept.
+func (s ConversationState) Refine(next ConversationState) ConversationState {
+	refined := s
+	if next.Category != "" {
+		refined.Category = next.Category
+	}
+	if next.Source != "" {
+		refined.Source = next.Source
+	}
+	if next.Location != nil {
+		refined.Location = next.Location
+	}
+	if next.DateRange != nil {
+		refined.DateRange = next.DateRange
+	}
+	return refined
+}
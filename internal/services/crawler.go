@@ -0,0 +1,504 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mahigadamsetty/Inshorts-task/internal/apperr"
+	"github.com/mahigadamsetty/Inshorts-task/internal/models"
+	"gorm.io/gorm"
+)
+
+// DefaultCrawlUserAgent is sent when neither the configuration nor a source's
+// policy names one. Some sites block default user agents.
+const DefaultCrawlUserAgent = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/58.0.3029.110 Safari/537.36"
+
+// robots.txt caching
+const (
+	robotsTTL      = 24 * time.Hour
+	maxRobotsBytes = 512 * 1024
+)
+
+// ErrDisallowed is returned for URLs a source's crawl policy or the host's
+// robots.txt forbids fetching
+var ErrDisallowed = errors.New("fetch disallowed")
+
+// CrawlDefaults apply to sources without their own crawl policy, and to the
+// fields a policy leaves unset
+type CrawlDefaults struct {
+	UserAgent     string
+	Delay         time.Duration // Minimum time between fetch starts for one source
+	MaxConcurrent int           // Fetches in flight for one source
+}
+
+// Crawler is the page fetcher of the services. It paces fetches per source,
+// caps their concurrency and honors robots.txt, so backfills don't hammer
+// publishers. Sources' crawl policies are read from its database.
+type Crawler struct {
+	db       *gorm.DB
+	defaults CrawlDefaults
+	client   *http.Client
+
+	mu     sync.Mutex
+	gates  map[string]*crawlGate   // By source key, or host for articles without a source
+	robots map[string]*robotsRules // By host and user agent token
+}
+
+// crawlGate limits the fetches of one source
+type crawlGate struct {
+	slots chan struct{}
+	next  time.Time // Earliest start time for the next fetch
+}
+
+// NewCrawler creates a crawler reading crawl policies from database, with the
+// given defaults
+func NewCrawler(database *gorm.DB, defaults CrawlDefaults) *Crawler {
+	if defaults.UserAgent == "" {
+		defaults.UserAgent = DefaultCrawlUserAgent
+	}
+	if defaults.MaxConcurrent <= 0 {
+		defaults.MaxConcurrent = 1
+	}
+	return &Crawler{
+		db:       database,
+		defaults: defaults,
+		client:   &http.Client{Timeout: 10 * time.Second},
+		gates:    make(map[string]*crawlGate),
+		robots:   make(map[string]*robotsRules),
+	}
+}
+
+// crawlSettings is a source's policy merged with the crawler defaults
+type crawlSettings struct {
+	userAgent     string
+	delay         time.Duration
+	maxConcurrent int
+	disallowPaths []string
+}
+
+// Fetch downloads a page for a source. It refuses paths disallowed by the
+// source's policy or the host's robots.txt with ErrDisallowed, then waits for
+// the source's concurrency and crawl-delay slot. The slot is held until the
+// response body is closed.
+func (c *Crawler) Fetch(ctx context.Context, sourceName string, target *url.URL) (*http.Response, error) {
+	return c.do(ctx, http.MethodGet, sourceName, target)
+}
+
+// Resolve follows a URL's redirects and returns where they end, under the same
+// crawl policy as Fetch. It asks with HEAD, falling back to GET for servers
+// that don't support it.
+func (c *Crawler) Resolve(ctx context.Context, sourceName string, target *url.URL) (*url.URL, error) {
+	resp, err := c.do(ctx, http.MethodHead, sourceName, target)
+	if err == nil && (resp.StatusCode == http.StatusMethodNotAllowed || resp.StatusCode == http.StatusNotImplemented) {
+		resp.Body.Close()
+		resp, err = c.do(ctx, http.MethodGet, sourceName, target)
+	}
+	if err != nil {
+		return nil, err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		return nil, fmt.Errorf("failed to resolve URL: status code %d", resp.StatusCode)
+	}
+	return resp.Request.URL, nil
+}
+
+// do sends one request once the crawl policy allows it
+func (c *Crawler) do(ctx context.Context, method, sourceName string, target *url.URL) (*http.Response, error) {
+	settings := c.settings(ctx, sourceName)
+	for _, prefix := range settings.disallowPaths {
+		if strings.HasPrefix(target.Path, prefix) {
+			return nil, fmt.Errorf("%w by crawl policy: %s", ErrDisallowed, target.Path)
+		}
+	}
+
+	rules := c.robotsRules(ctx, target, settings.userAgent)
+	if !rules.allowed(target) {
+		return nil, fmt.Errorf("%w by robots.txt: %s", ErrDisallowed, target.Path)
+	}
+	delay := settings.delay
+	if rules.crawlDelay > delay {
+		delay = rules.crawlDelay
+	}
+
+	gateKey := "host:" + target.Host
+	if key := models.SourceKey(sourceName); key != "" {
+		gateKey = "source:" + key
+	}
+	release, err := c.acquire(ctx, gateKey, settings.maxConcurrent, delay)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, target.String(), nil)
+	if err != nil {
+		release()
+		return nil, err
+	}
+	req.Header.Set("User-Agent", settings.userAgent)
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		release()
+		return nil, err
+	}
+	resp.Body = &crawlBody{ReadCloser: resp.Body, release: release}
+	return resp, nil
+}
+
+// settings loads a source's crawl policy, falling back to the defaults when it
+// has none or the lookup fails
+func (c *Crawler) settings(ctx context.Context, sourceName string) crawlSettings {
+	settings := crawlSettings{
+		userAgent:     c.defaults.UserAgent,
+		delay:         c.defaults.Delay,
+		maxConcurrent: c.defaults.MaxConcurrent,
+	}
+	key := models.SourceKey(sourceName)
+	if key == "" {
+		return settings
+	}
+
+	var policies []models.CrawlPolicy
+	if err := c.db.WithContext(ctx).Where("source_key = ?", key).Limit(1).Find(&policies).Error; err != nil {
+		log.Printf("Failed to load crawl policy for %s: %v", sourceName, err)
+		return settings
+	}
+	if len(policies) == 0 {
+		return settings
+	}
+
+	policy := policies[0]
+	if policy.UserAgent != "" {
+		settings.userAgent = policy.UserAgent
+	}
+	if policy.CrawlDelayMs > 0 {
+		settings.delay = time.Duration(policy.CrawlDelayMs) * time.Millisecond
+	}
+	if policy.MaxConcurrent > 0 {
+		settings.maxConcurrent = policy.MaxConcurrent
+	}
+	settings.disallowPaths = policy.DisallowPaths
+	return settings
+}
+
+// acquire waits for a concurrency slot and the gate's next start time,
+// returning a function that frees the slot
+func (c *Crawler) acquire(ctx context.Context, key string, maxConcurrent int, delay time.Duration) (func(), error) {
+	c.mu.Lock()
+	gate, ok := c.gates[key]
+	if !ok || cap(gate.slots) != maxConcurrent {
+		// A changed policy gets a new gate; fetches holding the old one release into it
+		replacement := &crawlGate{slots: make(chan struct{}, maxConcurrent)}
+		if ok {
+			replacement.next = gate.next
+		}
+		gate = replacement
+		c.gates[key] = gate
+	}
+	c.mu.Unlock()
+
+	select {
+	case gate.slots <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	var once sync.Once
+	release := func() { once.Do(func() { <-gate.slots }) }
+
+	c.mu.Lock()
+	start := time.Now()
+	if gate.next.After(start) {
+		start = gate.next
+	}
+	gate.next = start.Add(delay)
+	c.mu.Unlock()
+
+	timer := time.NewTimer(time.Until(start))
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return release, nil
+	case <-ctx.Done():
+		release()
+		return nil, ctx.Err()
+	}
+}
+
+// crawlBody frees the fetch's slot once the response body is closed
+type crawlBody struct {
+	io.ReadCloser
+	release func()
+}
+
+func (b *crawlBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.release()
+	return err
+}
+
+// robotsRules are the robots.txt rules that apply to one user agent on one host
+type robotsRules struct {
+	rules      []robotsRule
+	crawlDelay time.Duration
+	fetchedAt  time.Time
+}
+
+// robotsRule is one Allow or Disallow line
+type robotsRule struct {
+	pattern string
+	allow   bool
+}
+
+// robotsRules returns the host's robots.txt rules for a user agent, fetching
+// them when they are not cached. Hosts whose robots.txt is missing or
+// unreachable are treated as allowing everything.
+func (c *Crawler) robotsRules(ctx context.Context, target *url.URL, userAgent string) *robotsRules {
+	token := userAgentToken(userAgent)
+	key := target.Scheme + "://" + target.Host + "|" + token
+
+	c.mu.Lock()
+	cached, ok := c.robots[key]
+	c.mu.Unlock()
+	if ok && time.Since(cached.fetchedAt) < robotsTTL {
+		return cached
+	}
+
+	rules := &robotsRules{fetchedAt: time.Now()}
+	robotsURL := url.URL{Scheme: target.Scheme, Host: target.Host, Path: "/robots.txt"}
+	req, err := http.NewRequestWithContext(ctx, "GET", robotsURL.String(), nil)
+	if err == nil {
+		req.Header.Set("User-Agent", userAgent)
+		var resp *http.Response
+		resp, err = c.client.Do(req)
+		if err == nil {
+			if resp.StatusCode == http.StatusOK {
+				body, readErr := io.ReadAll(io.LimitReader(resp.Body, maxRobotsBytes))
+				if readErr == nil {
+					rules = parseRobots(string(body), token)
+				}
+			}
+			resp.Body.Close()
+		}
+	}
+	if err != nil {
+		log.Printf("Failed to fetch %s, assuming everything is allowed: %v", robotsURL.String(), err)
+	}
+
+	c.mu.Lock()
+	c.robots[key] = rules
+	c.mu.Unlock()
+	return rules
+}
+
+// userAgentToken is the lowercased product token robots.txt groups are matched
+// against, e.g. "newsbot" for "NewsBot/1.0 (+https://example.com)"
+func userAgentToken(userAgent string) string {
+	token := strings.ToLower(strings.TrimSpace(userAgent))
+	if i := strings.IndexAny(token, "/ "); i >= 0 {
+		token = token[:i]
+	}
+	return token
+}
+
+// parseRobots reads the group of a robots.txt that applies to the user agent
+// token: the group naming the longest matching agent, otherwise the "*" group
+func parseRobots(body, token string) *robotsRules {
+	type group struct {
+		agents     []string
+		rules      []robotsRule
+		crawlDelay time.Duration
+	}
+	var groups []*group
+	var current *group
+	lastWasAgent := false
+
+	for _, line := range strings.Split(body, "\n") {
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+		key, value, found := strings.Cut(line, ":")
+		if !found {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+
+		if key == "user-agent" {
+			if current == nil || !lastWasAgent {
+				current = &group{}
+				groups = append(groups, current)
+			}
+			current.agents = append(current.agents, strings.ToLower(value))
+			lastWasAgent = true
+			continue
+		}
+		lastWasAgent = false
+		if current == nil {
+			continue
+		}
+
+		switch key {
+		case "allow", "disallow":
+			// An empty Disallow allows everything, which is the default anyway
+			if value != "" {
+				current.rules = append(current.rules, robotsRule{pattern: value, allow: key == "allow"})
+			}
+		case "crawl-delay":
+			if seconds, err := strconv.ParseFloat(value, 64); err == nil && seconds > 0 {
+				current.crawlDelay = time.Duration(seconds * float64(time.Second))
+			}
+		}
+	}
+
+	var best, wildcard *group
+	bestLen := 0
+	for _, g := range groups {
+		for _, agent := range g.agents {
+			if agent == "*" {
+				if wildcard == nil {
+					wildcard = g
+				}
+			} else if strings.Contains(token, agent) && len(agent) > bestLen {
+				best, bestLen = g, len(agent)
+			}
+		}
+	}
+	if best == nil {
+		best = wildcard
+	}
+
+	rules := &robotsRules{fetchedAt: time.Now()}
+	if best != nil {
+		rules.rules = best.rules
+		rules.crawlDelay = best.crawlDelay
+	}
+	return rules
+}
+
+// allowed applies the longest matching rule to the URL's path and query. Allow
+// wins ties, and paths no rule matches are allowed.
+func (r *robotsRules) allowed(target *url.URL) bool {
+	path := target.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	if target.RawQuery != "" {
+		path += "?" + target.RawQuery
+	}
+
+	allow, matchLen := true, -1
+	for _, rule := range r.rules {
+		if !robotsMatch(rule.pattern, path) {
+			continue
+		}
+		if len(rule.pattern) > matchLen || (len(rule.pattern) == matchLen && rule.allow) {
+			allow, matchLen = rule.allow, len(rule.pattern)
+		}
+	}
+	return allow
+}
+
+// robotsMatch matches a robots.txt path pattern, supporting "*" wildcards and a
+// trailing "$" end anchor
+func robotsMatch(pattern, path string) bool {
+	anchored := strings.HasSuffix(pattern, "$")
+	parts := strings.Split(strings.TrimSuffix(pattern, "$"), "*")
+
+	if !strings.HasPrefix(path, parts[0]) {
+		return false
+	}
+	rest := path[len(parts[0]):]
+	if len(parts) == 1 {
+		return !anchored || rest == ""
+	}
+
+	last := len(parts) - 1
+	for _, part := range parts[1:last] {
+		i := strings.Index(rest, part)
+		if i < 0 {
+			return false
+		}
+		rest = rest[i+len(part):]
+	}
+	if anchored {
+		return strings.HasSuffix(rest, parts[last])
+	}
+	return strings.Contains(rest, parts[last])
+}
+
+// CrawlPolicyInput describes a source's crawl policy as given through the admin API
+type CrawlPolicyInput struct {
+	SourceName    string   `json:"-"`
+	UserAgent     string   `json:"user_agent"`
+	CrawlDelayMs  int      `json:"crawl_delay_ms"`
+	MaxConcurrent int      `json:"max_concurrent"`
+	DisallowPaths []string `json:"disallow_paths"`
+}
+
+// Policy validates the input and builds the stored policy
+func (in CrawlPolicyInput) Policy() (models.CrawlPolicy, error) {
+	policy := models.CrawlPolicy{
+		SourceKey:     models.SourceKey(in.SourceName),
+		SourceName:    strings.TrimSpace(in.SourceName),
+		UserAgent:     strings.TrimSpace(in.UserAgent),
+		CrawlDelayMs:  in.CrawlDelayMs,
+		MaxConcurrent: in.MaxConcurrent,
+		DisallowPaths: models.StringArray{},
+	}
+	if policy.SourceKey == "" {
+		return policy, apperr.InvalidFilterf("source name is required")
+	}
+	if in.CrawlDelayMs < 0 {
+		return policy, apperr.InvalidFilterf("crawl_delay_ms must not be negative")
+	}
+	if in.MaxConcurrent < 0 {
+		return policy, apperr.InvalidFilterf("max_concurrent must not be negative")
+	}
+	for _, path := range in.DisallowPaths {
+		if !strings.HasPrefix(path, "/") {
+			return policy, apperr.InvalidFilterf("disallowed path %q must start with /", path)
+		}
+		policy.DisallowPaths = append(policy.DisallowPaths, path)
+	}
+	return policy, nil
+}
+
+// ListCrawlPolicies returns all crawl policies ordered by source name
+func (s *Services) ListCrawlPolicies(ctx context.Context) ([]models.CrawlPolicy, error) {
+	var policies []models.CrawlPolicy
+	err := s.db.WithContext(ctx).Order("source_name").Find(&policies).Error
+	return policies, err
+}
+
+// GetCrawlPolicy returns the crawl policy of a source
+func (s *Services) GetCrawlPolicy(ctx context.Context, sourceName string) (*models.CrawlPolicy, error) {
+	var policy models.CrawlPolicy
+	if err := s.db.WithContext(ctx).Where("source_key = ?", models.SourceKey(sourceName)).First(&policy).Error; err != nil {
+		return nil, apperr.NotFound(err, "Crawl policy")
+	}
+	return &policy, nil
+}
+
+// SaveCrawlPolicy creates or replaces the crawl policy of a source
+func (s *Services) SaveCrawlPolicy(ctx context.Context, policy *models.CrawlPolicy) error {
+	return s.db.WithContext(ctx).Save(policy).Error
+}
+
+// DeleteCrawlPolicy removes a source's crawl policy
+func (s *Services) DeleteCrawlPolicy(ctx context.Context, sourceName string) error {
+	result := s.db.WithContext(ctx).Where("source_key = ?", models.SourceKey(sourceName)).Delete(&models.CrawlPolicy{})
+	if result.Error == nil && result.RowsAffected == 0 {
+		return apperr.NotFoundf("Crawl policy not found")
+	}
+	return result.Error
+}
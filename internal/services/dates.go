@@ -0,0 +1,162 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mahigadamsetty/Inshorts-task/internal/models"
+)
+
+// DefaultDateLayouts are tried for every source after its own layouts, most
+// common first. Numeric day/month layouts like 02/01/2006 are ambiguous, so
+// sources using them must register them.
+var DefaultDateLayouts = []string{
+	"2006-01-02T15:04:05",
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04:05Z07:00",
+	"2006-01-02 15:04",
+	time.RFC1123Z,
+	time.RFC1123,
+	"Mon, 2 Jan 2006 15:04:05 -0700",
+	"Mon, 2 Jan 2006 15:04:05 MST",
+	time.RFC822Z,
+	time.RFC822,
+	"2 Jan 2006 15:04:05",
+	"2 Jan 2006, 15:04",
+	"January 2, 2006 15:04",
+	"January 2, 2006 3:04 PM",
+	"Jan 2, 2006 3:04 PM",
+	"January 2, 2006",
+	"Jan 2, 2006",
+	"2006-01-02",
+}
+
+// maxFutureSkew is how far past now a publication date may lie, for clock
+// differences and timezone mistakes
+const maxFutureSkew = 24 * time.Hour
+
+// SourceDateFormat is how one source writes publication dates. Timezone
+// applies to layouts without a zone; it defaults to UTC.
+type SourceDateFormat struct {
+	Layouts  []string `json:"layouts"`
+	Timezone string   `json:"timezone"`
+
+	location *time.Location
+}
+
+// DateFormats parses publication dates with per-source layouts, falling back
+// to DefaultDateLayouts
+type DateFormats struct {
+	sources map[string]SourceDateFormat // By models.SourceKey
+}
+
+// NewDateFormats returns a registry with only the default layouts
+func NewDateFormats() *DateFormats {
+	return &DateFormats{sources: map[string]SourceDateFormat{}}
+}
+
+// LoadDateFormats reads a JSON object of source name -> {"layouts": [...],
+// "timezone": "Asia/Kolkata"}, with layouts in Go's reference time format.
+// An empty path gives a registry with only the default layouts.
+func LoadDateFormats(path string) (*DateFormats, error) {
+	formats := NewDateFormats()
+	if path == "" {
+		return formats, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var sources map[string]SourceDateFormat
+	if err := json.Unmarshal(data, &sources); err != nil {
+		return nil, fmt.Errorf("invalid date formats %s: %w", path, err)
+	}
+	for name, format := range sources {
+		if err := formats.Register(name, format); err != nil {
+			return nil, fmt.Errorf("date formats of %q: %w", name, err)
+		}
+	}
+	return formats, nil
+}
+
+// Register sets the date format of a source
+func (f *DateFormats) Register(source string, format SourceDateFormat) error {
+	format.location = time.UTC
+	if format.Timezone != "" {
+		location, err := time.LoadLocation(format.Timezone)
+		if err != nil {
+			return err
+		}
+		format.location = location
+	}
+	f.sources[models.SourceKey(source)] = format
+	return nil
+}
+
+// Parse reads a source's publication date, trying the source's layouts, then
+// the defaults, then Unix timestamps in seconds or milliseconds. Dates before
+// 1970 or more than a day in the future are rejected as implausible.
+func (f *DateFormats) Parse(source, value string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return time.Time{}, fmt.Errorf("publication date is empty")
+	}
+
+	format, ok := f.sources[models.SourceKey(source)]
+	if !ok {
+		format.location = time.UTC
+	}
+	parsed, err := parseDate(value, format)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if !parsed.After(time.Unix(0, 0)) || parsed.After(time.Now().Add(maxFutureSkew)) {
+		return time.Time{}, fmt.Errorf("publication date %q is implausible", value)
+	}
+	return parsed.UTC(), nil
+}
+
+// ParseSchedule reads a time an article is scheduled for, like an embargo or
+// an expiry, with the same layouts as Parse. Future times are expected, so
+// only dates before 1970 are rejected.
+func (f *DateFormats) ParseSchedule(source, value string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	format, ok := f.sources[models.SourceKey(source)]
+	if !ok {
+		format.location = time.UTC
+	}
+	parsed, err := parseDate(value, format)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%q matches no known format", value)
+	}
+	if !parsed.After(time.Unix(0, 0)) {
+		return time.Time{}, fmt.Errorf("%q is implausible", value)
+	}
+	return parsed.UTC(), nil
+}
+
+// parseDate tries every layout of a format and the defaults in turn
+func parseDate(value string, format SourceDateFormat) (time.Time, error) {
+	for _, layouts := range [][]string{format.Layouts, DefaultDateLayouts} {
+		for _, layout := range layouts {
+			if parsed, err := time.ParseInLocation(layout, value, format.location); err == nil {
+				return parsed, nil
+			}
+		}
+	}
+	if digits, err := strconv.ParseInt(value, 10, 64); err == nil {
+		switch len(value) {
+		case 10:
+			return time.Unix(digits, 0), nil
+		case 13:
+			return time.UnixMilli(digits), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("publication date %q matches no known format", value)
+}
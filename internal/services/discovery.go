@@ -0,0 +1,477 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"math/rand"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mahigadamsetty/Inshorts-task/internal/models"
+	"github.com/mahigadamsetty/Inshorts-task/internal/tenant"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Policies the discovery feed orders its articles with
+const (
+	DiscoveryThompson      = "thompson"       // Rank by a draw from each article's click-through posterior
+	DiscoveryEpsilonGreedy = "epsilon_greedy" // Fill a share of slots with little-served articles, the rest by CTR
+)
+
+// How a discovery slot was filled
+const (
+	DiscoveryExplore = "explore" // Epsilon-greedy exploration of a little-served article
+	DiscoveryExploit = "exploit" // Epsilon-greedy choice of the best CTR
+	DiscoverySample  = "sample"  // Thompson sampling
+)
+
+const (
+	// discoveryClickWindow is how long after a pull a click on its article by
+	// the same viewer is attributed to it
+	discoveryClickWindow = 30 * time.Minute
+	// discoveryPriorPulls is how many pulls the prior CTR counts for, so an
+	// article's own clicks outweigh it after a few dozen pulls
+	discoveryPriorPulls = 10
+	// discoveryFreshPulls is how often an article is served before
+	// epsilon-greedy stops exploring it
+	discoveryFreshPulls = 20
+	// discoveryHistory is how far back a viewer's events shape their for-you feed
+	discoveryHistory = 30 * 24 * time.Hour
+	// discoveryHistoryEvents caps the events a for-you feed reads
+	discoveryHistoryEvents = 500
+)
+
+// DiscoveryOptions configure the discovery feed
+type DiscoveryOptions struct {
+	Policy   string  // DiscoveryThompson or DiscoveryEpsilonGreedy
+	Epsilon  float64 // Share of epsilon-greedy slots filled by exploration, 0-1
+	PoolSize int     // Newest articles the feed chooses from
+}
+
+// InitDiscovery sets how the discovery feed orders its articles. Until it is
+// called, the feed uses Thompson sampling over the 200 newest articles.
+func (s *Services) InitDiscovery(options DiscoveryOptions) error {
+	if options.Policy != DiscoveryThompson && options.Policy != DiscoveryEpsilonGreedy {
+		return fmt.Errorf("discovery policy must be %s or %s, not %q", DiscoveryThompson, DiscoveryEpsilonGreedy, options.Policy)
+	}
+	if options.Epsilon < 0 || options.Epsilon > 1 {
+		return fmt.Errorf("discovery epsilon must be between 0 and 1")
+	}
+	if options.PoolSize <= 0 {
+		return fmt.Errorf("discovery pool size must be positive")
+	}
+	s.discovery = options
+	return nil
+}
+
+// DiscoveryRequest is one request for the discovery feed
+type DiscoveryRequest struct {
+	Endpoint    string // random or for-you
+	Limit       int
+	Filter      ArticleFilter
+	ClientID    string     // Identifies the viewer to attribute clicks, see RecordImpressions
+	AnonymousID string     // The client's own ID, whose events personalize the feed; "" for none
+	Personal    bool       // Weight articles by the viewer's categories and leave out those they saw
+	Rand        *rand.Rand // Draws the feed's random choices; nil for a time-based seed
+}
+
+// Discover picks the feed's articles from the newest ones, balancing articles
+// that were little served against those with the best click-through rates.
+// Each article is an arm of a multi-armed bandit whose pulls and clicks are
+// kept in discovery_arms. Personal feeds weight each article by up to twice,
+// by the share of the viewer's recent events in its categories. Requests with
+// a Rand seeded alike get the same feed while the articles and their arms
+// don't change. The served articles are recorded as pulls; failures to record
+// are logged rather than returned.
+func (s *Services) Discover(ctx context.Context, request DiscoveryRequest) ([]models.Article, error) {
+	database := s.db.WithContext(ctx)
+	options := s.discovery
+
+	var preferences map[string]float64
+	var seen map[string]bool
+	if request.Personal && request.AnonymousID != "" {
+		var err error
+		if preferences, seen, err = s.viewerPreferences(ctx, SessionViewer(ctx, request.AnonymousID)); err != nil {
+			return nil, err
+		}
+	}
+
+	var pool []models.Article
+	err := database.
+		Scopes(request.Filter.Scope).
+		Order("publication_date DESC").
+		Order("id").
+		Limit(options.PoolSize).
+		Find(&pool).Error
+	if err != nil {
+		return nil, err
+	}
+	candidates := pool[:0]
+	for _, article := range pool {
+		if !seen[article.ID] {
+			candidates = append(candidates, article)
+		}
+	}
+	if len(candidates) == 0 {
+		return []models.Article{}, nil
+	}
+
+	arms, prior, err := s.discoveryArms(ctx, []string(articleIDs(candidates)))
+	if err != nil {
+		return nil, err
+	}
+	weight := func(article models.Article) float64 {
+		return 1 + categoryShare(article.Category, preferences)
+	}
+
+	rng := request.Rand
+	if rng == nil {
+		rng = rand.New(rand.NewSource(time.Now().UnixNano()))
+	}
+	var picked []models.Article
+	var choices []string
+	if options.Policy == DiscoveryEpsilonGreedy {
+		picked, choices = epsilonGreedy(rng, candidates, arms, prior, options.Epsilon, request.Limit, weight)
+	} else {
+		picked, choices = thompsonSample(rng, candidates, arms, prior, request.Limit, weight)
+	}
+	for i := range picked {
+		arm := arms[picked[i].ID]
+		picked[i].Explanation = &models.ScoreExplanation{Ranker: "discovery_" + choices[i], Final: posteriorMean(arm, prior)}
+	}
+
+	s.recordPulls(ctx, request, picked, choices)
+	return picked, nil
+}
+
+// viewerPreferences returns the share of a viewer's recent events in each
+// category and the articles they saw
+func (s *Services) viewerPreferences(ctx context.Context, viewer string) (map[string]float64, map[string]bool, error) {
+	var rows []struct {
+		ArticleID string
+		Category  models.StringArray
+	}
+	err := s.db.WithContext(ctx).Model(&models.Event{}).
+		Select("events.article_id, articles.category").
+		Joins("JOIN articles ON articles.id = events.article_id").
+		Where("events.viewer = ? AND events.timestamp > ?", viewer, time.Now().Add(-discoveryHistory)).
+		Order("events.timestamp DESC").
+		Limit(discoveryHistoryEvents).
+		Scan(&rows).Error
+	if err != nil {
+		return nil, nil, err
+	}
+
+	preferences := make(map[string]float64)
+	seen := make(map[string]bool, len(rows))
+	for _, row := range rows {
+		seen[row.ArticleID] = true
+		for _, category := range row.Category {
+			preferences[strings.ToLower(category)] += 1 / float64(len(rows))
+		}
+	}
+	return preferences, seen, nil
+}
+
+// categoryShare returns the largest preference among an article's categories
+func categoryShare(categories []string, preferences map[string]float64) float64 {
+	share := 0.0
+	for _, category := range categories {
+		share = math.Max(share, preferences[strings.ToLower(category)])
+	}
+	return math.Min(share, 1)
+}
+
+// discoveryArms returns the arms of the given articles that were served
+// before and the prior CTR every arm's posterior starts from: the CTR over
+// all of the tenant's arms, smoothed towards a half
+func (s *Services) discoveryArms(ctx context.Context, ids []string) (map[string]models.DiscoveryArm, float64, error) {
+	database := s.db.WithContext(ctx)
+	var rows []models.DiscoveryArm
+	if err := database.Where("article_id IN ?", ids).Find(&rows).Error; err != nil {
+		return nil, 0, err
+	}
+	arms := make(map[string]models.DiscoveryArm, len(rows))
+	for _, arm := range rows {
+		arms[arm.ArticleID] = arm
+	}
+
+	prior, err := s.discoveryPrior(database)
+	return arms, prior, err
+}
+
+// discoveryPrior returns the CTR over the arms database reads, smoothed
+// towards a half
+func (s *Services) discoveryPrior(database *gorm.DB) (float64, error) {
+	var total struct {
+		Pulls  int64
+		Clicks int64
+	}
+	err := database.Model(&models.DiscoveryArm{}).
+		Select("COALESCE(SUM(pulls), 0) AS pulls, COALESCE(SUM(clicks), 0) AS clicks").
+		Scan(&total).Error
+	return float64(total.Clicks+1) / float64(total.Pulls+2), err
+}
+
+// posteriorMean is an arm's expected CTR: its clicks over its pulls, with the
+// prior counting for discoveryPriorPulls pulls
+func posteriorMean(arm models.DiscoveryArm, prior float64) float64 {
+	return (float64(arm.Clicks) + prior*discoveryPriorPulls) / (float64(arm.Pulls) + discoveryPriorPulls)
+}
+
+// thompsonSample orders the candidates by a draw from each one's Beta
+// posterior over its CTR, weighted, and returns the first limit. Little-served
+// articles have wide posteriors, so they are explored in proportion to how
+// likely they are to beat the proven ones.
+func thompsonSample(rng *rand.Rand, candidates []models.Article, arms map[string]models.DiscoveryArm, prior float64, limit int, weight func(models.Article) float64) ([]models.Article, []string) {
+	draws := make([]float64, len(candidates))
+	order := make([]int, len(candidates))
+	for i, article := range candidates {
+		arm := arms[article.ID]
+		alpha := prior*discoveryPriorPulls + float64(arm.Clicks)
+		beta := (1-prior)*discoveryPriorPulls + float64(arm.Pulls-arm.Clicks)
+		draws[i] = sampleBeta(rng, alpha, math.Max(beta, 0)) * weight(article)
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool { return draws[order[a]] > draws[order[b]] })
+
+	if limit > len(order) {
+		limit = len(order)
+	}
+	picked := make([]models.Article, limit)
+	choices := make([]string, limit)
+	for i := 0; i < limit; i++ {
+		picked[i] = candidates[order[i]]
+		choices[i] = DiscoverySample
+	}
+	return picked, choices
+}
+
+// epsilonGreedy fills each slot, with probability epsilon, with a random
+// candidate served fewer than discoveryFreshPulls times, or any candidate
+// when none is, and otherwise with the best weighted posterior mean
+func epsilonGreedy(rng *rand.Rand, candidates []models.Article, arms map[string]models.DiscoveryArm, prior, epsilon float64, limit int, weight func(models.Article) float64) ([]models.Article, []string) {
+	remaining := append([]models.Article(nil), candidates...)
+	var picked []models.Article
+	var choices []string
+	for len(picked) < limit && len(remaining) > 0 {
+		var index int
+		choice := DiscoveryExploit
+		if rng.Float64() < epsilon {
+			choice = DiscoveryExplore
+			var fresh []int
+			for i, article := range remaining {
+				if arms[article.ID].Pulls < discoveryFreshPulls {
+					fresh = append(fresh, i)
+				}
+			}
+			if len(fresh) > 0 {
+				index = fresh[rng.Intn(len(fresh))]
+			} else {
+				index = rng.Intn(len(remaining))
+			}
+		} else {
+			best := -1.0
+			for i, article := range remaining {
+				if value := posteriorMean(arms[article.ID], prior) * weight(article); value > best {
+					index, best = i, value
+				}
+			}
+		}
+		picked = append(picked, remaining[index])
+		choices = append(choices, choice)
+		remaining = append(remaining[:index], remaining[index+1:]...)
+	}
+	return picked, choices
+}
+
+// sampleBeta draws from a Beta distribution as the share of the first of two
+// Gamma draws
+func sampleBeta(rng *rand.Rand, alpha, beta float64) float64 {
+	x := sampleGamma(rng, alpha)
+	y := sampleGamma(rng, beta)
+	if x+y == 0 {
+		return alpha / (alpha + beta)
+	}
+	return x / (x + y)
+}
+
+// sampleGamma draws from a Gamma distribution of unit scale with the method
+// of Marsaglia and Tsang
+func sampleGamma(rng *rand.Rand, shape float64) float64 {
+	if shape <= 0 {
+		return 0
+	}
+	if shape < 1 {
+		return sampleGamma(rng, shape+1) * math.Pow(rng.Float64(), 1/shape)
+	}
+	d := shape - 1.0/3
+	c := 1 / math.Sqrt(9*d)
+	for {
+		x := rng.NormFloat64()
+		v := 1 + c*x
+		if v <= 0 {
+			continue
+		}
+		v = v * v * v
+		u := rng.Float64()
+		if u < 1-0.0331*x*x*x*x || math.Log(u) < 0.5*x*x+d*(1-v+math.Log(v)) {
+			return d * v
+		}
+	}
+}
+
+// recordPulls records the served articles as pulls of their arms
+func (s *Services) recordPulls(ctx context.Context, request DiscoveryRequest, articles []models.Article, choices []string) {
+	if len(articles) == 0 {
+		return
+	}
+	database := s.db.WithContext(ctx)
+	tenantID := tenant.IDFromContext(ctx)
+	viewer := viewerHash(tenantID, request.ClientID)
+	now := time.Now()
+
+	pulls := make([]models.DiscoveryPull, len(articles))
+	arms := make([]models.DiscoveryArm, len(articles))
+	for i, article := range articles {
+		pulls[i] = models.DiscoveryPull{
+			ArticleID: article.ID,
+			Endpoint:  request.Endpoint,
+			Position:  i + 1,
+			Policy:    s.discovery.Policy,
+			Choice:    choices[i],
+			Viewer:    viewer,
+			TenantID:  article.TenantID,
+			CreatedAt: now,
+		}
+		arms[i] = models.DiscoveryArm{ArticleID: article.ID, Pulls: 1, LastPulledAt: &now, TenantID: article.TenantID}
+	}
+	if err := database.Create(&pulls).Error; err != nil {
+		log.Printf("Failed to record discovery pulls: %v", err)
+		return
+	}
+	err := database.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "article_id"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"pulls":          gorm.Expr("discovery_arms.pulls + 1"),
+			"last_pulled_at": now,
+			"updated_at":     now,
+		}),
+	}).Create(&arms).Error
+	if err != nil {
+		log.Printf("Failed to count discovery pulls: %v", err)
+	}
+}
+
+// recordDiscoveryClick marks the viewer's latest unclicked pull of the
+// clicked article within the attribution window as clicked and counts the
+// click for its arm
+func (s *Services) recordDiscoveryClick(ctx context.Context, event *models.Event, clientID string) {
+	database := s.db.WithContext(ctx)
+	now := time.Now()
+	latest := database.Model(&models.DiscoveryPull{}).Select("MAX(id)").
+		Where("tenant_id = ? AND viewer = ? AND article_id = ? AND clicked_at IS NULL AND created_at > ?",
+			event.TenantID, viewerHash(event.TenantID, clientID), event.ArticleID, now.Add(-discoveryClickWindow))
+	result := database.Model(&models.DiscoveryPull{}).Where("id = (?)", latest).Update("clicked_at", now)
+	if result.Error != nil {
+		log.Printf("Failed to attribute click on %s to the discovery feed: %v", event.ArticleID, result.Error)
+		return
+	}
+	if result.RowsAffected == 0 {
+		return
+	}
+	err := database.Model(&models.DiscoveryArm{}).Where("article_id = ?", event.ArticleID).
+		Updates(map[string]interface{}{"clicks": gorm.Expr("clicks + 1"), "updated_at": now}).Error
+	if err != nil {
+		log.Printf("Failed to count discovery click on %s: %v", event.ArticleID, err)
+	}
+}
+
+// DiscoveryChoiceStats are the pulls and clicks of the slots a policy filled
+// one way
+type DiscoveryChoiceStats struct {
+	Policy string  `json:"policy"`
+	Choice string  `json:"choice"`
+	Pulls  int64   `json:"pulls"`
+	Clicks int64   `json:"clicks"`
+	CTR    float64 `json:"ctr"`
+}
+
+// DiscoveryArmStats is an arm with its observed and expected CTR
+type DiscoveryArmStats struct {
+	models.DiscoveryArm
+	CTR  float64 `json:"ctr"`            // Clicks per pull
+	Mean float64 `json:"posterior_mean"` // CTR expected with the prior, which exploitation ranks by
+}
+
+// DiscoveryReport describes how the discovery feed is ordering articles
+type DiscoveryReport struct {
+	Policy   string                 `json:"policy"`
+	Epsilon  float64                `json:"epsilon,omitempty"` // With epsilon_greedy
+	PoolSize int                    `json:"pool_size"`
+	Prior    float64                `json:"prior_ctr"` // CTR every arm's posterior starts from
+	Choices  []DiscoveryChoiceStats `json:"choices"`   // Pulls since the report's start, by policy and choice
+	Arms     []DiscoveryArmStats    `json:"arms"`      // Best posterior mean first
+}
+
+// DiscoveryReports returns the feed's settings, its pulls and clicks since a
+// time per policy and choice, and the limit arms with the best posterior
+// mean. An empty tenantID covers every tenant's feed.
+func (s *Services) DiscoveryReports(ctx context.Context, since time.Time, tenantID string, limit int) (*DiscoveryReport, error) {
+	database := s.db.WithContext(ctx)
+	scoped := func(model interface{}) *gorm.DB {
+		query := database.Model(model)
+		if tenantID != "" {
+			query = query.Where("tenant_id = ?", tenantID)
+		}
+		return query
+	}
+
+	report := &DiscoveryReport{Policy: s.discovery.Policy, PoolSize: s.discovery.PoolSize, Choices: []DiscoveryChoiceStats{}}
+	if report.Policy == DiscoveryEpsilonGreedy {
+		report.Epsilon = s.discovery.Epsilon
+	}
+	var err error
+	if report.Prior, err = s.discoveryPrior(scoped(&models.DiscoveryArm{})); err != nil {
+		return nil, err
+	}
+
+	err = scoped(&models.DiscoveryPull{}).
+		Select("policy, choice, COUNT(*) AS pulls, COUNT(clicked_at) AS clicks").
+		Where("created_at >= ?", since).
+		Group("policy, choice").
+		Order("policy, choice").
+		Scan(&report.Choices).Error
+	if err != nil {
+		return nil, err
+	}
+	for i := range report.Choices {
+		if choice := &report.Choices[i]; choice.Pulls > 0 {
+			choice.CTR = float64(choice.Clicks) / float64(choice.Pulls)
+		}
+	}
+
+	var arms []models.DiscoveryArm
+	err = scoped(&models.DiscoveryArm{}).
+		Order(clause.OrderBy{Expression: clause.Expr{
+			SQL:  "(clicks + ?) / (pulls + ?) DESC, pulls DESC, article_id",
+			Vars: []interface{}{report.Prior * discoveryPriorPulls, float64(discoveryPriorPulls)},
+		}}).
+		Limit(limit).
+		Find(&arms).Error
+	if err != nil {
+		return nil, err
+	}
+	report.Arms = make([]DiscoveryArmStats, len(arms))
+	for i, arm := range arms {
+		report.Arms[i] = DiscoveryArmStats{DiscoveryArm: arm, Mean: posteriorMean(arm, report.Prior)}
+		if arm.Pulls > 0 {
+			report.Arms[i].CTR = float64(arm.Clicks) / float64(arm.Pulls)
+		}
+	}
+	return report, nil
+}
@@ -0,0 +1,64 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math"
+
+	"github.com/mahigadamsetty/Inshorts-task/internal/models"
+	"github.com/mahigadamsetty/Inshorts-task/internal/utils"
+)
+
+// distanceSteps is how many steps a kilometer is rounded to, 100 m, so
+// distances don't give away articles' exact coordinates
+const distanceSteps = 10
+
+// ArticleDistance is how far an article is from a point
+type ArticleDistance struct {
+	ArticleID  string  `json:"article_id"`
+	DistanceKm float64 `json:"distance_km"`
+}
+
+// ArticleDistances returns the great-circle distance from a point to each of
+// the articles visible to the context's tenant, in the order asked and once
+// per article. Articles that don't exist are returned as missing and those
+// without a usable location as unlocated.
+func (s *Services) ArticleDistances(ctx context.Context, lat, lon float64, ids []string) (distances []ArticleDistance, missing, unlocated []string, err error) {
+	database := s.db.WithContext(ctx)
+	if database == nil {
+		return nil, nil, nil, fmt.Errorf("database not initialized")
+	}
+
+	var articles []models.Article
+	if err := database.Select("id", "latitude", "longitude", "location_source").Where("id IN ?", ids).Find(&articles).Error; err != nil {
+		return nil, nil, nil, err
+	}
+	byID := make(map[string]models.Article, len(articles))
+	for _, article := range articles {
+		byID[article.ID] = article
+	}
+
+	distances = []ArticleDistance{}
+	missing, unlocated = []string{}, []string{}
+	seen := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		article, ok := byID[id]
+		switch {
+		case !ok:
+			missing = append(missing, id)
+		case !article.HasLocation():
+			unlocated = append(unlocated, id)
+		default:
+			km := utils.HaversineDistance(lat, lon, article.Latitude, article.Longitude)
+			distances = append(distances, ArticleDistance{
+				ArticleID:  id,
+				DistanceKm: math.Round(km*distanceSteps) / distanceSteps,
+			})
+		}
+	}
+	return distances, missing, unlocated, nil
+}
@@ -0,0 +1,77 @@
+package services
+
+import (
+	"strings"
+
+	"github.com/mahigadamsetty/Inshorts-task/internal/models"
+)
+
+// DiversityLimits caps how many articles from one source or category may lead
+// a ranked list. Zero disables a limit.
+type DiversityLimits struct {
+	MaxPerSource   int `json:"source,omitempty"`
+	MaxPerCategory int `json:"category,omitempty"`
+}
+
+// Diversify reorders ranked articles so that, wherever possible, no source or
+// category appears more often than its limit. Articles over a limit are not
+// dropped but moved after the rest in their ranked order, so a list with too
+// little variety still fills up. An article counts toward each of its
+// categories and is held back if any of them is full. The category limit is
+// given up first: held back articles are placed under the source limit alone
+// before the rest follow, so a single-category list is still spread across
+// sources.
+func Diversify(articles []models.Article, limits DiversityLimits) []models.Article {
+	if limits.MaxPerSource <= 0 && limits.MaxPerCategory <= 0 {
+		return articles
+	}
+
+	d := diversifier{
+		perSource:   make(map[string]int),
+		perCategory: make(map[string]int),
+		result:      make([]models.Article, 0, len(articles)),
+	}
+	deferred := d.place(articles, limits)
+	deferred = d.place(deferred, DiversityLimits{MaxPerSource: limits.MaxPerSource})
+	return append(d.result, deferred...)
+}
+
+// diversifier accumulates a diversified list and how often each source and
+// category appears in it
+type diversifier struct {
+	perSource   map[string]int
+	perCategory map[string]int
+	result      []models.Article
+}
+
+// place appends the articles within limits to the result and returns the rest
+func (d *diversifier) place(articles []models.Article, limits DiversityLimits) []models.Article {
+	var deferred []models.Article
+	for _, article := range articles {
+		if !d.fits(article, limits) {
+			deferred = append(deferred, article)
+			continue
+		}
+		d.perSource[strings.ToLower(article.SourceName)]++
+		for _, category := range article.Category {
+			d.perCategory[strings.ToLower(category)]++
+		}
+		d.result = append(d.result, article)
+	}
+	return deferred
+}
+
+// fits reports whether an article can be added without exceeding a limit
+func (d *diversifier) fits(article models.Article, limits DiversityLimits) bool {
+	if limits.MaxPerSource > 0 && d.perSource[strings.ToLower(article.SourceName)] >= limits.MaxPerSource {
+		return false
+	}
+	if limits.MaxPerCategory > 0 {
+		for _, category := range article.Category {
+			if d.perCategory[strings.ToLower(category)] >= limits.MaxPerCategory {
+				return false
+			}
+		}
+	}
+	return true
+}
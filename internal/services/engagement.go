@@ -0,0 +1,146 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/mahigadamsetty/Inshorts-task/internal/apperr"
+	"github.com/mahigadamsetty/Inshorts-task/internal/models"
+)
+
+// Groupings of engagement reports
+const (
+	EngagementBySource   = "source"
+	EngagementByCategory = "category"
+	EngagementByPlatform = "platform"
+)
+
+// uncategorized labels the engagement of articles without a category
+const uncategorized = "uncategorized"
+
+// EngagementRow is the engagement of one source, category or client platform,
+// on one day or over the whole report
+type EngagementRow struct {
+	Day    string  `json:"day,omitempty"` // UTC date, empty for totals
+	Group  string  `json:"group"`         // Source name, category or platform
+	Views  int64   `json:"views"`
+	Clicks int64   `json:"clicks"`
+	CTR    float64 `json:"ctr"` // Clicks per view, 0 without views
+}
+
+// EngagementReport aggregates events by source, category or platform and day
+type EngagementReport struct {
+	Since   time.Time       `json:"since"`
+	GroupBy string          `json:"group_by"`
+	Days    []EngagementRow `json:"days"`   // By day, then group
+	Totals  []EngagementRow `json:"totals"` // By clicks, most first
+}
+
+// articleDayEngagement is the engagement of one article on one day and
+// platform
+type articleDayEngagement struct {
+	Day        string
+	Platform   string
+	SourceName string
+	Category   models.StringArray
+	Views      int64
+	Clicks     int64
+}
+
+// EngagementByGroup aggregates the events recorded since a time into views,
+// clicks and click-through rates per source, category or client platform and
+// UTC day. An article in several categories counts towards each. An empty
+// tenantID covers every tenant.
+func (s *Services) EngagementByGroup(ctx context.Context, groupBy string, since time.Time, tenantID string) (*EngagementReport, error) {
+	if groupBy != EngagementBySource && groupBy != EngagementByCategory && groupBy != EngagementByPlatform {
+		return nil, apperr.InvalidFilterf("group_by must be %s, %s or %s", EngagementBySource, EngagementByCategory, EngagementByPlatform)
+	}
+	database := s.db.WithContext(ctx)
+	if database == nil {
+		return nil, fmt.Errorf("database not initialized")
+	}
+
+	query := database.Table("events").
+		Select("DATE(events.timestamp) AS day, events.platform, articles.source_name, articles.category, "+
+			"SUM(CASE WHEN events.event_type = ? THEN 1 ELSE 0 END) AS views, "+
+			"SUM(CASE WHEN events.event_type = ? THEN 1 ELSE 0 END) AS clicks",
+			models.EventTypeView, models.EventTypeClick).
+		Joins("JOIN articles ON articles.id = events.article_id").
+		Scopes(s.trafficScope(ctx)).
+		Where("events.timestamp >= ?", since).
+		Group("articles.id, DATE(events.timestamp), events.platform")
+	if tenantID != "" {
+		query = query.Where("events.tenant_id = ?", tenantID)
+	}
+	var rows []articleDayEngagement
+	if err := query.Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	type groupDay struct{ group, day string }
+	byDay := map[groupDay]*EngagementRow{}
+	totals := map[string]*EngagementRow{}
+	for _, row := range rows {
+		groups := []string{row.SourceName}
+		switch groupBy {
+		case EngagementByCategory:
+			groups = row.Category
+			if len(groups) == 0 {
+				groups = []string{uncategorized}
+			}
+		case EngagementByPlatform:
+			groups = []string{row.Platform}
+		}
+		for _, group := range groups {
+			key := groupDay{group, row.Day}
+			if byDay[key] == nil {
+				byDay[key] = &EngagementRow{Day: row.Day, Group: group}
+			}
+			if totals[group] == nil {
+				totals[group] = &EngagementRow{Group: group}
+			}
+			for _, entry := range []*EngagementRow{byDay[key], totals[group]} {
+				entry.Views += row.Views
+				entry.Clicks += row.Clicks
+			}
+		}
+	}
+
+	report := &EngagementReport{
+		Since:   since,
+		GroupBy: groupBy,
+		Days:    make([]EngagementRow, 0, len(byDay)),
+		Totals:  make([]EngagementRow, 0, len(totals)),
+	}
+	for _, row := range byDay {
+		report.Days = append(report.Days, row.withCTR())
+	}
+	for _, row := range totals {
+		report.Totals = append(report.Totals, row.withCTR())
+	}
+	sort.Slice(report.Days, func(i, j int) bool {
+		a, b := report.Days[i], report.Days[j]
+		if a.Day != b.Day {
+			return a.Day < b.Day
+		}
+		return a.Group < b.Group
+	})
+	sort.Slice(report.Totals, func(i, j int) bool {
+		a, b := report.Totals[i], report.Totals[j]
+		if a.Clicks != b.Clicks {
+			return a.Clicks > b.Clicks
+		}
+		return a.Group < b.Group
+	})
+	return report, nil
+}
+
+// withCTR returns the row with its click-through rate filled in
+func (r EngagementRow) withCTR() EngagementRow {
+	if r.Views > 0 {
+		r.CTR = float64(r.Clicks) / float64(r.Views)
+	}
+	return r
+}
@@ -0,0 +1,195 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/mahigadamsetty/Inshorts-task/internal/apperr"
+	"github.com/mahigadamsetty/Inshorts-task/internal/models"
+	"github.com/mahigadamsetty/Inshorts-task/internal/platform"
+	"github.com/mahigadamsetty/Inshorts-task/internal/tenant"
+	"gorm.io/gorm"
+)
+
+// eventBatchSize is how many events are inserted per statement
+const eventBatchSize = 500
+
+// ErrEventQueueFull is returned when the event queue can't take more events
+// until its next flush
+var ErrEventQueueFull = apperr.Unavailablef("too many events queued, retry later")
+
+// queuedEvent is an event waiting to be written, with what is needed to count
+// it once it is
+type queuedEvent struct {
+	event    models.Event
+	clientID string
+	tenant   *tenant.Tenant
+	failures int // Flushes that failed to write it
+}
+
+// EventQueue buffers ingested events in memory and periodically writes them
+// in batches, so bursts of client traffic cost one insert per flush
+type EventQueue struct {
+	pending     []queuedEvent
+	capacity    int
+	maxAttempts int
+	mu          sync.Mutex
+}
+
+// InitEventQueue initializes the event queue, holding up to capacity events
+// and flushing every interval seconds. An event that fails to be written
+// maxAttempts times is dropped. With a non-positive interval there is no
+// queue and QueueEvents writes events as they arrive.
+func (s *Services) InitEventQueue(capacity, interval, maxAttempts int) {
+	if interval <= 0 {
+		s.events = nil
+		return
+	}
+	s.events = &EventQueue{capacity: capacity, maxAttempts: max(maxAttempts, 1)}
+
+	go func() {
+		for range time.Tick(time.Duration(interval) * time.Second) {
+			if _, err := s.FlushEvents(context.Background()); err != nil {
+				log.Printf("Failed to flush events: %v", err)
+			}
+		}
+	}()
+}
+
+// QueueEvents accepts client-reported events for writing on the next flush,
+// all or none of them: ErrEventQueueFull is returned when they don't fit, and
+// a *tenant.QuotaError when they exceed the tenant's monthly quota. The
+// events are timestamped, located and attributed to the context's tenant and
+// platform in place now, and counted once written. clientIDs holds the client
+// ID of each event, used as for RecordEvent. Without a queue, the events are
+// written right away.
+func (s *Services) QueueEvents(ctx context.Context, events []models.Event, clientIDs []string) error {
+	now := time.Now()
+	t, _ := tenant.FromContext(ctx)
+	queued := make([]queuedEvent, len(events))
+	for i := range events {
+		event := &events[i]
+		if event.Timestamp.IsZero() {
+			event.Timestamp = now
+		}
+		if event.Platform == "" {
+			event.Platform = platform.FromContext(ctx)
+		}
+		if event.TenantID == "" && t != nil {
+			event.TenantID = t.ID
+		}
+		event.Latitude, event.Longitude = s.PrivateLocation(event.Latitude, event.Longitude, true)
+		queued[i] = queuedEvent{event: *event, clientID: clientIDs[i], tenant: t}
+	}
+
+	queue := s.events
+	if queue == nil {
+		if err := s.ConsumeQuota(ctx, tenant.QuotaEvents, int64(len(queued))); err != nil {
+			return err
+		}
+		for _, q := range queued {
+			if err := s.RecordEvent(ctx, &q.event, q.clientID); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	queue.mu.Lock()
+	defer queue.mu.Unlock()
+	if len(queue.pending)+len(queued) > queue.capacity {
+		return ErrEventQueueFull
+	}
+	if err := s.ConsumeQuota(ctx, tenant.QuotaEvents, int64(len(queued))); err != nil {
+		return err
+	}
+	queue.pending = append(queue.pending, queued...)
+	return nil
+}
+
+// FlushEvents writes the queued events, counts them towards the view counters,
+// impressions and shadow ranking clicks, and returns how many were written.
+// When the batch fails to save, the events are written one at a time, so one
+// that can't be doesn't hold up the rest; those that fail are kept for the
+// next flush, until they have failed maxAttempts times.
+func (s *Services) FlushEvents(ctx context.Context) (int, error) {
+	queue := s.events
+	if queue == nil {
+		return 0, nil
+	}
+	database := s.db.WithContext(ctx)
+	if database == nil {
+		return 0, fmt.Errorf("database not initialized")
+	}
+
+	queue.mu.Lock()
+	pending := queue.pending
+	queue.pending = nil
+	queue.mu.Unlock()
+	if len(pending) == 0 {
+		return 0, nil
+	}
+
+	events := make([]models.Event, len(pending))
+	for i, q := range pending {
+		events[i] = q.event
+	}
+	var err error
+	if err = database.CreateInBatches(events, eventBatchSize).Error; err == nil {
+		for i := range pending {
+			pending[i].event = events[i]
+		}
+	} else {
+		pending, err = queue.saveEach(database, pending)
+	}
+
+	for i := range pending {
+		q := &pending[i]
+		event := &q.event
+		s.countEvent(event.TenantID, event.ArticleID, event.EventType, q.clientID)
+		if event.EventType == models.EventTypeClick {
+			eventCtx := platform.NewContext(ctx, event.Platform)
+			if q.tenant != nil {
+				eventCtx = tenant.NewContext(eventCtx, q.tenant)
+			}
+			s.recordImpressionClick(eventCtx, event, q.clientID)
+			s.recordShadowClick(eventCtx, event, q.clientID)
+			s.recordDiscoveryClick(eventCtx, event, q.clientID)
+		}
+	}
+	return len(pending), err
+}
+
+// saveEach writes events one at a time after their batch failed and returns
+// those written. Those that fail go back to the front of the queue, or are
+// dropped once they have failed maxAttempts times.
+func (queue *EventQueue) saveEach(database *gorm.DB, pending []queuedEvent) ([]queuedEvent, error) {
+	var saved, retry []queuedEvent
+	var firstErr error
+	dropped := 0
+	for _, q := range pending {
+		if err := database.Create(&q.event).Error; err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			if q.failures++; q.failures >= queue.maxAttempts {
+				dropped++
+				continue
+			}
+			retry = append(retry, q)
+			continue
+		}
+		saved = append(saved, q)
+	}
+	if dropped > 0 {
+		log.Printf("Dropped %d events that failed to save %d times: %v", dropped, queue.maxAttempts, firstErr)
+	}
+
+	queue.mu.Lock()
+	queue.pending = append(retry, queue.pending...)
+	queue.mu.Unlock()
+	return saved, firstErr
+}
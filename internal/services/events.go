@@ -1,45 +1,68 @@
 package services
 
 import (
+	"context"
 	"fmt"
 	"math/rand"
 	"time"
 
-	"github.com/mahigadamsetty/Inshorts-task/internal/db"
+	"github.com/mahigadamsetty/Inshorts-task/internal/apperr"
 	"github.com/mahigadamsetty/Inshorts-task/internal/models"
 )
 
+// CheckArticle returns an apperr.ErrNotFound unless the article exists for
+// the tenant in ctx
+func (s *Services) CheckArticle(ctx context.Context, id string) error {
+	var article models.Article
+	err := s.db.WithContext(ctx).Select("id").Where("id = ?", id).First(&article).Error
+	return apperr.NotFound(err, "Article")
+}
+
+// simulatedClients is the size of the pool simulated events draw viewers from
+const simulatedClients = 200
+
 // SimulateUserEvents creates a specified number of random user events (views/clicks)
-// for a given list of articles.
-func SimulateUserEvents(articles []models.Article, count int) error {
-	database := db.GetDB()
+// for a given list of articles, counting them towards the view counters. The
+// same seed and articles always produce the same events. It returns
+// ErrSimulationDisabled unless the simulation is enabled.
+func (s *Services) SimulateUserEvents(articles []models.Article, count int, seed int64) error {
+	database := s.db
 	if database == nil {
 		return fmt.Errorf("database not initialized")
 	}
 
+	rng := rand.New(rand.NewSource(seed))
 	for i := 0; i < count; i++ {
+		if !s.SimulationEnabled() {
+			return ErrSimulationDisabled
+		}
+
 		// Pick a random article
-		article := articles[rand.Intn(len(articles))]
+		article := articles[rng.Intn(len(articles))]
 
 		// Simulate a user location near the article's location
-		userLat := article.Latitude + (rand.Float64()-0.5)*0.5 // within ~55km
-		userLon := article.Longitude + (rand.Float64()-0.5)*0.5
+		userLat := article.Latitude + (rng.Float64()-0.5)*0.5 // within ~55km
+		userLon := article.Longitude + (rng.Float64()-0.5)*0.5
 
 		// Decide event type (80% view, 20% click)
 		eventType := models.EventTypeView
-		if rand.Float64() < 0.2 {
+		if rng.Float64() < 0.2 {
 			eventType = models.EventTypeClick
 		}
 
+		clientID := fmt.Sprintf("simulated-%d", rng.Intn(simulatedClients))
 		event := models.Event{
 			ArticleID: article.ID,
 			EventType: eventType,
 			Latitude:  userLat,
 			Longitude: userLon,
 			Timestamp: time.Now(),
+			Simulated: true,
+			TenantID:  article.TenantID,
+			Viewer:    viewerHash(article.TenantID, clientID),
 		}
 
-		if err := database.Create(&event).Error; err != nil {
+		if err := s.RecordEvent(context.Background(), &event, clientID); err != nil {
 			// Log or handle individual event creation errors if necessary,
 			// but continue simulating other events.
 		}
@@ -0,0 +1,241 @@
+package services
+
+import (
+	"bytes"
+	"math"
+	"net/url"
+	"strings"
+	"unicode/utf8"
+
+	readability "github.com/go-shiori/go-readability"
+	"github.com/mahigadamsetty/Inshorts-task/internal/models"
+	"golang.org/x/net/html"
+)
+
+// extractionTargetWords is the word count at which extracted text stops gaining
+// quality for its length
+const extractionTargetWords = 300
+
+// Paragraph heuristic thresholds
+const (
+	minParagraphChars = 40  // Shorter paragraphs are mostly captions, bylines and buttons
+	maxLinkDensity    = 0.5 // Share of a paragraph's text inside links above which it is navigation
+)
+
+// fetchedPage is a downloaded article page handed to the extraction strategies
+type fetchedPage struct {
+	body        []byte     // nil when the page could not be fetched
+	url         *url.URL   // Final URL after redirects
+	doc         *html.Node // nil when the HTML could not be parsed
+	description string     // The article's own description
+}
+
+// extractionStrategy pulls readable text out of a page
+type extractionStrategy struct {
+	name      string
+	needsPage bool
+	extract   func(page *fetchedPage) string
+}
+
+// extractionChain lists the strategies from most to least complete text
+var extractionChain = []extractionStrategy{
+	{models.ExtractionReadability, true, extractReadability},
+	{models.ExtractionMetaDescription, true, extractMetaDescription},
+	{models.ExtractionParagraphs, true, extractParagraphs},
+	{models.ExtractionDescription, false, func(page *fetchedPage) string { return page.description }},
+}
+
+// Elements that never hold article text
+var boilerplateElements = map[string]bool{
+	"script": true, "style": true, "noscript": true, "nav": true, "header": true, "footer": true,
+	"aside": true, "form": true, "figure": true, "figcaption": true, "button": true, "iframe": true, "svg": true,
+}
+
+// Class and id fragments of containers holding comments, promotions and links
+// to other articles
+var boilerplateHints = []string{"comment", "related", "share", "social", "newsletter", "promo", "sidebar", "footer", "breadcrumb", "advert"}
+
+// extractReadability runs go-readability over the page
+func extractReadability(page *fetchedPage) string {
+	article, err := readability.FromReader(bytes.NewReader(page.body), page.url)
+	if err != nil {
+		return ""
+	}
+	return article.TextContent
+}
+
+// extractMetaDescription returns the og:description, twitter:description or
+// description meta tag, in that order of preference
+func extractMetaDescription(page *fetchedPage) string {
+	return firstMeta(metaTags(page), "og:description", "twitter:description", "description")
+}
+
+// extractImage returns the page's og:image or twitter:image as an absolute
+// http or https URL, or "" when it has none
+func extractImage(page *fetchedPage) string {
+	found := metaTags(page)
+	image := firstMeta(found, "og:image:secure_url", "og:image", "og:image:url", "twitter:image", "twitter:image:src")
+	if image == "" {
+		return ""
+	}
+	ref, err := url.Parse(image)
+	if err != nil {
+		return ""
+	}
+	if page.url != nil {
+		ref = page.url.ResolveReference(ref)
+	}
+	if (ref.Scheme != "http" && ref.Scheme != "https") || ref.Host == "" {
+		return ""
+	}
+	return ref.String()
+}
+
+// metaTags returns the content of the head's meta tags by lowercased property
+// or name, keeping the first of repeated tags
+func metaTags(page *fetchedPage) map[string]string {
+	found := map[string]string{}
+	if page.doc == nil {
+		return found
+	}
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode && n.Data == "meta" {
+			key := strings.ToLower(attr(n, "property"))
+			if key == "" {
+				key = strings.ToLower(attr(n, "name"))
+			}
+			if _, seen := found[key]; !seen {
+				found[key] = attr(n, "content")
+			}
+			return
+		}
+		if n.Type == html.ElementNode && n.Data == "body" {
+			return
+		}
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			walk(child)
+		}
+	}
+	walk(page.doc)
+	return found
+}
+
+// firstMeta returns the first non-empty content among the given meta keys
+func firstMeta(found map[string]string, keys ...string) string {
+	for _, key := range keys {
+		if content := strings.TrimSpace(found[key]); content != "" {
+			return content
+		}
+	}
+	return ""
+}
+
+// extractParagraphs keeps the page's prose paragraphs, skipping boilerplate
+// containers, short fragments and link lists, in the spirit of trafilatura
+func extractParagraphs(page *fetchedPage) string {
+	if page.doc == nil {
+		return ""
+	}
+	var paragraphs []string
+	seen := map[string]bool{}
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode {
+			if boilerplateElements[n.Data] || hasBoilerplateHint(n) {
+				return
+			}
+			if n.Data == "p" {
+				text, linkChars := nodeText(n, false)
+				text = strings.Join(strings.Fields(text), " ")
+				if len(text) >= minParagraphChars && float64(linkChars)/float64(len(text)) <= maxLinkDensity && !seen[text] {
+					seen[text] = true
+					paragraphs = append(paragraphs, text)
+				}
+				return
+			}
+		}
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			walk(child)
+		}
+	}
+	walk(page.doc)
+	return strings.Join(paragraphs, "\n\n")
+}
+
+// nodeText returns the text under a node and how many of its bytes are inside links
+func nodeText(n *html.Node, inLink bool) (string, int) {
+	if n.Type == html.TextNode {
+		if inLink {
+			return n.Data, len(n.Data)
+		}
+		return n.Data, 0
+	}
+	if n.Type == html.ElementNode && boilerplateElements[n.Data] {
+		return "", 0
+	}
+	inLink = inLink || (n.Type == html.ElementNode && n.Data == "a")
+	var text strings.Builder
+	linkChars := 0
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		childText, childLinks := nodeText(child, inLink)
+		text.WriteString(childText)
+		linkChars += childLinks
+	}
+	return text.String(), linkChars
+}
+
+// Page-level containers are exempt from boilerplate hints, since their classes
+// often describe the layout ("has-sidebar") rather than the content
+var pageContainers = map[string]bool{"html": true, "body": true, "main": true, "article": true}
+
+// hasBoilerplateHint reports whether an element's class or id marks it as boilerplate
+func hasBoilerplateHint(n *html.Node) bool {
+	if pageContainers[n.Data] {
+		return false
+	}
+	names := strings.ToLower(attr(n, "class") + " " + attr(n, "id"))
+	for _, hint := range boilerplateHints {
+		if strings.Contains(names, hint) {
+			return true
+		}
+	}
+	return false
+}
+
+// attr returns an attribute of an element, or "" if it is missing
+func attr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+// extractionQuality scores extracted text from 0 to 1. Length counts up to
+// extractionTargetWords words, scaled by how much of the text sits in
+// sentence-like blocks rather than menus, captions and other fragments.
+func extractionQuality(text string) float64 {
+	words := len(strings.Fields(text))
+	if words == 0 {
+		return 0
+	}
+
+	proseWords := 0
+	for _, block := range strings.Split(text, "\n") {
+		block = strings.TrimSpace(block)
+		blockWords := len(strings.Fields(block))
+		if blockWords < 8 {
+			continue
+		}
+		last, _ := utf8.DecodeLastRuneInString(block)
+		if strings.ContainsRune(".!?\"'”’", last) {
+			proseWords += blockWords
+		}
+	}
+
+	length := math.Min(float64(words)/extractionTargetWords, 1)
+	prose := float64(proseWords) / float64(words)
+	return length * (0.4 + 0.6*prose)
+}
@@ -0,0 +1,327 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mahigadamsetty/Inshorts-task/internal/models"
+	"golang.org/x/net/html"
+)
+
+// minAccessibleTextLength is the shortest extracted text treated as a full article
+const minAccessibleTextLength = 500
+
+// Markers found in the HTML of pages that hide the article behind a subscription
+var paywallMarkers = []string{
+	`"isaccessibleforfree": false`,
+	`"isaccessibleforfree":false`,
+	`"isaccessibleforfree":"false"`,
+	"subscribe to continue reading",
+	"subscribe to read",
+	"this content is for subscribers",
+	"already a subscriber",
+	"class=\"paywall",
+	"id=\"paywall",
+	"piano-paywall",
+	"tp-modal",
+}
+
+// Markers found in the HTML of cookie/consent interstitials
+var consentMarkers = []string{
+	"before you continue",
+	"we value your privacy",
+	"consent.google.com",
+	"consent.yahoo.com",
+	"cookie wall",
+	"id=\"cmp-",
+	"class=\"fc-consent",
+}
+
+// extractionRetryDelay is how long a low-quality extraction waits before its
+// page is fetched again
+const extractionRetryDelay = time.Hour
+
+// TextFetchPolicy controls how article text is fetched and when poor
+// extractions are retried
+type TextFetchPolicy struct {
+	Workers     int     // Pages downloaded in parallel
+	BatchSize   int     // Articles loaded per query
+	MinQuality  float64 // Extractions scoring below this are retried (0-1)
+	MaxAttempts int     // Fetches per article before a low-quality extraction is kept for good
+
+	RefetchAfter  time.Duration // Age of a fetch after which recent articles are fetched again, 0 to never re-fetch
+	RefetchWindow time.Duration // Only articles published this recently are re-fetched
+}
+
+// FetchArticleTexts downloads the readable text of every article that has not
+// been fetched yet and stores it with the page's access classification, so
+// summaries never have to fetch URLs on the read path. Articles whose stored
+// extraction scored below the policy's minimum quality are fetched again with
+// a different strategy, keeping whichever extraction scores higher, and recent
+// articles are re-fetched to pick up updates. Text is stored with a content
+// hash; when an update changes it, the article's summary is marked stale and
+// its embedding no longer matches. Returns the number of articles whose text
+// changed.
+func (s *Services) FetchArticleTexts(ctx context.Context, policy TextFetchPolicy) (int, error) {
+	workers := policy.Workers
+	if workers <= 0 {
+		workers = 1
+	}
+	database := s.db.WithContext(ctx)
+
+	stored := 0
+	for {
+		now := time.Now()
+		due := database.
+			Where("fetched_at IS NULL").
+			Or("COALESCE(extraction_quality, 0) < ? AND COALESCE(extraction_attempts, 0) < ? AND fetched_at <= ?",
+				policy.MinQuality, policy.MaxAttempts, now.Add(-extractionRetryDelay))
+		if policy.RefetchAfter > 0 {
+			due = due.Or("publication_date >= ? AND fetched_at <= ?", now.Add(-policy.RefetchWindow), now.Add(-policy.RefetchAfter))
+		}
+
+		var articles []models.Article
+		err := database.
+			Select("id, url, description, source_name, llm_summary, fetched_at, extraction, extraction_quality, extraction_attempts, content_hash, image_url, image_blob_url").
+			Where(due).
+			Limit(policy.BatchSize).
+			Find(&articles).Error
+		if err != nil {
+			return stored, err
+		}
+		if len(articles) == 0 {
+			return stored, nil
+		}
+
+		var (
+			mu       sync.Mutex
+			wg       sync.WaitGroup
+			firstErr error
+		)
+		tasks := make(chan models.Article)
+		for i := 0; i < workers; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for article := range tasks {
+					changed, err := s.fetchArticleText(ctx, article, policy)
+					mu.Lock()
+					if err != nil && firstErr == nil {
+						firstErr = err
+					} else if changed {
+						stored++
+					}
+					mu.Unlock()
+				}
+			}()
+		}
+		for _, article := range articles {
+			tasks <- article
+		}
+		close(tasks)
+		wg.Wait()
+
+		if firstErr != nil {
+			return stored, firstErr
+		}
+		if err := ctx.Err(); err != nil {
+			return stored, err
+		}
+	}
+}
+
+// fetchArticleText fetches one article's page and stores the extraction if it
+// should replace the stored one, reporting whether the stored text changed. A
+// retry of a low-quality extraction skips the strategy that produced it and
+// only keeps a better one; a re-fetch keeps the new text unless the page failed
+// or extracted worse than before.
+func (s *Services) fetchArticleText(ctx context.Context, article models.Article, policy TextFetchPolicy) (bool, error) {
+	retry := article.FetchedAt != nil && article.ExtractionQuality < policy.MinQuality && article.ExtractionAttempts < policy.MaxAttempts
+	refetch := article.FetchedAt != nil && !retry
+
+	skip := ""
+	if retry {
+		skip = article.Extraction
+	}
+	extraction, fetchErr := s.ExtractArticleText(ctx, &article, skip, policy.MinQuality)
+	if fetchErr != nil {
+		log.Printf("Failed to fetch or parse URL %s: %v", article.URL, fetchErr)
+	}
+
+	updates := map[string]interface{}{"fetched_at": time.Now()}
+	if !refetch {
+		updates["extraction_attempts"] = article.ExtractionAttempts + 1
+	}
+	if extraction.Access != "" {
+		updates["access"] = extraction.Access
+	}
+	if fetchErr == nil {
+		updates["image_url"] = extraction.Image
+		if s.blobs.StoreImages {
+			updates["image_blob_url"] = s.storedImage(ctx, article, extraction.Image)
+		}
+	}
+
+	var keep bool
+	switch {
+	case article.FetchedAt == nil:
+		keep = extraction.Strategy != ""
+	case retry:
+		keep = extraction.Strategy != "" && extraction.Quality > article.ExtractionQuality
+	default:
+		keep = fetchErr == nil && extraction.Strategy != "" &&
+			extraction.Quality >= math.Min(policy.MinQuality, article.ExtractionQuality)
+	}
+
+	changed := false
+	if keep {
+		hash := contentHash(extraction.Text)
+		changed = hash != article.ContentHash
+		for column, value := range s.textUpdates(ctx, article.ID, extraction.Text) {
+			updates[column] = value
+		}
+		updates["extraction"] = extraction.Strategy
+		updates["extraction_quality"] = extraction.Quality
+		updates["content_hash"] = hash
+		if changed && article.LLMSummary != "" {
+			updates["summary_stale"] = true
+		}
+		if changed {
+			// Short and long summaries are regenerated on their next request
+			updates["summary_short"] = ""
+			updates["summary_long"] = ""
+		}
+	}
+
+	if err := s.db.WithContext(ctx).Model(&models.Article{ID: article.ID}).Updates(updates).Error; err != nil {
+		return false, err
+	}
+	return changed, nil
+}
+
+// contentHash fingerprints extracted text, ignoring whitespace differences
+func contentHash(text string) string {
+	sum := sha256.Sum256([]byte(strings.Join(strings.Fields(text), " ")))
+	return hex.EncodeToString(sum[:])
+}
+
+// Extraction is the readable text pulled from an article's page and how it was obtained
+type Extraction struct {
+	Text     string
+	Strategy string  // One of the models.Extraction* strategies, empty if none produced text
+	Quality  float64 // 0-1, see extractionQuality
+	Access   string  // Empty when the page could not be fetched
+	Image    string  // The page's preview image, empty when it has none or could not be fetched
+}
+
+// ExtractArticleText downloads an article through the services' crawler and runs
+// the extraction chain over it, stopping at the first strategy whose text reaches minQuality and
+// otherwise keeping the best one. The skip strategy is left out of the chain.
+// When the download fails the error is returned along with an extraction from
+// the description, so callers always get the best text available.
+func (s *Services) ExtractArticleText(ctx context.Context, article *models.Article, skip string, minQuality float64) (*Extraction, error) {
+	page, access, fetchErr := s.fetchPage(ctx, article.SourceName, article.URL)
+	page.description = article.Description
+
+	best := &Extraction{Access: access}
+	for _, strategy := range extractionChain {
+		if strategy.name == skip || (strategy.needsPage && page.body == nil) {
+			continue
+		}
+		text := strings.TrimSpace(strategy.extract(page))
+		if text == "" {
+			continue
+		}
+		quality := extractionQuality(text)
+		if best.Strategy == "" || quality > best.Quality {
+			best.Text, best.Strategy, best.Quality = text, strategy.name, quality
+		}
+		if quality >= minQuality {
+			break
+		}
+	}
+
+	if page.body != nil {
+		best.Access = detectAccess(page.body, page.url, best.Text)
+		best.Image = extractImage(page)
+	}
+	return best, fetchErr
+}
+
+// fetchPage downloads an article's page, subject to its source's crawl policy.
+// It also reports when the server refused the page as paywalled.
+func (s *Services) fetchPage(ctx context.Context, sourceName, rawURL string) (*fetchedPage, string, error) {
+	page := &fetchedPage{}
+	if rawURL == "" {
+		return page, "", fmt.Errorf("article has no URL")
+	}
+	parsedURL, err := url.Parse(rawURL)
+	if err != nil {
+		return page, "", fmt.Errorf("failed to parse URL: %w", err)
+	}
+
+	resp, err := s.crawler.Fetch(ctx, sourceName, parsedURL)
+	if err != nil {
+		return page, "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusPaymentRequired {
+		return page, models.AccessPaywalled, fmt.Errorf("failed to fetch URL: status code %d", resp.StatusCode)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return page, "", fmt.Errorf("failed to fetch URL: status code %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return page, "", err
+	}
+
+	page.body = body
+	page.url = resp.Request.URL
+	if doc, err := html.Parse(bytes.NewReader(body)); err == nil {
+		page.doc = doc
+	}
+	return page, "", nil
+}
+
+// detectAccess classifies a fetched page as open, paywalled or consent-walled
+func detectAccess(body []byte, finalURL *url.URL, text string) string {
+	// Redirects to consent hosts are the most reliable consent-wall signal
+	if finalURL != nil && strings.HasPrefix(finalURL.Host, "consent.") {
+		return models.AccessConsentWall
+	}
+
+	html := strings.ToLower(string(body))
+	textIsShort := len(strings.TrimSpace(text)) < minAccessibleTextLength
+
+	for _, marker := range paywallMarkers {
+		if strings.Contains(html, marker) {
+			return models.AccessPaywalled
+		}
+	}
+
+	// Consent phrases also appear in ordinary cookie banners, so only treat
+	// them as a wall when they leave little readable text behind
+	if textIsShort {
+		for _, marker := range consentMarkers {
+			if strings.Contains(html, marker) {
+				return models.AccessConsentWall
+			}
+		}
+	}
+
+	return models.AccessOpen
+}
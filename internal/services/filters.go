@@ -0,0 +1,91 @@
+package services
+
+import (
+	"time"
+
+	"github.com/mahigadamsetty/Inshorts-task/internal/llm"
+	"github.com/mahigadamsetty/Inshorts-task/internal/models"
+	"gorm.io/gorm"
+)
+
+// Safe-search levels accepted by the safe query parameter
+const (
+	SafeStrict   = "strict"
+	SafeModerate = "moderate"
+	SafeOff      = "off"
+)
+
+// ArticleFilter holds the result filters shared by all list endpoints
+type ArticleFilter struct {
+	ExcludePaywalled bool
+	Safe             string    // strict keeps only articles rated safe, moderate drops explicit ones
+	MinReliability   float64   // Minimum source reliability (0-1); sources without metadata count as neutral
+	PublishedFrom    time.Time // Earliest publication date (inclusive), zero for no bound
+	PublishedTo      time.Time // Latest publication date (exclusive), zero for no bound
+	State            string    // Lifecycle state, empty for any
+}
+
+// Scope applies the filter to a database query over the articles table
+func (f ArticleFilter) Scope(db *gorm.DB) *gorm.DB {
+	if f.ExcludePaywalled {
+		db = db.Where("access IS NULL OR access NOT IN ?", []string{models.AccessPaywalled, models.AccessConsentWall})
+	}
+	switch f.Safe {
+	case SafeStrict:
+		db = db.Where("content_rating = ?", llm.RatingSafe)
+	case SafeModerate:
+		db = db.Where("content_rating IS NULL OR content_rating <> ?", llm.RatingExplicit)
+	}
+	if f.MinReliability > 0 {
+		db = db.Where("COALESCE((SELECT reliability FROM sources WHERE sources.key = LOWER(TRIM(articles.source_name))), ?) >= ?",
+			neutralReliability, f.MinReliability)
+	}
+	if !f.PublishedFrom.IsZero() {
+		db = db.Where("publication_date >= ?", f.PublishedFrom)
+	}
+	if !f.PublishedTo.IsZero() {
+		db = db.Where("publication_date < ?", f.PublishedTo)
+	}
+	if f.State != "" {
+		db = db.Where("state = ?", f.State)
+	}
+	return db
+}
+
+// Match reports whether an already loaded article passes the filter. Source
+// reliability is read from SourceMeta, so metadata must be attached first.
+func (f ArticleFilter) Match(article models.Article) bool {
+	if f.ExcludePaywalled && (article.Access == models.AccessPaywalled || article.Access == models.AccessConsentWall) {
+		return false
+	}
+	if f.MinReliability > 0 && sourceReliabilityOf(article) < f.MinReliability {
+		return false
+	}
+	if !f.PublishedFrom.IsZero() && article.PublicationDate.Before(f.PublishedFrom) {
+		return false
+	}
+	if !f.PublishedTo.IsZero() && !article.PublicationDate.Before(f.PublishedTo) {
+		return false
+	}
+	if f.State != "" && article.State != f.State {
+		return false
+	}
+	switch f.Safe {
+	case SafeStrict:
+		return article.ContentRating == llm.RatingSafe
+	case SafeModerate:
+		return article.ContentRating != llm.RatingExplicit
+	}
+	return true
+}
+
+// Apply returns the articles that pass the filter, preserving order
+func (f ArticleFilter) Apply(articles []models.Article) []models.Article {
+	result := make([]models.Article, 0, len(articles))
+	for _, article := range articles {
+		if f.Match(article) {
+			result = append(result, article)
+		}
+	}
+	return result
+}
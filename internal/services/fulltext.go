@@ -0,0 +1,176 @@
+package services
+
+import (
+	"context"
+	"log"
+	"strings"
+
+	"github.com/mahigadamsetty/Inshorts-task/internal/db"
+	"github.com/mahigadamsetty/Inshorts-task/internal/models"
+	"github.com/mahigadamsetty/Inshorts-task/internal/tuning"
+	"gorm.io/gorm"
+)
+
+// SearchTerms are the terms of a keyword query, any of which matches: words,
+// which match as a prefix when they end in *, and double-quoted phrases,
+// which match their words in order
+type SearchTerms struct {
+	Words   []string
+	Phrases []string
+}
+
+// ParseSearchTerms lowercases a keyword query and splits it into terms. Stop
+// words are dropped unless nothing else is left to match; phrases are kept
+// whole.
+func ParseSearchTerms(query string) SearchTerms {
+	var terms SearchTerms
+	var words []string
+	parts := strings.Split(strings.ToLower(query), `"`)
+	for i, part := range parts {
+		// Odd parts are quoted, except what follows an unclosed quote
+		if i%2 == 0 || i == len(parts)-1 {
+			words = append(words, strings.Fields(part)...)
+			continue
+		}
+		switch phrase := strings.Fields(part); len(phrase) {
+		case 0:
+		case 1:
+			words = append(words, phrase[0])
+		default:
+			terms.Phrases = append(terms.Phrases, strings.Join(phrase, " "))
+		}
+	}
+
+	kept := make([]string, 0, len(words))
+	for _, word := range words {
+		prefix := strings.HasSuffix(word, "*")
+		if word = strings.Trim(word, "*"); word == "" {
+			continue
+		}
+		if prefix {
+			word += "*"
+		}
+		kept = append(kept, word)
+	}
+	terms.Words = tuning.FilterStopWords(kept)
+	if len(terms.Words) == 0 && len(terms.Phrases) == 0 {
+		terms.Words = kept
+	}
+	return terms
+}
+
+// Empty reports whether there is nothing to match
+func (t SearchTerms) Empty() bool {
+	return len(t.Words) == 0 && len(t.Phrases) == 0
+}
+
+// Substrings returns the terms as text to find in titles and descriptions
+// without the full-text index, where every word matches anywhere in a word
+func (t SearchTerms) Substrings() []string {
+	substrings := make([]string, 0, len(t.Words)+len(t.Phrases))
+	for _, word := range t.Words {
+		substrings = append(substrings, strings.TrimSuffix(word, "*"))
+	}
+	return append(substrings, t.Phrases...)
+}
+
+// matchExpression is the FTS5 query matching any of the terms. Every term is
+// quoted, so query syntax in the keywords is matched as text.
+func (t SearchTerms) matchExpression() string {
+	quote := func(text string) string {
+		return `"` + strings.ReplaceAll(text, `"`, `""`) + `"`
+	}
+	expressions := make([]string, 0, len(t.Words)+len(t.Phrases))
+	for _, word := range t.Words {
+		if prefix := strings.TrimSuffix(word, "*"); prefix != word {
+			expressions = append(expressions, quote(prefix)+"*")
+		} else {
+			expressions = append(expressions, quote(word))
+		}
+	}
+	for _, phrase := range t.Phrases {
+		expressions = append(expressions, quote(phrase))
+	}
+	return strings.Join(expressions, " OR ")
+}
+
+// SearchArticles returns a condition keeping the articles whose title or
+// description matches any of the terms in the full-text index, so it can be
+// combined with other filters. Words are stemmed, so "elections" matches
+// "election". ok is false without the index or terms, and the caller matches
+// with LIKE instead.
+func SearchArticles(database *gorm.DB, terms SearchTerms) (*gorm.DB, bool) {
+	if !db.FullText() || terms.Empty() {
+		return nil, false
+	}
+	return database.Where("id IN (SELECT article_id FROM "+db.FullTextKeys+" WHERE key IN "+
+		"(SELECT rowid FROM "+db.FullTextTable+" WHERE "+db.FullTextTable+" MATCH ?))", terms.matchExpression()), true
+}
+
+// TextRelevance is how well an article's title and description match a
+// keyword query, each 0-1
+type TextRelevance struct {
+	Title       float64
+	Description float64
+}
+
+// score weights the title and description relevance by the search boosts
+func (r TextRelevance) score(boosts SearchBoosts) float64 {
+	maxScore := boosts.Title + boosts.Description
+	if maxScore <= 0 {
+		return 0
+	}
+	return (r.Title*boosts.Title + r.Description*boosts.Description) / maxScore
+}
+
+// TextRelevances scores how well articles match a keyword query with BM25 in
+// the full-text index, field by field, scaled so the best match of each field
+// among the articles scores 1. It returns nil without the index or when
+// scoring fails, and the text match is scored by containment instead.
+func (s *Services) TextRelevances(ctx context.Context, query string, articles []models.Article) map[string]TextRelevance {
+	terms := ParseSearchTerms(query)
+	if !db.FullText() || terms.Empty() || len(articles) == 0 {
+		return nil
+	}
+	database := s.db.WithContext(ctx)
+	if database == nil {
+		return nil
+	}
+
+	ids := make([]string, len(articles))
+	for i, article := range articles {
+		ids[i] = article.ID
+	}
+	// bm25 is lower for better matches; a zero weight leaves a field out
+	var rows []struct {
+		ID          string
+		Title       float64
+		Description float64
+	}
+	err := database.Raw("SELECT keys.article_id AS id, -bm25("+db.FullTextTable+", 1.0, 0.0) AS title, -bm25("+db.FullTextTable+", 0.0, 1.0) AS description "+
+		"FROM "+db.FullTextTable+" JOIN "+db.FullTextKeys+" keys ON keys.key = "+db.FullTextTable+".rowid "+
+		"WHERE "+db.FullTextTable+" MATCH ? AND keys.article_id IN ?", terms.matchExpression(), ids).
+		Scan(&rows).Error
+	if err != nil {
+		log.Printf("Failed to score full-text matches, scoring by containment: %v", err)
+		return nil
+	}
+
+	var best TextRelevance
+	for _, row := range rows {
+		best.Title = max(best.Title, row.Title)
+		best.Description = max(best.Description, row.Description)
+	}
+	relevance := make(map[string]TextRelevance, len(rows))
+	for _, row := range rows {
+		var r TextRelevance
+		if best.Title > 0 {
+			r.Title = max(row.Title, 0) / best.Title
+		}
+		if best.Description > 0 {
+			r.Description = max(row.Description, 0) / best.Description
+		}
+		relevance[row.ID] = r
+	}
+	return relevance
+}
@@ -0,0 +1,63 @@
+package services
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseSearchTerms(t *testing.T) {
+	tests := []struct {
+		name    string
+		query   string
+		words   []string
+		phrases []string
+	}{
+		{"words", "Cricket World Cup", []string{"cricket", "world", "cup"}, nil},
+		{"stop words dropped", "the state of the economy", []string{"state", "economy"}, nil},
+		{"only stop words", "what is this", []string{"what", "is", "this"}, nil},
+		{"prefix", "elect* results", []string{"elect*", "results"}, nil},
+		{"stray stars", "*cricket** * ***", []string{"cricket*"}, nil},
+		{"phrase", `"Reserve Bank" rates`, []string{"rates"}, []string{"reserve bank"}},
+		{"phrase keeps stop words", `"state of the art"`, nil, []string{"state of the art"}},
+		{"phrase whitespace collapsed", `"  monsoon   session "`, nil, []string{"monsoon session"}},
+		{"one word phrase", `"budget" news`, []string{"budget", "news"}, nil},
+		{"empty phrase", `"" budget`, []string{"budget"}, nil},
+		{"unclosed quote", `stock "market crash`, []string{"stock", "market", "crash"}, nil},
+		{"stop words beside phrase", `the "lok sabha"`, nil, []string{"lok sabha"}},
+		{"empty", "   ", nil, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ParseSearchTerms(tt.query)
+			if strings.Join(got.Words, "|") != strings.Join(tt.words, "|") ||
+				strings.Join(got.Phrases, "|") != strings.Join(tt.phrases, "|") {
+				t.Errorf("ParseSearchTerms(%q) = %q %q, want %q %q", tt.query, got.Words, got.Phrases, tt.words, tt.phrases)
+			}
+			if got.Empty() != (len(tt.words) == 0 && len(tt.phrases) == 0) {
+				t.Errorf("ParseSearchTerms(%q).Empty() = %v", tt.query, got.Empty())
+			}
+		})
+	}
+}
+
+func TestSearchTermsMatchExpression(t *testing.T) {
+	tests := []struct {
+		name  string
+		terms SearchTerms
+		want  string
+	}{
+		{"words", SearchTerms{Words: []string{"cricket", "cup"}}, `"cricket" OR "cup"`},
+		{"prefix", SearchTerms{Words: []string{"elect*"}}, `"elect"*`},
+		{"phrase", SearchTerms{Words: []string{"rates"}, Phrases: []string{"reserve bank"}}, `"rates" OR "reserve bank"`},
+		{"query syntax quoted", SearchTerms{Words: []string{"near(a", `b"c`, "not"}}, `"near(a" OR "b""c" OR "not"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.terms.matchExpression(); got != tt.want {
+				t.Errorf("matchExpression() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
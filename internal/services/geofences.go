@@ -0,0 +1,331 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mahigadamsetty/Inshorts-task/internal/apperr"
+	"github.com/mahigadamsetty/Inshorts-task/internal/db"
+	"github.com/mahigadamsetty/Inshorts-task/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const spikeBaselineWindows = 4 // Windows before the current one that make up the spike baseline
+
+// GeofenceInput describes a geofence as registered through the API. Give either
+// a center with radius_km or a polygon of at least three [lat, lon] vertices.
+type GeofenceInput struct {
+	Name           string       `json:"name"`
+	Latitude       *float64     `json:"latitude"`
+	Longitude      *float64     `json:"longitude"`
+	RadiusKm       float64      `json:"radius_km"`
+	Polygon        [][2]float64 `json:"polygon"`
+	Category       string       `json:"category"`
+	Source         string       `json:"source"`
+	Keywords       []string     `json:"keywords"`
+	MinReliability float64      `json:"min_reliability"`
+	SpikeThreshold int          `json:"spike_threshold"`
+	WebhookURL     string       `json:"webhook_url"`
+	Secret         string       `json:"secret"` // Signs webhook bodies; generated when empty
+}
+
+// Geofence validates the input and builds the stored geofence. The webhook is
+// checked by CreateGeofence.
+func (in GeofenceInput) Geofence(ctx context.Context, maxRadiusKm float64) (models.Geofence, error) {
+	fence := models.Geofence{
+		Name:           strings.TrimSpace(in.Name),
+		Category:       strings.TrimSpace(in.Category),
+		Source:         strings.TrimSpace(in.Source),
+		MinReliability: in.MinReliability,
+		SpikeThreshold: in.SpikeThreshold,
+		WebhookURL:     strings.TrimSpace(in.WebhookURL),
+		Secret:         in.Secret,
+	}
+	if fence.Name == "" {
+		return fence, apperr.InvalidFilterf("name is required")
+	}
+
+	hasCenter := in.Latitude != nil || in.Longitude != nil
+	switch {
+	case hasCenter && len(in.Polygon) > 0:
+		return fence, apperr.InvalidFilterf("give either a center and radius_km or a polygon, not both")
+	case hasCenter:
+		if in.Latitude == nil || in.Longitude == nil || !validCoordinate(*in.Latitude, *in.Longitude) {
+			return fence, apperr.InvalidFilterf("latitude and longitude must both be valid coordinates")
+		}
+		if in.RadiusKm <= 0 || in.RadiusKm > maxRadiusKm {
+			return fence, apperr.InvalidFilterf("radius_km must be between 0 and %g", maxRadiusKm)
+		}
+		fence.Shape = models.FenceCircle
+		fence.Latitude, fence.Longitude, fence.RadiusKm = *in.Latitude, *in.Longitude, in.RadiusKm
+	case len(in.Polygon) > 0:
+		if len(in.Polygon) < 3 {
+			return fence, apperr.InvalidFilterf("polygon needs at least three vertices")
+		}
+		for _, vertex := range in.Polygon {
+			if !validCoordinate(vertex[0], vertex[1]) {
+				return fence, apperr.InvalidFilterf("polygon vertices must be valid [lat, lon] coordinates")
+			}
+			fence.Latitude += vertex[0] / float64(len(in.Polygon))
+			fence.Longitude += vertex[1] / float64(len(in.Polygon))
+		}
+		fence.Shape = models.FencePolygon
+		fence.Polygon = in.Polygon
+	default:
+		return fence, apperr.InvalidFilterf("a center with radius_km or a polygon is required")
+	}
+
+	for _, keyword := range in.Keywords {
+		if keyword = strings.ToLower(strings.TrimSpace(keyword)); keyword != "" {
+			fence.Keywords = append(fence.Keywords, keyword)
+		}
+	}
+	if fence.MinReliability < 0 || fence.MinReliability > 1 {
+		return fence, apperr.InvalidFilterf("min_reliability must be between 0 and 1")
+	}
+	if fence.SpikeThreshold < 0 {
+		return fence, apperr.InvalidFilterf("spike_threshold cannot be negative")
+	}
+
+	if fence.Secret == "" {
+		fence.Secret = NewSessionID()
+	}
+	return fence, nil
+}
+
+// validCoordinate reports whether a latitude and longitude are in range
+func validCoordinate(lat, lon float64) bool {
+	return lat >= -90 && lat <= 90 && lon >= -180 && lon <= 180
+}
+
+// CreateGeofence stores a geofence. Its webhook must resolve to public
+// addresses. Only articles created after registration raise alerts.
+func (s *Services) CreateGeofence(ctx context.Context, fence *models.Geofence) error {
+	if err := s.ValidateWebhookURL(ctx, fence.WebhookURL); err != nil {
+		return err
+	}
+	fence.CheckedAt = time.Now()
+	return s.db.WithContext(ctx).Create(fence).Error
+}
+
+// ListGeofences returns the geofences visible to the tenant in ctx
+func (s *Services) ListGeofences(ctx context.Context) ([]models.Geofence, error) {
+	var fences []models.Geofence
+	err := s.db.WithContext(ctx).Order("id").Find(&fences).Error
+	return fences, err
+}
+
+// GetGeofence returns a geofence by ID
+func (s *Services) GetGeofence(ctx context.Context, id uint) (*models.Geofence, error) {
+	var fence models.Geofence
+	if err := s.db.WithContext(ctx).First(&fence, id).Error; err != nil {
+		return nil, apperr.NotFound(err, "Geofence")
+	}
+	return &fence, nil
+}
+
+// DeleteGeofence removes a geofence and its alert history, along with the
+// alerts' outbox messages so undelivered ones are dropped
+func (s *Services) DeleteGeofence(ctx context.Context, id uint) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Delete(&models.Geofence{}, id)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return apperr.NotFoundf("Geofence not found")
+		}
+		alerts := tx.Model(&models.GeofenceAlert{}).Select("id").Where("geofence_id = ?", id)
+		err := tx.Where("subject = ? AND subject_id IN (?)", models.SubjectGeofenceAlert, alerts).Delete(&models.OutboxMessage{}).Error
+		if err != nil {
+			return err
+		}
+		return tx.Where("geofence_id = ?", id).Delete(&models.GeofenceAlert{}).Error
+	})
+}
+
+// ListGeofenceAlerts returns a geofence's most recent alerts
+func (s *Services) ListGeofenceAlerts(ctx context.Context, fenceID uint, limit int) ([]models.GeofenceAlert, error) {
+	var alerts []models.GeofenceAlert
+	err := s.db.WithContext(ctx).
+		Where("geofence_id = ?", fenceID).
+		Order("created_at DESC").
+		Limit(limit).
+		Find(&alerts).Error
+	return alerts, err
+}
+
+// EvaluateGeofences checks every geofence for matching articles created since its
+// last check and for reading spikes in the current window, raising an alert for
+// each hit. Alerts are written with their outbox messages and the fence's check
+// time in one transaction. Returns the number of alerts raised.
+func (s *Services) EvaluateGeofences(ctx context.Context, spikeWindow time.Duration, spikeFactor float64) (int, error) {
+	database := s.db
+	if database == nil {
+		return 0, fmt.Errorf("database not initialized")
+	}
+	database = database.WithContext(ctx)
+
+	var fences []models.Geofence
+	if err := database.Find(&fences).Error; err != nil {
+		return 0, err
+	}
+
+	raised := 0
+	defer func() {
+		if raised > 0 {
+			WakeOutbox()
+		}
+	}()
+	for i := range fences {
+		fence := &fences[i]
+		now := time.Now()
+
+		alerts, err := s.newArticleAlerts(ctx, fence, now)
+		if err != nil {
+			return raised, fmt.Errorf("geofence %d: %w", fence.ID, err)
+		}
+		if fence.SpikeThreshold > 0 {
+			spike, err := s.trendingSpikeAlert(ctx, fence, now, spikeWindow, spikeFactor)
+			if err != nil {
+				return raised, fmt.Errorf("geofence %d: %w", fence.ID, err)
+			}
+			if spike != nil {
+				alerts = append(alerts, *spike)
+			}
+		}
+
+		created := 0
+		err = database.Transaction(func(tx *gorm.DB) error {
+			for j := range alerts {
+				alerts[j].GeofenceID = fence.ID
+				alerts[j].Status = models.AlertPending
+				alerts[j].TenantID = fence.TenantID
+				result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&alerts[j])
+				if result.Error != nil {
+					return result.Error
+				}
+				if result.RowsAffected == 0 {
+					continue
+				}
+				if err := s.enqueueAlert(tx, fence, &alerts[j]); err != nil {
+					return err
+				}
+				created++
+			}
+			return tx.Model(fence).Update("checked_at", now).Error
+		})
+		if err != nil {
+			return raised, fmt.Errorf("geofence %d: %w", fence.ID, err)
+		}
+		raised += created
+	}
+	return raised, nil
+}
+
+// newArticleAlerts finds articles that became visible in (fence.CheckedAt, now],
+// when created or when their embargo lifted, that fall inside the fence and
+// pass its filters. Fences covering anywhere also match articles without a
+// location.
+func (s *Services) newArticleAlerts(ctx context.Context, fence *models.Geofence, now time.Time) ([]models.GeofenceAlert, error) {
+	minLat, maxLat, minLon, maxLon := fence.Bounds()
+	database := s.db.WithContext(db.VisibleOnly(ctx))
+	query := database.
+		Select("id, latitude, longitude").
+		Scopes(ArticleFilter{MinReliability: fence.MinReliability}.Scope).
+		Where("tenant_id = ?", fence.TenantID).
+		Where("MAX(created_at, COALESCE(visible_from, created_at)) > ? AND MAX(created_at, COALESCE(visible_from, created_at)) <= ?", fence.CheckedAt, now)
+	if fence.Shape != models.FenceAnywhere {
+		query = query.
+			Scopes(HasLocation).
+			Where("latitude BETWEEN ? AND ?", minLat, maxLat).
+			Where("longitude BETWEEN ? AND ?", minLon, maxLon)
+	}
+	if fence.Category != "" {
+		query = query.Where(s.CategoryMatch(ctx, fence.Category))
+	}
+	if fence.Source != "" {
+		query = query.Where("LOWER(source_name) = ?", strings.ToLower(fence.Source))
+	}
+	if len(fence.Keywords) > 0 {
+		condition := database.Where("1 = 0")
+		for _, keyword := range fence.Keywords {
+			pattern := "%" + keyword + "%"
+			condition = condition.Or("LOWER(title) LIKE ?", pattern).Or("LOWER(description) LIKE ?", pattern)
+		}
+		query = query.Where(condition)
+	}
+
+	var articles []models.Article
+	if err := query.Find(&articles).Error; err != nil {
+		return nil, err
+	}
+
+	var alerts []models.GeofenceAlert
+	for _, article := range articles {
+		if fence.Contains(article.Latitude, article.Longitude) {
+			alerts = append(alerts, models.GeofenceAlert{
+				DedupeKey: "article:" + article.ID,
+				Kind:      models.AlertNewArticle,
+				ArticleID: article.ID,
+			})
+		}
+	}
+	return alerts, nil
+}
+
+// trendingSpikeAlert compares reading events inside the fence in the current
+// window with the average of the windows before it. Returns nil without a spike.
+func (s *Services) trendingSpikeAlert(ctx context.Context, fence *models.Geofence, now time.Time, window time.Duration, factor float64) (*models.GeofenceAlert, error) {
+	minLat, maxLat, minLon, maxLon := fence.Bounds()
+	windowStart := now.Add(-window)
+
+	var events []models.Event
+	err := s.db.WithContext(ctx).
+		Select("article_id, latitude, longitude, timestamp").
+		Scopes(s.trafficScope(ctx)).
+		Where("tenant_id = ?", fence.TenantID).
+		Where("timestamp > ? AND timestamp <= ?", now.Add(-window*(spikeBaselineWindows+1)), now).
+		Where("latitude BETWEEN ? AND ?", minLat, maxLat).
+		Where("longitude BETWEEN ? AND ?", minLon, maxLon).
+		Find(&events).Error
+	if err != nil {
+		return nil, err
+	}
+
+	current, previous := 0, 0
+	reads := map[string]int{}
+	for _, event := range events {
+		if !fence.Contains(event.Latitude, event.Longitude) {
+			continue
+		}
+		if event.Timestamp.After(windowStart) {
+			current++
+			reads[event.ArticleID]++
+		} else {
+			previous++
+		}
+	}
+
+	baseline := float64(previous) / spikeBaselineWindows
+	if current < fence.SpikeThreshold || float64(current) < factor*baseline {
+		return nil, nil
+	}
+
+	top := ""
+	for articleID, count := range reads {
+		if count > reads[top] || (count == reads[top] && articleID < top) {
+			top = articleID
+		}
+	}
+	return &models.GeofenceAlert{
+		DedupeKey:  fmt.Sprintf("spike:%d", now.Truncate(window).Unix()), // At most one spike alert per window
+		Kind:       models.AlertTrendingSpike,
+		ArticleID:  top,
+		EventCount: current,
+		Baseline:   baseline,
+	}, nil
+}
@@ -0,0 +1,156 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/mahigadamsetty/Inshorts-task/internal/cache"
+	"github.com/mahigadamsetty/Inshorts-task/internal/models"
+	"github.com/mahigadamsetty/Inshorts-task/internal/tenant"
+	"github.com/mahigadamsetty/Inshorts-task/internal/utils"
+)
+
+// HeatmapCell is the engagement of one geohash cell
+type HeatmapCell struct {
+	Geohash   string  `json:"geohash"`
+	Latitude  float64 `json:"latitude"` // Center of the cell
+	Longitude float64 `json:"longitude"`
+	Events    int64   `json:"events"`
+	Views     int64   `json:"views"`
+	Clicks    int64   `json:"clicks"`
+	Density   float64 `json:"density"` // Events relative to the busiest cell, 0-1
+}
+
+// Heatmap is the geography of the events of a window, by geohash cell
+type Heatmap struct {
+	Since      time.Time     `json:"since"`
+	Precision  int           `json:"precision"`
+	Events     int64         `json:"events"` // Located events in the window, including cells cut by the limit
+	Cells      []HeatmapCell `json:"cells"`  // Busiest first
+	ComputedAt time.Time     `json:"computed_at"`
+}
+
+// HeatmapCache stores heatmaps by tenant, window, precision and limit, in a
+// cache store that may be shared with other replicas
+type HeatmapCache struct {
+	store cache.Store
+	ttl   time.Duration
+}
+
+// heatmapPrefix is the key prefix of heatmaps in the cache store
+const heatmapPrefix = "heatmap|"
+
+// NewHeatmapCache creates a heatmap cache whose entries expire after ttl
+// seconds, kept in store
+func NewHeatmapCache(ttl int, store cache.Store) *HeatmapCache {
+	return &HeatmapCache{
+		store: store,
+		ttl:   time.Duration(ttl) * time.Second,
+	}
+}
+
+// get returns an unexpired heatmap
+func (hc *HeatmapCache) get(ctx context.Context, key string) (*Heatmap, bool) {
+	var heatmap Heatmap
+	if !getCached(ctx, hc.store, heatmapPrefix+key, hc.ttl, &heatmap) {
+		return nil, false
+	}
+	if heatmap.Cells == nil {
+		heatmap.Cells = []HeatmapCell{}
+	}
+	return &heatmap, true
+}
+
+// set stores a heatmap
+func (hc *HeatmapCache) set(ctx context.Context, key string, heatmap *Heatmap) {
+	setCached(ctx, hc.store, heatmapPrefix+key, heatmap, hc.ttl)
+}
+
+// locationEvents counts the events recorded at one stored location. User
+// coordinates are truncated before they are stored, so there are far fewer
+// locations than events.
+type locationEvents struct {
+	Latitude  float64
+	Longitude float64
+	Views     int64
+	Clicks    int64
+}
+
+// EventHeatmap counts the events of the last window per geohash cell of the
+// given precision, returning at most limit cells, busiest first. Events
+// without a location are left out, and so are simulated events unless the
+// context counts them. Heatmaps are cached per tenant, window, precision, limit
+// and simulated traffic, so the window of a cached one ends when it was computed.
+func (s *Services) EventHeatmap(ctx context.Context, window time.Duration, precision, limit int) (*Heatmap, error) {
+	key := fmt.Sprintf("%s|%s|%d|%d|%t", tenant.IDFromContext(ctx), window, precision, limit, s.includesSimulated(ctx))
+	if s.heatmaps != nil {
+		if heatmap, ok := s.heatmaps.get(ctx, key); ok {
+			return heatmap, nil
+		}
+	}
+
+	database := s.db.WithContext(ctx)
+	if database == nil {
+		return nil, fmt.Errorf("database not initialized")
+	}
+
+	now := time.Now()
+	since := now.Add(-window)
+	var locations []locationEvents
+	err := database.Model(&models.Event{}).
+		Select("latitude, longitude, "+
+			"SUM(CASE WHEN event_type = ? THEN 1 ELSE 0 END) AS views, "+
+			"SUM(CASE WHEN event_type = ? THEN 1 ELSE 0 END) AS clicks",
+			models.EventTypeView, models.EventTypeClick).
+		Scopes(s.trafficScope(ctx)).
+		Where("timestamp >= ?", since).
+		Where("NOT (latitude = 0 AND longitude = 0)").
+		Group("latitude, longitude").
+		Scan(&locations).Error
+	if err != nil {
+		return nil, err
+	}
+
+	heatmap := &Heatmap{Since: since, Precision: precision, Cells: []HeatmapCell{}, ComputedAt: now}
+	cells := map[string]*HeatmapCell{}
+	for _, location := range locations {
+		hash := utils.Geohash(location.Latitude, location.Longitude, precision)
+		cell := cells[hash]
+		if cell == nil {
+			minLat, maxLat, minLon, maxLon := utils.GeohashBounds(hash)
+			cell = &HeatmapCell{Geohash: hash, Latitude: (minLat + maxLat) / 2, Longitude: (minLon + maxLon) / 2}
+			cells[hash] = cell
+		}
+		cell.Views += location.Views
+		cell.Clicks += location.Clicks
+		cell.Events += location.Views + location.Clicks
+		heatmap.Events += location.Views + location.Clicks
+	}
+
+	for _, cell := range cells {
+		heatmap.Cells = append(heatmap.Cells, *cell)
+	}
+	sort.Slice(heatmap.Cells, func(i, j int) bool {
+		a, b := heatmap.Cells[i], heatmap.Cells[j]
+		if a.Events != b.Events {
+			return a.Events > b.Events
+		}
+		return a.Geohash < b.Geohash
+	})
+	if len(heatmap.Cells) > limit {
+		heatmap.Cells = heatmap.Cells[:limit]
+	}
+	if len(heatmap.Cells) > 0 {
+		busiest := float64(heatmap.Cells[0].Events)
+		for i := range heatmap.Cells {
+			heatmap.Cells[i].Density = float64(heatmap.Cells[i].Events) / busiest
+		}
+	}
+
+	if s.heatmaps != nil {
+		s.heatmaps.set(ctx, key, heatmap)
+	}
+	return heatmap, nil
+}
@@ -0,0 +1,228 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/mahigadamsetty/Inshorts-task/internal/models"
+	"github.com/mahigadamsetty/Inshorts-task/internal/platform"
+	"github.com/mahigadamsetty/Inshorts-task/internal/tenant"
+	"github.com/mahigadamsetty/Inshorts-task/internal/tuning"
+)
+
+// impressionClickWindow is how long after an impression a click on its
+// article by the same viewer is attributed to it
+const impressionClickWindow = 30 * time.Minute
+
+// impressionBatchSize is how many impressions are inserted per statement
+const impressionBatchSize = 500
+
+// ImpressionRecorder buffers sampled impressions in memory and periodically
+// writes them in batches
+type ImpressionRecorder struct {
+	pending    []models.Impression
+	sampleRate float64
+	mu         sync.Mutex
+}
+
+// InitImpressions initializes impression recording for the given share of
+// list responses, flushing every interval seconds. With a non-positive
+// interval, impressions are only written by FlushImpressions.
+func (s *Services) InitImpressions(sampleRate float64, interval int) {
+	s.impressions = &ImpressionRecorder{sampleRate: sampleRate}
+	if interval <= 0 {
+		return
+	}
+
+	go func() {
+		for range time.Tick(time.Duration(interval) * time.Second) {
+			if _, err := s.FlushImpressions(context.Background()); err != nil {
+				log.Printf("Failed to flush impressions: %v", err)
+			}
+		}
+	}()
+}
+
+// RecordImpressions records the articles of a list response as impressions at
+// their 1-based positions, for the sampled share of responses. The client ID
+// is only kept hashed, to attribute later clicks.
+func (s *Services) RecordImpressions(ctx context.Context, endpoint, clientID string, articles []models.Article) {
+	recorder := s.impressions
+	if recorder == nil || len(articles) == 0 || recorder.sampleRate <= 0 || rand.Float64() >= recorder.sampleRate {
+		return
+	}
+
+	tenantID := tenant.IDFromContext(ctx)
+	profile := tuning.Current().Profile
+	viewer := viewerHash(tenantID, clientID)
+	client := platform.FromContext(ctx)
+	now := time.Now()
+	impressions := make([]models.Impression, len(articles))
+	for i, article := range articles {
+		impressions[i] = models.Impression{
+			ArticleID: article.ID,
+			Endpoint:  endpoint,
+			Position:  i + 1,
+			Profile:   profile,
+			Viewer:    viewer,
+			Platform:  client,
+			TenantID:  tenantID,
+			CreatedAt: now,
+		}
+		if article.Explanation != nil {
+			impressions[i].Ranker = article.Explanation.Ranker
+		}
+	}
+
+	recorder.mu.Lock()
+	recorder.pending = append(recorder.pending, impressions...)
+	recorder.mu.Unlock()
+}
+
+// FlushImpressions writes the buffered impressions and returns how many were
+// written. Impressions that fail to save are kept for the next flush.
+func (s *Services) FlushImpressions(ctx context.Context) (int, error) {
+	recorder := s.impressions
+	if recorder == nil {
+		return 0, nil
+	}
+	database := s.db.WithContext(ctx)
+	if database == nil {
+		return 0, fmt.Errorf("database not initialized")
+	}
+
+	recorder.mu.Lock()
+	pending := recorder.pending
+	recorder.pending = nil
+	recorder.mu.Unlock()
+	if len(pending) == 0 {
+		return 0, nil
+	}
+
+	if err := database.CreateInBatches(pending, impressionBatchSize).Error; err != nil {
+		recorder.mu.Lock()
+		recorder.pending = append(pending, recorder.pending...)
+		recorder.mu.Unlock()
+		return 0, err
+	}
+	return len(pending), nil
+}
+
+// recordImpressionClick marks the viewer's latest unclicked impression of the
+// clicked article within the attribution window as clicked, looking at the
+// buffered impressions before the stored ones
+func (s *Services) recordImpressionClick(ctx context.Context, event *models.Event, clientID string) {
+	recorder := s.impressions
+	if recorder == nil {
+		return
+	}
+	viewer := viewerHash(event.TenantID, clientID)
+	now := time.Now()
+	after := now.Add(-impressionClickWindow)
+
+	recorder.mu.Lock()
+	for i := len(recorder.pending) - 1; i >= 0; i-- {
+		impression := &recorder.pending[i]
+		if impression.Viewer == viewer && impression.ArticleID == event.ArticleID && impression.TenantID == event.TenantID &&
+			impression.ClickedAt == nil && impression.CreatedAt.After(after) {
+			impression.ClickedAt = &now
+			recorder.mu.Unlock()
+			return
+		}
+	}
+	recorder.mu.Unlock()
+
+	database := s.db.WithContext(ctx)
+	latest := database.Model(&models.Impression{}).Select("MAX(id)").
+		Where("tenant_id = ? AND viewer = ? AND article_id = ? AND clicked_at IS NULL AND created_at > ?", event.TenantID, viewer, event.ArticleID, after)
+	err := database.Model(&models.Impression{}).Where("id = (?)", latest).Update("clicked_at", now).Error
+	if err != nil {
+		log.Printf("Failed to attribute click on %s to an impression: %v", event.ArticleID, err)
+	}
+}
+
+// ImpressionCTR is the click-through rate of a set of impressions
+type ImpressionCTR struct {
+	Profile     string  `json:"profile,omitempty"`
+	Ranker      string  `json:"ranker,omitempty"`
+	Platform    string  `json:"platform,omitempty"`
+	Position    int     `json:"position,omitempty"`
+	Impressions int64   `json:"impressions"`
+	Clicks      int64   `json:"clicks"`
+	CTR         float64 `json:"ctr"` // Clicks per impression
+}
+
+// ImpressionReport breaks down the click-through rate of impressions by
+// ranking profile and ranker, by profile and client platform, and by position
+type ImpressionReport struct {
+	Since     time.Time       `json:"since"`
+	Rankings  []ImpressionCTR `json:"rankings"`  // By profile, then ranker
+	Platforms []ImpressionCTR `json:"platforms"` // By profile, then platform
+	Positions []ImpressionCTR `json:"positions"` // By position
+}
+
+// ImpressionReports computes the click-through rates of the impressions
+// recorded since a time, buffered ones included. An empty tenantID covers
+// every tenant.
+func (s *Services) ImpressionReports(ctx context.Context, since time.Time, tenantID string) (*ImpressionReport, error) {
+	database := s.db.WithContext(ctx)
+	if database == nil {
+		return nil, fmt.Errorf("database not initialized")
+	}
+	if _, err := s.FlushImpressions(ctx); err != nil {
+		return nil, err
+	}
+
+	query := func(groups string) ([]ImpressionCTR, error) {
+		var rows []ImpressionCTR
+		builder := database.Model(&models.Impression{}).
+			Select(groups+", COUNT(*) AS impressions, SUM(CASE WHEN clicked_at IS NOT NULL THEN 1 ELSE 0 END) AS clicks").
+			Where("created_at >= ?", since).
+			Group(groups)
+		if tenantID != "" {
+			builder = builder.Where("tenant_id = ?", tenantID)
+		}
+		if err := builder.Scan(&rows).Error; err != nil {
+			return nil, err
+		}
+		for i := range rows {
+			if rows[i].Impressions > 0 {
+				rows[i].CTR = float64(rows[i].Clicks) / float64(rows[i].Impressions)
+			}
+		}
+		return rows, nil
+	}
+
+	report := &ImpressionReport{Since: since}
+	var err error
+	if report.Rankings, err = query("profile, ranker"); err != nil {
+		return nil, err
+	}
+	if report.Platforms, err = query("profile, platform"); err != nil {
+		return nil, err
+	}
+	if report.Positions, err = query("position"); err != nil {
+		return nil, err
+	}
+	sort.Slice(report.Rankings, func(i, j int) bool {
+		a, b := report.Rankings[i], report.Rankings[j]
+		if a.Profile != b.Profile {
+			return a.Profile < b.Profile
+		}
+		return a.Ranker < b.Ranker
+	})
+	sort.Slice(report.Platforms, func(i, j int) bool {
+		a, b := report.Platforms[i], report.Platforms[j]
+		if a.Profile != b.Profile {
+			return a.Profile < b.Profile
+		}
+		return a.Platform < b.Platform
+	})
+	sort.Slice(report.Positions, func(i, j int) bool { return report.Positions[i].Position < report.Positions[j].Position })
+	return report, nil
+}
@@ -0,0 +1,655 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/mahigadamsetty/Inshorts-task/internal/apperr"
+	"github.com/mahigadamsetty/Inshorts-task/internal/models"
+	"github.com/mahigadamsetty/Inshorts-task/internal/scheduler"
+	"github.com/mahigadamsetty/Inshorts-task/internal/tenant"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/charset"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Limits on ingestion sources and what one run reads
+const (
+	maxCrawlURLs      = 100
+	maxIngestedItems  = 500     // Feed items or query results stored per run
+	maxFeedBytes      = 8 << 20 // Feed and API response bodies
+	newsAPIPageSize   = 100
+	newsAPITimeout    = 15 * time.Second
+	DefaultNewsAPIURL = "https://newsapi.org"
+)
+
+// ErrIngestionSourceExists is returned when an ingestion source's name is taken
+var ErrIngestionSourceExists = apperr.Conflictf("ingestion source already exists")
+
+// IngestionOptions configures how ingestion sources are read and stored
+type IngestionOptions struct {
+	NewsAPIKey     string
+	NewsAPIBaseURL string         // DefaultNewsAPIURL when empty
+	Dates          *DateFormats   // Publication date layouts per source
+	Publish        PublishOptions // Applied as to pushed and imported articles
+}
+
+// IngestionSourceInput describes an ingestion source as given through the admin API
+type IngestionSourceInput struct {
+	Name       string   `json:"name"`
+	Kind       string   `json:"kind"`
+	URL        string   `json:"url"`
+	Query      string   `json:"query"`
+	URLs       []string `json:"urls"`
+	SourceName string   `json:"source_name"`
+	TenantID   string   `json:"tenant"`
+	Category   []string `json:"category"`
+	Schedule   string   `json:"schedule"`
+	Enabled    *bool    `json:"enabled"` // Default true
+}
+
+// apply validates the input and copies it onto a stored or new source. The
+// source's tenant must be in tenants when it is set.
+func (in IngestionSourceInput) apply(source *models.IngestionSource, tenants *tenant.Registry) error {
+	source.Name = strings.TrimSpace(in.Name)
+	source.Kind = strings.ToLower(strings.TrimSpace(in.Kind))
+	source.URL = strings.TrimSpace(in.URL)
+	source.Query = strings.TrimSpace(in.Query)
+	source.SourceName = strings.TrimSpace(in.SourceName)
+	source.TenantID = strings.TrimSpace(in.TenantID)
+	source.Schedule = strings.TrimSpace(in.Schedule)
+	source.Enabled = in.Enabled == nil || *in.Enabled
+	source.URLs = models.StringArray{}
+	source.Category = models.StringArray{}
+
+	if source.Name == "" {
+		return apperr.InvalidFilterf("name is required")
+	}
+	if source.TenantID == "" {
+		source.TenantID = tenant.DefaultID
+	}
+	if tenants != nil {
+		if _, ok := tenants.Get(source.TenantID); !ok {
+			return apperr.InvalidFilterf("unknown tenant %q", source.TenantID)
+		}
+	}
+	if _, err := scheduler.Parse(source.Schedule); err != nil {
+		return apperr.InvalidFilterf("invalid schedule: %v", err)
+	}
+
+	switch source.Kind {
+	case models.IngestionRSS:
+		if !validPageURL(source.URL) {
+			return apperr.InvalidFilterf("url must be an absolute http or https URL")
+		}
+	case models.IngestionNewsAPI:
+		if source.Query == "" {
+			return apperr.InvalidFilterf("query is required for newsapi sources")
+		}
+	case models.IngestionCrawl:
+		if len(in.URLs) == 0 || len(in.URLs) > maxCrawlURLs {
+			return apperr.InvalidFilterf("crawl sources need 1 to %d urls", maxCrawlURLs)
+		}
+		for _, raw := range in.URLs {
+			if raw = strings.TrimSpace(raw); !validPageURL(raw) {
+				return apperr.InvalidFilterf("url %q must be an absolute http or https URL", raw)
+			}
+			source.URLs = append(source.URLs, raw)
+		}
+	default:
+		return apperr.InvalidFilterf("kind must be %s, %s or %s", models.IngestionRSS, models.IngestionNewsAPI, models.IngestionCrawl)
+	}
+	if source.SourceName == "" && source.Kind != models.IngestionNewsAPI {
+		return apperr.InvalidFilterf("source_name is required for %s sources", source.Kind)
+	}
+
+	if len(in.Category) > maxPublishedCategories {
+		return apperr.InvalidFilterf("at most %d categories are allowed", maxPublishedCategories)
+	}
+	for _, category := range in.Category {
+		if category = strings.TrimSpace(category); category == "" {
+			return apperr.InvalidFilterf("categories must not be empty")
+		}
+		source.Category = append(source.Category, category)
+	}
+
+	source.NextRunAt = nextIngestionRun(source, time.Now())
+	return nil
+}
+
+// nextIngestionRun returns when a source is next due by its schedule after its
+// last run, or nil while it is disabled. A source that never ran is due at now.
+func nextIngestionRun(source *models.IngestionSource, now time.Time) *time.Time {
+	if !source.Enabled {
+		return nil
+	}
+	schedule, err := scheduler.Parse(source.Schedule)
+	if err != nil {
+		return nil
+	}
+	if source.LastRunAt == nil {
+		return &now
+	}
+	next := schedule.Next(*source.LastRunAt)
+	if next.IsZero() {
+		return nil
+	}
+	return &next
+}
+
+// validPageURL reports whether raw is an absolute http or https URL
+func validPageURL(raw string) bool {
+	target, err := url.Parse(raw)
+	return err == nil && (target.Scheme == "http" || target.Scheme == "https") && target.Host != ""
+}
+
+// ListIngestionSources returns all ingestion sources ordered by name
+func (s *Services) ListIngestionSources(ctx context.Context) ([]models.IngestionSource, error) {
+	var sources []models.IngestionSource
+	err := s.db.WithContext(ctx).Order("name").Find(&sources).Error
+	return sources, err
+}
+
+// GetIngestionSource returns an ingestion source
+func (s *Services) GetIngestionSource(ctx context.Context, id uint) (*models.IngestionSource, error) {
+	var source models.IngestionSource
+	if err := s.db.WithContext(ctx).First(&source, id).Error; err != nil {
+		return nil, apperr.NotFound(err, "Ingestion source")
+	}
+	return &source, nil
+}
+
+// CreateIngestionSource stores a new ingestion source. Enabled sources are run
+// by the next poll of the ingestion job.
+func (s *Services) CreateIngestionSource(ctx context.Context, input IngestionSourceInput) (*models.IngestionSource, error) {
+	source := &models.IngestionSource{}
+	if err := input.apply(source, s.tenants); err != nil {
+		return nil, err
+	}
+	if err := s.checkIngestionName(ctx, source); err != nil {
+		return nil, err
+	}
+	if err := s.db.WithContext(ctx).Create(source).Error; err != nil {
+		return nil, err
+	}
+	return source, nil
+}
+
+// UpdateIngestionSource replaces the configuration of an ingestion source,
+// keeping its run status. Its next run follows the new schedule.
+func (s *Services) UpdateIngestionSource(ctx context.Context, id uint, input IngestionSourceInput) (*models.IngestionSource, error) {
+	source, err := s.GetIngestionSource(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := input.apply(source, s.tenants); err != nil {
+		return nil, err
+	}
+	if err := s.checkIngestionName(ctx, source); err != nil {
+		return nil, err
+	}
+	if err := s.db.WithContext(ctx).Save(source).Error; err != nil {
+		return nil, err
+	}
+	return source, nil
+}
+
+// DeleteIngestionSource removes an ingestion source. Articles it ingested are
+// kept.
+func (s *Services) DeleteIngestionSource(ctx context.Context, id uint) error {
+	result := s.db.WithContext(ctx).Delete(&models.IngestionSource{}, id)
+	if result.Error == nil && result.RowsAffected == 0 {
+		return apperr.NotFoundf("Ingestion source not found")
+	}
+	return result.Error
+}
+
+// checkIngestionName returns ErrIngestionSourceExists when another source has
+// the source's name
+func (s *Services) checkIngestionName(ctx context.Context, source *models.IngestionSource) error {
+	var count int64
+	err := s.db.WithContext(ctx).Model(&models.IngestionSource{}).
+		Where("name = ? AND id <> ?", source.Name, source.ID).
+		Count(&count).Error
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return fmt.Errorf("%w: %s", ErrIngestionSourceExists, source.Name)
+	}
+	return nil
+}
+
+// IngestionResult summarizes a poll of the ingestion sources
+type IngestionResult struct {
+	Sources  int `json:"sources"`  // Sources run
+	Failed   int `json:"failed"`   // Sources whose run failed
+	Ingested int `json:"ingested"` // New articles stored
+}
+
+// IngestDueSources runs every enabled ingestion source whose next run is due.
+// Sources are read from the database on every poll, so changes made through
+// the admin API apply without a restart. A failing source is recorded on the
+// source and does not stop the others.
+func (s *Services) IngestDueSources(ctx context.Context) (*IngestionResult, error) {
+	database := s.db.WithContext(ctx)
+	if database == nil {
+		return nil, fmt.Errorf("database not initialized")
+	}
+
+	var sources []models.IngestionSource
+	err := database.Where("enabled = ? AND next_run_at <= ?", true, time.Now()).Order("next_run_at").Find(&sources).Error
+	if err != nil {
+		return nil, err
+	}
+
+	result := &IngestionResult{}
+	for i := range sources {
+		if ctx.Err() != nil {
+			return result, ctx.Err()
+		}
+		if err := s.runIngestionSource(ctx, database, &sources[i]); err != nil {
+			return result, err
+		}
+		if sources[i].LastStatus == models.JobStatusFailed {
+			result.Failed++
+		}
+		result.Sources++
+		result.Ingested += sources[i].LastIngested
+	}
+	return result, nil
+}
+
+// RunIngestionSource runs an ingestion source right away, whether or not it is
+// due or enabled, and returns it with the run's status
+func (s *Services) RunIngestionSource(ctx context.Context, id uint) (*models.IngestionSource, error) {
+	database := s.db.WithContext(ctx)
+	if database == nil {
+		return nil, fmt.Errorf("database not initialized")
+	}
+	source, err := s.GetIngestionSource(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.runIngestionSource(ctx, database, source); err != nil {
+		return nil, err
+	}
+	return source, nil
+}
+
+// runIngestionSource reads a source's items, stores the new ones and records
+// the run's status on the source. Only a failure to record it is returned.
+func (s *Services) runIngestionSource(ctx context.Context, database *gorm.DB, source *models.IngestionSource) error {
+	items, err := s.readIngestionItems(ctx, source)
+	ingested, skipped := 0, 0
+	if err == nil {
+		ingested, skipped, err = s.storeIngestedItems(ctx, database, source, items)
+	}
+
+	now := time.Now()
+	source.LastRunAt = &now
+	source.LastIngested, source.LastSkipped = ingested, skipped
+	source.LastStatus, source.LastError = models.JobStatusSuccess, ""
+	if err != nil {
+		source.LastStatus, source.LastError = models.JobStatusFailed, err.Error()
+	}
+	source.NextRunAt = nextIngestionRun(source, now)
+	if ingested > 0 {
+		s.InvalidateTrendingCache(ctx)
+	}
+
+	// Only the status columns, so an edit made during the run is kept
+	return database.Model(source).Updates(map[string]interface{}{
+		"last_run_at":   source.LastRunAt,
+		"last_status":   source.LastStatus,
+		"last_error":    source.LastError,
+		"last_ingested": source.LastIngested,
+		"last_skipped":  source.LastSkipped,
+		"next_run_at":   source.NextRunAt,
+	}).Error
+}
+
+// ingestedItem is an article as read from an ingestion source
+type ingestedItem struct {
+	Title       string
+	Description string
+	URL         string
+	Published   string // Empty when the source gives no date
+	SourceName  string // Source reported by the item, for newsapi sources
+	Category    []string
+}
+
+// readIngestionItems reads the current items of a source
+func (s *Services) readIngestionItems(ctx context.Context, source *models.IngestionSource) ([]ingestedItem, error) {
+	switch source.Kind {
+	case models.IngestionRSS:
+		return s.readFeed(ctx, source)
+	case models.IngestionNewsAPI:
+		return s.readNewsAPI(ctx, source)
+	case models.IngestionCrawl:
+		return s.readCrawlList(ctx, source)
+	}
+	return nil, fmt.Errorf("unknown ingestion source kind %q", source.Kind)
+}
+
+// storeIngestedItems stores the items not stored yet as articles of the
+// source's tenant. Items without a usable title, URL or date, and items
+// duplicating a stored article by canonical URL and title, are skipped.
+func (s *Services) storeIngestedItems(ctx context.Context, database *gorm.DB, source *models.IngestionSource, items []ingestedItem) (ingested, skipped int, err error) {
+	if len(items) > maxIngestedItems {
+		skipped = len(items) - maxIngestedItems
+		items = items[:maxIngestedItems]
+	}
+	now := time.Now()
+	for _, item := range items {
+		sourceName := source.SourceName
+		if sourceName == "" {
+			sourceName = strings.TrimSpace(item.SourceName)
+		}
+		published := now
+		if item.Published != "" {
+			if published, err = s.ingestion.Dates.Parse(sourceName, item.Published); err != nil {
+				skipped++
+				continue
+			}
+		}
+		if storedURL(database, source.TenantID, item.URL, TitleKey(plainText(item.Title))) {
+			skipped++
+			continue
+		}
+		categories := []string(source.Category)
+		if len(categories) == 0 && len(item.Category) <= maxPublishedCategories {
+			categories = item.Category
+		}
+
+		input := PublishedArticle{
+			Title:           plainText(item.Title),
+			Description:     truncateUTF8(plainText(item.Description), maxPublishedDescription),
+			URL:             item.URL,
+			PublicationDate: published,
+			Category:        categories,
+		}
+		if sourceName == "" || input.validate() != nil {
+			skipped++
+			continue
+		}
+		article := &models.Article{ID: newArticleID(), SourceName: sourceName, TenantID: source.TenantID}
+		input.apply(ctx, s, article, s.ingestion.Publish)
+		if article.CanonicalURL == nil {
+			skipped++
+			continue
+		}
+
+		result := database.Clauses(clause.OnConflict{DoNothing: true}).Create(article)
+		if result.Error != nil {
+			return ingested, skipped, result.Error
+		}
+		if result.RowsAffected == 0 {
+			skipped++
+		} else {
+			ingested++
+		}
+	}
+	return ingested, skipped, nil
+}
+
+// feed holds the items of an RSS 2.0 channel or the entries of an Atom feed
+type feed struct {
+	Items []struct {
+		Title       string   `xml:"title"`
+		Link        string   `xml:"link"`
+		Description string   `xml:"description"`
+		PubDate     string   `xml:"pubDate"`
+		Categories  []string `xml:"category"`
+	} `xml:"channel>item"`
+	Entries []struct {
+		Title string `xml:"title"`
+		Links []struct {
+			Href string `xml:"href,attr"`
+			Rel  string `xml:"rel,attr"`
+		} `xml:"link"`
+		Summary    string `xml:"summary"`
+		Published  string `xml:"published"`
+		Updated    string `xml:"updated"`
+		Categories []struct {
+			Term string `xml:"term,attr"`
+		} `xml:"category"`
+	} `xml:"entry"`
+}
+
+// readFeed downloads and parses an RSS or Atom feed, subject to the crawl
+// policy of the source's name
+func (s *Services) readFeed(ctx context.Context, source *models.IngestionSource) ([]ingestedItem, error) {
+	target, err := url.Parse(source.URL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse feed URL: %w", err)
+	}
+	resp, err := s.crawler.Fetch(ctx, source.SourceName, target)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch feed: status code %d", resp.StatusCode)
+	}
+
+	var parsed feed
+	decoder := xml.NewDecoder(io.LimitReader(resp.Body, maxFeedBytes))
+	decoder.CharsetReader = charset.NewReaderLabel
+	decoder.Strict = false
+	if err := decoder.Decode(&parsed); err != nil {
+		return nil, fmt.Errorf("failed to parse feed: %w", err)
+	}
+
+	items := make([]ingestedItem, 0, len(parsed.Items)+len(parsed.Entries))
+	for _, item := range parsed.Items {
+		items = append(items, ingestedItem{
+			Title:       item.Title,
+			Description: item.Description,
+			URL:         strings.TrimSpace(item.Link),
+			Published:   strings.TrimSpace(item.PubDate),
+			Category:    item.Categories,
+		})
+	}
+	for _, entry := range parsed.Entries {
+		item := ingestedItem{Title: entry.Title, Description: entry.Summary, Published: strings.TrimSpace(entry.Published)}
+		if item.Published == "" {
+			item.Published = strings.TrimSpace(entry.Updated)
+		}
+		for _, link := range entry.Links {
+			if link.Rel == "" || link.Rel == "alternate" {
+				item.URL = strings.TrimSpace(link.Href)
+				break
+			}
+		}
+		for _, category := range entry.Categories {
+			item.Category = append(item.Category, category.Term)
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// newsAPIResponse is the body of a NewsAPI /v2/everything response
+type newsAPIResponse struct {
+	Status   string `json:"status"`
+	Message  string `json:"message"`
+	Articles []struct {
+		Source struct {
+			Name string `json:"name"`
+		} `json:"source"`
+		Title       string `json:"title"`
+		Description string `json:"description"`
+		URL         string `json:"url"`
+		PublishedAt string `json:"publishedAt"`
+	} `json:"articles"`
+}
+
+// readNewsAPI returns the newest articles matching a source's NewsAPI query
+func (s *Services) readNewsAPI(ctx context.Context, source *models.IngestionSource) ([]ingestedItem, error) {
+	ingestion := s.ingestion
+	if ingestion.NewsAPIKey == "" {
+		return nil, fmt.Errorf("NEWSAPI_KEY is not set")
+	}
+	params := url.Values{}
+	params.Set("q", source.Query)
+	params.Set("sortBy", "publishedAt")
+	params.Set("pageSize", fmt.Sprint(newsAPIPageSize))
+	endpoint := strings.TrimRight(ingestion.NewsAPIBaseURL, "/") + "/v2/everything?" + params.Encode()
+
+	ctx, cancel := context.WithTimeout(ctx, newsAPITimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-Api-Key", ingestion.NewsAPIKey)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query NewsAPI: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var body newsAPIResponse
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxFeedBytes)).Decode(&body); err != nil {
+		return nil, fmt.Errorf("failed to parse NewsAPI response: status code %d", resp.StatusCode)
+	}
+	if resp.StatusCode != http.StatusOK || body.Status != "ok" {
+		return nil, fmt.Errorf("NewsAPI returned status code %d: %s", resp.StatusCode, body.Message)
+	}
+
+	items := make([]ingestedItem, 0, len(body.Articles))
+	for _, article := range body.Articles {
+		items = append(items, ingestedItem{
+			Title:       article.Title,
+			Description: article.Description,
+			URL:         strings.TrimSpace(article.URL),
+			Published:   strings.TrimSpace(article.PublishedAt),
+			SourceName:  article.Source.Name,
+		})
+	}
+	return items, nil
+}
+
+// readCrawlList fetches the pages of a crawl source not stored yet and reads
+// their title, description and publication date from the page head. A page
+// that cannot be fetched is skipped; the run fails only when every page that
+// is not stored yet failed.
+func (s *Services) readCrawlList(ctx context.Context, source *models.IngestionSource) ([]ingestedItem, error) {
+	database := s.db.WithContext(ctx)
+	var items []ingestedItem
+	var lastErr error
+	failed := 0
+	for _, raw := range source.URLs {
+		if ctx.Err() != nil {
+			return items, ctx.Err()
+		}
+		// Stored pages are not fetched again on every run
+		if storedURL(database, source.TenantID, raw, "") {
+			continue
+		}
+
+		page, _, err := s.fetchPage(ctx, source.SourceName, raw)
+		if err != nil {
+			lastErr = err
+			failed++
+			continue
+		}
+		meta := metaTags(page)
+		title := firstMeta(meta, "og:title", "twitter:title")
+		if title == "" {
+			title = documentTitle(page.doc)
+		}
+		items = append(items, ingestedItem{
+			Title:       title,
+			Description: extractMetaDescription(page),
+			URL:         raw,
+			Published:   firstMeta(meta, "article:published_time", "og:published_time", "date"),
+		})
+	}
+	if len(items) == 0 && failed > 0 {
+		return nil, fmt.Errorf("all %d pages failed, last: %w", failed, lastErr)
+	}
+	return items, nil
+}
+
+// storedURL reports whether the tenant has an article at raw's canonical URL,
+// with the given title key unless it is empty. It checks the URL as given, so
+// items are skipped before their redirects are resolved.
+func storedURL(database *gorm.DB, tenantID, raw, titleKey string) bool {
+	canonical, err := CanonicalURL(raw)
+	if err != nil {
+		return false
+	}
+	query := database.Model(&models.Article{}).Where("tenant_id = ? AND canonical_url = ?", tenantID, canonical)
+	if titleKey != "" {
+		query = query.Where("title_key = ?", titleKey)
+	}
+	var stored int64
+	return query.Count(&stored).Error == nil && stored > 0
+}
+
+// documentTitle returns the text of a page's title element
+func documentTitle(doc *html.Node) string {
+	if doc == nil {
+		return ""
+	}
+	var title string
+	var walk func(n *html.Node) bool
+	walk = func(n *html.Node) bool {
+		if n.Type == html.ElementNode && n.Data == "title" {
+			if n.FirstChild != nil {
+				title = n.FirstChild.Data
+			}
+			return true
+		}
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			if walk(child) {
+				return true
+			}
+		}
+		return false
+	}
+	walk(doc)
+	return strings.TrimSpace(title)
+}
+
+// plainText strips the markup feeds often put in titles and descriptions and
+// collapses whitespace
+func plainText(s string) string {
+	if !strings.ContainsAny(s, "<&") {
+		return strings.Join(strings.Fields(s), " ")
+	}
+	var b strings.Builder
+	tokenizer := html.NewTokenizer(strings.NewReader(s))
+	for {
+		switch tokenizer.Next() {
+		case html.ErrorToken:
+			return strings.Join(strings.Fields(b.String()), " ")
+		case html.TextToken:
+			b.Write(tokenizer.Text())
+		case html.StartTagToken, html.EndTagToken, html.SelfClosingTagToken:
+			b.WriteByte(' ')
+		}
+	}
+}
+
+// truncateUTF8 cuts s to at most n bytes without splitting a character
+func truncateUTF8(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
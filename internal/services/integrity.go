@@ -0,0 +1,168 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/mahigadamsetty/Inshorts-task/internal/apperr"
+	"github.com/mahigadamsetty/Inshorts-task/internal/models"
+	"gorm.io/gorm"
+)
+
+// integritySamples is how many offending rows each check lists
+const integritySamples = 10
+
+// Integrity checks, in report order
+const (
+	CheckOrphanedEvents        = "orphaned_events"
+	CheckUncategorizedArticles = "uncategorized_articles"
+	CheckInvalidCoordinates    = "invalid_coordinates"
+	CheckUnparsedDates         = "unparsed_dates"
+	CheckDuplicateURLs         = "duplicate_urls"
+)
+
+// IntegrityCheck is the outcome of one check of an integrity report
+type IntegrityCheck struct {
+	Name    string   `json:"name"`
+	Count   int64    `json:"count"`             // Offending rows; for duplicate URLs, the articles sharing a URL
+	Samples []string `json:"samples,omitempty"` // IDs of a few offending rows, or URLs for duplicate URLs
+}
+
+// IntegrityReport describes the quality of the corpus of every tenant
+type IntegrityReport struct {
+	CheckedAt time.Time        `json:"checked_at"`
+	Duration  string           `json:"duration"`
+	Articles  int64            `json:"articles"`
+	Events    int64            `json:"events"`
+	Clean     bool             `json:"clean"` // No check found anything
+	Checks    []IntegrityCheck `json:"checks"`
+}
+
+// lastIntegrity keeps the latest report for the admin API
+var lastIntegrity struct {
+	mu     sync.RWMutex
+	report *IntegrityReport
+}
+
+// unparsedDateCutoff is before any real publication date; the zero time
+// written for dates that failed to parse sorts before it
+var unparsedDateCutoff = time.Date(1970, 1, 2, 0, 0, 0, 0, time.UTC)
+
+// rowChecks are the checks that count rows of one table matching a condition
+var rowChecks = []struct {
+	name  string
+	model interface{}
+	scope func(*gorm.DB) *gorm.DB
+}{
+	{CheckOrphanedEvents, &models.Event{}, func(database *gorm.DB) *gorm.DB {
+		return database.Where("article_id NOT IN (?)", database.Session(&gorm.Session{NewDB: true}).Model(&models.Article{}).Select("id"))
+	}},
+	{CheckUncategorizedArticles, &models.Article{}, func(database *gorm.DB) *gorm.DB {
+		// Categories are stored as JSON, as a blob in SQLite
+		return database.Where("(category IS NULL OR CAST(category AS TEXT) IN ?)", []string{"", "[]", "null"})
+	}},
+	// Articles flagged as missing a location are left out of geo queries on
+	// purpose, so only unflagged ones count
+	{CheckInvalidCoordinates, &models.Article{}, func(database *gorm.DB) *gorm.DB {
+		return database.
+			Where("(location_source IS NULL OR location_source <> ?)", models.LocationMissing).
+			Where("(NOT (latitude BETWEEN -90 AND 90 AND longitude BETWEEN -180 AND 180) OR (latitude = 0 AND longitude = 0))")
+	}},
+	{CheckUnparsedDates, &models.Article{}, func(database *gorm.DB) *gorm.DB {
+		return database.Where("(publication_date IS NULL OR publication_date < ? OR publication_date > ?)",
+			unparsedDateCutoff, time.Now().AddDate(0, 0, 1))
+	}},
+}
+
+// CheckIntegrity runs every integrity check over all tenants' data and keeps
+// the report for LastIntegrityReport. The checks only read.
+func (s *Services) CheckIntegrity(ctx context.Context) (*IntegrityReport, error) {
+	database := s.db.WithContext(ctx)
+	if database == nil {
+		return nil, fmt.Errorf("database not initialized")
+	}
+
+	start := time.Now()
+	report := &IntegrityReport{CheckedAt: start.UTC(), Clean: true}
+	if err := database.Model(&models.Article{}).Count(&report.Articles).Error; err != nil {
+		return nil, err
+	}
+	if err := database.Model(&models.Event{}).Count(&report.Events).Error; err != nil {
+		return nil, err
+	}
+
+	for _, rc := range rowChecks {
+		check := IntegrityCheck{Name: rc.name}
+		if err := database.Model(rc.model).Scopes(rc.scope).Count(&check.Count).Error; err != nil {
+			return nil, fmt.Errorf("%s: %w", rc.name, err)
+		}
+		if check.Count > 0 {
+			err := database.Model(rc.model).Scopes(rc.scope).Order("id").Limit(integritySamples).Pluck("id", &check.Samples).Error
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", rc.name, err)
+			}
+		}
+		report.add(check)
+	}
+
+	duplicates, err := duplicateURLs(database)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", CheckDuplicateURLs, err)
+	}
+	report.add(duplicates)
+
+	report.Duration = time.Since(start).Round(time.Millisecond).String()
+	lastIntegrity.mu.Lock()
+	lastIntegrity.report = report
+	lastIntegrity.mu.Unlock()
+	return report, nil
+}
+
+// add appends a check to the report
+func (r *IntegrityReport) add(check IntegrityCheck) {
+	if check.Count > 0 {
+		r.Clean = false
+	}
+	r.Checks = append(r.Checks, check)
+}
+
+// duplicateURLs counts the articles of a tenant sharing a URL with another, by
+// canonical URL where one was computed. Articles that deduplication kept apart
+// because their titles differ, like the updates of a live blog, are counted too.
+func duplicateURLs(database *gorm.DB) (IntegrityCheck, error) {
+	check := IntegrityCheck{Name: CheckDuplicateURLs}
+	var groups []struct {
+		URL      string
+		Articles int64
+	}
+	err := database.Model(&models.Article{}).
+		Select("COALESCE(canonical_url, url) AS url, COUNT(*) AS articles").
+		Where("COALESCE(canonical_url, url) <> ''").
+		Group("tenant_id, COALESCE(canonical_url, url)").
+		Having("COUNT(*) > 1").
+		Order("articles DESC, url").
+		Scan(&groups).Error
+	if err != nil {
+		return check, err
+	}
+	for _, group := range groups {
+		check.Count += group.Articles
+		if len(check.Samples) < integritySamples {
+			check.Samples = append(check.Samples, group.URL)
+		}
+	}
+	return check, nil
+}
+
+// LastIntegrityReport returns the report of the latest integrity check, or an
+// ErrNotFound when none has run since startup
+func LastIntegrityReport() (*IntegrityReport, error) {
+	lastIntegrity.mu.RLock()
+	defer lastIntegrity.mu.RUnlock()
+	if lastIntegrity.report == nil {
+		return nil, apperr.NotFoundf("No integrity check has run yet")
+	}
+	return lastIntegrity.report, nil
+}
@@ -0,0 +1,255 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/mahigadamsetty/Inshorts-task/internal/degradation"
+	"github.com/mahigadamsetty/Inshorts-task/internal/llm"
+)
+
+// IntentEvalIntents are the intents a golden query may expect, in report order
+var IntentEvalIntents = []string{llm.IntentCategory, llm.IntentSource, llm.IntentSearch, llm.IntentNearby, llm.IntentScore}
+
+// IntentSample is one labeled query of an intent evaluation set
+type IntentSample struct {
+	ID        string   `json:"id"`
+	Query     string   `json:"query"`
+	Intent    string   `json:"intent"`
+	Entities  []string `json:"entities"`
+	Locations []string `json:"locations"`
+}
+
+// IntentEvalResult scores one extraction
+type IntentEvalResult struct {
+	ID        string   `json:"id"`
+	Query     string   `json:"query"`
+	Expected  string   `json:"expected"`
+	Predicted string   `json:"predicted"`
+	Correct   bool     `json:"correct"`
+	Entities  []string `json:"entities"`
+	Locations []string `json:"locations"`
+	Fallback  bool     `json:"fallback,omitempty"` // The LLM failed and the heuristic answered; not scored
+	Error     string   `json:"error,omitempty"`
+}
+
+// IntentScore is the precision and recall of one intent
+type IntentScore struct {
+	Precision float64 `json:"precision"`
+	Recall    float64 `json:"recall"`
+	Support   int     `json:"support"` // Golden queries expecting the intent
+}
+
+// ExtractorReport aggregates the results of one extractor. Entity and location
+// scores compare lowercased words, so "Virat Kohli" and ["Virat", "Kohli"]
+// match alike. The confusion matrix maps expected intent -> predicted intent
+// -> count.
+type ExtractorReport struct {
+	Extractor         string                    `json:"extractor"`
+	Samples           int                       `json:"samples"`
+	Scored            int                       `json:"scored"`
+	Fallbacks         int                       `json:"fallbacks"`
+	Accuracy          float64                   `json:"accuracy"`
+	EntityPrecision   float64                   `json:"entity_precision"`
+	EntityRecall      float64                   `json:"entity_recall"`
+	LocationPrecision float64                   `json:"location_precision"`
+	LocationRecall    float64                   `json:"location_recall"`
+	Intents           map[string]IntentScore    `json:"intents"`
+	Confusion         map[string]map[string]int `json:"confusion"`
+	Results           []IntentEvalResult        `json:"results"`
+}
+
+// IntentEvalReport holds the reports of the LLM extraction and of the
+// heuristic fallback over the same golden set
+type IntentEvalReport struct {
+	Models    []string         `json:"models"`
+	LLM       *ExtractorReport `json:"llm,omitempty"` // nil without an API key
+	Heuristic ExtractorReport  `json:"heuristic"`
+}
+
+// LoadIntentSamples reads a JSON array of labeled queries
+func LoadIntentSamples(path string) ([]IntentSample, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var samples []IntentSample
+	if err := json.Unmarshal(data, &samples); err != nil {
+		return nil, fmt.Errorf("invalid intent samples %s: %w", path, err)
+	}
+	known := map[string]bool{}
+	for _, intent := range IntentEvalIntents {
+		known[intent] = true
+	}
+	for i, sample := range samples {
+		if sample.Query == "" || !known[sample.Intent] {
+			return nil, fmt.Errorf("intent sample %d needs a query and one of the intents %s", i+1, strings.Join(IntentEvalIntents, ", "))
+		}
+	}
+	return samples, nil
+}
+
+// EvaluateIntents scores the heuristic extraction over the samples, and the
+// LLM extraction too when the client asks a model. Queries the LLM failed on
+// are counted as fallbacks and left out of its scores, so they reflect the
+// model's own answers only.
+func EvaluateIntents(client *llm.Client, models []string, samples []IntentSample) IntentEvalReport {
+	report := IntentEvalReport{
+		Models:    models,
+		Heuristic: evaluateExtractor("heuristic", llm.NewClient("", nil, 0), samples, false),
+	}
+	if client.Enabled() {
+		llmReport := evaluateExtractor("llm", client, samples, true)
+		report.LLM = &llmReport
+	}
+	return report
+}
+
+// evaluateExtractor runs one extractor over the samples. With skipFallbacks,
+// answers the heuristic gave in its place are not scored.
+func evaluateExtractor(name string, client *llm.Client, samples []IntentSample, skipFallbacks bool) ExtractorReport {
+	report := ExtractorReport{
+		Extractor: name,
+		Samples:   len(samples),
+		Intents:   map[string]IntentScore{},
+		Confusion: map[string]map[string]int{},
+		Results:   make([]IntentEvalResult, 0, len(samples)),
+	}
+	var entities, locations wordOverlap
+	predictedCounts := map[string]int{}
+	for _, sample := range samples {
+		degraded := &degradation.Report{}
+		extraction, err := client.WithReport(degraded).ExtractIntentAndEntities(sample.Query)
+		result := IntentEvalResult{ID: sample.ID, Query: sample.Query, Expected: sample.Intent}
+		if err != nil {
+			result.Error = err.Error()
+			report.Results = append(report.Results, result)
+			continue
+		}
+		result.Predicted = extraction.Intent
+		result.Correct = extraction.Intent == sample.Intent
+		result.Entities = extraction.Entities
+		result.Locations = extraction.Locations
+		_, result.Fallback = degraded.Modes()[degradation.SubsystemIntent]
+		report.Results = append(report.Results, result)
+		if result.Fallback {
+			report.Fallbacks++
+			if skipFallbacks {
+				continue
+			}
+		}
+
+		if report.Confusion[sample.Intent] == nil {
+			report.Confusion[sample.Intent] = map[string]int{}
+		}
+		report.Confusion[sample.Intent][extraction.Intent]++
+		predictedCounts[extraction.Intent]++
+		if result.Correct {
+			report.Accuracy++
+		}
+		entities.add(extraction.Entities, sample.Entities)
+		locations.add(extraction.Locations, sample.Locations)
+		report.Scored++
+	}
+
+	if report.Scored > 0 {
+		report.Accuracy /= float64(report.Scored)
+	}
+	report.EntityPrecision, report.EntityRecall = entities.scores()
+	report.LocationPrecision, report.LocationRecall = locations.scores()
+	for _, intent := range IntentEvalIntents {
+		score := IntentScore{}
+		correct := report.Confusion[intent][intent]
+		for _, count := range report.Confusion[intent] {
+			score.Support += count
+		}
+		if score.Support > 0 {
+			score.Recall = float64(correct) / float64(score.Support)
+		}
+		if predictedCounts[intent] > 0 {
+			score.Precision = float64(correct) / float64(predictedCounts[intent])
+		}
+		report.Intents[intent] = score
+	}
+	return report
+}
+
+// wordOverlap accumulates the lowercased words shared by predicted and
+// expected phrase lists
+type wordOverlap struct {
+	overlap, predicted, expected int
+}
+
+func (w *wordOverlap) add(predicted, expected []string) {
+	predictedWords, expectedWords := phraseWords(predicted), phraseWords(expected)
+	for word := range predictedWords {
+		if expectedWords[word] {
+			w.overlap++
+		}
+	}
+	w.predicted += len(predictedWords)
+	w.expected += len(expectedWords)
+}
+
+// scores returns precision and recall. Nothing predicted and nothing expected
+// is a perfect score.
+func (w wordOverlap) scores() (float64, float64) {
+	precision, recall := 1.0, 1.0
+	if w.predicted > 0 {
+		precision = float64(w.overlap) / float64(w.predicted)
+	}
+	if w.expected > 0 {
+		recall = float64(w.overlap) / float64(w.expected)
+	}
+	return precision, recall
+}
+
+// phraseWords returns the set of lowercased words of some phrases
+func phraseWords(phrases []string) map[string]bool {
+	words := map[string]bool{}
+	for _, phrase := range phrases {
+		for _, token := range evalTokens(phrase) {
+			words[token] = true
+		}
+	}
+	return words
+}
+
+// ConfusionTable renders a confusion matrix as text, expected intents down the
+// side and predicted intents across
+func ConfusionTable(confusion map[string]map[string]int) string {
+	columns := append([]string{}, IntentEvalIntents...)
+	seen := map[string]bool{}
+	for _, intent := range columns {
+		seen[intent] = true
+	}
+	var extra []string
+	for _, row := range confusion {
+		for predicted := range row {
+			if !seen[predicted] {
+				seen[predicted] = true
+				extra = append(extra, predicted)
+			}
+		}
+	}
+	sort.Strings(extra)
+	columns = append(columns, extra...)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%-18s", "expected\\predicted")
+	for _, column := range columns {
+		fmt.Fprintf(&b, " %9s", column)
+	}
+	b.WriteString("\n")
+	for _, expected := range IntentEvalIntents {
+		fmt.Fprintf(&b, "%-18s", expected)
+		for _, column := range columns {
+			fmt.Fprintf(&b, " %9d", confusion[expected][column])
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
@@ -0,0 +1,144 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/mahigadamsetty/Inshorts-task/internal/models"
+	"gorm.io/gorm"
+)
+
+const (
+	lifecycleVelocityWindow = time.Hour      // Events in this window make up the velocity
+	lifecycleBreakingAge    = 6 * time.Hour  // Breaking coverage was published at most this long ago
+	lifecycleStaleAge       = 48 * time.Hour // Coverage older than this is stale unless readers return to it
+	lifecycleUpdateBatch    = 500            // Articles updated per statement
+)
+
+// LifecycleResult counts the stories and articles whose state changed
+type LifecycleResult struct {
+	Stories  int `json:"stories"`
+	Articles int `json:"articles"`
+}
+
+// ValidState reports whether a state is one of the lifecycle states
+func ValidState(state string) bool {
+	return state == models.StateBreaking || state == models.StateDeveloping || state == models.StateStale
+}
+
+// lifecycleState classifies coverage last published at latest that drew
+// velocity events in the last lifecycleVelocityWindow. Recent coverage with
+// enough events is breaking, and recent coverage or older coverage drawing
+// that many events again is developing.
+func lifecycleState(latest time.Time, velocity, breakingEvents int64, now time.Time) string {
+	age := now.Sub(latest)
+	hot := velocity >= breakingEvents
+	switch {
+	case age <= lifecycleBreakingAge && hot:
+		return models.StateBreaking
+	case age <= lifecycleStaleAge || hot:
+		return models.StateDeveloping
+	default:
+		return models.StateStale
+	}
+}
+
+// UpdateLifecycleStates classifies every story and article as breaking,
+// developing or stale from the events of the last hour and how recently it
+// was published, and stores the states that changed. A story's velocity is
+// that of all its articles, and articles in a story share its state.
+func (s *Services) UpdateLifecycleStates(ctx context.Context, breakingEvents int64) (*LifecycleResult, error) {
+	database := s.db.WithContext(ctx)
+	if database == nil {
+		return nil, fmt.Errorf("database not initialized")
+	}
+
+	now := time.Now()
+	var velocities []struct {
+		ArticleID string
+		Events    int64
+	}
+	err := database.Model(&models.Event{}).
+		Select("article_id, COUNT(*) AS events").
+		Scopes(s.trafficScope(ctx)).
+		Where("timestamp >= ?", now.Add(-lifecycleVelocityWindow)).
+		Group("article_id").
+		Scan(&velocities).Error
+	if err != nil {
+		return nil, err
+	}
+	velocity := make(map[string]int64, len(velocities))
+	for _, v := range velocities {
+		velocity[v.ArticleID] = v.Events
+	}
+
+	var articles []models.Article
+	if err := database.Select("id, story_id, publication_date, state").Find(&articles).Error; err != nil {
+		return nil, err
+	}
+	var stories []models.Story
+	if err := database.Select("id, last_published, state").Find(&stories).Error; err != nil {
+		return nil, err
+	}
+
+	storyVelocity := make(map[uint]int64)
+	for _, article := range articles {
+		if article.StoryID != nil {
+			storyVelocity[*article.StoryID] += velocity[article.ID]
+		}
+	}
+	storyStates := make(map[uint]string, len(stories))
+	changedStories := make(map[string][]uint)
+	for _, story := range stories {
+		state := lifecycleState(story.LastPublished, storyVelocity[story.ID], breakingEvents, now)
+		storyStates[story.ID] = state
+		if state != story.State {
+			changedStories[state] = append(changedStories[state], story.ID)
+		}
+	}
+	changedArticles := make(map[string][]string)
+	for _, article := range articles {
+		state, ok := "", false
+		if article.StoryID != nil {
+			state, ok = storyStates[*article.StoryID]
+		}
+		if !ok {
+			state = lifecycleState(article.PublicationDate, velocity[article.ID], breakingEvents, now)
+		}
+		if state != article.State {
+			changedArticles[state] = append(changedArticles[state], article.ID)
+		}
+	}
+
+	result := &LifecycleResult{}
+	err = database.Transaction(func(tx *gorm.DB) error {
+		for state, ids := range changedStories {
+			err := tx.Model(&models.Story{}).Where("id IN ?", ids).
+				Updates(map[string]interface{}{"state": state, "state_changed_at": now}).Error
+			if err != nil {
+				return err
+			}
+			result.Stories += len(ids)
+		}
+		for state, ids := range changedArticles {
+			for start := 0; start < len(ids); start += lifecycleUpdateBatch {
+				end := start + lifecycleUpdateBatch
+				if end > len(ids) {
+					end = len(ids)
+				}
+				err := tx.Model(&models.Article{}).Where("id IN ?", ids[start:end]).
+					Updates(map[string]interface{}{"state": state, "state_changed_at": now}).Error
+				if err != nil {
+					return err
+				}
+			}
+			result.Articles += len(ids)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
@@ -0,0 +1,86 @@
+package services
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/mahigadamsetty/Inshorts-task/internal/geocode"
+	"github.com/mahigadamsetty/Inshorts-task/internal/models"
+	"gorm.io/gorm"
+)
+
+// maxPlaceNameWords is the longest place name looked up, as in "Andhra Pradesh"
+// or "Navi Mumbai"
+const maxPlaceNameWords = 3
+
+// ValidateLocation checks an article's coordinates and sets its location
+// source. Invalid coordinates are replaced by the first place the title or
+// description names when impute is set, and zeroed otherwise. Returns false
+// when the imported coordinates were invalid.
+func ValidateLocation(article *models.Article, impute bool) bool {
+	if models.ValidCoordinates(article.Latitude, article.Longitude) {
+		article.LocationSource = models.LocationImported
+		return true
+	}
+
+	article.Latitude, article.Longitude = 0, 0
+	article.LocationSource = models.LocationMissing
+	if impute {
+		if place, ok := ImputeLocation(article.Title, article.Description); ok {
+			article.Latitude, article.Longitude = place.Latitude, place.Longitude
+			article.LocationSource = models.LocationImputed
+		}
+	}
+	return false
+}
+
+// ImputeLocation geocodes the first place named in the title, or failing that
+// the description. Runs of capitalized words are looked up longest first, so
+// "Navi Mumbai" wins over "Mumbai".
+func ImputeLocation(title, description string) (geocode.Place, bool) {
+	for _, text := range []string{title, description} {
+		words := strings.Fields(text)
+		for i := range words {
+			for n := min(maxPlaceNameWords, len(words)-i); n > 0; n-- {
+				name, ok := capitalizedPhrase(words[i : i+n])
+				if !ok {
+					continue
+				}
+				if place, found := geocode.Resolve(name); found {
+					return place, true
+				}
+			}
+		}
+	}
+	return geocode.Place{}, false
+}
+
+// capitalizedPhrase joins words that all start with a capital letter, dropping
+// possessives and punctuation. Only the last word may end in punctuation.
+func capitalizedPhrase(words []string) (string, bool) {
+	notLetter := func(r rune) bool {
+		return !unicode.IsLetter(r)
+	}
+	parts := make([]string, len(words))
+	for i, word := range words {
+		word = strings.TrimSuffix(strings.TrimSuffix(word, "'s"), "’s")
+		trimmed := strings.TrimFunc(word, notLetter)
+		if trimmed == "" || !unicode.IsUpper([]rune(trimmed)[0]) {
+			return "", false
+		}
+		if i < len(words)-1 && strings.TrimRightFunc(word, notLetter) != word {
+			return "", false
+		}
+		parts[i] = trimmed
+	}
+	return strings.Join(parts, " "), true
+}
+
+// HasLocation limits a query to articles whose coordinates can be used for geo
+// ranking, matching models.Article.HasLocation
+func HasLocation(database *gorm.DB) *gorm.DB {
+	return database.
+		Where("(location_source IS NULL OR location_source <> ?)", models.LocationMissing).
+		Where("latitude BETWEEN -90 AND 90 AND longitude BETWEEN -180 AND 180").
+		Where("NOT (latitude = 0 AND longitude = 0)")
+}
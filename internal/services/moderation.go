@@ -0,0 +1,51 @@
+package services
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/mahigadamsetty/Inshorts-task/internal/llm"
+	"github.com/mahigadamsetty/Inshorts-task/internal/models"
+)
+
+// ModerateUnratedArticles runs the content safety pass over every article that
+// has not been rated yet and returns the number of articles tagged
+func (s *Services) ModerateUnratedArticles(ctx context.Context, llmClient *llm.Client, batchSize int) (int, error) {
+	database := s.db.WithContext(ctx)
+	if database == nil {
+		return 0, fmt.Errorf("database not initialized")
+	}
+	llmClient = llmClient.WithContext(ctx)
+
+	moderated := 0
+	for {
+		var articles []models.Article
+		err := database.
+			Select("id, title, description").
+			Where("content_rating IS NULL OR content_rating = ''").
+			Limit(batchSize).
+			Find(&articles).Error
+		if err != nil {
+			return moderated, err
+		}
+		if len(articles) == 0 {
+			return moderated, nil
+		}
+
+		for _, article := range articles {
+			result, err := llmClient.ModerateContent(article.Title, article.Description)
+			if err != nil {
+				return moderated, fmt.Errorf("failed to moderate article %s: %w", article.ID, err)
+			}
+
+			err = database.Model(&models.Article{ID: article.ID}).Updates(map[string]interface{}{
+				"content_rating": result.Rating,
+				"safety_tags":    models.StringArray(result.Tags),
+			}).Error
+			if err != nil {
+				return moderated, err
+			}
+			moderated++
+		}
+	}
+}
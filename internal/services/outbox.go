@@ -0,0 +1,363 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/mahigadamsetty/Inshorts-task/internal/apperr"
+	"github.com/mahigadamsetty/Inshorts-task/internal/models"
+	"github.com/mahigadamsetty/Inshorts-task/internal/telegram"
+	"github.com/mahigadamsetty/Inshorts-task/pkg/signing"
+	"gorm.io/gorm"
+)
+
+const (
+	outboxBatchSize  = 100
+	outboxLease      = 2 * time.Minute // A claimed message is sent again after this if its worker never reported back
+	outboxMaxBackoff = 6 * time.Hour
+)
+
+// OutboxSender delivers a message over one channel
+type OutboxSender func(ctx context.Context, message *models.OutboxMessage) error
+
+// outboxSubjects mirror a message's delivery state onto the record it
+// notifies of, in the transaction that updates the message
+var outboxSubjects = map[string]func(tx *gorm.DB, message *models.OutboxMessage) error{
+	models.SubjectGeofenceAlert: mirrorAlertDelivery,
+	models.SubjectDataRequest:   mirrorDataRequestDelivery,
+}
+
+// outboxWake nudges the dispatcher to look for messages before its next poll
+var outboxWake = make(chan struct{}, 1)
+
+// OutboxOptions configure the outbox dispatcher
+type OutboxOptions struct {
+	Workers      int
+	MaxAttempts  int           // Attempts before a message is dead-lettered
+	Backoff      time.Duration // Wait after the first failed attempt, doubling after every other
+	PollInterval time.Duration
+}
+
+// Outbox delivers the messages of the outbox table: geofence alerts, to
+// webhooks or Telegram chats, and data request webhooks. Messages are written
+// with EnqueueOutbox in the transaction of the change they announce, so a
+// committed change is always announced and a rolled back one never is.
+// Deliveries are at least once: a message is claimed with a lease before it
+// is sent, failures are retried with exponential backoff, and messages out of
+// attempts are dead-lettered for an admin to retry. Other channels, such as
+// push or email, plug in with Register.
+type Outbox struct {
+	svc     *Services
+	client  *http.Client
+	senders map[string]OutboxSender
+	options OutboxOptions
+}
+
+// NewOutbox creates a dispatcher that delivers the messages of svc to
+// webhooks, and runs once started
+func NewOutbox(svc *Services, options OutboxOptions) *Outbox {
+	if options.Workers <= 0 {
+		options.Workers = 1
+	}
+	if options.MaxAttempts <= 0 {
+		options.MaxAttempts = 1
+	}
+	if options.PollInterval <= 0 {
+		options.PollInterval = 5 * time.Second
+	}
+	o := &Outbox{
+		svc:     svc,
+		client:  newWebhookClient(10*time.Second, svc.privateWebhooks, svc.resolver),
+		senders: make(map[string]OutboxSender),
+		options: options,
+	}
+	o.Register(models.ChannelWebhook, o.postWebhook)
+	return o
+}
+
+// Register delivers the messages of a channel with sender, replacing any
+// sender registered for it
+func (o *Outbox) Register(channel string, sender OutboxSender) {
+	o.senders[channel] = sender
+}
+
+// UseTelegram delivers the messages of the Telegram channel through the bot
+func (o *Outbox) UseTelegram(client *telegram.Client) {
+	o.Register(models.ChannelTelegram, func(ctx context.Context, message *models.OutboxMessage) error {
+		chatID, err := strconv.ParseInt(message.Target, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid chat ID %q", message.Target)
+		}
+		return client.SendMessage(ctx, chatID, message.Body)
+	})
+}
+
+// Start delivers due messages in the background until ctx is cancelled,
+// starting with those a previous process left pending
+func (o *Outbox) Start(ctx context.Context) {
+	if err := o.svc.enqueueLegacyAlerts(ctx); err != nil {
+		log.Printf("Failed to move pending geofence alerts to the outbox: %v", err)
+	}
+	go o.run(ctx)
+}
+
+// EnqueueOutbox writes a message for delivery as part of tx. Callers call
+// WakeOutbox once tx is committed.
+func EnqueueOutbox(tx *gorm.DB, message *models.OutboxMessage) error {
+	message.Status = models.OutboxPending
+	message.Attempts = 0
+	message.NextAttemptAt = time.Now()
+	return tx.Create(message).Error
+}
+
+// WakeOutbox has the dispatcher look for due messages now rather than at its
+// next poll. It never blocks.
+func WakeOutbox() {
+	select {
+	case outboxWake <- struct{}{}:
+	default:
+	}
+}
+
+// run polls for due messages until ctx is cancelled
+func (o *Outbox) run(ctx context.Context) {
+	ticker := time.NewTicker(o.options.PollInterval)
+	defer ticker.Stop()
+	for {
+		o.dispatch(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-outboxWake:
+		}
+	}
+}
+
+// dispatch claims and sends due messages, oldest first, until none are left
+func (o *Outbox) dispatch(ctx context.Context) {
+	database := o.svc.db.WithContext(ctx)
+	for ctx.Err() == nil {
+		now := time.Now()
+		var due []models.OutboxMessage
+		err := database.Where("status = ? AND next_attempt_at <= ?", models.OutboxPending, now).
+			Order("next_attempt_at").Order("id").
+			Limit(outboxBatchSize).
+			Find(&due).Error
+		if err != nil {
+			log.Printf("Failed to load due outbox messages: %v", err)
+			return
+		}
+		if len(due) == 0 {
+			return
+		}
+
+		sem := make(chan struct{}, o.options.Workers)
+		var wg sync.WaitGroup
+		for i := range due {
+			// Push the next attempt past the lease, so other replicas and later
+			// polls skip the message while it is being sent
+			claim := database.Model(&models.OutboxMessage{}).
+				Where("id = ? AND status = ? AND next_attempt_at <= ?", due[i].ID, models.OutboxPending, now).
+				Update("next_attempt_at", now.Add(outboxLease))
+			if claim.Error != nil || claim.RowsAffected == 0 {
+				continue
+			}
+			sem <- struct{}{}
+			wg.Add(1)
+			go func(message *models.OutboxMessage) {
+				defer func() { <-sem; wg.Done() }()
+				o.deliver(ctx, message)
+			}(&due[i])
+		}
+		wg.Wait()
+		if len(due) < outboxBatchSize {
+			return
+		}
+	}
+}
+
+// deliver sends one message and records the outcome, scheduling a retry or
+// dead-lettering the message on failure
+func (o *Outbox) deliver(ctx context.Context, message *models.OutboxMessage) {
+	var err error
+	if sender, ok := o.senders[message.Channel]; ok {
+		err = sender(ctx, message)
+	} else {
+		err = fmt.Errorf("no sender for channel %s", message.Channel)
+	}
+
+	message.Attempts++
+	switch {
+	case err == nil:
+		now := time.Now()
+		message.Status = models.OutboxDelivered
+		message.DeliveredAt = &now
+		message.LastError = ""
+	case message.Attempts >= o.options.MaxAttempts:
+		message.Status = models.OutboxDead
+		message.LastError = err.Error()
+		log.Printf("Outbox message %d (%s %d) failed for good: %v", message.ID, message.Subject, message.SubjectID, err)
+	default:
+		message.LastError = err.Error()
+		message.NextAttemptAt = time.Now().Add(outboxBackoff(o.options.Backoff, message.Attempts))
+	}
+	if err := o.svc.saveOutboxMessage(ctx, message); err != nil {
+		log.Printf("Failed to save outbox message %d: %v", message.ID, err)
+	}
+}
+
+// outboxBackoff is the wait after a message's attempts-th failed attempt
+func outboxBackoff(base time.Duration, attempts int) time.Duration {
+	backoff := base
+	for i := 1; i < attempts && backoff < outboxMaxBackoff; i++ {
+		backoff *= 2
+	}
+	return min(backoff, outboxMaxBackoff)
+}
+
+// saveOutboxMessage persists a message's delivery state along with the
+// record it notifies of
+func (s *Services) saveOutboxMessage(ctx context.Context, message *models.OutboxMessage) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(message).Select("status", "attempts", "next_attempt_at", "last_error", "delivered_at", "updated_at").Updates(message).Error
+		if err != nil {
+			return err
+		}
+		if mirror, ok := outboxSubjects[message.Subject]; ok {
+			return mirror(tx, message)
+		}
+		return nil
+	})
+}
+
+// postWebhook posts a message's body to its URL, signed with a fresh
+// timestamp and nonce when the message has a secret
+func (o *Outbox) postWebhook(ctx context.Context, message *models.OutboxMessage) error {
+	body := []byte(message.Body)
+	req, err := http.NewRequestWithContext(ctx, "POST", message.Target, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for name, value := range message.Headers {
+		req.Header.Set(name, value)
+	}
+	req.Header.Set("X-Outbox-Message-ID", strconv.FormatUint(uint64(message.ID), 10))
+	if message.Secret != "" {
+		signing.SetHeaders(req.Header, message.Secret, body)
+	}
+
+	resp, err := o.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned status code %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// OutboxStats counts the messages of the outbox by status
+type OutboxStats struct {
+	Pending   int64 `json:"pending"`
+	Delivered int64 `json:"delivered"`
+	Dead      int64 `json:"dead"`
+}
+
+// ListOutboxMessages returns the newest messages of a status, or of any status
+// when it is empty, optionally of one channel, with the counts per status
+func (s *Services) ListOutboxMessages(ctx context.Context, status, channel string, limit int) ([]models.OutboxMessage, *OutboxStats, error) {
+	database := s.db.WithContext(ctx)
+	if database == nil {
+		return nil, nil, fmt.Errorf("database not initialized")
+	}
+	switch status {
+	case "", models.OutboxPending, models.OutboxDelivered, models.OutboxDead:
+	default:
+		return nil, nil, apperr.InvalidFilterf("status must be pending, delivered or dead")
+	}
+
+	query := database.Model(&models.OutboxMessage{})
+	if channel != "" {
+		query = query.Where("channel = ?", channel)
+	}
+	var counts []struct {
+		Status string
+		Count  int64
+	}
+	if err := query.Session(&gorm.Session{}).Select("status, COUNT(*) AS count").Group("status").Scan(&counts).Error; err != nil {
+		return nil, nil, err
+	}
+	stats := &OutboxStats{}
+	for _, count := range counts {
+		switch count.Status {
+		case models.OutboxPending:
+			stats.Pending = count.Count
+		case models.OutboxDelivered:
+			stats.Delivered = count.Count
+		case models.OutboxDead:
+			stats.Dead = count.Count
+		}
+	}
+
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	var messages []models.OutboxMessage
+	err := query.Order("id DESC").Limit(limit).Find(&messages).Error
+	return messages, stats, err
+}
+
+// RetryOutboxMessage gives a dead or pending message a fresh set of attempts,
+// starting now
+func (s *Services) RetryOutboxMessage(ctx context.Context, id uint) (*models.OutboxMessage, error) {
+	var message models.OutboxMessage
+	if err := s.db.WithContext(ctx).First(&message, id).Error; err != nil {
+		return nil, apperr.NotFound(err, "Outbox message")
+	}
+	if message.Status == models.OutboxDelivered {
+		return nil, apperr.InvalidFilterf("message %d was already delivered", id)
+	}
+	message.Status = models.OutboxPending
+	message.Attempts = 0
+	message.NextAttemptAt = time.Now()
+	if err := s.saveOutboxMessage(ctx, &message); err != nil {
+		return nil, err
+	}
+	WakeOutbox()
+	return &message, nil
+}
+
+// DiscardOutboxMessage deletes a message that is not pending
+func (s *Services) DiscardOutboxMessage(ctx context.Context, id uint) error {
+	var message models.OutboxMessage
+	if err := s.db.WithContext(ctx).First(&message, id).Error; err != nil {
+		return apperr.NotFound(err, "Outbox message")
+	}
+	if message.Status == models.OutboxPending {
+		return apperr.InvalidFilterf("message %d is still pending", id)
+	}
+	return s.db.WithContext(ctx).Delete(&message).Error
+}
+
+// PurgeOutbox deletes delivered messages older than the retention period.
+// Dead messages are kept until retried or discarded.
+func (s *Services) PurgeOutbox(ctx context.Context, retention time.Duration) (int64, error) {
+	database := s.db.WithContext(ctx)
+	if database == nil {
+		return 0, fmt.Errorf("database not initialized")
+	}
+	result := database.
+		Where("status = ? AND delivered_at < ?", models.OutboxDelivered, time.Now().Add(-retention)).
+		Delete(&models.OutboxMessage{})
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,96 @@
+package services
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/mahigadamsetty/Inshorts-task/internal/models"
+)
+
+// SearchCursor marks the last article of a search page. Pages are cut from
+// results ranked by score and diversified, over the articles that existed at
+// the snapshot time, so later pages neither repeat nor skip articles when new
+// ones arrive.
+type SearchCursor struct {
+	Score     float64         `json:"s"`
+	Published time.Time       `json:"p"`
+	ID        string          `json:"id"`
+	Snapshot  time.Time       `json:"t"`
+	Query     string          `json:"q"`           // The query the cursor belongs to
+	Boosts    SearchBoosts    `json:"b"`           // The boosts the results were ranked with
+	Diversity DiversityLimits `json:"d,omitempty"` // The diversity limits the results were reordered with
+}
+
+// Encode returns the cursor as an opaque URL-safe string
+func (c SearchCursor) Encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// DecodeSearchCursor parses a cursor returned by Encode. An empty string
+// yields a nil cursor, meaning the first page.
+func DecodeSearchCursor(encoded string) (*SearchCursor, error) {
+	if encoded == "" {
+		return nil, nil
+	}
+	data, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("malformed cursor")
+	}
+	var cursor SearchCursor
+	if err := json.Unmarshal(data, &cursor); err != nil || cursor.ID == "" || cursor.Snapshot.IsZero() {
+		return nil, fmt.Errorf("malformed cursor")
+	}
+	return &cursor, nil
+}
+
+// SearchPage returns the page of ranked articles following the cursor, or the
+// first page for a nil cursor, along with the cursor of the next page, built
+// from base. The next cursor is nil on the last page. Articles must carry the
+// score explanation set by RankBySearchRelevance.
+//
+// A page resumes after the cursor's article. Since the snapshot fixes both the
+// candidates and their scores, that article keeps its position; should it have
+// been removed since, the page resumes after its place in score order instead.
+func SearchPage(ranked []models.Article, cursor *SearchCursor, limit int, base SearchCursor) ([]models.Article, *SearchCursor) {
+	start := 0
+	if cursor != nil {
+		start = -1
+		for i, article := range ranked {
+			if article.ID == cursor.ID {
+				start = i + 1
+				break
+			}
+		}
+		if start < 0 {
+			start = 0
+			for start < len(ranked) && !after(ranked[start], cursor) {
+				start++
+			}
+		}
+	}
+
+	end := start + limit
+	if end >= len(ranked) {
+		return ranked[start:], nil
+	}
+
+	last := ranked[end-1]
+	next := base
+	next.Score = last.Explanation.Final
+	next.Published = last.PublicationDate
+	next.ID = last.ID
+	return ranked[start:end], &next
+}
+
+// after reports whether an article ranks after the cursor's article by score
+// and TieBreak
+func after(article models.Article, cursor *SearchCursor) bool {
+	score := article.Explanation.Final
+	if score != cursor.Score {
+		return score < cursor.Score
+	}
+	return TieBreak(models.Article{ID: cursor.ID, PublicationDate: cursor.Published}, article)
+}
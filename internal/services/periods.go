@@ -0,0 +1,27 @@
+package services
+
+import "time"
+
+// Periods accepted by the period filter
+const (
+	PeriodToday    = "today"
+	PeriodThisWeek = "this_week"
+)
+
+// PeriodBounds returns the publication dates of a period as a half-open
+// interval [from, to) in UTC. Days start at midnight and weeks on Monday in
+// the timezone of now, so "today" is the client's day rather than the
+// server's. Returns false for unknown periods.
+func PeriodBounds(period string, now time.Time) (from, to time.Time, ok bool) {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	switch period {
+	case PeriodToday:
+		from, to = today, today.AddDate(0, 0, 1)
+	case PeriodThisWeek:
+		from = today.AddDate(0, 0, -((int(today.Weekday()) + 6) % 7))
+		to = from.AddDate(0, 0, 7)
+	default:
+		return time.Time{}, time.Time{}, false
+	}
+	return from.UTC(), to.UTC(), true
+}
@@ -0,0 +1,126 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/mahigadamsetty/Inshorts-task/internal/models"
+	"github.com/mahigadamsetty/Inshorts-task/internal/tuning"
+	"gorm.io/gorm"
+)
+
+// popularityScores returns every counted article's popularity at now: its
+// stored popularity decayed since it was last folded, plus the unique viewers
+// counted since. The returned rows hold these values, folded at now.
+func popularityScores(database *gorm.DB, now time.Time, halfLifeHours float64) (map[string]float64, []models.ArticlePopularity, error) {
+	var counters []models.ArticleViews
+	if err := database.Select("article_id, tenant_id, unique_viewers").Find(&counters).Error; err != nil {
+		return nil, nil, err
+	}
+	var stored []models.ArticlePopularity
+	if err := database.Find(&stored).Error; err != nil {
+		return nil, nil, err
+	}
+	storedByID := make(map[string]models.ArticlePopularity, len(stored))
+	for _, popularity := range stored {
+		storedByID[popularity.ArticleID] = popularity
+	}
+
+	scores := make(map[string]float64, len(counters))
+	folded := make([]models.ArticlePopularity, 0, len(counters))
+	for _, counter := range counters {
+		popularity, ok := storedByID[counter.ArticleID]
+		if !ok {
+			popularity = models.ArticlePopularity{ArticleID: counter.ArticleID, TenantID: counter.TenantID, DecayedAt: now}
+		}
+		// Decay is measured in time, so folding more or less often gives the same score
+		popularity.Score *= freshnessAt(popularity.DecayedAt, halfLifeHours, now)
+		if counter.UniqueViewers > popularity.Viewers {
+			popularity.Score += float64(counter.UniqueViewers - popularity.Viewers)
+		}
+		popularity.Viewers = counter.UniqueViewers
+		popularity.DecayedAt = now
+
+		scores[counter.ArticleID] = popularity.Score
+		folded = append(folded, popularity)
+	}
+	return scores, folded, nil
+}
+
+// DecayPopularity folds the unique viewers counted since the last run into
+// every article's decayed popularity, recalibrates scores so /score ranks by
+// it, and records each article's scores in the score history. Samples older
+// than keepDays are dropped; zero keeps them all. Returns the number of
+// articles sampled.
+func (s *Services) DecayPopularity(ctx context.Context, profile RankingProfile, keepDays int) (int, error) {
+	database := s.db.WithContext(ctx)
+	if database == nil {
+		return 0, fmt.Errorf("database not initialized")
+	}
+
+	// Count the viewers still buffered in memory
+	if _, err := s.FlushViewCounts(ctx); err != nil {
+		return 0, err
+	}
+
+	now := time.Now()
+	scores, folded, err := popularityScores(database, now, tuning.Current().Ranking.PopularityHalfLifeHours)
+	if err != nil {
+		return 0, err
+	}
+	err = database.Transaction(func(tx *gorm.DB) error {
+		for i := range folded {
+			if err := tx.Save(&folded[i]).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	if _, err := s.RecalibrateScores(ctx, profile); err != nil {
+		return 0, err
+	}
+
+	var articles []models.Article
+	if err := database.Select("id, tenant_id, computed_score").Find(&articles).Error; err != nil {
+		return 0, err
+	}
+	history := make([]models.ScoreHistory, len(articles))
+	for i, article := range articles {
+		history[i] = models.ScoreHistory{
+			ArticleID:     article.ID,
+			ComputedScore: article.ComputedScore,
+			Popularity:    scores[article.ID],
+			TenantID:      article.TenantID,
+			RecordedAt:    now,
+		}
+	}
+	if len(history) > 0 {
+		if err := database.CreateInBatches(history, 500).Error; err != nil {
+			return 0, err
+		}
+	}
+
+	if keepDays > 0 {
+		cutoff := now.AddDate(0, 0, -keepDays)
+		if err := database.Where("recorded_at < ?", cutoff).Delete(&models.ScoreHistory{}).Error; err != nil {
+			return 0, err
+		}
+	}
+	return len(history), nil
+}
+
+// ScoreHistoryOf returns an article's score samples since a time, oldest
+// first, limited to the tenant carried by ctx
+func (s *Services) ScoreHistoryOf(ctx context.Context, articleID string, since time.Time) ([]models.ScoreHistory, error) {
+	var history []models.ScoreHistory
+	err := s.db.WithContext(ctx).
+		Where("article_id = ? AND recorded_at >= ?", articleID, since).
+		Order("recorded_at").
+		Find(&history).Error
+	return history, err
+}
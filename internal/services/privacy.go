@@ -0,0 +1,51 @@
+package services
+
+import (
+	"context"
+
+	"github.com/mahigadamsetty/Inshorts-task/internal/tenant"
+	"github.com/mahigadamsetty/Inshorts-task/internal/utils"
+)
+
+// LocationPrivacy sets how many decimal places of user coordinates are kept:
+// Precision for storage and logs, CoarsePrecision for clients asking for
+// city-level targeting. Negative values keep coordinates as given.
+type LocationPrivacy struct {
+	Precision       int
+	CoarsePrecision int
+}
+
+// PrivateLocation truncates user coordinates to the stored precision, or to
+// the coarse precision when the client did not ask for precise targeting
+func (s *Services) PrivateLocation(lat, lon float64, precise bool) (float64, float64) {
+	decimals := s.privacy.Precision
+	coarse := s.privacy.CoarsePrecision
+	if !precise && coarse >= 0 && (decimals < 0 || coarse < decimals) {
+		decimals = coarse
+	}
+	return utils.TruncateCoordinate(lat, decimals), utils.TruncateCoordinate(lon, decimals)
+}
+
+// ResponseCoordinates returns how precisely responses to the tenant carried
+// by ctx give out coordinates, nil when as stored
+func (s *Services) ResponseCoordinates(ctx context.Context) *utils.CoordinateLimit {
+	limit := s.responseCoordinates
+	if t, ok := tenant.FromContext(ctx); ok {
+		limit = t.CoordinateLimit(limit)
+	}
+	if !limit.Limits() {
+		return nil
+	}
+	return &limit
+}
+
+// tenantCoordinates returns how precisely payloads sent to a tenant outside of
+// a request give out coordinates, nil when as stored
+func (s *Services) tenantCoordinates(tenantID string) *utils.CoordinateLimit {
+	if s.tenants != nil {
+		if t, ok := s.tenants.Get(tenantID); ok {
+			return s.ResponseCoordinates(tenant.NewContext(context.Background(), t))
+		}
+	}
+	return s.ResponseCoordinates(context.Background())
+}
@@ -0,0 +1,276 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/mahigadamsetty/Inshorts-task/internal/apperr"
+	"github.com/mahigadamsetty/Inshorts-task/internal/db"
+	"github.com/mahigadamsetty/Inshorts-task/internal/models"
+	"gorm.io/gorm"
+)
+
+// Limits on the articles publishers push
+const (
+	maxPublishedTitle       = 500
+	maxPublishedDescription = 5000
+	maxPublishedCategories  = 10
+)
+
+// publishedIDPattern restricts the IDs publishers may choose for their articles
+var publishedIDPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+// ErrDuplicateArticle is returned when a pushed article has the ID, or the
+// canonical URL and title, of a stored one
+var ErrDuplicateArticle = apperr.Conflictf("article already exists")
+
+// PublishedArticle is an article as a publisher pushes it. Its source is the
+// one the publisher's key is bound to.
+type PublishedArticle struct {
+	ID              string     `json:"id"` // Optional on create, generated when empty
+	Title           string     `json:"title"`
+	Description     string     `json:"description"`
+	URL             string     `json:"url"`
+	PublicationDate time.Time  `json:"publication_date"`
+	Category        []string   `json:"category"`
+	RelevanceScore  *float64   `json:"relevance_score"` // 0-1, default 0.5
+	Latitude        *float64   `json:"latitude"`        // Both or neither
+	Longitude       *float64   `json:"longitude"`
+	VisibleFrom     *time.Time `json:"visible_from"`
+	ExpiresAt       *time.Time `json:"expires_at"`
+}
+
+// PublishOptions are the ingestion settings applied to pushed articles, as
+// to imported ones
+type PublishOptions struct {
+	ResolveRedirects bool
+	ImputeLocations  bool
+}
+
+// validate checks a pushed article, returning an ErrInvalidFilter describing
+// the first problem
+func (p *PublishedArticle) validate() error {
+	p.Title = strings.TrimSpace(p.Title)
+	p.Description = strings.TrimSpace(p.Description)
+	p.URL = strings.TrimSpace(p.URL)
+	switch {
+	case p.Title == "":
+		return apperr.InvalidFilterf("title is required")
+	case len(p.Title) > maxPublishedTitle:
+		return apperr.InvalidFilterf("title must be at most %d bytes", maxPublishedTitle)
+	case len(p.Description) > maxPublishedDescription:
+		return apperr.InvalidFilterf("description must be at most %d bytes", maxPublishedDescription)
+	}
+
+	target, err := url.Parse(p.URL)
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+		return apperr.InvalidFilterf("url must be an absolute http or https URL")
+	}
+
+	if p.PublicationDate.IsZero() {
+		return apperr.InvalidFilterf("publication_date is required")
+	}
+	if !p.PublicationDate.After(time.Unix(0, 0)) || p.PublicationDate.After(time.Now().Add(maxFutureSkew)) {
+		return apperr.InvalidFilterf("publication_date %s is implausible", p.PublicationDate.Format(time.RFC3339))
+	}
+	if p.VisibleFrom != nil && p.ExpiresAt != nil && !p.ExpiresAt.After(*p.VisibleFrom) {
+		return apperr.InvalidFilterf("expires_at must be after visible_from")
+	}
+
+	if len(p.Category) > maxPublishedCategories {
+		return apperr.InvalidFilterf("at most %d categories are allowed", maxPublishedCategories)
+	}
+	categories := make([]string, 0, len(p.Category))
+	for _, category := range p.Category {
+		if category = strings.TrimSpace(category); category == "" {
+			return apperr.InvalidFilterf("categories must not be empty")
+		}
+		categories = append(categories, category)
+	}
+	p.Category = categories
+
+	if p.RelevanceScore != nil && (*p.RelevanceScore < 0 || *p.RelevanceScore > 1) {
+		return apperr.InvalidFilterf("relevance_score must be between 0 and 1")
+	}
+	if (p.Latitude == nil) != (p.Longitude == nil) {
+		return apperr.InvalidFilterf("latitude and longitude must be given together")
+	}
+	return nil
+}
+
+// apply copies the pushed fields onto a stored or new article, reporting
+// whether its URL and its text changed. Categories and URLs are canonicalized
+// with s.
+func (p *PublishedArticle) apply(ctx context.Context, s *Services, article *models.Article, options PublishOptions) (urlChanged, textChanged bool) {
+	urlChanged = article.URL != p.URL
+	textChanged = article.Title != p.Title || article.Description != p.Description
+
+	article.Title = p.Title
+	article.Description = p.Description
+	article.URL = p.URL
+	article.PublicationDate = p.PublicationDate.UTC()
+	article.Category = models.StringArray(s.CanonicalCategories(ctx, p.Category))
+	article.RelevanceScore = 0.5
+	if p.RelevanceScore != nil {
+		article.RelevanceScore = *p.RelevanceScore
+	}
+	article.Latitude, article.Longitude = 0, 0
+	if p.Latitude != nil {
+		article.Latitude, article.Longitude = *p.Latitude, *p.Longitude
+	}
+	article.VisibleFrom = p.VisibleFrom
+	article.ExpiresAt = p.ExpiresAt
+
+	article.CanonicalURL = s.CanonicalArticleURL(ctx, article.SourceName, article.URL, options.ResolveRedirects)
+	article.TitleKey = TitleKey(article.Title)
+	ValidateLocation(article, options.ImputeLocations)
+	return urlChanged, textChanged
+}
+
+// PublishArticle stores a new article of a publisher's source for the tenant
+// carried by ctx. Its text is fetched, and it is moderated and clustered, by
+// the same jobs as imported articles.
+func (s *Services) PublishArticle(ctx context.Context, source string, input PublishedArticle, options PublishOptions) (*models.Article, error) {
+	database := s.db.WithContext(db.IncludeHidden(ctx))
+	if database == nil {
+		return nil, fmt.Errorf("database not initialized")
+	}
+	if err := input.validate(); err != nil {
+		return nil, err
+	}
+	if input.ID == "" {
+		input.ID = newArticleID()
+	} else if !publishedIDPattern.MatchString(input.ID) {
+		return nil, apperr.InvalidFilterf("id must be 1-64 letters, digits, dashes or underscores")
+	}
+
+	// IDs are unique across tenants, so look the ID up unscoped
+	var existing int64
+	if err := s.db.WithContext(context.Background()).Model(&models.Article{}).Where("id = ?", input.ID).Count(&existing).Error; err != nil {
+		return nil, err
+	}
+	if existing > 0 {
+		return nil, fmt.Errorf("%w: id %s is taken", ErrDuplicateArticle, input.ID)
+	}
+
+	article := &models.Article{ID: input.ID, SourceName: source}
+	input.apply(ctx, s, article, options)
+	if err := checkDuplicate(database, article); err != nil {
+		return nil, err
+	}
+	if err := database.Create(article).Error; err != nil {
+		return nil, err
+	}
+	return article, nil
+}
+
+// UpdatePublishedArticle replaces the fields of an article of a publisher's
+// source. A new URL is fetched again, and new text is moderated and
+// summarized again.
+func (s *Services) UpdatePublishedArticle(ctx context.Context, source, id string, input PublishedArticle, options PublishOptions) (*models.Article, error) {
+	database := s.db.WithContext(db.IncludeHidden(ctx))
+	if database == nil {
+		return nil, fmt.Errorf("database not initialized")
+	}
+	if err := input.validate(); err != nil {
+		return nil, err
+	}
+	article, err := publishedArticle(database, source, id)
+	if err != nil {
+		return nil, err
+	}
+
+	urlChanged, textChanged := input.apply(ctx, s, article, options)
+	if err := checkDuplicate(database, article); err != nil {
+		return nil, err
+	}
+	if urlChanged {
+		article.FetchedAt = nil
+		article.ExtractionAttempts = 0
+	}
+	if textChanged {
+		article.ContentRating = ""
+		article.SafetyTags = nil
+		article.SummaryStale = article.LLMSummary != ""
+		article.SummaryShort, article.SummaryLong = "", ""
+	}
+	if err := database.Save(article).Error; err != nil {
+		return nil, err
+	}
+	if textChanged {
+		if err := database.Where("article_id = ?", article.ID).Delete(&models.ArticleEmbedding{}).Error; err != nil {
+			return nil, err
+		}
+	}
+	s.InvalidateTrendingCache(ctx)
+	return article, nil
+}
+
+// WithdrawArticle hides an article of a publisher's source from clients from
+// now on by expiring it. Withdrawn articles are kept, and an update with no
+// or a later expires_at publishes them again.
+func (s *Services) WithdrawArticle(ctx context.Context, source, id string) (*models.Article, error) {
+	database := s.db.WithContext(db.IncludeHidden(ctx))
+	if database == nil {
+		return nil, fmt.Errorf("database not initialized")
+	}
+	article, err := publishedArticle(database, source, id)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	if article.ExpiresAt == nil || article.ExpiresAt.After(now) {
+		article.ExpiresAt = &now
+		if err := database.Model(article).Update("expires_at", now).Error; err != nil {
+			return nil, err
+		}
+		s.InvalidateTrendingCache(ctx)
+	}
+	return article, nil
+}
+
+// publishedArticle loads an article of a source. Publishers see their
+// embargoed and withdrawn articles, so database must include hidden ones.
+func publishedArticle(database *gorm.DB, source, id string) (*models.Article, error) {
+	var article models.Article
+	err := database.Where("id = ? AND source_name = ?", id, source).First(&article).Error
+	if err != nil {
+		return nil, apperr.NotFound(err, "Article")
+	}
+	return &article, nil
+}
+
+// checkDuplicate returns an ErrDuplicateArticle when another article of the
+// tenant has the article's canonical URL and title
+func checkDuplicate(database *gorm.DB, article *models.Article) error {
+	if article.CanonicalURL == nil {
+		return nil
+	}
+	var other models.Article
+	err := database.Select("id").
+		Where("canonical_url = ? AND title_key = ? AND id <> ?", *article.CanonicalURL, article.TitleKey, article.ID).
+		First(&other).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return fmt.Errorf("%w: article %s has the same URL and title", ErrDuplicateArticle, other.ID)
+}
+
+// newArticleID returns a random UUID, like the IDs of imported articles
+func newArticleID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
@@ -0,0 +1,218 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/mahigadamsetty/Inshorts-task/internal/apperr"
+	"github.com/mahigadamsetty/Inshorts-task/internal/llm"
+	"github.com/mahigadamsetty/Inshorts-task/internal/models"
+	"gorm.io/gorm"
+)
+
+// ErrPurgeCountChanged is returned when the number of articles matching a
+// purge differs from the count its dry run reported
+var ErrPurgeCountChanged = apperr.Conflictf("the matching articles changed since the dry run")
+
+// PurgeFilter selects the articles a bulk purge deletes. Tenant only narrows
+// the other conditions, so a purge always names a source, category or dates.
+type PurgeFilter struct {
+	Tenant   string    `json:"tenant,omitempty"`
+	Source   string    `json:"source,omitempty"`
+	Category string    `json:"category,omitempty"` // Exact category, case-insensitive
+	From     time.Time `json:"from,omitempty"`     // Earliest publication date (inclusive), zero for no bound
+	To       time.Time `json:"to,omitempty"`       // Latest publication date (exclusive), zero for no bound
+}
+
+// Validate rejects filters that would match the whole archive or no dates
+func (f PurgeFilter) Validate() error {
+	if strings.TrimSpace(f.Source) == "" && strings.TrimSpace(f.Category) == "" && f.From.IsZero() && f.To.IsZero() {
+		return apperr.InvalidFilterf("at least one of source, category, from or to is required")
+	}
+	if !f.From.IsZero() && !f.To.IsZero() && !f.From.Before(f.To) {
+		return apperr.InvalidFilterf("from must be before to")
+	}
+	return nil
+}
+
+// scope limits a query over the articles table to the filter
+func (f PurgeFilter) scope(database *gorm.DB) *gorm.DB {
+	if f.Tenant != "" {
+		database = database.Where("tenant_id = ?", f.Tenant)
+	}
+	if key := models.SourceKey(f.Source); key != "" {
+		database = database.Where("LOWER(TRIM(source_name)) = ?", key)
+	}
+	if category := strings.ToLower(strings.TrimSpace(f.Category)); category != "" {
+		// Categories are stored as a JSON array, so match a whole quoted element
+		database = database.Where("LOWER(category) LIKE ?", `%"`+category+`"%`)
+	}
+	if !f.From.IsZero() {
+		database = database.Where("publication_date >= ?", f.From)
+	}
+	if !f.To.IsZero() {
+		database = database.Where("publication_date < ?", f.To)
+	}
+	return database
+}
+
+// PurgeInput is the body of a purge request. Dates are days like 2024-01-31,
+// both inclusive. The purge only runs with dry_run set to false and the
+// article count of the dry run in expected_articles.
+type PurgeInput struct {
+	Tenant           string `json:"tenant"`
+	Source           string `json:"source"`
+	Category         string `json:"category"`
+	From             string `json:"from"`
+	To               string `json:"to"`
+	DryRun           *bool  `json:"dry_run"` // Defaults to true
+	ExpectedArticles *int64 `json:"expected_articles"`
+}
+
+// Filter validates the input and converts it to a purge filter
+func (in PurgeInput) Filter() (PurgeFilter, error) {
+	filter := PurgeFilter{
+		Tenant:   strings.TrimSpace(in.Tenant),
+		Source:   in.Source,
+		Category: in.Category,
+	}
+	for _, bound := range []struct {
+		name  string
+		value string
+		day   *time.Time
+	}{{"from", in.From, &filter.From}, {"to", in.To, &filter.To}} {
+		if bound.value == "" {
+			continue
+		}
+		day, err := time.Parse(llm.DateLayout, bound.value)
+		if err != nil {
+			return PurgeFilter{}, apperr.InvalidFilterf("%s must be a date like 2024-01-31", bound.name)
+		}
+		*bound.day = day
+	}
+	if !filter.To.IsZero() {
+		filter.To = filter.To.AddDate(0, 0, 1) // Include the whole end day
+	}
+	return filter, filter.Validate()
+}
+
+// purgeTables are the per-article records deleted along with the articles.
+// Summaries are stored on the article itself.
+var purgeTables = []struct {
+	name  string
+	model interface{}
+}{
+	{"events", &models.Event{}},
+	{"article_views", &models.ArticleViews{}},
+	{"article_embeddings", &models.ArticleEmbedding{}},
+	{"geofence_alerts", &models.GeofenceAlert{}},
+	{"article_popularity", &models.ArticlePopularity{}},
+	{"score_history", &models.ScoreHistory{}},
+	{"impressions", &models.Impression{}},
+	{"article_attractiveness", &models.ArticleAttractiveness{}},
+	{"discovery_pulls", &models.DiscoveryPull{}},
+	{"discovery_arms", &models.DiscoveryArm{}},
+	{"article_audio", &models.ArticleAudio{}}, // Their files are removed by the next audio-summaries run
+	{"short_link_clicks", &models.ShortLinkClicks{}},
+	{"short_links", &models.ShortLink{}},
+}
+
+// CountPurge reports how many articles, and records of each kind linked to
+// them, a purge with the filter would delete
+func (s *Services) CountPurge(ctx context.Context, filter PurgeFilter) (map[string]int64, error) {
+	if err := filter.Validate(); err != nil {
+		return nil, err
+	}
+	database := s.db.WithContext(ctx)
+	if database == nil {
+		return nil, fmt.Errorf("database not initialized")
+	}
+	return countPurge(database, filter)
+}
+
+func countPurge(database *gorm.DB, filter PurgeFilter) (map[string]int64, error) {
+	records := make(map[string]int64)
+	var articles int64
+	if err := database.Model(&models.Article{}).Scopes(filter.scope).Count(&articles).Error; err != nil {
+		return nil, err
+	}
+	records["articles"] = articles
+	for _, table := range purgeTables {
+		var count int64
+		if err := database.Model(table.model).Where("article_id IN (?)", purgedIDs(database, filter)).Count(&count).Error; err != nil {
+			return nil, fmt.Errorf("count %s: %w", table.name, err)
+		}
+		records[table.name] = count
+	}
+	return records, nil
+}
+
+// purgedIDs is a subquery of the IDs of the articles matching the filter
+func purgedIDs(database *gorm.DB, filter PurgeFilter) *gorm.DB {
+	return database.Model(&models.Article{}).Select("id").Scopes(filter.scope)
+}
+
+// PurgeArticles deletes the articles matching the filter with their events,
+// view counters, embeddings, geofence alerts, popularity, score history and
+// summary audio, then drops the cached trending results and removes them
+// from the search index. expected is the article count of the dry run;
+// nothing is deleted when the filter matches a different number, so a purge
+// never removes more than was reviewed. Stories and topics are rebuilt by their
+// clustering jobs.
+func (s *Services) PurgeArticles(ctx context.Context, filter PurgeFilter, expected int64) (map[string]int64, error) {
+	if err := filter.Validate(); err != nil {
+		return nil, err
+	}
+	database := s.db.WithContext(ctx)
+	if database == nil {
+		return nil, fmt.Errorf("database not initialized")
+	}
+
+	// Write queued events, pending view counts and impressions first so they
+	// don't recreate records afterwards
+	if _, err := s.FlushEvents(ctx); err != nil {
+		return nil, err
+	}
+	if _, err := s.FlushViewCounts(ctx); err != nil {
+		return nil, err
+	}
+	if _, err := s.FlushImpressions(ctx); err != nil {
+		return nil, err
+	}
+
+	records := make(map[string]int64)
+	var ids []string
+	err := database.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Article{}).Scopes(filter.scope).Pluck("id", &ids).Error; err != nil {
+			return err
+		}
+		if articles := int64(len(ids)); articles != expected {
+			return fmt.Errorf("%w: %d articles match, expected %d", ErrPurgeCountChanged, articles, expected)
+		}
+
+		for _, table := range purgeTables {
+			result := tx.Where("article_id IN (?)", purgedIDs(tx, filter)).Delete(table.model)
+			if result.Error != nil {
+				return fmt.Errorf("delete %s: %w", table.name, result.Error)
+			}
+			records[table.name] = result.RowsAffected
+		}
+		result := tx.Scopes(filter.scope).Delete(&models.Article{})
+		if result.Error != nil {
+			return fmt.Errorf("delete articles: %w", result.Error)
+		}
+		records["articles"] = result.RowsAffected
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	records["trending_cache"] = int64(s.InvalidateTrendingCache(ctx))
+	s.removeFromSearch(ctx, ids)
+
+	log.Printf("Purged %d articles matching %+v", records["articles"], filter)
+	return records, nil
+}
@@ -0,0 +1,25 @@
+package services
+
+import (
+	"github.com/mahigadamsetty/Inshorts-task/internal/apperr"
+	"gorm.io/gorm"
+)
+
+// CheckCandidates estimates how many articles query would load by counting
+// its matches, stopping one past the MaxCandidates option, before the rows are
+// loaded. When there are more it returns an ErrTooExpensive that ends with
+// narrow, the way to make the request cheaper, instead of scanning the table.
+func (s *Services) CheckCandidates(query *gorm.DB, narrow string) error {
+	maxCandidates := s.maxCandidates
+	if maxCandidates <= 0 {
+		return nil
+	}
+	var ids []string
+	if err := query.Session(&gorm.Session{}).Limit(maxCandidates+1).Pluck("articles.id", &ids).Error; err != nil {
+		return err
+	}
+	if len(ids) > maxCandidates {
+		return apperr.TooExpensivef("This request matches more than %d articles, too many to rank; %s", maxCandidates, narrow)
+	}
+	return nil
+}
@@ -1,10 +1,14 @@
 package services
 
 import (
+	"math"
 	"sort"
 	"strings"
+	"time"
+	"unicode"
 
 	"github.com/mahigadamsetty/Inshorts-task/internal/models"
+	"github.com/mahigadamsetty/Inshorts-task/internal/tuning"
 	"github.com/mahigadamsetty/Inshorts-task/internal/utils"
 )
 
@@ -14,6 +18,250 @@ type ArticleWithScore struct {
 	Score   float64
 }
 
+// Ranker names reported in score explanations
+const (
+	RankerFreshness        = "relevance_freshness"
+	RankerRelevance        = "relevance_score"
+	RankerComputed         = "computed_score"
+	RankerSearch           = "text_match_freshness"
+	RankerDistance         = "distance"
+	RankerTrending         = "trending"
+	RankerDistanceTrending = "distance_trending"
+)
+
+// RankingProfile holds the tunable weights used by the hybrid rankers
+type RankingProfile struct {
+	FreshnessWeight        float64       // Share of the final score taken by freshness (0-1)
+	FreshnessHalfLifeHours float64       // Age at which the freshness score drops to 0.5
+	ReliabilityBoost       float64       // Largest +/- fraction applied for source reliability (0-1)
+	ShortReadBoost         float64       // Largest +/- fraction applied for how quick an article is to read (0-1)
+	AsOf                   time.Time     // Time article ages are measured at, zero for now
+	Boosts                 *SearchBoosts // Field boosts for search ranking, nil for DefaultSearchBoosts
+}
+
+// SearchBoosts weights the parts of a search score. Title and Description
+// weight keyword matches in those fields; Recent multiplies the freshness
+// weight against the text match.
+type SearchBoosts struct {
+	Title       float64 `json:"title"`
+	Description float64 `json:"description"`
+	Recent      float64 `json:"recent"`
+}
+
+// DefaultSearchBoosts returns the search boosts of the tuning settings, by
+// default weighting title matches 3x description matches and leaving the
+// freshness weight as configured
+func DefaultSearchBoosts() SearchBoosts {
+	return SearchBoosts(tuning.Current().Ranking.SearchBoosts)
+}
+
+// CurrentRankingProfile returns the hybrid ranker weights of the tuning settings
+func CurrentRankingProfile() RankingProfile {
+	return rankingProfileOf(tuning.Current().Ranking)
+}
+
+// rankingProfileOf returns the hybrid ranker weights of a set of tuning weights
+func rankingProfileOf(ranking tuning.Ranking) RankingProfile {
+	return RankingProfile{
+		FreshnessWeight:        ranking.FreshnessWeight,
+		FreshnessHalfLifeHours: ranking.FreshnessHalfLifeHours,
+		ReliabilityBoost:       ranking.ReliabilityBoost,
+		ShortReadBoost:         ranking.ShortReadBoost,
+	}
+}
+
+// MaxSearchBoost caps each search boost
+const MaxSearchBoost = 10.0
+
+// Capped returns the boosts limited to 0-MaxSearchBoost
+func (b SearchBoosts) Capped() SearchBoosts {
+	return SearchBoosts{
+		Title:       math.Max(0, math.Min(MaxSearchBoost, b.Title)),
+		Description: math.Max(0, math.Min(MaxSearchBoost, b.Description)),
+		Recent:      math.Max(0, math.Min(MaxSearchBoost, b.Recent)),
+	}
+}
+
+// searchBoosts returns the profile's search boosts, or the defaults
+func (p RankingProfile) searchBoosts() SearchBoosts {
+	if p.Boosts == nil {
+		return DefaultSearchBoosts()
+	}
+	return p.Boosts.Capped()
+}
+
+// withRecencyBoost returns the profile with its freshness weight scaled by
+// boost relative to the base score's weight, keeping the weights summing to 1
+func (p RankingProfile) withRecencyBoost(boost float64) RankingProfile {
+	weight := p.freshnessWeight()
+	total := weight*boost + (1 - weight)
+	if total > 0 {
+		p.FreshnessWeight = weight * boost / total
+	}
+	return p
+}
+
+// neutralReliability is assumed for sources without reliability metadata
+const neutralReliability = 0.5
+
+// FreshnessScore returns an exponential decay factor in (0, 1] based on article age
+func FreshnessScore(publicationDate time.Time, halfLifeHours float64) float64 {
+	return freshnessAt(publicationDate, halfLifeHours, time.Now())
+}
+
+// freshnessAt is FreshnessScore with article age measured at the given time
+func freshnessAt(publicationDate time.Time, halfLifeHours float64, now time.Time) float64 {
+	if halfLifeHours <= 0 {
+		return 1
+	}
+	ageHours := now.Sub(publicationDate).Hours()
+	if ageHours < 0 {
+		ageHours = 0
+	}
+	return math.Exp(-math.Ln2 * ageHours / halfLifeHours)
+}
+
+// recency returns an article's freshness at the profile's AsOf time
+func (p RankingProfile) recency(publicationDate time.Time) float64 {
+	if p.AsOf.IsZero() {
+		return FreshnessScore(publicationDate, p.FreshnessHalfLifeHours)
+	}
+	return freshnessAt(publicationDate, p.FreshnessHalfLifeHours, p.AsOf)
+}
+
+// freshnessWeight returns the freshness weight limited to 0-1
+func (p RankingProfile) freshnessWeight() float64 {
+	return math.Max(0, math.Min(1, p.FreshnessWeight))
+}
+
+// blend mixes a base score (expected in 0-1) with the article's freshness
+func (p RankingProfile) blend(base, recency float64) float64 {
+	weight := p.freshnessWeight()
+	return (1-weight)*base + weight*recency
+}
+
+// reliabilityMultiplier scales a score up or down by the article's source
+// reliability, returning the multiplier and the reliability used
+func (p RankingProfile) reliabilityMultiplier(article models.Article) (float64, float64) {
+	reliability := sourceReliabilityOf(article)
+	boost := math.Max(0, math.Min(1, p.ReliabilityBoost))
+	return 1 + boost*2*(reliability-neutralReliability), reliability
+}
+
+// neutralReadMinutes is the reading time the short read boost neither
+// rewards nor penalizes
+const neutralReadMinutes = 3
+
+// shortReadMultiplier scales a score up for articles quicker to read than
+// neutralReadMinutes and down for longer ones, returning the multiplier and
+// the reading time used, nil when the profile doesn't weight it. Reading time
+// is estimated from the stored text, or the description without one.
+func (p RankingProfile) shortReadMultiplier(article models.Article) (float64, *float64) {
+	boost := math.Max(0, math.Min(1, p.ShortReadBoost))
+	if boost == 0 {
+		return 1, nil
+	}
+	text := article.TextContent
+	if text == "" {
+		text = article.Description
+	}
+	minutes := float64(countWords(text)) / readingWordsPerMinute
+	shortness := 1 / (1 + minutes/neutralReadMinutes) // 1 for no text, 0.5 at the neutral time
+	return 1 + boost*2*(shortness-0.5), explainValue(minutes)
+}
+
+// countWords counts the whitespace-separated words of a text
+func countWords(text string) int {
+	words, inWord := 0, false
+	for _, r := range text {
+		if unicode.IsSpace(r) {
+			inWord = false
+		} else if !inWord {
+			words++
+			inWord = true
+		}
+	}
+	return words
+}
+
+// sourceReliabilityOf returns the article's source reliability, or the neutral
+// value when its source has no metadata
+func sourceReliabilityOf(article models.Article) float64 {
+	if article.SourceMeta == nil {
+		return neutralReliability
+	}
+	return article.SourceMeta.Reliability
+}
+
+// weights describes the profile for score explanations
+func (p RankingProfile) weights(baseName string) map[string]float64 {
+	weights := map[string]float64{
+		baseName:            1 - p.freshnessWeight(),
+		"recency":           p.freshnessWeight(),
+		"reliability_boost": p.ReliabilityBoost,
+	}
+	if p.ShortReadBoost > 0 {
+		weights["short_read_boost"] = p.ShortReadBoost
+	}
+	return weights
+}
+
+// sortScored orders scored articles by score, highest first when descending,
+// breaking ties with TieBreak so equal scores always rank the same way
+func sortScored(scored []ArticleWithScore, descending bool) {
+	sort.SliceStable(scored, func(i, j int) bool {
+		if scored[i].Score != scored[j].Score {
+			return (scored[i].Score > scored[j].Score) == descending
+		}
+		return TieBreak(scored[i].Article, scored[j].Article)
+	})
+}
+
+// TieBreak reports whether a ranks before b when their scores are equal: the
+// newer publication date first, then the lower ID
+func TieBreak(a, b models.Article) bool {
+	if !a.PublicationDate.Equal(b.PublicationDate) {
+		return a.PublicationDate.After(b.PublicationDate)
+	}
+	return a.ID < b.ID
+}
+
+// RankByFreshness ranks articles by relevance score blended with freshness and
+// boosted by source reliability
+func RankByFreshness(articles []models.Article, profile RankingProfile) []models.Article {
+	scored := make([]ArticleWithScore, len(articles))
+
+	for i, article := range articles {
+		recency := profile.recency(article.PublicationDate)
+		multiplier, reliability := profile.reliabilityMultiplier(article)
+		readMultiplier, readMinutes := profile.shortReadMultiplier(article)
+		score := profile.blend(article.RelevanceScore, recency) * multiplier * readMultiplier
+		article.Explanation = &models.ScoreExplanation{
+			Ranker:      RankerFreshness,
+			Relevance:   explainValue(article.RelevanceScore),
+			Recency:     explainValue(recency),
+			Reliability: explainValue(reliability),
+			ReadMinutes: readMinutes,
+			Weights:     profile.weights("relevance"),
+			Final:       score,
+		}
+		scored[i] = ArticleWithScore{
+			Article: article,
+			Score:   score,
+		}
+	}
+
+	// Sort by blended score (descending)
+	sortScored(scored, true)
+
+	result := make([]models.Article, len(scored))
+	for i, s := range scored {
+		result[i] = s.Article
+	}
+
+	return result
+}
+
 // RankByPublicationDate ranks articles by publication date (newest first)
 func RankByPublicationDate(articles []models.Article) []models.Article {
 	// Already sorted by database query
@@ -22,7 +270,27 @@ func RankByPublicationDate(articles []models.Article) []models.Article {
 
 // RankByRelevanceScore ranks articles by relevance score (highest first)
 func RankByRelevanceScore(articles []models.Article) []models.Article {
-	// Already sorted by database query
+	// Already sorted by database query, only attach explanations
+	for i := range articles {
+		articles[i].Explanation = &models.ScoreExplanation{
+			Ranker:    RankerRelevance,
+			Relevance: explainValue(articles[i].RelevanceScore),
+			Final:     articles[i].RelevanceScore,
+		}
+	}
+	return articles
+}
+
+// RankByComputedScore ranks articles by recalibrated score (highest first)
+func RankByComputedScore(articles []models.Article) []models.Article {
+	// Already sorted by database query, only attach explanations
+	for i := range articles {
+		articles[i].Explanation = &models.ScoreExplanation{
+			Ranker:    RankerComputed,
+			Relevance: explainValue(articles[i].RelevanceScore),
+			Final:     articles[i].ComputedScore,
+		}
+	}
 	return articles
 }
 
@@ -32,16 +300,66 @@ func RankByDistance(articles []models.Article, lat, lon float64) []models.Articl
 
 	for i, article := range articles {
 		distance := utils.HaversineDistance(lat, lon, article.Latitude, article.Longitude)
+		article.Explanation = &models.ScoreExplanation{
+			Ranker:       RankerDistance,
+			DistanceKm:   explainValue(distance),
+			GeoRelevance: explainValue(GeoRelevance(distance)),
+			Final:        distance,
+		}
 		scored[i] = ArticleWithScore{
 			Article: article,
 			Score:   distance,
 		}
 	}
 
-	// Sort by distance (ascending) using built-in sort
-	sort.Slice(scored, func(i, j int) bool {
-		return scored[i].Score < scored[j].Score
-	})
+	// Sort by distance (ascending)
+	sortScored(scored, false)
+
+	result := make([]models.Article, len(scored))
+	for i, s := range scored {
+		result[i] = s.Article
+	}
+
+	return result
+}
+
+// ScoreLookup returns a per-article score supplied by another service, such as
+// the current trending score
+type ScoreLookup func(articleID string) float64
+
+// RankByDistanceAndTrending ranks articles by geo relevance multiplied by a
+// trending boost between 1 and 2, where the most trending candidate gets 2 and
+// articles that aren't trending keep their distance ranking
+func RankByDistanceAndTrending(articles []models.Article, lat, lon float64, trending ScoreLookup) []models.Article {
+	maxTrending := 0.0
+	for _, article := range articles {
+		maxTrending = math.Max(maxTrending, trending(article.ID))
+	}
+
+	scored := make([]ArticleWithScore, len(articles))
+	for i, article := range articles {
+		distance := utils.HaversineDistance(lat, lon, article.Latitude, article.Longitude)
+		geo := GeoRelevance(distance)
+		article.TrendingScore = trending(article.ID)
+		boost := 1.0
+		if maxTrending > 0 {
+			boost += article.TrendingScore / maxTrending
+		}
+		score := geo * boost
+		article.Explanation = &models.ScoreExplanation{
+			Ranker:       RankerDistanceTrending,
+			DistanceKm:   explainValue(distance),
+			GeoRelevance: explainValue(geo),
+			Trending:     explainValue(article.TrendingScore),
+			Final:        score,
+		}
+		scored[i] = ArticleWithScore{
+			Article: article,
+			Score:   score,
+		}
+	}
+
+	sortScored(scored, true)
 
 	result := make([]models.Article, len(scored))
 	for i, s := range scored {
@@ -52,16 +370,38 @@ func RankByDistance(articles []models.Article, lat, lon float64) []models.Articl
 }
 
 // RankBySearchRelevance ranks articles by how well they match the search query.
-// It calculates a dynamic score based on keyword matches in the title and description.
-func RankBySearchRelevance(articles []models.Article, query string) []models.Article {
+// It calculates a dynamic score based on keyword matches in the title and description,
+// blended with article freshness and boosted by source reliability. The
+// matches are scored from relevance, the BM25 scores of TextRelevances, or
+// by which query terms the fields contain when it is nil.
+func RankBySearchRelevance(articles []models.Article, query string, profile RankingProfile, relevance map[string]TextRelevance) []models.Article {
 	scored := make([]ArticleWithScore, len(articles))
-	queryWords := strings.Fields(strings.ToLower(query))
+	queryTerms := ParseSearchTerms(query).Substrings()
 
-	// Filter out stop words from the query to focus on meaningful terms
-	queryWords = filterStopWords(queryWords)
+	boosts := profile.searchBoosts()
+	profile = profile.withRecencyBoost(boosts.Recent)
+	weights := profile.weights("text_match")
+	weights["title"] = boosts.Title
+	weights["description"] = boosts.Description
 
 	for i, article := range articles {
-		score := calculateTextMatchScore(article, queryWords)
+		textMatch := calculateTextMatchScore(article, queryTerms, boosts)
+		if relevance != nil {
+			textMatch = relevance[article.ID].score(boosts)
+		}
+		recency := profile.recency(article.PublicationDate)
+		multiplier, reliability := profile.reliabilityMultiplier(article)
+		readMultiplier, readMinutes := profile.shortReadMultiplier(article)
+		score := profile.blend(textMatch, recency) * multiplier * readMultiplier
+		article.Explanation = &models.ScoreExplanation{
+			Ranker:      RankerSearch,
+			TextMatch:   explainValue(textMatch),
+			Recency:     explainValue(recency),
+			Reliability: explainValue(reliability),
+			ReadMinutes: readMinutes,
+			Weights:     weights,
+			Final:       score,
+		}
 		scored[i] = ArticleWithScore{
 			Article: article,
 			Score:   score,
@@ -69,9 +409,7 @@ func RankBySearchRelevance(articles []models.Article, query string) []models.Art
 	}
 
 	// Sort by the dynamically calculated score (descending)
-	sort.Slice(scored, func(i, j int) bool {
-		return scored[i].Score > scored[j].Score
-	})
+	sortScored(scored, true)
 
 	result := make([]models.Article, len(scored))
 	for i, s := range scored {
@@ -81,10 +419,28 @@ func RankBySearchRelevance(articles []models.Article, query string) []models.Art
 	return result
 }
 
-// calculateTextMatchScore computes a text match score based on query terms.
-// Matches in the title are weighted more heavily than matches in the description.
-func calculateTextMatchScore(article models.Article, queryWords []string) float64 {
-	if len(queryWords) == 0 {
+// GeoRelevance converts a distance in km into a 0-1 proximity factor
+func GeoRelevance(distanceKm float64) float64 {
+	return math.Exp(-tuning.Current().Ranking.GeoDecayPerKm * distanceKm)
+}
+
+// StripExplanations removes score explanations from articles
+func StripExplanations(articles []models.Article) {
+	for i := range articles {
+		articles[i].Explanation = nil
+	}
+}
+
+// explainValue returns a pointer for an explanation component
+func explainValue(v float64) *float64 {
+	return &v
+}
+
+// calculateTextMatchScore computes a 0-1 text match score based on query terms,
+// weighting matches in the title and description by the given boosts.
+func calculateTextMatchScore(article models.Article, queryTerms []string, boosts SearchBoosts) float64 {
+	maxScore := boosts.Title + boosts.Description
+	if len(queryTerms) == 0 || maxScore <= 0 {
 		return 0
 	}
 
@@ -92,48 +448,16 @@ func calculateTextMatchScore(article models.Article, queryWords []string) float6
 	descLower := strings.ToLower(article.Description)
 
 	var score float64
-	titleWeight := 3.0 // Title matches are 3x more important
-	descWeight := 1.0
-
-	for _, word := range queryWords {
-		if strings.Contains(titleLower, word) {
-			score += titleWeight
+	for _, term := range queryTerms {
+		if strings.Contains(titleLower, term) {
+			score += boosts.Title
 		}
-		if strings.Contains(descLower, word) {
-			score += descWeight
+		if strings.Contains(descLower, term) {
+			score += boosts.Description
 		}
 	}
 
-	// Normalize the score by the number of query words to avoid favoring longer queries
-	return score / float64(len(queryWords))
-}
-
-var stopWords = map[string]struct{}{
-	"a": {}, "about": {}, "above": {}, "after": {}, "again": {}, "against": {}, "all": {}, "am": {}, "an": {}, "and": {}, "any": {}, "are": {}, "as": {}, "at": {},
-	"be": {}, "because": {}, "been": {}, "before": {}, "being": {}, "below": {}, "between": {}, "both": {}, "but": {}, "by": {},
-	"can": {}, "did": {}, "do": {}, "does": {}, "doing": {}, "don": {}, "down": {}, "during": {},
-	"each": {},
-	"few":  {}, "for": {}, "from": {}, "further": {},
-	"had": {}, "has": {}, "have": {}, "having": {}, "he": {}, "her": {}, "here": {}, "hers": {}, "herself": {}, "him": {}, "himself": {}, "his": {}, "how": {},
-	"i": {}, "if": {}, "in": {}, "into": {}, "is": {}, "it": {}, "its": {}, "itself": {},
-	"just": {},
-	"me":   {}, "more": {}, "most": {}, "my": {}, "myself": {},
-	"no": {}, "nor": {}, "not": {},
-	"of": {}, "off": {}, "on": {}, "once": {}, "only": {}, "or": {}, "other": {}, "our": {}, "ours": {}, "ourselves": {}, "out": {}, "over": {}, "own": {},
-	"s": {}, "same": {}, "she": {}, "should": {}, "so": {}, "some": {}, "such": {},
-	"t": {}, "than": {}, "that": {}, "the": {}, "their": {}, "theirs": {}, "them": {}, "themselves": {}, "then": {}, "there": {}, "these": {}, "they": {}, "this": {}, "those": {}, "through": {}, "to": {}, "too": {},
-	"under": {}, "until": {}, "up": {},
-	"very": {},
-	"was":  {}, "we": {}, "were": {}, "what": {}, "when": {}, "where": {}, "which": {}, "while": {}, "who": {}, "whom": {}, "why": {}, "will": {}, "with": {}, "would": {},
-	"you": {}, "your": {}, "yours": {}, "yourself": {}, "yourselves": {},
-}
-
-func filterStopWords(words []string) []string {
-	filtered := make([]string, 0, len(words))
-	for _, word := range words {
-		if _, found := stopWords[word]; !found {
-			filtered = append(filtered, word)
-		}
-	}
-	return filtered
+	// Normalize the score by the number of query terms to avoid favoring longer
+	// queries, and by the largest per-term score to keep it in 0-1
+	return score / float64(len(queryTerms)) / maxScore
 }
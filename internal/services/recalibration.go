@@ -0,0 +1,176 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/mahigadamsetty/Inshorts-task/internal/models"
+	"github.com/mahigadamsetty/Inshorts-task/internal/tuning"
+	"gorm.io/gorm"
+)
+
+// Smoothing strength for CTR and source reliability: how many pseudo-observations
+// of the global average are mixed into every estimate
+const (
+	ctrPriorViews     = 20.0
+	sourcePriorCounts = 5.0
+)
+
+// engagementWindow limits which events feed the engagement signal
+const engagementWindow = 7 * 24 * time.Hour
+
+// articleEngagement holds view and click counts for an article. With the
+// click model, views are its expected examinations.
+type articleEngagement struct {
+	ArticleID string
+	Views     float64
+	Clicks    float64
+}
+
+// RecalibrateScores recomputes every article's computed_score from its original
+// relevance score, click-through rate, decayed unique-viewer reach, source
+// reliability and recency. Once the click model has run, the click-through rate
+// is taken over impressions debiased for position rather than over views. The
+// original relevance_score is left untouched. Returns the number of articles updated.
+func (s *Services) RecalibrateScores(ctx context.Context, profile RankingProfile) (int, error) {
+	database := s.db.WithContext(ctx)
+	if database == nil {
+		return 0, fmt.Errorf("database not initialized")
+	}
+
+	// 1. Engagement per article from the click model, or from recent events
+	// before it has run
+	engagement, err := debiasedEngagement(database)
+	if err != nil {
+		return 0, err
+	}
+	if len(engagement) == 0 {
+		err = database.Model(&models.Event{}).
+			Select("article_id, "+
+				"SUM(CASE WHEN event_type = ? THEN 1 ELSE 0 END) AS views, "+
+				"SUM(CASE WHEN event_type = ? THEN 1 ELSE 0 END) AS clicks",
+				models.EventTypeView, models.EventTypeClick).
+			Scopes(s.trafficScope(ctx)).
+			Where("timestamp > ?", time.Now().Add(-engagementWindow)).
+			Group("article_id").
+			Scan(&engagement).Error
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	var totalViews, totalClicks float64
+	engagementByID := make(map[string]articleEngagement, len(engagement))
+	for _, e := range engagement {
+		engagementByID[e.ArticleID] = e
+		totalViews += e.Views
+		totalClicks += e.Clicks
+	}
+	globalCTR := 0.0
+	if totalViews > 0 {
+		globalCTR = totalClicks / totalViews
+	}
+
+	// 2. Reach from unique viewers, decayed so past spikes fade
+	ranking := tuning.Current().Ranking
+	popularity, _, err := popularityScores(database, time.Now(), ranking.PopularityHalfLifeHours)
+	if err != nil {
+		return 0, err
+	}
+	var maxPopularity float64
+	for _, score := range popularity {
+		maxPopularity = math.Max(maxPopularity, score)
+	}
+
+	// 3. Load articles and derive source reliability, preferring curated metadata
+	var articles []models.Article
+	if err := database.Select("id, source_name, relevance_score, publication_date").Find(&articles).Error; err != nil {
+		return 0, err
+	}
+	reliability := sourceReliability(articles)
+	names := make([]string, 0, len(reliability))
+	for name := range reliability {
+		names = append(names, name)
+	}
+	metadata, err := s.SourceMetadata(ctx, names)
+	if err != nil {
+		return 0, err
+	}
+	for name := range reliability {
+		if source, ok := metadata[models.SourceKey(name)]; ok {
+			reliability[name] = source.Reliability
+		}
+	}
+
+	// 4. Compute and store the recalibrated score with the current tuning weights
+	now := time.Now()
+	err = database.Transaction(func(tx *gorm.DB) error {
+		for _, article := range articles {
+			e := engagementByID[article.ID]
+			score := ranking.Recalibration.Original*article.RelevanceScore +
+				ranking.Recalibration.Engagement*engagementScore(e, globalCTR) +
+				ranking.Recalibration.Reach*reachScore(popularity[article.ID], maxPopularity) +
+				ranking.Recalibration.Reliability*reliability[article.SourceName] +
+				ranking.Recalibration.Recency*FreshnessScore(article.PublicationDate, profile.FreshnessHalfLifeHours)
+
+			err := tx.Model(&models.Article{}).Where("id = ?", article.ID).Updates(map[string]interface{}{
+				"computed_score":    score,
+				"score_computed_at": now,
+			}).Error
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return len(articles), nil
+}
+
+// engagementScore maps an article's smoothed CTR to 0-1, where 0.5 is the global average
+func engagementScore(e articleEngagement, globalCTR float64) float64 {
+	if globalCTR <= 0 {
+		return 0.5
+	}
+	ctr := (e.Clicks + ctrPriorViews*globalCTR) / (e.Views + ctrPriorViews)
+	return ctr / (ctr + globalCTR)
+}
+
+// reachScore maps decayed unique viewers to 0-1 on a log scale relative to
+// the most popular article, or 0.5 for every article while nothing has been
+// counted
+func reachScore(popularity, maxPopularity float64) float64 {
+	if maxPopularity <= 0 {
+		return 0.5
+	}
+	return math.Log1p(popularity) / math.Log1p(maxPopularity)
+}
+
+// sourceReliability estimates each source's reliability as the smoothed mean of
+// its articles' original relevance scores
+func sourceReliability(articles []models.Article) map[string]float64 {
+	sums := make(map[string]float64)
+	counts := make(map[string]float64)
+	var total float64
+	for _, article := range articles {
+		sums[article.SourceName] += article.RelevanceScore
+		counts[article.SourceName]++
+		total += article.RelevanceScore
+	}
+
+	globalMean := 0.0
+	if len(articles) > 0 {
+		globalMean = total / float64(len(articles))
+	}
+
+	reliability := make(map[string]float64, len(sums))
+	for source, sum := range sums {
+		reliability[source] = (sum + sourcePriorCounts*globalMean) / (counts[source] + sourcePriorCounts)
+	}
+	return reliability
+}
@@ -0,0 +1,27 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/mahigadamsetty/Inshorts-task/internal/models"
+)
+
+// RecordRequest stores a sampled request and its response for replaying
+func (s *Services) RecordRequest(ctx context.Context, recording *models.RecordedRequest) {
+	if err := s.db.WithContext(ctx).Create(recording).Error; err != nil {
+		log.Printf("Failed to record request to %s: %v", recording.Path, err)
+	}
+}
+
+// PurgeRecordings deletes recorded requests older than retention
+func (s *Services) PurgeRecordings(ctx context.Context, retention time.Duration) (int64, error) {
+	database := s.db.WithContext(ctx)
+	if database == nil {
+		return 0, fmt.Errorf("database not initialized")
+	}
+	result := database.Where("created_at < ?", time.Now().Add(-retention)).Delete(&models.RecordedRequest{})
+	return result.RowsAffected, result.Error
+}
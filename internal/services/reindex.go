@@ -0,0 +1,240 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mahigadamsetty/Inshorts-task/internal/apperr"
+	"github.com/mahigadamsetty/Inshorts-task/internal/db"
+	"github.com/mahigadamsetty/Inshorts-task/internal/llm"
+	"github.com/mahigadamsetty/Inshorts-task/internal/models"
+	"gorm.io/gorm"
+)
+
+// embeddingShadowTable holds the embedding index while it is rebuilt
+const embeddingShadowTable = "article_embeddings_rebuild"
+
+// ErrReindexRunning is returned when a reindex is requested while one is in progress
+var ErrReindexRunning = apperr.Conflictf("a reindex is already running")
+
+// Reindexer rebuilds the article embedding and full-text indexes in the
+// background. Each new index is written to shadow tables while readers keep
+// using the live one, and swapped in with a single transaction once every
+// article is indexed. The search backend's index, when there is one, is kept
+// up to date by IndexArticles instead.
+type Reindexer struct {
+	svc    *Services
+	client *llm.Client
+
+	mu      sync.Mutex
+	running *models.ReindexJob
+}
+
+// NewReindexer creates a reindexer embedding the articles of svc with the
+// given client
+func NewReindexer(svc *Services, client *llm.Client) *Reindexer {
+	return &Reindexer{svc: svc, client: client}
+}
+
+// Start marks reindexes left running by a previous process as interrupted and
+// drops their shadow table
+func (r *Reindexer) Start(ctx context.Context) {
+	database := r.svc.db.WithContext(ctx)
+	err := database.Model(&models.ReindexJob{}).
+		Where("status = ?", models.ReindexJobRunning).
+		Update("status", models.ReindexJobInterrupted).Error
+	if err != nil {
+		log.Printf("Failed to mark interrupted reindex jobs: %v", err)
+	}
+	if err := database.Migrator().DropTable(embeddingShadowTable); err != nil {
+		log.Printf("Failed to drop reindex shadow table: %v", err)
+	}
+}
+
+// Submit records a reindex job and starts it in the background
+func (r *Reindexer) Submit(ctx context.Context) (*models.ReindexJob, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.running != nil {
+		return nil, ErrReindexRunning
+	}
+
+	database := r.svc.db.WithContext(ctx)
+	var total int64
+	if err := database.Model(&models.Article{}).Count(&total).Error; err != nil {
+		return nil, err
+	}
+
+	job := &models.ReindexJob{
+		Status: models.ReindexJobRunning,
+		Model:  r.client.EmbeddingModel(),
+		Total:  int(total),
+	}
+	if err := database.Create(job).Error; err != nil {
+		return nil, err
+	}
+	r.running = job
+	snapshot := *job
+
+	go r.run(job)
+	return &snapshot, nil
+}
+
+// GetReindexJob returns a reindex job by ID
+func (s *Services) GetReindexJob(ctx context.Context, id uint) (*models.ReindexJob, error) {
+	var job models.ReindexJob
+	if err := s.db.WithContext(ctx).First(&job, id).Error; err != nil {
+		return nil, apperr.NotFound(err, "Job")
+	}
+	return &job, nil
+}
+
+// run rebuilds the indexes and records the outcome on the job
+func (r *Reindexer) run(job *models.ReindexJob) {
+	ctx := context.Background()
+	err := r.rebuild(ctx, job)
+	if err == nil {
+		err = db.RebuildFullText(r.svc.db.WithContext(ctx))
+	}
+
+	now := time.Now()
+	updates := map[string]interface{}{"status": models.ReindexJobCompleted, "finished_at": &now}
+	if err != nil {
+		log.Printf("Reindex %d failed: %v", job.ID, err)
+		updates["status"] = models.ReindexJobFailed
+		updates["error"] = err.Error()
+	}
+	if err := r.svc.db.WithContext(ctx).Model(&models.ReindexJob{}).Where("id = ?", job.ID).Updates(updates).Error; err != nil {
+		log.Printf("Failed to record reindex %d outcome: %v", job.ID, err)
+	}
+
+	r.mu.Lock()
+	r.running = nil
+	r.mu.Unlock()
+}
+
+// rebuild embeds every article into the shadow table, recording progress after
+// each batch, then swaps it in for the live index. Articles added or changed
+// during the rebuild are caught up by the next EmbedArticles run.
+func (r *Reindexer) rebuild(ctx context.Context, job *models.ReindexJob) error {
+	database := r.svc.db.WithContext(ctx)
+	live := models.ArticleEmbedding{}.TableName()
+
+	tableSQL, indexSQL, err := tableSchema(database, live)
+	if err != nil {
+		return err
+	}
+	// SQLite keeps the table's original quoting, which changes after a rename
+	shadowSQL := tableSQL
+	for _, quoted := range []string{"`" + live + "`", `"` + live + `"`, live} {
+		if strings.Contains(tableSQL, quoted) {
+			shadowSQL = strings.Replace(tableSQL, quoted, `"`+embeddingShadowTable+`"`, 1)
+			break
+		}
+	}
+	if shadowSQL == tableSQL {
+		return fmt.Errorf("unexpected schema for %s", live)
+	}
+	if err := database.Migrator().DropTable(embeddingShadowTable); err != nil {
+		return err
+	}
+	if err := database.Exec(shadowSQL).Error; err != nil {
+		return err
+	}
+
+	swapped := false
+	defer func() {
+		if !swapped {
+			database.Migrator().DropTable(embeddingShadowTable)
+		}
+	}()
+
+	// Page by ID so articles inserted mid-rebuild don't shift the batches
+	lastID := ""
+	for {
+		var articles []models.Article
+		err := database.
+			Select("id, title, description, text_content, text_blob_url, content_hash").
+			Where("id > ?", lastID).
+			Order("id").
+			Limit(embeddingBatchSize).
+			Find(&articles).Error
+		if err != nil {
+			return err
+		}
+		if len(articles) == 0 {
+			break
+		}
+
+		texts := make([]string, len(articles))
+		for i, article := range articles {
+			texts[i] = r.svc.embeddingText(ctx, article)
+		}
+		vectors, used, err := r.client.WithContext(ctx).Embed(texts)
+		if err != nil {
+			return err
+		}
+		if used != job.Model {
+			return fmt.Errorf("embedding fell back from %s to %s", job.Model, used)
+		}
+
+		rows := make([]models.ArticleEmbedding, len(articles))
+		for i, article := range articles {
+			rows[i] = models.ArticleEmbedding{ArticleID: article.ID, Model: used, Vector: vectors[i], ContentHash: article.ContentHash}
+		}
+		if err := database.Table(embeddingShadowTable).Create(&rows).Error; err != nil {
+			return err
+		}
+
+		lastID = articles[len(articles)-1].ID
+		job.Processed += len(articles)
+		if err := database.Model(&models.ReindexJob{}).Where("id = ?", job.ID).Update("processed", job.Processed).Error; err != nil {
+			return err
+		}
+	}
+
+	// Swap the tables together so readers see either the old index or the new one
+	err = database.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Migrator().DropTable(live); err != nil {
+			return err
+		}
+		if err := tx.Migrator().RenameTable(embeddingShadowTable, live); err != nil {
+			return err
+		}
+		for _, statement := range indexSQL {
+			if err := tx.Exec(statement).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	swapped = true
+	return nil
+}
+
+// tableSchema returns the statements creating a table and its indexes
+func tableSchema(database *gorm.DB, table string) (string, []string, error) {
+	var tableSQL string
+	err := database.Raw("SELECT sql FROM sqlite_master WHERE type = 'table' AND name = ?", table).Scan(&tableSQL).Error
+	if err != nil {
+		return "", nil, err
+	}
+	if tableSQL == "" {
+		return "", nil, fmt.Errorf("table %s not found", table)
+	}
+
+	var indexSQL []string
+	err = database.Raw("SELECT sql FROM sqlite_master WHERE type = 'index' AND tbl_name = ? AND sql IS NOT NULL", table).
+		Scan(&indexSQL).Error
+	if err != nil {
+		return "", nil, err
+	}
+	return tableSQL, indexSQL, nil
+}
@@ -0,0 +1,52 @@
+package services
+
+import "github.com/mahigadamsetty/Inshorts-task/internal/llm"
+
+// dateRangeWideningDays is how far each bound of a date range moves when widened
+const dateRangeWideningDays = 7
+
+// QueryRelaxation loosens one constraint of a /query request that found nothing
+type QueryRelaxation struct {
+	Name  string
+	Apply func(state ConversationState) (ConversationState, bool) // false when the constraint isn't present
+}
+
+// QueryRelaxations are tried in order until a /query request finds articles
+var QueryRelaxations = []QueryRelaxation{
+	{Name: "drop_geo", Apply: dropGeo},
+	{Name: "widen_date_range", Apply: widenDateRange},
+	{Name: "drop_source", Apply: dropSource},
+}
+
+// dropGeo removes the place constraint, turning a nearby query into a search
+func dropGeo(state ConversationState) (ConversationState, bool) {
+	if state.Location == nil && state.Intent != llm.IntentNearby {
+		return state, false
+	}
+	state.Location = nil
+	if state.Intent == llm.IntentNearby {
+		state.Intent = llm.IntentSearch
+	}
+	return state, true
+}
+
+// widenDateRange moves both bounds of the date range outwards
+func widenDateRange(state ConversationState) (ConversationState, bool) {
+	if state.DateRange == nil {
+		return state, false
+	}
+	state.DateRange = state.DateRange.Widen(dateRangeWideningDays)
+	return state, true
+}
+
+// dropSource removes the source constraint, turning a source query into a search
+func dropSource(state ConversationState) (ConversationState, bool) {
+	if state.Source == "" {
+		return state, false
+	}
+	state.Source = ""
+	if state.Intent == llm.IntentSource {
+		state.Intent = llm.IntentSearch
+	}
+	return state, true
+}
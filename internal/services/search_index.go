@@ -0,0 +1,194 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/mahigadamsetty/Inshorts-task/internal/db"
+	"github.com/mahigadamsetty/Inshorts-task/internal/degradation"
+	"github.com/mahigadamsetty/Inshorts-task/internal/models"
+	"github.com/mahigadamsetty/Inshorts-task/internal/search"
+	"github.com/mahigadamsetty/Inshorts-task/internal/tenant"
+)
+
+// searchColumns are the article columns copied to the search backend
+var searchColumns = []string{
+	"id", "tenant_id", "title", "description", "category", "source_name", "publication_date",
+	"latitude", "longitude", "location_source", "access", "content_rating", "state",
+	"visible_from", "expires_at", "updated_at",
+}
+
+// searchPosition is the last article copied to the search backend, in
+// updated_at then ID order. It is read from the index on the first run after
+// a start, so indexing resumes where it stopped.
+type searchPosition struct {
+	sync.Mutex
+	known     bool
+	updatedAt time.Time
+	id        string
+}
+
+// SearchScope narrows a keyword search to the filters of the endpoint serving
+// it, so the backend's candidates are ones the database query keeps
+type SearchScope struct {
+	Filter   ArticleFilter
+	TenantID string // Overrides the context's tenant, for admin searches of one tenant
+	Category string
+	Source   string
+	Near     *search.Point
+	RadiusKm float64
+}
+
+// SearchCandidates returns the IDs of the articles matching any of the terms
+// in the search backend, best matches first. ok is false without a backend or
+// when it failed, and the caller matches in the database instead. Filters the
+// backend can't apply, like source reliability, are left to the database.
+func (s *Services) SearchCandidates(ctx context.Context, terms SearchTerms, scope SearchScope) ([]string, bool) {
+	if s.search == nil {
+		return nil, false
+	}
+	query := search.Query{
+		Words:            terms.Words,
+		Phrases:          terms.Phrases,
+		TenantID:         tenant.IDFromContext(ctx),
+		ExcludePaywalled: scope.Filter.ExcludePaywalled,
+		SafeStrict:       scope.Filter.Safe == SafeStrict,
+		ExcludeExplicit:  scope.Filter.Safe == SafeModerate,
+		PublishedFrom:    scope.Filter.PublishedFrom,
+		PublishedTo:      scope.Filter.PublishedTo,
+		State:            scope.Filter.State,
+		Source:           scope.Source,
+		Near:             scope.Near,
+		RadiusKm:         scope.RadiusKm,
+		Limit:            s.searchMaxCandidates,
+	}
+	if scope.TenantID != "" {
+		query.TenantID = scope.TenantID
+	}
+	if scope.Category != "" {
+		query.Categories = s.CategoryTerms(ctx, scope.Category)
+	}
+	if db.HidesInvisible(ctx) {
+		query.VisibleAt = time.Now()
+	}
+
+	ids, err := s.search.Search(ctx, query)
+	if err != nil {
+		log.Printf("Search backend failed, matching in the database: %v", err)
+		degradation.Record(ctx, degradation.SubsystemSearch, degradation.ModeFallback)
+		return nil, false
+	}
+	return ids, true
+}
+
+// SearchIndexResult counts the work of an indexing pass
+type SearchIndexResult struct {
+	Created bool `json:"created"` // The index was missing and created empty
+	Indexed int  `json:"indexed"`
+}
+
+// IndexArticles copies the articles written since the last pass to the
+// search backend, in bulk batches of batchSize. Every write that bumps
+// updated_at is picked up, so the backend follows ingestion, publisher pushes
+// and enrichment without hooks in each of them. A missing index is created
+// and filled from scratch, which is also how it is rebuilt.
+func (s *Services) IndexArticles(ctx context.Context, batchSize int) (*SearchIndexResult, error) {
+	result := &SearchIndexResult{}
+	searchBackend, position := s.search, &s.searchPosition
+	if searchBackend == nil {
+		return result, nil
+	}
+	database := s.db.WithContext(ctx)
+	if database == nil {
+		return nil, fmt.Errorf("database not initialized")
+	}
+
+	position.Lock()
+	defer position.Unlock()
+
+	created, err := searchBackend.EnsureIndex(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if created {
+		result.Created = true
+		position.known, position.updatedAt, position.id = true, time.Time{}, ""
+	}
+	if !position.known {
+		last, err := searchBackend.LastUpdated(ctx)
+		if err != nil {
+			return nil, err
+		}
+		// The index keeps milliseconds, so the articles of that millisecond are
+		// indexed again
+		position.known, position.updatedAt, position.id = true, last.Local(), ""
+	}
+
+	for {
+		var articles []models.Article
+		query := database.Model(&models.Article{}).Select(searchColumns)
+		if !position.updatedAt.IsZero() {
+			query = query.Where("updated_at > ? OR (updated_at = ? AND id > ?)",
+				position.updatedAt, position.updatedAt, position.id)
+		}
+		err := query.Order("updated_at").Order("id").Limit(batchSize).Find(&articles).Error
+		if err != nil {
+			return result, err
+		}
+		if len(articles) == 0 {
+			return result, nil
+		}
+
+		docs := make([]search.Document, len(articles))
+		for i, article := range articles {
+			docs[i] = searchDocument(article)
+		}
+		if err := searchBackend.Index(ctx, docs); err != nil {
+			return result, err
+		}
+		last := articles[len(articles)-1]
+		position.updatedAt, position.id = last.UpdatedAt, last.ID
+		result.Indexed += len(articles)
+		if len(articles) < batchSize {
+			return result, nil
+		}
+	}
+}
+
+// removeFromSearch deletes articles removed from the database from the search
+// backend. Failures are only logged: the database drops IDs it no longer has
+// from search results anyway.
+func (s *Services) removeFromSearch(ctx context.Context, ids []string) {
+	if s.search == nil || len(ids) == 0 {
+		return
+	}
+	if err := s.search.Delete(ctx, ids); err != nil {
+		log.Printf("Failed to remove %d articles from the search index: %v", len(ids), err)
+	}
+}
+
+// searchDocument is the searchable copy of an article
+func searchDocument(article models.Article) search.Document {
+	doc := search.Document{
+		ID:              article.ID,
+		TenantID:        article.TenantID,
+		Title:           article.Title,
+		Description:     article.Description,
+		Category:        article.Category,
+		SourceName:      article.SourceName,
+		PublicationDate: article.PublicationDate,
+		Access:          article.Access,
+		ContentRating:   article.ContentRating,
+		State:           article.State,
+		VisibleFrom:     article.VisibleFrom,
+		ExpiresAt:       article.ExpiresAt,
+		UpdatedAt:       article.UpdatedAt,
+	}
+	if article.HasLocation() {
+		doc.Location = &search.Point{Latitude: article.Latitude, Longitude: article.Longitude}
+	}
+	return doc
+}
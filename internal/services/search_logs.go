@@ -0,0 +1,153 @@
+package services
+
+import (
+	"context"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mahigadamsetty/Inshorts-task/internal/llm"
+	"github.com/mahigadamsetty/Inshorts-task/internal/models"
+	"github.com/mahigadamsetty/Inshorts-task/internal/platform"
+)
+
+// LogSearch records a free-text query for offline analytics. Failures are
+// logged rather than returned so they never fail the request.
+func (s *Services) LogSearch(ctx context.Context, endpoint, query string, resultCount int) {
+	entry := models.SearchLog{
+		Query:       strings.TrimSpace(query),
+		Endpoint:    endpoint,
+		ResultCount: resultCount,
+		Platform:    platform.FromContext(ctx),
+	}
+	if err := s.db.WithContext(ctx).Create(&entry).Error; err != nil {
+		log.Printf("Failed to log search %q: %v", query, err)
+	}
+}
+
+// CountShare is how often a value occurred and its share of all analyzed searches
+type CountShare struct {
+	Value string  `json:"value"`
+	Count int     `json:"count"`
+	Share float64 `json:"share"`
+}
+
+// QueryAnalytics summarizes the intents and entities of logged searches
+type QueryAnalytics struct {
+	Since           time.Time    `json:"since"`
+	Searches        int          `json:"searches"`         // Logged searches analyzed
+	DistinctQueries int          `json:"distinct_queries"` // Queries sent for extraction, after folding case and whitespace
+	ZeroResults     int          `json:"zero_results"`     // Searches that returned no articles
+	Intents         []CountShare `json:"intents"`
+	Entities        []CountShare `json:"top_entities"`
+	Locations       []CountShare `json:"top_locations"`
+	Platforms       []CountShare `json:"platforms"` // Searches per client platform
+}
+
+// loggedQuery is a distinct query and how often it was searched
+type loggedQuery struct {
+	Query       string
+	Searches    int
+	ZeroResults int
+}
+
+// AnalyzeSearchLogs extracts the intent and entities of every distinct query
+// logged since the given time, in grouped LLM prompts, and returns their
+// distributions weighted by how often each query was searched. At most top
+// entities and locations are returned.
+func (s *Services) AnalyzeSearchLogs(ctx context.Context, client *llm.Client, since time.Time, top int) (*QueryAnalytics, error) {
+	var rows []loggedQuery
+	err := s.db.WithContext(ctx).Model(&models.SearchLog{}).
+		Select("MIN(query) AS query, COUNT(*) AS searches, SUM(CASE WHEN result_count = 0 THEN 1 ELSE 0 END) AS zero_results").
+		Where("created_at >= ? AND query <> ''", since).
+		Group("LOWER(TRIM(query))").
+		Order("searches DESC").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	analytics := &QueryAnalytics{Since: since, DistinctQueries: len(rows)}
+	queries := make([]string, len(rows))
+	for i, row := range rows {
+		queries[i] = row.Query
+		analytics.Searches += row.Searches
+		analytics.ZeroResults += row.ZeroResults
+	}
+
+	intents := map[string]int{}
+	entities := map[string]int{}
+	locations := map[string]int{}
+	for i, result := range client.ExtractIntentsBatch(queries) {
+		weight := rows[i].Searches
+		intents[result.Intent] += weight
+		for _, entity := range distinctFolded(result.Entities) {
+			entities[entity] += weight
+		}
+		for _, location := range distinctFolded(result.Locations) {
+			locations[location] += weight
+		}
+	}
+
+	analytics.Intents = countShares(intents, analytics.Searches, 0)
+	analytics.Entities = countShares(entities, analytics.Searches, top)
+	analytics.Locations = countShares(locations, analytics.Searches, top)
+
+	var platforms []struct {
+		Platform string
+		Searches int
+	}
+	err = s.db.WithContext(ctx).Model(&models.SearchLog{}).
+		Select("platform, COUNT(*) AS searches").
+		Where("created_at >= ? AND query <> ''", since).
+		Group("platform").
+		Scan(&platforms).Error
+	if err != nil {
+		return nil, err
+	}
+	byPlatform := make(map[string]int, len(platforms))
+	for _, row := range platforms {
+		byPlatform[row.Platform] = row.Searches
+	}
+	analytics.Platforms = countShares(byPlatform, analytics.Searches, 0)
+	return analytics, nil
+}
+
+// distinctFolded lowercases values and drops blanks and duplicates, so one
+// query counts each entity once
+func distinctFolded(values []string) []string {
+	seen := make(map[string]bool, len(values))
+	folded := make([]string, 0, len(values))
+	for _, value := range values {
+		value = strings.ToLower(strings.TrimSpace(value))
+		if value != "" && !seen[value] {
+			seen[value] = true
+			folded = append(folded, value)
+		}
+	}
+	return folded
+}
+
+// countShares orders counts from most to least frequent, keeping at most limit
+// entries when limit is positive
+func countShares(counts map[string]int, total, limit int) []CountShare {
+	shares := make([]CountShare, 0, len(counts))
+	for value, count := range counts {
+		share := CountShare{Value: value, Count: count}
+		if total > 0 {
+			share.Share = float64(count) / float64(total)
+		}
+		shares = append(shares, share)
+	}
+	sort.Slice(shares, func(i, j int) bool {
+		if shares[i].Count != shares[j].Count {
+			return shares[i].Count > shares[j].Count
+		}
+		return shares[i].Value < shares[j].Value
+	})
+	if limit > 0 && len(shares) > limit {
+		shares = shares[:limit]
+	}
+	return shares
+}
@@ -0,0 +1,124 @@
+package services
+
+import (
+	"net"
+	"time"
+
+	"github.com/mahigadamsetty/Inshorts-task/internal/search"
+	"github.com/mahigadamsetty/Inshorts-task/internal/tenant"
+	"github.com/mahigadamsetty/Inshorts-task/internal/utils"
+	"gorm.io/gorm"
+)
+
+// Services are the news features over one database. Each instance has its own
+// caches, crawler, settings and write buffers, so components built with
+// different dependencies can run side by side.
+type Services struct {
+	db            *gorm.DB
+	trending      *TrendingCache
+	conversations *ConversationCache
+	heatmaps      *HeatmapCache
+	crawler       *Crawler
+	tenants       *tenant.Registry
+	discovery     DiscoveryOptions
+
+	privacy             LocationPrivacy
+	responseCoordinates utils.CoordinateLimit
+	maxCandidates       int
+	privateWebhooks     bool
+	resolver            *net.Resolver
+	simulation          *simulationGate
+	ingestion           IngestionOptions
+	blobs               BlobStores
+	audio               AudioOptions
+
+	search              search.Backend // Nil when keyword search matches in the database
+	searchMaxCandidates int
+	searchPosition      searchPosition
+
+	// Buffered writes, started by the Init methods
+	views       *ViewCounter
+	events      *EventQueue
+	impressions *ImpressionRecorder
+	usage       *UsageMeter
+}
+
+// Options are the dependencies and settings of the services besides their
+// database. The trending cache is required by trending lists and scores;
+// without the other caches, sessions and heatmaps aren't cached. Without a
+// crawler, pages are fetched with the default crawl settings. Settings left
+// zero keep coordinates as given, leave simulated events out, match keywords
+// in the database and keep article text in the articles table.
+type Options struct {
+	Trending      *TrendingCache
+	Conversations *ConversationCache
+	Heatmaps      *HeatmapCache
+	Crawler       *Crawler
+	Tenants       *tenant.Registry // Tenants of webhook payloads and ingestion sources; unchecked when nil
+
+	LocationPrivacy     *LocationPrivacy       // How much of user coordinates is kept; all of it when nil
+	ResponseCoordinates *utils.CoordinateLimit // How precisely coordinates are given out to tenants without their own precision; as stored when nil
+	MaxCandidates       int                    // Most articles one request may load to rank, 0 for no limit
+	PrivateWebhooks     bool                   // Let webhooks target loopback, private and link-local addresses
+	Resolver            *net.Resolver          // Looks up webhook hosts; net.DefaultResolver when nil
+	Simulation          SimulationOptions
+	Ingestion           IngestionOptions
+	Blobs               BlobStores
+	Audio               AudioOptions
+
+	Search              search.Backend // Matches keyword searches instead of the database when set
+	SearchMaxCandidates int            // Most candidates the search backend returns per search
+}
+
+// New creates the services over database. Events, view counts, impressions
+// and usage are written right away until their Init methods start buffering.
+func New(database *gorm.DB, options Options) *Services {
+	crawler := options.Crawler
+	if crawler == nil {
+		crawler = NewCrawler(database, CrawlDefaults{UserAgent: DefaultCrawlUserAgent, Delay: time.Second, MaxConcurrent: 2})
+	}
+	privacy := LocationPrivacy{Precision: -1, CoarsePrecision: -1}
+	if options.LocationPrivacy != nil {
+		privacy = *options.LocationPrivacy
+	}
+	coordinates := utils.CoordinateLimit{Decimals: -1}
+	if options.ResponseCoordinates != nil {
+		coordinates = *options.ResponseCoordinates
+	}
+	ingestion := options.Ingestion
+	if ingestion.NewsAPIBaseURL == "" {
+		ingestion.NewsAPIBaseURL = DefaultNewsAPIURL
+	}
+	if ingestion.Dates == nil {
+		ingestion.Dates = NewDateFormats()
+	}
+	resolver := options.Resolver
+	if resolver == nil {
+		resolver = net.DefaultResolver
+	}
+	blobs := options.Blobs
+	blobs.StoreText = blobs.StoreText && blobs.Text != nil
+	blobs.StoreImages = blobs.StoreImages && blobs.Images != nil
+
+	return &Services{
+		db:                  database,
+		trending:            options.Trending,
+		conversations:       options.Conversations,
+		heatmaps:            options.Heatmaps,
+		crawler:             crawler,
+		tenants:             options.Tenants,
+		discovery:           DiscoveryOptions{Policy: DiscoveryThompson, Epsilon: 0.1, PoolSize: 200},
+		privacy:             privacy,
+		responseCoordinates: coordinates,
+		maxCandidates:       options.MaxCandidates,
+		privateWebhooks:     options.PrivateWebhooks,
+		resolver:            resolver,
+		simulation:          &simulationGate{enabled: options.Simulation.Enabled, includeTrending: options.Simulation.IncludeInTrending},
+		ingestion:           ingestion,
+		blobs:               blobs,
+		audio:               options.Audio,
+		search:              options.Search,
+		searchMaxCandidates: options.SearchMaxCandidates,
+		usage:               &UsageMeter{stored: make(map[usageKey]UsageCounts), pending: make(map[usageKey]*UsageCounts)},
+	}
+}
@@ -0,0 +1,268 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/mahigadamsetty/Inshorts-task/internal/models"
+	"github.com/mahigadamsetty/Inshorts-task/internal/tenant"
+	"gorm.io/gorm"
+)
+
+// SessionInactivity is the longest gap between a client's events and reads
+// within one session
+const SessionInactivity = 30 * time.Minute
+
+// sessionStitchBatch is how many events and reads are stitched at a time
+const sessionStitchBatch = 5000
+
+// SessionViewer returns the stored form of an anonymous client ID for the
+// tenant carried by ctx, or "" when the client sent none
+func SessionViewer(ctx context.Context, anonymousID string) string {
+	if anonymousID == "" {
+		return ""
+	}
+	return viewerHash(tenant.IDFromContext(ctx), anonymousID)
+}
+
+// RecordRead records a news request of a client that sent an anonymous ID,
+// for session stitching. Failures are logged rather than returned so they
+// never fail the request.
+func (s *Services) RecordRead(ctx context.Context, endpoint, anonymousID string) {
+	read := models.Read{Viewer: SessionViewer(ctx, anonymousID), Endpoint: endpoint}
+	if err := s.db.WithContext(ctx).Create(&read).Error; err != nil {
+		log.Printf("Failed to record read of %s: %v", endpoint, err)
+	}
+}
+
+// activity is an event or read waiting to be stitched into a session
+type activity struct {
+	tenantID string
+	viewer   string
+	at       time.Time
+	eventID  uint // Set for events
+	readID   uint // Set for reads
+}
+
+// StitchSessions groups the events and reads of anonymous clients that are
+// not in a session yet into sessions, extending a client's latest session
+// when the activity falls within SessionInactivity of it. Returns how many
+// sessions were created or extended.
+func (s *Services) StitchSessions(ctx context.Context) (int, error) {
+	database := s.db.WithContext(ctx)
+	if database == nil {
+		return 0, fmt.Errorf("database not initialized")
+	}
+
+	stitched := 0
+	for {
+		var events []models.Event
+		err := database.Select("id, viewer, timestamp, tenant_id").
+			Scopes(s.trafficScope(ctx)).
+			Where("session_id IS NULL AND viewer <> ''").
+			Order("timestamp").
+			Limit(sessionStitchBatch).
+			Find(&events).Error
+		if err != nil {
+			return stitched, err
+		}
+		var reads []models.Read
+		err = database.Select("id, viewer, created_at, tenant_id").
+			Where("session_id IS NULL AND viewer <> ''").
+			Order("created_at").
+			Limit(sessionStitchBatch).
+			Find(&reads).Error
+		if err != nil {
+			return stitched, err
+		}
+		if len(events) == 0 && len(reads) == 0 {
+			return stitched, nil
+		}
+
+		activities := make([]activity, 0, len(events)+len(reads))
+		for _, event := range events {
+			activities = append(activities, activity{tenantID: event.TenantID, viewer: event.Viewer, at: event.Timestamp, eventID: event.ID})
+		}
+		for _, read := range reads {
+			activities = append(activities, activity{tenantID: read.TenantID, viewer: read.Viewer, at: read.CreatedAt, readID: read.ID})
+		}
+		sort.Slice(activities, func(i, j int) bool {
+			a, b := activities[i], activities[j]
+			if a.tenantID != b.tenantID {
+				return a.tenantID < b.tenantID
+			}
+			if a.viewer != b.viewer {
+				return a.viewer < b.viewer
+			}
+			return a.at.Before(b.at)
+		})
+
+		touched, err := stitchActivities(database, activities)
+		if err != nil {
+			return stitched, err
+		}
+		stitched += touched
+	}
+}
+
+// stitchActivities assigns activities, sorted by client and time, to sessions
+// and recounts the sessions they went to
+func stitchActivities(database *gorm.DB, activities []activity) (int, error) {
+	touched := map[uint]bool{}
+	err := database.Transaction(func(tx *gorm.DB) error {
+		var session *models.Session
+		var eventIDs, readIDs []uint
+
+		// flush saves the current session and moves its new activity into it
+		flush := func() error {
+			if session == nil {
+				return nil
+			}
+			if err := tx.Save(session).Error; err != nil {
+				return err
+			}
+			if len(eventIDs) > 0 {
+				if err := tx.Model(&models.Event{}).Where("id IN ?", eventIDs).Update("session_id", session.ID).Error; err != nil {
+					return err
+				}
+			}
+			if len(readIDs) > 0 {
+				if err := tx.Model(&models.Read{}).Where("id IN ?", readIDs).Update("session_id", session.ID).Error; err != nil {
+					return err
+				}
+			}
+			touched[session.ID] = true
+			eventIDs, readIDs = nil, nil
+			return nil
+		}
+
+		for i, a := range activities {
+			if i == 0 || a.tenantID != activities[i-1].tenantID || a.viewer != activities[i-1].viewer {
+				if err := flush(); err != nil {
+					return err
+				}
+				latest, err := latestSession(tx, a.tenantID, a.viewer)
+				if err != nil {
+					return err
+				}
+				session = latest
+			}
+			if session == nil ||
+				a.at.After(session.LastActivityAt.Add(SessionInactivity)) ||
+				a.at.Before(session.StartedAt.Add(-SessionInactivity)) {
+				if err := flush(); err != nil {
+					return err
+				}
+				session = &models.Session{Viewer: a.viewer, TenantID: a.tenantID, StartedAt: a.at, LastActivityAt: a.at}
+			}
+			if a.at.Before(session.StartedAt) {
+				session.StartedAt = a.at
+			}
+			if a.at.After(session.LastActivityAt) {
+				session.LastActivityAt = a.at
+			}
+			if a.eventID != 0 {
+				eventIDs = append(eventIDs, a.eventID)
+			} else {
+				readIDs = append(readIDs, a.readID)
+			}
+		}
+		if err := flush(); err != nil {
+			return err
+		}
+
+		for id := range touched {
+			if err := recountSession(tx, id); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return len(touched), err
+}
+
+// latestSession returns a client's most recent session, or nil
+func latestSession(tx *gorm.DB, tenantID, viewer string) (*models.Session, error) {
+	var session models.Session
+	err := tx.Where("tenant_id = ? AND viewer = ?", tenantID, viewer).Order("last_activity_at DESC").First(&session).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// recountSession updates a session's counters from the events and reads in it
+func recountSession(tx *gorm.DB, id uint) error {
+	var counts struct {
+		Events   int
+		Views    int
+		Clicks   int
+		Articles int
+	}
+	err := tx.Model(&models.Event{}).
+		Select("COUNT(*) AS events, "+
+			"COALESCE(SUM(CASE WHEN event_type = ? THEN 1 ELSE 0 END), 0) AS views, "+
+			"COALESCE(SUM(CASE WHEN event_type = ? THEN 1 ELSE 0 END), 0) AS clicks, "+
+			"COUNT(DISTINCT article_id) AS articles",
+			models.EventTypeView, models.EventTypeClick).
+		Where("session_id = ?", id).
+		Scan(&counts).Error
+	if err != nil {
+		return err
+	}
+	var reads int64
+	if err := tx.Model(&models.Read{}).Where("session_id = ?", id).Count(&reads).Error; err != nil {
+		return err
+	}
+	return tx.Model(&models.Session{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"events":   counts.Events,
+		"views":    counts.Views,
+		"clicks":   counts.Clicks,
+		"articles": counts.Articles,
+		"reads":    reads,
+	}).Error
+}
+
+// SessionMetrics summarizes the sessions started in a window
+type SessionMetrics struct {
+	Since               time.Time `json:"since"`
+	Sessions            int64     `json:"sessions"`
+	Viewers             int64     `json:"viewers"` // Distinct anonymous clients
+	ArticlesPerSession  float64   `json:"articles_per_session"`
+	EventsPerSession    float64   `json:"events_per_session"`
+	ReadsPerSession     float64   `json:"reads_per_session"`
+	BounceRate          float64   `json:"bounce_rate"` // Share of sessions with a single event or read
+	MeanDurationSeconds float64   `json:"mean_duration_seconds"`
+}
+
+// GetSessionMetrics summarizes the sessions started since a time, scoped to
+// the tenant carried by ctx. Sessions still open count as they stand.
+func (s *Services) GetSessionMetrics(ctx context.Context, since time.Time) (*SessionMetrics, error) {
+	database := s.db.WithContext(ctx)
+	if database == nil {
+		return nil, fmt.Errorf("database not initialized")
+	}
+
+	metrics := &SessionMetrics{Since: since}
+	err := database.Model(&models.Session{}).
+		Select("COUNT(*) AS sessions, COUNT(DISTINCT viewer) AS viewers, "+
+			"COALESCE(AVG(articles), 0) AS articles_per_session, "+
+			"COALESCE(AVG(events), 0) AS events_per_session, "+
+			"COALESCE(AVG(reads), 0) AS reads_per_session, "+
+			"COALESCE(AVG(CASE WHEN events + reads <= 1 THEN 1.0 ELSE 0 END), 0) AS bounce_rate, "+
+			"COALESCE(AVG((julianday(last_activity_at) - julianday(started_at)) * 86400), 0) AS mean_duration_seconds").
+		Where("started_at >= ?", since).
+		Scan(metrics).Error
+	if err != nil {
+		return nil, err
+	}
+	metrics.Since = since
+	return metrics, nil
+}
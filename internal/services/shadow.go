@@ -0,0 +1,247 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mahigadamsetty/Inshorts-task/internal/apperr"
+	"github.com/mahigadamsetty/Inshorts-task/internal/hll"
+	"github.com/mahigadamsetty/Inshorts-task/internal/models"
+	"github.com/mahigadamsetty/Inshorts-task/internal/platform"
+	"github.com/mahigadamsetty/Inshorts-task/internal/tenant"
+	"github.com/mahigadamsetty/Inshorts-task/internal/tuning"
+	"gorm.io/gorm"
+)
+
+// shadowClickWindow is how long after a sampled request a click on one of its
+// articles is attributed to it
+const shadowClickWindow = 30 * time.Minute
+
+// ShadowRanking is the candidate ranking a sampled request is also ranked with
+type ShadowRanking struct {
+	Name    string
+	Profile RankingProfile
+	Boosts  SearchBoosts // The candidate's default search boosts
+}
+
+// SampleShadowRanking returns the shadow candidate of the tuning settings for
+// the share of requests set by its sample rate, out of those from the
+// platforms it targets
+func SampleShadowRanking(ctx context.Context) (ShadowRanking, bool) {
+	shadow := tuning.Current().Shadow
+	if shadow == nil || shadow.SampleRate <= 0 || !shadow.Targets(platform.FromContext(ctx)) || rand.Float64() >= shadow.SampleRate {
+		return ShadowRanking{}, false
+	}
+	return ShadowRanking{
+		Name:    shadow.Name,
+		Profile: rankingProfileOf(shadow.Ranking),
+		Boosts:  SearchBoosts(shadow.Ranking.SearchBoosts).Capped(),
+	}, true
+}
+
+// viewerHash hashes a client identifier, so a client's activity can be
+// matched up without storing the identifier
+func viewerHash(tenantID, clientID string) string {
+	return fmt.Sprintf("%016x", hll.Hash(tenantID, clientID))
+}
+
+// LogShadowComparison records the served ordering of a sampled request beside
+// the shadow candidate's. Failures are logged rather than returned so they
+// never fail the request.
+func (s *Services) LogShadowComparison(ctx context.Context, shadow ShadowRanking, endpoint, query, clientID string, live, candidate []models.Article) {
+	entry := models.ShadowComparison{
+		Candidate: shadow.Name,
+		Endpoint:  endpoint,
+		Query:     query,
+		Viewer:    viewerHash(tenant.IDFromContext(ctx), clientID),
+		Live:      articleIDs(live),
+		Shadow:    articleIDs(candidate),
+		Platform:  platform.FromContext(ctx),
+	}
+	if err := s.db.WithContext(ctx).Create(&entry).Error; err != nil {
+		log.Printf("Failed to log shadow ranking %s for %s %q: %v", shadow.Name, endpoint, query, err)
+	}
+}
+
+// recordShadowClick attributes a click to the viewer's latest sampled request
+// that served the article, recording its position in both orderings
+func (s *Services) recordShadowClick(ctx context.Context, event *models.Event, clientID string) {
+	var comparison models.ShadowComparison
+	err := s.db.WithContext(ctx).
+		Where("tenant_id = ? AND viewer = ? AND created_at > ?", event.TenantID, viewerHash(event.TenantID, clientID), time.Now().Add(-shadowClickWindow)).
+		Where("CAST(live AS TEXT) LIKE ?", `%"`+event.ArticleID+`"%`).
+		Order("created_at DESC").
+		First(&comparison).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to look up shadow ranking for click on %s: %v", event.ArticleID, err)
+		return
+	}
+
+	click := models.ShadowClick{
+		ComparisonID:   comparison.ID,
+		ArticleID:      event.ArticleID,
+		LivePosition:   position(comparison.Live, event.ArticleID),
+		ShadowPosition: position(comparison.Shadow, event.ArticleID),
+		TenantID:       event.TenantID,
+	}
+	if err := s.db.WithContext(ctx).Create(&click).Error; err != nil {
+		log.Printf("Failed to record shadow ranking click on %s: %v", event.ArticleID, err)
+	}
+}
+
+// ShadowReport compares a shadow candidate with the live ranking over its
+// sampled requests. Positions are 1-based, so lower means ranked higher.
+type ShadowReport struct {
+	Candidate          string  `json:"candidate"`
+	Comparisons        int     `json:"comparisons"`
+	Identical          int     `json:"identical"`    // Comparisons the candidate ordered exactly as served
+	MeanOverlap        float64 `json:"mean_overlap"` // Share of served articles the candidate also returned
+	Clicks             int     `json:"clicks"`
+	MeanLivePosition   float64 `json:"mean_live_position"`
+	MeanShadowPosition float64 `json:"mean_shadow_position"` // Over the clicks the candidate also returned
+	RankedHigher       int     `json:"ranked_higher"`        // Clicked articles the candidate placed higher
+	RankedLower        int     `json:"ranked_lower"`
+	RankedSame         int     `json:"ranked_same"`
+	NotReturned        int     `json:"not_returned"` // Clicked articles the candidate did not return
+}
+
+// ShadowReports summarizes the shadow comparisons logged since a time, one
+// report per candidate, only over requests from a client platform when one
+// is given
+func (s *Services) ShadowReports(ctx context.Context, since time.Time, client string) ([]ShadowReport, error) {
+	database := s.db.WithContext(ctx)
+	if database == nil {
+		return nil, fmt.Errorf("database not initialized")
+	}
+	if client != "" && !platform.Valid(client) {
+		return nil, apperr.InvalidFilterf("platform must be one of %s", strings.Join(platform.All, ", "))
+	}
+	sampled := func() *gorm.DB {
+		query := database.Model(&models.ShadowComparison{}).Where("created_at >= ?", since)
+		if client != "" {
+			query = query.Where("platform = ?", client)
+		}
+		return query
+	}
+
+	var comparisons []models.ShadowComparison
+	if err := sampled().Find(&comparisons).Error; err != nil {
+		return nil, err
+	}
+	var clicks []models.ShadowClick
+	err := database.
+		Where("comparison_id IN (?)", sampled().Select("id")).
+		Find(&clicks).Error
+	if err != nil {
+		return nil, err
+	}
+
+	byCandidate := map[string]*ShadowReport{}
+	candidateOf := make(map[uint]string, len(comparisons))
+	for _, comparison := range comparisons {
+		report := byCandidate[comparison.Candidate]
+		if report == nil {
+			report = &ShadowReport{Candidate: comparison.Candidate}
+			byCandidate[comparison.Candidate] = report
+		}
+		candidateOf[comparison.ID] = comparison.Candidate
+		report.Comparisons++
+		if sameOrder(comparison.Live, comparison.Shadow) {
+			report.Identical++
+		}
+		report.MeanOverlap += overlap(comparison.Live, comparison.Shadow)
+	}
+
+	shadowClicks := map[string]int{}
+	for _, click := range clicks {
+		report := byCandidate[candidateOf[click.ComparisonID]]
+		if report == nil {
+			continue
+		}
+		report.Clicks++
+		report.MeanLivePosition += float64(click.LivePosition)
+		switch {
+		case click.ShadowPosition == 0:
+			report.NotReturned++
+			continue
+		case click.ShadowPosition < click.LivePosition:
+			report.RankedHigher++
+		case click.ShadowPosition > click.LivePosition:
+			report.RankedLower++
+		default:
+			report.RankedSame++
+		}
+		report.MeanShadowPosition += float64(click.ShadowPosition)
+		shadowClicks[report.Candidate]++
+	}
+
+	reports := make([]ShadowReport, 0, len(byCandidate))
+	for _, report := range byCandidate {
+		report.MeanOverlap /= float64(report.Comparisons)
+		if report.Clicks > 0 {
+			report.MeanLivePosition /= float64(report.Clicks)
+		}
+		if n := shadowClicks[report.Candidate]; n > 0 {
+			report.MeanShadowPosition /= float64(n)
+		}
+		reports = append(reports, *report)
+	}
+	sort.Slice(reports, func(i, j int) bool { return reports[i].Candidate < reports[j].Candidate })
+	return reports, nil
+}
+
+// articleIDs lists the IDs of articles in order
+func articleIDs(articles []models.Article) models.StringArray {
+	ids := make(models.StringArray, len(articles))
+	for i, article := range articles {
+		ids[i] = article.ID
+	}
+	return ids
+}
+
+// position returns the 1-based position of an ID in a list, or 0
+func position(ids []string, id string) int {
+	for i, listed := range ids {
+		if listed == id {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// sameOrder reports whether two lists hold the same IDs in the same order
+func sameOrder(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// overlap returns the share of live IDs that are also in candidate, 1 when
+// live is empty
+func overlap(live, candidate []string) float64 {
+	if len(live) == 0 {
+		return 1
+	}
+	shared := 0
+	for _, id := range live {
+		if position(candidate, id) > 0 {
+			shared++
+		}
+	}
+	return float64(shared) / float64(len(live))
+}
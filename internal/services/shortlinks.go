@@ -0,0 +1,192 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/mahigadamsetty/Inshorts-task/internal/apperr"
+	"github.com/mahigadamsetty/Inshorts-task/internal/hll"
+	"github.com/mahigadamsetty/Inshorts-task/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	shortCodeLength    = 7
+	shortCodeAttempts  = 5  // Random codes tried before giving up on collisions
+	maxShortLinkLabel  = 64 // Longest channel or campaign
+	maxShortLinkDays   = 365
+	shortCodeAlphabet  = "abcdefghijkmnpqrstuvwxyzABCDEFGHJKLMNPQRSTUVWXYZ23456789" // Without look-alikes such as l, 1, O and 0
+	shortLinkDayLayout = "2006-01-02"
+)
+
+// ShortLinkInput labels the shares a short link tracks
+type ShortLinkInput struct {
+	Channel  string `json:"channel"`  // e.g. push, email
+	Campaign string `json:"campaign"` // e.g. the digest's date
+}
+
+// ShortLinkStats is a short link with its clicks per UTC day
+type ShortLinkStats struct {
+	*models.ShortLink
+	Daily []models.ShortLinkClicks `json:"daily"`
+}
+
+// CreateShortLink returns the short link of an article of the tenant carried
+// by ctx for a channel and campaign, creating it on the first request, and
+// reports whether it was created
+func (s *Services) CreateShortLink(ctx context.Context, articleID string, input ShortLinkInput) (*models.ShortLink, bool, error) {
+	database := s.db.WithContext(ctx)
+	if database == nil {
+		return nil, false, fmt.Errorf("database not initialized")
+	}
+	input.Channel = strings.TrimSpace(input.Channel)
+	input.Campaign = strings.TrimSpace(input.Campaign)
+	if len(input.Channel) > maxShortLinkLabel || len(input.Campaign) > maxShortLinkLabel {
+		return nil, false, apperr.InvalidFilterf("channel and campaign must be at most %d bytes", maxShortLinkLabel)
+	}
+
+	var article models.Article
+	if err := database.Select("id").Where("id = ?", articleID).First(&article).Error; err != nil {
+		return nil, false, apperr.NotFound(err, "Article")
+	}
+
+	var existing models.ShortLink
+	err := database.Where("article_id = ? AND channel = ? AND campaign = ?", article.ID, input.Channel, input.Campaign).
+		First(&existing).Error
+	if err == nil {
+		return &existing, false, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, false, err
+	}
+
+	for attempt := 0; attempt < shortCodeAttempts; attempt++ {
+		link := &models.ShortLink{
+			Code:      newShortCode(),
+			ArticleID: article.ID,
+			Channel:   input.Channel,
+			Campaign:  input.Campaign,
+		}
+		result := database.Clauses(clause.OnConflict{DoNothing: true}).Create(link)
+		if result.Error != nil {
+			return nil, false, result.Error
+		}
+		if result.RowsAffected == 1 {
+			return link, true, nil
+		}
+	}
+	return nil, false, fmt.Errorf("no free short code after %d attempts", shortCodeAttempts)
+}
+
+// GetShortLink looks a short code up across tenants, for redirects that
+// carry no API key
+func (s *Services) GetShortLink(ctx context.Context, code string) (*models.ShortLink, error) {
+	database := s.db.WithContext(ctx)
+	if database == nil {
+		return nil, fmt.Errorf("database not initialized")
+	}
+	var link models.ShortLink
+	if err := database.Where("code = ?", code).First(&link).Error; err != nil {
+		return nil, apperr.NotFound(err, "Short link")
+	}
+	return &link, nil
+}
+
+// ShortLinkArticle returns the article a short link redirects to, as the
+// link's tenant's clients see it: embargoed and expired articles are not found
+func (s *Services) ShortLinkArticle(ctx context.Context, link *models.ShortLink) (*models.Article, error) {
+	var article models.Article
+	if err := s.db.WithContext(ctx).Where("id = ?", link.ArticleID).First(&article).Error; err != nil {
+		return nil, apperr.NotFound(err, "Article")
+	}
+	return &article, nil
+}
+
+// RecordShortLinkClick counts a click on a short link, on the link and its
+// day, and records it as a click event of the article so it feeds trending
+// and the article's counters. ctx carries the link's tenant.
+func (s *Services) RecordShortLinkClick(ctx context.Context, link *models.ShortLink, clientID string) error {
+	database := s.db.WithContext(ctx)
+	if database == nil {
+		return fmt.Errorf("database not initialized")
+	}
+
+	now := time.Now().UTC()
+	err := database.Transaction(func(tx *gorm.DB) error {
+		var stored models.ShortLink
+		if err := tx.Where("code = ?", link.Code).First(&stored).Error; err != nil {
+			return err
+		}
+		sketch := hll.Sketch(stored.Sketch)
+		if !sketch.Valid() {
+			sketch = hll.New()
+		}
+		sketch.Add(hll.Hash(stored.TenantID, clientID))
+
+		err := tx.Model(&stored).Updates(map[string]interface{}{
+			"clicks":          gorm.Expr("clicks + 1"),
+			"sketch":          []byte(sketch),
+			"unique_visitors": int64(sketch.Estimate()),
+			"last_clicked_at": now,
+		}).Error
+		if err != nil {
+			return err
+		}
+		day := models.ShortLinkClicks{Code: stored.Code, Day: now.Format(shortLinkDayLayout), Clicks: 1, ArticleID: stored.ArticleID}
+		return tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "code"}, {Name: "day"}},
+			DoUpdates: clause.Assignments(map[string]interface{}{"clicks": gorm.Expr("short_link_clicks.clicks + 1")}),
+		}).Create(&day).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	event := models.Event{ArticleID: link.ArticleID, EventType: models.EventTypeClick, Timestamp: now}
+	return s.RecordEvent(ctx, &event, clientID)
+}
+
+// GetShortLinkStats returns a short link of the tenant carried by ctx with
+// its clicks per day over the last days days, oldest first. Days without
+// clicks are left out.
+func (s *Services) GetShortLinkStats(ctx context.Context, code string, days int) (*ShortLinkStats, error) {
+	database := s.db.WithContext(ctx)
+	if database == nil {
+		return nil, fmt.Errorf("database not initialized")
+	}
+	if days <= 0 || days > maxShortLinkDays {
+		return nil, apperr.InvalidFilterf("days must be between 1 and %d", maxShortLinkDays)
+	}
+
+	var link models.ShortLink
+	if err := database.Where("code = ?", code).First(&link).Error; err != nil {
+		return nil, apperr.NotFound(err, "Short link")
+	}
+	stats := &ShortLinkStats{ShortLink: &link, Daily: []models.ShortLinkClicks{}}
+	since := time.Now().UTC().AddDate(0, 0, -(days - 1)).Format(shortLinkDayLayout)
+	err := database.Where("code = ? AND day >= ?", code, since).Order("day").Find(&stats.Daily).Error
+	if err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+// newShortCode returns a random code of shortCodeLength characters
+func newShortCode() string {
+	code := make([]byte, shortCodeLength)
+	max := big.NewInt(int64(len(shortCodeAlphabet)))
+	for i := range code {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			panic(err)
+		}
+		code[i] = shortCodeAlphabet[n.Int64()]
+	}
+	return string(code)
+}
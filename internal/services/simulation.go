@@ -0,0 +1,420 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"math/rand"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/goccy/go-yaml"
+	"github.com/mahigadamsetty/Inshorts-task/internal/apperr"
+	"github.com/mahigadamsetty/Inshorts-task/internal/geocode"
+	"github.com/mahigadamsetty/Inshorts-task/internal/models"
+)
+
+// defaultClickRate is the share of simulated events that are clicks
+const defaultClickRate = 0.2
+
+// ErrSimulationDisabled is returned when simulated events would be written
+// while the simulation is disabled
+var ErrSimulationDisabled = apperr.Conflictf("event simulation is disabled")
+
+// RunningSimulation is a profile playing in the background
+type RunningSimulation struct {
+	Profile   string    `json:"profile"`
+	Seed      int64     `json:"seed"`
+	StartedAt time.Time `json:"started_at"`
+
+	cancel context.CancelFunc
+}
+
+// SimulationStatus is the state of the event simulation
+type SimulationStatus struct {
+	Enabled         bool                `json:"enabled"`
+	IncludeTrending bool                `json:"include_in_trending"`
+	Running         []RunningSimulation `json:"running"`
+}
+
+// SimulationOptions are the starting state of the simulation gate. Left zero
+// the gate is closed, so a production deployment never writes simulated
+// traffic unless asked to.
+type SimulationOptions struct {
+	Enabled           bool // Let simulated events be written
+	IncludeInTrending bool // Count simulated events in trending and analytics when a request doesn't say
+}
+
+// simulationGate decides whether simulated events may be written and whether
+// trending counts them
+type simulationGate struct {
+	enabled         bool
+	includeTrending bool
+	running         []*RunningSimulation
+	mu              sync.Mutex
+}
+
+// EnableSimulation opens or closes the gate for simulated events. Closing it
+// stops every simulation playing in the background, and any other run stops
+// before its next event.
+func (s *Services) EnableSimulation(enabled bool) {
+	simulation := s.simulation
+	simulation.mu.Lock()
+	defer simulation.mu.Unlock()
+	simulation.enabled = enabled
+	if !enabled {
+		for _, running := range simulation.running {
+			running.cancel()
+		}
+		simulation.running = nil
+	}
+}
+
+// SimulationEnabled reports whether simulated events may be written
+func (s *Services) SimulationEnabled() bool {
+	simulation := s.simulation
+	simulation.mu.Lock()
+	defer simulation.mu.Unlock()
+	return simulation.enabled
+}
+
+// IncludeSimulatedInTrending sets whether trending and analytics count simulated events
+// when a request doesn't say
+func (s *Services) IncludeSimulatedInTrending(include bool) {
+	simulation := s.simulation
+	simulation.mu.Lock()
+	defer simulation.mu.Unlock()
+	simulation.includeTrending = include
+}
+
+// GetSimulationStatus returns whether the simulation is enabled and the
+// profiles playing in the background
+func (s *Services) GetSimulationStatus() SimulationStatus {
+	simulation := s.simulation
+	simulation.mu.Lock()
+	defer simulation.mu.Unlock()
+	status := SimulationStatus{
+		Enabled:         simulation.enabled,
+		IncludeTrending: simulation.includeTrending,
+		Running:         make([]RunningSimulation, len(simulation.running)),
+	}
+	for i, running := range simulation.running {
+		status.Running[i] = *running
+	}
+	return status
+}
+
+type simulatedTrafficKey struct{}
+
+// WithSimulatedTraffic marks a context so trending and analytics computed
+// with it count simulated events or leave them out, whatever the deployment's
+// default
+func WithSimulatedTraffic(ctx context.Context, include bool) context.Context {
+	return context.WithValue(ctx, simulatedTrafficKey{}, include)
+}
+
+// includesSimulated reports whether trending and analytics computed with the
+// context count simulated events
+func (s *Services) includesSimulated(ctx context.Context) bool {
+	if include, ok := ctx.Value(simulatedTrafficKey{}).(bool); ok {
+		return include
+	}
+	simulation := s.simulation
+	simulation.mu.Lock()
+	defer simulation.mu.Unlock()
+	return simulation.includeTrending
+}
+
+// SimulationProfile is a named traffic shape: a steady base rate for the whole
+// duration plus bursts of extra traffic
+type SimulationProfile struct {
+	Name            string            `yaml:"-"`
+	Description     string            `yaml:"description"`
+	Duration        time.Duration     `yaml:"duration"`
+	EventsPerMinute float64           `yaml:"events_per_minute"`
+	ClickRate       *float64          `yaml:"click_rate"` // Defaults to 0.2
+	Bursts          []SimulationBurst `yaml:"bursts"`
+}
+
+// SimulationBurst adds traffic between two offsets from the start of a
+// profile. A city burst places its users around that city instead of around
+// the articles they read; a viral burst sends all of its events to one
+// article, article_id or one picked by the seed.
+type SimulationBurst struct {
+	Start           time.Duration `yaml:"start"`
+	End             time.Duration `yaml:"end"` // Defaults to the profile's duration
+	EventsPerMinute float64       `yaml:"events_per_minute"`
+	City            string        `yaml:"city"`
+	RadiusKm        float64       `yaml:"radius_km"` // Defaults to the city's own radius
+	Viral           bool          `yaml:"viral"`
+	ArticleID       string        `yaml:"article_id"`
+
+	place *geocode.Place
+}
+
+// LoadSimulationProfiles reads the named profiles of a YAML file
+func LoadSimulationProfiles(path string) (map[string]SimulationProfile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var file struct {
+		Profiles map[string]SimulationProfile `yaml:"profiles"`
+	}
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("invalid simulation profiles %s: %w", path, err)
+	}
+
+	for name, profile := range file.Profiles {
+		profile.Name = name
+		if err := profile.validate(); err != nil {
+			return nil, fmt.Errorf("simulation profile %q: %w", name, err)
+		}
+		file.Profiles[name] = profile
+	}
+	return file.Profiles, nil
+}
+
+// LoadSimulationProfile reads one named profile of a YAML file
+func LoadSimulationProfile(path, name string) (SimulationProfile, error) {
+	profiles, err := LoadSimulationProfiles(path)
+	if err != nil {
+		return SimulationProfile{}, err
+	}
+	profile, ok := profiles[name]
+	if !ok {
+		return SimulationProfile{}, fmt.Errorf("no simulation profile %q in %s", name, path)
+	}
+	return profile, nil
+}
+
+// validate checks a profile and resolves its burst cities
+func (p *SimulationProfile) validate() error {
+	if p.Duration <= 0 {
+		return fmt.Errorf("duration must be positive")
+	}
+	if p.EventsPerMinute < 0 {
+		return fmt.Errorf("events_per_minute must not be negative")
+	}
+	if p.ClickRate != nil && (*p.ClickRate < 0 || *p.ClickRate > 1) {
+		return fmt.Errorf("click_rate must be between 0 and 1")
+	}
+	for i := range p.Bursts {
+		burst := &p.Bursts[i]
+		if burst.End == 0 || burst.End > p.Duration {
+			burst.End = p.Duration
+		}
+		if burst.Start < 0 || burst.Start >= burst.End {
+			return fmt.Errorf("burst %d must start before it ends, within the duration", i+1)
+		}
+		if burst.EventsPerMinute <= 0 {
+			return fmt.Errorf("burst %d needs a positive events_per_minute", i+1)
+		}
+		if burst.City != "" {
+			place, ok := geocode.Resolve(burst.City)
+			if !ok {
+				return fmt.Errorf("burst %d: unknown city %q", i+1, burst.City)
+			}
+			burst.place = &place
+		}
+	}
+	return nil
+}
+
+// clickRate returns the profile's share of clicks
+func (p SimulationProfile) clickRate() float64 {
+	if p.ClickRate == nil {
+		return defaultClickRate
+	}
+	return *p.ClickRate
+}
+
+// simulatedEvent is one scheduled event of a profile run
+type simulatedEvent struct {
+	offset time.Duration
+	burst  *SimulationBurst // nil for base traffic
+}
+
+// schedule draws the event times of every traffic source as a Poisson process
+// and merges them in time order
+func (p SimulationProfile) schedule(rng *rand.Rand) []simulatedEvent {
+	var events []simulatedEvent
+	arrivals := func(start, end time.Duration, perMinute float64, burst *SimulationBurst) {
+		if perMinute <= 0 {
+			return
+		}
+		mean := float64(time.Minute) / perMinute
+		for t := float64(start) + rng.ExpFloat64()*mean; t < float64(end); t += rng.ExpFloat64() * mean {
+			events = append(events, simulatedEvent{offset: time.Duration(t), burst: burst})
+		}
+	}
+
+	arrivals(0, p.Duration, p.EventsPerMinute, nil)
+	for i := range p.Bursts {
+		burst := &p.Bursts[i]
+		arrivals(burst.Start, burst.End, burst.EventsPerMinute, burst)
+	}
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].offset < events[j].offset
+	})
+	return events
+}
+
+// RunSimulationProfile records the events of a profile over the given
+// articles. In realtime mode events are spread over the profile's duration as
+// it plays out; otherwise they are backfilled at once with timestamps ending
+// now, so trending reflects the whole shape immediately. The same seed and
+// articles always produce the same events. Returns the number recorded.
+func (s *Services) RunSimulationProfile(ctx context.Context, articles []models.Article, profile SimulationProfile, seed int64, realtime bool) (int, error) {
+	if len(articles) == 0 {
+		return 0, fmt.Errorf("no articles to simulate events for")
+	}
+
+	rng := rand.New(rand.NewSource(seed))
+	viral := make(map[*SimulationBurst]models.Article)
+	for i := range profile.Bursts {
+		burst := &profile.Bursts[i]
+		if !burst.Viral {
+			continue
+		}
+		article := articles[rng.Intn(len(articles))]
+		if burst.ArticleID != "" {
+			found := false
+			for _, candidate := range articles {
+				if candidate.ID == burst.ArticleID {
+					article, found = candidate, true
+					break
+				}
+			}
+			if !found {
+				return 0, fmt.Errorf("viral article %s not found", burst.ArticleID)
+			}
+		}
+		viral[burst] = article
+	}
+
+	start := time.Now()
+	if !realtime {
+		start = start.Add(-profile.Duration)
+	}
+	recorded := 0
+	for _, scheduled := range profile.schedule(rng) {
+		if !s.SimulationEnabled() {
+			return recorded, ErrSimulationDisabled
+		}
+		article := articles[rng.Intn(len(articles))]
+		if target, ok := viral[scheduled.burst]; ok {
+			article = target
+		}
+
+		// Users read from around the article's location, or around the burst's city
+		lat := article.Latitude + (rng.Float64()-0.5)*0.5 // within ~55km
+		lon := article.Longitude + (rng.Float64()-0.5)*0.5
+		if scheduled.burst != nil && scheduled.burst.place != nil {
+			lat, lon = pointNear(rng, *scheduled.burst.place, scheduled.burst.RadiusKm)
+		}
+
+		eventType := models.EventTypeView
+		if rng.Float64() < profile.clickRate() {
+			eventType = models.EventTypeClick
+		}
+		clientID := fmt.Sprintf("simulated-%d", rng.Intn(simulatedClients))
+
+		at := start.Add(scheduled.offset)
+		if realtime {
+			select {
+			case <-ctx.Done():
+				return recorded, ctx.Err()
+			case <-time.After(time.Until(at)):
+			}
+		}
+
+		event := models.Event{
+			ArticleID: article.ID,
+			EventType: eventType,
+			Latitude:  lat,
+			Longitude: lon,
+			Timestamp: at,
+			Simulated: true,
+			TenantID:  article.TenantID,
+		}
+		// The gate may have closed while waiting for the event
+		if !s.SimulationEnabled() {
+			return recorded, ErrSimulationDisabled
+		}
+		if err := s.RecordEvent(ctx, &event, clientID); err != nil {
+			return recorded, err
+		}
+		recorded++
+	}
+	return recorded, nil
+}
+
+// pointNear picks a uniformly distributed point within radiusKm of a place,
+// using the place's own radius when radiusKm is not positive
+func pointNear(rng *rand.Rand, place geocode.Place, radiusKm float64) (float64, float64) {
+	if radiusKm <= 0 {
+		radiusKm = place.RadiusKm
+	}
+	distance := radiusKm * math.Sqrt(rng.Float64())
+	bearing := rng.Float64() * 2 * math.Pi
+	dLat := distance / 111.0 * math.Cos(bearing)
+	dLon := distance / (111.0 * math.Cos(place.Latitude*math.Pi/180)) * math.Sin(bearing)
+	return place.Latitude + dLat, place.Longitude + dLon
+}
+
+// StartEventSimulation plays a profile in realtime in the background over
+// every stored article, for demos against a running server. It stops when ctx
+// is cancelled or the simulation is disabled, and returns
+// ErrSimulationDisabled without starting while it is.
+func (s *Services) StartEventSimulation(ctx context.Context, profile SimulationProfile, seed int64) error {
+	ctx, cancel := context.WithCancel(ctx)
+	running := &RunningSimulation{Profile: profile.Name, Seed: seed, StartedAt: time.Now(), cancel: cancel}
+	simulation := s.simulation
+	simulation.mu.Lock()
+	if !simulation.enabled {
+		simulation.mu.Unlock()
+		cancel()
+		return ErrSimulationDisabled
+	}
+	simulation.running = append(simulation.running, running)
+	simulation.mu.Unlock()
+
+	go func() {
+		defer s.stopSimulation(running)
+		var articles []models.Article
+		if err := s.db.WithContext(ctx).Select("id, latitude, longitude, tenant_id").Scopes(HasLocation).Order("id").Find(&articles).Error; err != nil {
+			log.Printf("Event simulation %s could not load articles: %v", profile.Name, err)
+			return
+		}
+		log.Printf("Event simulation %s started for %s with seed %d", profile.Name, profile.Duration, seed)
+		recorded, err := s.RunSimulationProfile(ctx, articles, profile, seed, true)
+		if err != nil && !s.SimulationEnabled() {
+			log.Printf("Event simulation %s stopped after %d events, the simulation was disabled", profile.Name, recorded)
+			return
+		}
+		if err != nil && ctx.Err() == nil {
+			log.Printf("Event simulation %s stopped after %d events: %v", profile.Name, recorded, err)
+			return
+		}
+		log.Printf("Event simulation %s recorded %d events", profile.Name, recorded)
+	}()
+	return nil
+}
+
+// stopSimulation releases a background simulation once it ends
+func (s *Services) stopSimulation(running *RunningSimulation) {
+	running.cancel()
+	simulation := s.simulation
+	simulation.mu.Lock()
+	defer simulation.mu.Unlock()
+	for i, r := range simulation.running {
+		if r == running {
+			simulation.running = append(simulation.running[:i], simulation.running[i+1:]...)
+			break
+		}
+	}
+}
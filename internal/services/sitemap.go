@@ -0,0 +1,75 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/mahigadamsetty/Inshorts-task/internal/apperr"
+	"github.com/mahigadamsetty/Inshorts-task/internal/models"
+)
+
+// MaxSitemapURLs is the most URLs the sitemap protocol allows in one sitemap
+const MaxSitemapURLs = 50000
+
+// SitemapPage is one page of the sitemap index
+type SitemapPage struct {
+	Number       int       // 1-based
+	LastModified time.Time // When the page last gained an article
+}
+
+// SitemapEntry is an article listed on a sitemap page
+type SitemapEntry struct {
+	ID              string
+	PublicationDate time.Time
+	CreatedAt       time.Time
+}
+
+// SitemapPages splits the articles visible to the tenant carried by ctx into
+// pages of pageSize. Articles are paged in the order they were stored, so new
+// articles only change the last page and earlier pages stay cacheable.
+// Updated_at is not used, as jobs touch it on every rescoring.
+func (s *Services) SitemapPages(ctx context.Context, pageSize int) ([]SitemapPage, error) {
+	database := s.db.WithContext(ctx)
+	if database == nil {
+		return nil, fmt.Errorf("database not initialized")
+	}
+
+	var created []time.Time
+	err := database.Model(&models.Article{}).Order("created_at, id").Pluck("created_at", &created).Error
+	if err != nil {
+		return nil, err
+	}
+
+	pages := make([]SitemapPage, 0, (len(created)+pageSize-1)/pageSize)
+	for i, t := range created {
+		if i%pageSize == 0 {
+			pages = append(pages, SitemapPage{Number: len(pages) + 1})
+		}
+		if page := &pages[len(pages)-1]; t.After(page.LastModified) {
+			page.LastModified = t
+		}
+	}
+	return pages, nil
+}
+
+// SitemapArticles returns the articles on a 1-based sitemap page, in the
+// order of SitemapPages. A page past the last is not found.
+func (s *Services) SitemapArticles(ctx context.Context, page, pageSize int) ([]SitemapEntry, error) {
+	database := s.db.WithContext(ctx)
+	if database == nil {
+		return nil, fmt.Errorf("database not initialized")
+	}
+
+	var entries []SitemapEntry
+	err := database.Model(&models.Article{}).
+		Select("id, publication_date, created_at").
+		Order("created_at, id").
+		Offset((page - 1) * pageSize).
+		Limit(pageSize).
+		Scan(&entries).Error
+	if err == nil && len(entries) == 0 {
+		return nil, apperr.NotFoundf("Sitemap not found")
+	}
+	return entries, err
+}
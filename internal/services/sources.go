@@ -0,0 +1,154 @@
+package services
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/mahigadamsetty/Inshorts-task/internal/apperr"
+	"github.com/mahigadamsetty/Inshorts-task/internal/models"
+	"gorm.io/gorm/clause"
+)
+
+//go:embed data/sources.json
+var bundledSources []byte
+
+// tierReliability is the reliability assigned to a tier when none is given
+var tierReliability = map[string]float64{
+	models.TierHigh:          0.9,
+	models.TierMedium:        0.7,
+	models.TierLow:           0.4,
+	models.TierUserGenerated: 0.2,
+}
+
+// SourceInput describes a source as given in the bundled dataset or through the
+// admin API. Reliability defaults to the tier's value when omitted.
+type SourceInput struct {
+	Name            string   `json:"name"`
+	ReliabilityTier string   `json:"reliability_tier"`
+	Reliability     *float64 `json:"reliability"`
+	Bias            string   `json:"bias"`
+}
+
+// Source validates the input and builds the stored source
+func (in SourceInput) Source() (models.Source, error) {
+	source := models.Source{
+		Key:             models.SourceKey(in.Name),
+		Name:            strings.TrimSpace(in.Name),
+		ReliabilityTier: in.ReliabilityTier,
+		Bias:            in.Bias,
+	}
+	if source.Key == "" {
+		return source, apperr.InvalidFilterf("source name is required")
+	}
+
+	reliability, ok := tierReliability[in.ReliabilityTier]
+	if !ok {
+		return source, apperr.InvalidFilterf("unknown reliability tier %q", in.ReliabilityTier)
+	}
+	if in.Reliability != nil {
+		reliability = *in.Reliability
+	}
+	if reliability < 0 || reliability > 1 {
+		return source, apperr.InvalidFilterf("reliability must be between 0 and 1")
+	}
+	source.Reliability = reliability
+
+	if source.Bias == "" {
+		source.Bias = "unknown"
+	}
+	return source, nil
+}
+
+// SeedSources inserts the bundled source metadata. Sources already in the table
+// are left alone so admin edits survive restarts. Returns the number inserted.
+func (s *Services) SeedSources(ctx context.Context) (int, error) {
+	var inputs []SourceInput
+	if err := json.Unmarshal(bundledSources, &inputs); err != nil {
+		return 0, fmt.Errorf("failed to parse bundled sources: %w", err)
+	}
+
+	sources := make([]models.Source, len(inputs))
+	for i, in := range inputs {
+		source, err := in.Source()
+		if err != nil {
+			return 0, fmt.Errorf("bundled source %q: %w", in.Name, err)
+		}
+		sources[i] = source
+	}
+
+	result := s.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(&sources)
+	if result.Error != nil {
+		return 0, result.Error
+	}
+	return int(result.RowsAffected), nil
+}
+
+// ListSources returns all source metadata ordered by name
+func (s *Services) ListSources(ctx context.Context) ([]models.Source, error) {
+	var sources []models.Source
+	err := s.db.WithContext(ctx).Order("name").Find(&sources).Error
+	return sources, err
+}
+
+// SaveSource creates or replaces the metadata for a source
+func (s *Services) SaveSource(ctx context.Context, source *models.Source) error {
+	return s.db.WithContext(ctx).Save(source).Error
+}
+
+// DeleteSource removes a source's metadata
+func (s *Services) DeleteSource(ctx context.Context, name string) error {
+	result := s.db.WithContext(ctx).Where("key = ?", models.SourceKey(name)).Delete(&models.Source{})
+	if result.Error == nil && result.RowsAffected == 0 {
+		return apperr.NotFoundf("Source not found")
+	}
+	return result.Error
+}
+
+// SourceMetadata loads metadata for the given source names, keyed by SourceKey
+func (s *Services) SourceMetadata(ctx context.Context, names []string) (map[string]*models.Source, error) {
+	keys := make([]string, 0, len(names))
+	seen := make(map[string]bool, len(names))
+	for _, name := range names {
+		key := models.SourceKey(name)
+		if key != "" && !seen[key] {
+			seen[key] = true
+			keys = append(keys, key)
+		}
+	}
+
+	metadata := make(map[string]*models.Source, len(keys))
+	if len(keys) == 0 {
+		return metadata, nil
+	}
+
+	var sources []models.Source
+	if err := s.db.WithContext(ctx).Where("key IN ?", keys).Find(&sources).Error; err != nil {
+		return nil, err
+	}
+	for i := range sources {
+		metadata[sources[i].Key] = &sources[i]
+	}
+	return metadata, nil
+}
+
+// AttachSourceMeta sets SourceMeta on each article whose source has metadata.
+// Lookup failures are logged and leave the articles without metadata.
+func (s *Services) AttachSourceMeta(ctx context.Context, articles []models.Article) {
+	names := make([]string, len(articles))
+	for i, article := range articles {
+		names[i] = article.SourceName
+	}
+
+	metadata, err := s.SourceMetadata(ctx, names)
+	if err != nil {
+		log.Printf("Failed to load source metadata: %v", err)
+		return
+	}
+	for i := range articles {
+		articles[i].SourceMeta = metadata[models.SourceKey(articles[i].SourceName)]
+	}
+}
@@ -0,0 +1,408 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/mahigadamsetty/Inshorts-task/internal/apperr"
+	"github.com/mahigadamsetty/Inshorts-task/internal/db"
+	"github.com/mahigadamsetty/Inshorts-task/internal/models"
+	"github.com/mahigadamsetty/Inshorts-task/internal/tuning"
+	"github.com/mahigadamsetty/Inshorts-task/internal/utils"
+	"gorm.io/gorm"
+)
+
+const (
+	storyWindow       = 72 * time.Hour // Articles further apart than this never share a story
+	storyGeoRadiusKm  = 500.0          // Beyond this distance the similarity is penalised
+	storyTitleWeight  = 0.6
+	storyEntityWeight = 0.4
+)
+
+// storyMember is a clustered article with its precomputed token sets
+type storyMember struct {
+	article     models.Article
+	titleTokens map[string]struct{}
+	entities    map[string]struct{}
+}
+
+// storyCluster is an in-progress group of articles during clustering
+type storyCluster struct {
+	members  []storyMember
+	lastDate time.Time
+	tenantID string
+}
+
+// ClusterStories groups articles about the same event into stories using title
+// similarity, shared entities and time/geo proximity. Existing stories are rebuilt,
+// keeping the ID of the story that shared most articles with each new cluster.
+func (s *Services) ClusterStories(ctx context.Context, threshold float64) (int, error) {
+	// Stories are published, so embargoed articles join them once visible
+	database := s.db.WithContext(db.VisibleOnly(ctx))
+	if database == nil {
+		return 0, fmt.Errorf("database not initialized")
+	}
+
+	var articles []models.Article
+	err := database.
+		Select("id, title, description, publication_date, latitude, longitude, location_source, tenant_id").
+		Order("publication_date ASC").
+		Order("id").
+		Find(&articles).Error
+	if err != nil {
+		return 0, err
+	}
+
+	var clusters []*storyCluster
+	for _, article := range articles {
+		member := storyMember{
+			article:     article,
+			titleTokens: titleTokenSet(article.Title),
+			entities:    entitySet(article.Title + " " + article.Description),
+		}
+
+		var best *storyCluster
+		bestScore := threshold
+		for _, cluster := range clusters {
+			if cluster.tenantID != article.TenantID || article.PublicationDate.Sub(cluster.lastDate) > storyWindow {
+				continue
+			}
+			score := cluster.similarity(member)
+			if score >= bestScore {
+				best = cluster
+				bestScore = score
+			}
+		}
+
+		if best == nil {
+			best = &storyCluster{tenantID: article.TenantID}
+			clusters = append(clusters, best)
+		}
+		best.add(member)
+	}
+
+	// Swap in the new membership together, so a failed run keeps the old stories
+	count := 0
+	err = database.Transaction(func(tx *gorm.DB) error {
+		var previous []models.Article
+		if err := tx.Select("id, story_id").Where("story_id IS NOT NULL").Find(&previous).Error; err != nil {
+			return err
+		}
+		var existing []models.Story
+		if err := tx.Find(&existing).Error; err != nil {
+			return err
+		}
+		matched := matchStories(clusters, previous)
+
+		if err := tx.Model(&models.Article{}).Where("story_id IS NOT NULL").Update("story_id", nil).Error; err != nil {
+			return err
+		}
+
+		kept := make(map[uint]bool, len(matched))
+		for i, cluster := range clusters {
+			if len(cluster.members) < 2 {
+				continue
+			}
+
+			story := cluster.toStory()
+			if id, ok := matched[i]; ok {
+				kept[id] = true
+				// A story keeps its ID, state and summary; the summary is
+				// regenerated on the next read once its articles change
+				updates := map[string]interface{}{
+					"title":           story.Title,
+					"article_count":   story.ArticleCount,
+					"first_published": story.FirstPublished,
+					"last_published":  story.LastPublished,
+					"latitude":        story.Latitude,
+					"longitude":       story.Longitude,
+					"updated_at":      time.Now(),
+				}
+				if !cluster.sameMembers(id, previous) {
+					updates["summary"] = ""
+				}
+				if err := tx.Model(&models.Story{}).Where("id = ?", id).Updates(updates).Error; err != nil {
+					return err
+				}
+				story.ID = id
+			} else if err := tx.Create(&story).Error; err != nil {
+				return err
+			}
+
+			if err := tx.Model(&models.Article{}).Where("id IN ?", cluster.memberIDs()).Update("story_id", story.ID).Error; err != nil {
+				return err
+			}
+			count++
+		}
+
+		// Stories whose articles all moved on or drifted apart are gone
+		var stale []uint
+		for _, story := range existing {
+			if !kept[story.ID] {
+				stale = append(stale, story.ID)
+			}
+		}
+		if len(stale) > 0 {
+			if err := tx.Where("id IN ?", stale).Delete(&models.Story{}).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// matchStories pairs clusters of at least two articles with the existing story
+// sharing most of their articles, so stories keep their IDs across runs. Each
+// story goes to at most one cluster, the pairs with the largest overlap first.
+func matchStories(clusters []*storyCluster, previous []models.Article) map[int]uint {
+	storyOf := make(map[string]uint, len(previous))
+	for _, article := range previous {
+		storyOf[article.ID] = *article.StoryID
+	}
+
+	type candidate struct {
+		cluster int
+		story   uint
+		overlap int
+	}
+	var candidates []candidate
+	for i, cluster := range clusters {
+		if len(cluster.members) < 2 {
+			continue
+		}
+		overlap := map[uint]int{}
+		for _, member := range cluster.members {
+			if id, ok := storyOf[member.article.ID]; ok {
+				overlap[id]++
+			}
+		}
+		for id, n := range overlap {
+			candidates = append(candidates, candidate{cluster: i, story: id, overlap: n})
+		}
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].overlap != candidates[j].overlap {
+			return candidates[i].overlap > candidates[j].overlap
+		}
+		if candidates[i].story != candidates[j].story {
+			return candidates[i].story < candidates[j].story
+		}
+		return candidates[i].cluster < candidates[j].cluster
+	})
+
+	matched := map[int]uint{}
+	taken := map[uint]bool{}
+	for _, c := range candidates {
+		if _, ok := matched[c.cluster]; ok || taken[c.story] {
+			continue
+		}
+		matched[c.cluster] = c.story
+		taken[c.story] = true
+	}
+	return matched
+}
+
+// memberIDs returns the IDs of the cluster's articles
+func (sc *storyCluster) memberIDs() []string {
+	ids := make([]string, len(sc.members))
+	for i, member := range sc.members {
+		ids[i] = member.article.ID
+	}
+	return ids
+}
+
+// sameMembers reports whether the cluster holds exactly the articles the story
+// had before the run
+func (sc *storyCluster) sameMembers(storyID uint, previous []models.Article) bool {
+	had := 0
+	for _, article := range previous {
+		if *article.StoryID == storyID {
+			had++
+		}
+	}
+	if had != len(sc.members) {
+		return false
+	}
+	members := make(map[string]bool, len(sc.members))
+	for _, member := range sc.members {
+		members[member.article.ID] = true
+	}
+	for _, article := range previous {
+		if *article.StoryID == storyID && !members[article.ID] {
+			return false
+		}
+	}
+	return true
+}
+
+// ListStories returns the latest stories, optionally only those in one
+// lifecycle state
+func (s *Services) ListStories(ctx context.Context, state string, limit int) ([]models.Story, error) {
+	database := s.db.WithContext(ctx)
+	if database == nil {
+		return nil, fmt.Errorf("database not initialized")
+	}
+	if state != "" {
+		database = database.Where("state = ?", state)
+	}
+	var stories []models.Story
+	err := database.
+		Order("last_published DESC").
+		Order("article_count DESC").
+		Order("id").
+		Limit(limit).
+		Find(&stories).Error
+	return stories, err
+}
+
+// GetStory returns a story with its articles passing filter, oldest first
+func (s *Services) GetStory(ctx context.Context, id uint, filter ArticleFilter) (*models.Story, []models.Article, error) {
+	database := s.db.WithContext(ctx)
+	if database == nil {
+		return nil, nil, fmt.Errorf("database not initialized")
+	}
+
+	var story models.Story
+	if err := database.First(&story, id).Error; err != nil {
+		return nil, nil, apperr.NotFound(err, "Story")
+	}
+	var articles []models.Article
+	err := database.
+		Scopes(filter.Scope).
+		Where("story_id = ?", story.ID).
+		Order("publication_date ASC").
+		Order("id").
+		Find(&articles).Error
+	if err != nil {
+		return nil, nil, err
+	}
+	s.AttachSourceMeta(ctx, articles)
+	return &story, articles, nil
+}
+
+// SaveStorySummary stores the combined summary generated for a story
+func (s *Services) SaveStorySummary(ctx context.Context, id uint, summary string) error {
+	return s.db.WithContext(ctx).Model(&models.Story{}).Where("id = ?", id).Update("summary", summary).Error
+}
+
+// similarity scores how likely an article belongs to the cluster (0-1), using
+// its best match among the cluster's recent members
+func (sc *storyCluster) similarity(candidate storyMember) float64 {
+	best := 0.0
+	for _, member := range sc.members {
+		if candidate.article.PublicationDate.Sub(member.article.PublicationDate) > storyWindow {
+			continue
+		}
+
+		score := storyTitleWeight*jaccard(member.titleTokens, candidate.titleTokens) +
+			storyEntityWeight*jaccard(member.entities, candidate.entities)
+
+		// Penalise reports that happened far apart, when both have a location
+		if member.article.HasLocation() && candidate.article.HasLocation() {
+			distance := utils.HaversineDistance(member.article.Latitude, member.article.Longitude,
+				candidate.article.Latitude, candidate.article.Longitude)
+			if distance > storyGeoRadiusKm {
+				score *= 0.5
+			}
+		}
+
+		best = math.Max(best, score)
+	}
+	return best
+}
+
+// add places an article into the cluster
+func (sc *storyCluster) add(member storyMember) {
+	sc.members = append(sc.members, member)
+	if member.article.PublicationDate.After(sc.lastDate) {
+		sc.lastDate = member.article.PublicationDate
+	}
+}
+
+// toStory builds the persisted story record for a cluster
+func (sc *storyCluster) toStory() models.Story {
+	sorted := make([]models.Article, len(sc.members))
+	var latSum, lonSum, located float64
+	for i, member := range sc.members {
+		sorted[i] = member.article
+		if member.article.HasLocation() {
+			latSum += member.article.Latitude
+			lonSum += member.article.Longitude
+			located++
+		}
+	}
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if !sorted[i].PublicationDate.Equal(sorted[j].PublicationDate) {
+			return sorted[i].PublicationDate.Before(sorted[j].PublicationDate)
+		}
+		return sorted[i].ID < sorted[j].ID
+	})
+
+	// Place the story at the centroid of its located members
+	if located == 0 {
+		located = 1
+	}
+	return models.Story{
+		Title:          sorted[len(sorted)-1].Title, // Latest headline describes the story best
+		ArticleCount:   len(sorted),
+		FirstPublished: sorted[0].PublicationDate,
+		LastPublished:  sorted[len(sorted)-1].PublicationDate,
+		Latitude:       latSum / located,
+		Longitude:      lonSum / located,
+		TenantID:       sc.tenantID,
+	}
+}
+
+// titleTokenSet returns the lowercase non-stop-word tokens of a title
+func titleTokenSet(title string) map[string]struct{} {
+	words := strings.FieldsFunc(strings.ToLower(title), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	set := make(map[string]struct{}, len(words))
+	for _, word := range tuning.FilterStopWords(words) {
+		if len(word) > 2 {
+			set[word] = struct{}{}
+		}
+	}
+	return set
+}
+
+// entitySet returns capitalised words, a cheap stand-in for named entities
+func entitySet(text string) map[string]struct{} {
+	set := map[string]struct{}{}
+	for _, word := range strings.Fields(text) {
+		word = strings.TrimFunc(word, func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+		})
+		if len(word) > 3 && unicode.IsUpper([]rune(word)[0]) {
+			if !tuning.IsStopWord(strings.ToLower(word)) {
+				set[strings.ToLower(word)] = struct{}{}
+			}
+		}
+	}
+	return set
+}
+
+// jaccard computes the Jaccard similarity of two sets
+func jaccard(a, b map[string]struct{}) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+	intersection := 0
+	for key := range b {
+		if _, ok := a[key]; ok {
+			intersection++
+		}
+	}
+	union := len(a) + len(b) - intersection
+	return float64(intersection) / math.Max(float64(union), 1)
+}
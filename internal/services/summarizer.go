@@ -0,0 +1,484 @@
+package services
+
+import (
+	"container/heap"
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/mahigadamsetty/Inshorts-task/internal/apperr"
+	"github.com/mahigadamsetty/Inshorts-task/internal/llm"
+	"github.com/mahigadamsetty/Inshorts-task/internal/models"
+)
+
+// Summary job priorities accepted by the bulk summarize API
+const (
+	SummaryPriorityLow    = "low"
+	SummaryPriorityNormal = "normal"
+	SummaryPriorityHigh   = "high"
+)
+
+// summaryPriorityRank orders queued work; higher ranks are taken first
+var summaryPriorityRank = map[string]int{
+	SummaryPriorityLow:    0,
+	SummaryPriorityNormal: 1,
+	SummaryPriorityHigh:   2,
+}
+
+// summaryRankRequested ranks the summaries readers are waiting on above every
+// bulk job
+const summaryRankRequested = 3
+
+// ValidSummaryPriority reports whether a priority is known
+func ValidSummaryPriority(priority string) bool {
+	_, ok := summaryPriorityRank[priority]
+	return ok
+}
+
+// summaryColumns are the columns caching an article's summaries of each
+// length other than medium, which is stored as llm_summary
+var summaryColumns = map[string]string{
+	llm.SummaryShort: "summary_short",
+	llm.SummaryLong:  "summary_long",
+}
+
+// SummarizeArticle generates and stores a summary for an article, preferring the
+// full text stored at ingest and falling back to the title and description. It
+// never fetches the article's URL.
+func (s *Services) SummarizeArticle(ctx context.Context, client llm.Provider, article *models.Article) error {
+	summary, err := s.generateSummary(ctx, client.For(llm.Call{Context: ctx}), article, llm.SummaryMedium)
+	if err != nil {
+		return err
+	}
+
+	article.LLMSummary = summary
+	article.SummaryStale = false
+	return s.db.WithContext(ctx).Model(article).Updates(map[string]interface{}{
+		"llm_summary":   summary,
+		"summary_stale": false,
+	}).Error
+}
+
+// SummarizeArticleLength generates a summary of the given length for an
+// article, caching it in the length's column, and serves it as the article's
+// llm_summary. Medium summaries are the stored ones of SummarizeArticle.
+func (s *Services) SummarizeArticleLength(ctx context.Context, client llm.Provider, article *models.Article, length string) error {
+	column, ok := summaryColumns[length]
+	if !ok {
+		return s.SummarizeArticle(ctx, client, article)
+	}
+	summary, err := s.generateSummary(ctx, client.For(llm.Call{Context: ctx}), article, length)
+	if err != nil {
+		return err
+	}
+
+	article.LLMSummary = summary
+	if length == llm.SummaryShort {
+		article.SummaryShort = summary
+	} else {
+		article.SummaryLong = summary
+	}
+	return s.db.WithContext(ctx).Model(article).Update(column, summary).Error
+}
+
+// generateSummary summarizes an article's stored text when it is open to
+// read, and its title and description otherwise
+func (s *Services) generateSummary(ctx context.Context, client llm.Provider, article *models.Article, length string) (string, error) {
+	var summary string
+	if article.Access == models.AccessOpen {
+		if text := s.articleText(ctx, *article); text != "" {
+			summary, _ = client.GenerateSummaryOfLength(article.Title, text, length)
+		}
+	}
+
+	// Fallback to title and description if there is no stored text or summarizing it failed
+	if summary == "" {
+		return client.GenerateSummaryOfLength(article.Title, article.Description, length)
+	}
+	return summary, nil
+}
+
+// storedSummary returns an article's stored summary of a length, empty when
+// it has none
+func storedSummary(article models.Article, length string) string {
+	switch length {
+	case llm.SummaryShort:
+		return article.SummaryShort
+	case llm.SummaryLong:
+		return article.SummaryLong
+	default:
+		return article.LLMSummary
+	}
+}
+
+// ApplySummaryLength replaces the llm_summary of articles with their stored
+// summaries of a length, read from the database so copies held by caches see
+// summaries generated since. Articles without one are left without a
+// summary, for SummarizeArticleLength to fill.
+func (s *Services) ApplySummaryLength(ctx context.Context, articles []models.Article, length string) error {
+	column, ok := summaryColumns[length]
+	if !ok {
+		column = "llm_summary"
+	}
+	if len(articles) == 0 {
+		return nil
+	}
+	database := s.db.WithContext(ctx)
+	if database == nil {
+		return fmt.Errorf("database not initialized")
+	}
+
+	var cached []struct {
+		ID      string
+		Summary string
+	}
+	err := database.Model(&models.Article{}).
+		Select("id, "+column+" AS summary").
+		Where("id IN ?", []string(articleIDs(articles))).
+		Scan(&cached).Error
+	if err != nil {
+		return err
+	}
+	summaries := make(map[string]string, len(cached))
+	for _, row := range cached {
+		summaries[row.ID] = row.Summary
+	}
+	for i := range articles {
+		articles[i].LLMSummary = summaries[articles[i].ID]
+	}
+	return nil
+}
+
+// RefreshStaleSummaries regenerates the summaries of articles whose text
+// changed since they were summarized and returns the number regenerated
+func (s *Services) RefreshStaleSummaries(ctx context.Context, client llm.Provider, batchSize int) (int, error) {
+	database := s.db.WithContext(ctx)
+	refreshed := 0
+	for {
+		var articles []models.Article
+		if err := database.Where("summary_stale = ?", true).Limit(batchSize).Find(&articles).Error; err != nil {
+			return refreshed, err
+		}
+		if len(articles) == 0 {
+			return refreshed, nil
+		}
+		for i := range articles {
+			if err := s.SummarizeArticle(ctx, client, &articles[i]); err != nil {
+				return refreshed, fmt.Errorf("failed to summarize article %s: %w", articles[i].ID, err)
+			}
+			refreshed++
+		}
+	}
+}
+
+// summaryTask is one article waiting to be summarized for a job, or on its
+// own when readers asked for it
+type summaryTask struct {
+	jobID     uint // Zero for requested summaries
+	articleID string
+	length    string       // Of a requested summary; jobs regenerate medium ones
+	client    llm.Provider // Generates a requested summary, nil for the summarizer's
+	rank      int
+	seq       uint64 // Submission order, keeps equal priorities first-in first-out
+}
+
+// requestKey identifies a requested summary, so it is queued once
+func (t summaryTask) requestKey() string {
+	return t.articleID + "/" + t.length
+}
+
+// summaryQueue is a max-heap of tasks by priority rank, then submission order
+type summaryQueue []summaryTask
+
+func (q summaryQueue) Len() int { return len(q) }
+func (q summaryQueue) Less(i, j int) bool {
+	if q[i].rank != q[j].rank {
+		return q[i].rank > q[j].rank
+	}
+	return q[i].seq < q[j].seq
+}
+func (q summaryQueue) Swap(i, j int)       { q[i], q[j] = q[j], q[i] }
+func (q *summaryQueue) Push(x interface{}) { *q = append(*q, x.(summaryTask)) }
+func (q *summaryQueue) Pop() interface{} {
+	old := *q
+	task := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return task
+}
+
+// Summarizer generates article summaries in the background, working through
+// the summaries readers requested first, then queued articles by job priority
+type Summarizer struct {
+	svc     *Services
+	client  llm.Provider
+	workers int
+
+	mu        sync.Mutex
+	cond      *sync.Cond
+	queue     summaryQueue
+	seq       uint64
+	jobs      map[uint]*models.SummaryJob // Jobs with queued or running work
+	requested map[string]bool             // Requested summaries queued or running, by requestKey
+}
+
+// NewSummarizer creates a summarizer that runs the given number of workers once started
+func NewSummarizer(svc *Services, client llm.Provider, workers int) *Summarizer {
+	if workers <= 0 {
+		workers = 1
+	}
+	s := &Summarizer{
+		svc:       svc,
+		client:    client,
+		workers:   workers,
+		jobs:      make(map[uint]*models.SummaryJob),
+		requested: make(map[string]bool),
+	}
+	s.cond = sync.NewCond(&s.mu)
+	return s
+}
+
+// Start marks jobs left unfinished by a previous process as interrupted and
+// launches the workers. Workers exit when ctx is cancelled.
+func (s *Summarizer) Start(ctx context.Context) {
+	err := s.svc.db.WithContext(ctx).Model(&models.SummaryJob{}).
+		Where("status IN ?", []string{models.SummaryJobQueued, models.SummaryJobRunning}).
+		Update("status", models.SummaryJobInterrupted).Error
+	if err != nil {
+		log.Printf("Failed to mark interrupted summary jobs: %v", err)
+	}
+
+	for i := 0; i < s.workers; i++ {
+		go s.work(ctx)
+	}
+	go func() {
+		<-ctx.Done()
+		s.mu.Lock()
+		s.cond.Broadcast()
+		s.mu.Unlock()
+	}()
+}
+
+// Submit records a job for the given articles and queues them. Unknown article
+// IDs are rejected before anything is queued.
+func (s *Summarizer) Submit(ctx context.Context, articleIDs []string, priority string) (*models.SummaryJob, error) {
+	rank, ok := summaryPriorityRank[priority]
+	if !ok {
+		return nil, apperr.InvalidFilterf("unknown priority %q", priority)
+	}
+
+	ids := make([]string, 0, len(articleIDs))
+	seen := make(map[string]bool, len(articleIDs))
+	for _, id := range articleIDs {
+		if id != "" && !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) == 0 {
+		return nil, apperr.InvalidFilterf("at least one article ID is required")
+	}
+
+	database := s.svc.db.WithContext(ctx)
+	var found int64
+	if err := database.Model(&models.Article{}).Where("id IN ?", ids).Count(&found).Error; err != nil {
+		return nil, err
+	}
+	if int(found) != len(ids) {
+		return nil, apperr.InvalidFilterf("%d of %d article IDs were not found", len(ids)-int(found), len(ids))
+	}
+
+	job := &models.SummaryJob{
+		Status:   models.SummaryJobQueued,
+		Priority: priority,
+		Total:    len(ids),
+	}
+	if err := database.Create(job).Error; err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	s.jobs[job.ID] = job
+	for _, id := range ids {
+		s.seq++
+		heap.Push(&s.queue, summaryTask{jobID: job.ID, articleID: id, rank: rank, seq: s.seq})
+	}
+	s.cond.Broadcast()
+	snapshot := *job
+	s.mu.Unlock()
+
+	return &snapshot, nil
+}
+
+// Request queues summaries of a length for articles readers are waiting on,
+// ahead of every bulk job, and returns how many were queued. Summaries already
+// queued are skipped. client generates them, so they are metered to the
+// reader's tenant; nil uses the summarizer's.
+func (s *Summarizer) Request(articleIDs []string, length string, client llm.Provider) int {
+	return s.enqueue(articleIDs, length, client, summaryRankRequested)
+}
+
+// Prewarm queues medium summaries for those of the newest n articles without
+// one, behind requested summaries and bulk jobs, and returns how many were
+// queued
+func (s *Summarizer) Prewarm(ctx context.Context, n int) (int, error) {
+	database := s.svc.db.WithContext(ctx)
+	if database == nil {
+		return 0, fmt.Errorf("database not initialized")
+	}
+	var newest []models.Article
+	err := database.Select("id", "llm_summary").Order("publication_date DESC").Limit(n).Find(&newest).Error
+	if err != nil {
+		return 0, err
+	}
+	var ids []string
+	for _, article := range newest {
+		if article.LLMSummary == "" {
+			ids = append(ids, article.ID)
+		}
+	}
+	return s.enqueue(ids, llm.SummaryMedium, nil, summaryPriorityRank[SummaryPriorityLow]), nil
+}
+
+// enqueue queues requested summaries not queued already
+func (s *Summarizer) enqueue(articleIDs []string, length string, client llm.Provider, rank int) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	queued := 0
+	for _, id := range articleIDs {
+		task := summaryTask{articleID: id, length: length, client: client, rank: rank}
+		if s.requested[task.requestKey()] {
+			continue
+		}
+		s.requested[task.requestKey()] = true
+		s.seq++
+		task.seq = s.seq
+		heap.Push(&s.queue, task)
+		queued++
+	}
+	if queued > 0 {
+		s.cond.Broadcast()
+	}
+	return queued
+}
+
+// GetSummaryJob returns a summary job by ID
+func (s *Services) GetSummaryJob(ctx context.Context, id uint) (*models.SummaryJob, error) {
+	var job models.SummaryJob
+	if err := s.db.WithContext(ctx).First(&job, id).Error; err != nil {
+		return nil, apperr.NotFound(err, "Job")
+	}
+	return &job, nil
+}
+
+// next blocks until a task is queued, returning false once ctx is cancelled
+func (s *Summarizer) next(ctx context.Context) (summaryTask, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for len(s.queue) == 0 {
+		if ctx.Err() != nil {
+			return summaryTask{}, false
+		}
+		s.cond.Wait()
+	}
+	if ctx.Err() != nil {
+		return summaryTask{}, false
+	}
+	return heap.Pop(&s.queue).(summaryTask), true
+}
+
+// work summarizes queued articles until ctx is cancelled
+func (s *Summarizer) work(ctx context.Context) {
+	for {
+		task, ok := s.next(ctx)
+		if !ok {
+			return
+		}
+		if task.jobID == 0 {
+			s.summarizeRequested(ctx, task)
+			continue
+		}
+		s.markStarted(ctx, task.jobID)
+
+		var article models.Article
+		err := s.svc.db.WithContext(ctx).Where("id = ?", task.articleID).First(&article).Error
+		if err == nil {
+			err = s.svc.SummarizeArticle(ctx, s.client, &article)
+		}
+		s.record(ctx, task, err)
+	}
+}
+
+// summarizeRequested generates a requested summary, unless the article got
+// one since it was queued. Failures are only logged: the article is requested
+// again by the next reader who gets it without a summary.
+func (s *Summarizer) summarizeRequested(ctx context.Context, task summaryTask) {
+	defer func() {
+		s.mu.Lock()
+		delete(s.requested, task.requestKey())
+		s.mu.Unlock()
+	}()
+
+	var article models.Article
+	if err := s.svc.db.WithContext(ctx).Where("id = ?", task.articleID).First(&article).Error; err != nil {
+		log.Printf("Failed to load article %s to summarize: %v", task.articleID, err)
+		return
+	}
+	if storedSummary(article, task.length) != "" {
+		return
+	}
+	client := task.client
+	if client == nil {
+		client = s.client
+	}
+	if err := s.svc.SummarizeArticleLength(ctx, client, &article, task.length); err != nil {
+		log.Printf("Failed to generate summary for article %s: %v", article.ID, err)
+	}
+}
+
+// markStarted moves a job to running when its first article is picked up
+func (s *Summarizer) markStarted(ctx context.Context, jobID uint) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	job, ok := s.jobs[jobID]
+	if !ok || job.StartedAt != nil {
+		return
+	}
+	now := time.Now()
+	job.Status = models.SummaryJobRunning
+	job.StartedAt = &now
+	s.save(ctx, job)
+}
+
+// record stores the outcome of one article and completes the job after its last one
+func (s *Summarizer) record(ctx context.Context, task summaryTask, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	job, ok := s.jobs[task.jobID]
+	if !ok {
+		return
+	}
+	if err != nil {
+		job.Failed++
+		job.Failures = append(job.Failures, fmt.Sprintf("%s: %v", task.articleID, err))
+	} else {
+		job.Succeeded++
+	}
+	if job.Succeeded+job.Failed >= job.Total {
+		now := time.Now()
+		job.Status = models.SummaryJobCompleted
+		job.FinishedAt = &now
+		delete(s.jobs, task.jobID)
+	}
+	s.save(ctx, job)
+}
+
+// save persists a job's progress, logging failures
+func (s *Summarizer) save(ctx context.Context, job *models.SummaryJob) {
+	if err := s.svc.db.WithContext(ctx).Save(job).Error; err != nil {
+		log.Printf("Failed to save summary job %d: %v", job.ID, err)
+	}
+}
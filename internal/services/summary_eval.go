@@ -0,0 +1,293 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"unicode"
+
+	"github.com/mahigadamsetty/Inshorts-task/internal/degradation"
+	"github.com/mahigadamsetty/Inshorts-task/internal/llm"
+)
+
+// SummarySample is one labeled article of a summary evaluation set
+type SummarySample struct {
+	ID        string `json:"id"`
+	Title     string `json:"title"`
+	Text      string `json:"text"`      // Article text or description the summarizer sees
+	Reference string `json:"reference"` // Human-written summary to score against
+}
+
+// SummaryEvalOptions are the limits a summary must respect
+type SummaryEvalOptions struct {
+	MinSentences int `json:"min_sentences"`
+	MaxSentences int `json:"max_sentences"`
+	MaxWords     int `json:"max_words"` // 0 for no limit
+}
+
+// DefaultSummaryEvalOptions match the 1-2 sentence summaries the prompt asks for
+var DefaultSummaryEvalOptions = SummaryEvalOptions{MinSentences: 1, MaxSentences: 2, MaxWords: 60}
+
+// SummaryEvalResult scores one generated summary
+type SummaryEvalResult struct {
+	ID           string   `json:"id"`
+	Summary      string   `json:"summary"`
+	Fallback     bool     `json:"fallback"` // The heuristic summary was used instead of the LLM
+	Sentences    int      `json:"sentences"`
+	Words        int      `json:"words"`
+	LengthOK     bool     `json:"length_ok"`
+	Rouge1       float64  `json:"rouge_1"`
+	Rouge2       float64  `json:"rouge_2"`
+	RougeL       float64  `json:"rouge_l"`
+	Hallucinated []string `json:"hallucinated_entities,omitempty"` // Entities of the summary missing from the article
+	Error        string   `json:"error,omitempty"`
+}
+
+// SummaryEvalReport aggregates the results of an evaluation run. ROUGE scores
+// are F1 averages over the samples that produced a summary.
+type SummaryEvalReport struct {
+	Models            []string            `json:"models"`
+	Samples           int                 `json:"samples"`
+	Failed            int                 `json:"failed"`
+	Fallbacks         int                 `json:"fallbacks"`
+	LengthCompliance  float64             `json:"length_compliance"`
+	Rouge1            float64             `json:"rouge_1"`
+	Rouge2            float64             `json:"rouge_2"`
+	RougeL            float64             `json:"rouge_l"`
+	HallucinationRate float64             `json:"hallucination_rate"` // Share of summaries with at least one hallucinated entity
+	Options           SummaryEvalOptions  `json:"options"`
+	Results           []SummaryEvalResult `json:"results"`
+}
+
+// LoadSummarySamples reads a JSON array of labeled samples
+func LoadSummarySamples(path string) ([]SummarySample, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var samples []SummarySample
+	if err := json.Unmarshal(data, &samples); err != nil {
+		return nil, fmt.Errorf("invalid summary samples %s: %w", path, err)
+	}
+	for i, sample := range samples {
+		if sample.Title == "" || sample.Reference == "" {
+			return nil, fmt.Errorf("summary sample %d needs a title and a reference", i+1)
+		}
+	}
+	return samples, nil
+}
+
+// EvaluateSummaries runs the summarizer over the samples and scores each summary
+func EvaluateSummaries(client *llm.Client, models []string, samples []SummarySample, options SummaryEvalOptions) SummaryEvalReport {
+	report := SummaryEvalReport{Models: models, Samples: len(samples), Options: options, Results: make([]SummaryEvalResult, 0, len(samples))}
+	scored := 0
+	hallucinating := 0
+	for _, sample := range samples {
+		degraded := &degradation.Report{}
+		summary, err := client.WithReport(degraded).GenerateSummary(sample.Title, sample.Text)
+		result := SummaryEvalResult{ID: sample.ID, Summary: summary}
+		if err != nil {
+			result.Error = err.Error()
+			report.Failed++
+			report.Results = append(report.Results, result)
+			continue
+		}
+
+		_, result.Fallback = degraded.Modes()[degradation.SubsystemLLMSummary]
+		result.Sentences = countSentences(summary)
+		result.Words = len(strings.Fields(summary))
+		result.LengthOK = result.Sentences >= options.MinSentences && result.Sentences <= options.MaxSentences &&
+			(options.MaxWords <= 0 || result.Words <= options.MaxWords)
+
+		candidate, reference := evalTokens(summary), evalTokens(sample.Reference)
+		result.Rouge1 = rougeN(candidate, reference, 1)
+		result.Rouge2 = rougeN(candidate, reference, 2)
+		result.RougeL = rougeL(candidate, reference)
+		result.Hallucinated = hallucinatedEntities(summary, sample.Title+"\n"+sample.Text)
+
+		if result.Fallback {
+			report.Fallbacks++
+		}
+		if result.LengthOK {
+			report.LengthCompliance++
+		}
+		if len(result.Hallucinated) > 0 {
+			hallucinating++
+		}
+		report.Rouge1 += result.Rouge1
+		report.Rouge2 += result.Rouge2
+		report.RougeL += result.RougeL
+		scored++
+		report.Results = append(report.Results, result)
+	}
+
+	if scored > 0 {
+		n := float64(scored)
+		report.LengthCompliance /= n
+		report.Rouge1 /= n
+		report.Rouge2 /= n
+		report.RougeL /= n
+		report.HallucinationRate = float64(hallucinating) / n
+	}
+	return report
+}
+
+// countSentences counts sentence-ending punctuation followed by a space or the
+// end of the text, so decimals like 21.1 don't split sentences. Trailing text
+// without one counts as a sentence.
+func countSentences(text string) int {
+	runes := []rune(text)
+	count := 0
+	pending := false
+	for i, r := range runes {
+		switch {
+		case (r == '.' || r == '!' || r == '?') && (i+1 == len(runes) || unicode.IsSpace(runes[i+1])):
+			if pending {
+				count++
+				pending = false
+			}
+		case !unicode.IsSpace(r):
+			pending = true
+		}
+	}
+	if pending {
+		count++
+	}
+	return count
+}
+
+// evalTokens lowercases text into letter and digit runs
+func evalTokens(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// rougeN is the F1 of the n-gram overlap between candidate and reference
+func rougeN(candidate, reference []string, n int) float64 {
+	grams := func(tokens []string) map[string]int {
+		counts := map[string]int{}
+		for i := 0; i+n <= len(tokens); i++ {
+			counts[strings.Join(tokens[i:i+n], " ")]++
+		}
+		return counts
+	}
+	candidateGrams, referenceGrams := grams(candidate), grams(reference)
+
+	overlap, candidateTotal, referenceTotal := 0, 0, 0
+	for gram, count := range candidateGrams {
+		candidateTotal += count
+		overlap += min(count, referenceGrams[gram])
+	}
+	for _, count := range referenceGrams {
+		referenceTotal += count
+	}
+	return f1(overlap, candidateTotal, referenceTotal)
+}
+
+// rougeL is the F1 of the longest common subsequence of candidate and reference
+func rougeL(candidate, reference []string) float64 {
+	if len(candidate) == 0 || len(reference) == 0 {
+		return 0
+	}
+	previous := make([]int, len(reference)+1)
+	current := make([]int, len(reference)+1)
+	for i := range candidate {
+		for j := range reference {
+			if candidate[i] == reference[j] {
+				current[j+1] = previous[j] + 1
+			} else {
+				current[j+1] = max(previous[j+1], current[j])
+			}
+		}
+		previous, current = current, previous
+	}
+	return f1(previous[len(reference)], len(candidate), len(reference))
+}
+
+func f1(overlap, candidateTotal, referenceTotal int) float64 {
+	if overlap == 0 || candidateTotal == 0 || referenceTotal == 0 {
+		return 0
+	}
+	precision := float64(overlap) / float64(candidateTotal)
+	recall := float64(overlap) / float64(referenceTotal)
+	return 2 * precision * recall / (precision + recall)
+}
+
+// hallucinatedEntities returns the names and numbers of the summary that don't
+// appear in the source. Names are capitalized words not starting a sentence,
+// joined into phrases.
+func hallucinatedEntities(summary, source string) []string {
+	sourceTokens := map[string]bool{}
+	for _, token := range evalTokens(source) {
+		sourceTokens[token] = true
+	}
+
+	var missing []string
+	seen := map[string]bool{}
+	for _, entity := range summaryEntities(summary) {
+		absent := false
+		for _, token := range evalTokens(entity) {
+			if !sourceTokens[token] {
+				absent = true
+				break
+			}
+		}
+		if absent && !seen[entity] {
+			seen[entity] = true
+			missing = append(missing, entity)
+		}
+	}
+	return missing
+}
+
+// leadingArticles are dropped from the start of capitalized phrases
+var leadingArticles = map[string]bool{"The": true, "A": true, "An": true}
+
+// summaryEntities picks capitalized phrases and numbers out of a summary. A
+// capitalized word starting a sentence only counts when the next word is
+// capitalized too, as in "Virat Kohli scored".
+func summaryEntities(summary string) []string {
+	var entities, phrase []string
+	tentative := false // phrase holds just a sentence's first word
+	flush := func() {
+		if len(phrase) > 1 && leadingArticles[phrase[0]] {
+			phrase = phrase[1:]
+		}
+		if len(phrase) > 0 && !tentative {
+			entities = append(entities, strings.Join(phrase, " "))
+		}
+		phrase = nil
+		tentative = false
+	}
+
+	sentenceStart := true
+	for _, word := range strings.Fields(summary) {
+		trimmed := strings.TrimFunc(word, func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+		})
+		if trimmed != "" {
+			first := []rune(trimmed)[0]
+			switch {
+			case unicode.IsDigit(first):
+				flush()
+				entities = append(entities, trimmed)
+			case unicode.IsUpper(first) && sentenceStart:
+				phrase = []string{trimmed}
+				tentative = true
+			case unicode.IsUpper(first):
+				phrase = append(phrase, trimmed)
+				tentative = false
+			default:
+				flush()
+			}
+		}
+		sentenceStart = strings.HasSuffix(word, ".") || strings.HasSuffix(word, "!") || strings.HasSuffix(word, "?")
+		if sentenceStart || strings.HasSuffix(word, ",") || strings.HasSuffix(word, ";") {
+			flush()
+		}
+	}
+	flush()
+	return entities
+}
@@ -0,0 +1,323 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mahigadamsetty/Inshorts-task/internal/apperr"
+	"github.com/mahigadamsetty/Inshorts-task/internal/geocode"
+	"github.com/mahigadamsetty/Inshorts-task/internal/models"
+	"github.com/mahigadamsetty/Inshorts-task/internal/telegram"
+	"github.com/mahigadamsetty/Inshorts-task/internal/tenant"
+	"gorm.io/gorm"
+)
+
+const (
+	telegramPollWait         = 30 * time.Second
+	telegramRetryDelay       = 5 * time.Second // After a failed poll
+	maxTelegramSubscriptions = 10              // Per chat
+	maxTelegramCategory      = 64
+	telegramSummaryChars     = 300
+)
+
+const telegramHelp = `Get alerts for new and trending articles:
+/subscribe sports - new sports articles
+/subscribe sports near Mumbai - new sports articles around Mumbai, and reading spikes there
+/subscribe near Mumbai - everything around Mumbai
+Or share a location to subscribe to everything around it.
+
+/subscriptions - your subscriptions
+/unsubscribe 12 - stop one, or /unsubscribe all`
+
+// TelegramBotOptions configures the Telegram bot
+type TelegramBotOptions struct {
+	Tenant         *tenant.Tenant // Whose articles subscriptions follow
+	RadiusKm       float64        // Radius of subscriptions to a shared location
+	SpikeThreshold int            // Fewest reads in a window that alert subscriptions with a place
+}
+
+// TelegramBot lets Telegram users subscribe to categories and places through
+// chat commands. Subscriptions are geofences of the bot's tenant with the
+// user's chat, so their alerts are raised with every other geofence's and
+// delivered through the outbox.
+type TelegramBot struct {
+	svc     *Services
+	client  *telegram.Client
+	options TelegramBotOptions
+}
+
+// NewTelegramBot creates a bot answering from svc that polls for messages
+// once started
+func NewTelegramBot(svc *Services, client *telegram.Client, options TelegramBotOptions) *TelegramBot {
+	return &TelegramBot{svc: svc, client: client, options: options}
+}
+
+// Start polls for messages in the background until ctx is cancelled
+func (b *TelegramBot) Start(ctx context.Context) {
+	go b.poll(ctx)
+}
+
+// poll answers messages as they come in. Updates are confirmed by the next
+// poll, so the last batch is handled again after a restart; subscribing twice
+// to the same thing is a no-op.
+func (b *TelegramBot) poll(ctx context.Context) {
+	var offset int64
+	for {
+		updates, err := b.client.GetUpdates(ctx, offset, telegramPollWait)
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			log.Printf("Failed to poll Telegram updates: %v", err)
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(telegramRetryDelay):
+			}
+			continue
+		}
+
+		for _, update := range updates {
+			offset = update.UpdateID + 1
+			if update.Message == nil {
+				continue
+			}
+			reply := b.handle(tenant.NewContext(ctx, b.options.Tenant), update.Message)
+			if reply == "" {
+				continue
+			}
+			if err := b.client.SendMessage(ctx, update.Message.Chat.ID, reply); err != nil {
+				log.Printf("Failed to reply to Telegram chat %d: %v", update.Message.Chat.ID, err)
+			}
+		}
+	}
+}
+
+// handle runs the command in a message and returns the reply, or "" for
+// messages that are not meant for the bot
+func (b *TelegramBot) handle(ctx context.Context, message *telegram.Message) string {
+	chatID := message.Chat.ID
+	if message.Location != nil {
+		place := geocode.Place{
+			Name:      fmt.Sprintf("%.3f, %.3f", message.Location.Latitude, message.Location.Longitude),
+			Latitude:  message.Location.Latitude,
+			Longitude: message.Location.Longitude,
+			RadiusKm:  b.options.RadiusKm,
+		}
+		return b.subscribe(ctx, chatID, "", &place)
+	}
+
+	command, args := parseTelegramCommand(message.Text)
+	switch command {
+	case "/start", "/help":
+		return telegramHelp
+	case "/subscribe":
+		category, placeName := parseTelegramSubscription(args)
+		if category == "" && placeName == "" {
+			return "Tell me what to follow, like /subscribe sports or /subscribe near Mumbai."
+		}
+		if len(category) > maxTelegramCategory {
+			return fmt.Sprintf("Categories are at most %d characters.", maxTelegramCategory)
+		}
+		if placeName == "" {
+			return b.subscribe(ctx, chatID, category, nil)
+		}
+		place, ok := geocode.Resolve(placeName)
+		if !ok {
+			return fmt.Sprintf("I don't know where %s is. Try a city name, or share a location.", html.EscapeString(placeName))
+		}
+		return b.subscribe(ctx, chatID, category, &place)
+	case "/subscriptions", "/list":
+		return b.list(ctx, chatID)
+	case "/unsubscribe":
+		return b.unsubscribe(ctx, chatID, args)
+	case "":
+		// Groups see every message; only private chats are answered
+		if message.Chat.Type != "private" {
+			return ""
+		}
+	}
+	return "I don't know that command. Send /help to see what I can do."
+}
+
+// subscribe follows new articles of a category, anywhere or around a place,
+// for a chat. Subscriptions with a place also alert on reading spikes there.
+func (b *TelegramBot) subscribe(ctx context.Context, chatID int64, category string, place *geocode.Place) string {
+	fence := models.Geofence{
+		Name:           telegramSubscriptionName(category, place),
+		Shape:          models.FenceAnywhere,
+		Category:       category,
+		TelegramChatID: chatID,
+	}
+	if place != nil {
+		fence.Shape = models.FenceCircle
+		fence.Latitude, fence.Longitude, fence.RadiusKm = place.Latitude, place.Longitude, place.RadiusKm
+		if fence.RadiusKm <= 0 {
+			fence.RadiusKm = b.options.RadiusKm
+		}
+		fence.SpikeThreshold = b.options.SpikeThreshold
+	}
+
+	database := b.svc.db.WithContext(ctx)
+	var existing models.Geofence
+	err := database.
+		Where("telegram_chat_id = ? AND shape = ? AND category = ?", chatID, fence.Shape, fence.Category).
+		Where("latitude = ? AND longitude = ?", fence.Latitude, fence.Longitude).
+		First(&existing).Error
+	if err == nil {
+		return fmt.Sprintf("You already follow <b>%s</b> (#%d).", html.EscapeString(existing.Name), existing.ID)
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		log.Printf("Failed to look up Telegram subscriptions of chat %d: %v", chatID, err)
+		return "Something went wrong. Try again later."
+	}
+
+	var count int64
+	if err := database.Model(&models.Geofence{}).Where("telegram_chat_id = ?", chatID).Count(&count).Error; err != nil {
+		log.Printf("Failed to count Telegram subscriptions of chat %d: %v", chatID, err)
+		return "Something went wrong. Try again later."
+	}
+	if count >= maxTelegramSubscriptions {
+		return fmt.Sprintf("You can follow at most %d things. Send /unsubscribe to make room.", maxTelegramSubscriptions)
+	}
+
+	if err := b.svc.CreateGeofence(ctx, &fence); err != nil {
+		log.Printf("Failed to create Telegram subscription for chat %d: %v", chatID, err)
+		return "Something went wrong. Try again later."
+	}
+	reply := fmt.Sprintf("Following <b>%s</b> (#%d). New articles will show up here", html.EscapeString(fence.Name), fence.ID)
+	if fence.SpikeThreshold > 0 {
+		reply += ", and so will articles trending there"
+	}
+	return reply + "."
+}
+
+// list describes a chat's subscriptions
+func (b *TelegramBot) list(ctx context.Context, chatID int64) string {
+	var fences []models.Geofence
+	if err := b.svc.db.WithContext(ctx).Where("telegram_chat_id = ?", chatID).Order("id").Find(&fences).Error; err != nil {
+		log.Printf("Failed to list Telegram subscriptions of chat %d: %v", chatID, err)
+		return "Something went wrong. Try again later."
+	}
+	if len(fences) == 0 {
+		return "You don't follow anything yet. Send /help to get started."
+	}
+	lines := make([]string, 0, len(fences))
+	for _, fence := range fences {
+		lines = append(lines, fmt.Sprintf("#%d %s", fence.ID, html.EscapeString(fence.Name)))
+	}
+	return "You follow:\n" + strings.Join(lines, "\n")
+}
+
+// unsubscribe removes one of a chat's subscriptions by ID, or all of them
+func (b *TelegramBot) unsubscribe(ctx context.Context, chatID int64, args string) string {
+	args = strings.TrimPrefix(strings.TrimSpace(args), "#")
+	query := b.svc.db.WithContext(ctx).Model(&models.Geofence{}).Where("telegram_chat_id = ?", chatID)
+	if !strings.EqualFold(args, "all") {
+		id, err := strconv.ParseUint(args, 10, 64)
+		if err != nil {
+			return "Tell me which one, like /unsubscribe 12 or /unsubscribe all. Send /subscriptions to see their numbers."
+		}
+		query = query.Where("id = ?", id)
+	}
+
+	var ids []uint
+	if err := query.Pluck("id", &ids).Error; err != nil {
+		log.Printf("Failed to look up Telegram subscriptions of chat %d: %v", chatID, err)
+		return "Something went wrong. Try again later."
+	}
+	if len(ids) == 0 {
+		return "No such subscription. Send /subscriptions to see yours."
+	}
+	for _, id := range ids {
+		if err := b.svc.DeleteGeofence(ctx, id); err != nil && !errors.Is(err, apperr.ErrNotFound) {
+			log.Printf("Failed to delete Telegram subscription %d: %v", id, err)
+			return "Something went wrong. Try again later."
+		}
+	}
+	if len(ids) == 1 {
+		return "Unsubscribed."
+	}
+	return fmt.Sprintf("Unsubscribed from %d subscriptions.", len(ids))
+}
+
+// parseTelegramCommand splits a message into its command, without the bot's
+// name groups add, and the rest. Messages that are no command return "".
+func parseTelegramCommand(text string) (string, string) {
+	text = strings.TrimSpace(text)
+	if !strings.HasPrefix(text, "/") {
+		return "", text
+	}
+	command, args, _ := strings.Cut(text, " ")
+	command, _, _ = strings.Cut(command, "@")
+	return strings.ToLower(command), strings.TrimSpace(args)
+}
+
+// parseTelegramSubscription splits "sports near Mumbai" into a category and
+// a place, either of which may be missing
+func parseTelegramSubscription(args string) (category, place string) {
+	args = strings.Join(strings.Fields(args), " ")
+	lower := strings.ToLower(args)
+	switch {
+	case strings.HasPrefix(lower, "near "):
+		place = args[len("near "):]
+	case strings.Contains(lower, " near "):
+		i := strings.Index(lower, " near ")
+		category, place = args[:i], args[i+len(" near "):]
+	default:
+		category = args
+	}
+	return strings.ToLower(category), place
+}
+
+// telegramSubscriptionName describes a subscription, like "sports near Mumbai"
+func telegramSubscriptionName(category string, place *geocode.Place) string {
+	switch {
+	case place == nil:
+		return category
+	case category == "":
+		return "near " + place.Name
+	default:
+		return category + " near " + place.Name
+	}
+}
+
+// telegramAlertText formats a geofence alert as a Telegram message
+func telegramAlertText(payload AlertPayload) string {
+	var text string
+	if payload.Kind == models.AlertTrendingSpike {
+		text = fmt.Sprintf("<b>Trending: %s</b>\n%d reads in the last window, up from about %.0f.",
+			html.EscapeString(payload.GeofenceName), payload.EventCount, payload.Baseline)
+		if payload.Article != nil {
+			text += "\nMost read: " + telegramArticleLink(payload.Article)
+		}
+		return text
+	}
+
+	text = fmt.Sprintf("<b>New: %s</b>", html.EscapeString(payload.GeofenceName))
+	if article := payload.Article; article != nil {
+		text += "\n" + telegramArticleLink(article)
+		summary := article.LLMSummary
+		if summary == "" {
+			summary = article.Description
+		}
+		if summary = strings.Join(strings.Fields(summary), " "); summary != "" {
+			text += "\n" + html.EscapeString(ShortenText(summary, telegramSummaryChars))
+		}
+		if article.SourceName != "" {
+			text += "\n<i>" + html.EscapeString(article.SourceName) + "</i>"
+		}
+	}
+	return text
+}
+
+// telegramArticleLink links an article's title to the original
+func telegramArticleLink(article *models.Article) string {
+	return fmt.Sprintf(`<a href="%s">%s</a>`, html.EscapeString(article.URL), html.EscapeString(article.Title))
+}
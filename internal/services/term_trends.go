@@ -0,0 +1,164 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/mahigadamsetty/Inshorts-task/internal/models"
+)
+
+// minRisingMentions is how many mentions a term needs in the current window
+// to be reported, so one-off words don't top the list
+const minRisingMentions = 2
+
+// RisingTerm is a term mentioned more often in the current window than in the
+// one before it. A mention is an article or a search containing the term, so
+// repeating it within one article counts once.
+type RisingTerm struct {
+	Term     string  `json:"term"`
+	Entity   bool    `json:"entity"`   // Capitalized in an article title or search, a stand-in for named entities
+	Current  int     `json:"current"`  // Mentions in the current window
+	Previous int     `json:"previous"` // Mentions in the previous window
+	Delta    int     `json:"delta"`
+	Growth   float64 `json:"growth"`   // (current + 1) / (previous + 1), so new terms still compare
+	Articles int     `json:"articles"` // Current mentions from articles
+	Searches int     `json:"searches"` // Current mentions from searches
+}
+
+// TermTrends lists the fastest-growing terms of a window compared with the
+// window of the same length before it
+type TermTrends struct {
+	Since         time.Time    `json:"since"`
+	PreviousSince time.Time    `json:"previous_since"`
+	Articles      int          `json:"articles"` // Articles published in the current window
+	Searches      int          `json:"searches"` // Searches logged in the current window
+	Terms         []RisingTerm `json:"terms"`
+}
+
+// termCounts tallies the mentions of terms in one window
+type termCounts struct {
+	articles map[string]int
+	searches map[string]int
+}
+
+func newTermCounts() termCounts {
+	return termCounts{articles: map[string]int{}, searches: map[string]int{}}
+}
+
+// trendTerms returns the distinct terms of a text: its non-stop-word tokens,
+// leaving out numbers like years, which rise and fall with the calendar
+func trendTerms(text string) map[string]struct{} {
+	terms := titleTokenSet(text)
+	for term := range terms {
+		if strings.IndexFunc(term, unicode.IsLetter) < 0 {
+			delete(terms, term)
+		}
+	}
+	return terms
+}
+
+// RisingTerms compares the terms of the articles published and the searches
+// logged in the last window with those of the window before it, returning at
+// most limit terms by growth in mentions. Articles and searches are scoped to
+// the tenant carried by ctx.
+func (s *Services) RisingTerms(ctx context.Context, window time.Duration, limit int) (*TermTrends, error) {
+	database := s.db.WithContext(ctx)
+	if database == nil {
+		return nil, fmt.Errorf("database not initialized")
+	}
+
+	now := time.Now()
+	since := now.Add(-window)
+	previousSince := since.Add(-window)
+	trends := &TermTrends{Since: since, PreviousSince: previousSince, Terms: []RisingTerm{}}
+
+	var articles []models.Article
+	err := database.Select("title, description, publication_date").
+		Where("publication_date >= ? AND publication_date <= ?", previousSince, now).
+		Find(&articles).Error
+	if err != nil {
+		return nil, err
+	}
+	var searches []models.SearchLog
+	err = database.Select("query, created_at").
+		Where("created_at >= ? AND query <> ''", previousSince).
+		Find(&searches).Error
+	if err != nil {
+		return nil, err
+	}
+
+	current, previous := newTermCounts(), newTermCounts()
+	entities := map[string]bool{}
+	for _, article := range articles {
+		counts := previous
+		if !article.PublicationDate.Before(since) {
+			counts = current
+			trends.Articles++
+		}
+		for term := range trendTerms(article.Title + " " + article.Description) {
+			counts.articles[term]++
+		}
+		for entity := range entitySet(article.Title) {
+			entities[entity] = true
+		}
+	}
+	for _, search := range searches {
+		counts := previous
+		if !search.CreatedAt.Before(since) {
+			counts = current
+			trends.Searches++
+		}
+		for term := range trendTerms(search.Query) {
+			counts.searches[term]++
+		}
+		for entity := range entitySet(search.Query) {
+			entities[entity] = true
+		}
+	}
+
+	mentions := func(counts termCounts, term string) int {
+		return counts.articles[term] + counts.searches[term]
+	}
+	seen := map[string]bool{}
+	for _, terms := range []map[string]int{current.articles, current.searches} {
+		for term := range terms {
+			if seen[term] {
+				continue
+			}
+			seen[term] = true
+			count, before := mentions(current, term), mentions(previous, term)
+			if count < minRisingMentions || count <= before {
+				continue
+			}
+			trends.Terms = append(trends.Terms, RisingTerm{
+				Term:     term,
+				Entity:   entities[term],
+				Current:  count,
+				Previous: before,
+				Delta:    count - before,
+				Growth:   float64(count+1) / float64(before+1),
+				Articles: current.articles[term],
+				Searches: current.searches[term],
+			})
+		}
+	}
+
+	sort.Slice(trends.Terms, func(i, j int) bool {
+		a, b := trends.Terms[i], trends.Terms[j]
+		if a.Delta != b.Delta {
+			return a.Delta > b.Delta
+		}
+		if a.Growth != b.Growth {
+			return a.Growth > b.Growth
+		}
+		return a.Term < b.Term
+	})
+	if len(trends.Terms) > limit {
+		trends.Terms = trends.Terms[:limit]
+	}
+	return trends, nil
+}
@@ -0,0 +1,64 @@
+package services
+
+import (
+	"time"
+
+	"github.com/mahigadamsetty/Inshorts-task/internal/models"
+)
+
+// Timeline bucket sizes
+const (
+	IntervalDay  = "day"
+	IntervalWeek = "week"
+)
+
+// TimelineBucket holds the articles published within one time window
+type TimelineBucket struct {
+	Start    time.Time        `json:"start"`
+	End      time.Time        `json:"end"`
+	Count    int              `json:"count"`
+	Note     string           `json:"note,omitempty"`
+	Articles []models.Article `json:"articles"`
+}
+
+// BucketArticles groups articles chronologically into day or week buckets (UTC).
+// Articles must already be sorted by publication date ascending.
+func BucketArticles(articles []models.Article, interval string) []TimelineBucket {
+	buckets := []TimelineBucket{}
+
+	for _, article := range articles {
+		start := bucketStart(article.PublicationDate, interval)
+		if n := len(buckets); n == 0 || !buckets[n-1].Start.Equal(start) {
+			buckets = append(buckets, TimelineBucket{
+				Start:    start,
+				End:      bucketEnd(start, interval),
+				Articles: []models.Article{},
+			})
+		}
+
+		last := &buckets[len(buckets)-1]
+		last.Articles = append(last.Articles, article)
+		last.Count++
+	}
+
+	return buckets
+}
+
+// bucketStart truncates a time to the start of its day or ISO week (Monday)
+func bucketStart(t time.Time, interval string) time.Time {
+	t = t.UTC()
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	if interval != IntervalWeek {
+		return day
+	}
+	offset := (int(day.Weekday()) + 6) % 7 // Days since Monday
+	return day.AddDate(0, 0, -offset)
+}
+
+// bucketEnd returns the exclusive end of a bucket
+func bucketEnd(start time.Time, interval string) time.Time {
+	if interval == IntervalWeek {
+		return start.AddDate(0, 0, 7)
+	}
+	return start.AddDate(0, 0, 1)
+}
@@ -0,0 +1,425 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+
+	"github.com/mahigadamsetty/Inshorts-task/internal/apperr"
+	"github.com/mahigadamsetty/Inshorts-task/internal/db"
+	"github.com/mahigadamsetty/Inshorts-task/internal/llm"
+	"github.com/mahigadamsetty/Inshorts-task/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	embeddingBatchSize  = 100
+	embeddingLeadChars  = 1000 // Characters of the stored text added to the title and description
+	topicMinArticles    = 3    // Smaller clusters are too thin for a topic page
+	topicMaxIterations  = 25   // k-means stops earlier once assignments settle
+	topicLabelHeadlines = 8
+	topicKeywordCount   = 5
+)
+
+// EmbedArticles stores embeddings for articles that have none from the client's
+// current embedding model, or whose text changed since they were embedded, and
+// returns the number embedded. When the client falls
+// back to a different model the batch is kept and the rest wait for the next run.
+func (s *Services) EmbedArticles(ctx context.Context, client *llm.Client) (int, error) {
+	database := s.db
+	if database == nil {
+		return 0, fmt.Errorf("database not initialized")
+	}
+	database = database.WithContext(ctx)
+	client = client.WithContext(ctx)
+	model := client.EmbeddingModel()
+
+	embedded := 0
+	for {
+		var articles []models.Article
+		err := database.
+			Select("articles.id, articles.title, articles.description, articles.text_content, articles.text_blob_url, articles.content_hash").
+			Joins("LEFT JOIN article_embeddings ON article_embeddings.article_id = articles.id").
+			Where("article_embeddings.article_id IS NULL OR article_embeddings.model <> ? OR "+
+				"COALESCE(article_embeddings.content_hash, '') <> COALESCE(articles.content_hash, '')", model).
+			Limit(embeddingBatchSize).
+			Find(&articles).Error
+		if err != nil {
+			return embedded, err
+		}
+		if len(articles) == 0 {
+			return embedded, nil
+		}
+
+		texts := make([]string, len(articles))
+		for i, article := range articles {
+			texts[i] = s.embeddingText(ctx, article)
+		}
+		vectors, used, err := client.Embed(texts)
+		if err != nil {
+			return embedded, err
+		}
+
+		rows := make([]models.ArticleEmbedding, len(articles))
+		for i, article := range articles {
+			rows[i] = models.ArticleEmbedding{ArticleID: article.ID, Model: used, Vector: vectors[i], ContentHash: article.ContentHash}
+		}
+		if err := database.Clauses(clause.OnConflict{UpdateAll: true}).Create(&rows).Error; err != nil {
+			return embedded, err
+		}
+		embedded += len(rows)
+
+		if used != model {
+			return embedded, nil
+		}
+	}
+}
+
+// embeddingText is what an article is embedded from: its title and description,
+// followed by the start of its stored text
+func (s *Services) embeddingText(ctx context.Context, article models.Article) string {
+	text := article.Title + ". " + article.Description
+	if lead := []rune(s.articleText(ctx, article)); len(lead) > 0 {
+		if len(lead) > embeddingLeadChars {
+			lead = lead[:embeddingLeadChars]
+		}
+		text += "\n\n" + string(lead)
+	}
+	return text
+}
+
+// topicPoint is an embedded article taking part in clustering
+type topicPoint struct {
+	article models.Article
+	vector  []float32
+}
+
+// ClusterTopics groups each tenant's embedded articles into at most topicCount
+// topics with k-means over cosine similarity and labels each topic with the
+// LLM. Existing topics are rebuilt. Returns the number of topics created.
+func (s *Services) ClusterTopics(ctx context.Context, client *llm.Client, topicCount int) (int, error) {
+	database := s.db
+	if database == nil {
+		return 0, fmt.Errorf("database not initialized")
+	}
+	// Topics are published, so embargoed articles join them once visible
+	database = database.WithContext(db.VisibleOnly(ctx))
+	client = client.WithContext(ctx)
+
+	var articles []models.Article
+	if err := database.Select("id, title, publication_date, tenant_id").Find(&articles).Error; err != nil {
+		return 0, err
+	}
+	var embeddings []models.ArticleEmbedding
+	if err := database.Find(&embeddings).Error; err != nil {
+		return 0, err
+	}
+
+	byID := make(map[string]models.Article, len(articles))
+	for _, article := range articles {
+		byID[article.ID] = article
+	}
+	byTenant := map[string]map[string][]topicPoint{} // tenant -> model -> points
+	for _, embedding := range embeddings {
+		article, ok := byID[embedding.ArticleID]
+		if !ok || len(embedding.Vector) == 0 {
+			continue
+		}
+		if byTenant[article.TenantID] == nil {
+			byTenant[article.TenantID] = map[string][]topicPoint{}
+		}
+		byTenant[article.TenantID][embedding.Model] = append(byTenant[article.TenantID][embedding.Model],
+			topicPoint{article: article, vector: embedding.Vector})
+	}
+
+	type builtTopic struct {
+		topic models.Topic
+		ids   []string
+	}
+	var built []builtTopic
+	for tenantID, byModel := range byTenant {
+		points := dominantModelPoints(byModel)
+		k := topicCount
+		if limit := len(points) / topicMinArticles; k > limit {
+			k = limit
+		}
+		if k < 1 {
+			continue
+		}
+
+		for _, cluster := range kMeans(points, k) {
+			if len(cluster) < topicMinArticles {
+				continue
+			}
+			topic, ids, err := buildTopic(client, cluster)
+			if err != nil {
+				return 0, err
+			}
+			topic.TenantID = tenantID
+			built = append(built, builtTopic{topic: topic, ids: ids})
+		}
+	}
+
+	// Swap in the new topics together so readers never see a half-built set
+	err := database.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Article{}).Where("topic_id IS NOT NULL").Update("topic_id", nil).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("1 = 1").Delete(&models.Topic{}).Error; err != nil {
+			return err
+		}
+		for i := range built {
+			if err := tx.Create(&built[i].topic).Error; err != nil {
+				return err
+			}
+			if err := tx.Model(&models.Article{}).Where("id IN ?", built[i].ids).Update("topic_id", built[i].topic.ID).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return len(built), nil
+}
+
+// ListTopics returns the largest topics first
+func (s *Services) ListTopics(ctx context.Context, limit int) ([]models.Topic, error) {
+	database := s.db.WithContext(ctx)
+	if database == nil {
+		return nil, fmt.Errorf("database not initialized")
+	}
+	var topics []models.Topic
+	err := database.
+		Order("article_count DESC").
+		Order("last_published DESC").
+		Order("id").
+		Limit(limit).
+		Find(&topics).Error
+	return topics, err
+}
+
+// GetTopic returns a topic with its latest articles passing filter
+func (s *Services) GetTopic(ctx context.Context, id uint, filter ArticleFilter, limit int) (*models.Topic, []models.Article, error) {
+	database := s.db.WithContext(ctx)
+	if database == nil {
+		return nil, nil, fmt.Errorf("database not initialized")
+	}
+
+	var topic models.Topic
+	if err := database.First(&topic, id).Error; err != nil {
+		return nil, nil, apperr.NotFound(err, "Topic")
+	}
+	var articles []models.Article
+	err := database.
+		Scopes(filter.Scope).
+		Where("topic_id = ?", topic.ID).
+		Order("publication_date DESC").
+		Order("id").
+		Limit(limit).
+		Find(&articles).Error
+	if err != nil {
+		return nil, nil, err
+	}
+	s.AttachSourceMeta(ctx, articles)
+	return &topic, articles, nil
+}
+
+// dominantModelPoints returns the points embedded by the most common model, since
+// vectors from different models cannot be compared
+func dominantModelPoints(byModel map[string][]topicPoint) []topicPoint {
+	var best []topicPoint
+	for _, points := range byModel {
+		if len(points) > len(best) {
+			best = points
+		}
+	}
+	return best
+}
+
+// kMeans partitions points into k clusters by cosine similarity, seeding with
+// k-means++ from a fixed seed so reruns over the same corpus agree. Each returned
+// cluster is ordered by similarity to its centroid, closest first.
+func kMeans(points []topicPoint, k int) [][]topicPoint {
+	rng := rand.New(rand.NewSource(1))
+	centroids := make([][]float64, 0, k)
+	centroids = append(centroids, toUnit(points[rng.Intn(len(points))].vector))
+
+	// k-means++: pick each further seed with probability proportional to its distance
+	distances := make([]float64, len(points))
+	for len(centroids) < k {
+		total := 0.0
+		for i, point := range points {
+			nearest := math.Inf(1)
+			for _, centroid := range centroids {
+				nearest = math.Min(nearest, 1-cosine(point.vector, centroid))
+			}
+			distances[i] = math.Max(nearest, 0)
+			total += distances[i]
+		}
+		if total == 0 {
+			break // Every point already coincides with a seed
+		}
+		target := rng.Float64() * total
+		chosen := len(points) - 1
+		for i, d := range distances {
+			if target -= d; target <= 0 {
+				chosen = i
+				break
+			}
+		}
+		centroids = append(centroids, toUnit(points[chosen].vector))
+	}
+
+	assignments := make([]int, len(points))
+	for i := range assignments {
+		assignments[i] = -1
+	}
+	for iteration := 0; iteration < topicMaxIterations; iteration++ {
+		changed := false
+		for i, point := range points {
+			best, bestScore := 0, math.Inf(-1)
+			for c, centroid := range centroids {
+				if score := cosine(point.vector, centroid); score > bestScore {
+					best, bestScore = c, score
+				}
+			}
+			if assignments[i] != best {
+				assignments[i] = best
+				changed = true
+			}
+		}
+		if !changed {
+			break
+		}
+
+		for c := range centroids {
+			sum := make([]float64, len(centroids[c]))
+			members := 0
+			for i, point := range points {
+				if assignments[i] != c {
+					continue
+				}
+				for d, v := range point.vector {
+					if d < len(sum) {
+						sum[d] += float64(v)
+					}
+				}
+				members++
+			}
+			if members > 0 {
+				centroids[c] = normalize(sum)
+			}
+		}
+	}
+
+	clusters := make([][]topicPoint, len(centroids))
+	for i, point := range points {
+		clusters[assignments[i]] = append(clusters[assignments[i]], point)
+	}
+	for c, cluster := range clusters {
+		centroid := centroids[c]
+		sort.SliceStable(cluster, func(i, j int) bool {
+			return cosine(cluster[i].vector, centroid) > cosine(cluster[j].vector, centroid)
+		})
+	}
+	return clusters
+}
+
+// buildTopic labels a cluster and returns the topic record with its member IDs
+func buildTopic(client *llm.Client, cluster []topicPoint) (models.Topic, []string, error) {
+	ids := make([]string, len(cluster))
+	counts := map[string]int{}
+	lastPublished := cluster[0].article.PublicationDate
+	for i, point := range cluster {
+		ids[i] = point.article.ID
+		for token := range titleTokenSet(point.article.Title) {
+			counts[token]++
+		}
+		if point.article.PublicationDate.After(lastPublished) {
+			lastPublished = point.article.PublicationDate
+		}
+	}
+
+	keywords := make([]string, 0, len(counts))
+	for token := range counts {
+		keywords = append(keywords, token)
+	}
+	sort.Slice(keywords, func(i, j int) bool {
+		if counts[keywords[i]] != counts[keywords[j]] {
+			return counts[keywords[i]] > counts[keywords[j]]
+		}
+		return keywords[i] < keywords[j]
+	})
+	if len(keywords) > topicKeywordCount {
+		keywords = keywords[:topicKeywordCount]
+	}
+
+	// Members are ordered closest to the centroid first, so these are the most typical
+	headlines := make([]string, 0, topicLabelHeadlines)
+	for _, point := range cluster {
+		if len(headlines) == topicLabelHeadlines {
+			break
+		}
+		headlines = append(headlines, point.article.Title)
+	}
+
+	label, err := client.GenerateTopicLabel(headlines, keywords)
+	if err != nil {
+		return models.Topic{}, nil, err
+	}
+	if label == "" {
+		label = headlines[0]
+	}
+
+	return models.Topic{
+		Label:         label,
+		Keywords:      keywords,
+		ArticleCount:  len(cluster),
+		LastPublished: lastPublished,
+	}, ids, nil
+}
+
+// cosine computes the cosine similarity of a vector and a unit-length centroid
+func cosine(vector []float32, centroid []float64) float64 {
+	var dot, norm float64
+	for i, v := range vector {
+		if i < len(centroid) {
+			dot += float64(v) * centroid[i]
+		}
+		norm += float64(v) * float64(v)
+	}
+	if norm == 0 {
+		return 0
+	}
+	return dot / math.Sqrt(norm)
+}
+
+// toUnit converts a vector to float64 and scales it to unit length
+func toUnit(vector []float32) []float64 {
+	out := make([]float64, len(vector))
+	for i, v := range vector {
+		out[i] = float64(v)
+	}
+	return normalize(out)
+}
+
+// normalize scales a vector to unit length in place, leaving zero vectors alone
+func normalize(vector []float64) []float64 {
+	var norm float64
+	for _, v := range vector {
+		norm += v * v
+	}
+	if norm == 0 {
+		return vector
+	}
+	scale := 1 / math.Sqrt(norm)
+	for i := range vector {
+		vector[i] *= scale
+	}
+	return vector
+}
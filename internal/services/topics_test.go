@@ -0,0 +1,94 @@
+package services
+
+import (
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/mahigadamsetty/Inshorts-task/internal/models"
+)
+
+func TestKMeans(t *testing.T) {
+	point := func(id string, vector ...float32) topicPoint {
+		return topicPoint{article: models.Article{ID: id}, vector: vector}
+	}
+
+	tests := []struct {
+		name   string
+		points []topicPoint
+		k      int
+		want   []string // Member IDs of each cluster, in any order
+	}{
+		{
+			name: "separated groups",
+			points: []topicPoint{
+				point("a1", 1, 0, 0), point("b1", 0, 1, 0), point("c1", 0, 0, 1),
+				point("a2", 0.9, 0.1, 0), point("b2", 0.1, 0.9, 0), point("c2", 0, 0.1, 0.9),
+				point("a3", 0.95, 0, 0.05), point("b3", 0, 0.95, 0.05), point("c3", 0.05, 0, 0.95),
+			},
+			k:    3,
+			want: []string{"a1 a2 a3", "b1 b2 b3", "c1 c2 c3"},
+		},
+		{
+			name: "scale ignored",
+			points: []topicPoint{
+				point("a1", 10, 1), point("a2", 0.5, 0.05), point("b1", 1, 10), point("b2", 0.05, 0.5),
+			},
+			k:    2,
+			want: []string{"a1 a2", "b1 b2"},
+		},
+		{
+			name: "single cluster",
+			points: []topicPoint{
+				point("a", 1, 0), point("b", 0.8, 0.6), point("c", 0.6, 0.8),
+			},
+			k:    1,
+			want: []string{"a b c"},
+		},
+		{
+			name: "fewer distinct points than clusters",
+			points: []topicPoint{
+				point("a", 1, 1), point("b", 2, 2), point("c", 3, 3),
+			},
+			k:    3,
+			want: []string{"a b c"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clusters := kMeans(tt.points, tt.k)
+
+			got := make([]string, 0, len(clusters))
+			for _, cluster := range clusters {
+				ids := make([]string, len(cluster))
+				for i, member := range cluster {
+					ids[i] = member.article.ID
+				}
+				sort.Strings(ids)
+				got = append(got, strings.Join(ids, " "))
+			}
+			sort.Strings(got)
+			if strings.Join(got, " | ") != strings.Join(tt.want, " | ") {
+				t.Fatalf("kMeans() clusters = %q, want %q", got, tt.want)
+			}
+
+			// Members come closest to the centroid first
+			for _, cluster := range clusters {
+				sum := make([]float64, len(cluster[0].vector))
+				for _, member := range cluster {
+					for d, v := range member.vector {
+						sum[d] += float64(v)
+					}
+				}
+				centroid := normalize(sum)
+				for i := 1; i < len(cluster); i++ {
+					if cosine(cluster[i].vector, centroid) > cosine(cluster[i-1].vector, centroid)+1e-9 {
+						t.Errorf("cluster member %s is closer to the centroid than %s before it",
+							cluster[i].article.ID, cluster[i-1].article.ID)
+					}
+				}
+			}
+		})
+	}
+}
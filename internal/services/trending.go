@@ -1,85 +1,109 @@
 package services
 
 import (
+	"context"
 	"fmt"
 	"math"
 	"sort"
-	"sync"
+	"strings"
 	"time"
 
-	"github.com/mahigadamsetty/Inshorts-task/internal/db"
+	"github.com/mahigadamsetty/Inshorts-task/internal/apperr"
+	"github.com/mahigadamsetty/Inshorts-task/internal/cache"
+	"github.com/mahigadamsetty/Inshorts-task/internal/degradation"
+	"github.com/mahigadamsetty/Inshorts-task/internal/faults"
 	"github.com/mahigadamsetty/Inshorts-task/internal/models"
+	"github.com/mahigadamsetty/Inshorts-task/internal/tenant"
 	"github.com/mahigadamsetty/Inshorts-task/internal/utils"
+	"gorm.io/gorm"
 )
 
-// TrendingCache stores trending results by location cluster
+// TrendingCache stores trending results and scores by location cluster, in
+// a cache store that may be shared with other replicas
 type TrendingCache struct {
-	cache  map[string]*CacheEntry
-	mu     sync.RWMutex
-	ttl    time.Duration
-	ticker *time.Ticker
+	store cache.Store
+	ttl   time.Duration
 }
 
-type CacheEntry struct {
-	Articles  []models.Article
-	Timestamp time.Time
-}
+// staleTTLMultiplier sets how long past its TTL an entry may still be served as stale
+const staleTTLMultiplier = 2
 
-var trendingCache *TrendingCache
+// Key prefixes of trending entries in the cache store
+const (
+	trendingListPrefix   = "trending|list|"
+	trendingScoresPrefix = "trending|scores|"
+)
 
-// InitTrendingCache initializes the trending cache
-func InitTrendingCache(ttl int) {
-	trendingCache = &TrendingCache{
-		cache:  make(map[string]*CacheEntry),
-		ttl:    time.Duration(ttl) * time.Second,
-		ticker: time.NewTicker(time.Duration(ttl) * time.Second),
+// NewTrendingCache creates a trending cache whose entries expire after ttl
+// seconds, kept in store
+func NewTrendingCache(ttl int, store cache.Store) *TrendingCache {
+	return &TrendingCache{
+		store: store,
+		ttl:   time.Duration(ttl) * time.Second,
 	}
-
-	// Start cleanup goroutine
-	go trendingCache.cleanup()
 }
 
-// cleanup periodically removes expired cache entries
-func (tc *TrendingCache) cleanup() {
-	for range tc.ticker.C {
-		tc.mu.Lock()
-		now := time.Now()
-		for key, entry := range tc.cache {
-			if now.Sub(entry.Timestamp) > tc.ttl {
-				delete(tc.cache, key)
-			}
-		}
-		tc.mu.Unlock()
+// Get retrieves cached trending articles for a location cluster, or an
+// ErrCacheMiss when there are none or they have expired
+func (tc *TrendingCache) Get(ctx context.Context, key string) ([]models.Article, error) {
+	var articles []models.Article
+	if !getCached(ctx, tc.store, trendingListPrefix+key, tc.ttl, &articles) {
+		return nil, apperr.ErrCacheMiss
 	}
+	return decodedArticles(articles), nil
 }
 
-// Get retrieves cached trending articles for a location cluster
-func (tc *TrendingCache) Get(key string) ([]models.Article, bool) {
-	tc.mu.RLock()
-	defer tc.mu.RUnlock()
+// GetStale retrieves cached trending articles even if they have expired, as long
+// as they are not too old to serve. Used when recomputation fails.
+func (tc *TrendingCache) GetStale(ctx context.Context, key string) ([]models.Article, error) {
+	var articles []models.Article
+	if !getCached(ctx, tc.store, trendingListPrefix+key, tc.ttl*staleTTLMultiplier, &articles) {
+		return nil, apperr.ErrCacheMiss
+	}
+	return decodedArticles(articles), nil
+}
 
-	entry, exists := tc.cache[key]
-	if !exists {
-		return nil, false
+// decodedArticles restores the empty lists encoding turns into nil, so cached
+// articles render like computed ones
+func decodedArticles(articles []models.Article) []models.Article {
+	if articles == nil {
+		return []models.Article{}
+	}
+	for i := range articles {
+		if articles[i].Category == nil {
+			articles[i].Category = models.StringArray{}
+		}
 	}
+	return articles
+}
+
+// Set stores trending articles for a location cluster
+func (tc *TrendingCache) Set(ctx context.Context, key string, articles []models.Article) {
+	setCached(ctx, tc.store, trendingListPrefix+key, articles, tc.ttl*staleTTLMultiplier)
+}
 
-	// Check if cache entry is still valid
-	if time.Since(entry.Timestamp) > tc.ttl {
+// getScores retrieves the cached trending scores of a location cluster
+func (tc *TrendingCache) getScores(ctx context.Context, key string) (map[string]float64, bool) {
+	var scores map[string]float64
+	if !getCached(ctx, tc.store, trendingScoresPrefix+key, tc.ttl, &scores) {
 		return nil, false
 	}
-
-	return entry.Articles, true
+	return scores, true
 }
 
-// Set stores trending articles for a location cluster
-func (tc *TrendingCache) Set(key string, articles []models.Article) {
-	tc.mu.Lock()
-	defer tc.mu.Unlock()
+// setScores stores the trending scores of a location cluster
+func (tc *TrendingCache) setScores(ctx context.Context, key string, scores map[string]float64) {
+	setCached(ctx, tc.store, trendingScoresPrefix+key, scores, tc.ttl)
+}
 
-	tc.cache[key] = &CacheEntry{
-		Articles:  articles,
-		Timestamp: time.Now(),
+// InvalidateTrendingCache drops every cached trending result and score, so
+// deleted articles aren't served from the cache. With a shared cache store
+// this drops them for every replica. Returns how many entries were dropped.
+func (s *Services) InvalidateTrendingCache(ctx context.Context) int {
+	if s.trending == nil {
+		return 0
 	}
+	return dropCached(ctx, s.trending.store, trendingListPrefix) + dropCached(ctx, s.trending.store, trendingScoresPrefix)
 }
 
 // ArticleScore represents an article with its trending score
@@ -88,13 +112,43 @@ type ArticleScore struct {
 	Score     float64
 }
 
-// GetTrendingArticles calculates and returns trending articles based on user events
-func GetTrendingArticles(lat, lon float64, limit int, clusterDegrees float64) ([]models.Article, error) {
-	// Use a geospatial cluster key for caching
-	clusterKey := getClusterKey(lat, lon, clusterDegrees)
+// WeightedLocation is one of the places a blended trending list is computed
+// for, like a commuter's home and office
+type WeightedLocation struct {
+	Latitude  float64
+	Longitude float64
+	Weight    float64
+}
 
-	// Check cache first
-	if articles, found := trendingCache.Get(clusterKey); found {
+// GetTrendingArticles calculates and returns trending articles based on user events.
+// Events, articles and the cache are scoped to the tenant carried by ctx.
+func (s *Services) GetTrendingArticles(ctx context.Context, lat, lon float64, limit int, clusterDegrees float64) ([]models.Article, error) {
+	return s.GetBlendedTrendingArticles(ctx, []WeightedLocation{{Latitude: lat, Longitude: lon, Weight: 1}}, limit, clusterDegrees)
+}
+
+// GetBlendedTrendingArticles returns the articles trending across several
+// locations: each event scores for every location as it would for that
+// location alone, weighted by the location's share of the total weight.
+// Blends are cached by their clusters and weights.
+func (s *Services) GetBlendedTrendingArticles(ctx context.Context, locations []WeightedLocation, limit int, clusterDegrees float64) ([]models.Article, error) {
+	if len(locations) == 0 {
+		return nil, apperr.InvalidFilterf("At least one location is required")
+	}
+	locations, err := normalizeWeights(locations)
+	if err != nil {
+		return nil, err
+	}
+
+	// Use a geospatial cluster key for caching, namespaced by tenant
+	cluster := blendClusterKey(locations, clusterDegrees)
+	clusterKey := s.trendingKey(ctx, cluster)
+
+	// Check cache first, unless the request simulates it being unavailable
+	cacheDown := faults.CacheDown(ctx)
+	if cacheDown {
+		degradation.Record(ctx, degradation.SubsystemCache, degradation.ModeBypassed)
+	} else if articles, err := s.trending.Get(ctx, clusterKey); err == nil {
+		articles = visibleArticles(articles)
 		if len(articles) > limit {
 			return articles[:limit], nil
 		}
@@ -102,13 +156,13 @@ func GetTrendingArticles(lat, lon float64, limit int, clusterDegrees float64) ([
 	}
 
 	// --- If not in cache, calculate trending scores ---
-	database := db.GetDB()
+	database := s.db.WithContext(ctx)
 
 	// 1. Fetch recent events (e.g., last 24 hours)
 	var recentEvents []models.Event
-	err := database.Where("timestamp > ?", time.Now().Add(-24*time.Hour)).Find(&recentEvents).Error
+	err = database.Scopes(s.trafficScope(ctx)).Where("timestamp > ?", time.Now().Add(-24*time.Hour)).Find(&recentEvents).Error
 	if err != nil {
-		return nil, err
+		return s.staleOrError(ctx, clusterKey, limit, err)
 	}
 
 	if len(recentEvents) == 0 {
@@ -121,8 +175,9 @@ func GetTrendingArticles(lat, lon float64, limit int, clusterDegrees float64) ([
 	articleIDs := make(map[string]bool)
 
 	for _, event := range recentEvents {
-		score := calculateEventScore(event, lat, lon)
-		articleScores[event.ArticleID] += score
+		for _, location := range locations {
+			articleScores[event.ArticleID] += location.Weight * calculateEventScore(event, location.Latitude, location.Longitude)
+		}
 		articleIDs[event.ArticleID] = true
 	}
 
@@ -135,29 +190,145 @@ func GetTrendingArticles(lat, lon float64, limit int, clusterDegrees float64) ([
 	var articles []models.Article
 	err = database.Where("id IN ?", ids).Find(&articles).Error
 	if err != nil {
-		return nil, err
+		return s.staleOrError(ctx, clusterKey, limit, err)
 	}
 
-	// 4. Attach scores and sort
+	// 4. Attach scores and source metadata, then sort
+	s.AttachSourceMeta(ctx, articles)
 	for i := range articles {
 		articles[i].TrendingScore = articleScores[articles[i].ID]
+		articles[i].Explanation = &models.ScoreExplanation{
+			Ranker:   RankerTrending,
+			Trending: explainValue(articles[i].TrendingScore),
+			Final:    articles[i].TrendingScore,
+		}
 	}
 
 	// Sort articles by trending score in descending order
-	sort.Slice(articles, func(i, j int) bool {
-		return articles[i].TrendingScore > articles[j].TrendingScore
+	sort.SliceStable(articles, func(i, j int) bool {
+		if articles[i].TrendingScore != articles[j].TrendingScore {
+			return articles[i].TrendingScore > articles[j].TrendingScore
+		}
+		return TieBreak(articles[i], articles[j])
 	})
 
-	// Limit the results
+	// Limit the results and number them, so clients can show the rank and
+	// where it was computed
 	if len(articles) > limit {
 		articles = articles[:limit]
 	}
+	for i := range articles {
+		articles[i].TrendingRank = i + 1
+		articles[i].TrendingCluster = cluster
+	}
+
+	if !cacheDown {
+		s.trending.Set(ctx, clusterKey, articles)
+	}
+
+	return articles, nil
+}
+
+// TrendingScores returns a lookup of each article's current trending score in
+// the location cluster containing lat/lon, scored from the cluster's center so
+// every user in the cluster sees the same values. Articles without recent
+// events score 0. Scores are cached per tenant and cluster like trending lists.
+func (s *Services) TrendingScores(ctx context.Context, lat, lon, clusterDegrees float64) (ScoreLookup, error) {
+	clusterKey := s.trendingKey(ctx, getClusterKey(lat, lon, clusterDegrees))
+	cacheDown := faults.CacheDown(ctx)
+	var scores map[string]float64
+	found := false
+	if cacheDown {
+		degradation.Record(ctx, degradation.SubsystemCache, degradation.ModeBypassed)
+	} else {
+		scores, found = s.trending.getScores(ctx, clusterKey)
+	}
+	if !found {
+		centerLat := math.Round(lat/clusterDegrees) * clusterDegrees
+		centerLon := math.Round(lon/clusterDegrees) * clusterDegrees
+
+		var recentEvents []models.Event
+		err := s.db.WithContext(ctx).
+			Select("article_id, event_type, latitude, longitude, timestamp").
+			Scopes(s.trafficScope(ctx)).
+			Where("timestamp > ?", time.Now().Add(-24*time.Hour)).
+			Find(&recentEvents).Error
+		if err != nil {
+			return nil, err
+		}
+
+		scores = make(map[string]float64)
+		for _, event := range recentEvents {
+			scores[event.ArticleID] += calculateEventScore(event, centerLat, centerLon)
+		}
+		if !cacheDown {
+			s.trending.setScores(ctx, clusterKey, scores)
+		}
+	}
 
-	trendingCache.Set(clusterKey, articles)
+	return func(articleID string) float64 {
+		return scores[articleID]
+	}, nil
+}
+
+// trendingKey is the cache key of a cluster's trending, namespaced by tenant
+// and by whether simulated events count
+func (s *Services) trendingKey(ctx context.Context, cluster string) string {
+	key := tenant.IDFromContext(ctx) + "|" + cluster
+	if s.includesSimulated(ctx) {
+		key += "|simulated"
+	}
+	return key
+}
+
+// trafficScope leaves simulated events out of an events query unless
+// trending and analytics computed with the context count them
+func (s *Services) trafficScope(ctx context.Context) func(*gorm.DB) *gorm.DB {
+	return func(database *gorm.DB) *gorm.DB {
+		if s.includesSimulated(ctx) {
+			return database
+		}
+		return database.Where("events.simulated = ?", false)
+	}
+}
 
+// staleOrError serves an expired cache entry when recomputing trending fails,
+// recording the stale cache in the request's degradation report
+func (s *Services) staleOrError(ctx context.Context, clusterKey string, limit int, err error) ([]models.Article, error) {
+	if faults.CacheDown(ctx) {
+		return nil, err
+	}
+	articles, staleErr := s.trending.GetStale(ctx, clusterKey)
+	if staleErr != nil {
+		return nil, err
+	}
+	degradation.Record(ctx, degradation.SubsystemCache, degradation.ModeStale)
+	articles = visibleArticles(articles)
+	if len(articles) > limit {
+		return articles[:limit], nil
+	}
 	return articles, nil
 }
 
+// visibleArticles drops cached articles that expired since they were cached,
+// without touching the cached list
+func visibleArticles(articles []models.Article) []models.Article {
+	now := time.Now()
+	for i, article := range articles {
+		if article.Visible(now) {
+			continue
+		}
+		visible := append([]models.Article{}, articles[:i]...)
+		for _, article := range articles[i+1:] {
+			if article.Visible(now) {
+				visible = append(visible, article)
+			}
+		}
+		return visible
+	}
+	return articles
+}
+
 // calculateEventScore computes a score for a single user event
 func calculateEventScore(event models.Event, userLat, userLon float64) float64 {
 	// Base score for event type
@@ -172,14 +343,80 @@ func calculateEventScore(event models.Event, userLat, userLon float64) float64 {
 
 	// Location proximity factor
 	distance := utils.HaversineDistance(userLat, userLon, event.Latitude, event.Longitude)
-	locationFactor := math.Exp(-0.05 * distance) // Closer events get higher score
+	locationFactor := GeoRelevance(distance) // Closer events get higher score
 
 	return baseScore * timeDecay * locationFactor
 }
 
+// normalizeWeights scales location weights to sum to 1, so a blend scores on
+// the same scale as a single location
+func normalizeWeights(locations []WeightedLocation) ([]WeightedLocation, error) {
+	total := 0.0
+	for _, location := range locations {
+		if location.Weight <= 0 || math.IsNaN(location.Weight) || math.IsInf(location.Weight, 0) {
+			return nil, apperr.InvalidFilterf("Location weights must be positive")
+		}
+		total += location.Weight
+	}
+	normalized := make([]WeightedLocation, len(locations))
+	for i, location := range locations {
+		location.Weight /= total
+		normalized[i] = location
+	}
+	return normalized, nil
+}
+
+// blendClusterKey creates a string key for a blend of locations: the cluster
+// key of a single location, or each location's cluster with its weight, in a
+// fixed order so the same blend hits the same cache entry
+func blendClusterKey(locations []WeightedLocation, clusterDegrees float64) string {
+	if len(locations) == 1 {
+		return getClusterKey(locations[0].Latitude, locations[0].Longitude, clusterDegrees)
+	}
+	parts := make([]string, len(locations))
+	for i, location := range locations {
+		parts[i] = fmt.Sprintf("%s@%.2f", getClusterKey(location.Latitude, location.Longitude, clusterDegrees), location.Weight)
+	}
+	sort.Strings(parts)
+	return strings.Join(parts, "|")
+}
+
 // getClusterKey creates a string key for a geographic cluster.
 func getClusterKey(lat, lon, clusterDegrees float64) string {
 	latCluster := math.Round(lat/clusterDegrees) * clusterDegrees
 	lonCluster := math.Round(lon/clusterDegrees) * clusterDegrees
 	return fmt.Sprintf("%.2f,%.2f", latCluster, lonCluster)
 }
+
+// CompareTrending splits per-location trending lists into the article IDs that
+// trend at every location and, for each location, the IDs trending only there.
+// IDs keep the order of the lists they come from.
+func CompareTrending(lists [][]models.Article) (common []string, unique [][]string) {
+	seenAt := make(map[string]int) // Number of lists each article appears in
+	for _, articles := range lists {
+		listed := make(map[string]bool, len(articles))
+		for _, article := range articles {
+			if !listed[article.ID] {
+				listed[article.ID] = true
+				seenAt[article.ID]++
+			}
+		}
+	}
+
+	common = []string{}
+	unique = make([][]string, len(lists))
+	for i, articles := range lists {
+		unique[i] = []string{}
+		for _, article := range articles {
+			switch seenAt[article.ID] {
+			case len(lists):
+				if i == 0 {
+					common = append(common, article.ID)
+				}
+			case 1:
+				unique[i] = append(unique[i], article.ID)
+			}
+		}
+	}
+	return common, unique
+}
@@ -0,0 +1,253 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/mahigadamsetty/Inshorts-task/internal/models"
+	"github.com/mahigadamsetty/Inshorts-task/internal/tenant"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// usageMonthLayout formats the UTC month usage is metered in
+const usageMonthLayout = "2006-01"
+
+// UsageCounts is what a tenant consumed in a month
+type UsageCounts struct {
+	Requests  int64 `json:"requests"`
+	LLMTokens int64 `json:"llm_tokens"`
+	Events    int64 `json:"events"`
+}
+
+// of returns the count of a kind of usage
+func (u *UsageCounts) of(kind string) *int64 {
+	switch kind {
+	case tenant.QuotaRequests:
+		return &u.Requests
+	case tenant.QuotaLLMTokens:
+		return &u.LLMTokens
+	default:
+		return &u.Events
+	}
+}
+
+// plus returns the counts with sign times other's added
+func (u UsageCounts) plus(other UsageCounts, sign int64) UsageCounts {
+	return UsageCounts{
+		Requests:  u.Requests + sign*other.Requests,
+		LLMTokens: u.LLMTokens + sign*other.LLMTokens,
+		Events:    u.Events + sign*other.Events,
+	}
+}
+
+type usageKey struct {
+	tenantID string
+	month    string
+}
+
+// UsageMeter counts each tenant's monthly usage in memory, on top of the
+// counts stored when it last flushed, and periodically adds it to the
+// api_usage table
+type UsageMeter struct {
+	stored  map[usageKey]UsageCounts
+	pending map[usageKey]*UsageCounts
+	mu      sync.Mutex
+}
+
+// InitUsage starts flushing metered usage every interval seconds. With a
+// non-positive interval, usage is only written by FlushUsage.
+func (s *Services) InitUsage(interval int) {
+	if interval <= 0 {
+		return
+	}
+
+	go func() {
+		for range time.Tick(time.Duration(interval) * time.Second) {
+			if _, err := s.FlushUsage(context.Background()); err != nil {
+				log.Printf("Failed to flush API usage: %v", err)
+			}
+		}
+	}()
+}
+
+// ConsumeQuota counts n units of a kind of usage against the monthly quota of
+// the context's tenant. When they would exceed it, nothing is counted and a
+// *tenant.QuotaError is returned. Contexts without a tenant aren't metered.
+func (s *Services) ConsumeQuota(ctx context.Context, kind string, n int64) error {
+	t, ok := tenant.FromContext(ctx)
+	if !ok {
+		return nil
+	}
+	now := time.Now()
+	key := usageKey{tenantID: t.ID, month: now.UTC().Format(usageMonthLayout)}
+	stored, err := s.storedUsage(ctx, key)
+	if err != nil {
+		// Metering must not take the API down with the database
+		log.Printf("Failed to load API usage of %s: %v", t.ID, err)
+	}
+
+	s.usage.mu.Lock()
+	defer s.usage.mu.Unlock()
+	pending := s.usage.pending[key]
+	if pending == nil {
+		pending = &UsageCounts{}
+		s.usage.pending[key] = pending
+	}
+	if quota := int64(t.Quota(kind)); quota > 0 && *stored.of(kind)+*pending.of(kind)+n > quota {
+		return &tenant.QuotaError{Quota: kind, Limit: int(quota), ResetsAt: tenant.MonthStart(now).AddDate(0, 1, 0)}
+	}
+	*pending.of(kind) += n
+	return nil
+}
+
+// QuotaLeft reports whether the context's tenant has some of its monthly
+// quota of a kind of usage left
+func (s *Services) QuotaLeft(ctx context.Context, kind string) bool {
+	t, ok := tenant.FromContext(ctx)
+	if !ok || t.Quota(kind) == 0 {
+		return true
+	}
+	usage, err := s.GetUsage(ctx, t.ID, time.Now())
+	if err != nil {
+		log.Printf("Failed to load API usage of %s: %v", t.ID, err)
+		return true
+	}
+	return *usage.of(kind) < int64(t.Quota(kind))
+}
+
+// RecordUsage counts n units of a kind of usage for the context's tenant
+// without checking its quota, for usage only known once it happened, like LLM
+// tokens
+func (s *Services) RecordUsage(ctx context.Context, kind string, n int64) {
+	t, ok := tenant.FromContext(ctx)
+	if !ok || n <= 0 {
+		return
+	}
+	key := usageKey{tenantID: t.ID, month: time.Now().UTC().Format(usageMonthLayout)}
+
+	s.usage.mu.Lock()
+	defer s.usage.mu.Unlock()
+	pending := s.usage.pending[key]
+	if pending == nil {
+		pending = &UsageCounts{}
+		s.usage.pending[key] = pending
+	}
+	*pending.of(kind) += n
+}
+
+// MeterRequest counts a request against the monthly request quota of the
+// context's tenant
+func (s *Services) MeterRequest(ctx context.Context) error {
+	return s.ConsumeQuota(ctx, tenant.QuotaRequests, 1)
+}
+
+// GetUsage returns a tenant's usage in the UTC month of at, including the
+// usage not flushed yet
+func (s *Services) GetUsage(ctx context.Context, tenantID string, at time.Time) (UsageCounts, error) {
+	key := usageKey{tenantID: tenantID, month: at.UTC().Format(usageMonthLayout)}
+	usage, err := s.storedUsage(ctx, key)
+	if err != nil {
+		return UsageCounts{}, err
+	}
+
+	s.usage.mu.Lock()
+	defer s.usage.mu.Unlock()
+	if pending := s.usage.pending[key]; pending != nil {
+		usage = usage.plus(*pending, 1)
+	}
+	return usage, nil
+}
+
+// storedUsage returns the stored usage of a tenant in a month, loading it on
+// first use
+func (s *Services) storedUsage(ctx context.Context, key usageKey) (UsageCounts, error) {
+	s.usage.mu.Lock()
+	usage, ok := s.usage.stored[key]
+	s.usage.mu.Unlock()
+	if ok {
+		return usage, nil
+	}
+
+	database := s.db
+	if database == nil {
+		return UsageCounts{}, fmt.Errorf("database not initialized")
+	}
+	var row models.APIUsage
+	err := database.WithContext(ctx).Where("tenant_id = ? AND month = ?", key.tenantID, key.month).First(&row).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return UsageCounts{}, err
+	}
+	usage = UsageCounts{Requests: row.Requests, LLMTokens: row.LLMTokens, Events: row.Events}
+
+	s.usage.mu.Lock()
+	s.usage.stored[key] = usage
+	s.usage.mu.Unlock()
+	return usage, nil
+}
+
+// FlushUsage adds the pending usage to the stored counts, reloading them so
+// usage metered by other instances counts towards quotas too, and returns the
+// number of tenant months updated. Usage that fails to save is kept for the
+// next flush.
+func (s *Services) FlushUsage(ctx context.Context) (int, error) {
+	database := s.db
+	if database == nil {
+		return 0, fmt.Errorf("database not initialized")
+	}
+
+	// Move the pending usage to the stored counts right away, so quotas keep
+	// counting it while it is written
+	s.usage.mu.Lock()
+	pending := s.usage.pending
+	s.usage.pending = make(map[usageKey]*UsageCounts)
+	for key, delta := range pending {
+		if stored, ok := s.usage.stored[key]; ok {
+			s.usage.stored[key] = stored.plus(*delta, 1)
+		}
+	}
+	s.usage.mu.Unlock()
+
+	flushed := 0
+	var firstErr error
+	for key, delta := range pending {
+		row := models.APIUsage{TenantID: key.tenantID, Month: key.month, Requests: delta.Requests, LLMTokens: delta.LLMTokens, Events: delta.Events}
+		err := database.WithContext(ctx).Clauses(clause.OnConflict{
+			Columns: []clause.Column{{Name: "tenant_id"}, {Name: "month"}},
+			DoUpdates: clause.Assignments(map[string]interface{}{
+				"requests":   gorm.Expr("api_usage.requests + ?", delta.Requests),
+				"llm_tokens": gorm.Expr("api_usage.llm_tokens + ?", delta.LLMTokens),
+				"events":     gorm.Expr("api_usage.events + ?", delta.Events),
+				"updated_at": time.Now(),
+			}),
+		}).Create(&row).Error
+		if err == nil {
+			err = database.WithContext(ctx).Where("tenant_id = ? AND month = ?", key.tenantID, key.month).First(&row).Error
+		}
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			s.usage.mu.Lock()
+			if stored, ok := s.usage.stored[key]; ok {
+				s.usage.stored[key] = stored.plus(*delta, -1)
+			}
+			if current := s.usage.pending[key]; current != nil {
+				*delta = delta.plus(*current, 1)
+			}
+			s.usage.pending[key] = delta
+			s.usage.mu.Unlock()
+			continue
+		}
+
+		s.usage.mu.Lock()
+		s.usage.stored[key] = UsageCounts{Requests: row.Requests, LLMTokens: row.LLMTokens, Events: row.Events}
+		s.usage.mu.Unlock()
+		flushed++
+	}
+	return flushed, firstErr
+}
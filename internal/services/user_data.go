@@ -0,0 +1,377 @@
+package services
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/mahigadamsetty/Inshorts-task/internal/apperr"
+	"github.com/mahigadamsetty/Inshorts-task/internal/blob"
+	"github.com/mahigadamsetty/Inshorts-task/internal/models"
+	"github.com/mahigadamsetty/Inshorts-task/internal/tenant"
+	"github.com/mahigadamsetty/Inshorts-task/internal/utils"
+	"github.com/mahigadamsetty/Inshorts-task/pkg/signing"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const userDataBatchSize = 500
+
+// ErrDataRequestRunning is returned when an API key asks for an export or
+// deletion while another of its requests is still running
+var ErrDataRequestRunning = apperr.Conflictf("another data request is still running")
+
+// DataRequests exports and deletes the data linked to an API key in the
+// background: interaction events, search history, geofences with their
+// alerts, view counters and /query conversations. Articles are news content
+// rather than personal data and are left alone. The caller's webhook is
+// notified through the outbox when a request finishes.
+type DataRequests struct {
+	svc     *Services
+	exports blob.Store
+}
+
+// NewDataRequests creates a data request runner over the data of svc, keeping
+// exports in a store
+func NewDataRequests(svc *Services, exports blob.Store) *DataRequests {
+	return &DataRequests{svc: svc, exports: exports}
+}
+
+// Start marks requests left running by a previous process as interrupted
+func (d *DataRequests) Start(ctx context.Context) {
+	err := d.svc.db.WithContext(ctx).Model(&models.DataRequest{}).
+		Where("status = ?", models.DataRequestRunning).
+		Update("status", models.DataRequestInterrupted).Error
+	if err != nil {
+		log.Printf("Failed to mark interrupted data requests: %v", err)
+	}
+}
+
+// Submit records an export or deletion for the tenant in ctx and runs it in
+// the background. An empty webhook URL skips the completion notification. The
+// secret signs the webhook and the export download; an empty one is generated.
+func (d *DataRequests) Submit(ctx context.Context, kind, webhookURL, secret string) (*models.DataRequest, error) {
+	if kind != models.DataRequestExport && kind != models.DataRequestDelete {
+		return nil, apperr.InvalidFilterf("unknown data request kind %q", kind)
+	}
+	webhookURL = strings.TrimSpace(webhookURL)
+	if webhookURL != "" {
+		if err := d.svc.ValidateWebhookURL(ctx, webhookURL); err != nil {
+			return nil, err
+		}
+	}
+	if secret == "" && (webhookURL != "" || kind == models.DataRequestExport) {
+		secret = NewSessionID()
+	}
+
+	// The partial unique index on the tenant's running request turns a
+	// concurrent submission into a skipped insert
+	request := &models.DataRequest{
+		Kind:       kind,
+		Status:     models.DataRequestRunning,
+		WebhookURL: webhookURL,
+		Secret:     secret,
+	}
+	result := d.svc.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(request)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrDataRequestRunning
+	}
+	snapshot := *request
+
+	// Keep the tenant but not the request's cancellation
+	go d.run(context.WithoutCancel(ctx), request)
+	return &snapshot, nil
+}
+
+// GetDataRequest returns a data request of the tenant in ctx by ID
+func (s *Services) GetDataRequest(ctx context.Context, id uint) (*models.DataRequest, error) {
+	var request models.DataRequest
+	if err := s.db.WithContext(ctx).First(&request, id).Error; err != nil {
+		return nil, apperr.NotFound(err, "Request")
+	}
+	return &request, nil
+}
+
+// run carries out a request and records its outcome, with the webhook's
+// notification in the same transaction
+func (d *DataRequests) run(ctx context.Context, request *models.DataRequest) {
+	var err error
+	if request.Kind == models.DataRequestExport {
+		request.ExportPath, request.Records, err = d.export(ctx, request.ID)
+	} else {
+		request.Records, err = d.purge(ctx)
+	}
+
+	now := time.Now()
+	request.FinishedAt = &now
+	request.Status = models.DataRequestCompleted
+	if err != nil {
+		log.Printf("Data request %d failed: %v", request.ID, err)
+		request.Status = models.DataRequestFailed
+		request.Error = err.Error()
+	}
+	err = d.svc.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(request).Error; err != nil {
+			return err
+		}
+		if request.WebhookURL == "" {
+			return nil
+		}
+		body, err := json.Marshal(request)
+		if err != nil {
+			return err
+		}
+		return EnqueueOutbox(tx, &models.OutboxMessage{
+			Channel:   models.ChannelWebhook,
+			Subject:   models.SubjectDataRequest,
+			SubjectID: request.ID,
+			Target:    request.WebhookURL,
+			Headers:   map[string]string{"X-Data-Request-ID": fmt.Sprint(request.ID)},
+			Body:      string(body),
+			Secret:    request.Secret,
+			TenantID:  request.TenantID,
+		})
+	})
+	if err != nil {
+		log.Printf("Failed to save data request %d: %v", request.ID, err)
+		return
+	}
+	WakeOutbox()
+}
+
+// mirrorDataRequestDelivery copies the delivery state of a request's webhook
+// notification to the request
+func mirrorDataRequestDelivery(tx *gorm.DB, message *models.OutboxMessage) error {
+	return tx.Model(&models.DataRequest{}).Where("id = ?", message.SubjectID).Updates(map[string]interface{}{
+		"webhook_delivered_at": message.DeliveredAt,
+		"webhook_error":        message.LastError,
+	}).Error
+}
+
+// export writes the tenant's data to a JSON file, one array per kind of
+// data, and stores it, returning the blob's URL. The file is written to a
+// temporary file first, since stores need its size up front.
+func (d *DataRequests) export(ctx context.Context, id uint) (string, map[string]int64, error) {
+	file, err := os.CreateTemp("", "export-*.json")
+	if err != nil {
+		return "", nil, err
+	}
+	defer os.Remove(file.Name())
+	defer file.Close()
+
+	records, err := d.svc.writeExport(ctx, file)
+	if err != nil {
+		return "", nil, err
+	}
+	size, err := file.Seek(0, io.SeekCurrent)
+	if err == nil {
+		_, err = file.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		return "", nil, err
+	}
+	ref, err := d.exports.Put(ctx, fmt.Sprintf("export-%d.json", id), file, size, "application/json")
+	if err != nil {
+		return "", nil, err
+	}
+	return ref, records, nil
+}
+
+// writeExport streams the tenant's data as one JSON object
+func (s *Services) writeExport(ctx context.Context, file *os.File) (map[string]int64, error) {
+	w := bufio.NewWriter(file)
+	header, err := json.Marshal(map[string]interface{}{
+		"tenant":      tenant.IDFromContext(ctx),
+		"exported_at": time.Now(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	w.Write(header[:len(header)-1]) // Leave the object open for the data arrays
+
+	database := s.db.WithContext(ctx)
+	coordinates := s.ResponseCoordinates(ctx)
+	records := make(map[string]int64)
+	sections := []struct {
+		name  string
+		write func() (int64, error)
+	}{
+		{"events", func() (int64, error) { return exportRows[models.Event](database, w, coordinates) }},
+		{"search_logs", func() (int64, error) { return exportRows[models.SearchLog](database, w, coordinates) }},
+		{"geofences", func() (int64, error) { return exportRows[models.Geofence](database, w, coordinates) }},
+		{"geofence_alerts", func() (int64, error) { return exportRows[models.GeofenceAlert](database, w, coordinates) }},
+		{"article_views", func() (int64, error) { return exportRows[models.ArticleViews](database, w, coordinates) }},
+		{"shadow_comparisons", func() (int64, error) { return exportRows[models.ShadowComparison](database, w, coordinates) }},
+		{"shadow_clicks", func() (int64, error) { return exportRows[models.ShadowClick](database, w, coordinates) }},
+		{"reads", func() (int64, error) { return exportRows[models.Read](database, w, coordinates) }},
+		{"sessions", func() (int64, error) { return exportRows[models.Session](database, w, coordinates) }},
+		{"impressions", func() (int64, error) { return exportRows[models.Impression](database, w, coordinates) }},
+		{"article_attractiveness", func() (int64, error) { return exportRows[models.ArticleAttractiveness](database, w, coordinates) }},
+		{"discovery_pulls", func() (int64, error) { return exportRows[models.DiscoveryPull](database, w, coordinates) }},
+		{"discovery_arms", func() (int64, error) { return exportRows[models.DiscoveryArm](database, w, coordinates) }},
+		{"short_links", func() (int64, error) { return exportRows[models.ShortLink](database, w, coordinates) }},
+		{"outbox_messages", func() (int64, error) { return exportRows[models.OutboxMessage](database, w, coordinates) }},
+		{"recorded_requests", func() (int64, error) { return exportRows[models.RecordedRequest](database, w, coordinates) }},
+	}
+	for _, section := range sections {
+		fmt.Fprintf(w, ",%q:", section.name)
+		count, err := section.write()
+		if err != nil {
+			return nil, fmt.Errorf("export %s: %w", section.name, err)
+		}
+		records[section.name] = count
+	}
+	w.WriteString("}\n")
+	return records, w.Flush()
+}
+
+// coordinateLimiter is a model whose JSON can give out its coordinates less
+// precisely
+type coordinateLimiter interface {
+	LimitCoordinates(limit *utils.CoordinateLimit)
+}
+
+// exportRows writes every row of a model visible to the database's tenant as
+// a JSON array, reading in batches. Coordinates are given out as coordinates
+// says.
+func exportRows[T any](database *gorm.DB, w *bufio.Writer, coordinates *utils.CoordinateLimit) (int64, error) {
+	var batch []T
+	var count int64
+	w.WriteByte('[')
+	err := database.Model(new(T)).FindInBatches(&batch, userDataBatchSize, func(tx *gorm.DB, _ int) error {
+		for _, row := range batch {
+			if limiter, ok := any(&row).(coordinateLimiter); ok {
+				limiter.LimitCoordinates(coordinates)
+			}
+			data, err := json.Marshal(row)
+			if err != nil {
+				return err
+			}
+			if count > 0 {
+				w.WriteByte(',')
+			}
+			w.Write(data)
+			count++
+		}
+		return nil
+	}).Error
+	w.WriteByte(']')
+	return count, err
+}
+
+// purge deletes the tenant's data and any exports made of it
+func (d *DataRequests) purge(ctx context.Context) (map[string]int64, error) {
+	tenantID := tenant.IDFromContext(ctx)
+	if tenantID == "" {
+		return nil, fmt.Errorf("no tenant to delete data for")
+	}
+
+	// Write queued events, pending view counts and impressions first so they
+	// don't recreate records afterwards
+	if _, err := d.svc.FlushEvents(ctx); err != nil {
+		return nil, err
+	}
+	if _, err := d.svc.FlushViewCounts(ctx); err != nil {
+		return nil, err
+	}
+	if _, err := d.svc.FlushImpressions(ctx); err != nil {
+		return nil, err
+	}
+
+	records := make(map[string]int64)
+	err := d.svc.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for _, table := range []struct {
+			name  string
+			model interface{}
+		}{
+			{"outbox_messages", &models.OutboxMessage{}},
+			{"geofence_alerts", &models.GeofenceAlert{}},
+			{"geofences", &models.Geofence{}},
+			{"events", &models.Event{}},
+			{"search_logs", &models.SearchLog{}},
+			{"article_views", &models.ArticleViews{}},
+			{"shadow_clicks", &models.ShadowClick{}},
+			{"shadow_comparisons", &models.ShadowComparison{}},
+			{"reads", &models.Read{}},
+			{"sessions", &models.Session{}},
+			{"impressions", &models.Impression{}},
+			{"article_attractiveness", &models.ArticleAttractiveness{}},
+			{"discovery_pulls", &models.DiscoveryPull{}},
+			{"discovery_arms", &models.DiscoveryArm{}},
+			{"short_link_clicks", &models.ShortLinkClicks{}},
+			{"short_links", &models.ShortLink{}},
+			{"recorded_requests", &models.RecordedRequest{}},
+		} {
+			result := tx.Where("tenant_id = ?", tenantID).Delete(table.model)
+			if result.Error != nil {
+				return fmt.Errorf("delete %s: %w", table.name, result.Error)
+			}
+			records[table.name] = result.RowsAffected
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	records["conversations"] = int64(d.svc.PurgeConversations(ctx))
+
+	var exports []models.DataRequest
+	err = d.svc.db.WithContext(ctx).Where("kind = ? AND export_path <> ''", models.DataRequestExport).Find(&exports).Error
+	if err != nil {
+		return records, err
+	}
+	for _, export := range exports {
+		if err := d.exports.Delete(ctx, export.ExportPath); err != nil {
+			return records, err
+		}
+		if err := d.svc.db.WithContext(ctx).Model(&export).Update("export_path", "").Error; err != nil {
+			return records, err
+		}
+	}
+	records["exports"] = int64(len(exports))
+	return records, nil
+}
+
+// ExportFile opens the file of a completed export of the tenant in ctx,
+// setting the headers that sign its content. The caller closes it.
+func (d *DataRequests) ExportFile(ctx context.Context, id uint, header http.Header) (io.ReadCloser, error) {
+	request, err := d.svc.GetDataRequest(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if request.Kind != models.DataRequestExport || request.Status != models.DataRequestCompleted || request.ExportPath == "" {
+		return nil, apperr.NotFoundf("No completed export with this id")
+	}
+	if request.Secret != "" {
+		file, err := d.openExport(ctx, request.ExportPath)
+		if err != nil {
+			return nil, err
+		}
+		err = signing.SetHeadersFrom(header, request.Secret, file)
+		file.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return d.openExport(ctx, request.ExportPath)
+}
+
+// openExport opens a stored export, reporting a missing file as not found
+func (d *DataRequests) openExport(ctx context.Context, ref string) (io.ReadCloser, error) {
+	file, err := d.exports.Open(ctx, ref)
+	if errors.Is(err, blob.ErrNotExist) {
+		return nil, apperr.NotFoundf("No completed export with this id")
+	}
+	return file, err
+}
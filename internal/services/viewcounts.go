@@ -0,0 +1,259 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/mahigadamsetty/Inshorts-task/internal/hll"
+	"github.com/mahigadamsetty/Inshorts-task/internal/models"
+	"github.com/mahigadamsetty/Inshorts-task/internal/platform"
+	"gorm.io/gorm"
+)
+
+// viewDelta is the not yet flushed activity for one article
+type viewDelta struct {
+	tenantID string
+	views    int64
+	clicks   int64
+	viewers  hll.Sketch
+}
+
+// ViewCounter buffers per-article view counts and viewer sketches in memory and
+// periodically folds them into the article_views table
+type ViewCounter struct {
+	pending map[string]*viewDelta // Keyed by article ID
+	mu      sync.Mutex
+}
+
+// InitViewCounter initializes the view counter, flushing every interval seconds.
+// With a non-positive interval, counts are only written by FlushViewCounts.
+func (s *Services) InitViewCounter(interval int) {
+	s.views = &ViewCounter{pending: make(map[string]*viewDelta)}
+	if interval <= 0 {
+		return
+	}
+
+	go func() {
+		for range time.Tick(time.Duration(interval) * time.Second) {
+			if _, err := s.FlushViewCounts(context.Background()); err != nil {
+				log.Printf("Failed to flush view counts: %v", err)
+			}
+		}
+	}()
+}
+
+// RecordEvent stores an interaction event and counts it towards the article's
+// totals. The client ID is only used hashed, for the unique-viewer sketch and to
+// attribute clicks to impressions and shadow ranking comparisons, and the
+// event's coordinates are truncated to the configured precision. Simulated
+// events are only stored unless the context counts them.
+func (s *Services) RecordEvent(ctx context.Context, event *models.Event, clientID string) error {
+	event.Latitude, event.Longitude = s.PrivateLocation(event.Latitude, event.Longitude, true)
+	if event.Platform == "" {
+		event.Platform = platform.FromContext(ctx)
+	}
+	if err := s.db.WithContext(ctx).Create(event).Error; err != nil {
+		return err
+	}
+	if event.Simulated && !s.includesSimulated(ctx) {
+		return nil
+	}
+	s.countEvent(event.TenantID, event.ArticleID, event.EventType, clientID)
+	if event.EventType == models.EventTypeClick {
+		s.recordImpressionClick(ctx, event, clientID)
+		s.recordShadowClick(ctx, event, clientID)
+		s.recordDiscoveryClick(ctx, event, clientID)
+	}
+	return nil
+}
+
+// countEvent adds one event to the pending counts
+func (s *Services) countEvent(tenantID, articleID string, eventType models.EventType, clientID string) {
+	if s.views == nil {
+		return
+	}
+	s.views.mu.Lock()
+	defer s.views.mu.Unlock()
+
+	delta, ok := s.views.pending[articleID]
+	if !ok {
+		delta = &viewDelta{tenantID: tenantID, viewers: hll.New()}
+		s.views.pending[articleID] = delta
+	}
+	if eventType == models.EventTypeClick {
+		delta.clicks++
+	} else {
+		delta.views++
+	}
+	if clientID != "" {
+		delta.viewers.Add(hll.Hash(tenantID, clientID))
+	}
+}
+
+// FlushViewCounts merges the pending counts into the stored counters and returns
+// the number of articles updated. Counts that fail to save are kept for the next flush.
+func (s *Services) FlushViewCounts(ctx context.Context) (int, error) {
+	if s.views == nil {
+		return 0, nil
+	}
+	database := s.db
+	if database == nil {
+		return 0, fmt.Errorf("database not initialized")
+	}
+
+	s.views.mu.Lock()
+	pending := s.views.pending
+	s.views.pending = make(map[string]*viewDelta)
+	s.views.mu.Unlock()
+
+	flushed := 0
+	var firstErr error
+	for articleID, delta := range pending {
+		err := database.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			stored := models.ArticleViews{ArticleID: articleID, TenantID: delta.tenantID}
+			if err := tx.Where("article_id = ?", articleID).First(&stored).Error; err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+				return err
+			}
+
+			sketch := hll.Sketch(stored.Sketch)
+			if !sketch.Valid() {
+				sketch = hll.New()
+			}
+			sketch.Merge(delta.viewers)
+
+			stored.Views += delta.views
+			stored.Clicks += delta.clicks
+			stored.Sketch = sketch
+			stored.UniqueViewers = int64(sketch.Estimate())
+			return tx.Save(&stored).Error
+		})
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			s.requeueViewDelta(articleID, delta)
+			continue
+		}
+		flushed++
+	}
+	return flushed, firstErr
+}
+
+// requeueViewDelta puts counts that failed to flush back into the buffer
+func (s *Services) requeueViewDelta(articleID string, delta *viewDelta) {
+	s.views.mu.Lock()
+	defer s.views.mu.Unlock()
+
+	current, ok := s.views.pending[articleID]
+	if !ok {
+		s.views.pending[articleID] = delta
+		return
+	}
+	current.views += delta.views
+	current.clicks += delta.clicks
+	current.viewers.Merge(delta.viewers)
+}
+
+// ViewTotals summarizes interactions across all of a tenant's articles
+type ViewTotals struct {
+	Views         int64 `json:"views"`
+	Clicks        int64 `json:"clicks"`
+	UniqueViewers int64 `json:"unique_viewers"` // Distinct viewers across all articles, not a sum
+	Articles      int64 `json:"articles"`       // Articles with at least one event
+}
+
+// GetViewTotals returns the interaction totals for the tenant in ctx. Unique
+// viewers are estimated by merging every article's sketch.
+func (s *Services) GetViewTotals(ctx context.Context) (ViewTotals, error) {
+	var rows []models.ArticleViews
+	if err := s.db.WithContext(ctx).Select("views, clicks, sketch").Find(&rows).Error; err != nil {
+		return ViewTotals{}, err
+	}
+
+	totals := ViewTotals{Articles: int64(len(rows))}
+	union := hll.New()
+	for _, row := range rows {
+		totals.Views += row.Views
+		totals.Clicks += row.Clicks
+		union.Merge(row.Sketch)
+	}
+	totals.UniqueViewers = int64(union.Estimate())
+	return totals, nil
+}
+
+// GetArticleViews returns the counters for one article, zero when it has no events yet
+func (s *Services) GetArticleViews(ctx context.Context, articleID string) (*models.ArticleViews, error) {
+	views := models.ArticleViews{ArticleID: articleID}
+	err := s.db.WithContext(ctx).Where("article_id = ?", articleID).First(&views).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	return &views, nil
+}
+
+// TopViewedArticles returns the articles with the most unique viewers, with
+// their counters attached
+func (s *Services) TopViewedArticles(ctx context.Context, limit int) ([]models.Article, error) {
+	database := s.db.WithContext(ctx)
+
+	var counters []models.ArticleViews
+	err := database.
+		Order("unique_viewers DESC").
+		Order("views DESC").
+		Order("article_id").
+		Limit(limit).
+		Find(&counters).Error
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]string, len(counters))
+	for i, counter := range counters {
+		ids[i] = counter.ArticleID
+	}
+	var articles []models.Article
+	if err := database.Where("id IN ?", ids).Find(&articles).Error; err != nil {
+		return nil, err
+	}
+
+	byID := make(map[string]models.Article, len(articles))
+	for _, article := range articles {
+		byID[article.ID] = article
+	}
+	result := make([]models.Article, 0, len(counters))
+	for i := range counters {
+		if article, ok := byID[counters[i].ArticleID]; ok {
+			article.Views = &counters[i]
+			result = append(result, article)
+		}
+	}
+	return result, nil
+}
+
+// AttachViews sets the counters of each article that has any
+func (s *Services) AttachViews(ctx context.Context, articles []models.Article) error {
+	if len(articles) == 0 {
+		return nil
+	}
+	ids := make([]string, len(articles))
+	for i, article := range articles {
+		ids[i] = article.ID
+	}
+
+	var counters []models.ArticleViews
+	if err := s.db.WithContext(ctx).Where("article_id IN ?", ids).Find(&counters).Error; err != nil {
+		return err
+	}
+	byID := make(map[string]*models.ArticleViews, len(counters))
+	for i := range counters {
+		byID[counters[i].ArticleID] = &counters[i]
+	}
+	for i := range articles {
+		articles[i].Views = byID[articles[i].ID]
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"syscall"
+	"time"
+
+	"github.com/mahigadamsetty/Inshorts-task/internal/apperr"
+)
+
+const webhookResolveTimeout = 5 * time.Second
+
+// ValidateWebhookURL checks that a webhook URL is absolute http or https and
+// that its host resolves only to public addresses, unless the PrivateWebhooks
+// option lets webhooks reach receivers running next to the server
+func (s *Services) ValidateWebhookURL(ctx context.Context, raw string) error {
+	target, err := url.Parse(raw)
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+		return apperr.InvalidFilterf("webhook_url must be an absolute http or https URL")
+	}
+	if s.privateWebhooks {
+		return nil
+	}
+
+	host := target.Hostname()
+	if ip := net.ParseIP(host); ip != nil {
+		if !publicIP(ip) {
+			return apperr.InvalidFilterf("webhook_url must not point to a private or local address")
+		}
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, webhookResolveTimeout)
+	defer cancel()
+	addrs, err := s.resolver.LookupIPAddr(ctx, host)
+	if err != nil || len(addrs) == 0 {
+		return apperr.InvalidFilterf("webhook_url host %q could not be resolved", host)
+	}
+	for _, addr := range addrs {
+		if !publicIP(addr.IP) {
+			return apperr.InvalidFilterf("webhook_url must not point to a private or local address")
+		}
+	}
+	return nil
+}
+
+// publicIP reports whether an address is reachable on the public internet,
+// rather than loopback, private, link-local, unspecified or multicast
+func publicIP(ip net.IP) bool {
+	return !ip.IsLoopback() && !ip.IsPrivate() && !ip.IsLinkLocalUnicast() &&
+		!ip.IsLinkLocalMulticast() && !ip.IsInterfaceLocalMulticast() &&
+		!ip.IsMulticast() && !ip.IsUnspecified()
+}
+
+// webhookDialControl refuses connections to non-public addresses. It runs
+// once the address is resolved, so a host that passed ValidateWebhookURL
+// cannot be rebound to an internal address before delivery.
+func webhookDialControl(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	if ip := net.ParseIP(host); ip == nil || !publicIP(ip) {
+		return fmt.Errorf("webhook address %s is not public", host)
+	}
+	return nil
+}
+
+// newWebhookClient returns an HTTP client that looks up hosts with resolver
+// and only connects to public addresses, or to any when allowPrivate is set.
+// It dials directly rather than through a proxy, so the check sees the
+// receiver's address.
+func newWebhookClient(timeout time.Duration, allowPrivate bool, resolver *net.Resolver) *http.Client {
+	dialer := &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+		Resolver:  resolver,
+	}
+	if !allowPrivate {
+		dialer.Control = webhookDialControl
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return &http.Client{Timeout: timeout, Transport: transport}
+}
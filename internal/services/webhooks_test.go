@@ -0,0 +1,199 @@
+package services
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// fakeResolver returns a resolver that answers every lookup with the
+// addresses answer returns for it, IPv4 ones as A and IPv6 ones as AAAA
+// records
+func fakeResolver(t *testing.T, answer func() []string) *net.Resolver {
+	t.Helper()
+	server, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("ListenPacket() returned error: %v", err)
+	}
+	t.Cleanup(func() { server.Close() })
+
+	go func() {
+		buf := make([]byte, 512)
+		for {
+			n, addr, err := server.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			var parser dnsmessage.Parser
+			header, err := parser.Start(buf[:n])
+			if err != nil {
+				continue
+			}
+			question, err := parser.Question()
+			if err != nil {
+				continue
+			}
+
+			builder := dnsmessage.NewBuilder(nil, dnsmessage.Header{ID: header.ID, Response: true, Authoritative: true})
+			builder.StartQuestions()
+			builder.Question(question)
+			builder.StartAnswers()
+			resource := dnsmessage.ResourceHeader{Name: question.Name, Class: dnsmessage.ClassINET, TTL: 60}
+			for _, address := range answer() {
+				ip := net.ParseIP(address)
+				if ip4 := ip.To4(); ip4 != nil && question.Type == dnsmessage.TypeA {
+					builder.AResource(resource, dnsmessage.AResource{A: [4]byte(ip4)})
+				} else if ip4 == nil && question.Type == dnsmessage.TypeAAAA {
+					builder.AAAAResource(resource, dnsmessage.AAAAResource{AAAA: [16]byte(ip)})
+				}
+			}
+			if msg, err := builder.Finish(); err == nil {
+				server.WriteTo(msg, addr)
+			}
+		}
+	}()
+
+	return &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var dialer net.Dialer
+			return dialer.DialContext(ctx, "udp", server.LocalAddr().String())
+		},
+	}
+}
+
+func TestValidateWebhookURL(t *testing.T) {
+	tests := []struct {
+		name         string
+		url          string
+		resolves     []string // Addresses the host resolves to
+		allowPrivate bool
+		wantErr      bool
+	}{
+		{"public address", "https://93.184.216.34/hook", nil, false, false},
+		{"public host", "https://hooks.example.com/hook", []string{"93.184.216.34", "2606:2800:220:1::"}, false, false},
+		{"not http", "ftp://93.184.216.34/hook", nil, false, true},
+		{"relative", "/hook", nil, false, true},
+		{"unresolvable host", "https://missing.example.com/hook", nil, false, true},
+		{"loopback", "http://127.0.0.1:8080/hook", nil, false, true},
+		{"loopback range", "http://127.1.2.3/hook", nil, false, true},
+		{"ipv6 loopback", "http://[::1]/hook", nil, false, true},
+		{"rfc1918 10/8", "http://10.0.0.5/hook", nil, false, true},
+		{"rfc1918 172.16/12", "http://172.20.1.1/hook", nil, false, true},
+		{"rfc1918 192.168/16", "http://192.168.1.10/hook", nil, false, true},
+		{"metadata", "http://169.254.169.254/latest/meta-data/", nil, false, true},
+		{"ipv6 link-local", "http://[fe80::1]/hook", nil, false, true},
+		{"ipv6 ula", "http://[fd12:3456:789a::1]/hook", nil, false, true},
+		{"ipv4-mapped loopback", "http://[::ffff:127.0.0.1]/hook", nil, false, true},
+		{"ipv4-mapped private", "http://[::ffff:10.0.0.5]/hook", nil, false, true},
+		{"ipv4-mapped metadata", "http://[::ffff:169.254.169.254]/hook", nil, false, true},
+		{"unspecified", "http://0.0.0.0/hook", nil, false, true},
+		{"multicast", "http://224.0.0.1/hook", nil, false, true},
+		{"host resolving to private", "https://internal.example.com/hook", []string{"10.0.0.5"}, false, true},
+		{"host resolving to metadata", "https://metadata.example.com/hook", []string{"169.254.169.254"}, false, true},
+		{"host resolving to ula", "https://ula.example.com/hook", []string{"fd00::1"}, false, true},
+		{"host with one private address", "https://mixed.example.com/hook", []string{"93.184.216.34", "192.168.1.10"}, false, true},
+		{"private allowed", "http://127.0.0.1:8080/hook", nil, true, false},
+		{"private host allowed", "https://internal.example.com/hook", []string{"10.0.0.5"}, true, false},
+		{"not http with private allowed", "ftp://127.0.0.1/hook", nil, true, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Services{
+				privateWebhooks: tt.allowPrivate,
+				resolver:        fakeResolver(t, func() []string { return tt.resolves }),
+			}
+			err := s.ValidateWebhookURL(context.Background(), tt.url)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateWebhookURL(%q) error = %v, want error %v", tt.url, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestWebhookDialControl(t *testing.T) {
+	tests := []struct {
+		name    string
+		address string
+		wantErr bool
+	}{
+		{"public", "93.184.216.34:443", false},
+		{"public ipv6", "[2606:2800:220:1::]:443", false},
+		{"loopback", "127.0.0.1:80", true},
+		{"ipv6 loopback", "[::1]:80", true},
+		{"rfc1918", "10.0.0.5:80", true},
+		{"metadata", "169.254.169.254:80", true},
+		{"ipv6 ula", "[fd00::1]:80", true},
+		{"ipv4-mapped loopback", "[::ffff:127.0.0.1]:80", true},
+		{"ipv4-mapped private", "[::ffff:192.168.1.10]:80", true},
+		{"unresolved host", "hooks.example.com:443", true},
+		{"no port", "93.184.216.34", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := webhookDialControl("tcp", tt.address, nil)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("webhookDialControl(%q) error = %v, want error %v", tt.address, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestWebhookClientRebinding(t *testing.T) {
+	var received atomic.Int32
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received.Add(1)
+	}))
+	defer receiver.Close()
+	_, port, _ := net.SplitHostPort(receiver.Listener.Addr().String())
+	hook := "http://" + net.JoinHostPort("rebind.example.com", port) + "/hook"
+
+	tests := []struct {
+		name         string
+		allowPrivate bool
+		wantErr      bool
+	}{
+		{"private refused", false, true},
+		{"private allowed", true, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			received.Store(0)
+
+			// The host is public when registered and loopback when delivered to
+			var rebound atomic.Bool
+			resolver := fakeResolver(t, func() []string {
+				if rebound.Load() {
+					return []string{"127.0.0.1"}
+				}
+				return []string{"93.184.216.34"}
+			})
+			s := &Services{privateWebhooks: tt.allowPrivate, resolver: resolver}
+			if err := s.ValidateWebhookURL(context.Background(), hook); err != nil {
+				t.Fatalf("ValidateWebhookURL(%q) returned error: %v", hook, err)
+			}
+			rebound.Store(true)
+
+			client := newWebhookClient(5*time.Second, tt.allowPrivate, resolver)
+			resp, err := client.Post(hook, "application/json", nil)
+			if err == nil {
+				resp.Body.Close()
+			}
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Post(%q) error = %v, want error %v", hook, err, tt.wantErr)
+			}
+			if got, want := received.Load() > 0, !tt.wantErr; got != want {
+				t.Errorf("receiver reached = %v, want %v", got, want)
+			}
+		})
+	}
+}
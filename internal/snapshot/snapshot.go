@@ -0,0 +1,313 @@
+// Package snapshot writes and restores portable backups of the news data. A
+// snapshot is a gzipped JSON Lines file: a header, one line per row keyed by
+// column name, and a trailer with the row count of every table. Values are
+// encoded from the model types rather than the database's column types, so a
+// snapshot of the SQLite demo database restores into any database GORM
+// supports.
+package snapshot
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"time"
+
+	"github.com/mahigadamsetty/Inshorts-task/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// FormatVersion is written to every snapshot; other versions are not restored
+const FormatVersion = 1
+
+const batchSize = 500
+
+// Header is the first line of a snapshot
+type Header struct {
+	Format    int       `json:"format"`
+	CreatedAt time.Time `json:"created_at"`
+	Tables    []string  `json:"tables"`
+}
+
+// line is one line of a snapshot: the header, a row of a table or the trailer.
+// The trailer tells a complete snapshot from a truncated one.
+type line struct {
+	Header *Header                    `json:"header,omitempty"`
+	Table  string                     `json:"table,omitempty"`
+	Row    map[string]json.RawMessage `json:"row,omitempty"`
+	Counts map[string]int64           `json:"counts,omitempty"`
+}
+
+// tables are the tables of a snapshot in restore order, with the columns left
+// out. Summaries are stored on the articles. Stories and topics are rebuilt by
+// their clustering jobs after a restore, so articles don't keep their IDs.
+var tables = []struct {
+	model interface{}
+	omit  []string
+}{
+	{&models.Article{}, []string{"story_id", "topic_id"}},
+	{&models.Event{}, nil},
+	{&models.ArticleViews{}, nil},
+	{&models.ArticleEmbedding{}, nil},
+	{&models.ArticlePopularity{}, nil},
+	{&models.ScoreHistory{}, nil},
+}
+
+// table is a snapshot table with its parsed schema
+type table struct {
+	schema *schema.Schema
+	model  interface{}
+	omit   map[string]bool
+}
+
+// parseTables parses the schema of every snapshot table
+func parseTables(database *gorm.DB) ([]table, error) {
+	parsed := make([]table, len(tables))
+	for i, t := range tables {
+		stmt := &gorm.Statement{DB: database}
+		if err := stmt.Parse(t.model); err != nil {
+			return nil, err
+		}
+		omit := make(map[string]bool, len(t.omit))
+		for _, column := range t.omit {
+			omit[column] = true
+		}
+		parsed[i] = table{schema: stmt.Schema, model: t.model, omit: omit}
+	}
+	return parsed, nil
+}
+
+// Write writes a snapshot of every tenant's data in database to w, read in
+// one transaction so it is consistent. Returns the rows written per table.
+func Write(ctx context.Context, database *gorm.DB, w io.Writer) (map[string]int64, error) {
+	database = database.WithContext(ctx)
+	snapshotTables, err := parseTables(database)
+	if err != nil {
+		return nil, err
+	}
+
+	header := &Header{Format: FormatVersion, CreatedAt: time.Now().UTC()}
+	for _, t := range snapshotTables {
+		header.Tables = append(header.Tables, t.schema.Table)
+	}
+
+	compressed := gzip.NewWriter(w)
+	encoder := json.NewEncoder(compressed)
+	counts := make(map[string]int64, len(snapshotTables))
+	err = database.Transaction(func(tx *gorm.DB) error {
+		if err := encoder.Encode(line{Header: header}); err != nil {
+			return err
+		}
+		for _, t := range snapshotTables {
+			counts[t.schema.Table] = 0
+			batch := reflect.New(reflect.SliceOf(t.schema.ModelType))
+			result := tx.Model(t.model).FindInBatches(batch.Interface(), batchSize, func(_ *gorm.DB, _ int) error {
+				rows := batch.Elem()
+				for i := 0; i < rows.Len(); i++ {
+					row, err := encodeRow(t, rows.Index(i))
+					if err != nil {
+						return err
+					}
+					if err := encoder.Encode(line{Table: t.schema.Table, Row: row}); err != nil {
+						return err
+					}
+					counts[t.schema.Table]++
+				}
+				return nil
+			})
+			if result.Error != nil {
+				return fmt.Errorf("back up %s: %w", t.schema.Table, result.Error)
+			}
+		}
+		return encoder.Encode(line{Counts: counts})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return counts, compressed.Close()
+}
+
+// encodeRow encodes every column of a model value as JSON, by column name
+func encodeRow(t table, value reflect.Value) (map[string]json.RawMessage, error) {
+	row := make(map[string]json.RawMessage, len(t.schema.DBNames))
+	for _, column := range t.schema.DBNames {
+		if t.omit[column] {
+			continue
+		}
+		field := t.schema.FieldsByDBName[column]
+		data, err := json.Marshal(value.FieldByIndex(field.StructField.Index).Interface())
+		if err != nil {
+			return nil, fmt.Errorf("encode %s.%s: %w", t.schema.Table, column, err)
+		}
+		row[column] = data
+	}
+	return row, nil
+}
+
+// Restore loads a snapshot from r into database in one transaction, so a
+// failed restore leaves the database unchanged. Tables of the snapshot must be
+// empty unless replace is set, which deletes their rows first. Model hooks are
+// skipped so timestamps are kept. Returns the rows restored per table.
+func Restore(ctx context.Context, database *gorm.DB, r io.Reader, replace bool) (map[string]int64, error) {
+	database = database.WithContext(ctx)
+	snapshotTables, err := parseTables(database)
+	if err != nil {
+		return nil, err
+	}
+	byName := make(map[string]table, len(snapshotTables))
+	for _, t := range snapshotTables {
+		byName[t.schema.Table] = t
+	}
+
+	compressed, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("not a snapshot: %w", err)
+	}
+	defer compressed.Close()
+	decoder := json.NewDecoder(compressed)
+
+	var first line
+	if err := decoder.Decode(&first); err != nil || first.Header == nil {
+		return nil, fmt.Errorf("not a snapshot: missing header")
+	}
+	if first.Header.Format != FormatVersion {
+		return nil, fmt.Errorf("snapshot format %d is not supported, expected %d", first.Header.Format, FormatVersion)
+	}
+
+	restored := make(map[string]int64, len(snapshotTables))
+	err = database.Transaction(func(tx *gorm.DB) error {
+		for _, name := range first.Header.Tables {
+			t, ok := byName[name]
+			if !ok {
+				return fmt.Errorf("snapshot has unknown table %s", name)
+			}
+			if err := prepareTable(tx, t, replace); err != nil {
+				return err
+			}
+			restored[name] = 0
+		}
+
+		var current table
+		var pending reflect.Value
+		flush := func() error {
+			if !pending.IsValid() || pending.Len() == 0 {
+				return nil
+			}
+			rows := reflect.New(pending.Type())
+			rows.Elem().Set(pending)
+			if err := tx.Session(&gorm.Session{SkipHooks: true}).Create(rows.Interface()).Error; err != nil {
+				return fmt.Errorf("restore %s: %w", current.schema.Table, err)
+			}
+			pending = pending.Slice(0, 0)
+			return nil
+		}
+
+		for {
+			var next line
+			if err := decoder.Decode(&next); errors.Is(err, io.EOF) {
+				return fmt.Errorf("snapshot is truncated: missing trailer")
+			} else if err != nil {
+				return fmt.Errorf("read snapshot: %w", err)
+			}
+
+			if next.Counts != nil {
+				if err := flush(); err != nil {
+					return err
+				}
+				for name, count := range next.Counts {
+					if restored[name] != count {
+						return fmt.Errorf("snapshot is incomplete: %d of %d rows of %s", restored[name], count, name)
+					}
+				}
+				return resetSequences(tx, snapshotTables)
+			}
+
+			t := byName[next.Table]
+			if _, listed := restored[next.Table]; !listed {
+				return fmt.Errorf("table %q is missing from the snapshot header", next.Table)
+			}
+			if t.schema != current.schema {
+				if err := flush(); err != nil {
+					return err
+				}
+				current = t
+				pending = reflect.MakeSlice(reflect.SliceOf(t.schema.ModelType), 0, batchSize)
+			}
+
+			value := reflect.New(t.schema.ModelType).Elem()
+			if err := decodeRow(t, next.Row, value); err != nil {
+				return err
+			}
+			pending = reflect.Append(pending, value)
+			restored[next.Table]++
+			if pending.Len() >= batchSize {
+				if err := flush(); err != nil {
+					return err
+				}
+			}
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return restored, nil
+}
+
+// prepareTable checks a table is empty before a restore, or empties it when
+// replace is set
+func prepareTable(tx *gorm.DB, t table, replace bool) error {
+	if replace {
+		if err := tx.Session(&gorm.Session{AllowGlobalUpdate: true}).Delete(t.model).Error; err != nil {
+			return fmt.Errorf("clear %s: %w", t.schema.Table, err)
+		}
+		return nil
+	}
+	var count int64
+	if err := tx.Model(t.model).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return fmt.Errorf("table %s already has %d rows, restore with replace to overwrite them", t.schema.Table, count)
+	}
+	return nil
+}
+
+// decodeRow sets the columns of a row on a model value. Columns missing from
+// the row keep their zero value.
+func decodeRow(t table, row map[string]json.RawMessage, value reflect.Value) error {
+	for column, data := range row {
+		field, ok := t.schema.FieldsByDBName[column]
+		if !ok || t.omit[column] {
+			return fmt.Errorf("snapshot has unknown column %s.%s", t.schema.Table, column)
+		}
+		if err := json.Unmarshal(data, value.FieldByIndex(field.StructField.Index).Addr().Interface()); err != nil {
+			return fmt.Errorf("decode %s.%s: %w", t.schema.Table, column, err)
+		}
+	}
+	return nil
+}
+
+// resetSequences moves Postgres sequences past the restored IDs, which are
+// inserted explicitly. Other databases advance auto-increment counters on
+// insert.
+func resetSequences(tx *gorm.DB, snapshotTables []table) error {
+	if tx.Dialector.Name() != "postgres" {
+		return nil
+	}
+	for _, t := range snapshotTables {
+		key := t.schema.PrioritizedPrimaryField
+		if key == nil || !key.AutoIncrement {
+			continue
+		}
+		statement := fmt.Sprintf("SELECT setval(pg_get_serial_sequence('%s', '%s'), COALESCE(MAX(%s), 0) + 1, false) FROM %s",
+			t.schema.Table, key.DBName, key.DBName, t.schema.Table)
+		if err := tx.Exec(statement).Error; err != nil {
+			return fmt.Errorf("reset %s sequence: %w", t.schema.Table, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,136 @@
+// Package telegram is a minimal client of the Telegram Bot API: it receives a
+// bot's messages by long polling and sends messages to chats.
+package telegram
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// DefaultBaseURL is the Bot API's address
+const DefaultBaseURL = "https://api.telegram.org"
+
+// Update is an incoming update. Only messages are requested.
+type Update struct {
+	UpdateID int64    `json:"update_id"`
+	Message  *Message `json:"message"`
+}
+
+// Message is a message sent to the bot
+type Message struct {
+	MessageID int64     `json:"message_id"`
+	Chat      Chat      `json:"chat"`
+	Text      string    `json:"text"`
+	Location  *Location `json:"location"` // Set when a location was shared
+}
+
+// Chat is the private chat or group a message was sent in
+type Chat struct {
+	ID   int64  `json:"id"`
+	Type string `json:"type"`
+}
+
+// Location is a point shared in a chat
+type Location struct {
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+}
+
+// Error is a request the Bot API refused, e.g. 403 once a user blocked the bot
+type Error struct {
+	Code        int
+	Description string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("telegram API returned %d: %s", e.Code, e.Description)
+}
+
+// Client calls the Bot API as one bot
+type Client struct {
+	token   string
+	baseURL string
+	http    *http.Client
+}
+
+// NewClient creates a client for the bot's token. baseURL defaults to the
+// Bot API's address.
+func NewClient(token, baseURL string) *Client {
+	if baseURL == "" {
+		baseURL = DefaultBaseURL
+	}
+	return &Client{
+		token:   token,
+		baseURL: strings.TrimRight(baseURL, "/"),
+		http:    &http.Client{Timeout: 90 * time.Second}, // Longer than any long poll
+	}
+}
+
+// GetUpdates waits up to wait for messages after offset, the ID of the last
+// update handled plus one, which also confirms the updates before it
+func (c *Client) GetUpdates(ctx context.Context, offset int64, wait time.Duration) ([]Update, error) {
+	var updates []Update
+	err := c.call(ctx, "getUpdates", map[string]interface{}{
+		"offset":          offset,
+		"timeout":         int(wait.Seconds()),
+		"allowed_updates": []string{"message"},
+	}, &updates)
+	return updates, err
+}
+
+// SendMessage sends text, formatted as Telegram's HTML subset, to a chat
+func (c *Client) SendMessage(ctx context.Context, chatID int64, text string) error {
+	return c.call(ctx, "sendMessage", map[string]interface{}{
+		"chat_id":    chatID,
+		"text":       text,
+		"parse_mode": "HTML",
+	}, nil)
+}
+
+// call posts a method's parameters and decodes its result into result
+func (c *Client) call(ctx context.Context, method string, params interface{}, result interface{}) error {
+	body, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", c.baseURL+"/bot"+c.token+"/"+method, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		// Leave out the request URL, which carries the token
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return fmt.Errorf("telegram %s failed: %w", method, err)
+	}
+	defer resp.Body.Close()
+
+	var response struct {
+		OK          bool            `json:"ok"`
+		Result      json.RawMessage `json:"result"`
+		ErrorCode   int             `json:"error_code"`
+		Description string          `json:"description"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return fmt.Errorf("telegram %s returned status %d", method, resp.StatusCode)
+	}
+	if !response.OK {
+		return &Error{Code: response.ErrorCode, Description: response.Description}
+	}
+	if result == nil {
+		return nil
+	}
+	return json.Unmarshal(response.Result, result)
+}
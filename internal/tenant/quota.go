@@ -0,0 +1,61 @@
+package tenant
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Kinds of usage metered per UTC month
+const (
+	QuotaRequests  = "requests"
+	QuotaLLMTokens = "llm_tokens"
+	QuotaEvents    = "events"
+)
+
+// Quota returns the tenant's monthly quota of a kind of usage, 0 when it is
+// unlimited
+func (t *Tenant) Quota(kind string) int {
+	var quota int
+	switch kind {
+	case QuotaRequests:
+		quota = t.MonthlyRequests
+	case QuotaLLMTokens:
+		quota = t.MonthlyLLMTokens
+	case QuotaEvents:
+		quota = t.MonthlyEvents
+	}
+	if quota < 0 {
+		return 0
+	}
+	return quota
+}
+
+// QuotaError is returned when usage would exceed a monthly quota
+type QuotaError struct {
+	Quota    string    // Kind of usage
+	Limit    int       // The tenant's quota of it
+	ResetsAt time.Time // Start of the next UTC month
+}
+
+func (e *QuotaError) Error() string {
+	return fmt.Sprintf("Monthly %s quota of %d exceeded", strings.ReplaceAll(e.Quota, "_", " "), e.Limit)
+}
+
+// RespondQuota refuses a request that would exceed a monthly quota with
+// status, 402 or 429, telling the client when the quota resets
+func RespondQuota(c *gin.Context, err *QuotaError, status int) {
+	if retryAfter := int(time.Until(err.ResetsAt).Seconds()) + 1; retryAfter > 0 {
+		c.Header("Retry-After", strconv.Itoa(retryAfter))
+	}
+	c.AbortWithStatusJSON(status, gin.H{"error": err.Error(), "quota": err.Quota, "limit": err.Limit, "resets_at": err.ResetsAt})
+}
+
+// MonthStart returns the start of the UTC month of a time
+func MonthStart(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,195 @@
+package tenant
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/mahigadamsetty/Inshorts-task/internal/utils"
+)
+
+// DefaultID is the tenant used for requests without an API key and for existing data
+const DefaultID = "default"
+
+// Tenant is one news product served by the deployment, identified by its API key
+type Tenant struct {
+	ID                 string `json:"id"`
+	Name               string `json:"name"`
+	APIKey             string `json:"api_key"`
+	RateLimitPerMinute int    `json:"rate_limit_per_minute"`   // 0 means unlimited
+	LLMDailyBudget     int    `json:"llm_daily_budget"`        // LLM calls per UTC day, 0 means unlimited
+	Timezone           string `json:"timezone"`                // IANA name of the clients' timezone, UTC when empty
+	MonthlyRequests    int    `json:"monthly_request_quota"`   // API requests per UTC month, 0 means unlimited
+	MonthlyLLMTokens   int    `json:"monthly_llm_token_quota"` // LLM tokens per UTC month, 0 means unlimited
+	MonthlyEvents      int    `json:"monthly_event_quota"`     // Ingested events per UTC month, 0 means unlimited
+	CoordinateDecimals *int   `json:"coordinate_precision"`    // Decimal places of coordinates in responses, -1 for as stored, nil for the deployment's
+	OmitCoordinates    bool   `json:"omit_coordinates"`        // Leave coordinates out of responses
+
+	location    *time.Location
+	mu          sync.Mutex
+	windowStart time.Time
+	windowCount int
+	llmDay      string
+	llmCount    int
+}
+
+// Registry resolves API keys to tenants
+type Registry struct {
+	byKey         map[string]*Tenant
+	defaultTenant *Tenant
+}
+
+type contextKey struct{}
+
+// LoadRegistry reads tenant definitions from a JSON file. An empty path yields a
+// registry containing only the default tenant.
+func LoadRegistry(path string) (*Registry, error) {
+	registry := &Registry{
+		byKey:         make(map[string]*Tenant),
+		defaultTenant: &Tenant{ID: DefaultID, Name: "Default"},
+	}
+	if path == "" {
+		return registry, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read tenants file: %w", err)
+	}
+
+	var tenants []*Tenant
+	if err := json.Unmarshal(data, &tenants); err != nil {
+		return nil, fmt.Errorf("failed to parse tenants file: %w", err)
+	}
+
+	for _, t := range tenants {
+		if t.ID == "" || t.APIKey == "" {
+			return nil, fmt.Errorf("tenant entries require id and api_key")
+		}
+		if _, exists := registry.byKey[t.APIKey]; exists {
+			return nil, fmt.Errorf("duplicate api_key for tenant %s", t.ID)
+		}
+		if t.Timezone != "" {
+			if t.location, err = time.LoadLocation(t.Timezone); err != nil || t.Timezone == "Local" {
+				return nil, fmt.Errorf("invalid timezone %q for tenant %s", t.Timezone, t.ID)
+			}
+		}
+		if t.ID == DefaultID {
+			registry.defaultTenant = t
+		}
+		registry.byKey[t.APIKey] = t
+	}
+
+	return registry, nil
+}
+
+// Lookup returns the tenant owning an API key
+func (r *Registry) Lookup(apiKey string) (*Tenant, bool) {
+	t, ok := r.byKey[apiKey]
+	return t, ok
+}
+
+// Get returns the tenant with an ID
+func (r *Registry) Get(id string) (*Tenant, bool) {
+	if id == r.defaultTenant.ID {
+		return r.defaultTenant, true
+	}
+	for _, t := range r.byKey {
+		if t.ID == id {
+			return t, true
+		}
+	}
+	return nil, false
+}
+
+// Default returns the tenant used for anonymous requests
+func (r *Registry) Default() *Tenant {
+	return r.defaultTenant
+}
+
+// Location returns the timezone of the tenant's clients
+func (t *Tenant) Location() *time.Location {
+	if t.location == nil {
+		return time.UTC
+	}
+	return t.location
+}
+
+// CoordinateLimit returns how precisely the tenant is given coordinates: the
+// deployment's limit, with the tenant's own precision and omission applied
+func (t *Tenant) CoordinateLimit(deployment utils.CoordinateLimit) utils.CoordinateLimit {
+	limit := deployment
+	if t.CoordinateDecimals != nil {
+		limit.Decimals = *t.CoordinateDecimals
+	}
+	limit.Omit = limit.Omit || t.OmitCoordinates
+	return limit
+}
+
+// AllowRequest counts a request against the tenant's per-minute rate limit
+func (t *Tenant) AllowRequest() bool {
+	if t.RateLimitPerMinute <= 0 {
+		return true
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(t.windowStart) >= time.Minute {
+		t.windowStart = now
+		t.windowCount = 0
+	}
+	if t.windowCount >= t.RateLimitPerMinute {
+		return false
+	}
+	t.windowCount++
+	return true
+}
+
+// AllowLLMCall counts an LLM call against the tenant's daily budget
+func (t *Tenant) AllowLLMCall() bool {
+	if t.LLMDailyBudget <= 0 {
+		return true
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	today := time.Now().UTC().Format("2006-01-02")
+	if t.llmDay != today {
+		t.llmDay = today
+		t.llmCount = 0
+	}
+	if t.llmCount >= t.LLMDailyBudget {
+		return false
+	}
+	t.llmCount++
+	return true
+}
+
+// NewContext returns a context carrying the tenant
+func NewContext(ctx context.Context, t *Tenant) context.Context {
+	return context.WithValue(ctx, contextKey{}, t)
+}
+
+// FromContext returns the tenant stored in the context, if any
+func FromContext(ctx context.Context) (*Tenant, bool) {
+	if ctx == nil {
+		return nil, false
+	}
+	t, ok := ctx.Value(contextKey{}).(*Tenant)
+	return t, ok && t != nil
+}
+
+// IDFromContext returns the tenant ID stored in the context, or "" when the
+// context is not tenant scoped (background jobs)
+func IDFromContext(ctx context.Context) string {
+	if t, ok := FromContext(ctx); ok {
+		return t.ID
+	}
+	return ""
+}
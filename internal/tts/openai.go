@@ -0,0 +1,55 @@
+package tts
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/mahigadamsetty/Inshorts-task/internal/llm"
+)
+
+// Defaults of the openai provider
+const (
+	OpenAIModel = "tts-1"
+	OpenAIVoice = "alloy"
+)
+
+func init() {
+	Register("openai", newOpenAI)
+}
+
+// openAI synthesizes speech with the speech endpoint of the OpenAI-compatible
+// API, paced by the shared LLM request queue
+type openAI struct {
+	client llm.Provider
+	model  string
+	voice  string
+}
+
+func newOpenAI(options Options) (Provider, error) {
+	if options.LLM == nil {
+		return nil, fmt.Errorf("the openai TTS provider needs an LLM client")
+	}
+	if provider := options.LLM.ProviderName(); provider != llm.ProviderOpenAI {
+		return nil, fmt.Errorf("the openai TTS provider needs the openai LLM provider, not %s", provider)
+	}
+	p := &openAI{client: options.LLM, model: options.Model, voice: options.Voice}
+	if p.model == "" {
+		p.model = OpenAIModel
+	}
+	if p.voice == "" {
+		p.voice = OpenAIVoice
+	}
+	return p, nil
+}
+
+func (p *openAI) Voice() string {
+	return "openai/" + p.model + "/" + p.voice
+}
+
+func (p *openAI) Format() string {
+	return "mp3"
+}
+
+func (p *openAI) Synthesize(ctx context.Context, text string) ([]byte, error) {
+	return p.client.For(llm.Call{Context: ctx}).Speech(p.model, p.voice, p.Format(), text)
+}
@@ -0,0 +1,70 @@
+// Package tts turns text into speech through pluggable providers. Providers
+// register a factory under a name, and the server creates the one TTS_PROVIDER
+// names at startup.
+package tts
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/mahigadamsetty/Inshorts-task/internal/llm"
+)
+
+// Provider synthesizes speech in one voice and audio format
+type Provider interface {
+	// Voice identifies the voice, so audio is made again when it changes
+	Voice() string
+	// Format is the audio format and file extension, e.g. mp3
+	Format() string
+	// Synthesize reads the text aloud
+	Synthesize(ctx context.Context, text string) ([]byte, error)
+}
+
+// Options configure a provider. Providers ignore the options they don't use.
+type Options struct {
+	Model string
+	Voice string
+	LLM   llm.Provider // Client of the OpenAI-compatible API, for providers speaking it
+}
+
+// Factory creates a provider from its options
+type Factory func(options Options) (Provider, error)
+
+var (
+	mu        sync.RWMutex
+	factories = map[string]Factory{}
+)
+
+// Register makes a provider available under a name, replacing any registered
+// under the same name
+func Register(name string, factory Factory) {
+	mu.Lock()
+	defer mu.Unlock()
+	factories[strings.ToLower(name)] = factory
+}
+
+// New creates the provider registered under name
+func New(name string, options Options) (Provider, error) {
+	mu.RLock()
+	factory, ok := factories[strings.ToLower(name)]
+	mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown TTS provider %q, expected one of %s", name, strings.Join(Names(), ", "))
+	}
+	return factory(options)
+}
+
+// Names lists the registered providers
+func Names() []string {
+	mu.RLock()
+	defer mu.RUnlock()
+	names := make([]string, 0, len(factories))
+	for name := range factories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,289 @@
+// Package tuning holds the relevance settings that can change at runtime: the
+// stop words dropped from search queries and the ranking weights. They are
+// read from a JSON file over the defaults and reloaded on SIGHUP or from the
+// admin API, so relevance tuning doesn't need a redeploy.
+package tuning
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mahigadamsetty/Inshorts-task/internal/platform"
+)
+
+// Settings are the runtime-tunable relevance settings
+type Settings struct {
+	Profile   string   `json:"profile"` // Names the live ranking weights in impression reports
+	StopWords []string `json:"stop_words"`
+	Ranking   Ranking  `json:"ranking"`
+	Shadow    *Shadow  `json:"shadow,omitempty"`
+}
+
+// DefaultProfile names the live ranking weights when the tuning file doesn't
+const DefaultProfile = "default"
+
+// Shadow is a candidate ranking run beside the live one on a sample of
+// requests, without changing what they return
+type Shadow struct {
+	Name       string   `json:"name"`                // Labels the candidate's logged comparisons
+	SampleRate float64  `json:"sample_rate"`         // Share of requests also ranked by the candidate (0-1)
+	Platforms  []string `json:"platforms,omitempty"` // Client platforms sampled, all when empty
+	Ranking    Ranking  `json:"ranking"`             // Weights left out keep their live value
+}
+
+// Targets reports whether the candidate samples requests from a platform
+func (s *Shadow) Targets(client string) bool {
+	if len(s.Platforms) == 0 {
+		return true
+	}
+	for _, p := range s.Platforms {
+		if p == client {
+			return true
+		}
+	}
+	return false
+}
+
+// Ranking holds the weights of the rankers and of score recalibration
+type Ranking struct {
+	FreshnessWeight         float64       `json:"freshness_weight"`           // Share of the final score taken by freshness (0-1)
+	FreshnessHalfLifeHours  float64       `json:"freshness_half_life_hours"`  // Age at which the freshness score drops to 0.5
+	ReliabilityBoost        float64       `json:"reliability_boost"`          // Largest +/- fraction applied for source reliability (0-1)
+	ShortReadBoost          float64       `json:"short_read_boost"`           // Largest +/- fraction applied for how quick an article is to read (0-1)
+	GeoDecayPerKm           float64       `json:"geo_decay_per_km"`           // How quickly geo relevance falls off with distance
+	PopularityHalfLifeHours float64       `json:"popularity_half_life_hours"` // Time for a unique viewer's weight in reach to halve, 0 for no decay
+	SearchBoosts            SearchBoosts  `json:"search_boosts"`
+	Recalibration           Recalibration `json:"recalibration"`
+}
+
+// SearchBoosts are the default field boosts of search ranking
+type SearchBoosts struct {
+	Title       float64 `json:"title"`
+	Description float64 `json:"description"`
+	Recent      float64 `json:"recent"`
+}
+
+// Recalibration weights each signal of the recalibrated relevance score
+type Recalibration struct {
+	Original    float64 `json:"original"`
+	Engagement  float64 `json:"engagement"`
+	Reach       float64 `json:"reach"`
+	Reliability float64 `json:"reliability"`
+	Recency     float64 `json:"recency"`
+}
+
+// DefaultRanking are the weights used when neither the environment nor the
+// tuning file set them
+var DefaultRanking = Ranking{
+	FreshnessWeight:         0.3,
+	FreshnessHalfLifeHours:  24,
+	ReliabilityBoost:        0.2,
+	GeoDecayPerKm:           0.05,
+	PopularityHalfLifeHours: 72,
+	SearchBoosts:            SearchBoosts{Title: 3, Description: 1, Recent: 1},
+	Recalibration:           Recalibration{Original: 0.4, Engagement: 0.2, Reach: 0.1, Reliability: 0.2, Recency: 0.1},
+}
+
+// DefaultStopWords are common English words that carry no search meaning
+var DefaultStopWords = []string{
+	"a", "about", "above", "after", "again", "against", "all", "am", "an", "and", "any", "are", "as", "at",
+	"be", "because", "been", "before", "being", "below", "between", "both", "but", "by",
+	"can", "did", "do", "does", "doing", "don", "down", "during",
+	"each",
+	"few", "for", "from", "further",
+	"had", "has", "have", "having", "he", "her", "here", "hers", "herself", "him", "himself", "his", "how",
+	"i", "if", "in", "into", "is", "it", "its", "itself",
+	"just",
+	"me", "more", "most", "my", "myself",
+	"no", "nor", "not",
+	"of", "off", "on", "once", "only", "or", "other", "our", "ours", "ourselves", "out", "over", "own",
+	"s", "same", "she", "should", "so", "some", "such",
+	"t", "than", "that", "the", "their", "theirs", "them", "themselves", "then", "there", "these", "they", "this", "those", "through", "to", "too",
+	"under", "until", "up",
+	"very",
+	"was", "we", "were", "what", "when", "where", "which", "while", "who", "whom", "why", "will", "with", "would",
+	"you", "your", "yours", "yourself", "yourselves",
+}
+
+var (
+	mu        sync.RWMutex
+	file      string
+	base      = Settings{Profile: DefaultProfile, StopWords: DefaultStopWords, Ranking: DefaultRanking}
+	current   = base
+	stopWords = wordSet(DefaultStopWords)
+	loadedAt  time.Time
+)
+
+// Configure sets the tuning file and the ranking weights it overrides, then
+// loads it. An empty path uses the base weights and default stop words.
+func Configure(path string, ranking Ranking) error {
+	mu.Lock()
+	file = path
+	base.Ranking = ranking
+	mu.Unlock()
+	_, err := Reload()
+	return err
+}
+
+// Reload rereads the tuning file. Settings the file leaves out keep their base
+// value; on error the previous settings stay active.
+func Reload() (Settings, error) {
+	mu.RLock()
+	path, settings := file, base
+	mu.RUnlock()
+
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return Settings{}, err
+		}
+		// Decode stop words into a fresh slice so the base list isn't overwritten
+		baseWords := settings.StopWords
+		settings.StopWords = nil
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&settings); err != nil {
+			return Settings{}, fmt.Errorf("invalid tuning file %s: %w", path, err)
+		}
+		if settings.StopWords == nil {
+			settings.StopWords = baseWords
+		}
+		if settings.Shadow != nil {
+			candidate, err := shadowRanking(data, settings.Ranking)
+			if err != nil {
+				return Settings{}, fmt.Errorf("invalid tuning file %s: %w", path, err)
+			}
+			settings.Shadow.Ranking = candidate
+		}
+	}
+	if settings.Profile == "" {
+		return Settings{}, fmt.Errorf("invalid tuning file %s: profile must not be empty", path)
+	}
+	if err := settings.Ranking.validate(); err != nil {
+		return Settings{}, fmt.Errorf("invalid ranking weights: %w", err)
+	}
+	if err := settings.Shadow.validate(); err != nil {
+		return Settings{}, fmt.Errorf("invalid shadow ranking: %w", err)
+	}
+
+	mu.Lock()
+	current = settings
+	stopWords = wordSet(settings.StopWords)
+	loadedAt = time.Now()
+	mu.Unlock()
+	return settings, nil
+}
+
+// shadowRanking decodes the shadow candidate's weights over the live ones, so
+// a candidate only lists the weights it changes
+func shadowRanking(data []byte, live Ranking) (Ranking, error) {
+	var file struct {
+		Shadow struct {
+			Ranking json.RawMessage `json:"ranking"`
+		} `json:"shadow"`
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		return Ranking{}, err
+	}
+	candidate := live
+	if len(file.Shadow.Ranking) > 0 {
+		if err := json.Unmarshal(file.Shadow.Ranking, &candidate); err != nil {
+			return Ranking{}, err
+		}
+	}
+	return candidate, nil
+}
+
+// validate rejects shadow rankings without a name, with a sample rate outside
+// 0-1 or with invalid weights. No shadow ranking is valid.
+func (s *Shadow) validate() error {
+	if s == nil {
+		return nil
+	}
+	if s.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	if s.SampleRate < 0 || s.SampleRate > 1 {
+		return fmt.Errorf("sample_rate must be between 0 and 1")
+	}
+	for _, p := range s.Platforms {
+		if !platform.Valid(p) {
+			return fmt.Errorf("unknown platform %q; use one of %s", p, strings.Join(platform.All, ", "))
+		}
+	}
+	return s.Ranking.validate()
+}
+
+// validate rejects weights outside their range
+func (r Ranking) validate() error {
+	if r.FreshnessWeight < 0 || r.FreshnessWeight > 1 {
+		return fmt.Errorf("freshness_weight must be between 0 and 1")
+	}
+	if r.ReliabilityBoost < 0 || r.ReliabilityBoost > 1 {
+		return fmt.Errorf("reliability_boost must be between 0 and 1")
+	}
+	if r.ShortReadBoost < 0 || r.ShortReadBoost > 1 {
+		return fmt.Errorf("short_read_boost must be between 0 and 1")
+	}
+	if r.FreshnessHalfLifeHours < 0 || r.GeoDecayPerKm < 0 || r.PopularityHalfLifeHours < 0 {
+		return fmt.Errorf("freshness_half_life_hours, geo_decay_per_km and popularity_half_life_hours must not be negative")
+	}
+	boosts, weights := r.SearchBoosts, r.Recalibration
+	for _, value := range []float64{boosts.Title, boosts.Description, boosts.Recent,
+		weights.Original, weights.Engagement, weights.Reach, weights.Reliability, weights.Recency} {
+		if value < 0 {
+			return fmt.Errorf("search_boosts and recalibration weights must not be negative")
+		}
+	}
+	return nil
+}
+
+// Current returns the active settings
+func Current() Settings {
+	mu.RLock()
+	defer mu.RUnlock()
+	return current
+}
+
+// Status describes where the active settings came from
+func Status() (string, time.Time) {
+	mu.RLock()
+	defer mu.RUnlock()
+	return file, loadedAt
+}
+
+// IsStopWord reports whether a lowercase word is an active stop word
+func IsStopWord(word string) bool {
+	mu.RLock()
+	defer mu.RUnlock()
+	_, found := stopWords[word]
+	return found
+}
+
+// FilterStopWords returns the lowercase words that are not stop words
+func FilterStopWords(words []string) []string {
+	mu.RLock()
+	active := stopWords
+	mu.RUnlock()
+
+	filtered := make([]string, 0, len(words))
+	for _, word := range words {
+		if _, found := active[word]; !found {
+			filtered = append(filtered, word)
+		}
+	}
+	return filtered
+}
+
+func wordSet(words []string) map[string]struct{} {
+	set := make(map[string]struct{}, len(words))
+	for _, word := range words {
+		set[strings.ToLower(word)] = struct{}{}
+	}
+	return set
+}
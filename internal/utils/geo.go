@@ -3,6 +3,7 @@ package utils
 import (
 	"fmt"
 	"math"
+	"strings"
 )
 
 const earthRadiusKm = 6371.0
@@ -31,3 +32,147 @@ func GetLocationClusterKey(lat, lon, clusterDegrees float64) string {
 	clusterLon := math.Round(lon/clusterDegrees) * clusterDegrees
 	return fmt.Sprintf("%.2f,%.2f", clusterLat, clusterLon)
 }
+
+// BoundingBox returns the latitude and longitude bounds enclosing a circle of
+// radiusKm around a point
+func BoundingBox(lat, lon, radiusKm float64) (minLat, maxLat, minLon, maxLon float64) {
+	latDelta := radiusKm / earthRadiusKm * 180 / math.Pi
+	lonDelta := latDelta / math.Max(math.Cos(lat*math.Pi/180), 0.01)
+	return lat - latDelta, lat + latDelta, lon - lonDelta, lon + lonDelta
+}
+
+// PointInPolygon reports whether a point lies inside a polygon of [lat, lon]
+// vertices using ray casting. Polygons crossing the antimeridian are not supported.
+func PointInPolygon(lat, lon float64, polygon [][2]float64) bool {
+	inside := false
+	for i, j := 0, len(polygon)-1; i < len(polygon); j, i = i, i+1 {
+		latI, lonI := polygon[i][0], polygon[i][1]
+		latJ, lonJ := polygon[j][0], polygon[j][1]
+		if (latI > lat) != (latJ > lat) &&
+			lon < (lonJ-lonI)*(lat-latI)/(latJ-latI)+lonI {
+			inside = !inside
+		}
+	}
+	return inside
+}
+
+// PolygonBounds returns the latitude and longitude bounds enclosing a polygon
+func PolygonBounds(polygon [][2]float64) (minLat, maxLat, minLon, maxLon float64) {
+	minLat, minLon = math.Inf(1), math.Inf(1)
+	maxLat, maxLon = math.Inf(-1), math.Inf(-1)
+	for _, vertex := range polygon {
+		minLat, maxLat = math.Min(minLat, vertex[0]), math.Max(maxLat, vertex[0])
+		minLon, maxLon = math.Min(minLon, vertex[1]), math.Max(maxLon, vertex[1])
+	}
+	return
+}
+
+// TruncateCoordinate drops the digits of a coordinate beyond the given number
+// of decimal places, moving it toward zero. Negative decimals leave it unchanged.
+func TruncateCoordinate(value float64, decimals int) float64 {
+	if decimals < 0 {
+		return value
+	}
+	scale := math.Pow(10, float64(decimals))
+	return math.Trunc(value*scale) / scale
+}
+
+// RoundCoordinate rounds a coordinate to the given number of decimal places.
+// Negative decimals leave it unchanged.
+func RoundCoordinate(value float64, decimals int) float64 {
+	if decimals < 0 {
+		return value
+	}
+	scale := math.Pow(10, float64(decimals))
+	return math.Round(value*scale) / scale
+}
+
+// CoordinateLimit is how precisely coordinates are given out in responses and
+// payloads, for licensing and privacy constraints on location data
+type CoordinateLimit struct {
+	Decimals int  // Decimal places kept, negative to keep coordinates as stored
+	Omit     bool // Leave coordinates out altogether
+}
+
+// Limits reports whether coordinates given out under the limit differ from
+// the stored ones
+func (l CoordinateLimit) Limits() bool {
+	return l.Omit || l.Decimals >= 0
+}
+
+// Apply returns a coordinate pair as given out under the limit: rounded, or
+// nil when left out
+func (l CoordinateLimit) Apply(latitude, longitude float64) (*float64, *float64) {
+	if l.Omit {
+		return nil, nil
+	}
+	latitude, longitude = RoundCoordinate(latitude, l.Decimals), RoundCoordinate(longitude, l.Decimals)
+	return &latitude, &longitude
+}
+
+// geohashAlphabet is the base32 alphabet of geohashes
+const geohashAlphabet = "0123456789bcdefghjkmnpqrstuvwxyz"
+
+// Geohash encodes a point as a geohash of the given number of characters.
+// Each character narrows the cell; 5 characters are about 5km across.
+func Geohash(lat, lon float64, precision int) string {
+	minLat, maxLat, minLon, maxLon := -90.0, 90.0, -180.0, 180.0
+	hash := make([]byte, 0, precision)
+	bit, index, even := 0, 0, true
+	for len(hash) < precision {
+		// Bits alternate between longitude and latitude, longitude first
+		if even {
+			if mid := (minLon + maxLon) / 2; lon >= mid {
+				index = index<<1 | 1
+				minLon = mid
+			} else {
+				index <<= 1
+				maxLon = mid
+			}
+		} else {
+			if mid := (minLat + maxLat) / 2; lat >= mid {
+				index = index<<1 | 1
+				minLat = mid
+			} else {
+				index <<= 1
+				maxLat = mid
+			}
+		}
+		even = !even
+		if bit++; bit == 5 {
+			hash = append(hash, geohashAlphabet[index])
+			bit, index = 0, 0
+		}
+	}
+	return string(hash)
+}
+
+// GeohashBounds returns the latitude and longitude bounds of a geohash cell.
+// Characters outside the geohash alphabet are skipped.
+func GeohashBounds(hash string) (minLat, maxLat, minLon, maxLon float64) {
+	minLat, maxLat, minLon, maxLon = -90, 90, -180, 180
+	even := true
+	for _, char := range hash {
+		index := strings.IndexRune(geohashAlphabet, char)
+		if index < 0 {
+			continue
+		}
+		for mask := 16; mask > 0; mask >>= 1 {
+			if even {
+				if mid := (minLon + maxLon) / 2; index&mask != 0 {
+					minLon = mid
+				} else {
+					maxLon = mid
+				}
+			} else {
+				if mid := (minLat + maxLat) / 2; index&mask != 0 {
+					minLat = mid
+				} else {
+					maxLat = mid
+				}
+			}
+			even = !even
+		}
+	}
+	return
+}
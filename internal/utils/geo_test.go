@@ -0,0 +1,42 @@
+package utils
+
+import "testing"
+
+func TestPointInPolygon(t *testing.T) {
+	square := [][2]float64{{0, 0}, {0, 10}, {10, 10}, {10, 0}}
+	// A U shape open to the north, with a notch between longitudes 4 and 6
+	notched := [][2]float64{{0, 0}, {0, 10}, {10, 10}, {10, 6}, {2, 6}, {2, 4}, {10, 4}, {10, 0}}
+	// Roughly central Bengaluru
+	bengaluru := [][2]float64{{12.90, 77.50}, {12.90, 77.70}, {13.05, 77.70}, {13.05, 77.50}}
+	triangle := [][2]float64{{0, 0}, {10, 5}, {0, 10}}
+
+	tests := []struct {
+		name     string
+		lat, lon float64
+		polygon  [][2]float64
+		want     bool
+	}{
+		{"centre of square", 5, 5, square, true},
+		{"north of square", 11, 5, square, false},
+		{"west of square", 5, -1, square, false},
+		{"far away", 50, 50, square, false},
+		{"inside notched arm", 5, 2, notched, true},
+		{"inside notch", 5, 5, notched, false},
+		{"below notch", 1, 5, notched, true},
+		{"inside triangle", 3, 5, triangle, true},
+		{"beside triangle tip", 8, 1, triangle, false},
+		{"city centre", 12.97, 77.59, bengaluru, true},
+		{"outside city", 12.30, 76.64, bengaluru, false},
+		{"southern hemisphere", -33.87, 151.21, [][2]float64{{-34, 151}, {-34, 152}, {-33, 152}, {-33, 151}}, true},
+		{"empty polygon", 5, 5, nil, false},
+		{"degenerate line", 5, 5, [][2]float64{{0, 0}, {10, 10}}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := PointInPolygon(tt.lat, tt.lon, tt.polygon); got != tt.want {
+				t.Errorf("PointInPolygon(%v, %v) = %v, want %v", tt.lat, tt.lon, got, tt.want)
+			}
+		})
+	}
+}